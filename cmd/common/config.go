@@ -0,0 +1,207 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// Config contains the defaults of the flags, they're used for the flags that
+// aren't set in the command line nor in environment variables
+type Config struct {
+	// Flags contains the defaults of the flags of all the commands
+	Flags map[string]any `yaml:"flags"`
+	// Commands contains the defaults of the flags of specific commands,
+	// indexed by their path without the root command, e.g. "trace exec".
+	// They override the ones in Flags.
+	Commands map[string]map[string]any `yaml:"commands"`
+	// Presets contains named sets of flags applied with --preset, indexed by
+	// the path of the command and their name
+	Presets map[string]map[string]map[string]any `yaml:"presets"`
+}
+
+var config Config
+
+// envPrefix is the prefix of the environment variables setting the flags,
+// they're ignored if empty
+var envPrefix string
+
+// SetEnvPrefix allows to set the flags with environment variables named
+// <PREFIX>_<COMMAND>_<FLAG> or <PREFIX>_<FLAG>, e.g. IG_TRACE_EXEC_OUTPUT or
+// IG_CONTAINERD_SOCKETPATH. They override the configuration files.
+func SetEnvPrefix(prefix string) {
+	envPrefix = prefix
+}
+
+var envReplacer = strings.NewReplacer("-", "_", ".", "_", " ", "_")
+
+func envName(parts ...string) string {
+	return strings.ToUpper(envReplacer.Replace(strings.Join(parts, "_")))
+}
+
+// DefaultConfigFiles returns the configuration files of ig, in increasing order
+// of precedence
+func DefaultConfigFiles() []string {
+	files := []string{"/etc/ig/config.yaml"}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".ig", "config.yaml"))
+	}
+	return files
+}
+
+// LoadConfig reads the given configuration files, the ones that don't exist are
+// ignored. The values of a file override the ones of the previous files.
+func LoadConfig(files ...string) error {
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading config file: %w", err)
+		}
+
+		var fileConfig Config
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&fileConfig); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing config file %q: %w", file, err)
+		}
+		config.merge(&fileConfig)
+	}
+	return nil
+}
+
+func (c *Config) merge(other *Config) {
+	if c.Flags == nil {
+		c.Flags = make(map[string]any)
+	}
+	maps.Copy(c.Flags, other.Flags)
+
+	if c.Commands == nil {
+		c.Commands = make(map[string]map[string]any)
+	}
+	for path, flags := range other.Commands {
+		if c.Commands[path] == nil {
+			c.Commands[path] = make(map[string]any)
+		}
+		maps.Copy(c.Commands[path], flags)
+	}
+
+	if c.Presets == nil {
+		c.Presets = make(map[string]map[string]map[string]any)
+	}
+	for path, presets := range other.Presets {
+		if c.Presets[path] == nil {
+			c.Presets[path] = make(map[string]map[string]any)
+		}
+		maps.Copy(c.Presets[path], presets)
+	}
+}
+
+// commandPath returns the path of cmd without the root command
+func commandPath(cmd *cobra.Command) string {
+	return strings.TrimPrefix(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()), " ")
+}
+
+// configValues returns the values of a flag in the configuration file
+func configValues(value any) []string {
+	if list, ok := value.([]any); ok {
+		values := make([]string, 0, len(list))
+		for _, v := range list {
+			values = append(values, fmt.Sprint(v))
+		}
+		return values
+	}
+	return []string{fmt.Sprint(value)}
+}
+
+// lookup returns the default of the flag name of cmd from the environment or
+// the configuration files
+func (c *Config) lookup(cmd *cobra.Command, name string) ([]string, bool) {
+	path := commandPath(cmd)
+
+	if envPrefix != "" {
+		names := []string{envName(envPrefix, name)}
+		if path != "" {
+			names = append([]string{envName(envPrefix, path, name)}, names...)
+		}
+		for _, name := range names {
+			if value, ok := os.LookupEnv(name); ok {
+				return strings.Split(value, ","), true
+			}
+		}
+	}
+
+	value, ok := c.Commands[path][name]
+	if !ok || value == nil {
+		value, ok = c.Flags[name]
+	}
+	if !ok || value == nil {
+		return nil, false
+	}
+	return configValues(value), true
+}
+
+// ApplyConfig sets the defaults from the environment and the configuration
+// files of the flags of the command that is going to be run with args. It has
+// to be called before executing rootCmd.
+func ApplyConfig(rootCmd *cobra.Command, args []string) error {
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil {
+		// cobra will report it
+		return nil
+	}
+	return applyConfig(cmd, cmd.LocalFlags(), cmd.InheritedFlags())
+}
+
+// applyConfig sets the defaults from the environment and the configuration
+// files of the flags that weren't set in the command line
+func applyConfig(cmd *cobra.Command, flagSets ...*pflag.FlagSet) error {
+	var err error
+	for _, flagSet := range flagSets {
+		flagSet.VisitAll(func(f *pflag.Flag) {
+			if err != nil || f.Changed {
+				return
+			}
+			values, ok := config.lookup(cmd, f.Name)
+			if !ok {
+				return
+			}
+			if slice, ok := f.Value.(pflag.SliceValue); ok {
+				err = slice.Replace(values)
+			} else {
+				err = f.Value.Set(strings.Join(values, ","))
+			}
+			if err != nil {
+				err = fmt.Errorf("setting flag %q from environment or config file: %w", f.Name, err)
+				return
+			}
+			f.DefValue = f.Value.String()
+		})
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o600))
+	return file
+}
+
+func TestConfig(t *testing.T) {
+	systemConfig := writeConfig(t, `
+flags:
+  socketpath: /system.sock
+  output: json
+  columns: [pid, comm]
+commands:
+  trace exec:
+    output: yaml
+`)
+	userConfig := writeConfig(t, `
+flags:
+  socketpath: /user.sock
+  timeout: 5
+`)
+
+	config = Config{}
+	defer func() { config = Config{} }()
+	require.NoError(t, LoadConfig(systemConfig, filepath.Join(t.TempDir(), "missing.yaml"), userConfig))
+
+	var socketPath, output string
+	var timeout int
+	var columns []string
+
+	rootCmd := &cobra.Command{Use: "ig"}
+	rootCmd.PersistentFlags().StringVar(&socketPath, "socketpath", "/default.sock", "")
+	traceCmd := &cobra.Command{Use: "trace"}
+	execCmd := &cobra.Command{Use: "exec", Run: func(*cobra.Command, []string) {}}
+	execCmd.Flags().StringVar(&output, "output", "columns", "")
+	execCmd.Flags().IntVar(&timeout, "timeout", 0, "")
+	execCmd.Flags().StringSliceVar(&columns, "columns", nil, "")
+	traceCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(traceCmd)
+
+	args := []string{"trace", "exec", "--timeout", "10", "--columns", "ppid"}
+	require.NoError(t, ApplyConfig(rootCmd, args))
+	rootCmd.SetArgs(args)
+	require.NoError(t, rootCmd.Execute())
+
+	// The user config overrides the system one
+	require.Equal(t, "/user.sock", socketPath)
+	// The command specific defaults override the global ones
+	require.Equal(t, "yaml", output)
+	// The command line overrides the config
+	require.Equal(t, 10, timeout)
+	require.Equal(t, []string{"ppid"}, columns)
+}
+
+func TestConfigEnv(t *testing.T) {
+	config = Config{}
+	defer func() { config = Config{} }()
+	require.NoError(t, LoadConfig(writeConfig(t, `
+flags:
+  socketpath: /config.sock
+  output: json
+`)))
+
+	SetEnvPrefix("IG")
+	defer SetEnvPrefix("")
+	t.Setenv("IG_SOCKETPATH", "/env.sock")
+	t.Setenv("IG_OUTPUT", "yaml")
+	t.Setenv("IG_TRACE_EXEC_OUTPUT", "columns")
+	t.Setenv("IG_TRACE_EXEC_FILTER_COLUMNS", "pid,comm")
+
+	var socketPath, output string
+	var columns []string
+
+	rootCmd := &cobra.Command{Use: "ig"}
+	rootCmd.PersistentFlags().StringVar(&socketPath, "socketpath", "/default.sock", "")
+	traceCmd := &cobra.Command{Use: "trace"}
+	execCmd := &cobra.Command{Use: "exec", Run: func(*cobra.Command, []string) {}}
+	execCmd.Flags().StringVar(&output, "output", "", "")
+	execCmd.Flags().StringSliceVar(&columns, "filter-columns", nil, "")
+	traceCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(traceCmd)
+
+	args := []string{"trace", "exec"}
+	require.NoError(t, ApplyConfig(rootCmd, args))
+	rootCmd.SetArgs(args)
+	require.NoError(t, rootCmd.Execute())
+
+	// The environment overrides the config files
+	require.Equal(t, "/env.sock", socketPath)
+	// The command specific variables override the global ones
+	require.Equal(t, "columns", output)
+	require.Equal(t, []string{"pid", "comm"}, columns)
+}
+
+func TestConfigInvalid(t *testing.T) {
+	config = Config{}
+	defer func() { config = Config{} }()
+
+	err := LoadConfig(writeConfig(t, "foo: bar\n"))
+	require.ErrorContains(t, err, "field foo not found")
+
+	require.NoError(t, LoadConfig(writeConfig(t, "flags:\n  timeout: foo\n")))
+	rootCmd := &cobra.Command{Use: "ig", Run: func(*cobra.Command, []string) {}}
+	rootCmd.Flags().Int("timeout", 0, "")
+	require.ErrorContains(t, ApplyConfig(rootCmd, nil), "setting flag \"timeout\" from environment or config file")
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const devFlag = "dev"
+
+// devPollInterval is how often the sources of the gadget are checked for
+// changes in dev mode
+const devPollInterval = 500 * time.Millisecond
+
+// DevBuildFunc builds the gadget whose sources are in dir and returns its image
+type DevBuildFunc func(dir string) (string, error)
+
+var devBuilder DevBuildFunc
+
+// EnableDevMode adds --dev to the run command: the gadget is built from the
+// sources in the directory given instead of an image with builder, and rebuilt
+// and restarted each time they change
+func EnableDevMode(builder DevBuildFunc) {
+	devBuilder = builder
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// snapshotSources returns the state of the files in dir. Hidden files and
+// directories, like .git, and backups of editors are ignored.
+func snapshotSources(dir string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if path != dir && (strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			// The file was removed meanwhile
+			return nil
+		}
+		files[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading sources: %w", err)
+	}
+	return files, nil
+}
+
+// sourceWatcher detects the changes of the sources of a gadget
+type sourceWatcher struct {
+	dir  string
+	last map[string]fileState
+}
+
+func newSourceWatcher(dir string) (*sourceWatcher, error) {
+	files, err := snapshotSources(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceWatcher{dir: dir, last: files}, nil
+}
+
+// changed returns whether files were added, removed or modified since the
+// last call
+func (w *sourceWatcher) changed() (bool, error) {
+	files, err := snapshotSources(w.dir)
+	if err != nil {
+		return false, err
+	}
+	if maps.Equal(files, w.last) {
+		return false, nil
+	}
+	w.last = files
+	return true, nil
+}
+
+// runDev runs the gadget with run until ctx is done, rebuilding it with
+// rebuild and restarting it when the sources in dir change. The running
+// gadget is only stopped once the new one is built, so a build error leaves
+// it running. rebuild returns a function to call once it's stopped, to switch
+// to the new gadget.
+func runDev(ctx context.Context, dir string, run func(context.Context) error, rebuild func() (func(), error)) error {
+	watcher, err := newSourceWatcher(dir)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(devPollInterval)
+	defer ticker.Stop()
+
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- run(runCtx)
+		}()
+
+		var apply func()
+		running := true
+		for apply == nil {
+			select {
+			case <-ctx.Done():
+				cancel()
+				if running {
+					<-done
+				}
+				return nil
+			case err := <-done:
+				// The gadget stopped by itself, e.g. after its timeout
+				running = false
+				if err != nil && !errors.Is(err, context.Canceled) {
+					log.Errorf("Gadget stopped: %v", err)
+				}
+				log.Infof("Waiting for changes in %s", dir)
+			case <-ticker.C:
+				changed, err := watcher.changed()
+				if err != nil {
+					log.Warnf("Checking for changes: %v", err)
+					continue
+				}
+				if !changed {
+					continue
+				}
+				log.Infof("Sources changed, rebuilding the gadget")
+				apply, err = rebuild()
+				if err != nil {
+					log.Errorf("Rebuilding the gadget: %v", err)
+					if running {
+						log.Infof("Keeping the running gadget")
+					}
+					apply = nil
+				}
+			}
+		}
+
+		cancel()
+		if running {
+			<-done
+		}
+		apply()
+		log.Infof("Restarting the gadget")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceWatcher(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	program := filepath.Join(dir, "program.bpf.c")
+	require.NoError(t, os.WriteFile(program, []byte("v1"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0o755))
+
+	w, err := newSourceWatcher(dir)
+	require.NoError(t, err)
+
+	changed, err := w.changed()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Hidden files and backups are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".git", "index"), []byte("x"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "program.bpf.c~"), []byte("x"), 0o644))
+	changed, err = w.changed()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, os.WriteFile(program, []byte("version 2"), 0o644))
+	changed, err = w.changed()
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	// The change is only reported once
+	changed, err = w.changed()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "gadget.yaml"), []byte("name: test"), 0o644))
+	changed, err = w.changed()
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	require.NoError(t, os.Remove(program))
+	changed, err = w.changed()
+	require.NoError(t, err)
+	require.True(t, changed)
+}
+
+func TestRunDev(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	program := filepath.Join(dir, "program.bpf.c")
+	require.NoError(t, os.WriteFile(program, []byte("v1"), 0o644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs, builds, applied atomic.Int32
+	run := func(ctx context.Context) error {
+		runs.Add(1)
+		<-ctx.Done()
+		return nil
+	}
+	rebuild := func() (func(), error) {
+		if builds.Add(1) == 1 {
+			return nil, errors.New("syntax error")
+		}
+		return func() { applied.Add(1) }, nil
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- runDev(ctx, dir, run, rebuild)
+	}()
+
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// A failed build keeps the gadget running
+	require.NoError(t, os.WriteFile(program, []byte("broken"), 0o644))
+	require.Eventually(t, func() bool { return builds.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), runs.Load())
+	require.Equal(t, int32(0), applied.Load())
+
+	require.NoError(t, os.WriteFile(program, []byte("version 3"), 0o644))
+	require.Eventually(t, func() bool { return runs.Load() == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, int32(1), applied.Load())
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
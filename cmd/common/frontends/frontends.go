@@ -22,6 +22,8 @@ import (
 
 type Frontend interface {
 	Output(payload string)
+	// OutputRaw outputs payload as is, e.g. for binary formats
+	OutputRaw(payload []byte)
 	Logf(severity logger.Level, fmt string, params ...any)
 	IsTerminal() bool
 	Clear()
@@ -0,0 +1,55 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgetpolicy"
+)
+
+var gadgetPolicyFile string
+
+// AddGadgetPolicyFlags adds the flag to set the policy deciding which gadgets
+// can run
+func AddGadgetPolicyFlags(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().StringVar(
+		&gadgetPolicyFile,
+		"gadget-policy",
+		"",
+		"YAML file with the policy denying gadgets by registry, digest, program types and attach targets, and limiting their resources",
+	)
+}
+
+// ApplyGadgetPolicyFlags sets the gadget policy from the flag added by
+// AddGadgetPolicyFlags. The flags must be parsed before calling it.
+func ApplyGadgetPolicyFlags() error {
+	if gadgetPolicyFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(gadgetPolicyFile)
+	if err != nil {
+		return fmt.Errorf("reading gadget policy: %w", err)
+	}
+	policy, err := gadgetpolicy.ParsePolicy(data)
+	if err != nil {
+		return fmt.Errorf("parsing gadget policy: %w", err)
+	}
+	gadgetpolicy.SetPolicy(policy)
+	return nil
+}
@@ -68,6 +68,7 @@ type cmdOpts struct {
 	builderImage     string
 	updateMetadata   bool
 	validateMetadata bool
+	btfhubArchive    string
 }
 
 func NewBuildCmd() *cobra.Command {
@@ -88,6 +89,14 @@ func NewBuildCmd() *cobra.Command {
 
 			opts.path = args[0]
 
+			if opts.btfhubArchive != "" {
+				var err error
+				opts.btfhubArchive, err = filepath.Abs(opts.btfhubArchive)
+				if err != nil {
+					return fmt.Errorf("getting absolute path of btfhub archive: %w", err)
+				}
+			}
+
 			return runBuild(opts)
 		},
 	}
@@ -98,6 +107,7 @@ func NewBuildCmd() *cobra.Command {
 	cmd.Flags().StringVar(&opts.builderImage, "builder-image", builderImage, "Builder image to use")
 	cmd.Flags().BoolVar(&opts.updateMetadata, "update-metadata", false, "Update the metadata according to the eBPF code")
 	cmd.Flags().BoolVar(&opts.validateMetadata, "validate-metadata", true, "Validate the metadata file before building the gadget image")
+	cmd.Flags().StringVar(&opts.btfhubArchive, "btfhub-archive", "", "Path to a btfhub-archive checkout. If set, btfgen-reduced BTFs are generated and included in the image")
 
 	return utils.MarkExperimental(cmd)
 }
@@ -173,6 +183,13 @@ func runBuild(opts *cmdOpts) error {
 		ValidateMetadata: opts.validateMetadata,
 	}
 
+	if opts.btfhubArchive != "" {
+		buildOpts.BTFGenPaths = map[string]string{
+			oci.ArchAmd64: filepath.Join(tmpDir, oci.ArchAmd64+".btfs.tar.gz"),
+			oci.ArchArm64: filepath.Join(tmpDir, oci.ArchArm64+".btfs.tar.gz"),
+		}
+	}
+
 	if strings.HasSuffix(conf.Wasm, ".wasm") {
 		// User provided an already-built wasm file
 		buildOpts.WasmObjectPath = conf.Wasm
@@ -204,6 +221,7 @@ func buildLocal(opts *cmdOpts, conf *buildFile, output string) error {
 		"WASM="+conf.Wasm,
 		"OUTPUTDIR="+output,
 		"CFLAGS="+conf.CFlags,
+		"BTFHUB_ARCHIVE="+opts.btfhubArchive,
 	)
 	if out, err := buildCmd.CombinedOutput(); err != nil {
 		return fmt.Errorf("build script: %w: %s", err, out)
@@ -262,6 +280,31 @@ func buildInContainer(opts *cmdOpts, conf *buildFile, output string) error {
 	if conf.Wasm != "" {
 		wasmFullPath = filepath.Join("/work", conf.Wasm)
 	}
+	mounts := []mount.Mount{
+		{
+			Type:     mount.TypeBind,
+			Target:   "/work",
+			Source:   cwd,
+			ReadOnly: true,
+		},
+		{
+			Type:   mount.TypeBind,
+			Target: "/out",
+			Source: output,
+		},
+	}
+
+	btfhubArchive := ""
+	if opts.btfhubArchive != "" {
+		btfhubArchive = "/btfhub-archive"
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Target:   btfhubArchive,
+			Source:   opts.btfhubArchive,
+			ReadOnly: true,
+		})
+	}
+
 	resp, err := cli.ContainerCreate(
 		ctx,
 		&container.Config{
@@ -272,23 +315,12 @@ func buildInContainer(opts *cmdOpts, conf *buildFile, output string) error {
 				"WASM=" + wasmFullPath,
 				"OUTPUTDIR=/out",
 				"CFLAGS=" + conf.CFlags,
+				"BTFHUB_ARCHIVE=" + btfhubArchive,
 			},
 			User: fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
 		},
 		&container.HostConfig{
-			Mounts: []mount.Mount{
-				{
-					Type:     mount.TypeBind,
-					Target:   "/work",
-					Source:   cwd,
-					ReadOnly: true,
-				},
-				{
-					Type:   mount.TypeBind,
-					Target: "/out",
-					Source: output,
-				},
-			},
+			Mounts: mounts,
 		},
 		nil, nil, "",
 	)
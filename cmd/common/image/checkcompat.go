@@ -0,0 +1,98 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/formatter/textcolumns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/tracer"
+)
+
+type compatRow struct {
+	Kind     string `column:"kind"`
+	Name     string `column:"name,width:32"`
+	Status   string `column:"status"`
+	Programs string `column:"programs"`
+	Reason   string `column:"reason,width:48"`
+}
+
+func NewCheckCompatCmd() *cobra.Command {
+	var objectPath string
+
+	cmd := &cobra.Command{
+		Use:   "check-compat [IMAGE]",
+		Short: "Check what the kernel supports of what a gadget needs",
+		Long: `Check what the kernel supports of what a gadget needs: its requirements, the
+types of its programs and maps, the helpers its programs call and the points they
+attach to. The programs of the gadget aren't loaded, only small programs probing
+the features of the kernel are.
+
+The status of a check is unknown when it can only be known when running the
+gadget, e.g. for uprobes on the binaries of the containers.`,
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gadget, err := localGadget(args, objectPath)
+			if err != nil {
+				return err
+			}
+
+			if err := rlimit.RemoveMemlock(); err != nil {
+				return fmt.Errorf("removing memlock limit: %w", err)
+			}
+
+			checks, err := tracer.CheckCompat(gadget)
+			if err != nil {
+				return err
+			}
+
+			rows := make([]*compatRow, 0, len(checks))
+			unsupported := 0
+			for _, check := range checks {
+				rows = append(rows, &compatRow{
+					Kind:     check.Kind,
+					Name:     check.Name,
+					Status:   string(check.Status),
+					Programs: strings.Join(check.Programs, ","),
+					Reason:   check.Reason,
+				})
+				if check.Status == tracer.CompatUnsupported {
+					unsupported++
+				}
+			}
+
+			cols := columns.MustCreateColumns[compatRow]()
+			formatter := textcolumns.NewFormatter(cols.GetColumnMap())
+			formatter.WriteTable(os.Stdout, rows)
+
+			if unsupported > 0 {
+				return fmt.Errorf("the kernel doesn't support %d of the things the gadget needs", unsupported)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&objectPath, "object", "", "Path to the eBPF object file")
+
+	return utils.MarkExperimental(cmd)
+}
@@ -0,0 +1,54 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// devImagePrefix is the prefix of the images built from the sources of the
+// gadgets run with --dev
+const devImagePrefix = "localhost/ig-dev/"
+
+var invalidImageChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// devImageName returns the name of the image built from the sources in dir
+func devImageName(dir string) string {
+	name := invalidImageChars.ReplaceAllString(strings.ToLower(filepath.Base(dir)), "-")
+	name = strings.Trim(name, ".-_")
+	if name == "" {
+		name = "gadget"
+	}
+	return devImagePrefix + name + ":latest"
+}
+
+// BuildDev builds the gadget whose sources are in dir, like "ig image build",
+// and returns its image. It's used to run gadgets with --dev.
+func BuildDev(dir string) (string, error) {
+	image := devImageName(dir)
+	opts := &cmdOpts{
+		path:             dir,
+		file:             "build.yaml",
+		image:            image,
+		builderImage:     builderImage,
+		validateMetadata: true,
+	}
+	if err := runBuild(opts); err != nil {
+		return "", err
+	}
+	return image, nil
+}
@@ -0,0 +1,29 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDevImageName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "localhost/ig-dev/mygadget:latest", devImageName("/home/user/mygadget"))
+	require.Equal(t, "localhost/ig-dev/my-gadget:latest", devImageName("/src/My Gadget"))
+	require.Equal(t, "localhost/ig-dev/gadget:latest", devImageName("/"))
+}
@@ -31,6 +31,9 @@ func NewImageCmd() *cobra.Command {
 	cmd.AddCommand(NewPullCmd())
 	cmd.AddCommand(NewTagCmd())
 	cmd.AddCommand(NewListCmd())
+	cmd.AddCommand(NewValidateCmd())
+	cmd.AddCommand(NewVerifyLoadCmd())
+	cmd.AddCommand(NewCheckCompatCmd())
 
 	return utils.MarkExperimental(cmd)
 }
@@ -0,0 +1,78 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+func NewValidateCmd() *cobra.Command {
+	var metadataPath, objectPath string
+
+	cmd := &cobra.Command{
+		Use:   "validate [IMAGE]",
+		Short: "Validate the metadata of a gadget against its eBPF object",
+		Long: `Validate the metadata of a gadget against its eBPF object.
+
+The metadata of the local IMAGE is validated, or the files given with
+--metadata and --object if no image is specified, e.g. before building it.`,
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				if metadataPath != "" || objectPath != "" {
+					return errors.New("--metadata and --object can't be used with an image")
+				}
+				image := args[0]
+				if err := oci.ValidateGadgetImage(context.TODO(), image); err != nil {
+					return fmt.Errorf("validating image %q: %w", image, err)
+				}
+				fmt.Printf("Metadata of %s is valid\n", image)
+				return nil
+			}
+
+			if metadataPath == "" || objectPath == "" {
+				return errors.New("either an image or both --metadata and --object are required")
+			}
+			metadata, err := os.ReadFile(metadataPath)
+			if err != nil {
+				return fmt.Errorf("reading metadata file: %w", err)
+			}
+			object, err := os.ReadFile(objectPath)
+			if err != nil {
+				return fmt.Errorf("reading eBPF object: %w", err)
+			}
+			if err := types.ValidateMetadata(metadata, object); err != nil {
+				return fmt.Errorf("validating %q: %w", metadataPath, err)
+			}
+			fmt.Printf("Metadata %s is valid\n", metadataPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&metadataPath, "metadata", "", "Path to the metadata file, e.g. gadget.yaml")
+	cmd.Flags().StringVar(&objectPath, "object", "", "Path to the eBPF object file")
+
+	return utils.MarkExperimental(cmd)
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/formatter/textcolumns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/tracer"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+type programLoadRow struct {
+	Program string `column:"program"`
+	Type    string `column:"type"`
+	Section string `column:"section"`
+	Result  string `column:"result"`
+	Stats   string `column:"stats"`
+}
+
+// localGadget returns the local gadget image given as argument, or a gadget
+// made of the eBPF object at objectPath
+func localGadget(args []string, objectPath string) (*oci.GadgetImage, error) {
+	switch {
+	case len(args) == 1 && objectPath != "":
+		return nil, errors.New("--object can't be used with an image")
+	case len(args) == 1:
+		gadget, err := oci.GetGadgetImage(context.TODO(), args[0], &oci.AuthOptions{}, oci.PullImageNever)
+		if err != nil {
+			return nil, fmt.Errorf("getting gadget image: %w", err)
+		}
+		return gadget, nil
+	case objectPath != "":
+		object, err := os.ReadFile(objectPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading eBPF object: %w", err)
+		}
+		return &oci.GadgetImage{EbpfObject: object}, nil
+	}
+	return nil, errors.New("either an image or --object is required")
+}
+
+func NewVerifyLoadCmd() *cobra.Command {
+	var objectPath string
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "verify-load [IMAGE]",
+		Short: "Check that the programs of a gadget load on the current kernel",
+		Long: `Check that the programs of a gadget load on the current kernel.
+
+The programs of the local IMAGE, or of the eBPF object given with --object, are
+loaded through the verifier one by one and unloaded right away. Nothing is
+attached, so it's safe to run on production nodes to check their compatibility
+with a gadget.`,
+		SilenceUsage: true,
+		Args:         cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			gadget, err := localGadget(args, objectPath)
+			if err != nil {
+				return err
+			}
+
+			if err := rlimit.RemoveMemlock(); err != nil {
+				return fmt.Errorf("removing memlock limit: %w", err)
+			}
+
+			results, err := tracer.VerifyLoad(gadget, verbose)
+			if err != nil {
+				return err
+			}
+
+			rows := make([]*programLoadRow, 0, len(results))
+			failed := 0
+			for _, result := range results {
+				row := &programLoadRow{
+					Program: result.Name,
+					Type:    result.Type.String(),
+					Section: result.Section,
+					Result:  "ok",
+					Stats:   result.Stats,
+				}
+				if result.Err != nil {
+					row.Result = "failed"
+					failed++
+				}
+				rows = append(rows, row)
+			}
+
+			cols := columns.MustCreateColumns[programLoadRow]()
+			formatter := textcolumns.NewFormatter(cols.GetColumnMap())
+			formatter.WriteTable(os.Stdout, rows)
+
+			for _, result := range results {
+				switch {
+				case result.Err != nil:
+					fmt.Printf("\nProgram %s failed to load:\n", result.Name)
+					var ve *ebpf.VerifierError
+					if verbose && errors.As(result.Err, &ve) {
+						fmt.Printf("%+v\n", ve)
+					} else {
+						fmt.Printf("%v\n", result.Err)
+					}
+				case result.VerifierLog != "":
+					fmt.Printf("\nVerifier log of program %s:\n%s", result.Name, result.VerifierLog)
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d programs failed to load", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&objectPath, "object", "", "Path to the eBPF object file")
+	cmd.Flags().BoolVarP(&verbose, "verbose-verifier", "", false, "Print the whole log of the verifier")
+
+	return utils.MarkExperimental(cmd)
+}
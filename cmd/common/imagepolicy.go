@@ -0,0 +1,79 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+var (
+	allowedImageDigests []string
+	trustedImageKeys    []string
+)
+
+// AddImagePolicyFlags adds the flags restricting the gadget images that can run
+func AddImagePolicyFlags(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().StringSliceVar(
+		&allowedImageDigests,
+		"allowed-image-digests",
+		nil,
+		"Only run the gadget images with these digests (e.g. sha256:...) or signed by one of --trusted-image-keys",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(
+		&trustedImageKeys,
+		"trusted-image-keys",
+		nil,
+		"Files with PEM encoded public keys (e.g. cosign.pub). Only run the gadget images with a cosign signature by one of them or listed in --allowed-image-digests",
+	)
+}
+
+// ReadPublicKeyFiles returns the content of the PEM files in paths
+func ReadPublicKeyFiles(paths []string) ([]byte, error) {
+	var keys []byte
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading public key: %w", err)
+		}
+		keys = append(keys, data...)
+		keys = append(keys, '\n')
+	}
+	return keys, nil
+}
+
+// ApplyImagePolicyFlags sets the image policy from the flags added by
+// AddImagePolicyFlags. The flags must be parsed before calling it.
+func ApplyImagePolicyFlags() error {
+	keys, err := ReadPublicKeyFiles(trustedImageKeys)
+	if err != nil {
+		return err
+	}
+	policy, err := oci.NewImagePolicy(allowedImageDigests, keys)
+	if err != nil {
+		return fmt.Errorf("creating image policy: %w", err)
+	}
+	if policy != nil {
+		log.Debugf("Only running gadget images with %d allowed digests or signed by %d trusted keys",
+			len(policy.AllowedDigests), len(policy.PublicKeys))
+	}
+	oci.SetImagePolicy(policy)
+	return nil
+}
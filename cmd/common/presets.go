@@ -0,0 +1,83 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	runTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+const presetFlag = "preset"
+
+// presets returns the presets available for cmd, the ones of the config file
+// override the ones of the gadget metadata
+func presets(cmd *cobra.Command, metadata *runTypes.GadgetMetadata) map[string]map[string][]string {
+	presets := make(map[string]map[string][]string)
+	if metadata != nil {
+		for name, preset := range metadata.Presets {
+			values := make(map[string][]string, len(preset.Params))
+			for key, value := range preset.Params {
+				values[key] = []string{value}
+			}
+			presets[name] = values
+		}
+	}
+	for name, preset := range config.Presets[commandPath(cmd)] {
+		values := make(map[string][]string, len(preset))
+		for key, value := range preset {
+			values[key] = configValues(value)
+		}
+		presets[name] = values
+	}
+	return presets
+}
+
+// applyPreset sets the flags of the preset name that weren't set in the command
+// line
+func applyPreset(cmd *cobra.Command, name string, metadata *runTypes.GadgetMetadata) error {
+	presets := presets(cmd, metadata)
+	preset, ok := presets[name]
+	if !ok {
+		names := make([]string, 0, len(presets))
+		for presetName := range presets {
+			names = append(names, presetName)
+		}
+		slices.Sort(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown preset %q: no presets available", name)
+		}
+		return fmt.Errorf("unknown preset %q: available presets are: %s", name, strings.Join(names, ", "))
+	}
+
+	for flagName, values := range preset {
+		f := cmd.Flag(flagName)
+		if f == nil {
+			return fmt.Errorf("preset %q sets unknown flag %q", name, flagName)
+		}
+		if f.Changed {
+			continue
+		}
+		// Marks the flag as changed, so the config doesn't override it
+		if err := cmd.Flags().Set(flagName, strings.Join(values, ",")); err != nil {
+			return fmt.Errorf("setting flag %q from preset %q: %w", flagName, name, err)
+		}
+	}
+	return nil
+}
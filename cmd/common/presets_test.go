@@ -0,0 +1,84 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	runTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+func TestApplyPreset(t *testing.T) {
+	config = Config{}
+	defer func() { config = Config{} }()
+	require.NoError(t, LoadConfig(writeConfig(t, `
+flags:
+  qtype: A
+presets:
+  trace dns:
+    noisy-dns:
+      qtype: [A, AAAA]
+      filter: name:~example
+`)))
+
+	metadata := &runTypes.GadgetMetadata{
+		Presets: map[string]runTypes.Preset{
+			"noisy-dns": {Params: map[string]string{"qtype": "MX"}},
+			"slow":      {Params: map[string]string{"latency": "10ms"}},
+		},
+	}
+
+	newCmd := func() (*cobra.Command, *[]string, *string, *string) {
+		var qtypes []string
+		var filter, latency string
+
+		rootCmd := &cobra.Command{Use: "ig"}
+		traceCmd := &cobra.Command{Use: "trace"}
+		dnsCmd := &cobra.Command{Use: "dns"}
+		dnsCmd.Flags().StringSliceVar(&qtypes, "qtype", nil, "")
+		dnsCmd.Flags().StringVar(&filter, "filter", "", "")
+		dnsCmd.Flags().StringVar(&latency, "latency", "", "")
+		traceCmd.AddCommand(dnsCmd)
+		rootCmd.AddCommand(traceCmd)
+		return dnsCmd, &qtypes, &filter, &latency
+	}
+
+	// The config file overrides the presets of the metadata and the command
+	// line overrides both
+	cmd, qtypes, filter, _ := newCmd()
+	require.NoError(t, cmd.ParseFlags([]string{"--filter", "comm:dig"}))
+	require.NoError(t, applyPreset(cmd, "noisy-dns", metadata))
+	require.NoError(t, applyConfig(cmd, cmd.Flags()))
+	require.Equal(t, []string{"A", "AAAA"}, *qtypes)
+	require.Equal(t, "comm:dig", *filter)
+
+	cmd, qtypes, _, latency := newCmd()
+	require.NoError(t, cmd.ParseFlags(nil))
+	require.NoError(t, applyPreset(cmd, "slow", metadata))
+	require.NoError(t, applyConfig(cmd, cmd.Flags()))
+	require.Equal(t, "10ms", *latency)
+	require.Equal(t, []string{"A"}, *qtypes)
+
+	cmd, _, _, _ = newCmd()
+	err := applyPreset(cmd, "foo", metadata)
+	require.ErrorContains(t, err, "unknown preset \"foo\": available presets are: noisy-dns, slow")
+
+	config.Presets["trace dns"]["bad"] = map[string]any{"foo": "bar"}
+	cmd, _, _, _ = newCmd()
+	require.ErrorContains(t, applyPreset(cmd, "bad", nil), "preset \"bad\" sets unknown flag \"foo\"")
+}
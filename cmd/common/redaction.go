@@ -0,0 +1,55 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
+)
+
+var redactionConfigFile string
+
+// AddRedactionFlags adds the flag to set the redaction of the values looking
+// like secrets in the events
+func AddRedactionFlags(rootCmd *cobra.Command) {
+	rootCmd.PersistentFlags().StringVar(
+		&redactionConfigFile,
+		"redaction-config",
+		"",
+		"YAML file with the fields and patterns of the values to mask in the events of all the gadgets",
+	)
+}
+
+// ApplyRedactionFlags sets the redaction configuration from the flag added by
+// AddRedactionFlags. The flags must be parsed before calling it.
+func ApplyRedactionFlags() error {
+	if redactionConfigFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(redactionConfigFile)
+	if err != nil {
+		return fmt.Errorf("reading redaction config: %w", err)
+	}
+	config, err := redaction.ParseConfig(data)
+	if err != nil {
+		return fmt.Errorf("parsing redaction config: %w", err)
+	}
+	redaction.SetConfig(config)
+	return nil
+}
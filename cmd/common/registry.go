@@ -19,17 +19,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	k8syaml "sigs.k8s.io/yaml"
 
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/frontends"
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/frontends/console"
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
 	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
@@ -39,6 +42,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/parser"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
 )
 
 const (
@@ -192,6 +196,10 @@ func buildCommandFromGadget(
 	var outputMode string
 	var filters []string
 	var timeout int
+	var preset string
+	// With --dev, the gadget is built from the sources in devDir as devImage
+	var dev bool
+	var devDir, devImage string
 
 	var skipParams []params.ValueHint
 	if skipParamsInterface, ok := gadgetDesc.(gadgets.GadgetDescSkipParams); ok {
@@ -231,6 +239,9 @@ func buildCommandFromGadget(
 		//   been parsed there (e.g. --verbose)
 		DisableFlagParsing: true,
 
+		// cobra doesn't complete the flags either, they're passed as args
+		ValidArgsFunction: completeFlagValues,
+
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			err := runtime.Init(runtimeGlobalParams)
 			if err != nil {
@@ -253,6 +264,18 @@ func buildCommandFromGadget(
 			// different tasks like creating the parser and setting flags for the
 			// gadget's parameters.
 			onlyArgs := cmd.Flags().Args()
+			if isRunGadget && dev && len(onlyArgs) > 0 {
+				var err error
+				devDir, err = filepath.Abs(onlyArgs[0])
+				if err != nil {
+					return fmt.Errorf("getting absolute path of %q: %w", onlyArgs[0], err)
+				}
+				devImage, err = devBuilder(devDir)
+				if err != nil {
+					return fmt.Errorf("building gadget: %w", err)
+				}
+				onlyArgs = []string{devImage}
+			}
 			if isRunGadget && len(onlyArgs) > 0 {
 				var err error
 				runGadgetInfo, err = runtime.GetGadgetInfo(context.TODO(), gadgetDesc, gadgetParams, onlyArgs)
@@ -354,7 +377,22 @@ func buildCommandFromGadget(
 
 			gadgetParams.Add(extraGadgetParams...)
 
-			return cmd.ParseFlags(args)
+			if err := cmd.ParseFlags(args); err != nil {
+				return err
+			}
+
+			if preset != "" {
+				var metadata *runTypes.GadgetMetadata
+				if runGadgetInfo != nil {
+					metadata = runGadgetInfo.GadgetMetadata
+				}
+				if err := applyPreset(cmd, preset, metadata); err != nil {
+					return err
+				}
+			}
+
+			// The flags added above didn't exist when the config was applied
+			return applyConfig(cmd, cmd.Flags())
 		},
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			// args from RunE still contains all flags, since we manually parsed them,
@@ -375,11 +413,19 @@ func buildCommandFromGadget(
 				return cmd.Help()
 			}
 
+			if dev {
+				args = []string{devImage}
+			}
+
 			// we also manually need to check the verbose flag, as PersistentPreRunE in
 			// verbose.go will not have the correct information due to manually parsing
 			// the flags
 			checkVerboseFlag()
 
+			if err := gadgetParams.ValidateConstraints(); err != nil {
+				return err
+			}
+
 			err := runtime.Init(runtimeGlobalParams)
 			if err != nil {
 				return fmt.Errorf("initializing runtime: %w", err)
@@ -414,295 +460,368 @@ func buildCommandFromGadget(
 				timeoutDuration = time.Duration(timeout) * time.Second
 			}
 
-			gadgetCtx := gadgetcontext.New(
-				ctx,
-				"",
-				runtime,
-				runtimeParams,
-				gadgetDesc,
-				gadgetParams,
-				args,
-				operatorsParamsCollection,
-				parser,
-				logger.DefaultLogger(),
-				timeoutDuration,
-				runGadgetInfo,
-			)
-			defer gadgetCtx.Cancel()
+			runGadget := func(ctx context.Context) error {
+				gadgetCtx := gadgetcontext.New(
+					ctx,
+					"",
+					runtime,
+					runtimeParams,
+					gadgetDesc,
+					gadgetParams,
+					args,
+					operatorsParamsCollection,
+					parser,
+					logger.DefaultLogger(),
+					timeoutDuration,
+					runGadgetInfo,
+				)
+				defer gadgetCtx.Cancel()
 
-			outputModeInfo := strings.SplitN(outputMode, "=", 2)
-			outputModeName := outputModeInfo[0]
-			outputModeParams := ""
-			if len(outputModeInfo) > 1 {
-				outputModeParams = outputModeInfo[1]
-			}
+				outputModeInfo := strings.SplitN(outputMode, "=", 2)
+				outputModeName := outputModeInfo[0]
+				outputModeParams := ""
+				if len(outputModeInfo) > 1 {
+					outputModeParams = outputModeInfo[1]
+				}
 
-			if parser == nil {
-				var transformResult func(any) ([]byte, error)
+				if parser == nil {
+					var transformResult func(any) ([]byte, error)
 
-				switch outputModeName {
-				default:
-					transformer, ok := gadgetDesc.(gadgets.GadgetOutputFormats)
-					if !ok {
-						return fmt.Errorf("gadget does not provide output formats")
-					}
-					formats, _ := transformer.OutputFormats()
-					if _, ok := formats[outputModeName]; !ok {
-						return fmt.Errorf("invalid output mode %q", outputModeName)
-					}
+					switch outputModeName {
+					default:
+						transformer, ok := gadgetDesc.(gadgets.GadgetOutputFormats)
+						if !ok {
+							return fmt.Errorf("gadget does not provide output formats")
+						}
+						formats, _ := transformer.OutputFormats()
+						if _, ok := formats[outputModeName]; !ok {
+							return fmt.Errorf("invalid output mode %q", outputModeName)
+						}
 
-					transformResult = formats[outputModeName].Transform
-				case OutputModeJSON:
-					transformResult = func(result any) ([]byte, error) {
-						r, _ := result.([]byte)
-						return r, nil
+						transformResult = formats[outputModeName].Transform
+					case OutputModeJSON:
+						transformResult = func(result any) ([]byte, error) {
+							r, _ := result.([]byte)
+							return r, nil
+						}
+					case OutputModeJSONPretty:
+						printEventAsJSONPrettyFn(fe)
+					case OutputModeYAML:
+						printEventAsYAMLFn(fe)
 					}
-				case OutputModeJSONPretty:
-					printEventAsJSONPrettyFn(fe)
-				case OutputModeYAML:
-					printEventAsYAMLFn(fe)
-				}
 
-				if timeout == 0 && gType != gadgets.TypeTrace && gType != gadgets.TypeTraceIntervals {
-					gadgetCtx.Logger().Info("Running. Press Ctrl + C to finish")
-				}
+					if timeout == 0 && gType != gadgets.TypeTrace && gType != gadgets.TypeTraceIntervals {
+						gadgetCtx.Logger().Info("Running. Press Ctrl + C to finish")
+					}
 
-				// This kind of gadgets return directly the result instead of
-				// using the parser. We allow partial results, so error is only
-				// returned after handling those results.
-				results, err := runtime.RunGadget(gadgetCtx)
+					// This kind of gadgets return directly the result instead of
+					// using the parser. We allow partial results, so error is only
+					// returned after handling those results.
+					results, err := runtime.RunGadget(gadgetCtx)
 
-				for node, result := range results {
-					if result.Error != nil {
-						continue
-					}
-					transformed, err := transformResult(result.Payload)
-					if err != nil {
-						gadgetCtx.Logger().Warnf("transform result for %q failed: %v", node, err)
-						continue
+					for node, result := range results {
+						if result.Error != nil {
+							continue
+						}
+						transformed, err := transformResult(result.Payload)
+						if err != nil {
+							gadgetCtx.Logger().Warnf("transform result for %q failed: %v", node, err)
+							continue
+						}
+						results[node].Payload = transformed
 					}
-					results[node].Payload = transformed
-				}
 
-				if len(results) == 1 {
-					// still need to iterate as we don't necessarily know the key
-					for _, result := range results {
-						fe.Output(string(result.Payload))
-					}
-				} else {
-					format := "%s: %s"
-					for _, result := range results {
-						// Check, whether we have a multi-line payload and adjust the output accordingly
-						if bytes.Contains(result.Payload, []byte("\n")) {
-							format = "\n---\n%s:\n%s"
-							break
+					if len(results) == 1 {
+						// still need to iterate as we don't necessarily know the key
+						for _, result := range results {
+							fe.Output(string(result.Payload))
+						}
+					} else {
+						format := "%s: %s"
+						for _, result := range results {
+							// Check, whether we have a multi-line payload and adjust the output accordingly
+							if bytes.Contains(result.Payload, []byte("\n")) {
+								format = "\n---\n%s:\n%s"
+								break
+							}
+						}
+						for key, result := range results {
+							fe.Output(fmt.Sprintf(format, key, string(result.Payload)))
 						}
 					}
-					for key, result := range results {
-						fe.Output(fmt.Sprintf(format, key, string(result.Payload)))
-					}
-				}
 
-				return err
-			}
+					return err
+				}
 
-			// Add filters if requested
-			if len(filters) > 0 {
-				err = parser.SetFilters(filters)
-				if err != nil {
-					return fmt.Errorf("setting filters: %w", err)
+				// Add filters if requested
+				if len(filters) > 0 {
+					err = parser.SetFilters(filters)
+					if err != nil {
+						return fmt.Errorf("setting filters: %w", err)
+					}
 				}
-			}
 
-			if gType.CanSort() {
-				sortBy := gadgetParams.Get(gadgets.ParamSortBy).AsStringSlice()
-				err := parser.SetSorting(sortBy)
-				if err != nil {
-					return fmt.Errorf("setting sort order: %w", err)
+				if gType.CanSort() {
+					sortBy := gadgetParams.Get(gadgets.ParamSortBy).AsStringSlice()
+					err := parser.SetSorting(sortBy)
+					if err != nil {
+						return fmt.Errorf("setting sort order: %w", err)
+					}
 				}
-			}
 
-			formatter := parser.GetTextColumnsFormatter()
+				formatter := parser.GetTextColumnsFormatter()
 
-			requestedStandardColumns := outputModeParams == ""
-			requestedColumns := make([]string, 0)
+				requestedStandardColumns := outputModeParams == ""
+				requestedColumns := make([]string, 0)
 
-			// Check, if columns were requested relatively
-			// (using only +column and -column syntax)
-			addCols := make([]string, 0)
-			removeCols := make([]string, 0)
-			requestedAllRelativeColumns := true
-			for _, col := range strings.Split(strings.ToLower(outputModeParams), ",") {
-				if strings.HasPrefix(col, "+") {
-					for _, c := range expandedColumns(strings.TrimPrefix(col, "+")) {
-						addCols = append(addCols, c)
+				// Check, if columns were requested relatively
+				// (using only +column and -column syntax)
+				addCols := make([]string, 0)
+				removeCols := make([]string, 0)
+				requestedAllRelativeColumns := true
+				for _, col := range strings.Split(strings.ToLower(outputModeParams), ",") {
+					if strings.HasPrefix(col, "+") {
+						for _, c := range expandedColumns(strings.TrimPrefix(col, "+")) {
+							addCols = append(addCols, c)
+						}
+						continue
 					}
-					continue
-				}
-				if strings.HasPrefix(col, "-") {
-					for _, c := range expandedColumns(strings.TrimPrefix(col, "-")) {
-						removeCols = append(removeCols, c)
+					if strings.HasPrefix(col, "-") {
+						for _, c := range expandedColumns(strings.TrimPrefix(col, "-")) {
+							removeCols = append(removeCols, c)
+						}
+						continue
 					}
-					continue
+					requestedAllRelativeColumns = false
+					requestedColumns = append(requestedColumns, expandedColumns(col)...)
 				}
-				requestedAllRelativeColumns = false
-				requestedColumns = append(requestedColumns, expandedColumns(col)...)
-			}
 
-			// If all column requests are relative, reset requestedStandardColumns
-			if requestedAllRelativeColumns {
-				requestedStandardColumns = true
-			}
+				// If all column requests are relative, reset requestedStandardColumns
+				if requestedAllRelativeColumns {
+					requestedStandardColumns = true
+				}
 
-			// If the standard columns are requested, hide columns that would be empty without specific features
-			// (bool params) enabled
-			if requestedStandardColumns {
-				var hiddenTags []string
-				if gadgetParams != nil {
-					for _, param := range *gadgetParams {
-						if param.TypeHint == params.TypeBool {
-							if !param.AsBool() {
-								hiddenTags = append(hiddenTags, "param:"+strings.ToLower(param.Key))
+				// If the standard columns are requested, hide columns that would be empty without specific features
+				// (bool params) enabled
+				if requestedStandardColumns {
+					var hiddenTags []string
+					if gadgetParams != nil {
+						for _, param := range *gadgetParams {
+							if param.TypeHint == params.TypeBool {
+								if !param.AsBool() {
+									hiddenTags = append(hiddenTags, "param:"+strings.ToLower(param.Key))
+								}
 							}
 						}
 					}
+					// hide columns by tag (e.g. kubernetes, runtime) if requested by the caller
+					if len(hiddenColumnTags) > 0 {
+						hiddenTags = append(hiddenTags, hiddenColumnTags...)
+					}
+					requestedColumns = append(requestedColumns, parser.GetDefaultColumns(hiddenTags...)...)
+
+					requestedColumns = addClusterColumn(requestedColumns, runtimeParams, parser.GetColumnAttributes())
 				}
-				// hide columns by tag (e.g. kubernetes, runtime) if requested by the caller
-				if len(hiddenColumnTags) > 0 {
-					hiddenTags = append(hiddenTags, hiddenColumnTags...)
-				}
-				requestedColumns = append(requestedColumns, parser.GetDefaultColumns(hiddenTags...)...)
-			}
 
-			// Add/remove relative column requests
-			if len(addCols) > 0 || len(removeCols) > 0 {
-				newRequestedColumns := make([]string, 0)
-				for _, col := range requestedColumns {
-					if containsColumn(removeCols, col) {
-						continue
+				// Add/remove relative column requests
+				if len(addCols) > 0 || len(removeCols) > 0 {
+					newRequestedColumns := make([]string, 0)
+					for _, col := range requestedColumns {
+						if containsColumn(removeCols, col) {
+							continue
+						}
+						newRequestedColumns = append(newRequestedColumns, col)
 					}
-					newRequestedColumns = append(newRequestedColumns, col)
-				}
-				// add remaining columns
-				for _, col := range addCols {
-					if containsColumn(newRequestedColumns, col) || containsColumn(removeCols, col) {
-						continue
+					// add remaining columns
+					for _, col := range addCols {
+						if containsColumn(newRequestedColumns, col) || containsColumn(removeCols, col) {
+							continue
+						}
+						newRequestedColumns = append(newRequestedColumns, col)
 					}
-					newRequestedColumns = append(newRequestedColumns, col)
+					requestedColumns = newRequestedColumns
 				}
-				requestedColumns = newRequestedColumns
-			}
-
-			// sort columns by runtime and kubernetes columns
-			if requestedAllRelativeColumns {
-				stableSortByPrefix(runtimeColumnPrefix, requestedColumns)
-				stableSortByPrefix(kubernetesColumnPrefix, requestedColumns)
-			}
-
-			if len(requestedColumns) == 0 {
-				log.Warn("no columns requested")
-				requestedColumns = parser.GetDefaultColumns(hiddenColumnTags...)
-			}
 
-			valid, invalid := parser.VerifyColumnNames(requestedColumns)
-
-			for _, c := range invalid {
-				log.Warnf("column %q not found", c)
-			}
+				// sort columns by runtime and kubernetes columns
+				if requestedAllRelativeColumns {
+					stableSortByPrefix(runtimeColumnPrefix, requestedColumns)
+					stableSortByPrefix(kubernetesColumnPrefix, requestedColumns)
+				}
 
-			if err := formatter.SetShowColumns(valid); err != nil {
-				return err
-			}
+				if len(requestedColumns) == 0 {
+					log.Warn("no columns requested")
+					requestedColumns = parser.GetDefaultColumns(hiddenColumnTags...)
+				}
 
-			parser.SetLogCallback(fe.Logf)
+				valid, invalid := parser.VerifyColumnNames(requestedColumns)
 
-			// Wire up callbacks before handing over to runtime depending on the output mode
-			switch outputModeName {
-			default:
-				transformer, ok := gadgetDesc.(gadgets.GadgetOutputFormats)
-				if !ok {
-					return fmt.Errorf("gadget does not provide output formats")
+				for _, c := range invalid {
+					log.Warnf("column %q not found", c)
 				}
-				formats, _ := transformer.OutputFormats()
-				if _, ok := formats[outputModeName]; !ok {
-					return fmt.Errorf("invalid output mode %q", outputModeName)
+
+				if err := formatter.SetShowColumns(valid); err != nil {
+					return err
 				}
 
-				format := formats[outputModeName]
+				parser.SetLogCallback(fe.Logf)
 
-				if format.RequiresCombinedResult {
-					parser.EnableCombiner()
-				}
+				// Wire up callbacks before handing over to runtime depending on the output mode
+				switch outputModeName {
+				default:
+					transformer, ok := gadgetDesc.(gadgets.GadgetOutputFormats)
+					if !ok {
+						return fmt.Errorf("gadget does not provide output formats")
+					}
+					formats, _ := transformer.OutputFormats()
+					if _, ok := formats[outputModeName]; !ok {
+						return fmt.Errorf("invalid output mode %q", outputModeName)
+					}
 
-				transformResult := format.Transform
-				parser.SetEventCallback(func(ev any) {
-					transformed, err := transformResult(ev)
-					if err != nil {
-						fe.Logf(logger.WarnLevel, "could not transform event: %v", err)
-						return
+					format := formats[outputModeName]
+
+					if format.RequiresCombinedResult {
+						parser.EnableCombiner()
 					}
-					fe.Output(string(transformed))
-				})
-			case OutputModeColumns:
-				formatter.SetEventCallback(fe.Output)
 
-				// Enable additional output, if the gadget supports it (e.g. profile/cpu)
-				//  TODO: This can be optimized later on
-				formatter.SetEnableExtraLines(true)
+					output := func(out []byte) {
+						fe.Output(string(out))
+					}
+					if format.Binary {
+						output = fe.OutputRaw
+					}
 
-				parser.SetEventCallback(formatter.EventHandlerFunc())
-				if gType.IsPeriodic() {
-					// In case of periodic outputting gadgets, this is done as full table output, and we need to
-					// clear the screen for every interval, that's why we add fe.Clear here
-					parser.SetEventCallback(formatter.EventHandlerFuncArray(
-						fe.Clear,
-						func() {
+					transformResult := format.Transform
+					parser.SetEventCallback(func(ev any) {
+						transformed, err := transformResult(ev)
+						if err != nil {
+							fe.Logf(logger.WarnLevel, "could not transform event: %v", err)
+							return
+						}
+						if len(transformed) == 0 {
+							return
+						}
+						output(transformed)
+					})
+					if format.Finish != nil {
+						defer func() {
+							out, err := format.Finish()
+							if err != nil {
+								fe.Logf(logger.WarnLevel, "could not finish output: %v", err)
+								return
+							}
+							output(out)
+						}()
+					}
+				case OutputModeColumns:
+					formatter.SetEventCallback(fe.Output)
+
+					// Enable additional output, if the gadget supports it (e.g. profile/cpu)
+					//  TODO: This can be optimized later on
+					formatter.SetEnableExtraLines(true)
+
+					parser.SetEventCallback(formatter.EventHandlerFunc())
+					if gType.IsPeriodic() {
+						// In case of periodic outputting gadgets, this is done as full table output, and we need to
+						// clear the screen for every interval, that's why we add fe.Clear here
+						parser.SetEventCallback(formatter.EventHandlerFuncArray(
+							fe.Clear,
+							func() {
+								fe.Output(formatter.FormatHeader())
+							},
+						))
+
+						// Print first header while we wait for input
+						if fe.IsTerminal() {
+							fe.Clear()
 							fe.Output(formatter.FormatHeader())
-						},
-					))
-
-					// Print first header while we wait for input
-					if fe.IsTerminal() {
-						fe.Clear()
-						fe.Output(formatter.FormatHeader())
+						}
+						break
 					}
-					break
-				}
-				fe.Output(formatter.FormatHeader())
-				parser.SetEventCallback(formatter.EventHandlerFuncArray())
-			case OutputModeJSON:
-				jsonCallback := printEventAsJSONFn(fe)
-				if isRunGadget {
-					jsonCallback = runGadgetDesc.JSONConverter(runGadgetInfo, fe)
+					fe.Output(formatter.FormatHeader())
+					parser.SetEventCallback(formatter.EventHandlerFuncArray())
+				case OutputModeJSON:
+					jsonCallback := printEventAsJSONFn(fe)
+					if isRunGadget {
+						jsonCallback = runGadgetDesc.JSONConverter(runGadgetInfo, fe)
+					}
+					parser.SetEventCallback(jsonCallback)
+				case OutputModeJSONPretty:
+					jsonPrettyCallback := printEventAsJSONPrettyFn(fe)
+					if isRunGadget {
+						jsonPrettyCallback = runGadgetDesc.JSONPrettyConverter(runGadgetInfo, fe)
+					}
+					parser.SetEventCallback(jsonPrettyCallback)
+				case OutputModeYAML:
+					yamlCallback := printEventAsYAMLFn(fe)
+					if isRunGadget {
+						yamlCallback = runGadgetDesc.YAMLConverter(runGadgetInfo, fe)
+					}
+					parser.SetEventCallback(yamlCallback)
 				}
-				parser.SetEventCallback(jsonCallback)
-			case OutputModeJSONPretty:
-				jsonPrettyCallback := printEventAsJSONPrettyFn(fe)
-				if isRunGadget {
-					jsonPrettyCallback = runGadgetDesc.JSONPrettyConverter(runGadgetInfo, fe)
+
+				// The callbacks of these output modes don't retain the events
+				// after printing them, so the ones of run gadgets can be reused
+				switch outputModeName {
+				case OutputModeColumns, OutputModeJSON, OutputModeJSONPretty, OutputModeYAML:
+					if isRunGadget {
+						parser.EnableEventRelease()
+					}
 				}
-				parser.SetEventCallback(jsonPrettyCallback)
-			case OutputModeYAML:
-				yamlCallback := printEventAsYAMLFn(fe)
-				if isRunGadget {
-					yamlCallback = runGadgetDesc.YAMLConverter(runGadgetInfo, fe)
+
+				// Gadgets with parser don't return anything, they provide the
+				// output via the parser
+				_, err = runtime.RunGadget(gadgetCtx)
+				if err != nil {
+					return fmt.Errorf("running gadget: %w", err)
 				}
-				parser.SetEventCallback(yamlCallback)
+
+				return nil
 			}
 
-			// Gadgets with parser don't return anything, they provide the
-			// output via the parser
-			_, err = runtime.RunGadget(gadgetCtx)
-			if err != nil {
-				return fmt.Errorf("running gadget: %w", err)
+			if dev {
+				return runDev(ctx, devDir, runGadget, func() (func(), error) {
+					image, err := devBuilder(devDir)
+					if err != nil {
+						return nil, err
+					}
+					info, err := runtime.GetGadgetInfo(context.TODO(), gadgetDesc, gadgetParams, []string{image})
+					if err != nil {
+						return nil, fmt.Errorf("getting gadget info: %w", err)
+					}
+					newParser, err := runGadgetDesc.CustomParser(info)
+					if err != nil {
+						return nil, fmt.Errorf("calling custom parser: %w", err)
+					}
+					return func() {
+						runGadgetInfo = info
+						parser = newParser
+						gType = info.GadgetType
+					}, nil
+				})
 			}
 
-			return nil
+			return runGadget(ctx)
 		},
 	}
 
 	// Add flags known at this time, others will be added in PreRunE
 
+	cmd.PersistentFlags().StringVar(
+		&preset,
+		presetFlag,
+		"",
+		"Set of flags to use, defined in the gadget metadata or in the presets section of the config file. The flags set in the command line take precedence",
+	)
+
+	if isRunGadget && devBuilder != nil {
+		cmd.PersistentFlags().BoolVar(
+			&dev,
+			devFlag,
+			false,
+			"Build the gadget from the sources in the directory given instead of an image, and rebuild and restart it each time they change",
+		)
+	}
+
 	// Add runtime flags
 	AddFlags(cmd, runtimeParams, skipParams, runtime)
 
@@ -723,6 +842,63 @@ func buildCommandFromGadget(
 	return cmd
 }
 
+// completeFlagValues completes the values of the flags with possible values of
+// commands with DisableFlagParsing
+func completeFlagValues(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var arg, prefix string
+	if name, value, ok := strings.Cut(toComplete, "="); ok && strings.HasPrefix(name, "-") {
+		arg, prefix, toComplete = name, name+"=", value
+	} else if len(args) > 0 {
+		arg = args[len(args)-1]
+	}
+
+	var flag *pflag.Flag
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		flag = cmd.Flag(arg[2:])
+	case strings.HasPrefix(arg, "-") && len(arg) == 2:
+		flag = cmd.LocalFlags().ShorthandLookup(arg[1:])
+		if flag == nil {
+			flag = cmd.InheritedFlags().ShorthandLookup(arg[1:])
+		}
+	}
+	if flag == nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	param, ok := flag.Value.(*Param)
+	if !ok || len(param.PossibleValues) == 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+
+	var completions []string
+	for _, value := range param.PossibleValues {
+		if strings.HasPrefix(value, toComplete) {
+			completions = append(completions, prefix+value)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// clusterColumn is the column filled with the name of the kubeconfig context of
+// the cluster the events come from, see types.K8sMetadata
+const clusterColumn = "k8s.cluster"
+
+// addClusterColumn adds the cluster column, which is hidden by default, to
+// columns when the gadget runs on several clusters
+func addClusterColumn(requested []string, runtimeParams *params.Params, attrs []columns.Attributes) []string {
+	p := runtimeParams.Get(grpcruntime.ParamContexts)
+	if p == nil || len(p.AsStringSlice()) == 0 || containsColumn(requested, clusterColumn) {
+		return requested
+	}
+	for _, attr := range attrs {
+		if strings.EqualFold(attr.Name, clusterColumn) {
+			return append(requested, attr.Name)
+		}
+	}
+	return requested
+}
+
 func containsColumn(columns []string, column string) bool {
 	for _, c := range columns {
 		if strings.EqualFold(c, column) {
@@ -794,6 +970,13 @@ func AddFlags(cmd *cobra.Command, params *params.Params, skipParams []params.Val
 			cmd.MarkPersistentFlagRequired(p.Key)
 		}
 
+		if len(p.PossibleValues) > 0 {
+			possibleValues := p.PossibleValues
+			cmd.RegisterFlagCompletionFunc(p.Key, func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+				return possibleValues, cobra.ShellCompDirectiveNoFileComp
+			})
+		}
+
 		// Allow passing a boolean flag as --foo instead of having to use --foo=true
 		if p.IsBoolFlag() {
 			flag.NoOptDefVal = "true"
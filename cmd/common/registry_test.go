@@ -0,0 +1,140 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
+)
+
+func TestAddClusterColumn(t *testing.T) {
+	t.Parallel()
+
+	attrs := []columns.Attributes{
+		{Name: "k8s.node", Visible: true},
+		{Name: "k8s.cluster"},
+		{Name: "pid", Visible: true},
+	}
+
+	type testDefinition struct {
+		contexts        string
+		requested       []string
+		attrs           []columns.Attributes
+		expectedColumns []string
+	}
+
+	tests := map[string]testDefinition{
+		"single_cluster": {
+			requested:       []string{"k8s.node", "pid"},
+			attrs:           attrs,
+			expectedColumns: []string{"k8s.node", "pid"},
+		},
+		"several_clusters": {
+			contexts:        "prod,staging",
+			requested:       []string{"k8s.node", "pid"},
+			attrs:           attrs,
+			expectedColumns: []string{"k8s.node", "pid", "k8s.cluster"},
+		},
+		"already_requested": {
+			contexts:        "prod,staging",
+			requested:       []string{"k8s.cluster", "pid"},
+			attrs:           attrs,
+			expectedColumns: []string{"k8s.cluster", "pid"},
+		},
+		"no_cluster_column": {
+			contexts:        "prod,staging",
+			requested:       []string{"pid"},
+			attrs:           []columns.Attributes{{Name: "pid", Visible: true}},
+			expectedColumns: []string{"pid"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			runtimeParams := params.ParamDescs{
+				{Key: grpcruntime.ParamContexts},
+			}.ToParams()
+			if test.contexts != "" {
+				require.NoError(t, runtimeParams.Set(grpcruntime.ParamContexts, test.contexts))
+			}
+
+			got := addClusterColumn(test.requested, runtimeParams, test.attrs)
+			require.Equal(t, test.expectedColumns, got)
+		})
+	}
+}
+
+func TestCompleteFlagValues(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		args        []string
+		toComplete  string
+		completions []string
+		directive   cobra.ShellCompDirective
+	}
+
+	tests := map[string]testDefinition{
+		"long": {
+			args:        []string{"--family"},
+			completions: []string{"all", "4", "6"},
+			directive:   cobra.ShellCompDirectiveNoFileComp,
+		},
+		"short_with_prefix": {
+			args:        []string{"-f"},
+			toComplete:  "a",
+			completions: []string{"all"},
+			directive:   cobra.ShellCompDirectiveNoFileComp,
+		},
+		"equal": {
+			toComplete:  "--family=",
+			completions: []string{"--family=all", "--family=4", "--family=6"},
+			directive:   cobra.ShellCompDirectiveNoFileComp,
+		},
+		"without_possible_values": {
+			args:      []string{"--other"},
+			directive: cobra.ShellCompDirectiveDefault,
+		},
+		"not_a_flag": {
+			args:      []string{"foo"},
+			directive: cobra.ShellCompDirectiveDefault,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := &cobra.Command{Use: "test", DisableFlagParsing: true}
+			p := (&params.ParamDesc{Key: "family", Alias: "f", PossibleValues: []string{"all", "4", "6"}}).ToParam()
+			cmd.PersistentFlags().VarPF(&Param{p}, p.Key, p.Alias, "")
+			cmd.PersistentFlags().String("other", "", "")
+
+			completions, directive := completeFlagValues(cmd, test.args, test.toComplete)
+			require.Equal(t, test.completions, completions)
+			require.Equal(t, test.directive, directive)
+		})
+	}
+}
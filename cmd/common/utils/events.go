@@ -38,6 +38,8 @@ func HandleSpecialEvent(e *eventtypes.Event, verbose bool) {
 		fallthrough
 	case eventtypes.WARN:
 		fallthrough
+	case eventtypes.EVENTS_DROPPED:
+		fallthrough
 	case eventtypes.INFO:
 		msgSyntax := ""
 		if e.K8s.Node != "" {
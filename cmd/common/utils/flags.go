@@ -110,7 +110,7 @@ func AddRuntimesSocketPathFlags(command *cobra.Command, config *RuntimesSocketPa
 		&config.Docker,
 		"docker-socketpath", "",
 		runtimeclient.DockerDefaultSocketPath,
-		"Docker Engine API Unix socket path",
+		"Docker Engine API Unix socket path or tcp:// address of a remote engine",
 	)
 
 	command.PersistentFlags().StringVarP(
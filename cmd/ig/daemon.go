@@ -15,18 +15,27 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	gadgetservice "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/tokenauth"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tlsconfig"
 )
 
 func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
@@ -39,7 +48,16 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 
 	var socket string
 	var group string
+	var socketMode string
 	var eventBufferLength uint64
+	var backpressurePolicy string
+	var backpressure gadgetservice.Backpressure
+	var tlsCertFile, tlsKeyFile, tlsClientCAFile string
+	var httpAddress string
+	var authPolicyFile string
+	var instancesDir string
+	var quota gadgetservice.Quota
+	var auditLogFile string
 
 	daemonCmd.PersistentFlags().StringVarP(
 		&group,
@@ -48,6 +66,12 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 		"0",
 		"Group name or id that the unix socket should use (if daemon-socket is set to e.g. unix:///path/to.socket)")
 
+	daemonCmd.PersistentFlags().StringVar(
+		&socketMode,
+		"socket-mode",
+		fmt.Sprintf("%04o", gadgetservice.DefaultSocketMode),
+		"File mode, in octal, of the unix socket (if daemon-socket is set to e.g. unix:///path/to.socket)")
+
 	daemonCmd.PersistentFlags().StringVarP(
 		&socket,
 		"host",
@@ -63,6 +87,90 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 		16384,
 		"The events buffer length. A low value could impact horizontal scaling.")
 
+	daemonCmd.PersistentFlags().StringVar(
+		&backpressurePolicy,
+		"events-backpressure",
+		string(gadgetservice.BackpressureDropNewest),
+		fmt.Sprintf("What to do when the events buffer of a client is full. One of %v", gadgetservice.BackpressurePolicies))
+
+	daemonCmd.PersistentFlags().StringVar(
+		&backpressure.SpillDir,
+		"events-spill-dir",
+		"",
+		"Directory where the events that don't fit in the buffer are written with --events-backpressure=spill. The default directory for temporary files is used if empty")
+
+	daemonCmd.PersistentFlags().Uint64Var(
+		&backpressure.SpillMaxBytes,
+		"events-spill-max-bytes",
+		gadgetservice.DefaultSpillMaxBytes,
+		"Maximum size, in bytes, of the events written to disk for each client with --events-backpressure=spill")
+
+	daemonCmd.PersistentFlags().Uint64Var(
+		&backpressure.MaxMemory,
+		"events-buffer-max-bytes",
+		0,
+		"Maximum size, in bytes, of the events buffered in memory for each client. --events-backpressure applies once it's reached. No limit if 0")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&tlsCertFile,
+		"tls-cert-file",
+		"",
+		"Certificate used to serve gRPC over TLS. It's reloaded when it changes")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&tlsKeyFile,
+		"tls-key-file",
+		"",
+		"Key of the certificate used to serve gRPC over TLS. It's reloaded when it changes")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&tlsClientCAFile,
+		"tls-client-ca-file",
+		"",
+		"CA certificates used to verify the certificates of the clients. If set, clients must present a valid certificate")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&httpAddress,
+		"http-address",
+		"",
+		"Address (e.g. 127.0.0.1:8080) to serve an HTTP+JSON gateway to the daemon API on. It uses the same TLS settings as the gRPC socket. Disabled if empty")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&authPolicyFile,
+		"auth-policy-file",
+		"",
+		"Policy file configuring the tokens clients authenticate with and the gadgets they are allowed to run. Clients aren't authenticated if empty")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&instancesDir,
+		"instances-dir",
+		"",
+		"Directory, e.g. /var/lib/ig/instances, where detached gadget instances are stored to restore them when the daemon restarts. Instances aren't stored if empty")
+
+	daemonCmd.PersistentFlags().IntVar(
+		&quota.MaxInstances,
+		"quota-max-instances",
+		0,
+		"Maximum number of gadgets each client can run at the same time. Clients are identified by --auth-policy-file, otherwise all clients share the quotas. Unlimited if 0")
+
+	daemonCmd.PersistentFlags().Uint64Var(
+		&quota.MaxBufferMemory,
+		"quota-max-buffer-memory",
+		0,
+		"Maximum memory, in bytes, used by the perf and ring buffers of the gadgets of each client. Unlimited if 0")
+
+	daemonCmd.PersistentFlags().Float64Var(
+		&quota.MaxEventRate,
+		"quota-max-event-rate",
+		0,
+		"Maximum number of events per second sent by the gadgets of each client, the others are dropped. Unlimited if 0")
+
+	daemonCmd.PersistentFlags().StringVar(
+		&auditLogFile,
+		"audit-log-file",
+		"",
+		"File to append a JSON record to each time a gadget starts, stops or is denied. Gadgets aren't audited if empty")
+
 	daemonCmd.RunE = func(cmd *cobra.Command, args []string) error {
 		if os.Geteuid() != 0 {
 			return fmt.Errorf("%s must be run as root to be able to run eBPF programs", filepath.Base(os.Args[0]))
@@ -73,6 +181,11 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 			return fmt.Errorf("invalid daemon-socket address: %w", err)
 		}
 
+		mode, err := strconv.ParseUint(socketMode, 8, 32)
+		if err != nil || mode > 0o777 {
+			return fmt.Errorf("invalid socket mode %q: must be an octal value between 0000 and 0777", socketMode)
+		}
+
 		gid := 0
 		if tmpGroup, err := user.LookupGroup(group); err == nil {
 			gid, err = strconv.Atoi(tmpGroup.Gid)
@@ -85,13 +198,113 @@ func newDaemonCommand(runtime runtime.Runtime) *cobra.Command {
 			return fmt.Errorf("group %q not found", group)
 		}
 
+		var serverOptions []grpc.ServerOption
+		var tlsConfig *tls.Config
+		if tlsCertFile != "" || tlsKeyFile != "" || tlsClientCAFile != "" {
+			grpcTLSConfig, err := tlsconfig.NewServerConfig(tlsCertFile, tlsKeyFile, tlsClientCAFile, "h2")
+			if err != nil {
+				return fmt.Errorf("creating TLS config: %w", err)
+			}
+			serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(grpcTLSConfig)))
+
+			// The HTTP gateway only serves HTTP/1.1
+			tlsConfig, err = tlsconfig.NewServerConfig(tlsCertFile, tlsKeyFile, tlsClientCAFile)
+			if err != nil {
+				return fmt.Errorf("creating TLS config: %w", err)
+			}
+			if tlsClientCAFile == "" {
+				log.Warn("--tls-client-ca-file not set, clients won't be authenticated")
+			}
+		} else if socketType == "tcp" {
+			log.Warn("listening on a tcp socket without TLS, connections are neither encrypted nor authenticated")
+		}
+
 		log.Infof("starting Inspektor Gadget daemon at %q", socket)
 		service := gadgetservice.NewService(log.StandardLogger(), eventBufferLength)
+
+		backpressure.Policy = gadgetservice.BackpressurePolicy(backpressurePolicy)
+		if err := service.SetBackpressure(backpressure); err != nil {
+			return err
+		}
+
+		if quota != (gadgetservice.Quota{}) {
+			service.SetQuota(quota)
+		}
+
+		if auditLogFile != "" {
+			sink, err := gadgetservice.NewAuditFileSink(auditLogFile)
+			if err != nil {
+				return err
+			}
+			defer sink.Close()
+			service.SetAuditSink(sink)
+		}
+
+		if instancesDir != "" {
+			if err := service.SetInstancesDir(instancesDir); err != nil {
+				return err
+			}
+		}
+
+		if authPolicyFile != "" {
+			policy, err := tokenauth.LoadPolicy(authPolicyFile)
+			if err != nil {
+				return fmt.Errorf("loading auth policy: %w", err)
+			}
+			authorizer, err := tokenauth.NewAuthorizer(policy)
+			if err != nil {
+				return fmt.Errorf("creating authorizer: %w", err)
+			}
+			service.SetAuthorizer(authorizer)
+
+			// Running gadgets is authorized by the service itself, all the
+			// other calls only require the client to be authenticated
+			serverOptions = append(serverOptions,
+				grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+					if err := authorizer.Authenticate(ctx); err != nil {
+						return nil, err
+					}
+					return handler(ctx, req)
+				}),
+				grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+					if err := authorizer.Authenticate(ss.Context()); err != nil {
+						return err
+					}
+					return handler(srv, ss)
+				}),
+			)
+		} else if socketType == "tcp" || httpAddress != "" {
+			log.Warn("--auth-policy-file not set, any client that can connect is allowed to run any gadget")
+		}
+
+		if httpAddress != "" {
+			listener, err := net.Listen("tcp", httpAddress)
+			if err != nil {
+				return fmt.Errorf("creating HTTP gateway listener: %w", err)
+			}
+			if tlsConfig != nil {
+				listener = tls.NewListener(listener, tlsConfig)
+			} else {
+				log.Warn("serving the HTTP gateway without TLS, connections are neither encrypted nor authenticated")
+			}
+
+			log.Infof("starting HTTP gateway at %q", httpAddress)
+			go func() {
+				server := &http.Server{
+					Handler:           gadgetservice.NewHTTPGateway(service),
+					ReadHeaderTimeout: 10 * time.Second,
+				}
+				if err := server.Serve(listener); err != nil {
+					log.Errorf("serving HTTP gateway: %v", err)
+				}
+			}()
+		}
 		return service.Run(gadgetservice.RunConfig{
 			SocketType: socketType,
 			SocketPath: socketPath,
 			SocketGID:  gid,
-		})
+			SocketMode: os.FileMode(mode),
+		}, serverOptions...)
 	}
 
 	return daemonCmd
@@ -28,6 +28,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common/image"
 	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/ig/containers"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/hardening"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/local"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
@@ -40,6 +41,7 @@ import (
 	// Another blank import for the used operator
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/localmanager"
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/prometheus"
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/redactor"
 )
 
 func main() {
@@ -53,7 +55,17 @@ func main() {
 	}
 	common.AddVerboseFlag(rootCmd)
 
+	common.SetEnvPrefix("IG")
+	if err := common.LoadConfig(common.DefaultConfigFiles()...); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	host.AddFlags(rootCmd)
+	hardening.AddFlags(rootCmd)
+	common.AddImagePolicyFlags(rootCmd)
+	common.AddGadgetPolicyFlags(rootCmd)
+	common.AddRedactionFlags(rootCmd)
 
 	rootCmd.AddCommand(
 		containers.NewListContainersCmd(),
@@ -69,15 +81,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := common.ApplyImagePolicyFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := common.ApplyGadgetPolicyFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := common.ApplyRedactionFlags(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Gadgets are built and run on the same host
+	common.EnableDevMode(image.BuildDev)
+
 	runtime := local.New()
 	hiddenColumnTags := []string{"kubernetes"}
 	common.AddCommandsFromRegistry(rootCmd, runtime, hiddenColumnTags)
 
 	rootCmd.AddCommand(newDaemonCommand(runtime))
 	rootCmd.AddCommand(image.NewImageCmd())
+	rootCmd.AddCommand(newTestCommand())
 	rootCmd.AddCommand(common.NewLoginCmd())
 	rootCmd.AddCommand(common.NewLogoutCmd())
 
+	if err := common.ApplyConfig(rootCmd, os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,121 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/testing/workload"
+)
+
+func newTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Helpers to test gadgets",
+	}
+
+	cmd.AddCommand(newTestGenerateCommand())
+
+	return commonutils.MarkExperimental(cmd)
+}
+
+func newTestGenerateCommand() *cobra.Command {
+	var kinds string
+	var count int
+	var address string
+	var port int
+	var dnsServer string
+	var dir string
+	var interval time.Duration
+	var output string
+	var run bool
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate a deterministic workload to test gadgets",
+		Long: `Generate a deterministic workload to test gadgets: execs, file opens,
+connects and DNS queries. The same steps are generated each time, so the events
+of the gadgets can be compared across kernels.
+
+The workload is printed as a shell script working with busybox, to run it in a
+container:
+
+  $ docker run --rm busybox sh -c "$(ig test generate --kinds exec,dns)"
+
+Use --run to run it right away instead.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			parsedKinds, err := workload.ParseKinds(kinds)
+			if err != nil {
+				return err
+			}
+			w := workload.New(
+				workload.WithKinds(parsedKinds...),
+				workload.WithCount(count),
+				workload.WithAddress(address, port),
+				workload.WithDNSServer(dnsServer),
+				workload.WithDir(dir),
+				workload.WithInterval(interval),
+			)
+			if err := w.Validate(); err != nil {
+				return err
+			}
+
+			if run {
+				c := exec.CommandContext(cmd.Context(), "/bin/sh", "-c", w.Script())
+				c.Stdout = os.Stdout
+				c.Stderr = os.Stderr
+				if err := c.Run(); err != nil {
+					return fmt.Errorf("running workload: %w", err)
+				}
+				return nil
+			}
+
+			switch output {
+			case "script":
+				fmt.Println(w.Script())
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(w.Steps()); err != nil {
+					return fmt.Errorf("encoding steps: %w", err)
+				}
+			default:
+				return fmt.Errorf("invalid output %q, expected script or json", output)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&kinds, "kinds", "exec,open,connect,dns", "Comma-separated kinds of steps to generate: exec, open, connect or dns")
+	cmd.Flags().IntVar(&count, "count", 1, "Number of times the steps of each kind are repeated")
+	cmd.Flags().StringVar(&address, "address", "127.0.0.1", "Address to connect to")
+	cmd.Flags().IntVar(&port, "port", 8080, "Port of the first connection, the next ones use the following ports")
+	cmd.Flags().StringVar(&dnsServer, "dns-server", "127.0.0.1", "Server to send the DNS queries to")
+	cmd.Flags().StringVar(&dir, "dir", "/tmp", "Directory where the files are created")
+	cmd.Flags().DurationVar(&interval, "interval", 100*time.Millisecond, "Time to wait between steps")
+	cmd.Flags().StringVarP(&output, "output", "o", "script", "Output format: script, or json to print the steps with the events they generate")
+	cmd.Flags().BoolVar(&run, "run", false, "Run the workload instead of printing it")
+
+	return cmd
+}
@@ -44,7 +44,8 @@ type CommonFlags struct {
 	Runtimes string
 
 	// Host, when set to true, specifies to include all events both from
-	// the host and from containers.
+	// the host and from containers. The host can also be selected alone
+	// through the "host" pseudo-container name.
 	Host bool
 
 	// RuntimeConfigs contains the list of the container runtimes to be used
@@ -54,8 +55,15 @@ type CommonFlags struct {
 	// Number of seconds that the gadget will run for
 	Timeout int
 
-	// ContainerdNamespace is the namespace used by containerd
+	// ContainerdNamespace is the comma-separated list of containerd namespaces
+	// to use
 	ContainerdNamespace string
+
+	// DockerTLSCAFile, DockerTLSCertFile and DockerTLSKeyFile are used to
+	// connect to a remote Docker engine over TLS
+	DockerTLSCAFile   string
+	DockerTLSCertFile string
+	DockerTLSKeyFile  string
 }
 
 func AddCommonFlags(command *cobra.Command, commonFlags *CommonFlags) {
@@ -65,27 +73,51 @@ func AddCommonFlags(command *cobra.Command, commonFlags *CommonFlags) {
 
 	partsLoop:
 		for _, p := range parts {
-			runtimeName := types.String2RuntimeName(strings.TrimSpace(p))
+			runtimeName, explicitSocketPath := containerutils.ParseRuntimeSpec(p)
 			socketPath := ""
-			namespace := ""
+			var extra *containerutilsTypes.ExtraConfig
 
 			switch runtimeName {
 			case types.RuntimeNameDocker:
 				socketPath = commonFlags.RuntimesSocketPathConfig.Docker
+				if commonFlags.DockerTLSCAFile != "" {
+					extra = &containerutilsTypes.ExtraConfig{
+						TLSCAFile:   commonFlags.DockerTLSCAFile,
+						TLSCertFile: commonFlags.DockerTLSCertFile,
+						TLSKeyFile:  commonFlags.DockerTLSKeyFile,
+					}
+				}
 			case types.RuntimeNameContainerd:
 				socketPath = commonFlags.RuntimesSocketPathConfig.Containerd
-				namespace = commonFlags.ContainerdNamespace
+				if commonFlags.ContainerdNamespace != "" {
+					extra = &containerutilsTypes.ExtraConfig{
+						Namespace: commonFlags.ContainerdNamespace,
+					}
+				}
 			case types.RuntimeNameCrio:
 				socketPath = commonFlags.RuntimesSocketPathConfig.Crio
 			case types.RuntimeNamePodman:
 				socketPath = commonFlags.RuntimesSocketPathConfig.Podman
+			case types.RuntimeNameLXC, types.RuntimeNameSystemdNspawn:
+				// Found without socket, see containerutils.AvailableRuntimes
+				if explicitSocketPath != "" {
+					return commonutils.WrapInErrInvalidArg("--runtime / -r",
+						fmt.Errorf("runtime %q doesn't use a socket", runtimeName))
+				}
 			default:
 				return commonutils.WrapInErrInvalidArg("--runtime / -r",
 					fmt.Errorf("runtime %q is not supported", p))
 			}
 
+			// A socket path given along with the runtime takes precedence
+			if explicitSocketPath != "" {
+				socketPath = explicitSocketPath
+			}
+
 			for _, r := range commonFlags.RuntimeConfigs {
-				if r.Name == runtimeName {
+				// The same runtime listed with another socket path is a fallback, see
+				// containercollection.WithMultipleContainerRuntimesEnrichment()
+				if r.Name == runtimeName && r.SocketPath == socketPath {
 					log.Infof("Ignoring duplicated runtime %q from %q",
 						runtimeName, commonFlags.Runtimes)
 					continue partsLoop
@@ -95,12 +127,7 @@ func AddCommonFlags(command *cobra.Command, commonFlags *CommonFlags) {
 			r := &containerutilsTypes.RuntimeConfig{
 				Name:       runtimeName,
 				SocketPath: socketPath,
-			}
-
-			if namespace != "" {
-				r.Extra = &containerutilsTypes.ExtraConfig{
-					Namespace: namespace,
-				}
+				Extra:      extra,
 			}
 
 			commonFlags.RuntimeConfigs = append(commonFlags.RuntimeConfigs, r)
@@ -132,14 +159,14 @@ func AddCommonFlags(command *cobra.Command, commonFlags *CommonFlags) {
 		"host",
 		"",
 		false,
-		"Show data from both the host and containers",
+		"Show data from both the host and containers. Use --containername host to show data only from the host",
 	)
 
 	command.PersistentFlags().StringVarP(
 		&commonFlags.Runtimes,
 		"runtimes", "r",
-		strings.Join(containerutils.AvailableRuntimes, ","),
-		fmt.Sprintf("Container runtimes to be used separated by comma. Supported values are: %s",
+		strings.Join(containerutils.DefaultRuntimes, ","),
+		fmt.Sprintf("Container runtimes to be used separated by comma, in order of priority. Each runtime can be followed by its socket path, e.g. containerd=/path/to/containerd.sock. Supported values are: %s",
 			strings.Join(containerutils.AvailableRuntimes, ", ")),
 	)
 
@@ -154,7 +181,26 @@ func AddCommonFlags(command *cobra.Command, commonFlags *CommonFlags) {
 		&commonFlags.ContainerdNamespace,
 		"containerd-namespace",
 		constants.K8sContainerdNamespace,
-		"Namespace used by containerd",
+		"Comma-separated list of containerd namespaces to use (e.g. k8s.io,default,moby)",
+	)
+
+	command.PersistentFlags().StringVar(
+		&commonFlags.DockerTLSCAFile,
+		"docker-tls-ca-file",
+		"",
+		"CA certificate used to verify a remote Docker engine listening on a tcp:// --docker-socketpath. TLS isn't used if not set",
+	)
+	command.PersistentFlags().StringVar(
+		&commonFlags.DockerTLSCertFile,
+		"docker-tls-cert-file",
+		"",
+		"Client certificate used to authenticate against a remote Docker engine",
+	)
+	command.PersistentFlags().StringVar(
+		&commonFlags.DockerTLSKeyFile,
+		"docker-tls-key-file",
+		"",
+		"Key of the client certificate used to authenticate against a remote Docker engine",
 	)
 }
 
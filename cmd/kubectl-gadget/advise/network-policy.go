@@ -42,8 +42,9 @@ var networkPolicyReportCmd = &cobra.Command{
 }
 
 var (
-	inputFileName  string
-	outputFileName string
+	inputFileName    string
+	dnsInputFileName string
+	outputFileName   string
 )
 
 func newNetworkPolicyCmd(gadgetNamespace string) *cobra.Command {
@@ -58,6 +59,7 @@ func newNetworkPolicyCmd(gadgetNamespace string) *cobra.Command {
 
 	networkPolicyCmd.AddCommand(networkPolicyReportCmd)
 	networkPolicyReportCmd.PersistentFlags().StringVarP(&inputFileName, "input", "", "", "File with recorded network activity")
+	networkPolicyReportCmd.PersistentFlags().StringVarP(&dnsInputFileName, "dns-input", "", "", "File with recorded DNS activity (trace dns -o json), used to generate FQDN-based egress rules as CiliumNetworkPolicies")
 	networkPolicyReportCmd.PersistentFlags().StringVarP(&outputFileName, "output", "", "-", "File name output")
 
 	return networkPolicyCmd
@@ -131,6 +133,12 @@ func runNetworkPolicyReport(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if dnsInputFileName != "" {
+		if err := adv.LoadDNSFile(dnsInputFileName); err != nil {
+			return fmt.Errorf("loading DNS activity: %w", err)
+		}
+	}
+
 	adv.GeneratePolicies()
 
 	w, closure, err := newWriter(outputFileName)
@@ -27,6 +27,7 @@ import (
 	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/kubectl-gadget/utils"
 	gadgetv1alpha1 "github.com/inspektor-gadget/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	seccomptracer "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/advise/seccomp/tracer"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -56,6 +57,7 @@ var seccompAdvisorListCmd = &cobra.Command{
 var (
 	outputMode    string
 	profilePrefix string
+	aggregate     string
 )
 
 func newSeccompProfileCmd(gadgetNamespace string) *cobra.Command {
@@ -70,6 +72,9 @@ func newSeccompProfileCmd(gadgetNamespace string) *cobra.Command {
 	seccompAdvisorStartCmd.PersistentFlags().StringVar(&profilePrefix,
 		"profile-prefix", "",
 		"Name prefix of the seccomp profile to be created when using --output-mode=seccomp-profile.\nNamespace can be specified by using namespace/profile-prefix.")
+	seccompAdvisorStartCmd.PersistentFlags().StringVar(&aggregate,
+		"aggregate", seccomptracer.AggregatePod,
+		"How the syscalls are aggregated when using --output-mode=seccomp-profile, possible values are pod and workload.\nWith workload, the syscalls of all the pods of a workload are merged into a single profile per container, which is updated by later traces.")
 
 	seccompProfileCmd.AddCommand(seccompAdvisorStopCmd)
 	seccompProfileCmd.AddCommand(seccompAdvisorListCmd)
@@ -91,7 +96,8 @@ func outputModeToTraceOutputMode(outputMode string) (gadgetv1alpha1.TraceOutputM
 // runSeccompAdvisorStart starts monitoring of syscalls for the given
 // parameters.
 func runSeccompAdvisorStart(cmd *cobra.Command, args []string) error {
-	if params.Podname == "" {
+	// The pods of a workload are selected by labels instead
+	if params.Podname == "" && aggregate != seccomptracer.AggregateWorkload {
 		return commonutils.WrapInErrMissingArgs("--podname")
 	}
 
@@ -104,6 +110,16 @@ func runSeccompAdvisorStart(cmd *cobra.Command, args []string) error {
 		return errors.New("you can only use --profile-prefix with --output seccomp-profile")
 	}
 
+	switch aggregate {
+	case seccomptracer.AggregatePod:
+	case seccomptracer.AggregateWorkload:
+		if traceOutputMode != gadgetv1alpha1.TraceOutputModeExternalResource {
+			return errors.New("you can only use --aggregate workload with --output-mode seccomp-profile")
+		}
+	default:
+		return fmt.Errorf("%q is not an accepted value for --aggregate, possible values are: pod (default) and workload", aggregate)
+	}
+
 	config := &utils.TraceConfig{
 		GadgetName:        "seccomp",
 		GadgetNamespace:   gadgetNamespace,
@@ -112,6 +128,9 @@ func runSeccompAdvisorStart(cmd *cobra.Command, args []string) error {
 		TraceOutput:       profilePrefix,
 		TraceInitialState: gadgetv1alpha1.TraceStateStarted,
 		CommonFlags:       &params,
+		Parameters: map[string]string{
+			seccomptracer.AggregateParam: aggregate,
+		},
 	}
 
 	traceID, err := utils.CreateTrace(config)
@@ -19,7 +19,9 @@ import (
 	_ "embed"
 	"encoding/json"
 	"fmt"
+	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -30,6 +32,7 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	apiextv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
@@ -53,7 +56,10 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common"
 	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/kubectl-gadget/utils"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgetpolicy"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/resources"
 	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
@@ -78,6 +84,7 @@ var (
 	livenessProbe       bool
 	deployTimeout       time.Duration
 	fallbackPodInformer bool
+	rbacAuthorization   bool
 	legacyHostPID       bool
 	printOnly           bool
 	quiet               bool
@@ -88,10 +95,24 @@ var (
 	experimentalVar     bool
 	skipSELinuxOpts     bool
 	eventBufferLength   uint64
+	eventsBackpressure  string
+	eventBufferMaxBytes uint64
+	tolerations         []string
+	resourceRequests    string
+	resourceLimits      string
+	nodeAffinityFile    string
+	allowedImageDigests []string
+	trustedImageKeys    []string
+	gadgetPolicyFile    string
+	redactionConfigFile string
+	priorityClassName   string
 )
 
 var supportedHooks = []string{"auto", "crio", "podinformer", "nri", "fanotify", "fanotify+ebpf"}
 
+// Keep this aligned with gadgetservice.BackpressurePolicies
+var supportedBackpressures = []string{"drop-newest", "drop-oldest", "block", "spill"}
+
 func init() {
 	commonutils.AddRuntimesSocketPathFlags(deployCmd, &runtimesConfig)
 
@@ -120,6 +141,11 @@ func init() {
 		"fallback-podinformer", "",
 		true,
 		"use pod informer as a fallback for the main hook")
+	deployCmd.PersistentFlags().BoolVarP(
+		&rbacAuthorization,
+		"rbac-authorization", "",
+		false,
+		"authorize the requests to run gadgets using the Kubernetes RBAC permissions of the user. It requires authenticating to the API server with a bearer token")
 	deployCmd.PersistentFlags().BoolVarP(
 		&legacyHostPID,
 		"legacy-host-pid", "",
@@ -171,6 +197,61 @@ func init() {
 		"events-buffer-length", "",
 		16384,
 		"The events buffer length. A low value could impact horizontal scaling.")
+	deployCmd.PersistentFlags().StringVarP(
+		&eventsBackpressure,
+		"events-backpressure", "",
+		"drop-newest",
+		fmt.Sprintf("what to do when the events buffer of a client is full (%s)", strings.Join(supportedBackpressures, ", ")))
+	deployCmd.PersistentFlags().Uint64VarP(
+		&eventBufferMaxBytes,
+		"events-buffer-max-bytes", "",
+		0,
+		"maximum size, in bytes, of the events buffered in memory for each client. No limit if 0")
+	deployCmd.PersistentFlags().StringSliceVarP(
+		&tolerations,
+		"tolerations", "",
+		nil,
+		"tolerations for the gadget pod, in the form [key[=value]][:effect] (e.g. gpu=true:NoSchedule). They replace the default ones that tolerate all taints")
+	deployCmd.PersistentFlags().StringSliceVarP(
+		&allowedImageDigests,
+		"allowed-image-digests", "",
+		nil,
+		"only run the gadget images with these digests (e.g. sha256:...) or signed by one of --trusted-image-keys")
+	deployCmd.PersistentFlags().StringSliceVarP(
+		&trustedImageKeys,
+		"trusted-image-keys", "",
+		nil,
+		"local files with PEM encoded public keys (e.g. cosign.pub). Only run the gadget images with a cosign signature by one of them or listed in --allowed-image-digests")
+	deployCmd.PersistentFlags().StringVarP(
+		&gadgetPolicyFile,
+		"gadget-policy", "",
+		"",
+		"local YAML file with the policy denying gadgets by registry, digest, program types and attach targets, and limiting their resources")
+	deployCmd.PersistentFlags().StringVarP(
+		&redactionConfigFile,
+		"redaction-config", "",
+		"",
+		"local YAML file with the fields and patterns of the values to mask in the events of all the gadgets before they leave the nodes")
+	deployCmd.PersistentFlags().StringVarP(
+		&resourceRequests,
+		"requests", "",
+		"",
+		"resource requests for the gadget container (e.g. cpu=100m,memory=256Mi)")
+	deployCmd.PersistentFlags().StringVarP(
+		&resourceLimits,
+		"limits", "",
+		"",
+		"resource limits for the gadget container (e.g. cpu=500m,memory=1Gi)")
+	deployCmd.PersistentFlags().StringVarP(
+		&nodeAffinityFile,
+		"node-affinity", "",
+		"",
+		"path to a YAML or JSON file containing the node affinity for the Inspektor Gadget DaemonSet. It can't be used together with --node-selector")
+	deployCmd.PersistentFlags().StringVarP(
+		&priorityClassName,
+		"priority-class-name", "",
+		"",
+		"priority class name for the gadget pod")
 	rootCmd.AddCommand(deployCmd)
 }
 
@@ -348,6 +429,75 @@ func createAffinity(client *kubernetes.Clientset) (*v1.Affinity, error) {
 	return affinity, nil
 }
 
+// parseTolerations parses tolerations in the form [key[=value]][:effect]. A
+// toleration without value uses the Exists operator.
+func parseTolerations(specs []string) ([]v1.Toleration, error) {
+	ret := make([]v1.Toleration, 0, len(specs))
+	for _, spec := range specs {
+		toleration := v1.Toleration{
+			Operator: v1.TolerationOpExists,
+		}
+
+		keyValue, effect, hasEffect := strings.Cut(spec, ":")
+		if hasEffect {
+			switch v1.TaintEffect(effect) {
+			case v1.TaintEffectNoSchedule, v1.TaintEffectPreferNoSchedule, v1.TaintEffectNoExecute:
+				toleration.Effect = v1.TaintEffect(effect)
+			default:
+				return nil, fmt.Errorf("invalid effect %q in toleration %q", effect, spec)
+			}
+		}
+
+		key, value, hasValue := strings.Cut(keyValue, "=")
+		if hasValue {
+			if key == "" {
+				return nil, fmt.Errorf("toleration %q has a value but no key", spec)
+			}
+			toleration.Operator = v1.TolerationOpEqual
+			toleration.Value = value
+		}
+		toleration.Key = key
+
+		if toleration.Key == "" && toleration.Effect == "" {
+			return nil, fmt.Errorf("invalid toleration %q", spec)
+		}
+
+		ret = append(ret, toleration)
+	}
+	return ret, nil
+}
+
+// parseResourceList parses a list of resources in the form
+// name=quantity[,name=quantity...]
+func parseResourceList(spec string) (v1.ResourceList, error) {
+	ret := v1.ResourceList{}
+	for _, pair := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid resource %q: expected name=quantity", pair)
+		}
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsing quantity for resource %q: %w", name, err)
+		}
+		ret[v1.ResourceName(name)] = quantity
+	}
+	return ret, nil
+}
+
+// readNodeAffinity reads the node affinity from the given YAML or JSON file.
+func readNodeAffinity(path string) (*v1.NodeAffinity, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading node affinity: %w", err)
+	}
+	nodeAffinity := &v1.NodeAffinity{}
+	if err := yaml.UnmarshalStrict(content, nodeAffinity); err != nil {
+		return nil, fmt.Errorf("parsing node affinity: %w", err)
+	}
+	return nodeAffinity, nil
+}
+
 func runDeploy(cmd *cobra.Command, args []string) error {
 	gadgetNamespace := runtimeGlobalParams.Get(grpcruntime.ParamGadgetNamespace).AsString()
 	if !printOnly {
@@ -374,10 +524,49 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid argument %q for --hook-mode=[%s]", hookMode, strings.Join(supportedHooks, ","))
 	}
 
+	if !slices.Contains(supportedBackpressures, eventsBackpressure) {
+		return fmt.Errorf("invalid argument %q for --events-backpressure=[%s]", eventsBackpressure, strings.Join(supportedBackpressures, ","))
+	}
+
+	imageKeys, err := common.ReadPublicKeyFiles(trustedImageKeys)
+	if err != nil {
+		return err
+	}
+	// Validate the policy before deploying, the gadget pod would crash otherwise
+	if _, err := oci.NewImagePolicy(allowedImageDigests, imageKeys); err != nil {
+		return fmt.Errorf("invalid image policy: %w", err)
+	}
+
+	var gadgetPolicy []byte
+	if gadgetPolicyFile != "" {
+		gadgetPolicy, err = os.ReadFile(gadgetPolicyFile)
+		if err != nil {
+			return fmt.Errorf("reading gadget policy: %w", err)
+		}
+		if _, err := gadgetpolicy.ParsePolicy(gadgetPolicy); err != nil {
+			return fmt.Errorf("invalid gadget policy: %w", err)
+		}
+	}
+
+	var redactionConfig []byte
+	if redactionConfigFile != "" {
+		redactionConfig, err = os.ReadFile(redactionConfigFile)
+		if err != nil {
+			return fmt.Errorf("reading redaction config: %w", err)
+		}
+		if _, err := redaction.ParseConfig(redactionConfig); err != nil {
+			return fmt.Errorf("invalid redaction config: %w", err)
+		}
+	}
+
 	if quiet && debug {
 		return fmt.Errorf("it's not possible to use --quiet and --debug together")
 	}
 
+	if nodeSelector != "" && nodeAffinityFile != "" {
+		return fmt.Errorf("it's not possible to use --node-selector and --node-affinity together")
+	}
+
 	objects, err := parseK8sYaml(resources.GadgetDeployment)
 	if err != nil {
 		return err
@@ -390,6 +579,20 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 
 	objects = append(objects, traceObjects...)
 
+	gadgetInstanceObjects, err := parseK8sYaml(resources.GadgetInstancesCustomResource)
+	if err != nil {
+		return err
+	}
+
+	objects = append(objects, gadgetInstanceObjects...)
+
+	gadgetCatalogObjects, err := parseK8sYaml(resources.GadgetCatalogsCustomResource)
+	if err != nil {
+		return err
+	}
+
+	objects = append(objects, gadgetCatalogObjects...)
+
 	config, err := utils.KubernetesConfigFlags.ToRESTConfig()
 	if err != nil {
 		return fmt.Errorf("creating RESTConfig: %w", err)
@@ -468,6 +671,8 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 					gadgetContainer.Env[i].Value = hookMode
 				case "INSPEKTOR_GADGET_OPTION_FALLBACK_POD_INFORMER":
 					gadgetContainer.Env[i].Value = strconv.FormatBool(fallbackPodInformer)
+				case "INSPEKTOR_GADGET_OPTION_RBAC_AUTHORIZATION":
+					gadgetContainer.Env[i].Value = strconv.FormatBool(rbacAuthorization)
 				case utils.GadgetEnvironmentContainerdSocketpath:
 					gadgetContainer.Env[i].Value = runtimesConfig.Containerd
 				case utils.GadgetEnvironmentCRIOSocketpath:
@@ -481,6 +686,18 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 					gadgetContainer.Env[i].Value = strconv.FormatBool(value)
 				case "EVENTS_BUFFER_LENGTH":
 					gadgetContainer.Env[i].Value = strconv.FormatUint(eventBufferLength, 10)
+				case "EVENTS_BACKPRESSURE":
+					gadgetContainer.Env[i].Value = eventsBackpressure
+				case "EVENTS_BUFFER_MAX_BYTES":
+					gadgetContainer.Env[i].Value = strconv.FormatUint(eventBufferMaxBytes, 10)
+				case "INSPEKTOR_GADGET_OPTION_ALLOWED_IMAGE_DIGESTS":
+					gadgetContainer.Env[i].Value = strings.Join(allowedImageDigests, ",")
+				case "INSPEKTOR_GADGET_OPTION_TRUSTED_IMAGE_KEYS":
+					gadgetContainer.Env[i].Value = string(imageKeys)
+				case "INSPEKTOR_GADGET_OPTION_GADGET_POLICY":
+					gadgetContainer.Env[i].Value = string(gadgetPolicy)
+				case "INSPEKTOR_GADGET_OPTION_REDACTION_CONFIG":
+					gadgetContainer.Env[i].Value = string(redactionConfig)
 				}
 			}
 
@@ -492,6 +709,42 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 				daemonSet.Spec.Template.Spec.Affinity = affinity
 			}
 
+			if nodeAffinityFile != "" {
+				nodeAffinity, err := readNodeAffinity(nodeAffinityFile)
+				if err != nil {
+					return err
+				}
+				daemonSet.Spec.Template.Spec.Affinity = &v1.Affinity{
+					NodeAffinity: nodeAffinity,
+				}
+			}
+
+			if len(tolerations) > 0 {
+				podTolerations, err := parseTolerations(tolerations)
+				if err != nil {
+					return fmt.Errorf("parsing tolerations: %w", err)
+				}
+				daemonSet.Spec.Template.Spec.Tolerations = podTolerations
+			}
+
+			if resourceRequests != "" {
+				requests, err := parseResourceList(resourceRequests)
+				if err != nil {
+					return fmt.Errorf("parsing resource requests: %w", err)
+				}
+				gadgetContainer.Resources.Requests = requests
+			}
+
+			if resourceLimits != "" {
+				limits, err := parseResourceList(resourceLimits)
+				if err != nil {
+					return fmt.Errorf("parsing resource limits: %w", err)
+				}
+				gadgetContainer.Resources.Limits = limits
+			}
+
+			daemonSet.Spec.Template.Spec.PriorityClassName = priorityClassName
+
 			// skip SELinux options if the user explicitly requests it
 			if legacyHostPID || skipSELinuxOpts {
 				gadgetContainer.SecurityContext.SELinuxOptions = nil
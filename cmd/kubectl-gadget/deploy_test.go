@@ -16,8 +16,12 @@ package main
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
 	"github.com/inspektor-gadget/inspektor-gadget/cmd/common"
 	grpcruntime "github.com/inspektor-gadget/inspektor-gadget/pkg/runtime/grpc"
 )
@@ -37,3 +41,44 @@ func TestPrintOnly(t *testing.T) {
 		t.Fatalf("Error while running command: %s", stdErr.String())
 	}
 }
+
+func TestParseTolerations(t *testing.T) {
+	tolerations, err := parseTolerations([]string{"gpu=true:NoSchedule", "dedicated", ":NoExecute"})
+	if err != nil {
+		t.Fatalf("parsing tolerations: %s", err)
+	}
+	expected := []v1.Toleration{
+		{Key: "gpu", Operator: v1.TolerationOpEqual, Value: "true", Effect: v1.TaintEffectNoSchedule},
+		{Key: "dedicated", Operator: v1.TolerationOpExists},
+		{Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+	}
+	if !reflect.DeepEqual(tolerations, expected) {
+		t.Fatalf("expected %v, got %v", expected, tolerations)
+	}
+
+	for _, invalid := range []string{"", "key:Invalid", "=value"} {
+		if _, err := parseTolerations([]string{invalid}); err == nil {
+			t.Fatalf("expected error for toleration %q", invalid)
+		}
+	}
+}
+
+func TestParseResourceList(t *testing.T) {
+	resources, err := parseResourceList("cpu=100m,memory=256Mi")
+	if err != nil {
+		t.Fatalf("parsing resources: %s", err)
+	}
+	expected := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("100m"),
+		v1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+	if !reflect.DeepEqual(resources, expected) {
+		t.Fatalf("expected %v, got %v", expected, resources)
+	}
+
+	for _, invalid := range []string{"cpu", "cpu=abc"} {
+		if _, err := parseResourceList(invalid); err == nil {
+			t.Fatalf("expected error for resources %q", invalid)
+		}
+	}
+}
@@ -92,6 +92,7 @@ func main() {
 		log.Fatalf("Creating RESTConfig: %s", err)
 	}
 	grpcRuntime.SetRestConfig(config)
+	grpcRuntime.SetRestConfigGetter(utils.RESTConfigForContext)
 
 	// evaluate flags early for runtimeGlobalParams; this will make
 	// sure that all flags relevant for the grpc connection are ready
@@ -136,15 +136,22 @@ again:
 		}
 	}
 
-	// 2. remove crd
-	fmt.Println("Removing CRD...")
-	err = crdClient.ApiextensionsV1().CustomResourceDefinitions().Delete(
-		context.TODO(), "traces.gadget.kinvolk.io", metav1.DeleteOptions{},
-	)
-	if err != nil && !errors.IsNotFound(err) {
-		errs = append(
-			errs, fmt.Sprintf("failed to remove \"traces.gadget.kinvolk.io\" CRD: %s", err),
+	// 2. remove crds. Gadget instances don't have finalizers, so they are
+	// removed together with their CRD.
+	fmt.Println("Removing CRDs...")
+	for _, crd := range []string{
+		"traces.gadget.kinvolk.io",
+		"gadgetinstances.gadget.kinvolk.io",
+		"gadgetcatalogs.gadget.kinvolk.io",
+	} {
+		err = crdClient.ApiextensionsV1().CustomResourceDefinitions().Delete(
+			context.TODO(), crd, metav1.DeleteOptions{},
 		)
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(
+				errs, fmt.Sprintf("failed to remove %q CRD: %s", crd, err),
+			)
+		}
 	}
 
 	// 3. gadget cluster role binding
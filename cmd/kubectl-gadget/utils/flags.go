@@ -22,6 +22,7 @@ import (
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
 
 	commonutils "github.com/inspektor-gadget/inspektor-gadget/cmd/common/utils"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
@@ -33,6 +34,16 @@ func FlagInit(rootCmd *cobra.Command) {
 	KubernetesConfigFlags.AddFlags(rootCmd.PersistentFlags())
 }
 
+// RESTConfigForContext returns the configuration to connect to the cluster of
+// the given kubeconfig context. The kubeconfig file given by the user, if any,
+// is used.
+func RESTConfigForContext(kubeContext string) (*rest.Config, error) {
+	flags := genericclioptions.NewConfigFlags(false)
+	flags.KubeConfig = KubernetesConfigFlags.KubeConfig
+	flags.Context = &kubeContext
+	return flags.ToRESTConfig()
+}
+
 // CommonFlags contains CLI flags common to several gadgets
 type CommonFlags struct {
 	// OutputConfig describes the way output should be printed
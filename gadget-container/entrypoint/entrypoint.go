@@ -316,6 +316,10 @@ func main() {
 		fmt.Sprintf("-fallback-podinformer=%s", os.Getenv("INSPEKTOR_GADGET_OPTION_FALLBACK_POD_INFORMER")),
 	}
 
+	if rbacAuthorization := os.Getenv("INSPEKTOR_GADGET_OPTION_RBAC_AUTHORIZATION"); rbacAuthorization != "" {
+		args = append(args, fmt.Sprintf("-rbac-authorization=%s", rbacAuthorization))
+	}
+
 	err = syscall.Exec("/bin/gadgettracermanager", args, os.Environ())
 	if err != nil {
 		log.Fatalf("exec'ing gadgettracermanager: %v", err)
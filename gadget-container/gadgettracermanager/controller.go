@@ -37,7 +37,7 @@ import (
 	//+kubebuilder:scaffold:imports
 )
 
-func startController(node string, tracerManager *gadgettracermanager.GadgetTracerManager) {
+func startController(node string, tracerManager *gadgettracermanager.GadgetTracerManager, runner controllers.GadgetRunner) {
 	scheme := runtime.NewScheme()
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
@@ -76,6 +76,22 @@ func startController(node string, tracerManager *gadgettracermanager.GadgetTrace
 		log.Errorf("unable to create trace controller: %s", err)
 		os.Exit(1)
 	}
+	if err = (&controllers.GadgetInstanceReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+		Node:   node,
+		Runner: runner,
+	}).SetupWithManager(mgr); err != nil {
+		log.Errorf("unable to create gadget instance controller: %s", err)
+		os.Exit(1)
+	}
+	if err = (&controllers.GadgetCatalogReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		log.Errorf("unable to create gadget catalog controller: %s", err)
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -0,0 +1,94 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+)
+
+// leaseName is the name of the lease used to elect the gadget pod that runs
+// cluster-scoped gadgets
+const leaseName = "gadget-leader"
+
+// startLeaderElection starts taking part in the election of the gadget pod
+// that runs cluster-scoped gadgets until ctx is done. identity must be unique
+// among the gadget pods, the node name is used for it.
+func startLeaderElection(ctx context.Context, identity, namespace string) (*leaderelection.LeaderElector, error) {
+	clientset, err := k8sutil.NewClientset("")
+	if err != nil {
+		return nil, fmt.Errorf("creating new k8s clientset: %w", err)
+	}
+	return startLeaderElectionWithClientset(ctx, clientset, identity, namespace)
+}
+
+func startLeaderElectionWithClientset(
+	ctx context.Context,
+	clientset kubernetes.Interface,
+	identity, namespace string,
+) (*leaderelection.LeaderElector, error) {
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      leaseName,
+			Namespace: namespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   15 * time.Second,
+		RenewDeadline:   10 * time.Second,
+		RetryPeriod:     2 * time.Second,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Info("Started leading, cluster-scoped gadgets will run on this node")
+			},
+			OnStoppedLeading: func() {
+				log.Info("Stopped leading")
+			},
+			OnNewLeader: func(identity string) {
+				log.Infof("Cluster-scoped gadgets run on %q", identity)
+			},
+		},
+		Name: leaseName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating leader elector: %w", err)
+	}
+
+	go func() {
+		// Run returns when the leadership is lost, take part in the election
+		// again until we're done
+		for ctx.Err() == nil {
+			elector.Run(ctx)
+		}
+	}()
+
+	return elector, nil
+}
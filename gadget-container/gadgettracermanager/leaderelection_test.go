@@ -0,0 +1,60 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLeaderElection(t *testing.T) {
+	t.Parallel()
+
+	clientset := fake.NewSimpleClientset()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	elector1, err := startLeaderElectionWithClientset(ctx1, clientset, "node1", "gadget")
+	require.NoError(t, err)
+
+	require.Eventually(t, elector1.IsLeader, 10*time.Second, 100*time.Millisecond)
+	require.Equal(t, "node1", elector1.GetLeader())
+
+	lease, err := clientset.CoordinationV1().Leases("gadget").Get(context.Background(), leaseName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "node1", *lease.Spec.HolderIdentity)
+
+	// A second instance follows the current leader
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	defer cancel2()
+	elector2, err := startLeaderElectionWithClientset(ctx2, clientset, "node2", "gadget")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return elector2.GetLeader() == "node1"
+	}, 10*time.Second, 100*time.Millisecond)
+	require.False(t, elector2.IsLeader())
+
+	// The lease is released when the leader stops, so the other instance takes
+	// over without waiting for it to expire
+	cancel1()
+	require.Eventually(t, elector2.IsLeader, 20*time.Second, 100*time.Millisecond)
+	require.False(t, elector1.IsLeader())
+}
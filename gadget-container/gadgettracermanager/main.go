@@ -44,10 +44,20 @@ import (
 	// The script gadget is designed only to work in k8s, hence it's not part of all-gadgets
 	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/script"
 
+	// The KubeEvents operator emits Kubernetes Events, so it's only useful in k8s
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubeevents"
+
+	// The Redactor operator masks secrets in the events before they leave the node
+	_ "github.com/inspektor-gadget/inspektor-gadget/pkg/operators/redactor"
+
 	gadgetservice "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/rbac"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgetpolicy"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgettracermanager"
 	pb "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgettracermanager/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
 )
 
@@ -56,6 +66,7 @@ var (
 	serve               bool
 	liveness            bool
 	fallbackPodInformer bool
+	rbacAuthorization   bool
 	dump                string
 	hookMode            string
 	socketfile          string
@@ -93,6 +104,7 @@ func init() {
 
 	flag.BoolVar(&liveness, "liveness", false, "Execute as client and perform liveness probe")
 	flag.BoolVar(&fallbackPodInformer, "fallback-podinformer", true, "Use pod informer as a fallback for main hook")
+	flag.BoolVar(&rbacAuthorization, "rbac-authorization", false, "Authorize the requests to run gadgets using the Kubernetes RBAC permissions of the user")
 }
 
 func main() {
@@ -310,8 +322,37 @@ func main() {
 		log.Printf("Serving on gRPC socket %s", socketfile)
 		go grpcServer.Serve(lis)
 
-		if controller {
-			go startController(node, tracerManager)
+		var allowedDigests []string
+		if digests := os.Getenv("INSPEKTOR_GADGET_OPTION_ALLOWED_IMAGE_DIGESTS"); digests != "" {
+			allowedDigests = strings.Split(digests, ",")
+		}
+		imagePolicy, err := oci.NewImagePolicy(allowedDigests, []byte(os.Getenv("INSPEKTOR_GADGET_OPTION_TRUSTED_IMAGE_KEYS")))
+		if err != nil {
+			log.Fatalf("Parsing image policy: %v", err)
+		}
+		if imagePolicy != nil {
+			log.Infof("Only running gadget images with %d allowed digests or signed by %d trusted keys",
+				len(imagePolicy.AllowedDigests), len(imagePolicy.PublicKeys))
+		}
+		oci.SetImagePolicy(imagePolicy)
+
+		if data := os.Getenv("INSPEKTOR_GADGET_OPTION_GADGET_POLICY"); data != "" {
+			gadgetPolicy, err := gadgetpolicy.ParsePolicy([]byte(data))
+			if err != nil {
+				log.Fatalf("Parsing gadget policy: %v", err)
+			}
+			log.Infof("Gadget policy with %d deny rules", len(gadgetPolicy.Deny))
+			gadgetpolicy.SetPolicy(gadgetPolicy)
+		}
+
+		if data := os.Getenv("INSPEKTOR_GADGET_OPTION_REDACTION_CONFIG"); data != "" {
+			redactionConfig, err := redaction.ParseConfig([]byte(data))
+			if err != nil {
+				log.Fatalf("Parsing redaction config: %v", err)
+			}
+			log.Infof("Redacting events with %d fields and %d patterns",
+				len(redactionConfig.Fields), len(redactionConfig.Patterns))
+			redaction.SetConfig(redactionConfig)
 		}
 
 		stringBufferLength := os.Getenv("EVENTS_BUFFER_LENGTH")
@@ -325,6 +366,51 @@ func main() {
 		}
 		service := gadgetservice.NewService(log.StandardLogger(), bufferLength)
 
+		backpressure := gadgetservice.Backpressure{
+			Policy: gadgetservice.BackpressurePolicy(os.Getenv("EVENTS_BACKPRESSURE")),
+		}
+		if stringMaxBytes := os.Getenv("EVENTS_BUFFER_MAX_BYTES"); stringMaxBytes != "" {
+			backpressure.MaxMemory, err = strconv.ParseUint(stringMaxBytes, 10, 64)
+			if err != nil {
+				log.Fatalf("Parsing EVENTS_BUFFER_MAX_BYTES %q: %v", stringMaxBytes, err)
+			}
+		}
+		if backpressure.Policy != "" || backpressure.MaxMemory != 0 {
+			if err := service.SetBackpressure(backpressure); err != nil {
+				log.Fatalf("Parsing EVENTS_BACKPRESSURE: %v", err)
+			}
+		}
+
+		if rbacAuthorization {
+			authorizer, err := rbac.NewAuthorizer()
+			if err != nil {
+				log.Fatalf("creating RBAC authorizer: %v", err)
+			}
+			service.SetAuthorizer(authorizer)
+			log.Info("Authorizing gadget runs using Kubernetes RBAC")
+		}
+
+		leaderCtx, cancelLeader := context.WithCancel(context.Background())
+		if gadgetNamespace := os.Getenv("GADGET_NAMESPACE"); gadgetNamespace != "" {
+			elector, err := startLeaderElection(leaderCtx, node, gadgetNamespace)
+			if err != nil {
+				log.Fatalf("starting leader election: %v", err)
+			}
+			service.SetLeaderChecker(elector)
+		} else {
+			log.Warn("Environment variable GADGET_NAMESPACE not set, cluster-scoped gadgets will run on all nodes")
+		}
+
+		// The controller runs gadgets right away, before the service is
+		// started below
+		if err := service.Init(); err != nil {
+			log.Fatalf("initializing gadget service: %v", err)
+		}
+
+		if controller {
+			go startController(node, tracerManager, service)
+		}
+
 		socketType, socketPath, err := api.ParseSocketAddress(gadgetServiceHost)
 		if err != nil {
 			log.Fatalf("invalid service host: %v", err)
@@ -343,6 +429,7 @@ func main() {
 		signal.Notify(exitSignal, syscall.SIGINT, syscall.SIGTERM)
 		<-exitSignal
 
+		cancelLeader()
 		service.Close()
 		tracerManager.Close()
 	}
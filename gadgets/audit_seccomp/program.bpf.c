@@ -0,0 +1,335 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2024 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_tracing.h>
+
+#include <gadget/buffer.h>
+#include <gadget/macros.h>
+#include <gadget/mntns_filter.h>
+#include <gadget/types.h>
+
+#define PATH_MAX 256
+#define AF_INET 2
+#define AF_INET6 10
+
+// Values of the seccomp action, from include/uapi/linux/seccomp.h
+enum seccomp_action {
+	KILL_THREAD = 0x00000000U,
+	TRAP = 0x00030000U,
+	ERRNO = 0x00050000U,
+	USER_NOTIF = 0x7fc00000U,
+	TRACE = 0x7ff00000U,
+	LOG = 0x7ffc0000U,
+	ALLOW = 0x7fff0000U,
+	KILL_PROCESS = 0x80000000U,
+};
+
+#define SECCOMP_RET_ACTION_FULL 0xffff0000U
+
+struct event {
+	gadget_timestamp timestamp;
+	gadget_mntns_id mntns_id;
+	__u32 pid;
+	__u32 tid;
+	__u32 uid;
+	__u32 gid;
+	__u8 comm[TASK_COMM_LEN];
+	gadget_syscall syscall;
+	enum seccomp_action code;
+	__u64 arg0;
+	__u64 arg1;
+	__u64 arg2;
+	__u64 arg3;
+	__u64 arg4;
+	__u64 arg5;
+	// Decoded arguments, only set for the syscalls using them
+	__u8 path[PATH_MAX];
+	struct gadget_l4endpoint_t addr;
+	__u64 flags;
+};
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+
+GADGET_TRACER(seccomp, events, event);
+
+// The syscall numbers depend on the architecture, arm64 uses the generic ones
+// and doesn't have the legacy syscalls without the *at() variants.
+#if defined(__TARGET_ARCH_x86)
+#define NR_open 2
+#define NR_stat 4
+#define NR_lstat 6
+#define NR_access 21
+#define NR_connect 42
+#define NR_sendto 44
+#define NR_bind 49
+#define NR_clone 56
+#define NR_execve 59
+#define NR_truncate 76
+#define NR_chdir 80
+#define NR_rename 82
+#define NR_mkdir 83
+#define NR_rmdir 84
+#define NR_creat 85
+#define NR_link 86
+#define NR_unlink 87
+#define NR_symlink 88
+#define NR_readlink 89
+#define NR_chmod 90
+#define NR_chown 92
+#define NR_pivot_root 155
+#define NR_chroot 161
+#define NR_mount 165
+#define NR_umount2 166
+#define NR_sethostname 170
+#define NR_openat 257
+#define NR_mkdirat 258
+#define NR_fchownat 260
+#define NR_newfstatat 262
+#define NR_unlinkat 263
+#define NR_renameat 264
+#define NR_linkat 265
+#define NR_symlinkat 266
+#define NR_readlinkat 267
+#define NR_fchmodat 268
+#define NR_faccessat 269
+#define NR_unshare 272
+#define NR_setns 308
+#define NR_execveat 322
+#define NR_statx 332
+#elif defined(__TARGET_ARCH_arm64)
+#define NR_mkdirat 34
+#define NR_unlinkat 35
+#define NR_symlinkat 36
+#define NR_linkat 37
+#define NR_renameat 38
+#define NR_umount2 39
+#define NR_mount 40
+#define NR_pivot_root 41
+#define NR_truncate 45
+#define NR_faccessat 48
+#define NR_chdir 49
+#define NR_chroot 51
+#define NR_fchmodat 53
+#define NR_fchownat 54
+#define NR_openat 56
+#define NR_readlinkat 78
+#define NR_newfstatat 79
+#define NR_unshare 97
+#define NR_sethostname 161
+#define NR_bind 200
+#define NR_connect 203
+#define NR_sendto 206
+#define NR_clone 220
+#define NR_execve 221
+#define NR_setns 268
+#define NR_execveat 281
+#define NR_statx 291
+#endif
+#define NR_openat2 437
+#define NR_faccessat2 439
+
+// path_arg returns the index of the argument with the path, or the string,
+// used by the syscall, or -1
+static __always_inline int path_arg(__u64 nr)
+{
+	switch (nr) {
+#if defined(__TARGET_ARCH_x86)
+	case NR_open:
+	case NR_stat:
+	case NR_lstat:
+	case NR_access:
+	case NR_rename:
+	case NR_mkdir:
+	case NR_rmdir:
+	case NR_creat:
+	case NR_link:
+	case NR_unlink:
+	case NR_symlink:
+	case NR_readlink:
+	case NR_chmod:
+	case NR_chown:
+#endif
+	case NR_execve:
+	case NR_truncate:
+	case NR_chdir:
+	case NR_pivot_root:
+	case NR_chroot:
+	case NR_umount2:
+	case NR_sethostname:
+	case NR_symlinkat:
+		return 0;
+	// The target is more relevant than the source
+	case NR_mount:
+	case NR_openat:
+	case NR_mkdirat:
+	case NR_fchownat:
+	case NR_newfstatat:
+	case NR_unlinkat:
+	case NR_renameat:
+	case NR_linkat:
+	case NR_readlinkat:
+	case NR_fchmodat:
+	case NR_faccessat:
+	case NR_execveat:
+	case NR_statx:
+	case NR_openat2:
+	case NR_faccessat2:
+		return 1;
+	default:
+		return -1;
+	}
+}
+
+// sockaddr_arg returns the index of the struct sockaddr argument of the
+// syscall, or -1
+static __always_inline int sockaddr_arg(__u64 nr)
+{
+	switch (nr) {
+	case NR_connect:
+	case NR_bind:
+		return 1;
+	case NR_sendto:
+		return 4;
+	default:
+		return -1;
+	}
+}
+
+// flags_arg returns the index of the flags argument of the syscall, or -1
+static __always_inline int flags_arg(__u64 nr)
+{
+	switch (nr) {
+	case NR_clone:
+	case NR_unshare:
+		return 0;
+#if defined(__TARGET_ARCH_x86)
+	case NR_open:
+#endif
+	case NR_setns:
+		return 1;
+	case NR_openat:
+	case NR_unlinkat:
+		return 2;
+	case NR_mount:
+		return 3;
+	default:
+		return -1;
+	}
+}
+
+static __always_inline __u64 get_arg(struct event *event, int i)
+{
+	switch (i) {
+	case 0:
+		return event->arg0;
+	case 1:
+		return event->arg1;
+	case 2:
+		return event->arg2;
+	case 3:
+		return event->arg3;
+	case 4:
+		return event->arg4;
+	case 5:
+		return event->arg5;
+	default:
+		return 0;
+	}
+}
+
+static __always_inline void read_sockaddr(struct event *event, __u64 ptr)
+{
+	__u16 family = 0;
+
+	if (bpf_probe_read_user(&family, sizeof(family), (void *)ptr))
+		return;
+
+	if (family == AF_INET) {
+		struct sockaddr_in sin = {};
+
+		if (bpf_probe_read_user(&sin, sizeof(sin), (void *)ptr))
+			return;
+		event->addr.l3.version = 4;
+		event->addr.l3.addr.v4 = sin.sin_addr.s_addr;
+		event->addr.port = bpf_ntohs(sin.sin_port);
+	} else if (family == AF_INET6) {
+		struct sockaddr_in6 sin6 = {};
+
+		if (bpf_probe_read_user(&sin6, sizeof(sin6), (void *)ptr))
+			return;
+		event->addr.l3.version = 6;
+		__builtin_memcpy(event->addr.l3.addr.v6,
+				 sin6.sin6_addr.in6_u.u6_addr8,
+				 sizeof(event->addr.l3.addr.v6));
+		event->addr.port = bpf_ntohs(sin6.sin6_port);
+	}
+}
+
+// audit_seccomp() is called in the context of the task doing the denied
+// syscall, before it's executed, so its arguments are still in the registers
+// saved at the syscall entry.
+SEC("kprobe/audit_seccomp")
+int BPF_KPROBE(ig_audit_secc, unsigned long syscall, long signr, int code)
+{
+	__u64 mntns_id;
+	__u64 pid_tgid;
+	__u64 uid_gid;
+	struct event *event;
+	struct pt_regs *regs;
+	int idx;
+
+	mntns_id = gadget_get_mntns_id();
+	if (gadget_should_discard_mntns_id(mntns_id))
+		return 0;
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		return 0;
+
+	pid_tgid = bpf_get_current_pid_tgid();
+	uid_gid = bpf_get_current_uid_gid();
+
+	event->timestamp = bpf_ktime_get_boot_ns();
+	event->mntns_id = mntns_id;
+	event->pid = pid_tgid >> 32;
+	event->tid = (__u32)pid_tgid;
+	event->uid = (__u32)uid_gid;
+	event->gid = (__u32)(uid_gid >> 32);
+	bpf_get_current_comm(&event->comm, sizeof(event->comm));
+	event->syscall = syscall;
+	event->code = code & SECCOMP_RET_ACTION_FULL;
+
+	regs = (struct pt_regs *)bpf_task_pt_regs(bpf_get_current_task_btf());
+	event->arg0 = PT_REGS_PARM1_CORE_SYSCALL(regs);
+	event->arg1 = PT_REGS_PARM2_CORE_SYSCALL(regs);
+	event->arg2 = PT_REGS_PARM3_CORE_SYSCALL(regs);
+	event->arg3 = PT_REGS_PARM4_CORE_SYSCALL(regs);
+	event->arg4 = PT_REGS_PARM5_CORE_SYSCALL(regs);
+	event->arg5 = PT_REGS_PARM6_CORE_SYSCALL(regs);
+
+	event->path[0] = '\0';
+	idx = path_arg(syscall);
+	if (idx >= 0)
+		bpf_probe_read_user_str(event->path, sizeof(event->path),
+					(void *)get_arg(event, idx));
+
+	__builtin_memset(&event->addr, 0, sizeof(event->addr));
+	idx = sockaddr_arg(syscall);
+	if (idx >= 0)
+		read_sockaddr(event, get_arg(event, idx));
+
+	event->flags = 0;
+	idx = flags_arg(syscall);
+	if (idx >= 0)
+		event->flags = get_arg(event, idx);
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+	return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
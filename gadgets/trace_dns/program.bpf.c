@@ -5,6 +5,7 @@
 #include <linux/if_ether.h>
 #include <linux/ip.h>
 #include <linux/in.h>
+#include <linux/tcp.h>
 #include <linux/udp.h>
 #include <sys/socket.h>
 
@@ -49,6 +50,9 @@ struct event_t {
 	unsigned char qr;
 	unsigned char pkt_type;
 	unsigned char rcode;
+	// truncated says if the message has the TC flag, telling the answer
+	// didn't fit in a UDP packet, or if it didn't fit in a TCP segment
+	__u8 truncated;
 
 	__u64 latency_ns; // Set only if qr is 1 (response) and pkt_type is 0 (Host).
 
@@ -60,7 +64,11 @@ struct event_t {
 		   [16]; // Either IPv4-mapped-IPv6 (A record) or IPv6 (AAAA record) addresses.
 };
 
-#define DNS_OFF (ETH_HLEN + sizeof(struct iphdr) + sizeof(struct udphdr))
+#define DNS_PORT 53
+
+// DNS over TCP prefixes each message with its length
+// https://datatracker.ietf.org/doc/html/rfc1035#section-4.2.2
+#define DNS_TCP_LEN_SIZE 2
 
 #define DNS_CLASS_IN \
 	1 // https://datatracker.ietf.org/doc/html/rfc1035#section-3.2.4
@@ -159,7 +167,8 @@ struct {
 	__uint(max_entries, 1024);
 } query_map SEC(".maps");
 
-static __always_inline __u32 dns_name_length(struct __sk_buff *skb)
+static __always_inline __u32 dns_name_length(struct __sk_buff *skb,
+					     __u32 dns_off)
 {
 	// This loop iterates over the DNS labels to find the total DNS name
 	// length.
@@ -170,7 +179,7 @@ static __always_inline __u32 dns_name_length(struct __sk_buff *skb)
 			skip--;
 		} else {
 			int label_len = load_byte(
-				skb, DNS_OFF + sizeof(struct dnshdr) + i);
+				skb, dns_off + sizeof(struct dnshdr) + i);
 			if (label_len == 0)
 				break;
 			// The simple solution "i += label_len" gives verifier
@@ -189,6 +198,10 @@ static __always_inline int load_addresses(struct __sk_buff *skb, int ancount,
 	int rroffset = anoffset;
 	int index = 0;
 	for (int i = 0; i < ancount && i < MAX_ADDR_ANSWERS; i++) {
+		// The answers of a message that was truncated could be missing
+		if (rroffset + sizeof(struct dnsrr) > skb->len)
+			break;
+
 		__u16 rrname =
 			load_byte(skb, rroffset + offsetof(struct dnsrr, name));
 
@@ -206,6 +219,8 @@ static __always_inline int load_addresses(struct __sk_buff *skb, int ancount,
 								   class));
 		__u16 rdlength = load_half(
 			skb, rroffset + offsetof(struct dnsrr, rdlength));
+		if (rroffset + sizeof(struct dnsrr) + rdlength > skb->len)
+			break;
 
 		if (rrtype == DNS_TYPE_A && rrclass == DNS_CLASS_IN &&
 		    rdlength == 4) {
@@ -230,8 +245,10 @@ static __always_inline int load_addresses(struct __sk_buff *skb, int ancount,
 }
 
 static __always_inline int output_dns_event(struct __sk_buff *skb,
+					    __u32 l4_off, __u32 dns_off,
 					    union dnsflags flags,
-					    __u32 name_len, __u16 ancount)
+					    __u32 name_len, __u16 ancount,
+					    __u8 truncated)
 {
 	__u32 zero = 0;
 	struct event_t *event = bpf_map_lookup_elem(&tmp_event, &zero);
@@ -242,7 +259,7 @@ static __always_inline int output_dns_event(struct __sk_buff *skb,
 
 	event->netns = skb->cb[0]; // cb[0] initialized by dispatcher.bpf.c
 	event->timestamp = bpf_ktime_get_boot_ns();
-	event->id = load_half(skb, DNS_OFF + offsetof(struct dnshdr, id));
+	event->id = load_half(skb, dns_off + offsetof(struct dnshdr, id));
 
 	event->src.l3.version = event->dst.l3.version = 4;
 	event->dst.l3.addr.v4 =
@@ -262,28 +279,25 @@ static __always_inline int output_dns_event(struct __sk_buff *skb,
 		load_byte(skb, ETH_HLEN + offsetof(struct iphdr, protocol));
 	if (event->src.proto == IPPROTO_TCP) {
 		event->src.port =
-			load_half(skb, ETH_HLEN + sizeof(struct iphdr) +
-					       offsetof(struct tcphdr, source));
+			load_half(skb, l4_off + offsetof(struct tcphdr, source));
 		event->dst.port =
-			load_half(skb, ETH_HLEN + sizeof(struct iphdr) +
-					       offsetof(struct tcphdr, dest));
+			load_half(skb, l4_off + offsetof(struct tcphdr, dest));
 	} else if (event->src.proto == IPPROTO_UDP) {
 		event->src.port =
-			load_half(skb, ETH_HLEN + sizeof(struct iphdr) +
-					       offsetof(struct udphdr, source));
+			load_half(skb, l4_off + offsetof(struct udphdr, source));
 		event->dst.port =
-			load_half(skb, ETH_HLEN + sizeof(struct iphdr) +
-					       offsetof(struct udphdr, dest));
+			load_half(skb, l4_off + offsetof(struct udphdr, dest));
 	}
 
 	event->qr = flags.qr;
+	event->truncated = truncated || flags.tc;
 
 	if (flags.qr == 1) {
 		// Response code set only for replies.
 		event->rcode = flags.rcode;
 	}
 
-	bpf_skb_load_bytes(skb, DNS_OFF + sizeof(struct dnshdr), event->name,
+	bpf_skb_load_bytes(skb, dns_off + sizeof(struct dnshdr), event->name,
 			   name_len);
 
 	event->pkt_type = skb->pkt_type;
@@ -291,7 +305,7 @@ static __always_inline int output_dns_event(struct __sk_buff *skb,
 	// Read QTYPE right after the QNAME (name_len + the zero length octet)
 	// https://datatracker.ietf.org/doc/html/rfc1035#section-4.1.2
 	event->qtype =
-		load_half(skb, DNS_OFF + sizeof(struct dnshdr) + name_len + 1);
+		load_half(skb, dns_off + sizeof(struct dnshdr) + name_len + 1);
 
 	// Enrich event with process metadata
 	struct sockets_value *skb_val = gadget_socket_lookup(skb);
@@ -309,7 +323,7 @@ static __always_inline int output_dns_event(struct __sk_buff *skb,
 
 	// DNS answers start immediately after qname (name_len octets)
 	// + the zero length octet + qtype (2 octets) + qclass (2 octets).
-	int anoffset = DNS_OFF + sizeof(struct dnshdr) + name_len + 5;
+	int anoffset = dns_off + sizeof(struct dnshdr) + name_len + 5;
 	int anaddrcount = load_addresses(skb, ancount, anoffset, event);
 	event->anaddrcount = anaddrcount;
 
@@ -365,36 +379,75 @@ static __always_inline int output_dns_event(struct __sk_buff *skb,
 SEC("socket1")
 int ig_trace_dns(struct __sk_buff *skb)
 {
+	__u8 truncated = 0;
+	__u32 l4_off, dns_off;
+
 	// Skip non-IP packets
 	if (load_half(skb, offsetof(struct ethhdr, h_proto)) != ETH_P_IP)
 		return 0;
 
-	// Skip non-UDP packets
-	if (load_byte(skb, ETH_HLEN + offsetof(struct iphdr, protocol)) !=
-	    IPPROTO_UDP)
+	l4_off = ETH_HLEN + (load_byte(skb, ETH_HLEN) & 0x0f) * 4;
+
+	switch (load_byte(skb, ETH_HLEN + offsetof(struct iphdr, protocol))) {
+	case IPPROTO_UDP:
+		dns_off = l4_off + sizeof(struct udphdr);
+		break;
+	case IPPROTO_TCP: {
+		// Only look at DNS servers' port, there's no way to tell DNS
+		// messages apart from other data in TCP streams
+		if (load_half(skb, l4_off + offsetof(struct tcphdr, source)) !=
+			    DNS_PORT &&
+		    load_half(skb, l4_off + offsetof(struct tcphdr, dest)) !=
+			    DNS_PORT)
+			return 0;
+
+		// doff is the length of the TCP header in 32 bits words
+		__u32 payload_off =
+			l4_off + (load_byte(skb, l4_off + 12) >> 4) * 4;
+
+		// Skip segments without data, like the ones of the handshake
+		// and the ACKs
+		if (payload_off + DNS_TCP_LEN_SIZE + sizeof(struct dnshdr) >
+		    skb->len)
+			return 0;
+
+		// Each message is prefixed with its length, only segments
+		// starting a message are parsed. Messages that don't fit in
+		// the segment are parsed as far as possible and reported as
+		// truncated.
+		__u16 msg_len = load_half(skb, payload_off);
+		if (msg_len < sizeof(struct dnshdr))
+			return 0;
+		dns_off = payload_off + DNS_TCP_LEN_SIZE;
+		truncated = dns_off + msg_len > skb->len;
+		break;
+	}
+	default:
 		return 0;
+	}
 
 	union dnsflags flags;
-	flags.flags = load_half(skb, DNS_OFF + offsetof(struct dnshdr, flags));
+	flags.flags = load_half(skb, dns_off + offsetof(struct dnshdr, flags));
 
 	// Skip DNS packets with more than 1 question
-	if (load_half(skb, DNS_OFF + offsetof(struct dnshdr, qdcount)) != 1)
+	if (load_half(skb, dns_off + offsetof(struct dnshdr, qdcount)) != 1)
 		return 0;
 
 	__u16 ancount =
-		load_half(skb, DNS_OFF + offsetof(struct dnshdr, ancount));
+		load_half(skb, dns_off + offsetof(struct dnshdr, ancount));
 	__u16 nscount =
-		load_half(skb, DNS_OFF + offsetof(struct dnshdr, nscount));
+		load_half(skb, dns_off + offsetof(struct dnshdr, nscount));
 
 	// Skip DNS queries with answers
 	if (flags.qr == 0 && ancount + nscount != 0)
 		return 0;
 
-	__u32 name_len = dns_name_length(skb);
+	__u32 name_len = dns_name_length(skb, dns_off);
 	if (name_len == 0)
 		return 0;
 
-	return output_dns_event(skb, flags, name_len, ancount);
+	return output_dns_event(skb, l4_off, dns_off, flags, name_len, ancount,
+				truncated);
 }
 
 char _license[] SEC("license") = "GPL";
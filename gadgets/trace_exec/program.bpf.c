@@ -4,6 +4,7 @@
 #include <bpf/bpf_helpers.h>
 #include <bpf/bpf_core_read.h>
 
+#include <gadget/filesystem.h>
 #include <gadget/macros.h>
 #include <gadget/mntns_filter.h>
 #include <gadget/types.h>
@@ -16,6 +17,13 @@
 #define BASE_EVENT_SIZE (size_t)(&((struct event *)0)->args)
 #define EVENT_SIZE(e) (BASE_EVENT_SIZE + e->args_size)
 #define LAST_ARG (FULL_MAX_ARGS_ARR - ARGSIZE)
+/* selected environment variables, as NAME=value separated by spaces */
+#define ENV_SIZE 1024
+#define ENV_VAR_SIZE 256
+#define LAST_ENV_VAR (ENV_SIZE - ENV_VAR_SIZE)
+#define MAX_ENV_ENTRIES 64
+/* comma separated names of the environment variables to capture */
+#define ENV_NAMES_SIZE 64
 
 struct event {
 	gadget_mntns_id mntns_id;
@@ -30,15 +38,24 @@ struct event {
 	int args_count;
 	unsigned int args_size;
 	__u8 comm[TASK_COMM_LEN];
+	__u8 cwd[MAX_STRING_SIZE];
+	__u8 env[ENV_SIZE];
+	/* args has to be the last field, only the used part is sent */
 	__u8 args[FULL_MAX_ARGS_ARR];
 };
 
 const volatile bool ignore_failed = true;
 const volatile uid_t targ_uid = INVALID_UID;
 const volatile int max_args = DEFAULT_MAXARGS;
+const volatile bool capture_cwd = false;
+const volatile bool full_args = false;
+const volatile char env_names[ENV_NAMES_SIZE] = {};
 
 GADGET_PARAM(ignore_failed);
 GADGET_PARAM(targ_uid);
+GADGET_PARAM(capture_cwd);
+GADGET_PARAM(full_args);
+GADGET_PARAM(env_names);
 
 static const struct event empty_event = {};
 
@@ -146,13 +163,121 @@ int ig_execve_e(struct trace_event_raw_sys_enter *ctx)
 	return 0;
 }
 
+/* env_selected returns whether var, a NAME=value string, is one of env_names */
+static __always_inline bool env_selected(const __u8 *var)
+{
+	bool match = true;
+	int k = 0;
+
+	for (int j = 0; j < ENV_NAMES_SIZE; j++) {
+		char c = env_names[j];
+
+		if (c == ',' || c == '\0') {
+			if (match && k > 0 && k < ENV_VAR_SIZE && var[k] == '=')
+				return true;
+			if (c == '\0')
+				return false;
+			match = true;
+			k = 0;
+			continue;
+		}
+
+		if (match && (k >= ENV_VAR_SIZE || var[k] != c))
+			match = false;
+		k++;
+	}
+	return false;
+}
+
+/* read_env copies the selected environment variables of the new program,
+ * separated by spaces */
+static __always_inline void read_env(struct event *event, struct mm_struct *mm)
+{
+	unsigned long env_start = BPF_CORE_READ(mm, env_start);
+	unsigned long env_end = BPF_CORE_READ(mm, env_end);
+	unsigned int env_size = 0;
+	buf_t *buf;
+	long ret;
+
+	/* The variables are read in a scratch buffer, so the ones that aren't
+	 * selected never get into the event */
+	buf = get_buf(STRING_BUF_IDX);
+	if (!buf)
+		return;
+
+	for (int i = 0; i < MAX_ENV_ENTRIES && env_start < env_end; i++) {
+		if (env_size > LAST_ENV_VAR)
+			break;
+
+		ret = bpf_probe_read_user_str(buf->buf, ENV_VAR_SIZE,
+					      (const char *)env_start);
+		/* The next one can't be found after a variable that is too long */
+		if (ret <= 0 || ret >= ENV_VAR_SIZE)
+			break;
+		env_start += ret;
+
+		if (!env_selected(buf->buf))
+			continue;
+
+		if (bpf_probe_read_kernel(&event->env[env_size], ret, buf->buf))
+			break;
+		env_size += ret;
+		event->env[env_size - 1] = ' ';
+	}
+
+	if (env_size > 0 && env_size <= ENV_SIZE)
+		event->env[env_size - 1] = '\0';
+}
+
+/* read_full_args replaces the arguments read when entering execve(), which
+ * are limited in number and length, with the whole argv of the new program */
+static __always_inline void read_full_args(struct event *event,
+					   struct mm_struct *mm)
+{
+	unsigned long arg_start = BPF_CORE_READ(mm, arg_start);
+	unsigned long arg_end = BPF_CORE_READ(mm, arg_end);
+	unsigned long size;
+	int count = 0;
+
+	if (arg_end <= arg_start)
+		return;
+	size = arg_end - arg_start;
+	if (size > FULL_MAX_ARGS_ARR)
+		size = FULL_MAX_ARGS_ARR;
+	/* for the verifier */
+	if (size == 0)
+		return;
+
+	if (bpf_probe_read_user(event->args, size, (const void *)arg_start))
+		return;
+
+	for (int i = 0; i < FULL_MAX_ARGS_ARR; i++) {
+		if (i >= size)
+			break;
+		if (event->args[i] == '\0')
+			count++;
+	}
+	/* the last argument was cut, make it a string anyway */
+	if (event->args[size - 1] != '\0') {
+		event->args[size - 1] = '\0';
+		count++;
+	}
+
+	event->args_size = size;
+	event->args_count = count;
+}
+
 SEC("tracepoint/syscalls/sys_exit_execve")
 int ig_execve_x(struct trace_event_raw_sys_exit *ctx)
 {
 	u64 id;
 	pid_t pid;
 	int ret;
+	char *cwd;
 	struct event *event;
+	struct fs_struct *fs;
+	struct mm_struct *mm;
+	struct task_struct *task;
 	u32 uid = (u32)bpf_get_current_uid_gid();
 
 	if (valid_uid(targ_uid) && targ_uid != uid)
@@ -168,6 +293,26 @@ int ig_execve_x(struct trace_event_raw_sys_exit *ctx)
 
 	event->retval = ret;
 	bpf_get_current_comm(&event->comm, sizeof(event->comm));
+
+	task = (struct task_struct *)bpf_get_current_task();
+	if (capture_cwd) {
+		fs = BPF_CORE_READ(task, fs);
+		cwd = get_path_str(&fs->pwd);
+		if (cwd)
+			bpf_probe_read_kernel_str(event->cwd, sizeof(event->cwd),
+						  cwd);
+	}
+
+	/* Only a successful execve() replaces the memory with the one of the
+	 * new program */
+	if (ret == 0) {
+		mm = BPF_CORE_READ(task, mm);
+		if (env_names[0] != '\0')
+			read_env(event, mm);
+		if (full_args)
+			read_full_args(event, mm);
+	}
+
 	size_t len = EVENT_SIZE(event);
 	if (len <= sizeof(*event))
 		bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, event,
@@ -0,0 +1,284 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2024 The Inspektor Gadget authors */
+
+#include <linux/bpf.h>
+#include <linux/if_ether.h>
+#include <linux/ip.h>
+#include <linux/ipv6.h>
+#include <linux/in.h>
+#include <linux/tcp.h>
+#include <sys/socket.h>
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_endian.h>
+
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define GADGET_TYPE_NETWORKING
+#include <gadget/sockets-map.h>
+
+#define HTTP_MAX_METHOD_LEN 8
+#define HTTP_MAX_PATH_LEN 128
+// "GET / HTTP/1.0\r\n" is the shortest request line
+#define HTTP_MIN_LEN 16
+#define HTTP_MAX_REQUESTS 10240
+
+struct event_t {
+	gadget_timestamp timestamp;
+
+	// Endpoints of the request, from the client to the server
+	struct gadget_l4endpoint_t src;
+	struct gadget_l4endpoint_t dst;
+
+	gadget_mntns_id mntns_id;
+	__u32 netns;
+	__u32 pid;
+	__u32 tid;
+	__u8 task[TASK_COMM_LEN];
+
+	char method[HTTP_MAX_METHOD_LEN];
+	char path[HTTP_MAX_PATH_LEN];
+	__u16 status;
+	// Time between the request and the response seen in this network namespace
+	__u64 latency_ns;
+};
+
+// Only parse the HTTP messages with this source or destination port. 0 parses all of them
+const volatile __u16 port = 0;
+
+GADGET_PARAM(port);
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(__u32));
+	__uint(value_size, sizeof(__u32));
+} events SEC(".maps");
+
+GADGET_TRACER(http, events, event_t);
+
+// The stack is limited, so use a map to build the event
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct event_t);
+} tmp_event SEC(".maps");
+
+// The connection of a request, as seen in the request
+struct conn_key_t {
+	struct gadget_l4endpoint_t src;
+	struct gadget_l4endpoint_t dst;
+	__u32 netns;
+};
+
+struct request_t {
+	__u64 timestamp;
+	char method[HTTP_MAX_METHOD_LEN];
+	char path[HTTP_MAX_PATH_LEN];
+};
+
+// Requests waiting for their response. It's an LRU map, so requests that never
+// get a response are evicted when it's full.
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, HTTP_MAX_REQUESTS);
+	__type(key, struct conn_key_t);
+	__type(value, struct request_t);
+} requests SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct request_t);
+} tmp_request SEC(".maps");
+
+static __always_inline bool has_prefix(const char *buf, const char *prefix,
+				       int len)
+{
+#pragma unroll
+	for (int i = 0; i < len; i++) {
+		if (buf[i] != prefix[i])
+			return false;
+	}
+	return true;
+}
+
+// method_len returns the length of the method starting the request line, or 0
+// if buf doesn't start with one
+static __always_inline int method_len(const char *buf)
+{
+	if (has_prefix(buf, "GET ", 4))
+		return 3;
+	if (has_prefix(buf, "PUT ", 4))
+		return 3;
+	if (has_prefix(buf, "POST ", 5))
+		return 4;
+	if (has_prefix(buf, "HEAD ", 5))
+		return 4;
+	if (has_prefix(buf, "PATCH ", 6))
+		return 5;
+	if (has_prefix(buf, "DELETE ", 7))
+		return 6;
+	if (has_prefix(buf, "OPTIONS ", 8))
+		return 7;
+	return 0;
+}
+
+static __always_inline bool is_digit(char c)
+{
+	return c >= '0' && c <= '9';
+}
+
+SEC("socket1")
+int ig_trace_http(struct __sk_buff *skb)
+{
+	struct sockets_value *skb_val;
+	struct conn_key_t key = {};
+	struct request_t *req;
+	struct event_t *event;
+	char buf[HTTP_MAX_METHOD_LEN];
+	__u32 zero = 0;
+	__u32 l4_off = 0;
+	__u32 data_off;
+	__u32 len;
+	__u8 ip_proto = 0;
+	int mlen;
+
+	switch (load_half(skb, offsetof(struct ethhdr, h_proto))) {
+	case ETH_P_IP:
+		ip_proto = load_byte(skb,
+				     ETH_HLEN + offsetof(struct iphdr, protocol));
+		l4_off = ETH_HLEN + (load_byte(skb, ETH_HLEN) & 0x0f) * 4;
+		key.src.l3.version = key.dst.l3.version = 4;
+		// load_word() converts to host byte order
+		key.src.l3.addr.v4 = bpf_htonl(
+			load_word(skb, ETH_HLEN + offsetof(struct iphdr, saddr)));
+		key.dst.l3.addr.v4 = bpf_htonl(
+			load_word(skb, ETH_HLEN + offsetof(struct iphdr, daddr)));
+		break;
+	case ETH_P_IPV6:
+		// Extension headers aren't followed
+		ip_proto = load_byte(skb,
+				     ETH_HLEN + offsetof(struct ipv6hdr, nexthdr));
+		l4_off = ETH_HLEN + sizeof(struct ipv6hdr);
+		key.src.l3.version = key.dst.l3.version = 6;
+		bpf_skb_load_bytes(skb,
+				   ETH_HLEN + offsetof(struct ipv6hdr, saddr),
+				   key.src.l3.addr.v6,
+				   sizeof(key.src.l3.addr.v6));
+		bpf_skb_load_bytes(skb,
+				   ETH_HLEN + offsetof(struct ipv6hdr, daddr),
+				   key.dst.l3.addr.v6,
+				   sizeof(key.dst.l3.addr.v6));
+		break;
+	default:
+		return 0;
+	}
+
+	if (ip_proto != IPPROTO_TCP)
+		return 0;
+
+	key.src.proto = key.dst.proto = IPPROTO_TCP;
+	key.src.port = load_half(skb, l4_off + offsetof(struct tcphdr, source));
+	key.dst.port = load_half(skb, l4_off + offsetof(struct tcphdr, dest));
+	if (port && key.src.port != port && key.dst.port != port)
+		return 0;
+
+	// The data offset is in the upper 4 bits of the 12th byte
+	data_off = l4_off + (load_byte(skb, l4_off + 12) >> 4) * 4;
+	if (skb->len < data_off + HTTP_MIN_LEN)
+		return 0;
+	if (bpf_skb_load_bytes(skb, data_off, buf, sizeof(buf)) < 0)
+		return 0;
+
+	key.netns = skb->cb[0]; // cb[0] initialized by dispatcher.bpf.c
+
+	mlen = method_len(buf);
+	if (mlen > 0) {
+		req = bpf_map_lookup_elem(&tmp_request, &zero);
+		if (!req)
+			return 0;
+		__builtin_memset(req, 0, sizeof(*req));
+
+		req->timestamp = bpf_ktime_get_boot_ns();
+		__builtin_memcpy(req->method, buf, HTTP_MAX_METHOD_LEN);
+		req->method[mlen] = 0;
+
+		// The path is only looked for in the first segment of the request
+		len = skb->len - (data_off + mlen + 1);
+		if (len > HTTP_MAX_PATH_LEN - 1)
+			len = HTTP_MAX_PATH_LEN - 1;
+		if (len == 0)
+			return 0;
+		if (bpf_skb_load_bytes(skb, data_off + mlen + 1, req->path,
+				       len) < 0)
+			return 0;
+		for (int i = 0; i < HTTP_MAX_PATH_LEN; i++) {
+			if (req->path[i] == ' ' || req->path[i] == '\r') {
+				req->path[i] = 0;
+				break;
+			}
+		}
+
+		// Pipelined requests replace the previous ones, only the last
+		// one is reported
+		bpf_map_update_elem(&requests, &key, req, BPF_ANY);
+		return 0;
+	}
+
+	// "HTTP/1.1 200 OK"
+	if (!has_prefix(buf, "HTTP/1.", 7))
+		return 0;
+
+	// The response goes in the opposite direction of the request
+	struct conn_key_t req_key = {
+		.src = key.dst,
+		.dst = key.src,
+		.netns = key.netns,
+	};
+	req = bpf_map_lookup_elem(&requests, &req_key);
+	if (!req)
+		return 0;
+
+	event = bpf_map_lookup_elem(&tmp_event, &zero);
+	if (!event)
+		goto out;
+	__builtin_memset(event, 0, sizeof(*event));
+
+	if (bpf_skb_load_bytes(skb, data_off + 9, buf, 3) < 0)
+		goto out;
+	if (!is_digit(buf[0]) || !is_digit(buf[1]) || !is_digit(buf[2]))
+		goto out;
+	event->status = (buf[0] - '0') * 100 + (buf[1] - '0') * 10 +
+			(buf[2] - '0');
+
+	event->timestamp = bpf_ktime_get_boot_ns();
+	if (event->timestamp > req->timestamp)
+		event->latency_ns = event->timestamp - req->timestamp;
+	event->src = req_key.src;
+	event->dst = req_key.dst;
+	event->netns = key.netns;
+	__builtin_memcpy(event->method, req->method, sizeof(event->method));
+	__builtin_memcpy(event->path, req->path, sizeof(event->path));
+
+	// Enrich event with process metadata
+	skb_val = gadget_socket_lookup(skb);
+	if (skb_val != NULL) {
+		event->mntns_id = skb_val->mntns;
+		event->pid = skb_val->pid_tgid >> 32;
+		event->tid = (__u32)skb_val->pid_tgid;
+		__builtin_memcpy(&event->task, skb_val->task,
+				 sizeof(event->task));
+	}
+
+	bpf_perf_event_output(skb, &events, BPF_F_CURRENT_CPU, event,
+			      sizeof(*event));
+
+out:
+	bpf_map_delete_elem(&requests, &req_key);
+	return 0;
+}
+
+char _license[] SEC("license") = "GPL";
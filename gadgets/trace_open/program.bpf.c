@@ -9,6 +9,7 @@
 #include <gadget/buffer.h>
 #include <gadget/macros.h>
 #include <gadget/mntns_filter.h>
+#include <gadget/ratelimit.h>
 #include <gadget/types.h>
 
 #define TASK_RUNNING 0
@@ -124,6 +125,7 @@ static __always_inline int trace_exit(struct trace_event_raw_sys_exit *ctx)
 {
 	struct event *event;
 	struct args_t *ap;
+	gadget_mntns_id mntns_id;
 	int ret;
 	u32 pid = bpf_get_current_pid_tgid();
 	u64 uid_gid = bpf_get_current_uid_gid();
@@ -135,6 +137,10 @@ static __always_inline int trace_exit(struct trace_event_raw_sys_exit *ctx)
 	if (targ_failed && ret >= 0)
 		goto cleanup; /* want failed only */
 
+	mntns_id = gadget_get_mntns_id();
+	if (gadget_ratelimit_should_throttle(mntns_id))
+		goto cleanup;
+
 	event = gadget_reserve_buf(&events, sizeof(*event));
 	if (!event)
 		goto cleanup;
@@ -148,7 +154,7 @@ static __always_inline int trace_exit(struct trace_event_raw_sys_exit *ctx)
 	event->flags = ap->flags;
 	event->mode = ap->mode;
 	event->ret = ret;
-	event->mntns_id = gadget_get_mntns_id();
+	event->mntns_id = mntns_id;
 	event->timestamp = bpf_ktime_get_boot_ns();
 
 	/* emit event */
@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2024 The Inspektor Gadget authors */
+
+#include <linux/bpf.h>
+#include <linux/if_ether.h>
+#include <linux/ip.h>
+#include <linux/ipv6.h>
+#include <linux/in.h>
+#include <linux/tcp.h>
+#include <linux/udp.h>
+#include <sys/socket.h>
+
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_endian.h>
+
+#include <gadget/macros.h>
+#include <gadget/types.h>
+
+#define GADGET_TYPE_NETWORKING
+#include <gadget/sockets-map.h>
+
+struct event_t {
+	gadget_timestamp timestamp;
+
+	struct gadget_l4endpoint_t src;
+	struct gadget_l4endpoint_t dst;
+
+	gadget_mntns_id mntns_id;
+	__u32 netns;
+	__u32 pid;
+	__u32 tid;
+	__u8 task[TASK_COMM_LEN];
+
+	unsigned char pkt_type;
+
+	struct gadget_packet_t packet;
+};
+
+// Number of bytes of each packet to capture
+const volatile __u32 snaplen = GADGET_PACKET_MAX_SNAPLEN;
+// Capture one packet out of sample_rate, randomly
+const volatile __u32 sample_rate = 1;
+// Only capture the packets of this IP protocol, e.g. 6 for TCP. 0 captures all of them
+const volatile __u8 proto = 0;
+// Only capture the TCP and UDP packets with this source or destination port. 0 captures all of them
+const volatile __u16 port = 0;
+
+GADGET_PARAM(snaplen);
+GADGET_PARAM(sample_rate);
+GADGET_PARAM(proto);
+GADGET_PARAM(port);
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERF_EVENT_ARRAY);
+	__uint(key_size, sizeof(__u32));
+	__uint(value_size, sizeof(__u32));
+} events SEC(".maps");
+
+GADGET_TRACER(packets, events, event_t);
+
+// The stack is limited, so use a map to build the event
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, struct event_t);
+} tmp_event SEC(".maps");
+
+SEC("socket1")
+int ig_trace_packets(struct __sk_buff *skb)
+{
+	struct sockets_value *skb_val;
+	struct event_t *event;
+	__u32 zero = 0;
+	__u32 l4_off = 0;
+	__u32 caplen;
+	__u8 ip_proto = 0;
+
+	if (sample_rate > 1 && bpf_get_prandom_u32() % sample_rate != 0)
+		return 0;
+
+	event = bpf_map_lookup_elem(&tmp_event, &zero);
+	if (!event)
+		return 0;
+
+	// The data of the packet is bounded by caplen, don't clear it
+	__builtin_memset(event, 0, offsetof(struct event_t, packet));
+
+	switch (load_half(skb, offsetof(struct ethhdr, h_proto))) {
+	case ETH_P_IP:
+		ip_proto = load_byte(skb,
+				     ETH_HLEN + offsetof(struct iphdr, protocol));
+		l4_off = ETH_HLEN + (load_byte(skb, ETH_HLEN) & 0x0f) * 4;
+		event->src.l3.version = event->dst.l3.version = 4;
+		// load_word() converts to host byte order
+		event->src.l3.addr.v4 = bpf_htonl(
+			load_word(skb, ETH_HLEN + offsetof(struct iphdr, saddr)));
+		event->dst.l3.addr.v4 = bpf_htonl(
+			load_word(skb, ETH_HLEN + offsetof(struct iphdr, daddr)));
+		break;
+	case ETH_P_IPV6:
+		// Extension headers aren't followed
+		ip_proto = load_byte(skb,
+				     ETH_HLEN + offsetof(struct ipv6hdr, nexthdr));
+		l4_off = ETH_HLEN + sizeof(struct ipv6hdr);
+		event->src.l3.version = event->dst.l3.version = 6;
+		bpf_skb_load_bytes(skb,
+				   ETH_HLEN + offsetof(struct ipv6hdr, saddr),
+				   event->src.l3.addr.v6,
+				   sizeof(event->src.l3.addr.v6));
+		bpf_skb_load_bytes(skb,
+				   ETH_HLEN + offsetof(struct ipv6hdr, daddr),
+				   event->dst.l3.addr.v6,
+				   sizeof(event->dst.l3.addr.v6));
+		break;
+	}
+
+	event->src.proto = event->dst.proto = ip_proto;
+	if (ip_proto == IPPROTO_TCP || ip_proto == IPPROTO_UDP) {
+		// The ports are at the same offsets in both headers
+		event->src.port =
+			load_half(skb, l4_off + offsetof(struct udphdr, source));
+		event->dst.port =
+			load_half(skb, l4_off + offsetof(struct udphdr, dest));
+	}
+
+	if (proto && ip_proto != proto)
+		return 0;
+	if (port && event->src.port != port && event->dst.port != port)
+		return 0;
+
+	caplen = skb->len;
+	if (caplen > snaplen)
+		caplen = snaplen;
+	if (caplen > GADGET_PACKET_MAX_SNAPLEN)
+		caplen = GADGET_PACKET_MAX_SNAPLEN;
+	if (caplen == 0)
+		return 0;
+	if (bpf_skb_load_bytes(skb, 0, event->packet.data, caplen) < 0)
+		return 0;
+	event->packet.len = skb->len;
+	event->packet.caplen = caplen;
+
+	event->timestamp = bpf_ktime_get_boot_ns();
+	event->netns = skb->cb[0]; // cb[0] initialized by dispatcher.bpf.c
+	event->pkt_type = skb->pkt_type;
+
+	// Enrich event with process metadata
+	skb_val = gadget_socket_lookup(skb);
+	if (skb_val != NULL) {
+		event->mntns_id = skb_val->mntns;
+		event->pid = skb_val->pid_tgid >> 32;
+		event->tid = (__u32)skb_val->pid_tgid;
+		__builtin_memcpy(&event->task, skb_val->task,
+				 sizeof(event->task));
+	}
+
+	bpf_perf_event_output(skb, &events, BPF_F_CURRENT_CPU, event,
+			      sizeof(*event));
+
+	return 0;
+}
+
+char _license[] SEC("license") = "GPL";
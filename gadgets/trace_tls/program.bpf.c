@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: GPL-2.0
+/* Copyright (c) 2024 The Inspektor Gadget authors */
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
+
+#include <gadget/buffer.h>
+#include <gadget/macros.h>
+#include <gadget/mntns_filter.h>
+#include <gadget/types.h>
+
+// Max hostname length: 255
+// https://datatracker.ietf.org/doc/html/rfc1034#section-3.1
+#define MAX_HOSTNAME 256
+#define MAX_SAMPLE 256
+
+// SSL_set_tlsext_host_name() is a macro calling SSL_ctrl() with this command
+// in OpenSSL
+#define SSL_CTRL_SET_TLSEXT_HOSTNAME 55
+
+enum tls_library {
+	OPENSSL,
+	GNUTLS,
+};
+
+enum tls_operation {
+	READ,
+	WRITE,
+};
+
+struct event {
+	gadget_timestamp timestamp;
+	gadget_mntns_id mntns_id;
+	__u32 pid;
+	__u32 tid;
+	__u32 uid;
+	__u32 gid;
+	__u8 comm[TASK_COMM_LEN];
+	enum tls_library library;
+	enum tls_operation operation;
+	// Plaintext bytes read or written
+	__u32 len;
+	// Server name sent by the client in the SNI extension
+	__u8 hostname[MAX_HOSTNAME];
+	// First bytes of the plaintext, only captured if sample_size is set
+	__u32 sample_len;
+	__u8 sample[MAX_SAMPLE];
+};
+
+struct call_t {
+	__u64 session;
+	const void *buf;
+	// Where SSL_read_ex() and SSL_write_ex() store the number of bytes
+	size_t *processed;
+	enum tls_library library;
+	enum tls_operation operation;
+};
+
+// Number of bytes of the plaintext to capture in the events, 0 disables it
+const volatile __u32 sample_size = 0;
+
+GADGET_PARAM(sample_size);
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u32);
+	__type(value, struct call_t);
+} calls SEC(".maps");
+
+// Server names set by the clients, by session
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, 10240);
+	__type(key, u64);
+	__type(value, __u8[MAX_HOSTNAME]);
+} hostnames SEC(".maps");
+
+GADGET_TRACER_MAP(events, 1024 * 256);
+
+GADGET_TRACER(tls, events, event);
+
+static __always_inline int save_hostname(void *session, const char *name)
+{
+	__u64 key = (__u64)session;
+	__u8 hostname[MAX_HOSTNAME] = {};
+
+	if (gadget_should_discard_mntns_id(gadget_get_mntns_id()))
+		return 0;
+
+	bpf_probe_read_user_str(hostname, sizeof(hostname), name);
+	bpf_map_update_elem(&hostnames, &key, hostname, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int forget_session(void *session)
+{
+	__u64 key = (__u64)session;
+
+	bpf_map_delete_elem(&hostnames, &key);
+	return 0;
+}
+
+static __always_inline int enter_call(void *session, const void *buf,
+				      size_t *processed,
+				      enum tls_library library,
+				      enum tls_operation operation)
+{
+	u32 tid = (u32)bpf_get_current_pid_tgid();
+	struct call_t call = {
+		.session = (__u64)session,
+		.buf = buf,
+		.processed = processed,
+		.library = library,
+		.operation = operation,
+	};
+
+	if (gadget_should_discard_mntns_id(gadget_get_mntns_id()))
+		return 0;
+
+	bpf_map_update_elem(&calls, &tid, &call, BPF_ANY);
+	return 0;
+}
+
+static __always_inline int exit_call(struct pt_regs *ctx, long ret)
+{
+	u64 pid_tgid = bpf_get_current_pid_tgid();
+	u64 uid_gid = bpf_get_current_uid_gid();
+	u32 tid = (u32)pid_tgid;
+	struct call_t *call;
+	struct event *event;
+	__u8 *hostname;
+	__u32 len, size;
+	size_t processed;
+
+	call = bpf_map_lookup_elem(&calls, &tid);
+	if (!call)
+		return 0;
+
+	if (call->processed) {
+		// The _ex() variants return 1 on success
+		if (ret != 1)
+			goto cleanup;
+		if (bpf_probe_read_user(&processed, sizeof(processed),
+					call->processed))
+			goto cleanup;
+		len = processed;
+	} else {
+		if (ret <= 0)
+			goto cleanup;
+		len = ret;
+	}
+
+	event = gadget_reserve_buf(&events, sizeof(*event));
+	if (!event)
+		goto cleanup;
+
+	event->timestamp = bpf_ktime_get_boot_ns();
+	event->mntns_id = gadget_get_mntns_id();
+	event->pid = pid_tgid >> 32;
+	event->tid = tid;
+	event->uid = (u32)uid_gid;
+	event->gid = (u32)(uid_gid >> 32);
+	bpf_get_current_comm(&event->comm, sizeof(event->comm));
+	event->library = call->library;
+	event->operation = call->operation;
+	event->len = len;
+
+	hostname = bpf_map_lookup_elem(&hostnames, &call->session);
+	if (hostname)
+		__builtin_memcpy(event->hostname, hostname, MAX_HOSTNAME);
+	else
+		event->hostname[0] = '\0';
+
+	size = len < sample_size ? len : sample_size;
+	if (size > MAX_SAMPLE)
+		size = MAX_SAMPLE;
+	event->sample_len = size;
+	if (size > 0)
+		bpf_probe_read_user(event->sample, size, call->buf);
+
+	gadget_submit_buf(ctx, &events, event, sizeof(*event));
+
+cleanup:
+	bpf_map_delete_elem(&calls, &tid);
+	return 0;
+}
+
+// OpenSSL and BoringSSL
+
+SEC("uprobe/libssl:SSL_ctrl")
+int BPF_KPROBE(ig_ssl_ctrl, void *ssl, int cmd, long larg, void *parg)
+{
+	if (cmd != SSL_CTRL_SET_TLSEXT_HOSTNAME)
+		return 0;
+	return save_hostname(ssl, parg);
+}
+
+// BoringSSL has it as a function
+SEC("uprobe/libssl:SSL_set_tlsext_host_name")
+int BPF_KPROBE(ig_ssl_set_host, void *ssl, const char *name)
+{
+	return save_hostname(ssl, name);
+}
+
+SEC("uprobe/libssl:SSL_free")
+int BPF_KPROBE(ig_ssl_free, void *ssl)
+{
+	return forget_session(ssl);
+}
+
+SEC("uprobe/libssl:SSL_read")
+int BPF_KPROBE(ig_ssl_read_e, void *ssl, void *buf, int num)
+{
+	return enter_call(ssl, buf, NULL, OPENSSL, READ);
+}
+
+SEC("uretprobe/libssl:SSL_read")
+int BPF_KRETPROBE(ig_ssl_read_x, int ret)
+{
+	return exit_call(ctx, ret);
+}
+
+SEC("uprobe/libssl:SSL_write")
+int BPF_KPROBE(ig_ssl_write_e, void *ssl, const void *buf, int num)
+{
+	return enter_call(ssl, buf, NULL, OPENSSL, WRITE);
+}
+
+SEC("uretprobe/libssl:SSL_write")
+int BPF_KRETPROBE(ig_ssl_write_x, int ret)
+{
+	return exit_call(ctx, ret);
+}
+
+SEC("uprobe/libssl:SSL_read_ex")
+int BPF_KPROBE(ig_ssl_read_ex_e, void *ssl, void *buf, size_t num,
+	       size_t *readbytes)
+{
+	return enter_call(ssl, buf, readbytes, OPENSSL, READ);
+}
+
+SEC("uretprobe/libssl:SSL_read_ex")
+int BPF_KRETPROBE(ig_ssl_read_ex_x, int ret)
+{
+	return exit_call(ctx, ret);
+}
+
+SEC("uprobe/libssl:SSL_write_ex")
+int BPF_KPROBE(ig_ssl_write_ex_e, void *ssl, const void *buf, size_t num,
+	       size_t *written)
+{
+	return enter_call(ssl, buf, written, OPENSSL, WRITE);
+}
+
+SEC("uretprobe/libssl:SSL_write_ex")
+int BPF_KRETPROBE(ig_ssl_write_ex_x, int ret)
+{
+	return exit_call(ctx, ret);
+}
+
+// GnuTLS
+
+SEC("uprobe/libgnutls:gnutls_server_name_set")
+int BPF_KPROBE(ig_gnutls_sni, void *session, int type, const void *name)
+{
+	return save_hostname(session, name);
+}
+
+SEC("uprobe/libgnutls:gnutls_deinit")
+int BPF_KPROBE(ig_gnutls_deinit, void *session)
+{
+	return forget_session(session);
+}
+
+SEC("uprobe/libgnutls:gnutls_record_recv")
+int BPF_KPROBE(ig_gnutls_recv_e, void *session, void *data, size_t size)
+{
+	return enter_call(session, data, NULL, GNUTLS, READ);
+}
+
+SEC("uretprobe/libgnutls:gnutls_record_recv")
+int BPF_KRETPROBE(ig_gnutls_recv_x, long ret)
+{
+	return exit_call(ctx, ret);
+}
+
+SEC("uprobe/libgnutls:gnutls_record_send")
+int BPF_KPROBE(ig_gnutls_send_e, void *session, const void *data, size_t size)
+{
+	return enter_call(session, data, NULL, GNUTLS, WRITE);
+}
+
+SEC("uretprobe/libgnutls:gnutls_record_send")
+int BPF_KRETPROBE(ig_gnutls_send_x, long ret)
+{
+	return exit_call(ctx, ret);
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,35 @@
+/* SPDX-License-Identifier: Apache-2.0 */
+
+#ifndef __AGGREGATE_H
+#define __AGGREGATE_H
+
+#include <vmlinux.h>
+#include <bpf/bpf_helpers.h>
+#include <gadget/maps.bpf.h>
+
+/* GADGET_AGGREGATOR_MAP defines a hash map where a gadget accumulates data,
+ * e.g. counters per connection. User space reads and deletes its entries
+ * periodically, each entry is sent as an event. The value has to be the struct
+ * given to GADGET_AGGREGATOR() and should contain the fields identifying the
+ * entry, as the key isn't sent to user space.
+ */
+#define GADGET_AGGREGATOR_MAP(name, key_type, value_type, size)	\
+	struct {						\
+		__uint(type, BPF_MAP_TYPE_HASH);		\
+		__uint(max_entries, size);			\
+		__type(key, key_type);				\
+		__type(value, struct value_type);		\
+	} name SEC(".maps");
+
+/* gadget_aggregate_lookup_or_init returns the entry of key in map, creating it
+ * with init if it doesn't exist yet. It returns NULL if the map is full.
+ */
+#define gadget_aggregate_lookup_or_init(map, key, init) \
+	bpf_map_lookup_or_try_init(map, key, init)
+
+/* gadget_aggregate_add atomically adds val to the field pointed by ptr, as the
+ * same entry can be updated concurrently from different CPUs.
+ */
+#define gadget_aggregate_add(ptr, val) __sync_fetch_and_add(ptr, val)
+
+#endif /* __AGGREGATE_H */
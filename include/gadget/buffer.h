@@ -6,6 +6,7 @@
 
 #include <vmlinux.h>
 #include <bpf/bpf_helpers.h>
+#include <gadget/types.h>
 
 #ifndef MAX_EVENT_SIZE
 #define MAX_EVENT_SIZE		10240
@@ -18,6 +19,12 @@
 	} name SEC(".maps");				\
 	const void *gadget_map_tracer_##name __attribute__((unused));
 
+/* Number of bytes that must be pending in the ring buffer before user space is
+ * woken up. 0 wakes it up on each event. Set by user space, keep in sync with
+ * gadgets.RingbufWakeupBytesName.
+ */
+const volatile __u64 gadget_ringbuf_wakeup_bytes = 0;
+
 struct {
 	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
 	__uint(max_entries, 1);
@@ -38,11 +45,46 @@ static __always_inline void *gadget_reserve_buf(void *map, __u64 size)
 static __always_inline long gadget_submit_buf(void *ctx, void *map, void *buf, __u64 size)
 {
 	if (bpf_core_type_exists(struct bpf_ringbuf)) {
-		bpf_ringbuf_submit(buf, 0);
+		__u64 flags = 0;
+
+		if (gadget_ringbuf_wakeup_bytes &&
+		    bpf_ringbuf_query(map, BPF_RB_AVAIL_DATA) <
+			    gadget_ringbuf_wakeup_bytes)
+			flags = BPF_RB_NO_WAKEUP;
+
+		bpf_ringbuf_submit(buf, flags);
 		return 0;
 	}
 
 	return bpf_perf_event_output(ctx, map, BPF_F_CURRENT_CPU, buf, size);
 }
 
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, 1);
+	__type(key, __u32);
+	__type(value, __u64);
+} gadget_seq_counters SEC(".maps");
+
+/* gadget_seq_next returns the sequence number of the next event generated on
+ * the current CPU. It has to be called for each event the gadget tries to
+ * send, before reserving its buffer, so user space also detects the events
+ * that couldn't be reserved. It returns 0 on error.
+ */
+static __always_inline gadget_seq gadget_seq_next(void)
+{
+	static const int zero = 0;
+	__u64 *counter;
+	__u64 cpu;
+
+	counter = bpf_map_lookup_elem(&gadget_seq_counters, &zero);
+	if (!counter)
+		return 0;
+
+	cpu = bpf_get_smp_processor_id();
+	*counter += 1;
+
+	return (cpu << GADGET_SEQ_CPU_SHIFT) | (*counter & GADGET_SEQ_COUNTER_MASK);
+}
+
 #endif /* __BUFFER_BPF_H */
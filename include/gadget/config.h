@@ -0,0 +1,31 @@
+/* SPDX-License-Identifier: (GPL-2.0 WITH Linux-syscall-note) OR Apache-2.0 */
+
+#ifndef CONFIG_H
+#define CONFIG_H
+
+#include <bpf/bpf_helpers.h>
+
+/* GADGET_CONFIG defines the gadget_config map, holding a single struct type
+ * whose fields are params that can be changed while the gadget runs. User
+ * space writes the struct when the gadget starts and every time one of the
+ * params changes. Keep in sync with types.ConfigMapName.
+ *
+ * It also defines gadget_get_config(), returning the current values of the
+ * params. The fields can change between two calls, copy the ones that have to
+ * be consistent.
+ */
+#define GADGET_CONFIG(type)                                          \
+	struct {                                                     \
+		__uint(type, BPF_MAP_TYPE_ARRAY);                    \
+		__uint(max_entries, 1);                              \
+		__type(key, __u32);                                  \
+		__type(value, struct type);                          \
+	} gadget_config SEC(".maps");                                \
+                                                                     \
+	static __always_inline struct type *gadget_get_config(void)  \
+	{                                                            \
+		__u32 zero = 0;                                      \
+		return bpf_map_lookup_elem(&gadget_config, &zero);   \
+	}
+
+#endif /* CONFIG_H */
@@ -19,9 +19,24 @@
 #define GADGET_PARAM(name) \
 	const void * gadget_param_##name __attribute__((unused));
 
+// GADGET_MAP_PARAM is used to indicate that the keys of a given hash map are set from a parameter
+// with a list of integers, e.g. to filter on a set of ports. The values are left zeroed.
+#define GADGET_MAP_PARAM(name) \
+	const void * gadget_map_param_##name __attribute__((unused));
+
 // GADGET_SNAPSHOTTER is used to mark a struct as being produced by a snapshotter gadget.
 #define GADGET_SNAPSHOTTER(name, type) \
 	const void *gadget_snapshotter_##name##___##type __attribute__((unused)); \
 	const struct type *unusedevent_##name##___##type __attribute__((unused));
 
+// GADGET_AGGREGATOR is used to define an aggregator. Currently only one aggregator per eBPF
+// object is allowed.
+// name is the aggregator's name
+// map_name is the name of the hash map where the data is aggregated, see
+// include/gadget/aggregate.h
+// value_type is the name of the structure stored as value in the map
+#define GADGET_AGGREGATOR(name, map_name, value_type) \
+	const void *gadget_aggregator_##name##___##map_name##___##value_type __attribute__((unused)); \
+	const struct value_type *__gadget_aggregator_type_##name __attribute__((unused));
+
 #endif /* __MACROS_H */
@@ -0,0 +1,93 @@
+/* SPDX-License-Identifier: (GPL-2.0 WITH Linux-syscall-note) OR Apache-2.0 */
+
+#ifndef RATELIMIT_H
+#define RATELIMIT_H
+
+#include <gadget/types.h>
+
+#include <bpf/bpf_helpers.h>
+
+#ifndef GADGET_RATELIMIT_MAX_CONTAINERS
+#define GADGET_RATELIMIT_MAX_CONTAINERS 1024
+#endif
+
+#define GADGET_NSEC_PER_SEC 1000000000ULL
+
+/* Maximum number of events per second sent by each mount namespace, 0
+ * disables the rate limiting, and number of events it can send at once. Set by
+ * user space, keep in sync with gadgets.RateLimitName and
+ * gadgets.RateLimitBurstName.
+ */
+const volatile __u64 gadget_ratelimit = 0;
+const volatile __u64 gadget_ratelimit_burst = 0;
+
+/* Token bucket of a mount namespace. The tokens are counted in nanoseconds:
+ * each event costs 1s / gadget_ratelimit and the bucket fills up with the
+ * time elapsed since the last event. Keep in sync with rateLimitBucket in
+ * pkg/gadgets/run/tracer/ratelimit.go.
+ */
+struct gadget_ratelimit_bucket {
+	__u64 tokens;
+	__u64 last;
+	__u64 throttled;
+};
+
+/* Keep in sync with gadgets.RateLimitMapName */
+struct {
+	__uint(type, BPF_MAP_TYPE_LRU_HASH);
+	__uint(max_entries, GADGET_RATELIMIT_MAX_CONTAINERS);
+	__type(key, gadget_mntns_id);
+	__type(value, struct gadget_ratelimit_bucket);
+} gadget_ratelimit_map SEC(".maps");
+
+/* gadget_ratelimit_should_throttle returns true if the event generated from
+ * the given mntns_id exceeds the rate limit and must be discarded. The
+ * throttled events are counted and reported by user space. It should be called
+ * right before reserving the buffer of the event.
+ */
+static __always_inline bool
+gadget_ratelimit_should_throttle(gadget_mntns_id mntns_id)
+{
+	struct gadget_ratelimit_bucket *bucket;
+	__u64 cost, capacity, tokens, now;
+
+	if (!gadget_ratelimit)
+		return false;
+
+	cost = GADGET_NSEC_PER_SEC / gadget_ratelimit;
+	capacity = (gadget_ratelimit_burst ?: gadget_ratelimit) * cost;
+	now = bpf_ktime_get_ns();
+
+	bucket = bpf_map_lookup_elem(&gadget_ratelimit_map, &mntns_id);
+	if (!bucket) {
+		struct gadget_ratelimit_bucket new_bucket = {
+			.tokens = capacity - cost,
+			.last = now,
+		};
+
+		bpf_map_update_elem(&gadget_ratelimit_map, &mntns_id,
+				    &new_bucket, BPF_NOEXIST);
+		return false;
+	}
+
+	/* Buckets are shared by all the CPUs without locking, concurrent
+	 * events can make the limit slightly inaccurate.
+	 */
+	tokens = bucket->tokens;
+	if (now > bucket->last)
+		tokens += now - bucket->last;
+	if (tokens > capacity)
+		tokens = capacity;
+	bucket->last = now;
+
+	if (tokens < cost) {
+		bucket->tokens = tokens;
+		__sync_fetch_and_add(&bucket->throttled, 1);
+		return true;
+	}
+
+	bucket->tokens = tokens - cost;
+	return false;
+}
+
+#endif
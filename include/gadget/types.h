@@ -34,4 +34,46 @@ typedef __u64 gadget_mntns_id;
 // time.
 typedef __u64 gadget_timestamp;
 
+// gadget_seq is a sequence number returned by gadget_seq_next(), see include/gadget/buffer.h. The
+// CPU the event was generated on is stored in the upper GADGET_SEQ_CPU_SHIFT bits, the number of
+// events generated on that CPU in the lower ones. User space uses it to detect lost events.
+typedef __u64 gadget_seq;
+
+// gadget_syscall is the number of a syscall, as used by the architecture the gadget runs on. It's
+// shown as the name of the syscall, e.g. "openat".
+typedef __u64 gadget_syscall;
+
+// Maximum number of bytes of a packet stored in struct gadget_packet_t, enough for a full Ethernet
+// frame without jumbo frames.
+#define GADGET_PACKET_MAX_SNAPLEN 1514
+
+// struct defining a packet captured by a gadget, starting at the Ethernet header. Only the first
+// caplen bytes of data are valid. The packets can be written to a pcapng file by user space.
+struct gadget_packet_t {
+	__u32 len; // original length of the packet
+	__u32 caplen; // number of bytes captured in data
+	__u8 data[GADGET_PACKET_MAX_SNAPLEN];
+};
+
+#define GADGET_SEQ_CPU_SHIFT 48
+#define GADGET_SEQ_COUNTER_MASK ((1ULL << GADGET_SEQ_CPU_SHIFT) - 1)
+
+// The following types can be used for parameters, see GADGET_PARAM() in include/gadget/macros.h.
+// Their values are parsed from a human friendly representation by Inspektor Gadget. IP parameters
+// use struct gadget_l3endpoint_t.
+
+// gadget_duration is a duration in nanoseconds, e.g. "500ms"
+typedef __u64 gadget_duration;
+
+// gadget_size is a size in bytes, e.g. "64MiB"
+typedef __u64 gadget_size;
+
+// struct defining an IPv4 or IPv6 network, e.g. "10.0.0.0/8"
+struct gadget_cidr_t {
+	union gadget_ip_addr_t addr;
+	__u8 version; // 4 or 6
+	__u8 prefixlen;
+	__u8 pad[2]; // manual padding to avoid issues between C and Go
+};
+
 #endif /* __TYPES_H */
@@ -53,6 +53,12 @@ func newTopFileCmd(ns string, cmd string, startAndStop bool) *Command {
 			e.ReadBytes = 0
 			e.Writes = 0
 			e.WriteBytes = 0
+			e.ReadLatencyP50 = 0
+			e.ReadLatencyP95 = 0
+			e.ReadLatencyP99 = 0
+			e.WriteLatencyP50 = 0
+			e.WriteLatencyP95 = 0
+			e.WriteLatencyP99 = 0
 
 			e.Runtime.ContainerID = ""
 			e.Runtime.ContainerImageDigest = ""
@@ -55,6 +55,11 @@ func TestTraceSignal(t *testing.T) {
 				e.Timestamp = 0
 				e.Pid = 0
 				e.TargetPid = 0
+				e.TargetComm = ""
+				e.TargetMountNsID = 0
+				e.TargetNamespace = ""
+				e.TargetPod = ""
+				e.TargetContainer = ""
 				e.Retval = 0
 				e.MountNsID = 0
 
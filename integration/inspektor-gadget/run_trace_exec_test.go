@@ -0,0 +1,114 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+
+	. "github.com/inspektor-gadget/inspektor-gadget/integration"
+)
+
+func runTraceExec(t *testing.T, ns string, cmd string) {
+	// TODO: Handle it once we support getting container image name from docker
+	isDockerRuntime := IsDockerRuntime(t)
+
+	traceExecCmd := &Command{
+		Name:         "StartRunTraceExecGadget",
+		Cmd:          cmd,
+		StartAndStop: true,
+		ValidateOutput: func(t *testing.T, output string) {
+			expectedBaseJsonObj := RunEventToObj(t, &types.Event{
+				CommonData: BuildCommonData(ns, WithContainerImageName("docker.io/library/busybox:latest", isDockerRuntime)),
+			})
+
+			// Only FOO is selected with --env, BAR and the variables
+			// inherited from the pod must not be captured.
+			expectedTraceExecJsonObj := map[string]interface{}{
+				"comm":       "true",
+				"env":        "FOO=bar",
+				"uid":        0,
+				"gid":        0,
+				"retval":     0,
+				"pid":        0,
+				"ppid":       0,
+				"loginuid":   0,
+				"sessionid":  0,
+				"args":       "",
+				"args_count": 0,
+				"args_size":  0,
+				"cwd":        "",
+				"mntns_id":   0,
+				"timestamp":  "",
+			}
+
+			expectedJsonObj := MergeJsonObjs(t, expectedBaseJsonObj, expectedTraceExecJsonObj)
+
+			normalize := func(m map[string]interface{}) {
+				SetEventK8sNode(m, "")
+
+				// TODO: Verify container runtime and container name
+				SetEventRuntimeName(m, "")
+				SetEventRuntimeContainerID(m, "")
+				SetEventRuntimeContainerName(m, "")
+
+				m["timestamp"] = ""
+				m["mntns_id"] = 0
+				m["pid"] = uint32(0)
+				m["ppid"] = uint32(0)
+				m["loginuid"] = uint32(0)
+				m["sessionid"] = uint32(0)
+				m["args"] = ""
+				m["args_count"] = 0
+				m["args_size"] = 0
+			}
+
+			ExpectEntriesToMatchObj(t, output, normalize, expectedJsonObj)
+		},
+	}
+
+	commands := []*Command{
+		traceExecCmd,
+		BusyboxPodRepeatCommand(ns, "FOO=bar BAR=baz /bin/true"),
+		WaitUntilTestPodReadyCommand(ns),
+	}
+
+	RunTestSteps(commands, t, WithCbBeforeCleanup(PrintLogsFn(ns)))
+}
+
+func TestRunTraceExecEnv(t *testing.T) {
+	ns := GenerateTestNamespaceName("test-run-trace-exec-env")
+
+	t.Parallel()
+
+	commandsPreTest := []*Command{
+		CreateTestNamespaceCommand(ns),
+	}
+
+	RunTestSteps(commandsPreTest, t)
+
+	t.Cleanup(func() {
+		commands := []*Command{
+			DeleteTestNamespaceCommand(ns),
+		}
+		RunTestSteps(commands, t, WithCbBeforeCleanup(PrintLogsFn(ns)))
+	})
+
+	cmd := fmt.Sprintf("$KUBECTL_GADGET run %s/trace_exec:%s -n %s -o json --env FOO", *gadgetRepository, *gadgetTag, ns)
+
+	runTraceExec(t, ns, cmd)
+}
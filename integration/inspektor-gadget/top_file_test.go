@@ -36,6 +36,12 @@ func newTopFileCmd(ns, cmd string, startAndStop bool, isDockerRuntime bool) *Com
 		normalize := func(e *topfileTypes.Stats) {
 			e.Writes = 0
 			e.WriteBytes = 0
+			e.ReadLatencyP50 = 0
+			e.ReadLatencyP95 = 0
+			e.ReadLatencyP99 = 0
+			e.WriteLatencyP50 = 0
+			e.WriteLatencyP95 = 0
+			e.WriteLatencyP99 = 0
 			e.Pid = 0
 			e.Tid = 0
 			e.MountNsID = 0
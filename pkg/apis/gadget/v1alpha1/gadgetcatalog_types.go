@@ -0,0 +1,64 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GadgetCatalogImage describes gadget images that are allowed to run
+type GadgetCatalogImage struct {
+	// Repository is the repository of the image without tag, e.g.
+	// "ghcr.io/inspektor-gadget/gadget/trace_open". Shell patterns are
+	// supported, e.g. "ghcr.io/inspektor-gadget/gadget/*"
+	Repository string `json:"repository"`
+
+	// Digests restricts the allowed images of the repository to the ones
+	// with these digests, e.g. "sha256:0123...". All images of the
+	// repository are allowed if empty
+	Digests []string `json:"digests,omitempty"`
+}
+
+// GadgetCatalogSpec defines the desired state of GadgetCatalog
+type GadgetCatalogSpec struct {
+	// Images is the list of gadget images allowed to run
+	Images []GadgetCatalogImage `json:"images,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// GadgetCatalog is the Schema for the gadgetcatalogs API. It lists the gadget
+// images that are allowed to run in the cluster. When at least one
+// GadgetCatalog exists, only the images listed in any of them can be run.
+type GadgetCatalog struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GadgetCatalogSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GadgetCatalogList contains a list of GadgetCatalog
+type GadgetCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GadgetCatalog `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GadgetCatalog{}, &GadgetCatalogList{})
+}
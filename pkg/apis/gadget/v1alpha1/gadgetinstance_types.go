@@ -0,0 +1,155 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GadgetInstanceSinkType defines where the events of a gadget instance are sent
+// +kubebuilder:validation:Enum=Log;File;ConfigMap
+type GadgetInstanceSinkType string
+
+const (
+	// GadgetInstanceSinkTypeLog indicates to write events to the logs of the
+	// gadget pod
+	GadgetInstanceSinkTypeLog GadgetInstanceSinkType = "Log"
+	// GadgetInstanceSinkTypeFile indicates to append events to a file on the
+	// node
+	GadgetInstanceSinkTypeFile GadgetInstanceSinkType = "File"
+	// GadgetInstanceSinkTypeConfigMap indicates to store the results of the
+	// gadget in a ConfigMap once it finishes
+	GadgetInstanceSinkTypeConfigMap GadgetInstanceSinkType = "ConfigMap"
+)
+
+// GadgetInstanceSink defines where the events of a gadget instance are sent
+type GadgetInstanceSink struct {
+	// Type is "Log", "File" or "ConfigMap". Defaults to "Log"
+	Type GadgetInstanceSinkType `json:"type,omitempty"`
+
+	// Path is the file path on the node where events are written to, one JSON
+	// object per line, relative to /var/log/inspektor-gadget. Only used with
+	// Type=File
+	Path string `json:"path,omitempty"`
+
+	// ConfigMapName is the prefix of the ConfigMaps, in the namespace of the
+	// gadget instance, where the results are stored. Each node stores its
+	// results in the "<configMapName>-<node>" ConfigMap. Defaults to
+	// "<name>-results". Only used with Type=ConfigMap
+	ConfigMapName string `json:"configMapName,omitempty"`
+}
+
+// GadgetInstanceSpec defines the desired state of GadgetInstance
+type GadgetInstanceSpec struct {
+	// Image is the OCI image of the gadget to run, as passed to "run"
+	Image string `json:"image"`
+
+	// Nodes is the list of nodes on which this gadget instance should run.
+	// If empty, it runs on all nodes
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Filter is to tell the gadget to filter events based on namespace,
+	// pod name, labels or container name
+	Filter *ContainerFilter `json:"filter,omitempty"`
+
+	// Parameters contains the parameters of the run gadget and of the
+	// gadget image itself, e.g. "pull" or the eBPF parameters
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Duration is how long the gadget runs before it finishes, e.g. "1m". If
+	// unset, it runs until the gadget instance is deleted
+	Duration metav1.Duration `json:"duration,omitempty"`
+
+	// Sink defines where the events generated by the gadget are sent
+	Sink GadgetInstanceSink `json:"sink,omitempty"`
+
+	// ServiceAccountName is the service account, in the namespace of the
+	// gadget instance, whose RBAC permissions are needed to run the gadget
+	// when the authorization of the gadget pods is enabled. Defaults to
+	// "default"
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// GadgetInstanceState defines the state of a gadget instance on a node
+// +kubebuilder:validation:Enum=Running;Completed;Failed
+type GadgetInstanceState string
+
+const (
+	// GadgetInstanceStateRunning indicates the gadget is running
+	GadgetInstanceStateRunning GadgetInstanceState = "Running"
+	// GadgetInstanceStateCompleted indicates the gadget finished without errors
+	GadgetInstanceStateCompleted GadgetInstanceState = "Completed"
+	// GadgetInstanceStateFailed indicates the gadget couldn't be started or
+	// finished with an error
+	GadgetInstanceStateFailed GadgetInstanceState = "Failed"
+)
+
+const (
+	// GadgetInstanceConditionRunning is the condition type reporting whether
+	// the gadget is running on the node
+	GadgetInstanceConditionRunning = "Running"
+)
+
+// GadgetInstanceNodeStatus defines the observed state of a GadgetInstance on a
+// node
+type GadgetInstanceNodeStatus struct {
+	// State is "Running", "Completed" or "Failed"
+	State GadgetInstanceState `json:"state,omitempty"`
+
+	// ObservedGeneration is the generation of the spec the gadget on this
+	// node was started with
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Message gives details about the state, e.g. the error that made the
+	// gadget fail
+	Message string `json:"message,omitempty"`
+
+	// Conditions contains the conditions of the gadget instance on the node
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GadgetInstanceStatus defines the observed state of GadgetInstance
+type GadgetInstanceStatus struct {
+	// Nodes contains the status of the gadget instance on each node, indexed
+	// by the node name
+	Nodes map[string]GadgetInstanceNodeStatus `json:"nodes,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.spec.image`
+
+// GadgetInstance is the Schema for the gadgetinstances API. It describes a
+// gadget image that runs continuously on the nodes without a client attached.
+type GadgetInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GadgetInstanceSpec   `json:"spec,omitempty"`
+	Status GadgetInstanceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GadgetInstanceList contains a list of GadgetInstance
+type GadgetInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GadgetInstance `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GadgetInstance{}, &GadgetInstanceList{})
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btfgen
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/cilium/ebpf/btf"
+)
+
+// LoadSpecFromArchive looks for the BTF file matching the current system in
+// archive, a gzip-compressed tarball with files following the btfhub-archive
+// layout, and loads it. It returns nil, nil if the kernel exposes BTF
+// information or if there is no BTF file for the current system in the
+// archive.
+func LoadSpecFromArchive(archive []byte) (*btf.Spec, error) {
+	if _, err := btf.LoadKernelSpec(); err == nil {
+		return nil, nil
+	}
+
+	info, err := getOSInfo()
+	if err != nil {
+		return nil, fmt.Errorf("getting os info: %w", err)
+	}
+
+	file, err := findInArchive(archive, info.btfPath())
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, nil
+	}
+
+	s, err := btf.LoadSpecFromReader(bytes.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("loading BTF spec: %w", err)
+	}
+
+	return s, nil
+}
+
+// findInArchive returns the content of the file at filePath in the given
+// gzip-compressed tarball or nil if it's not found.
+func findInArchive(archive []byte, filePath string) ([]byte, error) {
+	gzReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("creating gzip reader: %w", err)
+	}
+	defer gzReader.Close()
+
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if strings.TrimPrefix(path.Clean(header.Name), "/") != filePath {
+			continue
+		}
+
+		file, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s from archive: %w", filePath, err)
+		}
+		return file, nil
+	}
+}
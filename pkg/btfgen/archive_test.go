@@ -0,0 +1,77 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package btfgen
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	for name, content := range files {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		})
+		require.NoError(t, err)
+		_, err = tarWriter.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzWriter.Close())
+
+	return buf.Bytes()
+}
+
+func TestFindInArchive(t *testing.T) {
+	t.Parallel()
+
+	info := &osInfo{
+		ID:        "ubuntu",
+		VersionID: "20.04",
+		Arch:      "x86_64",
+		Kernel:    "5.4.0-42-generic",
+	}
+
+	archive := createArchive(t, map[string]string{
+		"./ubuntu/20.04/x86_64/5.4.0-42-generic.btf": "foo",
+		"./ubuntu/20.04/x86_64/5.4.0-43-generic.btf": "bar",
+	})
+
+	file, err := findInArchive(archive, info.btfPath())
+	require.NoError(t, err)
+	require.Equal(t, []byte("foo"), file)
+
+	info.Kernel = "5.4.0-44-generic"
+	file, err = findInArchive(archive, info.btfPath())
+	require.NoError(t, err)
+	require.Nil(t, file)
+
+	_, err = findInArchive([]byte("not an archive"), info.btfPath())
+	require.Error(t, err)
+}
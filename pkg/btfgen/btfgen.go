@@ -60,8 +60,7 @@ func initialize() error {
 		goarch = "x86"
 	}
 
-	btfFile := fmt.Sprintf("btfs/%s/%s/%s/%s/%s.btf",
-		goarch, info.ID, info.VersionID, info.Arch, info.Kernel)
+	btfFile := fmt.Sprintf("btfs/%s/%s", goarch, info.btfPath())
 
 	file, err := btfs.ReadFile(btfFile)
 	if err != nil {
@@ -96,6 +95,12 @@ type osInfo struct {
 	Kernel    string
 }
 
+// btfPath returns the path of the BTF file for this system following the
+// btfhub-archive layout: <id>/<version_id>/<arch>/<kernel>.btf
+func (i *osInfo) btfPath() string {
+	return fmt.Sprintf("%s/%s/%s/%s.btf", i.ID, i.VersionID, i.Arch, i.Kernel)
+}
+
 func getOSInfo() (*osInfo, error) {
 	osInfo := &osInfo{}
 
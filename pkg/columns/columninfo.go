@@ -75,6 +75,8 @@ type Attributes struct {
 	Tags []string `yaml:"tags"`
 	// Template defines the template that will be used. Non-typed templates will be applied first.
 	Template string `yaml:"template"`
+	// Unit of the values of this column, like ns or bytes; it's shown next to the name in headers
+	Unit string `yaml:"unit"`
 }
 
 type Column[T any] struct {
@@ -260,6 +262,11 @@ func (ci *Column[T]) parseTagInfo(tagInfo []string) error {
 				return fmt.Errorf("negative precision value %q for field %q", params[1], ci.Name)
 			}
 			ci.Precision = w
+		case "unit":
+			if paramsLen < 2 || params[1] == "" {
+				return fmt.Errorf("missing unit value for field %q", ci.Name)
+			}
+			ci.Unit = params[1]
 		case "width":
 			ci.Width, err = ci.getWidth(params)
 			if err != nil {
@@ -58,6 +58,7 @@ The parameter could be used for specific options, passing nil will use the defau
 	| group     | sum                    | defines what should happen with the field whenever entries are grouped (see grouping)                                |
 	| hide      | none                   | specifies that this column is not to be considered by default (see custom columns)                                   |
 	| precision | int                    | specifies the precision of floats (number of decimals)                                                               |
+	| unit      | string                 | specifies the unit of the values, shown next to the column name in headers, eg: "ns"                                 |
 	| width     | int                    | defines the space allocated for the column                                                                           |
 
 # Virtual Columns or Custom Extractors
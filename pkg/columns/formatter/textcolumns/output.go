@@ -71,18 +71,26 @@ func (tf *TextColumnsFormatter[T]) FormatHeader() string {
 		if i > 0 {
 			row.WriteString(tf.options.ColumnDivider)
 		}
-		name := column.col.Name
-		switch tf.options.HeaderStyle {
-		case HeaderStyleUppercase:
-			name = strings.ToUpper(name)
-		case HeaderStyleLowercase:
-			name = strings.ToLower(name)
-		}
-		row.WriteString(tf.buildFixedString(name, column.calculatedWidth, ellipsis.End, column.col.Alignment))
+		row.WriteString(tf.buildFixedString(tf.headerName(column), column.calculatedWidth, ellipsis.End, column.col.Alignment))
 	}
 	return row.String()
 }
 
+// headerName returns the name of the column as shown in the header, followed by its unit if any
+func (tf *TextColumnsFormatter[T]) headerName(column *Column[T]) string {
+	name := column.col.Name
+	switch tf.options.HeaderStyle {
+	case HeaderStyleUppercase:
+		name = strings.ToUpper(name)
+	case HeaderStyleLowercase:
+		name = strings.ToLower(name)
+	}
+	if column.col.Unit != "" {
+		name += "(" + column.col.Unit + ")"
+	}
+	return name
+}
+
 // FormatRowDivider returns a string that repeats the defined RowDivider until the total length of a row is reached
 func (tf *TextColumnsFormatter[T]) FormatRowDivider() string {
 	if tf.options.RowDivider == DividerNone {
@@ -283,7 +283,7 @@ func (tf *TextColumnsFormatter[T]) AdjustWidthsToContent(entries []*T, considerH
 			if column.col.FixedWidth {
 				continue
 			}
-			headerLen := len([]rune(column.col.Name))
+			headerLen := len([]rune(tf.headerName(column)))
 			if headerLen > columnWidths[columnIndex] {
 				columnWidths[columnIndex] = headerLen
 			}
@@ -80,6 +80,22 @@ func TestTextColumnsFormatter_FormatHeader(t *testing.T) {
 	assert.Equal(t, "name        age   size  balance canDance", formatter.FormatHeader())
 }
 
+func TestTextColumnsFormatter_FormatHeaderUnit(t *testing.T) {
+	type unitStruct struct {
+		Name    string `column:"name,width:6"`
+		Latency uint64 `column:"latency,width:11,align:right,unit:ns"`
+	}
+
+	cols := columns.MustCreateColumns[unitStruct]().GetColumnMap()
+	formatter := NewFormatter(cols)
+	assert.Equal(t, "NAME   LATENCY(ns)", formatter.FormatHeader())
+
+	// The unit is taken into account when adjusting the width to the header
+	formatter.AdjustWidthsToContent([]*unitStruct{{"foo", 1500}}, true, 0, false)
+	assert.Equal(t, "NAME LATENCY(ns)", formatter.FormatHeader())
+	assert.Equal(t, "foo         1500", formatter.FormatEntry(&unitStruct{"foo", 1500}))
+}
+
 func TestTextColumnsFormatter_FormatRowDivider(t *testing.T) {
 	formatter := NewFormatter(testColumns, WithRowDivider(DividerDash))
 	assert.Equal(t, "————————————————————————————————————————", formatter.FormatRowDivider())
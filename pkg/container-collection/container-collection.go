@@ -161,13 +161,16 @@ func (cc *ContainerCollection) RemoveContainer(id string) {
 	cc.mu.Lock()
 	defer cc.mu.Unlock()
 
-	// Remove from MntNs lookup
-	mntNsContainer, ok := cc.containersByMntNs.Load(container.Mntns)
-	if !ok || mntNsContainer.(*Container).Runtime.ContainerID != container.Runtime.ContainerID {
-		log.Warn("container not found or mismatch in mntns lookup map")
-		return
-	} else {
-		cc.containersByMntNs.Delete(container.Mntns)
+	// Remove from MntNs lookup. Containers running in a virtual machine aren't
+	// there, see AddContainer().
+	if !container.Runtime.VMIsolated {
+		mntNsContainer, ok := cc.containersByMntNs.Load(container.Mntns)
+		if !ok || mntNsContainer.(*Container).Runtime.ContainerID != container.Runtime.ContainerID {
+			log.Warn("container not found or mismatch in mntns lookup map")
+			return
+		} else {
+			cc.containersByMntNs.Delete(container.Mntns)
+		}
 	}
 
 	// Remove from NetNs lookup; arrays should be immutable, so recreate them
@@ -210,12 +213,23 @@ func (cc *ContainerCollection) AddContainer(container *Container) {
 		}
 	}
 
+	// The mount namespace of containers running in a virtual machine is the
+	// one of a process on the host, e.g. the VMM. Don't use it, otherwise the
+	// events of that process would be attributed to the container.
+	if container.Runtime.VMIsolated && container.Mntns != 0 {
+		log.Infof("container %q (%s) runs in a virtual machine: its processes can't be traced from the host",
+			container.Runtime.ContainerName, container.Runtime.ContainerID)
+		container.Mntns = 0
+	}
+
 	_, loaded := cc.containers.LoadOrStore(container.Runtime.ContainerID, container)
 	if loaded {
 		return
 	}
 	cc.mu.Lock()
-	cc.containersByMntNs.Store(container.Mntns, container)
+	if !container.Runtime.VMIsolated {
+		cc.containersByMntNs.Store(container.Mntns, container)
+	}
 	arr, ok := cc.containersByNetNs.Load(container.Netns)
 	var newContainerArr []*Container
 	if ok {
@@ -431,6 +445,13 @@ func (cc *ContainerCollection) EnrichByMntNs(event *eventtypes.CommonData, mount
 		event.Runtime.ContainerID = container.Runtime.ContainerID
 		event.Runtime.ContainerImageName = container.Runtime.ContainerImageName
 		event.Runtime.ContainerImageDigest = container.Runtime.ContainerImageDigest
+		event.Runtime.RuntimeHandler = container.Runtime.RuntimeHandler
+		event.Runtime.UIDMap = container.Runtime.UIDMap
+		event.Runtime.GIDMap = container.Runtime.GIDMap
+		event.Runtime.VMIsolated = container.Runtime.VMIsolated
+		event.Runtime.ContainerAnnotations = container.Runtime.ContainerAnnotations
+		event.Runtime.Hostname = container.Runtime.Hostname
+		event.Runtime.SystemdUnit = container.Runtime.SystemdUnit
 	}
 }
 
@@ -459,6 +480,13 @@ func (cc *ContainerCollection) EnrichByNetNs(event *eventtypes.CommonData, netns
 		event.Runtime.ContainerID = containers[0].Runtime.ContainerID
 		event.Runtime.ContainerImageName = containers[0].Runtime.ContainerImageName
 		event.Runtime.ContainerImageDigest = containers[0].Runtime.ContainerImageDigest
+		event.Runtime.RuntimeHandler = containers[0].Runtime.RuntimeHandler
+		event.Runtime.UIDMap = containers[0].Runtime.UIDMap
+		event.Runtime.GIDMap = containers[0].Runtime.GIDMap
+		event.Runtime.VMIsolated = containers[0].Runtime.VMIsolated
+		event.Runtime.ContainerAnnotations = containers[0].Runtime.ContainerAnnotations
+		event.Runtime.Hostname = containers[0].Runtime.Hostname
+		event.Runtime.SystemdUnit = containers[0].Runtime.SystemdUnit
 		return
 	}
 	if containers[0].K8s.PodName != "" && containers[0].K8s.Namespace != "" {
@@ -468,6 +496,7 @@ func (cc *ContainerCollection) EnrichByNetNs(event *eventtypes.CommonData, netns
 
 		// All containers in the same pod share the same container runtime
 		event.Runtime.RuntimeName = containers[0].Runtime.RuntimeName
+		event.Runtime.VMIsolated = containers[0].Runtime.VMIsolated
 	}
 	// else {
 	// 	TODO: Non-Kubernetes containers sharing the same network namespace.
@@ -241,3 +241,42 @@ func TestWithTracerCollection(t *testing.T) {
 	cc.EnrichByNetNs(&ev, containers[0].Netns)
 	require.Equal(t, expected, ev, "events should be equal")
 }
+
+func TestVMIsolatedContainer(t *testing.T) {
+	t.Parallel()
+
+	cc := ContainerCollection{}
+	container := &Container{
+		Runtime: RuntimeMetadata{
+			BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+				RuntimeName:    types.RuntimeNameCrio,
+				ContainerName:  "kata",
+				ContainerID:    "id1",
+				RuntimeHandler: "kata-qemu",
+				VMIsolated:     true,
+			},
+		},
+		// Mount namespace of the VMM on the host
+		Mntns: 1234,
+		Netns: 5678,
+	}
+	cc.AddContainer(container)
+
+	require.NotNil(t, cc.GetContainer("id1"))
+	require.Zero(t, container.Mntns)
+	require.Nil(t, cc.LookupContainerByMntns(1234))
+
+	ev := types.CommonData{}
+	cc.EnrichByMntNs(&ev, 1234)
+	require.Empty(t, ev.Runtime.ContainerID)
+
+	// The network of the container is still visible from the host
+	cc.EnrichByNetNs(&ev, 5678)
+	require.Equal(t, "id1", ev.Runtime.ContainerID)
+	require.Equal(t, "kata-qemu", ev.Runtime.RuntimeHandler)
+	require.True(t, ev.Runtime.VMIsolated)
+
+	cc.RemoveContainer("id1")
+	require.Nil(t, cc.GetContainer("id1"))
+	require.Empty(t, cc.LookupContainersByNetns(5678))
+}
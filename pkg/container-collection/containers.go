@@ -94,12 +94,19 @@ type K8sMetadata struct {
 	PodLabels              map[string]string `json:"podLabels,omitempty"`
 	PodUID                 string            `json:"podUID,omitempty"`
 
+	// Ephemeral is true for ephemeral containers, e.g. the ones created by
+	// "kubectl debug"
+	Ephemeral bool `json:"ephemeral,omitempty"`
+
 	ownerReference *metav1.OwnerReference
 }
 
 type K8sSelector struct {
 	types.BasicK8sMetadata
 	PodLabels map[string]string
+
+	// EphemeralOnly restricts the selection to ephemeral containers
+	EphemeralOnly bool
 }
 
 type RuntimeSelector struct {
@@ -110,6 +117,11 @@ type RuntimeSelector struct {
 type ContainerSelector struct {
 	K8s     K8sSelector
 	Runtime RuntimeSelector
+
+	// Host makes the selector match the host pseudo-container, see
+	// WithHost(), in addition to the containers matching the other
+	// criteria. Otherwise, it's only matched when selected by name.
+	Host bool
 }
 
 // GetOwnerReference returns the owner reference information of the
@@ -156,6 +156,7 @@ func (k *K8sClient) GetRunningContainers(pod *v1.Pod) []Container {
 					ContainerName: s.Name,
 				},
 				PodLabels: labels,
+				Ephemeral: isEphemeralContainer(pod, s.Name),
 			},
 		}
 		containers = append(containers, containerDef)
@@ -164,6 +165,17 @@ func (k *K8sClient) GetRunningContainers(pod *v1.Pod) []Container {
 	return containers
 }
 
+// isEphemeralContainer returns whether the container with the given name is
+// an ephemeral container of the pod
+func isEphemeralContainer(pod *v1.Pod, name string) bool {
+	for _, c := range pod.Spec.EphemeralContainers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // ListContainers return a list of the current containers that are
 // running in the node.
 func (k *K8sClient) ListContainers() (arr []Container, err error) {
@@ -17,11 +17,16 @@ package containercollection
 import (
 	"slices"
 	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
 // ContainerSelectorMatches tells if a container matches the criteria in a
 // container selector.
 func ContainerSelectorMatches(s *ContainerSelector, c *Container) bool {
+	if c.Runtime.RuntimeName == types.RuntimeNameHost {
+		return s.Host || s.Runtime.ContainerName == c.Runtime.ContainerName
+	}
 	if s.K8s.Namespace != "" && !slices.Contains(strings.Split(s.K8s.Namespace, ","), c.K8s.Namespace) {
 		return false
 	}
@@ -31,6 +36,9 @@ func ContainerSelectorMatches(s *ContainerSelector, c *Container) bool {
 	if s.K8s.ContainerName != "" && s.K8s.ContainerName != c.K8s.ContainerName {
 		return false
 	}
+	if s.K8s.EphemeralOnly && !c.K8s.Ephemeral {
+		return false
+	}
 	if s.Runtime.ContainerName != "" && s.Runtime.ContainerName != c.Runtime.ContainerName {
 		return false
 	}
@@ -170,6 +170,108 @@ func TestSelector(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "Ephemeral only with ephemeral container",
+			match:       true,
+			selector: &ContainerSelector{
+				K8s: K8sSelector{
+					EphemeralOnly: true,
+				},
+			},
+			container: &Container{
+				K8s: K8sMetadata{
+					BasicK8sMetadata: types.BasicK8sMetadata{
+						Namespace:     "this-namespace",
+						PodName:       "this-pod",
+						ContainerName: "debugger-abcde",
+					},
+					Ephemeral: true,
+				},
+			},
+		},
+		{
+			description: "Ephemeral only with regular container",
+			match:       false,
+			selector: &ContainerSelector{
+				K8s: K8sSelector{
+					EphemeralOnly: true,
+				},
+			},
+			container: &Container{
+				K8s: K8sMetadata{
+					BasicK8sMetadata: types.BasicK8sMetadata{
+						Namespace:     "this-namespace",
+						PodName:       "this-pod",
+						ContainerName: "this-container",
+					},
+				},
+			},
+		},
+		{
+			description: "Selector without filter doesn't match the host",
+			match:       false,
+			selector:    &ContainerSelector{},
+			container: &Container{
+				Runtime: RuntimeMetadata{
+					BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+						RuntimeName:   types.RuntimeNameHost,
+						ContainerID:   HostContainerName,
+						ContainerName: HostContainerName,
+					},
+				},
+			},
+		},
+		{
+			description: "Selector with host",
+			match:       true,
+			selector:    &ContainerSelector{Host: true},
+			container: &Container{
+				Runtime: RuntimeMetadata{
+					BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+						RuntimeName:   types.RuntimeNameHost,
+						ContainerID:   HostContainerName,
+						ContainerName: HostContainerName,
+					},
+				},
+			},
+		},
+		{
+			description: "Selector with host and other container name",
+			match:       true,
+			selector: &ContainerSelector{
+				Runtime: RuntimeSelector{
+					ContainerName: "this-container",
+				},
+				Host: true,
+			},
+			container: &Container{
+				Runtime: RuntimeMetadata{
+					BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+						RuntimeName:   types.RuntimeNameHost,
+						ContainerID:   HostContainerName,
+						ContainerName: HostContainerName,
+					},
+				},
+			},
+		},
+		{
+			description: "Selector with host container name",
+			match:       true,
+			selector: &ContainerSelector{
+				Runtime: RuntimeSelector{
+					ContainerName: HostContainerName,
+				},
+			},
+			container: &Container{
+				Runtime: RuntimeMetadata{
+					BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+						RuntimeName:   types.RuntimeNameHost,
+						ContainerID:   HostContainerName,
+						ContainerName: HostContainerName,
+					},
+				},
+			},
+		},
 	}
 
 	for i, entry := range table {
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -26,11 +27,16 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 
@@ -52,6 +58,21 @@ func enrichContainerWithContainerData(containerData *runtimeclient.ContainerData
 	container.Runtime.ContainerName = containerData.Runtime.ContainerName
 	container.Runtime.ContainerImageName = containerData.Runtime.ContainerImageName
 	container.Runtime.ContainerImageDigest = containerData.Runtime.ContainerImageDigest
+	// Only container details include the runtime spec, don't override what
+	// other enrichers found with empty values
+	if containerData.Runtime.RuntimeHandler != "" {
+		container.Runtime.RuntimeHandler = containerData.Runtime.RuntimeHandler
+	}
+	if containerData.Runtime.UIDMap != "" {
+		container.Runtime.UIDMap = containerData.Runtime.UIDMap
+		container.Runtime.GIDMap = containerData.Runtime.GIDMap
+	}
+	if containerData.Runtime.VMIsolated {
+		container.Runtime.VMIsolated = true
+	}
+	if len(containerData.Runtime.ContainerAnnotations) > 0 {
+		container.Runtime.ContainerAnnotations = containerData.Runtime.ContainerAnnotations
+	}
 
 	// Kubernetes
 	container.K8s.Namespace = containerData.K8s.Namespace
@@ -65,6 +86,13 @@ func containerRuntimeEnricher(
 	runtimeClient runtimeclient.ContainerRuntimeClient,
 	container *Container,
 ) bool {
+	// The container was already attributed to another runtime, e.g. one with
+	// a higher priority, see WithMultipleContainerRuntimesEnrichment(). Don't
+	// let this one claim it too.
+	if container.Runtime.RuntimeName != "" && container.Runtime.RuntimeName != runtimeName {
+		return true
+	}
+
 	// If the container is already enriched with the metadata a runtime client
 	// is able to provide, skip it.
 	if runtimeclient.IsEnrichedWithK8sMetadata(container.K8s.BasicK8sMetadata) &&
@@ -103,17 +131,17 @@ func WithDisableContainerRuntimeWarnings() ContainerCollectionOption {
 // WithContainerRuntimeEnrichment() to allow caller to add multiple runtimes in
 // one single call.
 //
+// The order of the runtimes is their priority: a container reported by
+// several runtimes, e.g. a Docker container also visible in the "moby"
+// containerd namespace, is attributed to the first one. A runtime can be
+// listed several times with different socket paths. They are fallbacks: the
+// first socket path where the runtime answers is used.
+//
 // ContainerCollection.Initialize(WithMultipleContainerRuntimesEnrichment([]*RuntimeConfig)...)
 func WithMultipleContainerRuntimesEnrichment(runtimes []*containerutilsTypes.RuntimeConfig) ContainerCollectionOption {
-	var opts []ContainerCollectionOption
-
-	for _, r := range runtimes {
-		opts = append(opts, WithContainerRuntimeEnrichment(r))
-	}
-
 	return func(cc *ContainerCollection) error {
-		for _, o := range opts {
-			err := o(cc)
+		for _, r := range selectRuntimeConfigs(runtimes, probeRuntime) {
+			err := WithContainerRuntimeEnrichment(r)(cc)
 			if err != nil {
 				return err
 			}
@@ -122,6 +150,60 @@ func WithMultipleContainerRuntimesEnrichment(runtimes []*containerutilsTypes.Run
 	}
 }
 
+// probeRuntime checks that a runtime answers on its socket
+func probeRuntime(runtime *containerutilsTypes.RuntimeConfig) error {
+	runtimeClient, err := containerutils.NewContainerRuntimeClient(runtime)
+	if err != nil {
+		return err
+	}
+	defer runtimeClient.Close()
+
+	_, err = runtimeClient.GetContainers()
+	return err
+}
+
+// selectRuntimeConfigs returns a single configuration for each runtime, in the
+// order they appear in runtimes. When a runtime has several configurations, the
+// first one that probe accepts is used, or the first one if none is accepted.
+func selectRuntimeConfigs(runtimes []*containerutilsTypes.RuntimeConfig,
+	probe func(*containerutilsTypes.RuntimeConfig) error,
+) []*containerutilsTypes.RuntimeConfig {
+	selected := []*containerutilsTypes.RuntimeConfig{}
+	seen := map[types.RuntimeName]struct{}{}
+
+	for i, r := range runtimes {
+		if _, ok := seen[r.Name]; ok {
+			continue
+		}
+		seen[r.Name] = struct{}{}
+
+		candidates := []*containerutilsTypes.RuntimeConfig{}
+		for _, c := range runtimes[i:] {
+			if c.Name == r.Name {
+				candidates = append(candidates, c)
+			}
+		}
+		if len(candidates) == 1 {
+			selected = append(selected, r)
+			continue
+		}
+
+		chosen := r
+		for _, c := range candidates {
+			if err := probe(c); err != nil {
+				log.Debugf("Runtime enricher (%s): skipping socket path %q: %s", c.Name, c.SocketPath, err)
+				continue
+			}
+			chosen = c
+			break
+		}
+		log.Debugf("Runtime enricher (%s): using socket path %q", chosen.Name, chosen.SocketPath)
+		selected = append(selected, chosen)
+	}
+
+	return selected
+}
+
 // WithContainerRuntimeEnrichment automatically adds the container name using
 // the requested container runtime.
 //
@@ -149,6 +231,10 @@ func WithContainerRuntimeEnrichment(runtime *containerutilsTypes.RuntimeConfig)
 			// As a consequence, we need to ensure that new podman containers will be enriched with all
 			// the information via other enrichers e.g. see RuncNotifier.futureContainers implementation
 			// to see how container name is enriched.
+		case types.RuntimeNameLXC, types.RuntimeNameSystemdNspawn:
+			// These containers aren't created through an OCI runtime, so
+			// other mechanisms never find them. Poll them instead.
+			cc.pollContainers(runtime.Name, runtimeClient)
 		default:
 			// Add the enricher for future containers even if enriching the current
 			// containers fails. We do it because the runtime could be temporarily
@@ -175,33 +261,43 @@ func WithContainerRuntimeEnrichment(runtime *containerutilsTypes.RuntimeConfig)
 			return nil
 		}
 		for _, container := range containers {
-			if container.Runtime.State != runtimeclient.StateRunning {
-				log.Debugf("Runtime enricher(%s): Skip container %q (ID: %s): not running",
-					runtime.Name, container.Runtime.ContainerName, container.Runtime.ContainerID)
-				continue
+			if c := containerFromRuntime(runtime.Name, runtimeClient, container); c != nil {
+				cc.initialContainers = append(cc.initialContainers, c)
 			}
+		}
 
-			containerDetails, err := runtimeClient.GetContainerDetails(container.Runtime.ContainerID)
-			if err != nil {
-				log.Debugf("Runtime enricher (%s): Skip container %q (ID: %s): couldn't find container: %s",
-					runtime.Name, container.Runtime.ContainerName, container.Runtime.ContainerID, err)
-				continue
-			}
+		return nil
+	}
+}
 
-			pid := containerDetails.Pid
-			if pid > math.MaxUint32 {
-				log.Errorf("Container PID (%d) exceeds math.MaxUint32 (%d), skipping this container", pid, math.MaxUint32)
-				continue
-			}
+// containerFromRuntime returns the container to add to the collection for a
+// container returned by a runtime client, or nil if it can't be added
+func containerFromRuntime(runtimeName types.RuntimeName, runtimeClient runtimeclient.ContainerRuntimeClient,
+	container *runtimeclient.ContainerData,
+) *Container {
+	if container.Runtime.State != runtimeclient.StateRunning {
+		log.Debugf("Runtime enricher(%s): Skip container %q (ID: %s): not running",
+			runtimeName, container.Runtime.ContainerName, container.Runtime.ContainerID)
+		return nil
+	}
 
-			var c Container
-			c.Pid = uint32(pid)
-			enrichContainerWithContainerData(&containerDetails.ContainerData, &c)
-			cc.initialContainers = append(cc.initialContainers, &c)
-		}
+	containerDetails, err := runtimeClient.GetContainerDetails(container.Runtime.ContainerID)
+	if err != nil {
+		log.Debugf("Runtime enricher (%s): Skip container %q (ID: %s): couldn't find container: %s",
+			runtimeName, container.Runtime.ContainerName, container.Runtime.ContainerID, err)
+		return nil
+	}
 
+	pid := containerDetails.Pid
+	if pid > math.MaxUint32 {
+		log.Errorf("Container PID (%d) exceeds math.MaxUint32 (%d), skipping this container", pid, math.MaxUint32)
 		return nil
 	}
+
+	var c Container
+	c.Pid = uint32(pid)
+	enrichContainerWithContainerData(&containerDetails.ContainerData, &c)
+	return &c
 }
 
 // WithPodInformer uses a pod informer to get both initial containers and the
@@ -312,19 +408,60 @@ func withPodInformer(nodeName string, fallbackMode bool) ContainerCollectionOpti
 	}
 }
 
-// WithHost adds the host as a virtual/fake container; TODO: Just for testing
+// HostContainerName is the name, and ID, of the pseudo-container representing
+// the processes of the host
+const HostContainerName = "host"
+
+// WithHost adds the host as a pseudo-container, so its processes can be
+// selected and their events enriched the same way as the ones of containers.
+// It's only matched by the selectors asking for it explicitly, see
+// ContainerSelector.Host.
 func WithHost() ContainerCollectionOption {
 	return func(cc *ContainerCollection) error {
 		newContainer := Container{}
-		newContainer.K8s.ContainerName = "host"
-		newContainer.CgroupID = 1
+		newContainer.Runtime.RuntimeName = types.RuntimeNameHost
+		newContainer.Runtime.ContainerID = HostContainerName
+		newContainer.Runtime.ContainerName = HostContainerName
+		newContainer.Runtime.Hostname = hostHostname()
 		newContainer.Pid = 1
 		newContainer.HostNetwork = true
+
+		cgroupPathV1, cgroupPathV2, err := cgroups.GetCgroupPaths(1)
+		if err != nil {
+			log.Debugf("host enricher: failed to get cgroup paths of the init process: %s", err)
+		} else if cgroupPathV2 != "" {
+			newContainer.Runtime.SystemdUnit = systemdUnit(cgroupPathV2)
+		} else {
+			newContainer.Runtime.SystemdUnit = systemdUnit(cgroupPathV1)
+		}
+
 		cc.initialContainers = append(cc.initialContainers, &newContainer)
 		return nil
 	}
 }
 
+// hostHostname returns the hostname of the host, which can differ from the one
+// of the current UTS namespace when running in a container
+func hostHostname() string {
+	if hostname, err := os.ReadFile(filepath.Join(host.HostRoot, "/etc/hostname")); err == nil {
+		if hostname := strings.TrimSpace(string(hostname)); hostname != "" {
+			return hostname
+		}
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// systemdUnit returns the systemd unit a cgroup path belongs to, e.g.
+// "sshd.service" for /system.slice/sshd.service
+func systemdUnit(cgroupPath string) string {
+	unit := filepath.Base(cgroupPath)
+	if strings.HasSuffix(unit, ".service") || strings.HasSuffix(unit, ".scope") {
+		return unit
+	}
+	return ""
+}
+
 // WithInitialKubernetesContainers gets initial containers from the Kubernetes
 // API with the process ID from CRI.
 //
@@ -454,88 +591,136 @@ func WithKubernetesEnrichment(nodeName string, kubeconfig *rest.Config) Containe
 			return fmt.Errorf("getting Kubernetes client: %w", err)
 		}
 
-		// Future containers
-		cc.containerEnrichers = append(cc.containerEnrichers, func(container *Container) bool {
-			if container.K8s.PodName != "" {
-				return true
+		// Keep the pods of this node in a cache instead of querying the API
+		// server for each new container
+		factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
+			}),
+		)
+		lister := factory.Core().V1().Pods().Lister()
+		stop := make(chan struct{})
+		factory.Start(stop)
+		cc.cleanUpFuncs = append(cc.cleanUpFuncs, func() {
+			close(stop)
+		})
+		for _, ok := range factory.WaitForCacheSync(stop) {
+			if !ok {
+				return errors.New("syncing pods cache")
 			}
+		}
 
-			if container.CgroupV1 == "" && container.CgroupV2 == "" {
-				log.Errorf("kubernetes enricher: cannot work without cgroup paths")
-				return true
-			}
+		// Future containers
+		cc.containerEnrichers = append(cc.containerEnrichers, newKubernetesEnricher(clientset, lister))
+		return nil
+	}
+}
 
-			fieldSelector := fields.OneTermEqualSelector("spec.nodeName", nodeName).String()
-			pods, err := clientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-				FieldSelector: fieldSelector,
-			})
+// getPod returns the pod from the cache of the pods of this node. Pods that
+// aren't in the cache yet are fetched from the API server.
+func getPod(clientset kubernetes.Interface, lister corelisters.PodLister, namespace, name string) (*v1.Pod, error) {
+	pod, err := lister.Pods(namespace).Get(name)
+	if err == nil {
+		return pod, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return nil, err
+	}
+	return clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+// newKubernetesEnricher returns an enricher adding the metadata of the pods
+// in lister to the containers
+func newKubernetesEnricher(clientset kubernetes.Interface, lister corelisters.PodLister) func(container *Container) bool {
+	return func(container *Container) bool {
+		if container.K8s.PodName != "" {
+			// Other enrichers already found the pod, but they can't tell
+			// whether it's an ephemeral container.
+			pod, err := getPod(clientset, lister, container.K8s.Namespace, container.K8s.PodName)
 			if err != nil {
-				log.Errorf("kubernetes enricher: cannot fetch pods: %s", err)
+				log.Debugf("kubernetes enricher: cannot get pod %s/%s: %s",
+					container.K8s.Namespace, container.K8s.PodName, err)
 				return true
 			}
+			container.K8s.Ephemeral = isEphemeralContainer(pod, container.K8s.ContainerName)
+			return true
+		}
 
-			// Fill Kubernetes fields
-			namespace := ""
-			podname := ""
-			podUID := ""
-			containerName := ""
-			labels := make(map[string]string)
-			for _, pod := range pods.Items {
-				uid := string(pod.ObjectMeta.UID)
-				// check if this container is associated to this pod
-				uidWithUnderscores := strings.ReplaceAll(uid, "-", "_")
-
-				if !strings.Contains(container.CgroupV2, uidWithUnderscores) &&
-					!strings.Contains(container.CgroupV2, uid) &&
-					!strings.Contains(container.CgroupV1, uidWithUnderscores) &&
-					!strings.Contains(container.CgroupV1, uid) {
-					continue
-				}
+		if container.CgroupV1 == "" && container.CgroupV2 == "" {
+			log.Errorf("kubernetes enricher: cannot work without cgroup paths")
+			return true
+		}
 
-				namespace = pod.ObjectMeta.Namespace
-				podname = pod.ObjectMeta.Name
-				podUID = uid
+		// The lister only contains the pods of this node
+		pods, err := lister.List(labels.Everything())
+		if err != nil {
+			log.Errorf("kubernetes enricher: cannot fetch pods: %s", err)
+			return true
+		}
 
-				for k, v := range pod.ObjectMeta.Labels {
-					labels[k] = v
-				}
+		// Fill Kubernetes fields
+		namespace := ""
+		podname := ""
+		podUID := ""
+		containerName := ""
+		ephemeral := false
+		podLabels := make(map[string]string)
+		for _, pod := range pods {
+			uid := string(pod.ObjectMeta.UID)
+			// check if this container is associated to this pod
+			uidWithUnderscores := strings.ReplaceAll(uid, "-", "_")
+
+			if !strings.Contains(container.CgroupV2, uidWithUnderscores) &&
+				!strings.Contains(container.CgroupV2, uid) &&
+				!strings.Contains(container.CgroupV1, uidWithUnderscores) &&
+				!strings.Contains(container.CgroupV1, uid) {
+				continue
+			}
 
-				containerNames := []string{}
-				for _, c := range pod.Spec.Containers {
-					containerNames = append(containerNames, c.Name)
-				}
-				for _, c := range pod.Spec.InitContainers {
-					containerNames = append(containerNames, c.Name)
-				}
-				for _, c := range pod.Spec.EphemeralContainers {
-					containerNames = append(containerNames, c.Name)
-				}
-			outerLoop:
-				for _, name := range containerNames {
-					for _, m := range container.OciConfig.Mounts {
-						pattern := fmt.Sprintf("pods/%s/containers/%s/", uid, name)
-						if strings.Contains(m.Source, pattern) {
-							containerName = name
-							break outerLoop
-						}
+			namespace = pod.ObjectMeta.Namespace
+			podname = pod.ObjectMeta.Name
+			podUID = uid
+
+			for k, v := range pod.ObjectMeta.Labels {
+				podLabels[k] = v
+			}
+
+			containerNames := []string{}
+			for _, c := range pod.Spec.Containers {
+				containerNames = append(containerNames, c.Name)
+			}
+			for _, c := range pod.Spec.InitContainers {
+				containerNames = append(containerNames, c.Name)
+			}
+			for _, c := range pod.Spec.EphemeralContainers {
+				containerNames = append(containerNames, c.Name)
+			}
+		outerLoop:
+			for _, name := range containerNames {
+				for _, m := range container.OciConfig.Mounts {
+					pattern := fmt.Sprintf("pods/%s/containers/%s/", uid, name)
+					if strings.Contains(m.Source, pattern) {
+						containerName = name
+						ephemeral = isEphemeralContainer(pod, name)
+						break outerLoop
 					}
 				}
 			}
+		}
 
-			container.K8s.Namespace = namespace
-			container.K8s.PodName = podname
-			container.K8s.PodUID = podUID
-			container.K8s.ContainerName = containerName
-			container.K8s.PodLabels = labels
+		container.K8s.Namespace = namespace
+		container.K8s.PodName = podname
+		container.K8s.PodUID = podUID
+		container.K8s.ContainerName = containerName
+		container.K8s.PodLabels = podLabels
+		container.K8s.Ephemeral = ephemeral
 
-			// drop pause containers
-			if container.K8s.PodName != "" && containerName == "" {
-				return false
-			}
+		// drop pause containers
+		if container.K8s.PodName != "" && containerName == "" {
+			return false
+		}
 
-			return true
-		})
-		return nil
+		return true
 	}
 }
 
@@ -716,7 +901,17 @@ func isEnrichedWithOCIConfigInfo(container *Container) bool {
 func WithOCIConfigEnrichment() ContainerCollectionOption {
 	return func(cc *ContainerCollection) error {
 		cc.containerEnrichers = append(cc.containerEnrichers, func(container *Container) bool {
-			if container.OciConfig == nil || isEnrichedWithOCIConfigInfo(container) {
+			if container.OciConfig == nil {
+				return true
+			}
+
+			// User namespaces don't depend on the runtime
+			if linux := container.OciConfig.Linux; linux != nil && container.Runtime.UIDMap == "" {
+				container.Runtime.UIDMap = runtimeclient.IDMappingsToString(linux.UIDMappings)
+				container.Runtime.GIDMap = runtimeclient.IDMappingsToString(linux.GIDMappings)
+			}
+
+			if isEnrichedWithOCIConfigInfo(container) {
 				return true
 			}
 
@@ -754,6 +949,13 @@ func WithOCIConfigEnrichment() ContainerCollectionOption {
 			if imageName := resolver.ContainerImageName(container.OciConfig.Annotations); imageName != "" {
 				container.Runtime.ContainerImageName = imageName
 			}
+			if handler := resolver.RuntimeHandler(container.OciConfig.Annotations); handler != "" {
+				container.Runtime.RuntimeHandler = handler
+				container.Runtime.VMIsolated = runtimeclient.IsVMRuntime(handler)
+			}
+			if annotations := resolver.ContainerAnnotations(container.OciConfig.Annotations); len(annotations) > 0 {
+				container.Runtime.ContainerAnnotations = annotations
+			}
 
 			return true
 		})
@@ -15,9 +15,21 @@
 package containercollection
 
 import (
+	"errors"
 	"testing"
 
+	ocispec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	containerutilsTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
 func TestGetExpectedOwnerReference(t *testing.T) {
@@ -268,3 +280,147 @@ func TestGetExpectedOwnerReference(t *testing.T) {
 		}
 	}
 }
+
+func TestSystemdUnit(t *testing.T) {
+	table := []struct {
+		cgroupPath string
+		expected   string
+	}{
+		{cgroupPath: "/init.scope", expected: "init.scope"},
+		{cgroupPath: "/system.slice/sshd.service", expected: "sshd.service"},
+		{cgroupPath: "/user.slice/user-1000.slice/session-2.scope", expected: "session-2.scope"},
+		{cgroupPath: "/user.slice", expected: ""},
+		{cgroupPath: "/", expected: ""},
+	}
+
+	for _, entry := range table {
+		if unit := systemdUnit(entry.cgroupPath); unit != entry.expected {
+			t.Fatalf("Failed test %q: result %q expected %q", entry.cgroupPath, unit, entry.expected)
+		}
+	}
+}
+
+func TestSelectRuntimeConfigs(t *testing.T) {
+	t.Parallel()
+
+	docker := &containerutilsTypes.RuntimeConfig{Name: types.RuntimeNameDocker, SocketPath: "/run/docker.sock"}
+	k3s := &containerutilsTypes.RuntimeConfig{Name: types.RuntimeNameContainerd, SocketPath: "/run/k3s/containerd/containerd.sock"}
+	containerd := &containerutilsTypes.RuntimeConfig{Name: types.RuntimeNameContainerd, SocketPath: "/run/containerd/containerd.sock"}
+	crio := &containerutilsTypes.RuntimeConfig{Name: types.RuntimeNameCrio, SocketPath: "/run/crio/crio.sock"}
+
+	type testDefinition struct {
+		runtimes  []*containerutilsTypes.RuntimeConfig
+		available []string
+		expected  []*containerutilsTypes.RuntimeConfig
+	}
+
+	tests := map[string]testDefinition{
+		"no_fallback": {
+			runtimes: []*containerutilsTypes.RuntimeConfig{crio, docker},
+			// Runtimes without fallbacks are used even if they aren't available
+			expected: []*containerutilsTypes.RuntimeConfig{crio, docker},
+		},
+		"first_fallback_available": {
+			runtimes:  []*containerutilsTypes.RuntimeConfig{k3s, docker, containerd},
+			available: []string{k3s.SocketPath, containerd.SocketPath},
+			expected:  []*containerutilsTypes.RuntimeConfig{k3s, docker},
+		},
+		"second_fallback_available": {
+			runtimes:  []*containerutilsTypes.RuntimeConfig{docker, k3s, containerd},
+			available: []string{containerd.SocketPath},
+			expected:  []*containerutilsTypes.RuntimeConfig{docker, containerd},
+		},
+		"no_fallback_available": {
+			runtimes: []*containerutilsTypes.RuntimeConfig{k3s, containerd},
+			expected: []*containerutilsTypes.RuntimeConfig{k3s},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			probe := func(r *containerutilsTypes.RuntimeConfig) error {
+				for _, socketPath := range test.available {
+					if r.SocketPath == socketPath {
+						return nil
+					}
+				}
+				return errors.New("not available")
+			}
+			require.Equal(t, test.expected, selectRuntimeConfigs(test.runtimes, probe))
+		})
+	}
+}
+
+func TestContainerRuntimeEnricherPriority(t *testing.T) {
+	t.Parallel()
+
+	// A Docker container is also visible in the "moby" containerd namespace
+	dockerClient := &fakeRuntimeClient{runtimeName: types.RuntimeNameDocker, pids: map[string]int{"abc": 100}}
+	containerdClient := &fakeRuntimeClient{runtimeName: types.RuntimeNameContainerd, pids: map[string]int{"abc": 100}}
+
+	container := &Container{}
+	container.Runtime.ContainerID = "abc"
+
+	require.True(t, containerRuntimeEnricher(types.RuntimeNameDocker, dockerClient, container))
+	require.Equal(t, types.RuntimeNameDocker, container.Runtime.RuntimeName)
+
+	require.True(t, containerRuntimeEnricher(types.RuntimeNameContainerd, containerdClient, container))
+	require.Equal(t, types.RuntimeNameDocker, container.Runtime.RuntimeName)
+}
+
+func TestKubernetesEnricher(t *testing.T) {
+	t.Parallel()
+
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "mypod",
+			UID:       "1234-5678",
+			Labels:    map[string]string{"app": "web"},
+		},
+		Spec: v1.PodSpec{
+			Containers:          []v1.Container{{Name: "app"}},
+			EphemeralContainers: []v1.EphemeralContainer{{EphemeralContainerCommon: v1.EphemeralContainerCommon{Name: "debugger"}}},
+		},
+	}
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	require.NoError(t, indexer.Add(pod))
+
+	// The API server is only queried for pods that aren't in the cache
+	gets := 0
+	clientset := fake.NewSimpleClientset(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "newpod"}})
+	clientset.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		gets++
+		return false, nil, nil
+	})
+	enrich := newKubernetesEnricher(clientset, corelisters.NewPodLister(indexer))
+
+	// Found by its cgroup and the mounts of the container
+	container := &Container{
+		CgroupV2:  "/kubepods/pod1234-5678/abc",
+		OciConfig: &ocispec.Spec{Mounts: []ocispec.Mount{{Source: "/var/lib/kubelet/pods/1234-5678/containers/debugger/0"}}},
+	}
+	require.True(t, enrich(container))
+	require.Equal(t, "mypod", container.K8s.PodName)
+	require.Equal(t, "debugger", container.K8s.ContainerName)
+	require.Equal(t, map[string]string{"app": "web"}, container.K8s.PodLabels)
+	require.True(t, container.K8s.Ephemeral)
+
+	// Already found by other enrichers
+	container = &Container{}
+	container.K8s.Namespace = "default"
+	container.K8s.PodName = "mypod"
+	container.K8s.ContainerName = "app"
+	require.True(t, enrich(container))
+	require.False(t, container.K8s.Ephemeral)
+	require.Zero(t, gets)
+
+	container = &Container{}
+	container.K8s.Namespace = "default"
+	container.K8s.PodName = "newpod"
+	require.True(t, enrich(container))
+	require.Equal(t, 1, gets)
+}
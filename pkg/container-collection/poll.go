@@ -0,0 +1,81 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containercollection
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// pollInterval is how often the containers of runtimes without notifications
+// are listed
+const pollInterval = 2 * time.Second
+
+// pollContainers periodically lists the containers of a runtime that can't
+// notify about new containers, like LXC, and adds or removes them from the
+// collection accordingly
+func (cc *ContainerCollection) pollContainers(runtimeName types.RuntimeName, runtimeClient runtimeclient.ContainerRuntimeClient) {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				cc.syncContainers(runtimeName, runtimeClient)
+			case <-cc.done:
+				return
+			}
+		}
+	}()
+}
+
+// syncContainers makes the containers of the collection belonging to
+// runtimeName match the ones the runtime reports
+func (cc *ContainerCollection) syncContainers(runtimeName types.RuntimeName, runtimeClient runtimeclient.ContainerRuntimeClient) {
+	containers, err := runtimeClient.GetContainers()
+	if err != nil {
+		log.Debugf("Runtime enricher (%s): couldn't get current containers: %s", runtimeName, err)
+		return
+	}
+
+	current := make(map[string]struct{}, len(containers))
+	for _, container := range containers {
+		current[container.Runtime.ContainerID] = struct{}{}
+		if cc.GetContainer(container.Runtime.ContainerID) != nil {
+			continue
+		}
+		if c := containerFromRuntime(runtimeName, runtimeClient, container); c != nil {
+			cc.AddContainer(c)
+		}
+	}
+
+	removed := []string{}
+	cc.ContainerRange(func(c *Container) {
+		if c.Runtime.RuntimeName != runtimeName {
+			return
+		}
+		if _, ok := current[c.Runtime.ContainerID]; !ok {
+			removed = append(removed, c.Runtime.ContainerID)
+		}
+	})
+	for _, id := range removed {
+		cc.RemoveContainer(id)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package containercollection
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// fakeRuntimeClient reports the containers in pids, indexed by ID
+type fakeRuntimeClient struct {
+	runtimeName types.RuntimeName
+	pids        map[string]int
+}
+
+func (f *fakeRuntimeClient) containerData(id string) *runtimeclient.ContainerData {
+	return &runtimeclient.ContainerData{
+		Runtime: runtimeclient.RuntimeContainerData{
+			BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+				ContainerID:   id,
+				ContainerName: id,
+				RuntimeName:   f.runtimeName,
+			},
+			State: runtimeclient.StateRunning,
+		},
+	}
+}
+
+func (f *fakeRuntimeClient) GetContainers() ([]*runtimeclient.ContainerData, error) {
+	ret := []*runtimeclient.ContainerData{}
+	for id := range f.pids {
+		ret = append(ret, f.containerData(id))
+	}
+	return ret, nil
+}
+
+func (f *fakeRuntimeClient) GetContainer(containerID string) (*runtimeclient.ContainerData, error) {
+	if _, ok := f.pids[containerID]; !ok {
+		return nil, fmt.Errorf("container %q not found", containerID)
+	}
+	return f.containerData(containerID), nil
+}
+
+func (f *fakeRuntimeClient) GetContainerDetails(containerID string) (*runtimeclient.ContainerDetailsData, error) {
+	pid, ok := f.pids[containerID]
+	if !ok {
+		return nil, fmt.Errorf("container %q not found", containerID)
+	}
+	return &runtimeclient.ContainerDetailsData{
+		ContainerData: *f.containerData(containerID),
+		Pid:           pid,
+	}, nil
+}
+
+func (f *fakeRuntimeClient) Close() error {
+	return nil
+}
+
+func TestSyncContainers(t *testing.T) {
+	t.Parallel()
+
+	cc := ContainerCollection{}
+	// Containers of other runtimes must be left alone
+	cc.AddContainer(&Container{
+		Runtime: RuntimeMetadata{
+			BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+				RuntimeName: types.RuntimeNameDocker,
+				ContainerID: "docker1",
+			},
+		},
+	})
+
+	client := &fakeRuntimeClient{
+		runtimeName: types.RuntimeNameLXC,
+		pids: map[string]int{
+			"web": 101,
+			"db":  201,
+		},
+	}
+
+	cc.syncContainers(types.RuntimeNameLXC, client)
+	require.NotNil(t, cc.GetContainer("docker1"))
+	web := cc.GetContainer("web")
+	require.NotNil(t, web)
+	require.Equal(t, uint32(101), web.Pid)
+	require.Equal(t, types.RuntimeNameLXC, web.Runtime.RuntimeName)
+	require.NotNil(t, cc.GetContainer("db"))
+
+	delete(client.pids, "db")
+	client.pids["cache"] = 301
+
+	cc.syncContainers(types.RuntimeNameLXC, client)
+	require.NotNil(t, cc.GetContainer("docker1"))
+	require.Equal(t, web, cc.GetContainer("web"))
+	require.Nil(t, cc.GetContainer("db"))
+	require.NotNil(t, cc.GetContainer("cache"))
+}
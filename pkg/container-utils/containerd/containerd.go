@@ -17,10 +17,12 @@ package containerd
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/containerd/containerd"
 	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/identifiers"
 	"github.com/containerd/containerd/namespaces"
 	"github.com/containerd/containerd/pkg/cri/constants"
 	"github.com/sirupsen/logrus"
@@ -44,16 +46,29 @@ var log = logrus.WithField("container-client", "containerd")
 
 type ContainerdClient struct {
 	client *containerd.Client
-	ctx    context.Context
+	// ctxs contains a context for each of the containerd namespaces to use
+	ctxs []context.Context
 }
 
+// NewContainerdClient returns a client for the containerd instance listening
+// on socketPath. The containers of all the namespaces in config.Namespace are
+// handled, the k8s.io namespace is used if it's not set.
 func NewContainerdClient(socketPath string, config *containerutilsTypes.ExtraConfig) (runtimeclient.ContainerRuntimeClient, error) {
 	if socketPath == "" {
 		socketPath = runtimeclient.ContainerdDefaultSocketPath
 	}
-	namespace := constants.K8sContainerdNamespace
+	namespaceList := constants.K8sContainerdNamespace
 	if config != nil && config.Namespace != "" {
-		namespace = config.Namespace
+		namespaceList = config.Namespace
+	}
+
+	var ctxs []context.Context
+	for _, namespace := range strings.Split(namespaceList, ",") {
+		namespace = strings.TrimSpace(namespace)
+		if err := identifiers.Validate(namespace); err != nil {
+			return nil, fmt.Errorf("invalid containerd namespace %q: %w", namespace, err)
+		}
+		ctxs = append(ctxs, namespaces.WithNamespace(context.TODO(), namespace))
 	}
 
 	dialCtx, cancelFunc := context.WithTimeout(context.TODO(), DefaultTimeout)
@@ -72,14 +87,9 @@ func NewContainerdClient(socketPath string, config *containerutilsTypes.ExtraCon
 		return nil, err
 	}
 
-	containerdCtx := namespaces.WithNamespace(
-		context.TODO(),
-		namespace,
-	)
-
 	return &ContainerdClient{
 		client: client,
-		ctx:    containerdCtx,
+		ctxs:   ctxs,
 	}, nil
 }
 
@@ -91,25 +101,39 @@ func (c *ContainerdClient) Close() error {
 }
 
 func (c *ContainerdClient) GetContainers() ([]*runtimeclient.ContainerData, error) {
-	containers, err := c.client.Containers(c.ctx)
+	ret := make([]*runtimeclient.ContainerData, 0)
+	for _, ctx := range c.ctxs {
+		containers, err := c.getContainers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, containers...)
+	}
+	return ret, nil
+}
+
+// getContainers returns the containers of the namespace of ctx
+func (c *ContainerdClient) getContainers(ctx context.Context) ([]*runtimeclient.ContainerData, error) {
+	containers, err := c.client.Containers(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("listing containers: %w", err)
+		namespace, _ := namespaces.Namespace(ctx)
+		return nil, fmt.Errorf("listing containers of namespace %q: %w", namespace, err)
 	}
 
 	ret := make([]*runtimeclient.ContainerData, 0, len(containers))
 	for _, container := range containers {
-		if c.isSandboxContainer(container) {
+		if c.isSandboxContainer(ctx, container) {
 			log.Debugf("container %q is a sandbox container. Temporary skipping it", container.ID())
 			continue
 		}
 
-		task, err := c.getContainerTask(container)
+		task, err := c.getContainerTask(ctx, container)
 		if err != nil {
 			log.Debugf("getting containerTask for container %q: %s", container.ID(), err)
 			continue
 		}
 
-		containerData, err := c.taskAndContainerToContainerData(task, container)
+		containerData, err := c.taskAndContainerToContainerData(ctx, task, container)
 		if err != nil {
 			log.Debugf("creating containerData for container %q: %s", container.ID(), err)
 			continue
@@ -122,12 +146,12 @@ func (c *ContainerdClient) GetContainers() ([]*runtimeclient.ContainerData, erro
 }
 
 func (c *ContainerdClient) GetContainer(containerID string) (*runtimeclient.ContainerData, error) {
-	container, err := c.getContainer(containerID)
+	ctx, container, err := c.getContainer(containerID)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.buildContainerData(container, nil)
+	return c.buildContainerData(ctx, container, nil)
 }
 
 func (c *ContainerdClient) GetContainerDetails(containerID string) (*runtimeclient.ContainerDetailsData, error) {
@@ -136,7 +160,7 @@ func (c *ContainerdClient) GetContainerDetails(containerID string) (*runtimeclie
 		return nil, err
 	}
 
-	containerData, container, task, err := c.getContainerDataAndContainerAndTask(containerID)
+	ctx, containerData, container, task, err := c.getContainerDataAndContainerAndTask(containerID)
 	if err != nil {
 		return nil, err
 	}
@@ -144,7 +168,7 @@ func (c *ContainerdClient) GetContainerDetails(containerID string) (*runtimeclie
 		return nil, fmt.Errorf("got zero pid")
 	}
 
-	spec, err := container.Spec(c.ctx)
+	spec, err := container.Spec(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("getting spec for container %q: %w", containerID, err)
 	}
@@ -166,39 +190,47 @@ func (c *ContainerdClient) GetContainerDetails(containerID string) (*runtimeclie
 	}, nil
 }
 
-func (c *ContainerdClient) getContainerDataAndContainerAndTask(containerID string) (*runtimeclient.ContainerData, containerd.Container, *containerTask, error) {
-	container, err := c.getContainer(containerID)
+func (c *ContainerdClient) getContainerDataAndContainerAndTask(containerID string) (context.Context, *runtimeclient.ContainerData, containerd.Container, *containerTask, error) {
+	ctx, container, err := c.getContainer(containerID)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	task, err := c.getContainerTask(container)
+	task, err := c.getContainerTask(ctx, container)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	containerData, err := c.taskAndContainerToContainerData(task, container)
+	containerData, err := c.taskAndContainerToContainerData(ctx, task, container)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	return containerData, container, task, nil
+	return ctx, containerData, container, task, nil
 }
 
 // getContainer returns the corresponding container.Container instance to
-// the given id
-func (c *ContainerdClient) getContainer(id string) (containerd.Container, error) {
-	container, err := c.client.LoadContainer(c.ctx, id)
+// the given id, together with the context of its namespace
+func (c *ContainerdClient) getContainer(id string) (context.Context, containerd.Container, error) {
+	var container containerd.Container
+	var ctx context.Context
+	var err error
+	for _, ctx = range c.ctxs {
+		container, err = c.client.LoadContainer(ctx, id)
+		if err == nil || !errdefs.IsNotFound(err) {
+			break
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("loading container with id %q: %w", id, err)
+		return nil, nil, fmt.Errorf("loading container with id %q: %w", id, err)
 	}
 
-	if c.isSandboxContainer(container) {
+	if c.isSandboxContainer(ctx, container) {
 		log.Debugf("container %q is a sandbox container. Temporary skipping it", container.ID())
-		return nil, runtimeclient.ErrPauseContainer
+		return nil, nil, runtimeclient.ErrPauseContainer
 	}
 
-	return container, nil
+	return ctx, container, nil
 }
 
 // containerTask represents the task information for a given container.
@@ -210,8 +242,8 @@ type containerTask struct {
 // getContainerTask returns the containerTask information for a given container.
 // If the container is not running yet, it returns a containerTask with status
 // StateCreated and pid 0.
-func (c *ContainerdClient) getContainerTask(container containerd.Container) (*containerTask, error) {
-	task, err := container.Task(c.ctx, nil)
+func (c *ContainerdClient) getContainerTask(ctx context.Context, container containerd.Container) (*containerTask, error) {
+	task, err := container.Task(ctx, nil)
 	if err != nil {
 		// According to nerdctl, if there is no task, we can assume the
 		// container was just created but it is not running yet:
@@ -229,7 +261,7 @@ func (c *ContainerdClient) getContainerTask(container containerd.Container) (*co
 		return t, nil
 	}
 
-	containerdStatus, err := task.Status(c.ctx)
+	containerdStatus, err := task.Status(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("getting status of task for container %q: %w", container.ID(), err)
 	}
@@ -242,13 +274,13 @@ func (c *ContainerdClient) getContainerTask(container containerd.Container) (*co
 
 // Constructs a ContainerData from a containerTask and containerd.Container
 // The extra containerd.Container parameter saves an additional call to the API
-func (c *ContainerdClient) taskAndContainerToContainerData(task *containerTask, container containerd.Container) (*runtimeclient.ContainerData, error) {
-	return c.buildContainerData(container, task)
+func (c *ContainerdClient) taskAndContainerToContainerData(ctx context.Context, task *containerTask, container containerd.Container) (*runtimeclient.ContainerData, error) {
+	return c.buildContainerData(ctx, container, task)
 }
 
 // Checks if the K8s Label for the Containerkind equals to sandbox
-func (c *ContainerdClient) isSandboxContainer(container containerd.Container) bool {
-	labels, err := container.Labels(c.ctx)
+func (c *ContainerdClient) isSandboxContainer(ctx context.Context, container containerd.Container) bool {
+	labels, err := container.Labels(ctx)
 	if err != nil {
 		return false
 	}
@@ -288,17 +320,22 @@ func getContainerName(container containerd.Container, labels map[string]string)
 // buildContainerData  retrieves and sets basic runtime metadata for a given container,
 // including its ID, name, runtime name, image name, and image digest. It also retrieves and sets
 // the container's state (which is set to "Running" by default, unless a container task is provided).
-func (c *ContainerdClient) buildContainerData(container containerd.Container, task *containerTask) (*runtimeclient.ContainerData, error) {
-	labels, err := container.Labels(c.ctx)
+func (c *ContainerdClient) buildContainerData(ctx context.Context, container containerd.Container, task *containerTask) (*runtimeclient.ContainerData, error) {
+	labels, err := container.Labels(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("listing labels of container %q: %w", container.ID(), err)
 	}
 
-	image, err := container.Image(c.ctx)
+	image, err := container.Image(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("getting image of container %q: %w", container.ID(), err)
 	}
 
+	info, err := container.Info(ctx, containerd.WithoutRefreshedMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("getting info of container %q: %w", container.ID(), err)
+	}
+
 	// When `task` is nil, state is getting set to `Running` for the following reasons:
 	// 1. `buildContainerData` is called by `GetContainer`, which is only getting called on
 	//    new created containers
@@ -317,6 +354,7 @@ func (c *ContainerdClient) buildContainerData(container containerd.Container, ta
 				RuntimeName:          types.RuntimeNameContainerd,
 				ContainerImageName:   image.Name(),
 				ContainerImageDigest: image.Metadata().Target.Digest.String(),
+				VMIsolated:           runtimeclient.IsVMRuntime(info.Runtime.Name),
 			},
 			State: taskState,
 		},
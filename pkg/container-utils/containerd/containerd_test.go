@@ -69,4 +69,27 @@ func TestNamespace(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, containers)
 	require.Len(t, containers, 0)
+
+	// validate we can see the containers of several namespaces at once
+	multiClient, err := NewContainerdClient("", &containerutilsTypes.ExtraConfig{Namespace: "k8s.io,default,empty-ns"})
+	t.Cleanup(func() {
+		multiClient.Close()
+	})
+	require.Nil(t, err)
+	require.NotNil(t, multiClient)
+
+	for _, id := range []string{"test-k8s-io", "test-default"} {
+		container, err = multiClient.GetContainer(id)
+		require.Nil(t, err)
+		require.NotNil(t, container)
+		require.Equal(t, id, container.Runtime.ContainerID)
+	}
+
+	containers, err = multiClient.GetContainers()
+	require.Nil(t, err)
+	require.GreaterOrEqual(t, len(containers), 2)
+
+	// validate invalid namespaces are rejected
+	_, err = NewContainerdClient("", &containerutilsTypes.ExtraConfig{Namespace: "k8s.io,"})
+	require.Error(t, err)
 }
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"syscall"
 
@@ -29,6 +30,8 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/containerd"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/crio"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/docker"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/lxc"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/nspawn"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/podman"
 	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
 	containerutilsTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/types"
@@ -36,21 +39,41 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
 )
 
-var AvailableRuntimes = []string{
+// DefaultRuntimes are the runtimes used when none is specified
+var DefaultRuntimes = []string{
 	types.RuntimeNameDocker.String(),
 	types.RuntimeNameContainerd.String(),
 	types.RuntimeNameCrio.String(),
 	types.RuntimeNamePodman.String(),
 }
 
+// AvailableRuntimes are all the supported runtimes. LXC and systemd-nspawn
+// containers are detected by polling, so they have to be enabled explicitly.
+var AvailableRuntimes = append(slices.Clone(DefaultRuntimes),
+	types.RuntimeNameLXC.String(),
+	types.RuntimeNameSystemdNspawn.String(),
+)
+
+// ParseRuntimeSpec parses an element of the list of runtimes to use. It's the
+// name of a runtime, optionally followed by the path of its socket, e.g.
+// "containerd=/run/k3s/containerd/containerd.sock".
+func ParseRuntimeSpec(spec string) (types.RuntimeName, string) {
+	name, socketPath, _ := strings.Cut(strings.TrimSpace(spec), "=")
+	return types.String2RuntimeName(strings.TrimSpace(name)), strings.TrimSpace(socketPath)
+}
+
 func NewContainerRuntimeClient(runtime *containerutilsTypes.RuntimeConfig) (runtimeclient.ContainerRuntimeClient, error) {
 	switch runtime.Name {
 	case types.RuntimeNameDocker:
 		socketPath := runtime.SocketPath
 		if envsp := os.Getenv("INSPEKTOR_GADGET_DOCKER_SOCKETPATH"); envsp != "" && socketPath == "" {
-			socketPath = filepath.Join(host.HostRoot, envsp)
+			socketPath = envsp
+			// Remote engines aren't reached through the host filesystem
+			if !strings.HasPrefix(envsp, "tcp://") {
+				socketPath = filepath.Join(host.HostRoot, envsp)
+			}
 		}
-		return docker.NewDockerClient(socketPath)
+		return docker.NewDockerClient(socketPath, runtime.Extra)
 	case types.RuntimeNameContainerd:
 		socketPath := runtime.SocketPath
 		if envsp := os.Getenv("INSPEKTOR_GADGET_CONTAINERD_SOCKETPATH"); envsp != "" && socketPath == "" {
@@ -67,8 +90,18 @@ func NewContainerRuntimeClient(runtime *containerutilsTypes.RuntimeConfig) (runt
 		socketPath := runtime.SocketPath
 		if envsp := os.Getenv("INSPEKTOR_GADGET_PODMAN_SOCKETPATH"); envsp != "" && socketPath == "" {
 			socketPath = filepath.Join(host.HostRoot, envsp)
+			if envsp == runtimeclient.PodmanDefaultSocketPath {
+				// Handle rootless containers as well, like for the default
+				// socket path on the host
+				return podman.NewRootlessPodmanClient(socketPath,
+					filepath.Join(host.HostRoot, podman.RootlessSocketsGlob)), nil
+			}
 		}
 		return podman.NewPodmanClient(socketPath), nil
+	case types.RuntimeNameLXC:
+		return lxc.NewLXCClient(host.HostProcFs), nil
+	case types.RuntimeNameSystemdNspawn:
+		return nspawn.NewNspawnClient(filepath.Join(host.HostRoot, nspawn.MachinesPath), host.HostProcFs), nil
 	default:
 		return nil, fmt.Errorf("unknown container runtime: %s (available %s)",
 			runtime.Name, strings.Join(AvailableRuntimes, ", "))
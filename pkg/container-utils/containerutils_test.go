@@ -68,6 +68,53 @@ func TestNewContainerRuntimeClient(t *testing.T) {
 	}
 }
 
+func TestParseRuntimeSpec(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		spec               string
+		expectedName       types.RuntimeName
+		expectedSocketPath string
+	}
+
+	tests := map[string]testDefinition{
+		"name_only": {
+			spec:         "docker",
+			expectedName: types.RuntimeNameDocker,
+		},
+		"with_spaces": {
+			spec:         " cri-o ",
+			expectedName: types.RuntimeNameCrio,
+		},
+		"with_socket_path": {
+			spec:               "containerd=/run/k3s/containerd/containerd.sock",
+			expectedName:       types.RuntimeNameContainerd,
+			expectedSocketPath: "/run/k3s/containerd/containerd.sock",
+		},
+		"with_tcp_address": {
+			spec:               "docker=tcp://dockerhost:2376",
+			expectedName:       types.RuntimeNameDocker,
+			expectedSocketPath: "tcp://dockerhost:2376",
+		},
+		"unknown": {
+			spec:               "foo=/run/foo.sock",
+			expectedName:       types.RuntimeNameUnknown,
+			expectedSocketPath: "/run/foo.sock",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			name, socketPath := ParseRuntimeSpec(test.spec)
+			require.Equal(t, test.expectedName, name)
+			require.Equal(t, test.expectedSocketPath, socketPath)
+		})
+	}
+}
+
 func TestParseOCIState(t *testing.T) {
 	t.Parallel()
 
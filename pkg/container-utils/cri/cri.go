@@ -23,11 +23,13 @@ import (
 	"strings"
 	"time"
 
+	ocispec "github.com/opencontainers/runtime-spec/specs-go"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	runtime "k8s.io/cri-api/pkg/apis/runtime/v1"
 
+	ociannotations "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/oci-annotations"
 	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
@@ -177,8 +179,11 @@ func parseExtraInfo(extraInfo map[string]string,
 			Destination string `json:"destination"`
 			Source      string `json:"source,omitempty"`
 		} `json:"mounts,omitempty"`
-		Linux *struct {
-			CgroupsPath string `json:"cgroupsPath,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+		Linux       *struct {
+			CgroupsPath string                   `json:"cgroupsPath,omitempty"`
+			UIDMappings []ocispec.LinuxIDMapping `json:"uidMappings,omitempty"`
+			GIDMappings []ocispec.LinuxIDMapping `json:"gidMappings,omitempty"`
 		} `json:"linux,omitempty" platform:"linux"`
 	}
 	type InfoContent struct {
@@ -244,6 +249,12 @@ func parseExtraInfo(extraInfo map[string]string,
 	if runtimeSpec != nil {
 		if runtimeSpec.Linux != nil {
 			containerDetailsData.CgroupsPath = runtimeSpec.Linux.CgroupsPath
+			containerDetailsData.Runtime.UIDMap = runtimeclient.IDMappingsToString(runtimeSpec.Linux.UIDMappings)
+			containerDetailsData.Runtime.GIDMap = runtimeclient.IDMappingsToString(runtimeSpec.Linux.GIDMappings)
+		}
+		if resolver, err := ociannotations.NewResolverFromAnnotations(runtimeSpec.Annotations); err == nil {
+			containerDetailsData.Runtime.RuntimeHandler = resolver.RuntimeHandler(runtimeSpec.Annotations)
+			containerDetailsData.Runtime.VMIsolated = runtimeclient.IsVMRuntime(containerDetailsData.Runtime.RuntimeHandler)
 		}
 		if len(runtimeSpec.Mounts) > 0 {
 			containerDetailsData.Mounts = make([]runtimeclient.ContainerMountData, len(runtimeSpec.Mounts))
@@ -286,6 +297,7 @@ type CRIContainer interface {
 	GetLabels() map[string]string
 	GetImage() *runtime.ImageSpec
 	GetImageRef() string
+	GetAnnotations() map[string]string
 }
 
 func CRIContainerToContainerData(runtimeName types.RuntimeName, container CRIContainer) *runtimeclient.ContainerData {
@@ -308,6 +320,9 @@ func CRIContainerToContainerData(runtimeName types.RuntimeName, container CRICon
 			State: containerStatusStateToRuntimeClientState(container.GetState()),
 		},
 	}
+	if annotations := container.GetAnnotations(); len(annotations) > 0 {
+		containerData.Runtime.ContainerAnnotations = annotations
+	}
 
 	// Fill K8S information.
 	runtimeclient.EnrichWithK8sMetadata(containerData, container.GetLabels())
@@ -20,6 +20,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
 func TestParseExtraInfo(t *testing.T) {
@@ -174,6 +175,44 @@ func TestParseExtraInfo(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "New format: user namespace and runtime handler",
+			info: map[string]string{
+				"info": `{
+					"pid": 1234,
+					"runtimeSpec": {
+						"annotations": {
+							"io.container.manager": "cri-o",
+							"io.kubernetes.cri-o.RuntimeHandler": "kata"
+						},
+						"linux": {
+							"cgroupsPath": "/mypath",
+							"uidMappings": [
+								{ "containerID": 0, "hostID": 100000, "size": 65536 }
+							],
+							"gidMappings": [
+								{ "containerID": 0, "hostID": 200000, "size": 1000 },
+								{ "containerID": 1000, "hostID": 1000, "size": 1 }
+							]
+						}
+					}
+				}`,
+			},
+			expected: &runtimeclient.ContainerDetailsData{
+				ContainerData: runtimeclient.ContainerData{
+					Runtime: runtimeclient.RuntimeContainerData{
+						BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+							RuntimeHandler: "kata",
+							VMIsolated:     true,
+							UIDMap:         "0:100000:65536",
+							GIDMap:         "0:200000:1000,1000:1000:1",
+						},
+					},
+				},
+				Pid:         1234,
+				CgroupsPath: "/mypath",
+			},
+		},
 	}
 
 	// Iterate on all tests.
@@ -16,8 +16,10 @@ package docker
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -28,11 +30,16 @@ import (
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/cgroups"
 	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
+	containerutilsTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/types"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tlsconfig"
 )
 
 const (
 	DefaultTimeout = 2 * time.Second
+
+	// tcpPrefix is the prefix of the addresses of remote engines
+	tcpPrefix = "tcp://"
 )
 
 // DockerClient implements the ContainerRuntimeClient interface but using the
@@ -43,18 +50,40 @@ const (
 type DockerClient struct {
 	client     *client.Client
 	socketPath string
+
+	// remote is true when connected to an engine over TCP. In that case,
+	// the files of the local host don't describe its containers.
+	remote bool
 }
 
-func NewDockerClient(socketPath string) (runtimeclient.ContainerRuntimeClient, error) {
+// NewDockerClient connects to the Docker Engine API listening on socketPath.
+// socketPath can also be a tcp:// address of a remote engine, in which case
+// the TLS settings of config are used, if any.
+func NewDockerClient(socketPath string, config *containerutilsTypes.ExtraConfig) (runtimeclient.ContainerRuntimeClient, error) {
 	if socketPath == "" {
 		socketPath = runtimeclient.DockerDefaultSocketPath
 	}
 
-	cli, err := client.NewClientWithOpts(
+	remote := strings.HasPrefix(socketPath, tcpPrefix)
+	host := socketPath
+	if !remote {
+		host = "unix://" + socketPath
+	}
+
+	opts := []client.Opt{
 		client.WithAPIVersionNegotiation(),
-		client.WithHost("unix://"+socketPath),
+		client.WithHost(host),
 		client.WithTimeout(DefaultTimeout),
-	)
+	}
+	if remote && config != nil && config.TLSCAFile != "" {
+		tlsConfig, err := tlsconfig.NewClientConfig(config.TLSCertFile, config.TLSKeyFile, config.TLSCAFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("creating TLS configuration for %q: %w", socketPath, err)
+		}
+		opts = append(opts, withTLSConfig(tlsConfig))
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -62,9 +91,23 @@ func NewDockerClient(socketPath string) (runtimeclient.ContainerRuntimeClient, e
 	return &DockerClient{
 		client:     cli,
 		socketPath: socketPath,
+		remote:     remote,
 	}, nil
 }
 
+// withTLSConfig uses tlsConfig for the connections to the engine. Unlike
+// client.WithTLSClientConfig, the certificates are reloaded when they change.
+func withTLSConfig(tlsConfig *tls.Config) client.Opt {
+	return func(c *client.Client) error {
+		transport, ok := c.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot apply TLS config to transport %T", c.HTTPClient().Transport)
+		}
+		transport.TLSClientConfig = tlsConfig
+		return nil
+	}
+}
+
 func listContainers(c *DockerClient, filter *dockerfilters.Args) ([]dockertypes.Container, error) {
 	opts := dockertypes.ContainerListOptions{
 		// We need to request for all containers (also non-running) because
@@ -171,6 +214,7 @@ func (c *DockerClient) GetContainerDetails(containerID string) (*runtimeclient.C
 		Pid:           containerJSON.State.Pid,
 		CgroupsPath:   string(containerJSON.HostConfig.Cgroup),
 	}
+	containerDetailsData.Runtime.VMIsolated = runtimeclient.IsVMRuntime(containerJSON.HostConfig.Runtime)
 	if len(containerJSON.Mounts) > 0 {
 		containerDetailsData.Mounts = make([]runtimeclient.ContainerMountData, len(containerJSON.Mounts))
 		for i, containerMount := range containerJSON.Mounts {
@@ -184,7 +228,7 @@ func (c *DockerClient) GetContainerDetails(containerID string) (*runtimeclient.C
 	// Try to get cgroups information from /proc/<pid>/cgroup as a fallback.
 	// However, don't fail if such a file is not available, as it would prevent the
 	// whole feature to work on systems without this file.
-	if containerDetailsData.CgroupsPath == "" {
+	if containerDetailsData.CgroupsPath == "" && !c.remote {
 		log.Debugf("cgroups info not available on Docker for container %s. Trying /proc/%d/cgroup as a fallback",
 			containerID, containerDetailsData.Pid)
 
@@ -0,0 +1,202 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package docker
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	containerutilsTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tlsconfig"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, serial int64, commonName string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+
+	if keyFile == "" {
+		return
+	}
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+}
+
+// startFakeEngine serves a minimal Docker Engine API listing a single
+// container over TLS, requiring clients to present a certificate signed by the
+// CA in caFile. It returns the tcp:// address of the engine.
+func startFakeEngine(t *testing.T, certFile, keyFile, caFile string) string {
+	t.Helper()
+
+	serverConfig, err := tlsconfig.NewServerConfig(certFile, keyFile, caFile)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.41")
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			json.NewEncoder(w).Encode([]map[string]any{{
+				"Id":     "abc",
+				"Names":  []string{"/web"},
+				"Image":  "nginx:latest",
+				"State":  "running",
+				"Labels": map[string]string{},
+			}})
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/_ping") {
+			w.Write([]byte("OK"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	server.TLS = serverConfig
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	return "tcp://localhost:" + port
+}
+
+func TestRemoteEngineTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCert(t, 1, "ca", nil)
+	caFile := filepath.Join(dir, "ca.pem")
+	ca.write(t, caFile, "")
+
+	serverCertFile := filepath.Join(dir, "server.pem")
+	serverKeyFile := filepath.Join(dir, "server-key.pem")
+	newTestCert(t, 2, "localhost", ca).write(t, serverCertFile, serverKeyFile)
+
+	clientCertFile := filepath.Join(dir, "client.pem")
+	clientKeyFile := filepath.Join(dir, "client-key.pem")
+	newTestCert(t, 3, "client", ca).write(t, clientCertFile, clientKeyFile)
+
+	address := startFakeEngine(t, serverCertFile, serverKeyFile, caFile)
+
+	type testDefinition struct {
+		config      *containerutilsTypes.ExtraConfig
+		expectedErr bool
+	}
+
+	tests := map[string]testDefinition{
+		"mutual_tls": {
+			config: &containerutilsTypes.ExtraConfig{
+				TLSCAFile:   caFile,
+				TLSCertFile: clientCertFile,
+				TLSKeyFile:  clientKeyFile,
+			},
+		},
+		"no_client_cert": {
+			config: &containerutilsTypes.ExtraConfig{
+				TLSCAFile: caFile,
+			},
+			expectedErr: true,
+		},
+		"no_tls": {
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewDockerClient(address, test.config)
+			require.NoError(t, err)
+			defer client.Close()
+			require.True(t, client.(*DockerClient).remote)
+
+			containers, err := client.GetContainers()
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, containers, 1)
+			require.Equal(t, "abc", containers[0].Runtime.ContainerID)
+			require.Equal(t, "web", containers[0].Runtime.ContainerName)
+			require.Equal(t, "nginx:latest", containers[0].Runtime.ContainerImageName)
+		})
+	}
+}
+
+func TestMissingTLSFiles(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewDockerClient("tcp://localhost:2376", &containerutilsTypes.ExtraConfig{
+		TLSCAFile: filepath.Join(t.TempDir(), "ca.pem"),
+	})
+	require.Error(t, err)
+}
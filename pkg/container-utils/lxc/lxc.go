@@ -0,0 +1,242 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lxc detects LXC (and LXD) containers. LXC doesn't provide an API
+// that can be used from outside of its containers, so they are found using the
+// names of the cgroups it creates for them.
+package lxc
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+const (
+	// payloadPrefix is the prefix of the cgroup of the containers since LXC
+	// 4.0, e.g. /lxc.payload.mycontainer
+	payloadPrefix = "lxc.payload."
+
+	// legacyParent is the parent cgroup of the containers before LXC 4.0,
+	// e.g. /lxc/mycontainer
+	legacyParent = "lxc"
+)
+
+// LXCClient implements the ContainerRuntimeClient interface for LXC
+// containers by looking at the cgroups of the processes in procPath
+type LXCClient struct {
+	procPath string
+}
+
+func NewLXCClient(procPath string) runtimeclient.ContainerRuntimeClient {
+	return &LXCClient{procPath: procPath}
+}
+
+// lxcContainer contains the processes of an LXC container
+type lxcContainer struct {
+	name       string
+	cgroupPath string
+	pids       map[int]struct{}
+}
+
+// containerName returns the name of the LXC container a cgroup belongs to,
+// if any. The cgroup of the LXC monitor process, lxc.monitor.<name>, isn't
+// considered part of the container.
+func containerName(cgroupPath string) (name string, containerCgroup string, ok bool) {
+	parts := strings.Split(strings.Trim(cgroupPath, "/"), "/")
+	for i, part := range parts {
+		if name, ok := strings.CutPrefix(part, payloadPrefix); ok && name != "" {
+			return name, "/" + strings.Join(parts[:i+1], "/"), true
+		}
+		if part == legacyParent && i+1 < len(parts) && parts[i+1] != "" {
+			return parts[i+1], "/" + strings.Join(parts[:i+2], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// readCgroupPaths returns the cgroup paths of a process in all the
+// hierarchies
+func (c *LXCClient) readCgroupPaths(pid int) ([]string, error) {
+	file, err := os.Open(filepath.Join(c.procPath, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	paths := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(scanner.Text(), ":", 3)
+		if len(fields) == 3 {
+			paths = append(paths, fields[2])
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// readPPid returns the parent process ID of a process
+func (c *LXCClient) readPPid(pid int) (int, error) {
+	stat, err := os.ReadFile(filepath.Join(c.procPath, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// The command name can contain spaces and parentheses, skip it
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return 0, fmt.Errorf("parsing stat of process %d", pid)
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("parsing stat of process %d", pid)
+	}
+	return strconv.Atoi(fields[1])
+}
+
+// listContainers returns the running LXC containers, indexed by name
+func (c *LXCClient) listContainers() (map[string]*lxcContainer, error) {
+	entries, err := os.ReadDir(c.procPath)
+	if err != nil {
+		return nil, fmt.Errorf("listing processes: %w", err)
+	}
+
+	containers := map[string]*lxcContainer{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// The process could have terminated in the meantime
+		paths, err := c.readCgroupPaths(pid)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			name, cgroupPath, ok := containerName(path)
+			if !ok {
+				continue
+			}
+			container, ok := containers[name]
+			if !ok {
+				container = &lxcContainer{
+					name:       name,
+					cgroupPath: cgroupPath,
+					pids:       map[int]struct{}{},
+				}
+				containers[name] = container
+			}
+			container.pids[pid] = struct{}{}
+			break
+		}
+	}
+	return containers, nil
+}
+
+// initPid returns the process ID of the init process of the container, i.e.
+// the one whose parent isn't part of the container
+func (c *LXCClient) initPid(container *lxcContainer) (int, error) {
+	pids := make([]int, 0, len(container.pids))
+	for pid := range container.pids {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+
+	for _, pid := range pids {
+		ppid, err := c.readPPid(pid)
+		if err != nil {
+			continue
+		}
+		if _, ok := container.pids[ppid]; !ok {
+			return pid, nil
+		}
+	}
+	return 0, fmt.Errorf("init process of LXC container %q not found", container.name)
+}
+
+func containerData(name string) *runtimeclient.ContainerData {
+	return &runtimeclient.ContainerData{
+		Runtime: runtimeclient.RuntimeContainerData{
+			BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+				ContainerID:   name,
+				ContainerName: name,
+				RuntimeName:   types.RuntimeNameLXC,
+			},
+			State: runtimeclient.StateRunning,
+		},
+	}
+}
+
+func (c *LXCClient) GetContainers() ([]*runtimeclient.ContainerData, error) {
+	containers, err := c.listContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*runtimeclient.ContainerData, 0, len(containers))
+	for name := range containers {
+		ret = append(ret, containerData(name))
+	}
+	return ret, nil
+}
+
+func (c *LXCClient) GetContainer(containerID string) (*runtimeclient.ContainerData, error) {
+	containers, err := c.listContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := containers[containerID]; !ok {
+		return nil, fmt.Errorf("container %q not found", containerID)
+	}
+	return containerData(containerID), nil
+}
+
+func (c *LXCClient) GetContainerDetails(containerID string) (*runtimeclient.ContainerDetailsData, error) {
+	containerID, err := runtimeclient.ParseContainerID(types.RuntimeNameLXC, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := c.listContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	container, ok := containers[containerID]
+	if !ok {
+		return nil, fmt.Errorf("container %q not found", containerID)
+	}
+	pid, err := c.initPid(container)
+	if err != nil {
+		return nil, err
+	}
+
+	return &runtimeclient.ContainerDetailsData{
+		ContainerData: *containerData(containerID),
+		Pid:           pid,
+		CgroupsPath:   container.cgroupPath,
+	}, nil
+}
+
+func (c *LXCClient) Close() error {
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lxc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContainerName(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		cgroupPath     string
+		expectedName   string
+		expectedCgroup string
+	}
+
+	tests := map[string]testDefinition{
+		"payload": {
+			cgroupPath:     "/lxc.payload.web",
+			expectedName:   "web",
+			expectedCgroup: "/lxc.payload.web",
+		},
+		"payload_nested": {
+			cgroupPath:     "/lxc.payload.web/init.scope",
+			expectedName:   "web",
+			expectedCgroup: "/lxc.payload.web",
+		},
+		"unprivileged": {
+			cgroupPath:     "/user.slice/user-1000.slice/user@1000.service/app.slice/lxc.payload.db/system.slice",
+			expectedName:   "db",
+			expectedCgroup: "/user.slice/user-1000.slice/user@1000.service/app.slice/lxc.payload.db",
+		},
+		"legacy": {
+			cgroupPath:     "/lxc/cache/init.scope",
+			expectedName:   "cache",
+			expectedCgroup: "/lxc/cache",
+		},
+		"monitor": {
+			cgroupPath: "/lxc.monitor.web",
+		},
+		"legacy_parent_only": {
+			cgroupPath: "/lxc",
+		},
+		"host": {
+			cgroupPath: "/system.slice/sshd.service",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			name, cgroup, ok := containerName(test.cgroupPath)
+			require.Equal(t, test.expectedName != "", ok)
+			require.Equal(t, test.expectedName, name)
+			require.Equal(t, test.expectedCgroup, cgroup)
+		})
+	}
+}
+
+func writeProcess(t *testing.T, procPath string, pid, ppid int, cgroupPath string) {
+	t.Helper()
+
+	dir := filepath.Join(procPath, strconv.Itoa(pid))
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cgroup"), []byte("0::"+cgroupPath+"\n"), 0o644))
+	stat := fmt.Sprintf("%d (my (comm)) S %d 1 1 0 -1\n", pid, ppid)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644))
+}
+
+func TestLXCClient(t *testing.T) {
+	t.Parallel()
+
+	procPath := t.TempDir()
+	writeProcess(t, procPath, 1, 0, "/init.scope")
+	writeProcess(t, procPath, 100, 1, "/lxc.monitor.web")
+	writeProcess(t, procPath, 101, 100, "/lxc.payload.web/init.scope")
+	writeProcess(t, procPath, 150, 101, "/lxc.payload.web/system.slice/nginx.service")
+	writeProcess(t, procPath, 200, 1, "/system.slice/sshd.service")
+
+	client := NewLXCClient(procPath)
+	defer client.Close()
+
+	containers, err := client.GetContainers()
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+	require.Equal(t, "web", containers[0].Runtime.ContainerID)
+	require.Equal(t, "web", containers[0].Runtime.ContainerName)
+
+	details, err := client.GetContainerDetails("lxc://web")
+	require.NoError(t, err)
+	require.Equal(t, 101, details.Pid)
+	require.Equal(t, "/lxc.payload.web", details.CgroupsPath)
+
+	_, err = client.GetContainer("db")
+	require.Error(t, err)
+}
@@ -0,0 +1,203 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package nspawn detects the containers registered in systemd-machined, like
+// the ones started by systemd-nspawn or machinectl. It reads the state files
+// of machined instead of using its D-Bus API, so it works from a container
+// that only has access to the host filesystem.
+package nspawn
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+const (
+	// MachinesPath is the directory where systemd-machined stores the state
+	// of the registered machines
+	MachinesPath = "/run/systemd/machines"
+
+	// classContainer is the class of the machines that are containers, as
+	// opposed to virtual machines
+	classContainer = "container"
+)
+
+// NspawnClient implements the ContainerRuntimeClient interface for the
+// containers registered in systemd-machined
+type NspawnClient struct {
+	machinesPath string
+	procPath     string
+}
+
+func NewNspawnClient(machinesPath, procPath string) runtimeclient.ContainerRuntimeClient {
+	return &NspawnClient{
+		machinesPath: machinesPath,
+		procPath:     procPath,
+	}
+}
+
+// machine contains the state of a machine as stored by systemd-machined
+type machine struct {
+	name   string
+	class  string
+	leader int
+}
+
+// readMachine parses the state file of a machine. It has the format of an
+// environment file, e.g. LEADER=1234.
+func readMachine(path string) (*machine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	m := &machine{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		switch key {
+		case "NAME":
+			m.name = value
+		case "CLASS":
+			m.class = value
+		case "LEADER":
+			m.leader, err = strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("parsing leader of machine %q: %w", path, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m.name == "" {
+		m.name = filepath.Base(path)
+	}
+	return m, nil
+}
+
+// listMachines returns the running containers registered in machined
+func (c *NspawnClient) listMachines() ([]*machine, error) {
+	entries, err := os.ReadDir(c.machinesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// machined isn't running or no machine was ever registered
+			return nil, nil
+		}
+		return nil, fmt.Errorf("listing machines: %w", err)
+	}
+
+	machines := []*machine{}
+	for _, entry := range entries {
+		// Skip the "unit:<scope>" symlinks pointing to the machines
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		m, err := readMachine(filepath.Join(c.machinesPath, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if m.class != classContainer || m.leader == 0 {
+			continue
+		}
+		// The state file could be stale
+		if _, err := os.Stat(filepath.Join(c.procPath, strconv.Itoa(m.leader))); err != nil {
+			continue
+		}
+		machines = append(machines, m)
+	}
+	return machines, nil
+}
+
+func (c *NspawnClient) getMachine(name string) (*machine, error) {
+	machines, err := c.listMachines()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range machines {
+		if m.name == name {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("container %q not found", name)
+}
+
+func containerData(m *machine) *runtimeclient.ContainerData {
+	return &runtimeclient.ContainerData{
+		Runtime: runtimeclient.RuntimeContainerData{
+			BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+				ContainerID:   m.name,
+				ContainerName: m.name,
+				RuntimeName:   types.RuntimeNameSystemdNspawn,
+			},
+			State: runtimeclient.StateRunning,
+		},
+	}
+}
+
+func (c *NspawnClient) GetContainers() ([]*runtimeclient.ContainerData, error) {
+	machines, err := c.listMachines()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*runtimeclient.ContainerData, len(machines))
+	for i, m := range machines {
+		ret[i] = containerData(m)
+	}
+	return ret, nil
+}
+
+func (c *NspawnClient) GetContainer(containerID string) (*runtimeclient.ContainerData, error) {
+	m, err := c.getMachine(containerID)
+	if err != nil {
+		return nil, err
+	}
+	return containerData(m), nil
+}
+
+func (c *NspawnClient) GetContainerDetails(containerID string) (*runtimeclient.ContainerDetailsData, error) {
+	containerID, err := runtimeclient.ParseContainerID(types.RuntimeNameSystemdNspawn, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := c.getMachine(containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &runtimeclient.ContainerDetailsData{
+		ContainerData: *containerData(m),
+		Pid:           m.leader,
+	}, nil
+}
+
+func (c *NspawnClient) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nspawn
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNspawnClient(t *testing.T) {
+	t.Parallel()
+
+	machinesPath := t.TempDir()
+	procPath := t.TempDir()
+
+	writeFile := func(path, content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	writeFile(filepath.Join(machinesPath, "web"),
+		"# This is private data. Do not parse.\nNAME=web\nSCOPE=machine-web.scope\nSERVICE=systemd-nspawn\nROOT=\"/var/lib/machines/web\"\nCLASS=container\nLEADER=1234\n")
+	require.NoError(t, os.Symlink("web", filepath.Join(machinesPath, "unit:machine-web.scope")))
+	// Virtual machines aren't containers
+	writeFile(filepath.Join(machinesPath, "vm"), "NAME=vm\nCLASS=vm\nLEADER=2345\n")
+	// The leader of stale machines doesn't exist anymore
+	writeFile(filepath.Join(machinesPath, "stale"), "NAME=stale\nCLASS=container\nLEADER=3456\n")
+
+	for _, pid := range []string{"1234", "2345"} {
+		require.NoError(t, os.Mkdir(filepath.Join(procPath, pid), 0o755))
+	}
+
+	client := NewNspawnClient(machinesPath, procPath)
+	defer client.Close()
+
+	containers, err := client.GetContainers()
+	require.NoError(t, err)
+	require.Len(t, containers, 1)
+	require.Equal(t, "web", containers[0].Runtime.ContainerID)
+
+	details, err := client.GetContainerDetails("systemd-nspawn://web")
+	require.NoError(t, err)
+	require.Equal(t, 1234, details.Pid)
+
+	_, err = client.GetContainer("stale")
+	require.Error(t, err)
+
+	// machined isn't running
+	client = NewNspawnClient(filepath.Join(machinesPath, "missing"), procPath)
+	containers, err = client.GetContainers()
+	require.NoError(t, err)
+	require.Empty(t, containers)
+}
@@ -57,6 +57,18 @@ func (containerdResolver) PodNamespace(annotations map[string]string) string {
 	return annotations[containerdPodNamespaceAnnotation]
 }
 
+// RuntimeHandler returns an empty string as containerd doesn't store the runtime
+// handler in the annotations of the containers
+func (containerdResolver) RuntimeHandler(annotations map[string]string) string {
+	return ""
+}
+
+// ContainerAnnotations returns nil as containerd doesn't store the Kubernetes
+// annotations of the containers in their annotations
+func (containerdResolver) ContainerAnnotations(annotations map[string]string) map[string]string {
+	return nil
+}
+
 func (containerdResolver) Runtime() types.RuntimeName {
 	return types.RuntimeNameContainerd
 }
@@ -14,7 +14,13 @@
 
 package ociannotations
 
-import "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+import (
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
 
 const (
 	// cri-o container annotations to get container information
@@ -27,6 +33,10 @@ const (
 	crioContainerNameAnnotation    = "io.kubernetes.container.name"
 	crioContainerTypeAnnotation    = "io.kubernetes.cri-o.ContainerType"
 	crioContainerImageName         = "io.kubernetes.cri-o.ImageName"
+
+	// https://github.com/cri-o/cri-o/blob/main/pkg/annotations/internal.go
+	crioRuntimeHandlerAnnotation = "io.kubernetes.cri-o.RuntimeHandler"
+	crioAnnotationsAnnotation    = "io.kubernetes.cri-o.Annotations"
 )
 
 type crioResolver struct{}
@@ -55,6 +65,25 @@ func (crioResolver) PodNamespace(annotations map[string]string) string {
 	return annotations[crioPodNamespaceAnnotation]
 }
 
+func (crioResolver) RuntimeHandler(annotations map[string]string) string {
+	return annotations[crioRuntimeHandlerAnnotation]
+}
+
+// ContainerAnnotations decodes the Kubernetes annotations of the container that
+// CRI-O stores as JSON in a single annotation
+func (crioResolver) ContainerAnnotations(annotations map[string]string) map[string]string {
+	value, ok := annotations[crioAnnotationsAnnotation]
+	if !ok {
+		return nil
+	}
+	var containerAnnotations map[string]string
+	if err := json.Unmarshal([]byte(value), &containerAnnotations); err != nil {
+		log.Debugf("CRI-O resolver: decoding annotation %q: %s", crioAnnotationsAnnotation, err)
+		return nil
+	}
+	return containerAnnotations
+}
+
 func (crioResolver) Runtime() types.RuntimeName {
 	return types.RuntimeNameCrio
 }
@@ -18,12 +18,14 @@ import "testing"
 
 func Test_crioResolver(t *testing.T) {
 	annotations := map[string]string{
-		crioPodNameAnnotation:       "test-pod-name",
-		crioPodNamespaceAnnotation:  "test-pod-namespace",
-		crioPodUIDAnnotation:        "test-pod-uid",
-		crioContainerNameAnnotation: "test-container-name",
-		crioContainerTypeAnnotation: "test-container-type",
-		crioContainerImageName:      "test-container-image-name",
+		crioPodNameAnnotation:        "test-pod-name",
+		crioPodNamespaceAnnotation:   "test-pod-namespace",
+		crioPodUIDAnnotation:         "test-pod-uid",
+		crioContainerNameAnnotation:  "test-container-name",
+		crioContainerTypeAnnotation:  "test-container-type",
+		crioContainerImageName:       "test-container-image-name",
+		crioRuntimeHandlerAnnotation: "test-runtime-handler",
+		crioAnnotationsAnnotation:    `{"io.kubernetes.container.restartCount":"2"}`,
 	}
 
 	resolver := crioResolver{}
@@ -40,4 +42,11 @@ func Test_crioResolver(t *testing.T) {
 	assert(resolver.ContainerName(annotations), "test-container-name")
 	assert(resolver.ContainerType(annotations), "test-container-type")
 	assert(resolver.ContainerImageName(annotations), "test-container-image-name")
+	assert(resolver.RuntimeHandler(annotations), "test-runtime-handler")
+	assert(resolver.ContainerAnnotations(annotations)["io.kubernetes.container.restartCount"], "2")
+
+	annotations[crioAnnotationsAnnotation] = "invalid"
+	if got := resolver.ContainerAnnotations(annotations); got != nil {
+		t.Fatalf("Assertion failed got=%v, want=nil", got)
+	}
 }
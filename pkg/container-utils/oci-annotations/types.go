@@ -38,6 +38,10 @@ type Resolver interface {
 	PodUID(annotations map[string]string) string
 	// PodNamespace returns the namespace of the pod to which container belongs
 	PodNamespace(annotations map[string]string) string
+	// RuntimeHandler returns the handler of the runtime class of the container
+	RuntimeHandler(annotations map[string]string) string
+	// ContainerAnnotations returns the Kubernetes annotations of the container
+	ContainerAnnotations(annotations map[string]string) map[string]string
 	// Runtime returns runtime in which the container is running
 	Runtime() types.RuntimeName
 }
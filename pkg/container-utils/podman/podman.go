@@ -17,10 +17,13 @@ package podman
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -33,30 +36,106 @@ const (
 	defaultConnectionTimeout = 2 * time.Second
 	containerListAllURL      = "http://d/v4.0.0/libpod/containers/json?all=true"
 	containerInspectURL      = "http://d/v4.0.0/libpod/containers/%s/json"
+
+	// RootlessSocketsGlob matches the sockets of the Podman services of
+	// rootless users, which listen in the runtime directory of each user
+	RootlessSocketsGlob = "/run/user/*/podman/podman.sock"
 )
 
 type PodmanClient struct {
-	client http.Client
+	socketPath string
+	// rootlessSocketsGlob, if set, matches the sockets of rootless Podman
+	// services that are used in addition to socketPath. They are looked up
+	// on each request as they come and go with the sessions of the users.
+	rootlessSocketsGlob string
+
+	mu      sync.Mutex
+	clients map[string]*http.Client
 }
 
+// NewPodmanClient returns a client for the Podman service listening on
+// socketPath. When the default socket path is used, the containers of the
+// rootless Podman services of all users are handled as well.
 func NewPodmanClient(socketPath string) runtimeclient.ContainerRuntimeClient {
-	if socketPath == "" {
-		socketPath = runtimeclient.PodmanDefaultSocketPath
+	p := &PodmanClient{
+		socketPath: socketPath,
+		clients:    map[string]*http.Client{},
 	}
+	if socketPath == "" || socketPath == runtimeclient.PodmanDefaultSocketPath {
+		p.socketPath = runtimeclient.PodmanDefaultSocketPath
+		p.rootlessSocketsGlob = RootlessSocketsGlob
+	}
+	return p
+}
 
+// NewRootlessPodmanClient is like NewPodmanClient but looks up the sockets of
+// the rootless Podman services with the given pattern, e.g. when the runtime
+// directories of the users are mounted elsewhere
+func NewRootlessPodmanClient(socketPath, rootlessSocketsGlob string) runtimeclient.ContainerRuntimeClient {
 	return &PodmanClient{
-		client: http.Client{
-			Transport: &http.Transport{
-				DialContext: func(ctx context.Context, _, _ string) (conn net.Conn, err error) {
-					return net.Dial("unix", socketPath)
-				},
+		socketPath:          socketPath,
+		rootlessSocketsGlob: rootlessSocketsGlob,
+		clients:             map[string]*http.Client{},
+	}
+}
+
+// sockets returns the sockets of the Podman services to query
+func (p *PodmanClient) sockets() []string {
+	sockets := []string{p.socketPath}
+	if p.rootlessSocketsGlob == "" {
+		return sockets
+	}
+	rootlessSockets, err := filepath.Glob(p.rootlessSocketsGlob)
+	if err != nil {
+		log.Debugf("PodmanClient: looking up rootless sockets: %v", err)
+		return sockets
+	}
+	return append(sockets, rootlessSockets...)
+}
+
+func (p *PodmanClient) client(socketPath string) *http.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if client, ok := p.clients[socketPath]; ok {
+		return client
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (conn net.Conn, err error) {
+				return net.Dial("unix", socketPath)
 			},
-			Timeout: defaultConnectionTimeout,
 		},
+		Timeout: defaultConnectionTimeout,
 	}
+	p.clients[socketPath] = client
+	return client
 }
 
+// listContainers lists the containers of all the Podman services. Services
+// that can't be reached are skipped, an error is only returned if none of them
+// can.
 func (p *PodmanClient) listContainers(containerID string) ([]*runtimeclient.ContainerData, error) {
+	var ret []*runtimeclient.ContainerData
+	var errs []error
+	for _, socketPath := range p.sockets() {
+		containers, err := p.listSocketContainers(socketPath, containerID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", socketPath, err))
+			continue
+		}
+		ret = append(ret, containers...)
+	}
+	if ret == nil && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		log.Debugf("PodmanClient: %v", err)
+	}
+	return ret, nil
+}
+
+func (p *PodmanClient) listSocketContainers(socketPath, containerID string) ([]*runtimeclient.ContainerData, error) {
 	var filters string
 	if containerID != "" {
 		f, err := json.Marshal(map[string][]string{"id": {containerID}})
@@ -66,7 +145,7 @@ func (p *PodmanClient) listContainers(containerID string) ([]*runtimeclient.Cont
 		filters = "&filters=" + url.QueryEscape(string(f))
 	}
 
-	resp, err := p.client.Get(containerListAllURL + filters)
+	resp, err := p.client(socketPath).Get(containerListAllURL + filters)
 	if err != nil {
 		return nil, fmt.Errorf("listing containers: %w", err)
 	}
@@ -121,7 +200,20 @@ func (p *PodmanClient) GetContainer(containerID string) (*runtimeclient.Containe
 }
 
 func (p *PodmanClient) GetContainerDetails(containerID string) (*runtimeclient.ContainerDetailsData, error) {
-	resp, err := p.client.Get(fmt.Sprintf(containerInspectURL, containerID))
+	// Containers of rootless users are only known by their own service
+	var errs []error
+	for _, socketPath := range p.sockets() {
+		details, err := p.getSocketContainerDetails(socketPath, containerID)
+		if err == nil {
+			return details, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", socketPath, err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (p *PodmanClient) getSocketContainerDetails(socketPath, containerID string) (*runtimeclient.ContainerDetailsData, error) {
+	resp, err := p.client(socketPath).Get(fmt.Sprintf(containerInspectURL, containerID))
 	if err != nil {
 		return nil, fmt.Errorf("inspecting container %q: %w", containerID, err)
 	}
@@ -162,6 +254,13 @@ func (p *PodmanClient) GetContainerDetails(containerID string) (*runtimeclient.C
 }
 
 func (p *PodmanClient) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for socketPath, client := range p.clients {
+		client.CloseIdleConnections()
+		delete(p.clients, socketPath)
+	}
 	return nil
 }
 
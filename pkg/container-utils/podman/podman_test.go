@@ -0,0 +1,141 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podman
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	runtimeclient "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/runtime-client"
+)
+
+type fakeContainer struct {
+	id   string
+	name string
+	pid  int
+}
+
+// startFakePodman serves a minimal Podman API for the given containers on a
+// unix socket at socketPath
+func startFakePodman(t *testing.T, socketPath string, containers ...fakeContainer) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(socketPath), 0o700))
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			var filters map[string][]string
+			if f := r.URL.Query().Get("filters"); f != "" {
+				json.Unmarshal([]byte(f), &filters)
+			}
+			list := []map[string]any{}
+			for _, c := range containers {
+				if ids, ok := filters["id"]; ok && !slices.Contains(ids, c.id) {
+					continue
+				}
+				list = append(list, map[string]any{"Id": c.id, "Names": []string{c.name}, "State": "running"})
+			}
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+		for _, c := range containers {
+			if strings.HasSuffix(r.URL.Path, "/containers/"+c.id+"/json") {
+				json.NewEncoder(w).Encode(map[string]any{
+					"Id":    c.id,
+					"Name":  c.name,
+					"State": map[string]any{"Status": "running", "Pid": c.pid},
+				})
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+}
+
+func TestRootlessContainers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rootSocket := filepath.Join(dir, "podman", "podman.sock")
+	rootlessGlob := filepath.Join(dir, "user", "*", "podman", "podman.sock")
+
+	startFakePodman(t, rootSocket, fakeContainer{id: "root1", name: "web", pid: 10})
+	startFakePodman(t, filepath.Join(dir, "user", "1000", "podman", "podman.sock"),
+		fakeContainer{id: "alice1", name: "db", pid: 20})
+
+	// A user whose service isn't running anymore
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "user", "1001", "podman"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "user", "1001", "podman", "podman.sock"), nil, 0o600))
+
+	client := NewRootlessPodmanClient(rootSocket, rootlessGlob)
+	defer client.Close()
+
+	containers, err := client.GetContainers()
+	require.NoError(t, err)
+	ids := []string{}
+	for _, c := range containers {
+		ids = append(ids, c.Runtime.ContainerID)
+		require.Equal(t, runtimeclient.StateRunning, c.Runtime.State)
+	}
+	require.ElementsMatch(t, []string{"root1", "alice1"}, ids)
+
+	details, err := client.GetContainerDetails("alice1")
+	require.NoError(t, err)
+	require.Equal(t, "db", details.Runtime.ContainerName)
+	require.Equal(t, 20, details.Pid)
+
+	_, err = client.GetContainerDetails("foo")
+	require.Error(t, err)
+
+	// Users starting their service later are taken into account
+	startFakePodman(t, filepath.Join(dir, "user", "1002", "podman", "podman.sock"),
+		fakeContainer{id: "bob1", name: "cache", pid: 30})
+	container, err := client.GetContainer("bob1")
+	require.NoError(t, err)
+	require.Equal(t, "cache", container.Runtime.ContainerName)
+}
+
+func TestRootlessOnlyWithDefaultSocket(t *testing.T) {
+	t.Parallel()
+
+	client := NewPodmanClient("").(*PodmanClient)
+	require.Equal(t, runtimeclient.PodmanDefaultSocketPath, client.socketPath)
+	require.Equal(t, RootlessSocketsGlob, client.rootlessSocketsGlob)
+
+	client = NewPodmanClient("/custom/podman.sock").(*PodmanClient)
+	require.Equal(t, []string{"/custom/podman.sock"}, client.sockets())
+}
+
+func TestNoServiceRunning(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	client := NewRootlessPodmanClient(filepath.Join(dir, "podman.sock"), filepath.Join(dir, "user", "*", "podman.sock"))
+
+	_, err := client.GetContainers()
+	require.Error(t, err)
+}
@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"strings"
 
+	ocispec "github.com/opencontainers/runtime-spec/specs-go"
+
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
@@ -163,3 +165,31 @@ func IsEnrichedWithK8sMetadata(k8s types.BasicK8sMetadata) bool {
 func IsEnrichedWithRuntimeMetadata(runtime types.BasicRuntimeMetadata) bool {
 	return runtime.IsEnriched()
 }
+
+// IDMappingsToString formats user namespace mappings like
+// BasicRuntimeMetadata.UIDMap and BasicRuntimeMetadata.GIDMap expect
+func IDMappingsToString(mappings []ocispec.LinuxIDMapping) string {
+	parts := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		parts = append(parts, fmt.Sprintf("%d:%d:%d", m.ContainerID, m.HostID, m.Size))
+	}
+	return strings.Join(parts, ",")
+}
+
+// vmRuntimes contains substrings of the names of the runtimes running
+// containers in virtual machines, like the runtime class handlers ("kata",
+// "kata-fc") or the containerd shims ("io.containerd.kata.v2",
+// "aws.firecracker")
+var vmRuntimes = []string{"kata", "firecracker"}
+
+// IsVMRuntime returns true if the given runtime handler, containerd shim or
+// Docker runtime runs containers in virtual machines
+func IsVMRuntime(name string) bool {
+	name = strings.ToLower(name)
+	for _, vmRuntime := range vmRuntimes {
+		if strings.Contains(name, vmRuntime) {
+			return true
+		}
+	}
+	return false
+}
@@ -17,7 +17,15 @@ package types
 import "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 
 type ExtraConfig struct {
+	// Namespace is the comma-separated list of containerd namespaces to use
 	Namespace string
+
+	// TLSCAFile, TLSCertFile and TLSKeyFile are used by docker to connect to
+	// a remote engine listening on a tcp:// address. TLS is only used if
+	// TLSCAFile is set.
+	TLSCAFile   string
+	TLSCertFile string
+	TLSKeyFile  string
 }
 
 type RuntimeConfig struct {
@@ -0,0 +1,133 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	gadgetv1alpha1 "github.com/inspektor-gadget/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+// GadgetCatalogReconciler reconciles GadgetCatalog objects
+type GadgetCatalogReconciler struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+
+	// SetAllowedImages is called with the images allowed by all the gadget
+	// catalogs, or nil when there isn't any catalog
+	SetAllowedImages func(images []oci.AllowedImage)
+}
+
+// catalogSyncRetryInterval is how often the initial sync of the gadget
+// catalogs is retried
+const catalogSyncRetryInterval = 5 * time.Second
+
+//+kubebuilder:rbac:groups=gadget.kinvolk.io,resources=gadgetcatalogs,verbs=get;list;watch
+
+// Reconcile merges the images of all the gadget catalogs and restricts the
+// gadget images that can run on this node to them. All catalogs are handled at
+// once, so the request itself isn't relevant.
+func (r *GadgetCatalogReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	if err := r.sync(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// sync restricts the gadget images to the ones allowed by the current gadget
+// catalogs
+func (r *GadgetCatalogReconciler) sync(ctx context.Context) error {
+	catalogs := &gadgetv1alpha1.GadgetCatalogList{}
+	if err := r.Client.List(ctx, catalogs); err != nil {
+		log.Errorf("Failed to list gadget catalogs: %s", err)
+		return err
+	}
+
+	images := allowedImagesFromCatalogs(catalogs.Items)
+	if images == nil {
+		log.Infof("No gadget catalogs found, all gadget images are allowed")
+	} else {
+		log.Infof("Gadget catalogs updated, %d image entries allowed", len(images))
+	}
+	r.SetAllowedImages(images)
+
+	return nil
+}
+
+// Start syncs the gadget catalogs once the cache is ready, even if there
+// aren't any catalogs to reconcile. Images are denied until then.
+func (r *GadgetCatalogReconciler) Start(ctx context.Context) error {
+	for {
+		if err := r.sync(ctx); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(catalogSyncRetryInterval):
+		}
+	}
+}
+
+// NeedLeaderElection returns false: the catalogs are synced on all the nodes
+func (r *GadgetCatalogReconciler) NeedLeaderElection() bool {
+	return false
+}
+
+// allowedImagesFromCatalogs returns the images allowed by the given catalogs.
+// It returns nil if there are no catalogs, or all of them are being deleted,
+// meaning that all images are allowed.
+func allowedImagesFromCatalogs(catalogs []gadgetv1alpha1.GadgetCatalog) []oci.AllowedImage {
+	var images []oci.AllowedImage
+	for _, catalog := range catalogs {
+		if !catalog.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if images == nil {
+			images = []oci.AllowedImage{}
+		}
+		for _, image := range catalog.Spec.Images {
+			images = append(images, oci.AllowedImage{
+				Repository: image.Repository,
+				Digests:    image.Digests,
+			})
+		}
+	}
+	return images
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GadgetCatalogReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.SetAllowedImages == nil {
+		// Don't run any image before the catalogs are known
+		oci.WaitForAllowedImages()
+		r.SetAllowedImages = oci.SetAllowedImages
+	}
+	// Runnables added to the manager start once its cache is synced
+	if err := mgr.Add(r); err != nil {
+		return fmt.Errorf("adding gadget catalog sync: %w", err)
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gadgetv1alpha1.GadgetCatalog{}).
+		Complete(r)
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gadgetv1alpha1 "github.com/inspektor-gadget/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+func TestAllowedImagesFromCatalogs(t *testing.T) {
+	now := metav1.Now()
+	catalog := func(name string, deleted bool, repositories ...string) gadgetv1alpha1.GadgetCatalog {
+		c := gadgetv1alpha1.GadgetCatalog{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+		}
+		if deleted {
+			c.DeletionTimestamp = &now
+		}
+		for _, repository := range repositories {
+			c.Spec.Images = append(c.Spec.Images, gadgetv1alpha1.GadgetCatalogImage{Repository: repository})
+		}
+		return c
+	}
+
+	tests := map[string]struct {
+		catalogs []gadgetv1alpha1.GadgetCatalog
+		expected []oci.AllowedImage
+	}{
+		"no_catalogs": {},
+		"all_catalogs_deleted": {
+			catalogs: []gadgetv1alpha1.GadgetCatalog{catalog("a", true, "foo")},
+		},
+		"empty_catalog": {
+			catalogs: []gadgetv1alpha1.GadgetCatalog{catalog("a", false)},
+			expected: []oci.AllowedImage{},
+		},
+		"merged": {
+			catalogs: []gadgetv1alpha1.GadgetCatalog{
+				catalog("a", false, "foo"),
+				catalog("b", true, "bar"),
+				catalog("c", false, "baz"),
+			},
+			expected: []oci.AllowedImage{{Repository: "foo"}, {Repository: "baz"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			images := allowedImagesFromCatalogs(test.catalogs)
+			if !reflect.DeepEqual(images, test.expected) {
+				t.Fatalf("expected %#v, got %#v", test.expected, images)
+			}
+		})
+	}
+}
+
+func TestGadgetCatalogInitialSync(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := gadgetv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("adding to scheme: %s", err)
+	}
+
+	// Nothing is reconciled without catalogs, the initial sync has to tell
+	// that all images are allowed
+	called := false
+	r := &GadgetCatalogReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+		SetAllowedImages: func(images []oci.AllowedImage) {
+			called = true
+			if images != nil {
+				t.Fatalf("expected all images to be allowed, got %v", images)
+			}
+		},
+	}
+	if err := r.Start(context.Background()); err != nil {
+		t.Fatalf("starting: %s", err)
+	}
+	if !called {
+		t.Fatalf("allowed images weren't set")
+	}
+}
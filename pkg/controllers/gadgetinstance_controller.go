@@ -0,0 +1,585 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+
+	gadgetv1alpha1 "github.com/inspektor-gadget/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/rbac"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubemanager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
+)
+
+// GadgetRunner runs gadgets on the local node without a client attached
+type GadgetRunner interface {
+	RunHeadless(
+		ctx context.Context,
+		request *api.GadgetRunRequest,
+		logger logger.Logger,
+		eventCallback func(data []byte),
+	) ([][]byte, error)
+}
+
+// runningGadgetInstance keeps track of a gadget instance running on this node
+type runningGadgetInstance struct {
+	generation int64
+	cancel     context.CancelFunc
+	done       chan struct{}
+	// keepResults is set when the gadget is stopped while the gadget
+	// instance still exists, so the results collected so far are stored
+	keepResults atomic.Bool
+}
+
+// maxConcurrentReconciles is the number of gadget instances reconciled at the
+// same time
+const maxConcurrentReconciles = 4
+
+// GadgetInstanceReconciler reconciles a GadgetInstance object
+type GadgetInstanceReconciler struct {
+	Client client.Client
+	Scheme *runtime.Scheme
+	Node   string
+	Runner GadgetRunner
+
+	mu        sync.Mutex
+	instances map[types.NamespacedName]*runningGadgetInstance
+}
+
+//+kubebuilder:rbac:groups=gadget.kinvolk.io,resources=gadgetinstances,verbs=get;list;watch
+//+kubebuilder:rbac:groups=gadget.kinvolk.io,resources=gadgetinstances/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
+
+// Reconcile starts the gadget described by a GadgetInstance on this node,
+// restarts it when its spec changes and stops it when it's deleted or doesn't
+// target this node anymore.
+//
+// The same object is never reconciled concurrently, so r.mu is only held to
+// access r.instances and not while waiting for a gadget to stop.
+func (r *GadgetInstanceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	instance := &gadgetv1alpha1.GadgetInstance{}
+	err := r.Client.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			log.Infof("Gadget instance %q has been deleted", req.NamespacedName)
+			r.stop(req.NamespacedName, false)
+			return ctrl.Result{}, nil
+		}
+		log.Errorf("Failed to get gadget instance %q: %s", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
+
+	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		r.stop(req.NamespacedName, false)
+		return ctrl.Result{}, nil
+	}
+	if !r.runsOnNode(instance) {
+		r.stop(req.NamespacedName, true)
+		return ctrl.Result{}, nil
+	}
+
+	r.mu.Lock()
+	running, ok := r.instances[req.NamespacedName]
+	r.mu.Unlock()
+	if ok {
+		if running.generation == instance.Generation {
+			return ctrl.Result{}, nil
+		}
+		log.Infof("Gadget instance %q changed, restarting it", req.NamespacedName)
+		r.stop(req.NamespacedName, true)
+	}
+
+	log.Infof("Starting gadget instance %q (image %s, node %s)",
+		req.NamespacedName, instance.Spec.Image, r.Node)
+
+	sink, err := r.newGadgetInstanceSink(instance)
+	if err != nil {
+		r.updateNodeStatus(ctx, req.NamespacedName, instance.Generation,
+			gadgetv1alpha1.GadgetInstanceStateFailed, fmt.Sprintf("setting up sink: %s", err))
+		return ctrl.Result{}, nil
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	running = &runningGadgetInstance{
+		generation: instance.Generation,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	r.mu.Lock()
+	if r.instances == nil {
+		r.instances = make(map[types.NamespacedName]*runningGadgetInstance)
+	}
+	r.instances[req.NamespacedName] = running
+	r.mu.Unlock()
+
+	r.updateNodeStatus(ctx, req.NamespacedName, instance.Generation,
+		gadgetv1alpha1.GadgetInstanceStateRunning, "")
+
+	request := gadgetRunRequestFromSpec(&instance.Spec)
+	go func() {
+		defer close(running.done)
+		defer sink.Close()
+
+		// The gadget is authorized with the permissions of the service
+		// account of the gadget instance, see rbac.Authorizer
+		authCtx := rbac.WithServiceAccount(runCtx, instance.Namespace, serviceAccountName(&instance.Spec))
+		results, err := r.Runner.RunHeadless(authCtx, request, logger.DefaultLogger(), sink.Event)
+
+		// The gadget was stopped by the reconciler, the object is either gone
+		// or a new run is taking care of its status. Gadgets without a
+		// duration only stop this way, keep what they collected if the object
+		// still exists.
+		if runCtx.Err() != nil {
+			if running.keepResults.Load() {
+				if err := sink.Results(context.Background(), results); err != nil {
+					log.Errorf("Failed to store results of gadget instance %q: %s", req.NamespacedName, err)
+				}
+			}
+			return
+		}
+
+		state := gadgetv1alpha1.GadgetInstanceStateCompleted
+		message := ""
+		if err != nil {
+			log.Errorf("Gadget instance %q failed: %s", req.NamespacedName, err)
+			state = gadgetv1alpha1.GadgetInstanceStateFailed
+			message = err.Error()
+		} else if err := sink.Results(context.Background(), results); err != nil {
+			log.Errorf("Failed to store results of gadget instance %q: %s", req.NamespacedName, err)
+			state = gadgetv1alpha1.GadgetInstanceStateFailed
+			message = fmt.Sprintf("storing results: %s", err)
+		}
+		r.updateNodeStatus(context.Background(), req.NamespacedName, running.generation, state, message)
+	}()
+
+	return ctrl.Result{}, nil
+}
+
+// runsOnNode returns whether the gadget instance targets the node of this
+// reconciler
+func (r *GadgetInstanceReconciler) runsOnNode(instance *gadgetv1alpha1.GadgetInstance) bool {
+	if len(instance.Spec.Nodes) == 0 {
+		return true
+	}
+	for _, node := range instance.Spec.Nodes {
+		if node == r.Node {
+			return true
+		}
+	}
+	return false
+}
+
+// stop stops the gadget instance, if running, and waits for it to finish. If
+// keepResults is set, the results of the gadget are passed to its sink.
+func (r *GadgetInstanceReconciler) stop(nsName types.NamespacedName, keepResults bool) {
+	r.mu.Lock()
+	running, ok := r.instances[nsName]
+	delete(r.instances, nsName)
+	r.mu.Unlock()
+	if !ok {
+		return
+	}
+	log.Infof("Stopping gadget instance %q", nsName)
+	running.keepResults.Store(keepResults)
+	running.cancel()
+	<-running.done
+}
+
+// updateNodeStatus sets the status of the gadget instance on this node. Only
+// the entry of this node is patched, so nodes don't override each other.
+func (r *GadgetInstanceReconciler) updateNodeStatus(ctx context.Context,
+	nsName types.NamespacedName,
+	generation int64,
+	state gadgetv1alpha1.GadgetInstanceState,
+	message string,
+) {
+	log.Infof("Updating status of gadget instance %q on node %s: state=%s message=%q",
+		nsName, r.Node, state, message)
+
+	instance := &gadgetv1alpha1.GadgetInstance{}
+	if err := r.Client.Get(ctx, nsName, instance); err != nil {
+		if !k8serrors.IsNotFound(err) {
+			log.Errorf("Failed to get gadget instance %q: %s", nsName, err)
+		}
+		return
+	}
+
+	patch := client.MergeFrom(instance.DeepCopy())
+
+	if instance.Status.Nodes == nil {
+		instance.Status.Nodes = make(map[string]gadgetv1alpha1.GadgetInstanceNodeStatus)
+	}
+	nodeStatus := instance.Status.Nodes[r.Node]
+	nodeStatus.State = state
+	nodeStatus.ObservedGeneration = generation
+	nodeStatus.Message = message
+
+	condition := metav1.Condition{
+		Type:               gadgetv1alpha1.GadgetInstanceConditionRunning,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             string(state),
+		Message:            message,
+	}
+	if state == gadgetv1alpha1.GadgetInstanceStateRunning {
+		condition.Status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(&nodeStatus.Conditions, condition)
+
+	instance.Status.Nodes[r.Node] = nodeStatus
+
+	if err := r.Client.Status().Patch(ctx, instance, patch); err != nil {
+		log.Errorf("Failed to update gadget instance %q status: %s", nsName, err)
+	}
+}
+
+// serviceAccountName returns the service account the gadget instance runs as
+func serviceAccountName(spec *gadgetv1alpha1.GadgetInstanceSpec) string {
+	if spec.ServiceAccountName == "" {
+		return "default"
+	}
+	return spec.ServiceAccountName
+}
+
+// gadgetRunRequestFromSpec creates the request to run the gadget described by
+// spec. The filter is translated to the parameters of the KubeManager
+// operator.
+func gadgetRunRequestFromSpec(spec *gadgetv1alpha1.GadgetInstanceSpec) *api.GadgetRunRequest {
+	params := make(map[string]string, len(spec.Parameters))
+	for k, v := range spec.Parameters {
+		params[k] = v
+	}
+
+	if filter := spec.Filter; filter != nil {
+		prefix := "operator." + kubemanager.OperatorName + "."
+		if filter.Namespace != "" {
+			params[prefix+kubemanager.ParamNamespace] = filter.Namespace
+		}
+		if filter.Podname != "" {
+			params[prefix+kubemanager.ParamPodName] = filter.Podname
+		}
+		if filter.ContainerName != "" {
+			params[prefix+kubemanager.ParamContainerName] = filter.ContainerName
+		}
+		if len(filter.Labels) > 0 {
+			selector := make([]string, 0, len(filter.Labels))
+			for k, v := range filter.Labels {
+				selector = append(selector, k+"="+v)
+			}
+			sort.Strings(selector)
+			params[prefix+kubemanager.ParamSelector] = strings.Join(selector, ",")
+		}
+	}
+
+	return &api.GadgetRunRequest{
+		GadgetName:     "run",
+		GadgetCategory: gadgets.CategoryNone,
+		Params:         params,
+		Args:           []string{spec.Image},
+		LogLevel:       uint32(logger.InfoLevel),
+		Timeout:        int64(spec.Duration.Duration),
+	}
+}
+
+// gadgetInstanceSink receives the output of a gadget instance
+type gadgetInstanceSink interface {
+	// Event is called for each event generated by the gadget
+	Event(data []byte)
+	// Results is called with the results of the gadget once it finished, if
+	// any. Events received before are part of the results too.
+	Results(ctx context.Context, results [][]byte) error
+	// Close releases the resources of the sink
+	Close()
+}
+
+// newGadgetInstanceSink returns the sink handling the events of the given
+// gadget instance according to its spec
+func (r *GadgetInstanceReconciler) newGadgetInstanceSink(instance *gadgetv1alpha1.GadgetInstance) (gadgetInstanceSink, error) {
+	nsName := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	sink := &instance.Spec.Sink
+
+	switch sink.Type {
+	case "", gadgetv1alpha1.GadgetInstanceSinkTypeLog:
+		return &logSink{
+			entry: log.WithField("gadgetinstance", nsName.String()),
+		}, nil
+	case gadgetv1alpha1.GadgetInstanceSinkTypeFile:
+		f, err := openFileSink(filepath.Join(host.HostRoot, FileSinkDir), sink.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &fileSink{nsName: nsName, f: f}, nil
+	case gadgetv1alpha1.GadgetInstanceSinkTypeConfigMap:
+		return &configMapSink{
+			client:   r.Client,
+			instance: instance.DeepCopy(),
+			name:     resultsConfigMapName(instance, r.Node),
+			node:     r.Node,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported sink type %q", sink.Type)
+	}
+}
+
+// logSink writes the events of a gadget instance to the logs of the gadget pod
+type logSink struct {
+	entry *log.Entry
+}
+
+func (s *logSink) Event(data []byte) {
+	s.entry.Info(string(data))
+}
+
+func (s *logSink) Results(_ context.Context, results [][]byte) error {
+	for _, result := range results {
+		s.Event(result)
+	}
+	return nil
+}
+
+func (s *logSink) Close() {}
+
+// FileSinkDir is the directory of the node where the File sink writes. Paths
+// are relative to it.
+const FileSinkDir = "/var/log/inspektor-gadget"
+
+// openFileSink opens the file of a File sink for appending. path must be
+// relative and stay within dir, which is created if needed.
+func openFileSink(dir, path string) (*os.File, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path must be set for sink type %q", gadgetv1alpha1.GadgetInstanceSinkTypeFile)
+	}
+	// IsLocal rejects absolute paths and paths escaping dir with ".."
+	if !filepath.IsLocal(path) {
+		return nil, fmt.Errorf("path %q must be relative to %s and can't contain \"..\"", path, FileSinkDir)
+	}
+
+	fullPath := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return nil, fmt.Errorf("creating directory for %q: %w", path, err)
+	}
+	// Don't follow a symlink replacing the file
+	f, err := os.OpenFile(fullPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY|syscall.O_NOFOLLOW, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening %q: %w", path, err)
+	}
+	return f, nil
+}
+
+// fileSink appends the events of a gadget instance to a file on the node
+type fileSink struct {
+	nsName types.NamespacedName
+	mu     sync.Mutex
+	f      *os.File
+}
+
+func (s *fileSink) Event(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(data, '\n')); err != nil {
+		log.Warnf("Failed to write event of gadget instance %q: %s", s.nsName, err)
+	}
+}
+
+func (s *fileSink) Results(_ context.Context, results [][]byte) error {
+	for _, result := range results {
+		s.Event(result)
+	}
+	return nil
+}
+
+func (s *fileSink) Close() {
+	s.f.Close()
+}
+
+// maxConfigMapResultsSize is the maximum size of the results stored by a node
+// in its ConfigMap. ConfigMaps are limited to 1MiB, including their metadata.
+const maxConfigMapResultsSize = 1000 * 1024
+
+const (
+	// ResultsKey is the key of the results in the ConfigMaps of the gadget
+	// instances
+	ResultsKey = "results"
+	// GadgetInstanceUIDLabel is set on the ConfigMaps storing the results of
+	// a gadget instance to the UID of the instance
+	GadgetInstanceUIDLabel = "gadget.kinvolk.io/gadgetinstance-uid"
+	// NodeAnnotation is set on the ConfigMaps storing the results of a gadget
+	// instance to the node they come from
+	NodeAnnotation = "gadget.kinvolk.io/node"
+)
+
+// configMapSink stores the output of a gadget instance on a node in a
+// ConfigMap once the gadget finishes. It's intended for gadgets with a final
+// result, like snapshot or advise gadgets, so their results are kept after the
+// gadget stopped. Each node uses its own ConfigMap, so nodes don't compete for
+// the size limit of ConfigMaps.
+type configMapSink struct {
+	client   client.Client
+	instance *gadgetv1alpha1.GadgetInstance
+	name     string
+	node     string
+
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+// resultsConfigMapName returns the name of the ConfigMap storing the results of
+// the gadget instance on node: "<configMapName>-<node>". The node name is
+// replaced by its hash if the name would be too long.
+func resultsConfigMapName(instance *gadgetv1alpha1.GadgetInstance, node string) string {
+	prefix := instance.Spec.Sink.ConfigMapName
+	if prefix == "" {
+		prefix = instance.Name + "-results"
+	}
+
+	name := prefix + "-" + node
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(node))
+	suffix := "-" + hex.EncodeToString(sum[:])[:16]
+	if maxLen := validation.DNS1123SubdomainMaxLength - len(suffix); len(prefix) > maxLen {
+		prefix = strings.TrimRight(prefix[:maxLen], "-.")
+	}
+	return prefix + suffix
+}
+
+func (s *configMapSink) Event(data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.buf.Len()+len(data)+1 > maxConfigMapResultsSize {
+		if !s.truncated {
+			log.Warnf("Results of gadget instance %s/%s exceed %d bytes, dropping further events",
+				s.instance.Namespace, s.instance.Name, maxConfigMapResultsSize)
+		}
+		s.truncated = true
+		return
+	}
+	s.buf.Write(data)
+	s.buf.WriteByte('\n')
+}
+
+// ownedBy returns whether the ConfigMap belongs to the gadget instance
+func ownedBy(cm *corev1.ConfigMap, instance *gadgetv1alpha1.GadgetInstance) bool {
+	for _, ref := range cm.OwnerReferences {
+		if ref.UID == instance.UID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *configMapSink) Results(ctx context.Context, results [][]byte) error {
+	for _, result := range results {
+		s.Event(result)
+	}
+
+	s.mu.Lock()
+	data := s.buf.String()
+	s.mu.Unlock()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.name,
+			Namespace: s.instance.Namespace,
+			Labels: map[string]string{
+				GadgetInstanceUIDLabel: string(s.instance.UID),
+			},
+			Annotations: map[string]string{
+				NodeAnnotation: s.node,
+			},
+			// Remove the results together with the gadget instance. Don't
+			// set BlockOwnerDeletion, it'd require permissions on the
+			// finalizers of gadget instances.
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: gadgetv1alpha1.SchemeGroupVersion.String(),
+					Kind:       "GadgetInstance",
+					Name:       s.instance.Name,
+					UID:        s.instance.UID,
+				},
+			},
+		},
+		Data: map[string]string{
+			ResultsKey: data,
+		},
+	}
+	err := s.client.Create(ctx, cm)
+	if err == nil {
+		return nil
+	}
+	if !k8serrors.IsAlreadyExists(err) {
+		return fmt.Errorf("creating ConfigMap %q: %w", s.name, err)
+	}
+
+	// The ConfigMap was created by a previous run of the gadget instance.
+	// Don't touch it if it belongs to something else.
+	existing := &corev1.ConfigMap{}
+	if err := s.client.Get(ctx, client.ObjectKeyFromObject(cm), existing); err != nil {
+		return fmt.Errorf("getting ConfigMap %q: %w", s.name, err)
+	}
+	if !ownedBy(existing, s.instance) {
+		return fmt.Errorf("ConfigMap %q already exists and doesn't belong to the gadget instance", s.name)
+	}
+
+	existing.Data = cm.Data
+	if err := s.client.Update(ctx, existing); err != nil {
+		return fmt.Errorf("updating ConfigMap %q: %w", s.name, err)
+	}
+	return nil
+}
+
+func (s *configMapSink) Close() {}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GadgetInstanceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&gadgetv1alpha1.GadgetInstance{}).
+		// Stopping a gadget can take a while, don't block the other instances
+		WithOptions(controller.Options{
+			Controller: config.Controller{MaxConcurrentReconciles: maxConcurrentReconciles},
+		}).
+		Complete(r)
+}
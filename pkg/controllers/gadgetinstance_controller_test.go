@@ -0,0 +1,258 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	gadgetv1alpha1 "github.com/inspektor-gadget/inspektor-gadget/pkg/apis/gadget/v1alpha1"
+)
+
+func TestGadgetRunRequestFromSpec(t *testing.T) {
+	spec := &gadgetv1alpha1.GadgetInstanceSpec{
+		Image: "ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+		Filter: &gadgetv1alpha1.ContainerFilter{
+			Namespace:     "default",
+			Podname:       "mypod",
+			ContainerName: "mycontainer",
+			Labels: map[string]string{
+				"b": "2",
+				"a": "1",
+			},
+		},
+		Parameters: map[string]string{
+			"pull": "missing",
+		},
+		Duration: metav1.Duration{Duration: time.Minute},
+	}
+
+	request := gadgetRunRequestFromSpec(spec)
+
+	if request.GadgetName != "run" {
+		t.Fatalf("expected gadget name %q, got %q", "run", request.GadgetName)
+	}
+	if request.Timeout != int64(time.Minute) {
+		t.Fatalf("expected timeout %d, got %d", int64(time.Minute), request.Timeout)
+	}
+	if !reflect.DeepEqual(request.Args, []string{spec.Image}) {
+		t.Fatalf("expected args %v, got %v", []string{spec.Image}, request.Args)
+	}
+
+	expectedParams := map[string]string{
+		"pull":                               "missing",
+		"operator.KubeManager.namespace":     "default",
+		"operator.KubeManager.podname":       "mypod",
+		"operator.KubeManager.containername": "mycontainer",
+		"operator.KubeManager.selector":      "a=1,b=2",
+	}
+	if !reflect.DeepEqual(request.Params, expectedParams) {
+		t.Fatalf("expected params %v, got %v", expectedParams, request.Params)
+	}
+
+	// The spec must not be modified
+	if len(spec.Parameters) != 1 {
+		t.Fatalf("spec parameters were modified: %v", spec.Parameters)
+	}
+}
+
+func TestConfigMapSink(t *testing.T) {
+	instance := &gadgetv1alpha1.GadgetInstance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "advise",
+			Namespace: "gadget",
+			UID:       "1234",
+		},
+		Spec: gadgetv1alpha1.GadgetInstanceSpec{
+			Sink: gadgetv1alpha1.GadgetInstanceSink{
+				Type: gadgetv1alpha1.GadgetInstanceSinkTypeConfigMap,
+			},
+		},
+	}
+	// Owned by something else
+	foreign := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "advise-results-node3",
+			Namespace: "gadget",
+		},
+	}
+	c := fake.NewClientBuilder().WithObjects(foreign).Build()
+	ctx := context.Background()
+
+	storeResults := func(node, result string) error {
+		r := &GadgetInstanceReconciler{Client: c, Node: node}
+		sink, err := r.newGadgetInstanceSink(instance)
+		if err != nil {
+			t.Fatalf("creating sink: %s", err)
+		}
+		defer sink.Close()
+		sink.Event([]byte(`{"event":"` + node + `"}`))
+		return sink.Results(ctx, [][]byte{[]byte(`{"result":"` + result + `"}`)})
+	}
+
+	// Each node writes its results to its own ConfigMap. Later runs replace
+	// the results.
+	for _, node := range []string{"node1", "node2", "node1"} {
+		if err := storeResults(node, node); err != nil {
+			t.Fatalf("storing results of %s: %s", node, err)
+		}
+	}
+
+	for _, node := range []string{"node1", "node2"} {
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: "gadget", Name: "advise-results-" + node}, cm); err != nil {
+			t.Fatalf("getting ConfigMap: %s", err)
+		}
+
+		expectedData := map[string]string{
+			ResultsKey: "{\"event\":\"" + node + "\"}\n{\"result\":\"" + node + "\"}\n",
+		}
+		if !reflect.DeepEqual(cm.Data, expectedData) {
+			t.Fatalf("expected data %v, got %v", expectedData, cm.Data)
+		}
+		if len(cm.OwnerReferences) != 1 || cm.OwnerReferences[0].UID != instance.UID {
+			t.Fatalf("expected owner reference to the gadget instance, got %v", cm.OwnerReferences)
+		}
+		if cm.Labels[GadgetInstanceUIDLabel] != "1234" || cm.Annotations[NodeAnnotation] != node {
+			t.Fatalf("unexpected labels %v or annotations %v", cm.Labels, cm.Annotations)
+		}
+	}
+
+	if err := storeResults("node3", "node3"); err == nil {
+		t.Fatalf("expected error overwriting a ConfigMap of another owner")
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "gadget", Name: "advise-results-node3"}, cm); err != nil {
+		t.Fatalf("getting ConfigMap: %s", err)
+	}
+	if len(cm.Data) != 0 {
+		t.Fatalf("ConfigMap of another owner was modified: %v", cm.Data)
+	}
+}
+
+func TestResultsConfigMapName(t *testing.T) {
+	instance := &gadgetv1alpha1.GadgetInstance{
+		ObjectMeta: metav1.ObjectMeta{Name: "advise"},
+	}
+	if name := resultsConfigMapName(instance, "node1"); name != "advise-results-node1" {
+		t.Fatalf("unexpected name %q", name)
+	}
+
+	instance.Spec.Sink.ConfigMapName = "custom"
+	if name := resultsConfigMapName(instance, "node1"); name != "custom-node1" {
+		t.Fatalf("unexpected name %q", name)
+	}
+
+	// Too long names use a hash of the node name
+	longNode := strings.Repeat("n", validation.DNS1123SubdomainMaxLength)
+	name := resultsConfigMapName(instance, longNode)
+	if len(name) > validation.DNS1123SubdomainMaxLength || !strings.HasPrefix(name, "custom-") {
+		t.Fatalf("unexpected name %q", name)
+	}
+	if name == resultsConfigMapName(instance, longNode+"2") {
+		t.Fatalf("different nodes must use different ConfigMaps")
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		t.Fatalf("invalid name %q: %v", name, errs)
+	}
+}
+
+func TestOpenFileSink(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, path := range []string{"", "/etc/passwd", "../escape.json", "logs/../../escape.json"} {
+		if f, err := openFileSink(dir, path); err == nil {
+			f.Close()
+			t.Fatalf("expected error opening %q", path)
+		}
+	}
+
+	f, err := openFileSink(dir, "logs/trace.json")
+	if err != nil {
+		t.Fatalf("opening sink: %s", err)
+	}
+	f.Close()
+	if _, err := os.Stat(filepath.Join(dir, "logs", "trace.json")); err != nil {
+		t.Fatalf("sink file not created: %s", err)
+	}
+
+	// Symlinks aren't followed
+	if err := os.Symlink("/etc/passwd", filepath.Join(dir, "link.json")); err != nil {
+		t.Fatalf("creating symlink: %s", err)
+	}
+	if f, err := openFileSink(dir, "link.json"); err == nil {
+		f.Close()
+		t.Fatalf("expected error opening a symlink")
+	}
+}
+
+func TestStopDoesNotBlockOtherInstances(t *testing.T) {
+	stopping := types.NamespacedName{Namespace: "gadget", Name: "stopping"}
+	other := types.NamespacedName{Namespace: "gadget", Name: "other"}
+
+	release := make(chan struct{})
+	slow := &runningGadgetInstance{done: make(chan struct{})}
+	slow.cancel = func() {
+		// The gadget takes a while to stop
+		go func() {
+			<-release
+			close(slow.done)
+		}()
+	}
+	fast := &runningGadgetInstance{done: make(chan struct{})}
+	fast.cancel = func() { close(fast.done) }
+
+	r := &GadgetInstanceReconciler{
+		instances: map[types.NamespacedName]*runningGadgetInstance{
+			stopping: slow,
+			other:    fast,
+		},
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		r.stop(stopping, false)
+		close(stopped)
+	}()
+
+	// Other instances can be stopped while the first one is still stopping
+	done := make(chan struct{})
+	go func() {
+		r.stop(other, false)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stopping an instance blocked the other ones")
+	}
+
+	close(release)
+	<-stopped
+	if len(r.instances) != 0 {
+		t.Fatalf("expected no running instances, got %v", r.instances)
+	}
+}
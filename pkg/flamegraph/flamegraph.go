@@ -0,0 +1,207 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package flamegraph provides a FlameGraph struct that merges sampled stacks.
+// It can be written as folded stacks, the format used by the flame graph
+// tools, or rendered directly as an SVG flame graph.
+package flamegraph
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	imageWidth  = 1200
+	frameHeight = 16
+	fontSize    = 12
+	// Approximate width of a character, used to fit the names in the frames
+	charWidth = 7
+	padTop    = 40
+	padBottom = 10
+	padSide   = 10
+	// Frames narrower than this aren't drawn
+	minFrameWidth = 0.1
+)
+
+// FoldedLine returns the folded representation of a stack sampled count
+// times, e.g. "bash;main;read 12". The frames go from the root to the leaf.
+func FoldedLine(stack []string, count uint64) string {
+	frames := make([]string, len(stack))
+	for i, frame := range stack {
+		// ";" separates the frames and the count comes after the last space
+		frames[i] = strings.NewReplacer(";", ":", "\n", " ").Replace(frame)
+	}
+	return fmt.Sprintf("%s %d", strings.Join(frames, ";"), count)
+}
+
+type node struct {
+	name     string
+	value    uint64
+	children map[string]*node
+}
+
+func (n *node) child(name string) *node {
+	if n.children == nil {
+		n.children = make(map[string]*node)
+	}
+	c, ok := n.children[name]
+	if !ok {
+		c = &node{name: name}
+		n.children[name] = c
+	}
+	return c
+}
+
+// sortedChildren returns the children ordered by name, as the order of the
+// frames in a flame graph doesn't mean anything
+func (n *node) sortedChildren() []*node {
+	children := make([]*node, 0, len(n.children))
+	for _, c := range n.children {
+		children = append(children, c)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].name < children[j].name })
+	return children
+}
+
+func (n *node) depth() int {
+	depth := 0
+	for _, c := range n.children {
+		depth = max(depth, c.depth())
+	}
+	return depth + 1
+}
+
+// FlameGraph merges the stacks added to it
+type FlameGraph struct {
+	Title string
+	root  node
+}
+
+func New(title string) *FlameGraph {
+	return &FlameGraph{Title: title, root: node{name: "all"}}
+}
+
+// Add adds a stack, going from the root to the leaf, sampled count times
+func (f *FlameGraph) Add(stack []string, count uint64) {
+	if count == 0 {
+		return
+	}
+	n := &f.root
+	n.value += count
+	for _, frame := range stack {
+		n = n.child(frame)
+		n.value += count
+	}
+}
+
+// WriteFolded writes the merged stacks as folded stacks, one per line
+func (f *FlameGraph) WriteFolded(w io.Writer) error {
+	var walk func(n *node, stack []string) error
+	walk = func(n *node, stack []string) error {
+		var childrenValue uint64
+		for _, c := range n.sortedChildren() {
+			childrenValue += c.value
+			if err := walk(c, append(stack, c.name)); err != nil {
+				return err
+			}
+		}
+		// Samples whose stack ends in this frame
+		if self := n.value - childrenValue; self > 0 && len(stack) > 0 {
+			if _, err := fmt.Fprintln(w, FoldedLine(stack, self)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(&f.root, nil)
+}
+
+// frameColor returns a warm color that only depends on the name, so the same
+// frames have the same color in different flame graphs
+func frameColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	r := 205 + v%50
+	g := (v >> 8) % 230
+	b := (v >> 16) % 55
+	return fmt.Sprintf("rgb(%d,%d,%d)", r, g, b)
+}
+
+// fitName returns name shortened to fit in width pixels, or an empty string
+// if not even a few characters fit
+func fitName(name string, width float64) string {
+	chars := int(width / charWidth)
+	if chars < 3 {
+		return ""
+	}
+	if len(name) <= chars {
+		return name
+	}
+	return name[:chars-2] + ".."
+}
+
+// WriteSVG renders the merged stacks as an SVG flame graph, with the roots at
+// the bottom
+func (f *FlameGraph) WriteSVG(w io.Writer) error {
+	depth := f.root.depth()
+	height := padTop + depth*frameHeight + padBottom
+	total := f.root.value
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
+<rect x="0" y="0" width="%d" height="%d" fill="rgb(248,248,248)"/>
+<text x="%d" y="24" font-size="17" font-family="Verdana" text-anchor="middle">%s</text>
+`, imageWidth, height, imageWidth, height, imageWidth, height, imageWidth/2, html.EscapeString(f.Title))
+
+	scale := 0.0
+	if total > 0 {
+		scale = float64(imageWidth-2*padSide) / float64(total)
+	}
+
+	var draw func(n *node, x float64, level int)
+	draw = func(n *node, x float64, level int) {
+		width := float64(n.value) * scale
+		if width < minFrameWidth {
+			return
+		}
+		y := height - padBottom - (level+1)*frameHeight
+		info := fmt.Sprintf("%s (%d samples, %.2f%%)", n.name, n.value, 100*float64(n.value)/float64(total))
+		fmt.Fprintf(&sb, `<g><title>%s</title><rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2" ry="2"/>`,
+			html.EscapeString(info), x, y, width, frameHeight-1, frameColor(n.name))
+		if label := fitName(n.name, width); label != "" {
+			fmt.Fprintf(&sb, `<text x="%.1f" y="%d" font-size="%d" font-family="Verdana">%s</text>`,
+				x+3, y+frameHeight-4, fontSize, html.EscapeString(label))
+		}
+		sb.WriteString("</g>\n")
+
+		for _, c := range n.sortedChildren() {
+			draw(c, x, level+1)
+			x += float64(c.value) * scale
+		}
+	}
+	if total > 0 {
+		draw(&f.root, padSide, 0)
+	}
+
+	sb.WriteString("</svg>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flamegraph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFoldedLine(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "bash;main;read 12", FoldedLine([]string{"bash", "main", "read"}, 12))
+	require.Equal(t, "a:b;c d 1", FoldedLine([]string{"a;b", "c\nd"}, 1))
+}
+
+func TestWriteFolded(t *testing.T) {
+	t.Parallel()
+
+	f := New("test")
+	f.Add([]string{"bash", "main", "read"}, 3)
+	f.Add([]string{"bash", "main"}, 1)
+	f.Add([]string{"bash", "main", "read"}, 2)
+	f.Add([]string{"app", "[unknown]"}, 4)
+	f.Add([]string{"app"}, 0)
+
+	var buf bytes.Buffer
+	require.NoError(t, f.WriteFolded(&buf))
+	require.Equal(t, "app;[unknown] 4\nbash;main;read 5\nbash;main 1\n", buf.String())
+}
+
+func TestWriteSVG(t *testing.T) {
+	t.Parallel()
+
+	f := New("CPU <profile>")
+	f.Add([]string{"bash", "main", "read"}, 3)
+	f.Add([]string{"app", "[unknown]"}, 1)
+
+	var buf bytes.Buffer
+	require.NoError(t, f.WriteSVG(&buf))
+	svg := buf.String()
+
+	require.True(t, strings.HasPrefix(svg, "<?xml"))
+	require.True(t, strings.HasSuffix(svg, "</svg>\n"))
+	require.Contains(t, svg, "CPU &lt;profile&gt;")
+	// all, app, [unknown], bash, main and read
+	require.Equal(t, 6, strings.Count(svg, "<rect x=\"")-1)
+	require.Contains(t, svg, "<title>all (4 samples, 100.00%)</title>")
+	require.Contains(t, svg, "<title>read (3 samples, 75.00%)</title>")
+	require.Contains(t, svg, "<title>[unknown] (1 samples, 25.00%)</title>")
+}
+
+func TestWriteSVGEmpty(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, New("empty").WriteSVG(&buf))
+	require.NotContains(t, buf.String(), "<title>")
+}
@@ -18,15 +18,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 
 	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	apimachineryruntime "k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	seccompprofile "sigs.k8s.io/security-profiles-operator/api/seccompprofile/v1beta1"
 	k8syaml "sigs.k8s.io/yaml"
@@ -106,6 +109,16 @@ SeccompProfiles will have the following annotations:
 * seccomp.gadget.kinvolk.io/ownerReference-UID: the ownerReference's UID of the
   pod that was traced
 
+By default, one SeccompProfile is generated per pod. With the
+Trace.Spec.Parameters aggregate=workload and the outputMode ExternalResource,
+the syscalls are instead merged into one SeccompProfile per container of the
+workload (Deployment, StatefulSet, DaemonSet, etc.) owning the pods, named
+<kind>-<name>-<container>. The syscalls of all the replicas are added to the
+same SeccompProfile and, as it's updated instead of replaced, the ones of
+previous traces too. Such SeccompProfiles also have the
+seccomp.gadget.kinvolk.io/workload annotation with the kind and name of the
+workload.
+
 SeccompProfiles will have the same labels as the Trace custom resource that
 generated them. They don't have meaning for the seccomp gadget. They are
 merely copied for convenience.
@@ -155,7 +168,9 @@ func (f *TraceFactory) Operations() map[gadgetv1alpha1.Operation]gadgets.TraceOp
 		},
 		gadgetv1alpha1.OperationGenerate: {
 			Doc: `Generate a seccomp profile for the pod specified in Trace.Spec.Filter. The
-namespace and pod name should be specified at the exclusion of other fields.`,
+namespace and pod name should be specified at the exclusion of other fields.
+When aggregating by workload, all the containers matching Trace.Spec.Filter
+are added to the SeccompProfiles of their workloads.`,
 			Operation: func(name string, trace *gadgetv1alpha1.Trace) {
 				f.LookupOrCreate(name, n).(*Trace).Generate(trace)
 			},
@@ -296,6 +311,111 @@ func getSeccompProfileNsName(
 	}, nil
 }
 
+// mergeSyscallNames returns the sorted union of the given syscall names
+func mergeSyscallNames(a, b []string) []string {
+	merged := make([]string, 0, len(a)+len(b))
+	merged = append(merged, a...)
+	merged = append(merged, b...)
+	sort.Strings(merged)
+	return slices.Compact(merged)
+}
+
+// getWorkload returns the kind and name of the workload owning a pod. The
+// ReplicaSets created by a Deployment are named after it with the
+// pod-template-hash as suffix, the Deployment is used instead so the workload
+// doesn't change with every rollout.
+func getWorkload(ownerReference *metav1.OwnerReference, podLabels map[string]string) (string, string) {
+	kind, name := ownerReference.Kind, ownerReference.Name
+	hash := podLabels["pod-template-hash"]
+	if kind == "ReplicaSet" && hash != "" && strings.HasSuffix(name, "-"+hash) {
+		return "Deployment", strings.TrimSuffix(name, "-"+hash)
+	}
+	return kind, name
+}
+
+// getWorkloadProfileNsName computes the namespace and name of the seccomp
+// profile aggregating the syscalls of a container of a workload. Unlike the
+// per-pod profiles, the name is always the same so the profile can be updated
+// by the following traces.
+func getWorkloadProfileNsName(traceNs, traceOutputName, kind, name, containerName string) *SeccompProfileNsName {
+	profileName := strings.ToLower(fmt.Sprintf("%s-%s-%s", kind, name, containerName))
+	namespace := traceNs
+	if traceOutputName != "" {
+		prefix := traceOutputName
+		if parts := strings.SplitN(traceOutputName, "/", 2); len(parts) == 2 {
+			namespace = parts[0]
+			prefix = parts[1]
+		}
+		profileName = prefix + "-" + profileName
+	}
+
+	return &SeccompProfileNsName{
+		namespace: namespace,
+		name:      profileName,
+	}
+}
+
+// writeWorkloadSeccompPolicy adds syscallNames to the SeccompProfile of the
+// workload owning the container, creating it if it doesn't exist yet.
+func writeWorkloadSeccompPolicy(
+	cli client.Client,
+	trace *gadgetv1alpha1.Trace,
+	syscallNames []string,
+	container *containercollection.Container,
+	ownerReference *metav1.OwnerReference,
+) (*seccompprofile.SeccompProfile, error) {
+	podName := fmt.Sprintf("%s/%s", container.K8s.Namespace, container.K8s.PodName)
+	if ownerReference == nil {
+		return nil, fmt.Errorf("pod %s is not owned by a workload", podName)
+	}
+
+	kind, name := getWorkload(ownerReference, container.K8s.PodLabels)
+	profileName := getWorkloadProfileNsName(trace.ObjectMeta.Namespace, trace.Spec.Output,
+		kind, name, container.K8s.ContainerName)
+
+	var r *seccompprofile.SeccompProfile
+	// Containers of the same workload can terminate at the same time on
+	// different nodes, retry with the latest version of the profile.
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		r = &seccompprofile.SeccompProfile{}
+		err := cli.Get(context.TODO(), client.ObjectKey{
+			Namespace: profileName.namespace,
+			Name:      profileName.name,
+		}, r)
+		if apierrors.IsNotFound(err) {
+			r = syscallNamesToSeccompPolicy(profileName, syscallNames)
+			seccompProfileAddLabelsAndAnnotations(r, trace, podName, container.K8s.ContainerName, ownerReference)
+			r.ObjectMeta.Annotations["seccomp.gadget.kinvolk.io/workload"] = fmt.Sprintf("%s/%s", kind, name)
+			return cli.Create(context.TODO(), r)
+		}
+		if err != nil {
+			return err
+		}
+
+		addSyscallNamesToSeccompPolicy(r, syscallNames)
+		if r.ObjectMeta.Annotations == nil {
+			r.ObjectMeta.Annotations = map[string]string{}
+		}
+		if r.ObjectMeta.Labels == nil {
+			r.ObjectMeta.Labels = map[string]string{}
+		}
+		seccompProfileAddLabelsAndAnnotations(r, trace, podName, container.K8s.ContainerName, ownerReference)
+		r.ObjectMeta.Annotations["seccomp.gadget.kinvolk.io/workload"] = fmt.Sprintf("%s/%s", kind, name)
+		return cli.Update(context.TODO(), r)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("writing SeccompProfile %s/%s: %w", profileName.namespace, profileName.name, err)
+	}
+
+	return r, nil
+}
+
+func aggregateByWorkload(trace *gadgetv1alpha1.Trace) bool {
+	return trace.Spec.Parameters[seccomptracer.AggregateParam] == seccomptracer.AggregateWorkload
+}
+
 // generateSeccompPolicy generates a seccomp policy which is ready to be
 // created.
 func generateSeccompPolicy(client client.Client, trace *gadgetv1alpha1.Trace, syscallNames []string, podname, containername, fullPodName string, ownerReference *metav1.OwnerReference) (*seccompprofile.SeccompProfile, error) {
@@ -346,6 +466,18 @@ func (t *Trace) containerTerminated(trace *gadgetv1alpha1.Trace, event container
 	// This field was fetched when the container was created
 	ownerReference := getContainerOwnerReference(event.Container)
 
+	if aggregateByWorkload(trace) {
+		r, err := writeWorkloadSeccompPolicy(t.client, trace, syscallNames, event.Container, ownerReference)
+		if err != nil {
+			log.Errorf("Trace %s: %v", traceName, err)
+			return
+		}
+		log.Infof("Trace %s: added syscalls of pod %s to SeccompProfile %s/%s",
+			traceName, namespacedName, r.ObjectMeta.Namespace, r.ObjectMeta.Name)
+		t.policyGenerated = true
+		return
+	}
+
 	r, err := generateSeccompPolicy(t.client, trace, syscallNames, event.Container.K8s.PodName,
 		event.Container.K8s.ContainerName, namespacedName, ownerReference)
 	if err != nil {
@@ -388,6 +520,21 @@ func getContainerOwnerReference(c *containercollection.Container) *metav1.OwnerR
 
 func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	trace.Status.Output = ""
+
+	switch aggregate := trace.Spec.Parameters[seccomptracer.AggregateParam]; aggregate {
+	case "", seccomptracer.AggregatePod:
+	case seccomptracer.AggregateWorkload:
+		if trace.Spec.OutputMode != gadgetv1alpha1.TraceOutputModeExternalResource {
+			trace.Status.OperationError = fmt.Sprintf("Aggregating by workload is only supported with the outputMode %s",
+				gadgetv1alpha1.TraceOutputModeExternalResource)
+			return
+		}
+	default:
+		trace.Status.OperationError = fmt.Sprintf("Invalid %s parameter %q, possible values are %s and %s",
+			seccomptracer.AggregateParam, aggregate, seccomptracer.AggregatePod, seccomptracer.AggregateWorkload)
+		return
+	}
+
 	if t.started {
 		trace.Status.State = gadgetv1alpha1.TraceStateStarted
 		t.policyGenerated = false
@@ -449,6 +596,10 @@ func (t *Trace) Generate(trace *gadgetv1alpha1.Trace) {
 		trace.Status.OperationError = "Not started"
 		return
 	}
+	if aggregateByWorkload(trace) {
+		t.generateWorkloadPolicies(trace)
+		return
+	}
 	if trace.Spec.Filter == nil || trace.Spec.Filter.Namespace == "" || trace.Spec.Filter.Podname == "" {
 		trace.Status.OperationError = "Missing pod"
 		return
@@ -559,6 +710,37 @@ func (t *Trace) Generate(trace *gadgetv1alpha1.Trace) {
 	}
 }
 
+// generateWorkloadPolicies adds the syscalls of all the containers matching
+// Trace.Spec.Filter to the SeccompProfiles of their workloads. Unlike the
+// per-pod generation, any filter is supported.
+func (t *Trace) generateWorkloadPolicies(trace *gadgetv1alpha1.Trace) {
+	containers := t.helpers.GetContainersBySelector(gadgets.ContainerSelectorFromContainerFilter(trace.Spec.Filter))
+	if len(containers) == 0 {
+		// Notify this only if the policy was not already generated at pod termination
+		if !t.policyGenerated {
+			trace.Status.OperationWarning = "No container matching the filter found"
+		}
+		return
+	}
+
+	var errs []string
+	for _, container := range containers {
+		syscallNames, err := traceSingleton.tracer.Peek(container.Mntns)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("peeking syscalls for mntns %d: %s", container.Mntns, err))
+			continue
+		}
+
+		ownerReference := getContainerOwnerReference(container)
+		if _, err := writeWorkloadSeccompPolicy(t.client, trace, syscallNames, container, ownerReference); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		trace.Status.OperationError = strings.Join(errs, "; ")
+	}
+}
+
 func (t *Trace) Stop(trace *gadgetv1alpha1.Trace) {
 	if !t.started {
 		trace.Status.OperationError = "Not started"
@@ -17,6 +17,8 @@ package seccomp
 import (
 	"testing"
 
+	commonseccomp "github.com/containers/common/pkg/seccomp"
+	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	seccompprofile "sigs.k8s.io/security-profiles-operator/api/seccompprofile/v1beta1"
 )
@@ -215,3 +217,110 @@ func TestGetSeccompProfileNextName(t *testing.T) {
 			nextName, expectedNextName)
 	}
 }
+
+func TestMergeSyscallNames(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []string{}, mergeSyscallNames(nil, nil))
+	require.Equal(t,
+		[]string{"close", "openat", "read", "write"},
+		mergeSyscallNames([]string{"read", "openat", "write"}, []string{"close", "read", "write"}),
+	)
+}
+
+func TestGetWorkload(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		ownerReference metav1.OwnerReference
+		podLabels      map[string]string
+		expectedKind   string
+		expectedName   string
+	}
+
+	tests := map[string]testDefinition{
+		"deployment": {
+			ownerReference: metav1.OwnerReference{Kind: "ReplicaSet", Name: "nginx-5d8f7b9c4d"},
+			podLabels:      map[string]string{"app": "nginx", "pod-template-hash": "5d8f7b9c4d"},
+			expectedKind:   "Deployment",
+			expectedName:   "nginx",
+		},
+		"replicaset": {
+			ownerReference: metav1.OwnerReference{Kind: "ReplicaSet", Name: "nginx"},
+			podLabels:      map[string]string{"app": "nginx"},
+			expectedKind:   "ReplicaSet",
+			expectedName:   "nginx",
+		},
+		"statefulset": {
+			ownerReference: metav1.OwnerReference{Kind: "StatefulSet", Name: "db"},
+			expectedKind:   "StatefulSet",
+			expectedName:   "db",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			kind, name := getWorkload(&test.ownerReference, test.podLabels)
+			require.Equal(t, test.expectedKind, kind)
+			require.Equal(t, test.expectedName, name)
+		})
+	}
+}
+
+func TestGetWorkloadProfileNsName(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		traceOutputName string
+		expected        SeccompProfileNsName
+	}
+
+	tests := map[string]testDefinition{
+		"no_prefix": {
+			expected: SeccompProfileNsName{namespace: "gadget", name: "deployment-nginx-proxy"},
+		},
+		"prefix": {
+			traceOutputName: "myprefix",
+			expected:        SeccompProfileNsName{namespace: "gadget", name: "myprefix-deployment-nginx-proxy"},
+		},
+		"namespace_and_prefix": {
+			traceOutputName: "default/myprefix",
+			expected:        SeccompProfileNsName{namespace: "default", name: "myprefix-deployment-nginx-proxy"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			profileName := getWorkloadProfileNsName("gadget", test.traceOutputName, "Deployment", "nginx", "proxy")
+			require.Equal(t, test.expected, *profileName)
+		})
+	}
+}
+
+func TestAddSyscallNamesToSeccompPolicy(t *testing.T) {
+	t.Parallel()
+
+	profile := syscallNamesToSeccompPolicy(&SeccompProfileNsName{name: "profile"}, []string{"openat", "read"})
+	profile.Spec.Syscalls = append(profile.Spec.Syscalls, &seccompprofile.Syscall{
+		Names:  []string{"kill"},
+		Action: commonseccomp.ActErrno,
+	})
+
+	addSyscallNamesToSeccompPolicy(profile, []string{"close", "read"})
+	require.Len(t, profile.Spec.Syscalls, 2)
+	require.Equal(t, []string{"close", "openat", "read"}, profile.Spec.Syscalls[0].Names)
+	require.Equal(t, []string{"kill"}, profile.Spec.Syscalls[1].Names)
+
+	// A new rule is added when there isn't one allowing syscalls
+	profile.Spec.Syscalls = profile.Spec.Syscalls[1:]
+	addSyscallNamesToSeccompPolicy(profile, []string{"write"})
+	require.Len(t, profile.Spec.Syscalls, 2)
+	require.Equal(t, []string{"write"}, profile.Spec.Syscalls[1].Names)
+	require.Equal(t, commonseccomp.ActAllow, profile.Spec.Syscalls[1].Action)
+}
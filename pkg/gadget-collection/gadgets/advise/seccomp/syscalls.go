@@ -60,3 +60,22 @@ func syscallNamesToSeccompPolicy(profileName *SeccompProfileNsName, syscallNames
 
 	return &ret
 }
+
+// addSyscallNamesToSeccompPolicy adds syscallNames to the syscalls allowed by
+// an existing SeccompProfile. Other rules, like the ones added by hand, are
+// kept as they are.
+func addSyscallNamesToSeccompPolicy(profile *seccompprofile.SeccompProfile, syscallNames []string) {
+	for _, syscall := range profile.Spec.Syscalls {
+		if syscall.Action != commonseccomp.ActAllow || len(syscall.Args) != 0 {
+			continue
+		}
+		syscall.Names = mergeSyscallNames(syscall.Names, syscallNames)
+		return
+	}
+
+	profile.Spec.Syscalls = append(profile.Spec.Syscalls, &seccompprofile.Syscall{
+		Names:  mergeSyscallNames(nil, syscallNames),
+		Action: commonseccomp.ActAllow,
+		Args:   []*seccompprofile.Arg{},
+	})
+}
@@ -25,3 +25,7 @@ func syscallNamesToSeccompPolicy(profileName *SeccompProfileNsName, syscallNames
 	panic("Not implemented")
 	return nil
 }
+
+func addSyscallNamesToSeccompPolicy(profile *seccompprofile.SeccompProfile, syscallNames []string) {
+	panic("Not implemented")
+}
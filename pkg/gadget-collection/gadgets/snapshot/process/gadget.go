@@ -75,6 +75,7 @@ func (t *Trace) Collect(trace *gadgetv1alpha1.Trace) {
 	}
 
 	showThreads := false
+	showDetails := false
 
 	params := trace.Spec.Parameters
 	if params != nil {
@@ -86,10 +87,19 @@ func (t *Trace) Collect(trace *gadgetv1alpha1.Trace) {
 				return
 			}
 		}
+		if val, ok := params[types.ShowDetailsParam]; ok {
+			var err error
+			showDetails, err = strconv.ParseBool(val)
+			if err != nil {
+				trace.Status.OperationError = fmt.Sprintf("%q is not valid for %s: %v", val, types.ShowDetailsParam, err)
+				return
+			}
+		}
 	}
 	config := &tracer.Config{
 		MountnsMap:  mountNsMap,
 		ShowThreads: showThreads,
+		ShowDetails: showDetails,
 	}
 	events, err := tracer.RunCollector(config, t.helpers)
 	if err != nil {
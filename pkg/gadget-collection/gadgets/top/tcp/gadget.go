@@ -59,11 +59,12 @@ The following parameters are supported:
 - %s: Maximum rows to print. (default %d)
 - %s: The field to sort the results by (%s). (default %s)
 - %s: Only get events for this PID (default to all).
-- %s: Only get events for this IP version. (either 4 or 6, default to all)`
+- %s: Only get events for this IP version. (either 4 or 6, default to all)
+- %s: Show the round trip time, retransmissions and congestion window. (default false)`
 	return fmt.Sprintf(t, top.IntervalParam, top.IntervalDefault,
 		top.MaxRowsParam, top.MaxRowsDefault,
 		top.SortByParam, strings.Join(validCols, ","), strings.Join(types.SortByDefault, ","),
-		types.PidParam, types.FamilyParam)
+		types.PidParam, types.FamilyParam, types.TCPInfoParam)
 }
 
 func (f *TraceFactory) OutputModesSupported() map[gadgetv1alpha1.TraceOutputMode]struct{} {
@@ -115,6 +116,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 	sortBy := types.SortByDefault
 	targetPid := int32(0)
 	targetFamily := int32(-1)
+	tcpInfo := false
 
 	if trace.Spec.Parameters != nil {
 		params := trace.Spec.Parameters
@@ -165,6 +167,14 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 				return
 			}
 		}
+
+		if val, ok := params[types.TCPInfoParam]; ok {
+			tcpInfo, err = strconv.ParseBool(val)
+			if err != nil {
+				trace.Status.OperationError = fmt.Sprintf("%q is not valid for %q", val, types.TCPInfoParam)
+				return
+			}
+		}
 	}
 
 	mountNsMap, err := t.helpers.TracerMountNsMap(traceName)
@@ -179,6 +189,7 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		MountnsMap:   mountNsMap,
 		TargetPid:    targetPid,
 		TargetFamily: targetFamily,
+		TCPInfo:      tcpInfo,
 	}
 
 	eventCallback := func(ev *top.Event[types.Stats]) {
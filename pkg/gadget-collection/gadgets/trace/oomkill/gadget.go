@@ -109,7 +109,8 @@ func (t *Trace) Start(trace *gadgetv1alpha1.Trace) {
 		return
 	}
 	config := &tracer.Config{
-		MountnsMap: mountNsMap,
+		MountnsMap:   mountNsMap,
+		TopProcesses: tracer.DefaultTopProcesses,
 	}
 	t.tracer, err = tracer.NewTracer(config, t.helpers, eventCallback)
 	if err != nil {
@@ -20,6 +20,8 @@ package gadgetcontext
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
@@ -50,7 +52,23 @@ type GadgetContext struct {
 	resultError              error
 	timeout                  time.Duration
 	gadgetInfo               *runTypes.GadgetInfo
-}
+
+	// instance is the gadget running locally, if any
+	instanceMu sync.Mutex
+	instance   gadgets.Gadget
+}
+
+var (
+	// ErrGadgetNotStarted is returned when pausing or resuming a gadget that
+	// isn't running locally yet, or anymore
+	ErrGadgetNotStarted = errors.New("gadget isn't running")
+	// ErrGadgetNotPausable is returned when pausing or resuming a gadget that
+	// doesn't implement gadgets.PausableGadget
+	ErrGadgetNotPausable = errors.New("gadget can't be paused")
+	// ErrGadgetParamsNotUpdatable is returned when updating the params of a
+	// gadget that doesn't implement gadgets.UpdatableParamsGadget
+	ErrGadgetParamsNotUpdatable = errors.New("params of the gadget can't be changed while it runs")
+)
 
 func New(
 	ctx context.Context,
@@ -142,6 +160,62 @@ func (c *GadgetContext) GadgetInfo() *runTypes.GadgetInfo {
 	return c.gadgetInfo
 }
 
+// SetGadgetInstance is called by the local runtime with the instance of the
+// gadget it runs, see runtime.GadgetInstanceSetter
+func (c *GadgetContext) SetGadgetInstance(instance gadgets.Gadget) {
+	c.instanceMu.Lock()
+	defer c.instanceMu.Unlock()
+	c.instance = instance
+}
+
+func (c *GadgetContext) pausable() (gadgets.PausableGadget, error) {
+	c.instanceMu.Lock()
+	defer c.instanceMu.Unlock()
+	if c.instance == nil {
+		return nil, ErrGadgetNotStarted
+	}
+	pausable, ok := c.instance.(gadgets.PausableGadget)
+	if !ok {
+		return nil, ErrGadgetNotPausable
+	}
+	return pausable, nil
+}
+
+// Pause pauses the gadget running locally, see gadgets.PausableGadget
+func (c *GadgetContext) Pause() error {
+	pausable, err := c.pausable()
+	if err != nil {
+		return err
+	}
+	return pausable.Pause()
+}
+
+// Resume resumes the gadget paused with Pause
+func (c *GadgetContext) Resume() error {
+	pausable, err := c.pausable()
+	if err != nil {
+		return err
+	}
+	return pausable.Resume()
+}
+
+// UpdateParams changes the params of the gadget running locally, see
+// gadgets.UpdatableParamsGadget
+func (c *GadgetContext) UpdateParams(values map[string]string) error {
+	c.instanceMu.Lock()
+	instance := c.instance
+	c.instanceMu.Unlock()
+
+	if instance == nil {
+		return ErrGadgetNotStarted
+	}
+	updatable, ok := instance.(gadgets.UpdatableParamsGadget)
+	if !ok {
+		return ErrGadgetParamsNotUpdatable
+	}
+	return updatable.UpdateParams(values)
+}
+
 func WithTimeoutOrCancel(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
 	if timeout == 0 {
 		return context.WithCancel(ctx)
@@ -21,6 +21,23 @@ type GadgetManagerClient interface {
 	GetInfo(ctx context.Context, in *InfoRequest, opts ...grpc.CallOption) (*InfoResponse, error)
 	GetGadgetInfo(ctx context.Context, in *GetGadgetInfoRequest, opts ...grpc.CallOption) (*GetGadgetInfoResponse, error)
 	RunGadget(ctx context.Context, opts ...grpc.CallOption) (GadgetManager_RunGadgetClient, error)
+	// ListInstances returns the gadget instances currently running
+	ListInstances(ctx context.Context, in *ListInstancesRequest, opts ...grpc.CallOption) (*ListInstancesResponse, error)
+	GetInstance(ctx context.Context, in *GetInstanceRequest, opts ...grpc.CallOption) (*GadgetInstance, error)
+	// StopInstance stops a running instance, the clients attached to it are
+	// disconnected
+	StopInstance(ctx context.Context, in *StopInstanceRequest, opts ...grpc.CallOption) (*StopInstanceResponse, error)
+	// AttachToInstance streams the events of a running instance until it stops
+	AttachToInstance(ctx context.Context, in *AttachToInstanceRequest, opts ...grpc.CallOption) (GadgetManager_AttachToInstanceClient, error)
+	// PauseInstance stops a running instance from producing events without
+	// stopping it, e.g. by detaching its eBPF programs while keeping its maps
+	PauseInstance(ctx context.Context, in *PauseInstanceRequest, opts ...grpc.CallOption) (*PauseInstanceResponse, error)
+	// ResumeInstance resumes an instance paused with PauseInstance
+	ResumeInstance(ctx context.Context, in *ResumeInstanceRequest, opts ...grpc.CallOption) (*ResumeInstanceResponse, error)
+	// UpdateInstanceParams changes the params of a running instance, only the
+	// ones the gadget can change while it runs, e.g. the ones of its
+	// gadget_config map
+	UpdateInstanceParams(ctx context.Context, in *UpdateInstanceParamsRequest, opts ...grpc.CallOption) (*UpdateInstanceParamsResponse, error)
 }
 
 type gadgetManagerClient struct {
@@ -80,6 +97,92 @@ func (x *gadgetManagerRunGadgetClient) Recv() (*GadgetEvent, error) {
 	return m, nil
 }
 
+func (c *gadgetManagerClient) ListInstances(ctx context.Context, in *ListInstancesRequest, opts ...grpc.CallOption) (*ListInstancesResponse, error) {
+	out := new(ListInstancesResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetManager/ListInstances", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetManagerClient) GetInstance(ctx context.Context, in *GetInstanceRequest, opts ...grpc.CallOption) (*GadgetInstance, error) {
+	out := new(GadgetInstance)
+	err := c.cc.Invoke(ctx, "/api.GadgetManager/GetInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetManagerClient) StopInstance(ctx context.Context, in *StopInstanceRequest, opts ...grpc.CallOption) (*StopInstanceResponse, error) {
+	out := new(StopInstanceResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetManager/StopInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetManagerClient) AttachToInstance(ctx context.Context, in *AttachToInstanceRequest, opts ...grpc.CallOption) (GadgetManager_AttachToInstanceClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GadgetManager_ServiceDesc.Streams[1], "/api.GadgetManager/AttachToInstance", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gadgetManagerAttachToInstanceClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GadgetManager_AttachToInstanceClient interface {
+	Recv() (*GadgetEvent, error)
+	grpc.ClientStream
+}
+
+type gadgetManagerAttachToInstanceClient struct {
+	grpc.ClientStream
+}
+
+func (x *gadgetManagerAttachToInstanceClient) Recv() (*GadgetEvent, error) {
+	m := new(GadgetEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gadgetManagerClient) PauseInstance(ctx context.Context, in *PauseInstanceRequest, opts ...grpc.CallOption) (*PauseInstanceResponse, error) {
+	out := new(PauseInstanceResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetManager/PauseInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetManagerClient) ResumeInstance(ctx context.Context, in *ResumeInstanceRequest, opts ...grpc.CallOption) (*ResumeInstanceResponse, error) {
+	out := new(ResumeInstanceResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetManager/ResumeInstance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gadgetManagerClient) UpdateInstanceParams(ctx context.Context, in *UpdateInstanceParamsRequest, opts ...grpc.CallOption) (*UpdateInstanceParamsResponse, error) {
+	out := new(UpdateInstanceParamsResponse)
+	err := c.cc.Invoke(ctx, "/api.GadgetManager/UpdateInstanceParams", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GadgetManagerServer is the server API for GadgetManager service.
 // All implementations must embed UnimplementedGadgetManagerServer
 // for forward compatibility
@@ -87,6 +190,23 @@ type GadgetManagerServer interface {
 	GetInfo(context.Context, *InfoRequest) (*InfoResponse, error)
 	GetGadgetInfo(context.Context, *GetGadgetInfoRequest) (*GetGadgetInfoResponse, error)
 	RunGadget(GadgetManager_RunGadgetServer) error
+	// ListInstances returns the gadget instances currently running
+	ListInstances(context.Context, *ListInstancesRequest) (*ListInstancesResponse, error)
+	GetInstance(context.Context, *GetInstanceRequest) (*GadgetInstance, error)
+	// StopInstance stops a running instance, the clients attached to it are
+	// disconnected
+	StopInstance(context.Context, *StopInstanceRequest) (*StopInstanceResponse, error)
+	// AttachToInstance streams the events of a running instance until it stops
+	AttachToInstance(*AttachToInstanceRequest, GadgetManager_AttachToInstanceServer) error
+	// PauseInstance stops a running instance from producing events without
+	// stopping it, e.g. by detaching its eBPF programs while keeping its maps
+	PauseInstance(context.Context, *PauseInstanceRequest) (*PauseInstanceResponse, error)
+	// ResumeInstance resumes an instance paused with PauseInstance
+	ResumeInstance(context.Context, *ResumeInstanceRequest) (*ResumeInstanceResponse, error)
+	// UpdateInstanceParams changes the params of a running instance, only the
+	// ones the gadget can change while it runs, e.g. the ones of its
+	// gadget_config map
+	UpdateInstanceParams(context.Context, *UpdateInstanceParamsRequest) (*UpdateInstanceParamsResponse, error)
 	mustEmbedUnimplementedGadgetManagerServer()
 }
 
@@ -103,6 +223,27 @@ func (UnimplementedGadgetManagerServer) GetGadgetInfo(context.Context, *GetGadge
 func (UnimplementedGadgetManagerServer) RunGadget(GadgetManager_RunGadgetServer) error {
 	return status.Errorf(codes.Unimplemented, "method RunGadget not implemented")
 }
+func (UnimplementedGadgetManagerServer) ListInstances(context.Context, *ListInstancesRequest) (*ListInstancesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInstances not implemented")
+}
+func (UnimplementedGadgetManagerServer) GetInstance(context.Context, *GetInstanceRequest) (*GadgetInstance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInstance not implemented")
+}
+func (UnimplementedGadgetManagerServer) StopInstance(context.Context, *StopInstanceRequest) (*StopInstanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopInstance not implemented")
+}
+func (UnimplementedGadgetManagerServer) AttachToInstance(*AttachToInstanceRequest, GadgetManager_AttachToInstanceServer) error {
+	return status.Errorf(codes.Unimplemented, "method AttachToInstance not implemented")
+}
+func (UnimplementedGadgetManagerServer) PauseInstance(context.Context, *PauseInstanceRequest) (*PauseInstanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PauseInstance not implemented")
+}
+func (UnimplementedGadgetManagerServer) ResumeInstance(context.Context, *ResumeInstanceRequest) (*ResumeInstanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumeInstance not implemented")
+}
+func (UnimplementedGadgetManagerServer) UpdateInstanceParams(context.Context, *UpdateInstanceParamsRequest) (*UpdateInstanceParamsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateInstanceParams not implemented")
+}
 func (UnimplementedGadgetManagerServer) mustEmbedUnimplementedGadgetManagerServer() {}
 
 // UnsafeGadgetManagerServer may be embedded to opt out of forward compatibility for this service.
@@ -178,6 +319,135 @@ func (x *gadgetManagerRunGadgetServer) Recv() (*GadgetControlRequest, error) {
 	return m, nil
 }
 
+func _GadgetManager_ListInstances_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInstancesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetManagerServer).ListInstances(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetManager/ListInstances",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetManagerServer).ListInstances(ctx, req.(*ListInstancesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetManager_GetInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetManagerServer).GetInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetManager/GetInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetManagerServer).GetInstance(ctx, req.(*GetInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetManager_StopInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetManagerServer).StopInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetManager/StopInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetManagerServer).StopInstance(ctx, req.(*StopInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetManager_AttachToInstance_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AttachToInstanceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GadgetManagerServer).AttachToInstance(m, &gadgetManagerAttachToInstanceServer{stream})
+}
+
+type GadgetManager_AttachToInstanceServer interface {
+	Send(*GadgetEvent) error
+	grpc.ServerStream
+}
+
+type gadgetManagerAttachToInstanceServer struct {
+	grpc.ServerStream
+}
+
+func (x *gadgetManagerAttachToInstanceServer) Send(m *GadgetEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GadgetManager_PauseInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PauseInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetManagerServer).PauseInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetManager/PauseInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetManagerServer).PauseInstance(ctx, req.(*PauseInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetManager_ResumeInstance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumeInstanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetManagerServer).ResumeInstance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetManager/ResumeInstance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetManagerServer).ResumeInstance(ctx, req.(*ResumeInstanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GadgetManager_UpdateInstanceParams_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateInstanceParamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GadgetManagerServer).UpdateInstanceParams(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.GadgetManager/UpdateInstanceParams",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GadgetManagerServer).UpdateInstanceParams(ctx, req.(*UpdateInstanceParamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GadgetManager_ServiceDesc is the grpc.ServiceDesc for GadgetManager service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -193,6 +463,30 @@ var GadgetManager_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetGadgetInfo",
 			Handler:    _GadgetManager_GetGadgetInfo_Handler,
 		},
+		{
+			MethodName: "ListInstances",
+			Handler:    _GadgetManager_ListInstances_Handler,
+		},
+		{
+			MethodName: "GetInstance",
+			Handler:    _GadgetManager_GetInstance_Handler,
+		},
+		{
+			MethodName: "StopInstance",
+			Handler:    _GadgetManager_StopInstance_Handler,
+		},
+		{
+			MethodName: "PauseInstance",
+			Handler:    _GadgetManager_PauseInstance_Handler,
+		},
+		{
+			MethodName: "ResumeInstance",
+			Handler:    _GadgetManager_ResumeInstance_Handler,
+		},
+		{
+			MethodName: "UpdateInstanceParams",
+			Handler:    _GadgetManager_UpdateInstanceParams_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -201,6 +495,11 @@ var GadgetManager_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "AttachToInstance",
+			Handler:       _GadgetManager_AttachToInstance_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "api/api.proto",
 }
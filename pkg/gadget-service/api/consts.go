@@ -26,4 +26,9 @@ const (
 const (
 	GadgetServicePort = 8080
 	DefaultDaemonPath = "unix:///var/run/ig/ig.socket"
+
+	// AuthorizationMetadataKey is the gRPC metadata key used to send the
+	// "Bearer <token>" authorization header used with the Kubernetes API
+	// server, so that the gadget service can authorize requests
+	AuthorizationMetadataKey = "authorization"
 )
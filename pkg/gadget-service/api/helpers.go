@@ -15,10 +15,42 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	// ErrNoCredentials is returned by TokenFromContext when the client didn't
+	// send any credentials
+	ErrNoCredentials = errors.New("no credentials provided")
+	// ErrInvalidCredentials is returned by TokenFromContext when the
+	// credentials sent by the client aren't a bearer token
+	ErrInvalidCredentials = errors.New("invalid credentials, only bearer tokens are supported")
 )
 
+// TokenFromContext returns the bearer token sent by the client in the
+// metadata of the incoming request in ctx
+func TokenFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	values := md.Get(AuthorizationMetadataKey)
+	if len(values) == 0 {
+		return "", ErrNoCredentials
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" {
+		return "", ErrInvalidCredentials
+	}
+	return token, nil
+}
+
 func ParseSocketAddress(addr string) (string, string, error) {
 	socketURL, err := url.Parse(addr)
 	if err != nil {
@@ -0,0 +1,99 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	verifierLogKey       = "verifierLog"
+	verifierTruncatedKey = "verifierLogTruncated"
+
+	// maxVerifierLogBytes limits the size of the verifier log sent to the
+	// client, it has to fit in the trailers of the response
+	maxVerifierLogBytes = 1024 * 1024
+)
+
+// VerifierLog is the log of the verifier explaining why it rejected a program
+type VerifierLog struct {
+	Lines []string
+	// Truncated is true when the beginning of the log is missing
+	Truncated bool
+}
+
+// ErrorWithVerifierLog returns a gRPC status error with the message of err and
+// the verifier log attached as details. Only the end of the log is kept if
+// it's too big.
+func ErrorWithVerifierLog(err error, log *VerifierLog) error {
+	lines := log.Lines
+	truncated := log.Truncated
+	size := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		size += len(lines[i]) + 1
+		if size > maxVerifierLogBytes {
+			lines = lines[i+1:]
+			truncated = true
+			break
+		}
+	}
+
+	values := make([]any, len(lines))
+	for i, line := range lines {
+		values[i] = line
+	}
+	details, serr := structpb.NewStruct(map[string]any{
+		verifierLogKey:       values,
+		verifierTruncatedKey: truncated,
+	})
+	st := status.New(codes.Unknown, err.Error())
+	if serr != nil {
+		return st.Err()
+	}
+	withDetails, serr := st.WithDetails(details)
+	if serr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// VerifierLogFromError returns the verifier log attached to a gRPC status error
+// by ErrorWithVerifierLog
+func VerifierLogFromError(err error) (*VerifierLog, bool) {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil, false
+	}
+	for _, detail := range st.Details() {
+		s, ok := detail.(*structpb.Struct)
+		if !ok {
+			continue
+		}
+		values, ok := s.GetFields()[verifierLogKey]
+		if !ok {
+			continue
+		}
+		log := &VerifierLog{
+			Truncated: s.GetFields()[verifierTruncatedKey].GetBoolValue(),
+		}
+		for _, value := range values.GetListValue().GetValues() {
+			log.Lines = append(log.Lines, value.GetStringValue())
+		}
+		return log, true
+	}
+	return nil, false
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/status"
+)
+
+func TestVerifierLog(t *testing.T) {
+	t.Parallel()
+
+	log := &VerifierLog{Lines: []string{
+		"0: R1=ctx(off=0,imm=0) R10=fp0",
+		"0: (b7) r0 = 0",
+		"1: (95) exit",
+		"R0 !read_ok",
+	}}
+	err := ErrorWithVerifierLog(errors.New("running gadget: program ig_open: load program: permission denied"), log)
+	require.Equal(t, "running gadget: program ig_open: load program: permission denied", status.Convert(err).Message())
+
+	got, ok := VerifierLogFromError(err)
+	require.True(t, ok)
+	require.Equal(t, log, got)
+
+	_, ok = VerifierLogFromError(errors.New("other error"))
+	require.False(t, ok)
+	_, ok = VerifierLogFromError(status.Error(0, "no details"))
+	require.False(t, ok)
+}
+
+func TestVerifierLogTruncated(t *testing.T) {
+	t.Parallel()
+
+	line := strings.Repeat("x", 1023)
+	lines := make([]string, 2*maxVerifierLogBytes/1024)
+	for i := range lines {
+		lines[i] = line
+	}
+	lines[len(lines)-1] = "last"
+
+	err := ErrorWithVerifierLog(errors.New("failed"), &VerifierLog{Lines: lines})
+	got, ok := VerifierLogFromError(err)
+	require.True(t, ok)
+	require.True(t, got.Truncated)
+	require.Less(t, len(got.Lines), len(lines))
+	require.Equal(t, "last", got.Lines[len(got.Lines)-1])
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	runTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+// AuditEvent is the kind of an audit record
+type AuditEvent string
+
+const (
+	// AuditEventStart is recorded when a gadget starts running
+	AuditEventStart AuditEvent = "start"
+	// AuditEventStop is recorded when a gadget stops running
+	AuditEventStop AuditEvent = "stop"
+	// AuditEventDenied is recorded when a client isn't allowed to run a gadget
+	AuditEventDenied AuditEvent = "denied"
+)
+
+// AuditRecord describes a gadget being started, stopped or denied
+type AuditRecord struct {
+	Time  time.Time  `json:"time"`
+	Event AuditEvent `json:"event"`
+	ID    string     `json:"id"`
+	// Client is the identifier of the client that requested the gadget, see
+	// ClientIdentifier. It's empty for gadgets started by the service itself
+	// or when clients can't be identified.
+	Client         string `json:"client,omitempty"`
+	GadgetCategory string `json:"gadgetCategory,omitempty"`
+	GadgetName     string `json:"gadgetName"`
+	// Image and ImageDigest are only set for gadgets packaged in images
+	Image       string `json:"image,omitempty"`
+	ImageDigest string `json:"imageDigest,omitempty"`
+	// Params are the gadget parameters that aren't set to their default value
+	Params map[string]string `json:"params,omitempty"`
+	// Filters are the operator parameters, like the namespace or the container
+	// name, that aren't set to their default value
+	Filters  map[string]string `json:"filters,omitempty"`
+	Detached bool              `json:"detached,omitempty"`
+	// Duration is only set for stop records
+	Duration string `json:"duration,omitempty"`
+	// Error is the reason a gadget was denied or failed
+	Error string `json:"error,omitempty"`
+}
+
+// AuditSink receives the audit records of the service
+type AuditSink interface {
+	Record(record *AuditRecord) error
+}
+
+// AuditFileSink appends audit records to a file, one JSON object per line
+type AuditFileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewAuditFileSink opens path to append audit records to it, creating it if
+// needed
+func NewAuditFileSink(path string) (*AuditFileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log %q: %w", path, err)
+	}
+	return &AuditFileSink{file: file}, nil
+}
+
+func (a *AuditFileSink) Record(record *AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshaling audit record: %w", err)
+	}
+	data = append(data, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// A single write keeps records from being interleaved with the ones of
+	// other processes appending to the same file
+	if _, err := a.file.Write(data); err != nil {
+		return fmt.Errorf("writing audit record: %w", err)
+	}
+	return nil
+}
+
+func (a *AuditFileSink) Close() error {
+	return a.file.Close()
+}
+
+// nonDefaultParams adds the params that aren't set to their default value to
+// target, with their keys prefixed by prefix
+func nonDefaultParams(target map[string]string, p *params.Params, prefix string) {
+	for _, param := range *p {
+		if !param.IsDefault() {
+			target[prefix+param.Key] = param.String()
+		}
+	}
+}
+
+// newAuditRecord describes the gadget of gadgetCtx, run by client
+func newAuditRecord(event AuditEvent, client string, gadgetCtx *gadgetcontext.GadgetContext) *AuditRecord {
+	gadgetDesc := gadgetCtx.GadgetDesc()
+	record := &AuditRecord{
+		Event:          event,
+		ID:             gadgetCtx.ID(),
+		Client:         client,
+		GadgetCategory: gadgetDesc.Category(),
+		GadgetName:     gadgetDesc.Name(),
+		Params:         map[string]string{},
+		Filters:        map[string]string{},
+	}
+
+	nonDefaultParams(record.Params, gadgetCtx.GadgetParams(), "")
+	for opName, opParams := range gadgetCtx.OperatorsParamCollection() {
+		nonDefaultParams(record.Filters, opParams, "operator."+opName+".")
+	}
+
+	if _, ok := gadgetDesc.(runTypes.RunGadgetDesc); ok && len(gadgetCtx.Args()) > 0 {
+		record.Image = gadgetCtx.Args()[0]
+		if gadgetInfo := gadgetCtx.GadgetInfo(); gadgetInfo != nil {
+			record.ImageDigest = gadgetInfo.ImageDigest
+		}
+	}
+
+	return record
+}
+
+// audit sends record to the audit sink, if any
+func (s *Service) audit(record *AuditRecord) error {
+	if s.auditSink == nil {
+		return nil
+	}
+	record.Time = time.Now()
+	return s.auditSink.Record(record)
+}
+
+// auditDenied records that client wasn't allowed to run the gadget of
+// gadgetCtx because of err
+func (s *Service) auditDenied(client string, gadgetCtx *gadgetcontext.GadgetContext, err error) {
+	record := newAuditRecord(AuditEventDenied, client, gadgetCtx)
+	record.Error = err.Error()
+	if err := s.audit(record); err != nil {
+		s.logger.Warnf("recording denied gadget: %v", err)
+	}
+}
+
+// auditStart records that the gadget of gadgetCtx is starting. The gadget must
+// not run if it fails, so that no gadget runs without being audited. It
+// returns a function to call with the result of the gadget once it stops.
+func (s *Service) auditStart(client string, gadgetCtx *gadgetcontext.GadgetContext, detached bool) (func(err error), error) {
+	startedAt := time.Now()
+
+	record := newAuditRecord(AuditEventStart, client, gadgetCtx)
+	record.Detached = detached
+	if err := s.audit(record); err != nil {
+		return nil, fmt.Errorf("recording gadget start: %w", err)
+	}
+
+	return func(err error) {
+		record := newAuditRecord(AuditEventStop, client, gadgetCtx)
+		record.Detached = detached
+		record.Duration = time.Since(startedAt).String()
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if err := s.audit(record); err != nil {
+			s.logger.Warnf("recording gadget stop: %v", err)
+		}
+	}, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/parser"
+)
+
+type fakeGadgetDesc struct{}
+
+func (fakeGadgetDesc) Name() string                  { return "exec" }
+func (fakeGadgetDesc) Description() string           { return "" }
+func (fakeGadgetDesc) Category() string              { return gadgets.CategoryTrace }
+func (fakeGadgetDesc) Type() gadgets.GadgetType      { return gadgets.TypeTrace }
+func (fakeGadgetDesc) ParamDescs() params.ParamDescs { return nil }
+func (fakeGadgetDesc) Parser() parser.Parser         { return nil }
+func (fakeGadgetDesc) EventPrototype() any           { return nil }
+
+type memoryAuditSink struct {
+	records []*AuditRecord
+	err     error
+}
+
+func (m *memoryAuditSink) Record(record *AuditRecord) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.records = append(m.records, record)
+	return nil
+}
+
+func newAuditGadgetContext(t *testing.T) *gadgetcontext.GadgetContext {
+	gadgetParams := params.ParamDescs{
+		{Key: "ignore-failed", DefaultValue: "true"},
+		{Key: "paths", DefaultValue: "false"},
+	}.ToParams()
+	require.NoError(t, gadgetParams.Set("paths", "true"))
+
+	operatorParams := params.Collection{
+		"LocalManager": params.ParamDescs{
+			{Key: "containername"},
+			{Key: "host", DefaultValue: "false"},
+		}.ToParams(),
+	}
+	require.NoError(t, operatorParams.Set("LocalManager", "containername", "web"))
+
+	return gadgetcontext.New(
+		context.Background(),
+		"id1",
+		nil,
+		nil,
+		fakeGadgetDesc{},
+		gadgetParams,
+		nil,
+		operatorParams,
+		nil,
+		log.StandardLogger(),
+		0,
+		nil,
+	)
+}
+
+func TestAudit(t *testing.T) {
+	t.Parallel()
+
+	sink := &memoryAuditSink{}
+	service := NewService(log.StandardLogger(), 16)
+	service.SetAuditSink(sink)
+
+	gadgetCtx := newAuditGadgetContext(t)
+
+	service.auditDenied("bob", gadgetCtx, errors.New("not allowed"))
+
+	auditStop, err := service.auditStart("alice", gadgetCtx, true)
+	require.NoError(t, err)
+	auditStop(errors.New("failed"))
+
+	require.Len(t, sink.records, 3)
+
+	denied := sink.records[0]
+	require.Equal(t, AuditEventDenied, denied.Event)
+	require.Equal(t, "bob", denied.Client)
+	require.Equal(t, "not allowed", denied.Error)
+
+	start := sink.records[1]
+	require.Equal(t, AuditEventStart, start.Event)
+	require.Equal(t, "id1", start.ID)
+	require.Equal(t, "alice", start.Client)
+	require.Equal(t, "trace", start.GadgetCategory)
+	require.Equal(t, "exec", start.GadgetName)
+	require.Equal(t, map[string]string{"paths": "true"}, start.Params)
+	require.Equal(t, map[string]string{"operator.LocalManager.containername": "web"}, start.Filters)
+	require.True(t, start.Detached)
+	require.Empty(t, start.Duration)
+	require.False(t, start.Time.IsZero())
+
+	stop := sink.records[2]
+	require.Equal(t, AuditEventStop, stop.Event)
+	require.NotEmpty(t, stop.Duration)
+	require.Equal(t, "failed", stop.Error)
+}
+
+func TestAuditStartFails(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	service.SetAuditSink(&memoryAuditSink{err: errors.New("disk full")})
+
+	_, err := service.auditStart("alice", newAuditGadgetContext(t), false)
+	require.ErrorContains(t, err, "disk full")
+}
+
+func TestAuditFileSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	// Records are appended to existing files
+	for i := 0; i < 2; i++ {
+		sink, err := NewAuditFileSink(path)
+		require.NoError(t, err)
+		require.NoError(t, sink.Record(&AuditRecord{Event: AuditEventStart, ID: "id1", GadgetName: "exec"}))
+		require.NoError(t, sink.Close())
+	}
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record AuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		require.Equal(t, AuditEventStart, record.Event)
+		require.Equal(t, "id1", record.ID)
+		lines++
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, 2, lines)
+}
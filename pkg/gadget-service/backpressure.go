@@ -0,0 +1,414 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// BackpressurePolicy defines what happens to the events of a gadget when the
+// client doesn't receive them as fast as they're generated and the events
+// buffer is full
+type BackpressurePolicy string
+
+const (
+	// BackpressureDropNewest drops the events that don't fit in the buffer
+	BackpressureDropNewest BackpressurePolicy = "drop-newest"
+	// BackpressureDropOldest drops the oldest events of the buffer to make
+	// room for the new ones
+	BackpressureDropOldest BackpressurePolicy = "drop-oldest"
+	// BackpressureBlock blocks the gadget until there is room in the buffer.
+	// The events are then lost in the kernel if the gadget can't keep up.
+	BackpressureBlock BackpressurePolicy = "block"
+	// BackpressureSpill writes the events that don't fit in the buffer to a
+	// bounded queue on disk, they're dropped once it's full
+	BackpressureSpill BackpressurePolicy = "spill"
+)
+
+// BackpressurePolicies contains the supported backpressure policies
+var BackpressurePolicies = []BackpressurePolicy{
+	BackpressureDropNewest,
+	BackpressureDropOldest,
+	BackpressureBlock,
+	BackpressureSpill,
+}
+
+// DefaultSpillMaxBytes is the default maximum size of the on-disk queue of
+// each gadget run with BackpressureSpill
+const DefaultSpillMaxBytes = 64 * 1024 * 1024
+
+// Backpressure configures how the events are buffered when the clients are
+// slower than the gadgets
+type Backpressure struct {
+	Policy BackpressurePolicy
+	// SpillDir is the directory where the on-disk queues are created, the
+	// default directory for temporary files is used if empty. Only used with
+	// BackpressureSpill.
+	SpillDir string
+	// SpillMaxBytes is the maximum size of the on-disk queue of each gadget
+	// run. Only used with BackpressureSpill.
+	SpillMaxBytes uint64
+	// MaxMemory is the maximum size, in bytes, of the payloads of the events
+	// buffered in memory for each gadget run. There is no limit if 0.
+	MaxMemory uint64
+}
+
+// ParseBackpressurePolicy returns the backpressure policy named s
+func ParseBackpressurePolicy(s string) (BackpressurePolicy, error) {
+	for _, policy := range BackpressurePolicies {
+		if string(policy) == s {
+			return policy, nil
+		}
+	}
+	return "", fmt.Errorf("invalid backpressure policy %q, expected one of %v", s, BackpressurePolicies)
+}
+
+// droppedClientEvents counts the events dropped because the clients were too
+// slow to receive them
+var droppedClientEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gadget_service_events_dropped_total",
+	Help: "Number of events dropped because the clients didn't receive them fast enough",
+}, []string{"policy"})
+
+func init() {
+	prometheus.MustRegister(droppedClientEvents)
+}
+
+// eventQueue buffers the events of a gadget run until they're sent to the
+// client, applying the backpressure policy once length events or maxMemory
+// bytes are buffered
+type eventQueue struct {
+	policy    BackpressurePolicy
+	length    int
+	maxMemory uint64
+
+	mu      sync.Mutex
+	events  []*api.GadgetEvent
+	memory  uint64
+	spill   *spillQueue
+	dropped uint64
+	closed  bool
+
+	// available is signaled when events are pushed, space when they're
+	// popped and done when the queue is closed
+	available chan struct{}
+	space     chan struct{}
+	done      chan struct{}
+}
+
+func newEventQueue(length uint64, backpressure Backpressure) (*eventQueue, error) {
+	q := &eventQueue{
+		policy:    backpressure.Policy,
+		length:    int(length),
+		maxMemory: backpressure.MaxMemory,
+		available: make(chan struct{}, 1),
+		space:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	if q.policy == "" {
+		q.policy = BackpressureDropNewest
+	}
+	if q.length < 1 {
+		q.length = 1
+	}
+	if q.policy == BackpressureSpill {
+		maxBytes := backpressure.SpillMaxBytes
+		if maxBytes == 0 {
+			maxBytes = DefaultSpillMaxBytes
+		}
+		spill, err := newSpillQueue(backpressure.SpillDir, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		q.spill = spill
+	}
+	return q, nil
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// push adds ev to the queue. With BackpressureBlock it waits until there is
+// room for it or the queue is closed.
+func (q *eventQueue) push(ev *api.GadgetEvent) {
+	q.mu.Lock()
+	for !q.closed && q.policy == BackpressureBlock && q.full(ev) {
+		q.mu.Unlock()
+		select {
+		case <-q.space:
+		case <-q.done:
+		}
+		q.mu.Lock()
+	}
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+
+	// Events already spilled have to be sent first to keep the order
+	if !q.full(ev) && (q.spill == nil || q.spill.empty()) {
+		q.append(ev)
+		signal(q.available)
+		return
+	}
+
+	switch q.policy {
+	case BackpressureDropOldest:
+		for q.full(ev) {
+			q.shift()
+			q.drop()
+		}
+		q.append(ev)
+	case BackpressureSpill:
+		if err := q.spill.push(ev); err != nil {
+			q.drop()
+		}
+	default:
+		q.drop()
+	}
+	signal(q.available)
+}
+
+// full returns whether ev doesn't fit in memory. An event is always accepted
+// by an empty queue, even if it's bigger than maxMemory.
+func (q *eventQueue) full(ev *api.GadgetEvent) bool {
+	if len(q.events) == 0 {
+		return false
+	}
+	if len(q.events) >= q.length {
+		return true
+	}
+	return q.maxMemory > 0 && q.memory+eventSize(ev) > q.maxMemory
+}
+
+func eventSize(ev *api.GadgetEvent) uint64 {
+	return uint64(len(ev.Payload))
+}
+
+func (q *eventQueue) append(ev *api.GadgetEvent) {
+	q.events = append(q.events, ev)
+	q.memory += eventSize(ev)
+}
+
+// shift removes the oldest event from memory and returns it
+func (q *eventQueue) shift() *api.GadgetEvent {
+	ev := q.events[0]
+	q.events[0] = nil
+	q.events = q.events[1:]
+	q.memory -= eventSize(ev)
+	return ev
+}
+
+func (q *eventQueue) drop() {
+	q.dropped++
+	droppedClientEvents.WithLabelValues(string(q.policy)).Inc()
+}
+
+// pop returns the oldest event of the queue, waiting for one if it's empty. It
+// returns false if stop receives or the queue is closed while waiting.
+func (q *eventQueue) pop(stop <-chan bool) (*api.GadgetEvent, bool) {
+	for {
+		q.mu.Lock()
+		ev := q.next()
+		q.mu.Unlock()
+		if ev != nil {
+			signal(q.space)
+			return ev, true
+		}
+
+		select {
+		case <-q.available:
+		case <-stop:
+			return nil, false
+		case <-q.done:
+			return nil, false
+		}
+	}
+}
+
+// next returns the oldest event, or nil if the queue is empty. Spilled events
+// are moved to memory as the events are sent.
+func (q *eventQueue) next() *api.GadgetEvent {
+	var ev *api.GadgetEvent
+	if len(q.events) > 0 {
+		ev = q.shift()
+	}
+
+	for q.spill != nil && !q.spill.empty() && len(q.events) < q.length &&
+		(q.maxMemory == 0 || q.memory < q.maxMemory) {
+		spilled, err := q.spill.pop()
+		if err != nil {
+			// The spill file can't be read anymore, drop what's left
+			dropped := q.spill.reset()
+			q.dropped += dropped
+			droppedClientEvents.WithLabelValues(string(q.policy)).Add(float64(dropped))
+			break
+		}
+		q.append(spilled)
+	}
+
+	if ev == nil && len(q.events) > 0 {
+		ev = q.shift()
+	}
+	return ev
+}
+
+// takeDropped returns the number of events dropped since it was last called
+func (q *eventQueue) takeDropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	dropped := q.dropped
+	q.dropped = 0
+	return dropped
+}
+
+// close discards the buffered events and releases the goroutines waiting on
+// the queue. Events pushed afterwards are discarded.
+func (q *eventQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.events = nil
+	q.memory = 0
+	close(q.done)
+	if q.spill != nil {
+		q.spill.close()
+	}
+}
+
+// spillQueue is a FIFO of events stored in a file, each one prefixed by its
+// size. The file is truncated once all the events have been read.
+type spillQueue struct {
+	file     *os.File
+	writer   *bufio.Writer
+	maxBytes uint64
+	readOff  int64
+	writeOff int64
+	// count is the number of events in the file
+	count uint64
+}
+
+func newSpillQueue(dir string, maxBytes uint64) (*spillQueue, error) {
+	file, err := os.CreateTemp(dir, "gadget-events-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating spill file: %w", err)
+	}
+	// Nobody else needs to find it and this way it's removed even if we
+	// crash
+	if err := os.Remove(file.Name()); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("removing spill file: %w", err)
+	}
+	return &spillQueue{
+		file:     file,
+		writer:   bufio.NewWriter(file),
+		maxBytes: maxBytes,
+	}, nil
+}
+
+func (s *spillQueue) empty() bool {
+	return s.count == 0
+}
+
+// reset discards the events in the file and returns how many there were
+func (s *spillQueue) reset() uint64 {
+	count := s.count
+	s.writer.Reset(s.file)
+	s.file.Truncate(0)
+	s.file.Seek(0, io.SeekStart)
+	s.readOff = 0
+	s.writeOff = 0
+	s.count = 0
+	return count
+}
+
+func (s *spillQueue) push(ev *api.GadgetEvent) error {
+	data, err := proto.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("marshaling event: %w", err)
+	}
+	size := int64(binary.MaxVarintLen64 + len(data))
+	if uint64(s.writeOff+size) > s.maxBytes {
+		return errors.New("spill file is full")
+	}
+
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(data)))
+	if _, err := s.writer.Write(prefix[:n]); err != nil {
+		return fmt.Errorf("writing spill file: %w", err)
+	}
+	if _, err := s.writer.Write(data); err != nil {
+		return fmt.Errorf("writing spill file: %w", err)
+	}
+	s.writeOff += int64(n + len(data))
+	s.count++
+	return nil
+}
+
+func (s *spillQueue) pop() (*api.GadgetEvent, error) {
+	if err := s.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("writing spill file: %w", err)
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.file, s.readOff, s.writeOff-s.readOff))
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading spill file: %w", err)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("reading spill file: %w", err)
+	}
+	s.readOff += int64(uvarintLen(size)) + int64(size)
+	s.count--
+
+	if s.empty() {
+		s.reset()
+	}
+
+	ev := &api.GadgetEvent{}
+	if err := proto.Unmarshal(data, ev); err != nil {
+		return nil, fmt.Errorf("unmarshaling event: %w", err)
+	}
+	return ev, nil
+}
+
+func uvarintLen(x uint64) int {
+	var buf [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(buf[:], x)
+}
+
+func (s *spillQueue) close() {
+	s.file.Close()
+}
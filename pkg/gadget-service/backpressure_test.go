@@ -0,0 +1,238 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func pushEvents(q *eventQueue, from, to uint32) {
+	for seq := from; seq <= to; seq++ {
+		q.push(&api.GadgetEvent{Seq: seq, Payload: []byte("payload")})
+	}
+}
+
+func popEvents(t *testing.T, q *eventQueue, n int) []uint32 {
+	t.Helper()
+
+	stop := make(chan bool)
+	var seqs []uint32
+	for i := 0; i < n; i++ {
+		ev, ok := q.pop(stop)
+		require.True(t, ok)
+		seqs = append(seqs, ev.Seq)
+	}
+	return seqs
+}
+
+func TestEventQueue(t *testing.T) {
+	type testDefinition struct {
+		backpressure    Backpressure
+		expectedSeqs    []uint32
+		expectedDropped uint64
+	}
+
+	tests := map[string]testDefinition{
+		"drop_newest": {
+			backpressure:    Backpressure{Policy: BackpressureDropNewest},
+			expectedSeqs:    []uint32{1, 2, 3},
+			expectedDropped: 2,
+		},
+		"default_drops_newest": {
+			expectedSeqs:    []uint32{1, 2, 3},
+			expectedDropped: 2,
+		},
+		"drop_oldest": {
+			backpressure:    Backpressure{Policy: BackpressureDropOldest},
+			expectedSeqs:    []uint32{3, 4, 5},
+			expectedDropped: 2,
+		},
+		"spill": {
+			backpressure: Backpressure{Policy: BackpressureSpill, SpillDir: t.TempDir()},
+			expectedSeqs: []uint32{1, 2, 3, 4, 5},
+		},
+		"spill_full": {
+			backpressure: Backpressure{
+				Policy:   BackpressureSpill,
+				SpillDir: t.TempDir(),
+				// Only room for one event
+				SpillMaxBytes: 30,
+			},
+			expectedSeqs:    []uint32{1, 2, 3, 4},
+			expectedDropped: 1,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			q, err := newEventQueue(3, test.backpressure)
+			require.NoError(t, err)
+			defer q.close()
+
+			pushEvents(q, 1, 5)
+			require.Equal(t, test.expectedSeqs, popEvents(t, q, len(test.expectedSeqs)))
+			require.Equal(t, test.expectedDropped, q.takeDropped())
+			require.Equal(t, uint64(0), q.takeDropped())
+		})
+	}
+}
+
+func TestEventQueueMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		backpressure    Backpressure
+		expectedSeqs    []uint32
+		expectedDropped uint64
+	}
+
+	// Each payload is 7 bytes long, so only two events fit in memory
+	tests := map[string]testDefinition{
+		"drop_newest": {
+			backpressure:    Backpressure{Policy: BackpressureDropNewest, MaxMemory: 20},
+			expectedSeqs:    []uint32{1, 2},
+			expectedDropped: 3,
+		},
+		"drop_oldest": {
+			backpressure:    Backpressure{Policy: BackpressureDropOldest, MaxMemory: 20},
+			expectedSeqs:    []uint32{4, 5},
+			expectedDropped: 3,
+		},
+		"spill": {
+			backpressure: Backpressure{Policy: BackpressureSpill, SpillDir: t.TempDir(), MaxMemory: 20},
+			expectedSeqs: []uint32{1, 2, 3, 4, 5},
+		},
+		"bigger_than_max": {
+			backpressure:    Backpressure{Policy: BackpressureDropNewest, MaxMemory: 5},
+			expectedSeqs:    []uint32{1},
+			expectedDropped: 4,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			q, err := newEventQueue(10, test.backpressure)
+			require.NoError(t, err)
+			defer q.close()
+
+			pushEvents(q, 1, 5)
+			if test.backpressure.Policy == BackpressureSpill {
+				require.Len(t, q.events, 2)
+			}
+			require.Equal(t, test.expectedSeqs, popEvents(t, q, len(test.expectedSeqs)))
+			require.Equal(t, test.expectedDropped, q.takeDropped())
+			require.Equal(t, uint64(0), q.memory)
+		})
+	}
+}
+
+func TestEventQueueSpillOrder(t *testing.T) {
+	t.Parallel()
+
+	q, err := newEventQueue(2, Backpressure{Policy: BackpressureSpill, SpillDir: t.TempDir()})
+	require.NoError(t, err)
+	defer q.close()
+
+	// Events pushed while there are spilled ones are sent after them
+	pushEvents(q, 1, 4)
+	require.Equal(t, []uint32{1}, popEvents(t, q, 1))
+	pushEvents(q, 5, 6)
+	require.Equal(t, []uint32{2, 3, 4, 5, 6}, popEvents(t, q, 5))
+	require.True(t, q.spill.empty())
+
+	// The file is reused once it's empty
+	pushEvents(q, 7, 9)
+	require.Equal(t, []uint32{7, 8, 9}, popEvents(t, q, 3))
+}
+
+func TestEventQueueBlock(t *testing.T) {
+	t.Parallel()
+
+	q, err := newEventQueue(1, Backpressure{Policy: BackpressureBlock})
+	require.NoError(t, err)
+	defer q.close()
+
+	pushEvents(q, 1, 1)
+
+	pushed := make(chan struct{})
+	go func() {
+		pushEvents(q, 2, 2)
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatalf("push didn't block with a full queue")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.Equal(t, []uint32{1}, popEvents(t, q, 1))
+	select {
+	case <-pushed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("push didn't finish after popping an event")
+	}
+	require.Equal(t, []uint32{2}, popEvents(t, q, 1))
+	require.Equal(t, uint64(0), q.takeDropped())
+}
+
+func TestEventQueueClose(t *testing.T) {
+	t.Parallel()
+
+	q, err := newEventQueue(1, Backpressure{Policy: BackpressureBlock})
+	require.NoError(t, err)
+
+	pushEvents(q, 1, 1)
+
+	pushed := make(chan struct{})
+	go func() {
+		pushEvents(q, 2, 2)
+		close(pushed)
+	}()
+
+	// Closing releases both the blocked pushes and pops
+	q.close()
+	select {
+	case <-pushed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("push didn't finish after closing the queue")
+	}
+	_, ok := q.pop(make(chan bool))
+	require.False(t, ok)
+}
+
+func TestParseBackpressurePolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range BackpressurePolicies {
+		parsed, err := ParseBackpressurePolicy(string(policy))
+		require.NoError(t, err)
+		require.Equal(t, policy, parsed)
+	}
+
+	_, err := ParseBackpressurePolicy("foo")
+	require.ErrorContains(t, err, "invalid backpressure policy \"foo\"")
+}
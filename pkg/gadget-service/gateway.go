@@ -0,0 +1,427 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// HTTPRunRequest is the body of the requests to run a gadget through the HTTP
+// gateway
+type HTTPRunRequest struct {
+	GadgetCategory string            `json:"gadgetCategory"`
+	GadgetName     string            `json:"gadgetName"`
+	Params         map[string]string `json:"params,omitempty"`
+	Args           []string          `json:"args,omitempty"`
+	// Timeout is a duration like "10s". The gadget runs until the client
+	// disconnects if empty.
+	Timeout  string `json:"timeout,omitempty"`
+	LogLevel string `json:"logLevel,omitempty"`
+}
+
+// wsWriteTimeout is the maximum time to write a WebSocket message
+const wsWriteTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{}
+
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// HTTPGateway exposes the API of the service over HTTP+JSON:
+//
+//	GET  /api/v1/info                  returns the catalog of gadgets
+//	GET  /api/v1/instances             returns the gadgets that are running
+//	GET  /api/v1/instances/{id}/events streams the events of a running gadget
+//	                                   over a WebSocket, one JSON event per frame
+//	POST /api/v1/run                   runs a gadget and streams its events
+//	                                   using server-sent events
+type HTTPGateway struct {
+	service *Service
+	mux     *http.ServeMux
+}
+
+func NewHTTPGateway(service *Service) *HTTPGateway {
+	g := &HTTPGateway{
+		service: service,
+		mux:     http.NewServeMux(),
+	}
+	g.mux.HandleFunc("/api/v1/info", g.handleInfo)
+	g.mux.HandleFunc("/api/v1/instances", g.handleInstances)
+	g.mux.HandleFunc("/api/v1/instances/", g.handleInstanceEvents)
+	g.mux.HandleFunc("/api/v1/run", g.handleRun)
+	return g
+}
+
+func (g *HTTPGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Forward the credentials of the client like gRPC clients do, so that the
+	// authorizer of the service can check them
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		md := metadata.Pairs(api.AuthorizationMetadataKey, authorization)
+		r = r.WithContext(metadata.NewIncomingContext(r.Context(), md))
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+// authenticate returns false and writes an error if the authorizer of the
+// service can't authenticate the client
+func (g *HTTPGateway) authenticate(w http.ResponseWriter, r *http.Request) bool {
+	authenticator, ok := g.service.authorizer.(Authenticator)
+	if !ok {
+		return true
+	}
+	if err := authenticator.Authenticate(r.Context()); err != nil {
+		writeError(w, http.StatusUnauthorized, "%s", status.Convert(err).Message())
+		return false
+	}
+	return true
+}
+
+// clientID returns the identifier of the client that sent the request, like
+// Service.clientID does for gRPC requests. It returns false and writes an
+// error if the client can't be identified.
+func (g *HTTPGateway) clientID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	client, err := g.service.clientID(r.Context())
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, "%s", status.Convert(err).Message())
+		return "", false
+	}
+	return client, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, httpError{Error: fmt.Sprintf(format, args...)})
+}
+
+func checkMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return false
+	}
+	return true
+}
+
+func (g *HTTPGateway) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) || !g.authenticate(w, r) {
+		return
+	}
+
+	catalog, err := g.service.runtime.GetCatalog()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "getting catalog: %s", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, catalog)
+}
+
+func (g *HTTPGateway) handleInstances(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) || !g.authenticate(w, r) {
+		return
+	}
+	client, ok := g.clientID(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, g.service.RunningGadgets(client))
+}
+
+func (g *HTTPGateway) handleInstanceEvents(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodGet) || !g.authenticate(w, r) {
+		return
+	}
+
+	id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/instances/"), "/events")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	client, ok := g.clientID(w, r)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe, err := g.service.SubscribeRunningGadget(id, client)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "%s: %s", err, id)
+		return
+	}
+	defer unsubscribe()
+
+	// Upgrade writes an error response itself on failure
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Read messages to process control frames and detect when the client
+	// goes away
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case data, ok := <-events:
+			if !ok {
+				msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "gadget stopped")
+				conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-clientGone:
+			return
+		}
+	}
+}
+
+// checkRunRequest returns false and writes an error if the request to run a
+// gadget could come from a web page of another site: browsers send simple
+// cross-site requests, e.g. with a text/plain body, without asking first.
+func checkRunRequest(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return false
+	}
+
+	// Like the WebSocket connections, only same-origin browser requests are
+	// accepted. Clients other than browsers don't send an origin.
+	if origin := r.Header.Get("Origin"); origin != "" {
+		u, err := url.Parse(origin)
+		if err != nil || !strings.EqualFold(u.Host, r.Host) {
+			writeError(w, http.StatusForbidden, "origin %q not allowed", origin)
+			return false
+		}
+	}
+	return true
+}
+
+func (g *HTTPGateway) handleRun(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r, http.MethodPost) || !checkRunRequest(w, r) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	var req HTTPRunRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "decoding request: %s", err)
+		return
+	}
+
+	runRequest := &api.GadgetRunRequest{
+		GadgetCategory: req.GadgetCategory,
+		GadgetName:     req.GadgetName,
+		Params:         req.Params,
+		Args:           req.Args,
+		LogLevel:       uint32(logger.InfoLevel),
+	}
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "parsing timeout: %s", err)
+			return
+		}
+		runRequest.Timeout = int64(timeout)
+	}
+	if req.LogLevel != "" {
+		level, err := log.ParseLevel(req.LogLevel)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "parsing log level: %s", err)
+			return
+		}
+		runRequest.LogLevel = uint32(level)
+	}
+
+	stream := &sseRunStream{
+		ctx:     r.Context(),
+		request: runRequest,
+		w:       w,
+		flusher: flusher,
+	}
+	err := g.service.RunGadget(stream)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	// The response writer can't be used after returning
+	stream.closed = true
+
+	if !stream.started {
+		// Nothing was sent yet, the request itself was wrong
+		if err == nil {
+			err = fmt.Errorf("gadget didn't run")
+		}
+		writeError(w, httpStatus(err), "%s", status.Convert(err).Message())
+		return
+	}
+	if err != nil {
+		stream.writeEvent("error", []byte(err.Error()))
+		return
+	}
+	stream.writeEvent("done", nil)
+}
+
+// httpStatus returns the HTTP status code corresponding to an error returned
+// when running a gadget
+func httpStatus(err error) int {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// sseRunStream is used to run gadgets through the same code path as gRPC
+// clients. It sends the events of the gadget as server-sent events.
+type sseRunStream struct {
+	// Only Context() is used from grpc.ServerStream
+	grpc.ServerStream
+
+	ctx      context.Context
+	request  *api.GadgetRunRequest
+	received bool
+
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+	closed  bool
+}
+
+func (s *sseRunStream) Context() context.Context {
+	return s.ctx
+}
+
+// Recv returns the run request first, and then blocks until the client
+// disconnects, which stops the gadget.
+func (s *sseRunStream) Recv() (*api.GadgetControlRequest, error) {
+	if !s.received {
+		s.received = true
+		return &api.GadgetControlRequest{
+			Event: &api.GadgetControlRequest_RunRequest{RunRequest: s.request},
+		}, nil
+	}
+	<-s.ctx.Done()
+	return nil, s.ctx.Err()
+}
+
+func (s *sseRunStream) Send(ev *api.GadgetEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return io.ErrClosedPipe
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", "text/event-stream")
+		s.w.Header().Set("Cache-Control", "no-cache")
+		s.w.WriteHeader(http.StatusOK)
+	}
+
+	if level := ev.Type >> api.EventLogShift; level != 0 {
+		data, _ := json.Marshal(struct {
+			Level   string `json:"level"`
+			Message string `json:"message"`
+		}{
+			Level:   logger.Level(level).String(),
+			Message: string(ev.Payload),
+		})
+		return s.writeEvent("log", data)
+	}
+
+	switch ev.Type {
+	case api.EventTypeGadgetPayload:
+		return s.writeEvent("payload", ev.Payload)
+	case api.EventTypeGadgetResult:
+		return s.writeEvent("result", ev.Payload)
+	case api.EventTypeGadgetJobID:
+		return s.writeEvent("id", ev.Payload)
+	}
+	return nil
+}
+
+// writeEvent writes a server-sent event. It must be called with s.mu held.
+func (s *sseRunStream) writeEvent(event string, data []byte) error {
+	if _, err := io.WriteString(s.w, formatSSE(event, data)); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// formatSSE formats a server-sent event. Each line of data is sent in its own
+// data field.
+func formatSSE(event string, data []byte) string {
+	var sb strings.Builder
+	sb.WriteString("event: ")
+	sb.WriteString(event)
+	sb.WriteString("\n")
+	for _, line := range strings.Split(string(data), "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
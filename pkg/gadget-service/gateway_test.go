@@ -0,0 +1,310 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestFormatSSE(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "event: payload\ndata: {\"a\":1}\n\n", formatSSE("payload", []byte(`{"a":1}`)))
+	require.Equal(t, "event: result\ndata: line1\ndata: line2\n\n", formatSSE("result", []byte("line1\nline2")))
+	require.Equal(t, "event: done\ndata: \n\n", formatSSE("done", nil))
+}
+
+func TestHTTPGatewayInstances(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	gateway := NewHTTPGateway(service)
+
+	untrack := service.addRunningGadget(newRunningGadget("id1", "", &api.GadgetRunRequest{
+		GadgetCategory: "trace",
+		GadgetName:     "exec",
+		Params:         map[string]string{"operator.LocalManager.containername": "foo"},
+	}))
+
+	rec := httptest.NewRecorder()
+	gateway.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/instances", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var instances []RunningGadget
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &instances))
+	require.Len(t, instances, 1)
+	require.Equal(t, "id1", instances[0].ID)
+	require.Equal(t, "trace", instances[0].GadgetCategory)
+	require.Equal(t, "exec", instances[0].GadgetName)
+	require.Equal(t, "foo", instances[0].Params["operator.LocalManager.containername"])
+
+	untrack()
+
+	rec = httptest.NewRecorder()
+	gateway.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/instances", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &instances))
+	require.Empty(t, instances)
+}
+
+func TestHTTPGatewayRunErrors(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	gateway := NewHTTPGateway(service)
+
+	type testDefinition struct {
+		method      string
+		contentType string
+		origin      string
+		body        string
+		code        int
+		errMsg      string
+	}
+
+	tests := map[string]testDefinition{
+		"wrong_method": {
+			method: http.MethodGet,
+			code:   http.StatusMethodNotAllowed,
+		},
+		"missing_content_type": {
+			method:      http.MethodPost,
+			contentType: "-",
+			body:        `{"gadgetCategory":"trace","gadgetName":"exec"}`,
+			code:        http.StatusUnsupportedMediaType,
+			errMsg:      "content type must be application/json",
+		},
+		"text_plain": {
+			method:      http.MethodPost,
+			contentType: "text/plain",
+			body:        `{"gadgetCategory":"trace","gadgetName":"exec"}`,
+			code:        http.StatusUnsupportedMediaType,
+			errMsg:      "content type must be application/json",
+		},
+		"cross_origin": {
+			method: http.MethodPost,
+			origin: "https://evil.example.com",
+			body:   `{"gadgetCategory":"trace","gadgetName":"exec"}`,
+			code:   http.StatusForbidden,
+			errMsg: "origin \"https://evil.example.com\" not allowed",
+		},
+		"same_origin": {
+			method: http.MethodPost,
+			origin: "http://example.com",
+			body:   `{"gadgetCategory":"foo","gadgetName":"bar"}`,
+			code:   http.StatusBadRequest,
+			errMsg: "gadget not found",
+		},
+		"invalid_body": {
+			method: http.MethodPost,
+			body:   "{",
+			code:   http.StatusBadRequest,
+			errMsg: "decoding request",
+		},
+		"invalid_timeout": {
+			method: http.MethodPost,
+			body:   `{"gadgetCategory":"trace","gadgetName":"exec","timeout":"foo"}`,
+			code:   http.StatusBadRequest,
+			errMsg: "parsing timeout",
+		},
+		"unknown_gadget": {
+			method: http.MethodPost,
+			body:   `{"gadgetCategory":"foo","gadgetName":"bar"}`,
+			code:   http.StatusBadRequest,
+			errMsg: "gadget not found",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, "/api/v1/run", strings.NewReader(test.body))
+			switch test.contentType {
+			case "":
+				req.Header.Set("Content-Type", "application/json; charset=utf-8")
+			case "-":
+			default:
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			gateway.ServeHTTP(rec, req)
+			require.Equal(t, test.code, rec.Code)
+
+			var resp httpError
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Contains(t, resp.Error, test.errMsg)
+		})
+	}
+}
+
+func TestHTTPGatewayInstanceEvents(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	server := httptest.NewServer(NewHTTPGateway(service))
+	defer server.Close()
+
+	running := newRunningGadget("id1", "", &api.GadgetRunRequest{GadgetCategory: "trace", GadgetName: "exec"})
+	untrack := service.addRunningGadget(running)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/instances/"
+
+	// Unknown gadget
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"foo/events", nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"id1/events", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The subscription is done before upgrading the connection, so the event
+	// can't be lost
+	running.publish([]byte(`{"comm":"ls"}`))
+
+	msgType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, websocket.TextMessage, msgType)
+	require.JSONEq(t, `{"comm":"ls"}`, string(data))
+
+	// The connection is closed when the gadget stops
+	untrack()
+
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "unexpected error: %v", err)
+}
+
+// tokenAuthorizer accepts requests carrying a fixed token
+type tokenAuthorizer struct {
+	token string
+}
+
+func (a *tokenAuthorizer) Authenticate(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(api.AuthorizationMetadataKey); len(values) == 0 || values[0] != "Bearer "+a.token {
+		return status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	return nil
+}
+
+func (a *tokenAuthorizer) Authorize(ctx context.Context, gadgetCtx *gadgetcontext.GadgetContext) error {
+	return a.Authenticate(ctx)
+}
+
+func TestHTTPGatewayAuthentication(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	service.SetAuthorizer(&tokenAuthorizer{token: "foo"})
+	gateway := NewHTTPGateway(service)
+
+	type testDefinition struct {
+		authorization string
+		code          int
+	}
+
+	tests := map[string]testDefinition{
+		"no_credentials": {
+			code: http.StatusUnauthorized,
+		},
+		"wrong_token": {
+			authorization: "Bearer bar",
+			code:          http.StatusUnauthorized,
+		},
+		"valid_token": {
+			authorization: "Bearer foo",
+			code:          http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/instances", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			gateway.ServeHTTP(rec, req)
+			require.Equal(t, test.code, rec.Code)
+		})
+	}
+}
+
+func TestHTTPGatewayInstancesOwnership(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	service.SetAuthorizer(fakeClientAuthorizer{})
+	server := httptest.NewServer(NewHTTPGateway(service))
+	defer server.Close()
+
+	defer service.addRunningGadget(newRunningGadget("id1", "alice", &api.GadgetRunRequest{GadgetCategory: "trace", GadgetName: "exec"}))()
+
+	get := func(client string) []RunningGadget {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/instances", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+client)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var instances []RunningGadget
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&instances))
+		return instances
+	}
+
+	require.Len(t, get("alice"), 1)
+	require.Empty(t, get("bob"))
+
+	resp, err := http.Get(server.URL + "/api/v1/instances")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/instances/id1/events"
+
+	// The instances of other clients look like they don't exist
+	_, resp, err = websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": []string{"Bearer bob"}})
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Authorization": []string{"Bearer alice"}})
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	goruntime "runtime"
+	"sync"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+)
+
+// ClientIdentifier is implemented by authorizers that can tell which client a
+// request comes from. When the authorizer of the service implements it, quotas
+// apply to each client separately; otherwise all clients share them.
+type ClientIdentifier interface {
+	// ClientID returns the identifier of the client the request in ctx comes
+	// from
+	ClientID(ctx context.Context) (string, error)
+}
+
+// Quota limits the resources each client can use. Zero values mean no limit.
+type Quota struct {
+	// MaxInstances is the maximum number of gadgets a client can run at the
+	// same time
+	MaxInstances int
+	// MaxBufferMemory is the maximum memory, in bytes, used by the perf and
+	// ring buffers of all the gadgets of a client
+	MaxBufferMemory uint64
+	// MaxEventRate is the maximum number of events per second sent by all the
+	// gadgets of a client. Events beyond it are dropped.
+	MaxEventRate float64
+}
+
+// clientUsage is the resources currently used by a client
+type clientUsage struct {
+	instances    int
+	bufferMemory uint64
+	limiter      *rate.Limiter
+}
+
+// quotaTracker keeps track of the resources used by each client
+type quotaTracker struct {
+	quota Quota
+
+	mu      sync.Mutex
+	clients map[string]*clientUsage
+}
+
+func newQuotaTracker(quota Quota) *quotaTracker {
+	return &quotaTracker{
+		quota:   quota,
+		clients: map[string]*clientUsage{},
+	}
+}
+
+// acquire reserves the resources for a new gadget of client using the given
+// buffer memory. It returns a function telling whether an event can be sent
+// and a function releasing the resources once the gadget stops.
+func (q *quotaTracker) acquire(client string, bufferMemory uint64) (func() bool, func(), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	usage, ok := q.clients[client]
+	if !ok {
+		usage = &clientUsage{}
+		if q.quota.MaxEventRate > 0 {
+			// Allow bursts of up to a second of events
+			burst := int(q.quota.MaxEventRate)
+			if burst < 1 {
+				burst = 1
+			}
+			usage.limiter = rate.NewLimiter(rate.Limit(q.quota.MaxEventRate), burst)
+		}
+	}
+
+	if q.quota.MaxInstances > 0 && usage.instances+1 > q.quota.MaxInstances {
+		return nil, nil, status.Errorf(codes.ResourceExhausted,
+			"client %q reached the maximum number of gadget instances (%d)", client, q.quota.MaxInstances)
+	}
+	if q.quota.MaxBufferMemory > 0 && usage.bufferMemory+bufferMemory > q.quota.MaxBufferMemory {
+		return nil, nil, status.Errorf(codes.ResourceExhausted,
+			"client %q would exceed the maximum buffer memory (%d bytes in use, %d requested, %d allowed)",
+			client, usage.bufferMemory, bufferMemory, q.quota.MaxBufferMemory)
+	}
+
+	usage.instances++
+	usage.bufferMemory += bufferMemory
+	q.clients[client] = usage
+
+	allow := func() bool { return true }
+	if usage.limiter != nil {
+		allow = usage.limiter.Allow
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+
+			usage.instances--
+			usage.bufferMemory -= bufferMemory
+			if usage.instances == 0 {
+				delete(q.clients, client)
+			}
+		})
+	}
+
+	return allow, release, nil
+}
+
+// bufferMemory estimates the memory used by the perf and ring buffers of a
+// gadget. Built-in trace gadgets are assumed to use a single perf buffer.
+func bufferMemory(gadgetCtx *gadgetcontext.GadgetContext) (uint64, error) {
+	perfBufferMemory := uint64(gadgets.PerfBufferPages*os.Getpagesize()) * uint64(goruntime.NumCPU())
+
+	gadgetInfo := gadgetCtx.GadgetInfo()
+	if gadgetInfo == nil || len(gadgetInfo.ProgContent) == 0 {
+		switch gadgetCtx.GadgetDesc().Type() {
+		case gadgets.TypeTrace, gadgets.TypeTraceIntervals:
+			return perfBufferMemory, nil
+		}
+		return 0, nil
+	}
+
+	spec, err := ebpf.LoadCollectionSpecFromReader(bytes.NewReader(gadgetInfo.ProgContent))
+	if err != nil {
+		return 0, fmt.Errorf("loading spec: %w", err)
+	}
+
+	memory := uint64(0)
+	for _, m := range spec.Maps {
+		switch m.Type {
+		case ebpf.RingBuf:
+			memory += uint64(m.MaxEntries)
+		case ebpf.PerfEventArray:
+			memory += perfBufferMemory
+		}
+	}
+	return memory, nil
+}
+
+// clientID returns the identifier of the client the request in ctx comes
+// from, or an empty string if the authorizer can't identify clients
+func (s *Service) clientID(ctx context.Context) (string, error) {
+	identifier, ok := s.authorizer.(ClientIdentifier)
+	if !ok {
+		return "", nil
+	}
+	return identifier.ClientID(ctx)
+}
+
+// acquireQuota reserves the resources needed by the gadget described by
+// gadgetCtx for client. See quotaTracker.acquire for the returned values.
+func (s *Service) acquireQuota(client string, gadgetCtx *gadgetcontext.GadgetContext) (func() bool, func(), error) {
+	if s.quotas == nil {
+		return func() bool { return true }, func() {}, nil
+	}
+
+	memory, err := bufferMemory(gadgetCtx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("estimating buffer memory: %w", err)
+	}
+
+	return s.quotas.acquire(client, memory)
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuotaInstances(t *testing.T) {
+	t.Parallel()
+
+	q := newQuotaTracker(Quota{MaxInstances: 2})
+
+	_, release1, err := q.acquire("alice", 0)
+	require.NoError(t, err)
+	_, release2, err := q.acquire("alice", 0)
+	require.NoError(t, err)
+
+	_, _, err = q.acquire("alice", 0)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Quotas are per client
+	_, release3, err := q.acquire("bob", 0)
+	require.NoError(t, err)
+
+	// Releasing twice has no effect
+	release1()
+	release1()
+
+	_, release4, err := q.acquire("alice", 0)
+	require.NoError(t, err)
+	_, _, err = q.acquire("alice", 0)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	release2()
+	release3()
+	release4()
+	require.Empty(t, q.clients)
+}
+
+func TestQuotaBufferMemory(t *testing.T) {
+	t.Parallel()
+
+	q := newQuotaTracker(Quota{MaxBufferMemory: 100})
+
+	_, release1, err := q.acquire("alice", 60)
+	require.NoError(t, err)
+
+	_, _, err = q.acquire("alice", 50)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, release2, err := q.acquire("alice", 40)
+	require.NoError(t, err)
+
+	release1()
+	_, release3, err := q.acquire("alice", 50)
+	require.NoError(t, err)
+
+	release2()
+	release3()
+	require.Empty(t, q.clients)
+}
+
+func TestQuotaEventRate(t *testing.T) {
+	t.Parallel()
+
+	q := newQuotaTracker(Quota{MaxEventRate: 10})
+
+	allow1, release1, err := q.acquire("alice", 0)
+	require.NoError(t, err)
+	defer release1()
+	allow2, release2, err := q.acquire("alice", 0)
+	require.NoError(t, err)
+	defer release2()
+	allowOther, releaseOther, err := q.acquire("bob", 0)
+	require.NoError(t, err)
+	defer releaseOther()
+
+	// The limit is shared by all the gadgets of a client, with bursts of up
+	// to a second of events
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		if allow1() {
+			allowed++
+		}
+		if allow2() {
+			allowed++
+		}
+	}
+	require.InDelta(t, 10, allowed, 1)
+
+	// Other clients aren't affected
+	require.True(t, allowOther())
+}
+
+func TestQuotaUnlimited(t *testing.T) {
+	t.Parallel()
+
+	q := newQuotaTracker(Quota{})
+
+	for i := 0; i < 100; i++ {
+		allow, _, err := q.acquire("alice", 1<<30)
+		require.NoError(t, err)
+		require.True(t, allow())
+	}
+}
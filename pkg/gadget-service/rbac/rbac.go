@@ -0,0 +1,367 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rbac authorizes the requests to run gadgets using the Kubernetes
+// RBAC permissions of the user that sent them. The user is identified by the
+// bearer token the client sends along with the request.
+package rbac
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/client-go/kubernetes"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	runTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubemanager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	// CapabilitiesGroup and CapabilitiesResource identify the virtual
+	// resource used to grant gadget capabilities, e.g.:
+	//
+	//	- apiGroups: ["gadget.kinvolk.io"]
+	//	  resources: ["capabilities"]
+	//	  resourceNames: ["host", "uprobe"]
+	//	  verbs: ["use"]
+	CapabilitiesGroup    = "gadget.kinvolk.io"
+	CapabilitiesResource = "capabilities"
+
+	// CapabilityHost is needed to run gadgets that can't be filtered by
+	// namespace, i.e. that trace the whole host
+	CapabilityHost = "host"
+	// CapabilityUprobe is needed to run gadgets that attach uprobes
+	CapabilityUprobe = "uprobe"
+)
+
+// Authorizer authorizes the requests to run gadgets using SubjectAccessReviews:
+//   - Gadgets filtered to some namespaces need list access to the pods in
+//     those namespaces.
+//   - Gadgets running on all namespaces need list access to the pods of the
+//     whole cluster.
+//   - Gadgets that can't be filtered by namespace and gadgets attaching
+//     uprobes need the corresponding capability, see CapabilitiesResource.
+//
+// The results of the TokenReviews and SubjectAccessReviews are cached, so
+// changes of the permissions take up to allowedTTL to apply.
+type Authorizer struct {
+	client kubernetes.Interface
+
+	// users caches the users the tokens belong to
+	users *cache.LRUExpireCache
+	// decisions caches whether a user can perform an action
+	decisions *cache.LRUExpireCache
+}
+
+const (
+	// cacheSize is the maximum number of entries of each cache
+	cacheSize = 1024
+
+	authenticatedTTL = time.Minute
+	allowedTTL       = time.Minute
+	deniedTTL        = 10 * time.Second
+)
+
+// NewAuthorizer creates an authorizer using the in-cluster configuration
+func NewAuthorizer() (*Authorizer, error) {
+	clientset, err := k8sutil.NewClientset("")
+	if err != nil {
+		return nil, fmt.Errorf("creating new k8s clientset: %w", err)
+	}
+	return newAuthorizer(clientset), nil
+}
+
+func newAuthorizer(client kubernetes.Interface) *Authorizer {
+	return &Authorizer{
+		client:    client,
+		users:     cache.NewLRUExpireCache(cacheSize),
+		decisions: cache.NewLRUExpireCache(cacheSize),
+	}
+}
+
+func (a *Authorizer) Authorize(ctx context.Context, gadgetCtx *gadgetcontext.GadgetContext) error {
+	permissions, err := requiredPermissions(gadgetCtx.Operators(), gadgetCtx.OperatorsParamCollection(), gadgetCtx.GadgetInfo())
+	if err != nil {
+		return fmt.Errorf("getting required permissions: %w", err)
+	}
+	return a.authorize(ctx, permissions)
+}
+
+// ClientID returns the name of the Kubernetes user that sent the request in
+// ctx, so that each user can only access the gadget instances it started
+func (a *Authorizer) ClientID(ctx context.Context) (string, error) {
+	user, err := a.user(ctx)
+	if err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+// authorize returns an error if the user that sent the request in ctx doesn't
+// have all the given permissions
+func (a *Authorizer) authorize(ctx context.Context, permissions []authorizationv1.ResourceAttributes) error {
+	user, err := a.user(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, attrs := range permissions {
+		if err := a.checkAccess(ctx, user, attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type userKey struct{}
+
+// WithServiceAccount returns a context whose requests are authorized with the
+// permissions of the given service account rather than with the credentials
+// of a client. It's used to run gadgets that aren't requested by a client,
+// e.g. the ones described by GadgetInstance resources.
+func WithServiceAccount(ctx context.Context, namespace, name string) context.Context {
+	// Same user and groups the API server assigns to service accounts
+	return context.WithValue(ctx, userKey{}, &authenticationv1.UserInfo{
+		Username: fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name),
+		Groups: []string{
+			"system:serviceaccounts",
+			"system:serviceaccounts:" + namespace,
+			"system:authenticated",
+		},
+	})
+}
+
+// WithClient returns a context whose requests are authorized as the given
+// Kubernetes user, e.g. to authorize again the gadget instances restored by
+// the daemon. Only the groups of service accounts are known without their
+// token, other users only get the system:authenticated group.
+func (a *Authorizer) WithClient(ctx context.Context, client string) context.Context {
+	parts := strings.Split(client, ":")
+	if len(parts) == 4 && parts[0] == "system" && parts[1] == "serviceaccount" {
+		return WithServiceAccount(ctx, parts[2], parts[3])
+	}
+	return context.WithValue(ctx, userKey{}, &authenticationv1.UserInfo{
+		Username: client,
+		Groups:   []string{"system:authenticated"},
+	})
+}
+
+// user returns the user the request in ctx is authorized as
+func (a *Authorizer) user(ctx context.Context) (*authenticationv1.UserInfo, error) {
+	if user, ok := ctx.Value(userKey{}).(*authenticationv1.UserInfo); ok {
+		return user, nil
+	}
+
+	token, err := api.TokenFromContext(ctx)
+	if err != nil {
+		if errors.Is(err, api.ErrNoCredentials) {
+			err = fmt.Errorf("%w, authenticating to the Kubernetes API server with a bearer token is required", err)
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return a.authenticate(ctx, token)
+}
+
+// authenticate returns the user the token belongs to. Successful reviews are
+// cached for authenticatedTTL.
+func (a *Authorizer) authenticate(ctx context.Context, token string) (*authenticationv1.UserInfo, error) {
+	// Don't keep the tokens themselves in memory
+	key := sha256.Sum256([]byte(token))
+	if user, ok := a.users.Get(key); ok {
+		return user.(*authenticationv1.UserInfo), nil
+	}
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	}
+	review, err := a.client.AuthenticationV1().TokenReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("creating token review: %w", err)
+	}
+	if !review.Status.Authenticated {
+		msg := "invalid credentials"
+		if review.Status.Error != "" {
+			msg += ": " + review.Status.Error
+		}
+		return nil, status.Error(codes.Unauthenticated, msg)
+	}
+
+	a.users.Add(key, &review.Status.User, authenticatedTTL)
+	return &review.Status.User, nil
+}
+
+// checkAccess returns an error if user can't perform the action described by
+// attrs. The decisions are cached for allowedTTL or deniedTTL.
+func (a *Authorizer) checkAccess(ctx context.Context, user *authenticationv1.UserInfo, attrs authorizationv1.ResourceAttributes) error {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	spec := authorizationv1.SubjectAccessReviewSpec{
+		ResourceAttributes: &attrs,
+		User:               user.Username,
+		Groups:             user.Groups,
+		UID:                user.UID,
+		Extra:              extra,
+	}
+
+	key, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("marshaling subject access review: %w", err)
+	}
+
+	allowed, ok := a.decisions.Get(string(key))
+	if !ok {
+		review := &authorizationv1.SubjectAccessReview{Spec: spec}
+		review, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("creating subject access review: %w", err)
+		}
+		allowed = review.Status.Allowed
+
+		ttl := deniedTTL
+		if review.Status.Allowed {
+			ttl = allowedTTL
+		}
+		a.decisions.Add(string(key), allowed, ttl)
+	}
+
+	if !allowed.(bool) {
+		return status.Errorf(codes.PermissionDenied, "user %q is not allowed to %s", user.Username, describe(attrs))
+	}
+	return nil
+}
+
+// describe returns a human readable description of the action described by
+// attrs
+func describe(attrs authorizationv1.ResourceAttributes) string {
+	switch {
+	case attrs.Group == CapabilitiesGroup && attrs.Resource == CapabilitiesResource:
+		return fmt.Sprintf("use the %q gadget capability", attrs.Name)
+	case attrs.Namespace == "":
+		return fmt.Sprintf("%s %s in all namespaces", attrs.Verb, attrs.Resource)
+	default:
+		return fmt.Sprintf("%s %s in namespace %q", attrs.Verb, attrs.Resource, attrs.Namespace)
+	}
+}
+
+func capability(name string) authorizationv1.ResourceAttributes {
+	return authorizationv1.ResourceAttributes{
+		Group:    CapabilitiesGroup,
+		Resource: CapabilitiesResource,
+		Name:     name,
+		Verb:     "use",
+	}
+}
+
+// requiredPermissions returns the permissions needed to run a gadget with the
+// given operators and parameters
+func requiredPermissions(ops operators.Operators, operatorParams params.Collection, gadgetInfo *runTypes.GadgetInfo) ([]authorizationv1.ResourceAttributes, error) {
+	var permissions []authorizationv1.ResourceAttributes
+
+	kubeManagerParams, ok := operatorParams[kubemanager.OperatorName]
+	if hasOperator(ops, kubemanager.OperatorName) && ok {
+		permissions = append(permissions, namespacePermissions(kubeManagerParams)...)
+	} else {
+		// The gadget can't be filtered by namespace
+		permissions = append(permissions, capability(CapabilityHost))
+	}
+
+	if gadgetInfo != nil {
+		uprobes, err := usesUprobes(gadgetInfo.ProgContent)
+		if err != nil {
+			return nil, err
+		}
+		if uprobes {
+			permissions = append(permissions, capability(CapabilityUprobe))
+		}
+	}
+
+	return permissions, nil
+}
+
+// namespacePermissions returns the permissions needed to trace the namespaces
+// selected by the KubeManager parameters
+func namespacePermissions(kubeManagerParams *params.Params) []authorizationv1.ResourceAttributes {
+	namespace := kubeManagerParams.Get(kubemanager.ParamNamespace).AsString()
+	allNamespaces := kubeManagerParams.Get(kubemanager.ParamAllNamespaces).AsBool()
+	// The namespaces matching a selector can change while the gadget runs
+	namespaceSelector := kubeManagerParams.Get(kubemanager.ParamNamespaceSelector).AsString()
+
+	if allNamespaces || namespaceSelector != "" || namespace == "" {
+		return []authorizationv1.ResourceAttributes{{Verb: "list", Resource: "pods"}}
+	}
+
+	var permissions []authorizationv1.ResourceAttributes
+	for _, ns := range strings.Split(namespace, ",") {
+		permissions = append(permissions, authorizationv1.ResourceAttributes{
+			Namespace: ns,
+			Verb:      "list",
+			Resource:  "pods",
+		})
+	}
+	return permissions
+}
+
+func hasOperator(ops operators.Operators, name string) bool {
+	for _, op := range ops {
+		if op.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// usesUprobes returns whether the eBPF object contains uprobe programs
+func usesUprobes(progContent []byte) (bool, error) {
+	if len(progContent) == 0 {
+		return false, nil
+	}
+
+	spec, err := ebpf.LoadCollectionSpecFromReader(bytes.NewReader(progContent))
+	if err != nil {
+		return false, fmt.Errorf("loading spec: %w", err)
+	}
+
+	for _, p := range spec.Programs {
+		if p.Type != ebpf.Kprobe {
+			continue
+		}
+		if strings.HasPrefix(p.SectionName, "uprobe/") || strings.HasPrefix(p.SectionName, "uretprobe/") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
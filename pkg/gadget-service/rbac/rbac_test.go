@@ -0,0 +1,322 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubemanager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+func TestRequiredPermissions(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		params   map[string]string
+		noKube   bool
+		expected []authorizationv1.ResourceAttributes
+	}
+
+	tests := map[string]testDefinition{
+		"single_namespace": {
+			params: map[string]string{kubemanager.ParamNamespace: "ns1"},
+			expected: []authorizationv1.ResourceAttributes{
+				{Namespace: "ns1", Verb: "list", Resource: "pods"},
+			},
+		},
+		"several_namespaces": {
+			params: map[string]string{kubemanager.ParamNamespace: "ns1,ns2"},
+			expected: []authorizationv1.ResourceAttributes{
+				{Namespace: "ns1", Verb: "list", Resource: "pods"},
+				{Namespace: "ns2", Verb: "list", Resource: "pods"},
+			},
+		},
+		"all_namespaces": {
+			params: map[string]string{
+				kubemanager.ParamNamespace:     "ns1",
+				kubemanager.ParamAllNamespaces: "true",
+			},
+			expected: []authorizationv1.ResourceAttributes{
+				{Verb: "list", Resource: "pods"},
+			},
+		},
+		"no_namespace": {
+			expected: []authorizationv1.ResourceAttributes{
+				{Verb: "list", Resource: "pods"},
+			},
+		},
+		"namespace_selector": {
+			params: map[string]string{
+				kubemanager.ParamNamespace:         "ns1",
+				kubemanager.ParamNamespaceSelector: "team=payments",
+			},
+			expected: []authorizationv1.ResourceAttributes{
+				{Verb: "list", Resource: "pods"},
+			},
+		},
+		"host": {
+			noKube: true,
+			expected: []authorizationv1.ResourceAttributes{
+				capability(CapabilityHost),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			km := &kubemanager.KubeManager{}
+			ops := operators.Operators{km}
+			operatorParams := params.Collection{}
+			if !test.noKube {
+				kmParams := km.ParamDescs().ToParams()
+				for k, v := range test.params {
+					require.NoError(t, kmParams.Set(k, v))
+				}
+				operatorParams[kubemanager.OperatorName] = kmParams
+			} else {
+				ops = operators.Operators{}
+			}
+
+			permissions, err := requiredPermissions(ops, operatorParams, nil)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, permissions)
+		})
+	}
+}
+
+func newFakeAuthorizer(allowed func(attrs *authorizationv1.ResourceAttributes) bool) *Authorizer {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if review.Spec.Token == "valid" {
+			review.Status.Authenticated = true
+			review.Status.User = authenticationv1.UserInfo{Username: "alice"}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User == "alice" && allowed(review.Spec.ResourceAttributes)
+		return true, review, nil
+	})
+	return newAuthorizer(client)
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	// alice can only read pods in ns1
+	authorizer := newFakeAuthorizer(func(attrs *authorizationv1.ResourceAttributes) bool {
+		return attrs.Namespace == "ns1" && attrs.Resource == "pods" && attrs.Verb == "list"
+	})
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(api.AuthorizationMetadataKey, "Bearer "+token))
+	}
+
+	type testDefinition struct {
+		ctx         context.Context
+		permissions []authorizationv1.ResourceAttributes
+		code        codes.Code
+	}
+
+	tests := map[string]testDefinition{
+		"allowed_namespace": {
+			ctx: withToken("valid"),
+			permissions: []authorizationv1.ResourceAttributes{
+				{Namespace: "ns1", Verb: "list", Resource: "pods"},
+			},
+			code: codes.OK,
+		},
+		"forbidden_namespace": {
+			ctx: withToken("valid"),
+			permissions: []authorizationv1.ResourceAttributes{
+				{Namespace: "ns1", Verb: "list", Resource: "pods"},
+				{Namespace: "ns2", Verb: "list", Resource: "pods"},
+			},
+			code: codes.PermissionDenied,
+		},
+		"forbidden_all_namespaces": {
+			ctx: withToken("valid"),
+			permissions: []authorizationv1.ResourceAttributes{
+				{Verb: "list", Resource: "pods"},
+			},
+			code: codes.PermissionDenied,
+		},
+		"forbidden_host": {
+			ctx: withToken("valid"),
+			permissions: []authorizationv1.ResourceAttributes{
+				capability(CapabilityHost),
+			},
+			code: codes.PermissionDenied,
+		},
+		"invalid_token": {
+			ctx: withToken("invalid"),
+			permissions: []authorizationv1.ResourceAttributes{
+				{Namespace: "ns1", Verb: "list", Resource: "pods"},
+			},
+			code: codes.Unauthenticated,
+		},
+		"no_token": {
+			ctx: context.Background(),
+			permissions: []authorizationv1.ResourceAttributes{
+				{Namespace: "ns1", Verb: "list", Resource: "pods"},
+			},
+			code: codes.Unauthenticated,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := authorizer.authorize(test.ctx, test.permissions)
+			require.Equal(t, test.code, status.Code(err), "unexpected error: %v", err)
+		})
+	}
+}
+
+func TestClientID(t *testing.T) {
+	t.Parallel()
+
+	authorizer := newFakeAuthorizer(func(attrs *authorizationv1.ResourceAttributes) bool { return false })
+
+	client, err := authorizer.ClientID(metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(api.AuthorizationMetadataKey, "Bearer valid")))
+	require.NoError(t, err)
+	require.Equal(t, "alice", client)
+
+	_, err = authorizer.ClientID(metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(api.AuthorizationMetadataKey, "Bearer invalid")))
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = authorizer.ClientID(context.Background())
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthorizeCache(t *testing.T) {
+	t.Parallel()
+
+	tokenReviews := 0
+	accessReviews := 0
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		tokenReviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review.Status.Authenticated = review.Spec.Token == "valid"
+		review.Status.User = authenticationv1.UserInfo{Username: "alice"}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		accessReviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Namespace == "ns1"
+		return true, review, nil
+	})
+	authorizer := newAuthorizer(client)
+
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs(api.AuthorizationMetadataKey, "Bearer "+token))
+	}
+	ns1 := []authorizationv1.ResourceAttributes{{Namespace: "ns1", Verb: "list", Resource: "pods"}}
+	ns2 := []authorizationv1.ResourceAttributes{{Namespace: "ns2", Verb: "list", Resource: "pods"}}
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, authorizer.authorize(withToken("valid"), ns1))
+		require.Equal(t, codes.PermissionDenied, status.Code(authorizer.authorize(withToken("valid"), ns2)))
+	}
+	require.Equal(t, 1, tokenReviews)
+	require.Equal(t, 2, accessReviews)
+
+	// Invalid tokens aren't cached
+	for i := 0; i < 2; i++ {
+		require.Equal(t, codes.Unauthenticated, status.Code(authorizer.authorize(withToken("invalid"), ns1)))
+	}
+	require.Equal(t, 3, tokenReviews)
+}
+
+func TestWithServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		t.Error("unexpected token review")
+		return true, nil, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User == "system:serviceaccount:ns1:auditor" &&
+			review.Spec.ResourceAttributes.Namespace == "ns1"
+		return true, review, nil
+	})
+	authorizer := newAuthorizer(client)
+
+	ctx := WithServiceAccount(context.Background(), "ns1", "auditor")
+	require.NoError(t, authorizer.authorize(ctx, []authorizationv1.ResourceAttributes{
+		{Namespace: "ns1", Verb: "list", Resource: "pods"},
+	}))
+	err := authorizer.authorize(ctx, []authorizationv1.ResourceAttributes{capability(CapabilityHost)})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	id, err := authorizer.ClientID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "system:serviceaccount:ns1:auditor", id)
+}
+
+func TestWithClient(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		t.Error("unexpected token review")
+		return true, nil, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User == "alice"
+		return true, review, nil
+	})
+	authorizer := newAuthorizer(client)
+	ns1 := []authorizationv1.ResourceAttributes{{Namespace: "ns1", Verb: "list", Resource: "pods"}}
+
+	require.NoError(t, authorizer.authorize(authorizer.WithClient(context.Background(), "alice"), ns1))
+	err := authorizer.authorize(authorizer.WithClient(context.Background(), "bob"), ns1)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Service accounts get the same groups as when they authenticate
+	user, err := authorizer.user(authorizer.WithClient(context.Background(), "system:serviceaccount:ns1:auditor"))
+	require.NoError(t, err)
+	require.Contains(t, user.Groups, "system:serviceaccounts:ns1")
+}
@@ -0,0 +1,490 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// ErrGadgetNotRunning is returned when subscribing to a gadget that isn't
+// running
+var ErrGadgetNotRunning = errors.New("gadget not running")
+
+// subscriberBufferLength is the number of events buffered for each
+// subscriber. Events are dropped for slow subscribers.
+const subscriberBufferLength = 1024
+
+// RunningGadget describes a gadget that is currently running
+type RunningGadget struct {
+	ID             string            `json:"id"`
+	GadgetCategory string            `json:"gadgetCategory"`
+	GadgetName     string            `json:"gadgetName"`
+	Params         map[string]string `json:"params,omitempty"`
+	Args           []string          `json:"args,omitempty"`
+	StartedAt      time.Time         `json:"startedAt"`
+	// Detached is true if the gadget keeps running when the client that
+	// started it disconnects
+	Detached bool `json:"detached,omitempty"`
+	// Paused is true if the gadget was paused with PauseRunningGadget
+	Paused bool `json:"paused,omitempty"`
+}
+
+func (r *RunningGadget) toProto() *api.GadgetInstance {
+	return &api.GadgetInstance{
+		Id:             r.ID,
+		GadgetCategory: r.GadgetCategory,
+		GadgetName:     r.GadgetName,
+		Params:         r.Params,
+		Args:           r.Args,
+		StartedAt:      r.StartedAt.UnixNano(),
+		Detached:       r.Detached,
+		Paused:         r.Paused,
+	}
+}
+
+// runningGadget keeps track of a running gadget and of the subscribers to its
+// events
+type runningGadget struct {
+	info RunningGadget
+	// request started the gadget, its params are the initial ones
+	request *api.GadgetRunRequest
+
+	// owner is the identifier of the client that started the gadget, see
+	// ClientIdentifier. It's empty for gadgets started by the service itself
+	// and when clients can't be identified.
+	owner string
+
+	// cancel stops the gadget, pause and resume pause and resume it and
+	// updateParams changes its params. They're set with setContext before the
+	// gadget is added to the running gadgets.
+	cancel       func()
+	pause        func() error
+	resume       func() error
+	updateParams func(map[string]string) error
+
+	mu          sync.Mutex
+	subscribers map[chan []byte]struct{}
+	stopped     bool
+
+	// pauseMu serializes the changes to the running gadget: pauses, resumes
+	// and updates of its params
+	pauseMu sync.Mutex
+}
+
+func newRunningGadget(runID, owner string, request *api.GadgetRunRequest) *runningGadget {
+	return &runningGadget{
+		owner:   owner,
+		request: request,
+		info: RunningGadget{
+			ID:             runID,
+			GadgetCategory: request.GadgetCategory,
+			GadgetName:     request.GadgetName,
+			Params:         request.Params,
+			Args:           request.Args,
+			StartedAt:      time.Now(),
+			Detached:       request.Detach,
+		},
+		subscribers: map[chan []byte]struct{}{},
+	}
+}
+
+// setContext sets the functions controlling the gadget run with gadgetCtx
+func (r *runningGadget) setContext(gadgetCtx *gadgetcontext.GadgetContext) {
+	r.cancel = gadgetCtx.Cancel
+	r.pause = gadgetCtx.Pause
+	r.resume = gadgetCtx.Resume
+	r.updateParams = gadgetCtx.UpdateParams
+}
+
+// visibleTo returns whether client can see, attach to and stop the gadget.
+// Identified clients can only access the gadgets they started; when clients
+// can't be identified, all of them can access all the gadgets.
+func (r *runningGadget) visibleTo(client string) bool {
+	return client == "" || r.owner == client
+}
+
+// publish sends an event, marshaled to JSON, to all subscribers
+func (r *runningGadget) publish(data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for ch := range r.subscribers {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+}
+
+func (r *runningGadget) subscribe() (<-chan []byte, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch := make(chan []byte, subscriberBufferLength)
+	if r.stopped {
+		close(ch)
+		return ch, func() {}
+	}
+	r.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := r.subscribers[ch]; ok {
+			delete(r.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// stop closes the channels of all subscribers
+func (r *runningGadget) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stopped = true
+	for ch := range r.subscribers {
+		close(ch)
+	}
+	r.subscribers = map[chan []byte]struct{}{}
+}
+
+// RunningGadgets returns the gadgets that are currently running and that
+// client can access, sorted by start time
+func (s *Service) RunningGadgets(client string) []RunningGadget {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	gadgets := make([]RunningGadget, 0, len(s.runningGadgets))
+	for _, gadget := range s.runningGadgets {
+		if gadget.visibleTo(client) {
+			gadgets = append(gadgets, gadget.info)
+		}
+	}
+	sort.Slice(gadgets, func(i, j int) bool {
+		return gadgets[i].StartedAt.Before(gadgets[j].StartedAt)
+	})
+	return gadgets
+}
+
+// getRunningGadget returns the running gadget with the given ID if client can
+// access it. Gadgets of other clients are reported as not running, so their
+// IDs can't be probed.
+func (s *Service) getRunningGadget(id, client string) (*runningGadget, error) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	running, ok := s.runningGadgets[id]
+	if !ok || !running.visibleTo(client) {
+		return nil, ErrGadgetNotRunning
+	}
+	return running, nil
+}
+
+// RunningGadget returns the running gadget with the given ID if client can
+// access it
+func (s *Service) RunningGadget(id, client string) (RunningGadget, error) {
+	running, err := s.getRunningGadget(id, client)
+	if err != nil {
+		return RunningGadget{}, err
+	}
+	return running.info, nil
+}
+
+// StopRunningGadget stops the running gadget with the given ID if client can
+// access it. It returns without waiting for the gadget to stop.
+func (s *Service) StopRunningGadget(id, client string) error {
+	running, err := s.getRunningGadget(id, client)
+	if err != nil {
+		return err
+	}
+
+	if running.cancel != nil {
+		running.cancel()
+	}
+	return nil
+}
+
+// PauseRunningGadget pauses the running gadget with the given ID if client can
+// access it, see gadgets.PausableGadget
+func (s *Service) PauseRunningGadget(id, client string) error {
+	return s.setPaused(id, client, true)
+}
+
+// ResumeRunningGadget resumes the running gadget with the given ID paused with
+// PauseRunningGadget
+func (s *Service) ResumeRunningGadget(id, client string) error {
+	return s.setPaused(id, client, false)
+}
+
+func (s *Service) setPaused(id, client string, paused bool) error {
+	running, err := s.getRunningGadget(id, client)
+	if err != nil {
+		return err
+	}
+
+	// Serialize the pauses and resumes of the gadget, so its state matches
+	// the last call
+	running.pauseMu.Lock()
+	defer running.pauseMu.Unlock()
+
+	control := running.resume
+	if paused {
+		control = running.pause
+	}
+	if control == nil {
+		return gadgetcontext.ErrGadgetNotPausable
+	}
+	if err := control(); err != nil {
+		return err
+	}
+
+	s.runningMu.Lock()
+	running.info.Paused = paused
+	s.runningMu.Unlock()
+	return nil
+}
+
+// UpdateRunningGadgetParams changes the params of the running gadget with the
+// given ID if client, that sent the request in ctx, can access it and run the
+// gadget with the new params, see gadgets.UpdatableParamsGadget
+func (s *Service) UpdateRunningGadgetParams(ctx context.Context, id, client string, values map[string]string) error {
+	running, err := s.getRunningGadget(id, client)
+	if err != nil {
+		return err
+	}
+
+	running.pauseMu.Lock()
+	defer running.pauseMu.Unlock()
+
+	if running.updateParams == nil {
+		return gadgetcontext.ErrGadgetParamsNotUpdatable
+	}
+
+	s.runningMu.Lock()
+	params := make(map[string]string, len(running.info.Params)+len(values))
+	for key, value := range running.info.Params {
+		params[key] = value
+	}
+	s.runningMu.Unlock()
+	for key, value := range values {
+		params[key] = value
+	}
+
+	// The client could be allowed to run the gadget with the initial params
+	// only
+	if err := s.authorizeParams(ctx, running, params); err != nil {
+		return err
+	}
+
+	if err := running.updateParams(values); err != nil {
+		return err
+	}
+
+	// info is shared with RunningGadgets, replace its params instead of
+	// changing them
+	s.runningMu.Lock()
+	running.info.Params = params
+	s.runningMu.Unlock()
+	return nil
+}
+
+// authorizeParams checks the client that sent the request in ctx can run the
+// gadget with the given params, like when it's started
+func (s *Service) authorizeParams(ctx context.Context, running *runningGadget, params map[string]string) error {
+	authorize := s.authorizeFunc(ctx, running.owner)
+	if authorize == nil {
+		return nil
+	}
+
+	request := proto.Clone(running.request).(*api.GadgetRunRequest)
+	request.Params = params
+	gadgetCtx, err := s.newGadgetContext(ctx, running.info.ID, request, s.logger, func([]byte) {}, authorize)
+	if err != nil {
+		return err
+	}
+	defer gadgetCtx.Cancel()
+	return authorize(gadgetCtx)
+}
+
+// SubscribeRunningGadget returns a channel receiving the events, marshaled to
+// JSON, of the running gadget with the given ID, and a function to
+// unsubscribe. The channel is closed when the gadget stops. client must be
+// able to access the gadget.
+func (s *Service) SubscribeRunningGadget(id, client string) (<-chan []byte, func(), error) {
+	running, err := s.getRunningGadget(id, client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events, unsubscribe := running.subscribe()
+	return events, unsubscribe, nil
+}
+
+// addRunningGadget adds a gadget to the running gadgets and returns a
+// function that removes it once it stops
+func (s *Service) addRunningGadget(running *runningGadget) func() {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+
+	s.runningGadgets[running.info.ID] = running
+
+	return func() {
+		s.runningMu.Lock()
+		delete(s.runningGadgets, running.info.ID)
+		s.runningMu.Unlock()
+
+		running.stop()
+	}
+}
+
+// instanceError converts errors about running gadgets to gRPC errors
+func instanceError(err error, id string) error {
+	switch {
+	case errors.Is(err, ErrGadgetNotRunning):
+		return status.Errorf(codes.NotFound, "%s: %s", err, id)
+	case errors.Is(err, gadgetcontext.ErrGadgetNotPausable), errors.Is(err, gadgetcontext.ErrGadgetNotStarted),
+		errors.Is(err, gadgetcontext.ErrGadgetParamsNotUpdatable):
+		return status.Errorf(codes.FailedPrecondition, "%s: %s", err, id)
+	}
+	return err
+}
+
+func (s *Service) ListInstances(ctx context.Context, req *api.ListInstancesRequest) (*api.ListInstancesResponse, error) {
+	client, err := s.clientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	running := s.RunningGadgets(client)
+	instances := make([]*api.GadgetInstance, 0, len(running))
+	for _, gadget := range running {
+		instances = append(instances, gadget.toProto())
+	}
+	return &api.ListInstancesResponse{Instances: instances}, nil
+}
+
+func (s *Service) GetInstance(ctx context.Context, req *api.GetInstanceRequest) (*api.GadgetInstance, error) {
+	client, err := s.clientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	running, err := s.RunningGadget(req.Id, client)
+	if err != nil {
+		return nil, instanceError(err, req.Id)
+	}
+	return running.toProto(), nil
+}
+
+func (s *Service) StopInstance(ctx context.Context, req *api.StopInstanceRequest) (*api.StopInstanceResponse, error) {
+	client, err := s.clientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.StopRunningGadget(req.Id, client); err != nil {
+		return nil, instanceError(err, req.Id)
+	}
+	return &api.StopInstanceResponse{}, nil
+}
+
+func (s *Service) PauseInstance(ctx context.Context, req *api.PauseInstanceRequest) (*api.PauseInstanceResponse, error) {
+	client, err := s.clientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.PauseRunningGadget(req.Id, client); err != nil {
+		return nil, instanceError(err, req.Id)
+	}
+	return &api.PauseInstanceResponse{}, nil
+}
+
+func (s *Service) ResumeInstance(ctx context.Context, req *api.ResumeInstanceRequest) (*api.ResumeInstanceResponse, error) {
+	client, err := s.clientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ResumeRunningGadget(req.Id, client); err != nil {
+		return nil, instanceError(err, req.Id)
+	}
+	return &api.ResumeInstanceResponse{}, nil
+}
+
+func (s *Service) UpdateInstanceParams(ctx context.Context, req *api.UpdateInstanceParamsRequest) (*api.UpdateInstanceParamsResponse, error) {
+	client, err := s.clientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.UpdateRunningGadgetParams(ctx, req.Id, client, req.Params); err != nil {
+		err = instanceError(err, req.Id)
+		if _, ok := status.FromError(err); !ok {
+			// The gadget refused the values
+			err = status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, err
+	}
+	return &api.UpdateInstanceParamsResponse{}, nil
+}
+
+// AttachToInstance streams the events of a running gadget. Like with
+// RunGadget, events are dropped for clients that don't keep up.
+func (s *Service) AttachToInstance(req *api.AttachToInstanceRequest, stream api.GadgetManager_AttachToInstanceServer) error {
+	client, err := s.clientID(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	events, unsubscribe, err := s.SubscribeRunningGadget(req.Id, client)
+	if err != nil {
+		return instanceError(err, req.Id)
+	}
+	defer unsubscribe()
+
+	seq := uint32(0)
+	for {
+		select {
+		case data, ok := <-events:
+			if !ok {
+				return nil
+			}
+			seq++
+			err := stream.Send(&api.GadgetEvent{
+				Type:    api.EventTypeGadgetPayload,
+				Seq:     seq,
+				Payload: data,
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
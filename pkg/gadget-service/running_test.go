@@ -0,0 +1,397 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/tokenauth"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+type attachStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *api.GadgetEvent
+}
+
+func (s *attachStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *attachStream) Send(ev *api.GadgetEvent) error {
+	s.events <- ev
+	return nil
+}
+
+func TestInstances(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	service := NewService(log.StandardLogger(), 16)
+
+	stopped := make(chan struct{})
+	running := newRunningGadget("id1", "", &api.GadgetRunRequest{
+		GadgetCategory: "trace",
+		GadgetName:     "exec",
+		Args:           []string{"foo"},
+		Detach:         true,
+	})
+	running.cancel = func() { close(stopped) }
+	untrack := service.addRunningGadget(running)
+
+	list, err := service.ListInstances(ctx, &api.ListInstancesRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Instances, 1)
+	require.Equal(t, "id1", list.Instances[0].Id)
+	require.True(t, list.Instances[0].Detached)
+
+	instance, err := service.GetInstance(ctx, &api.GetInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.Equal(t, "trace", instance.GadgetCategory)
+	require.Equal(t, "exec", instance.GadgetName)
+	require.Equal(t, []string{"foo"}, instance.Args)
+	require.Equal(t, running.info.StartedAt.UnixNano(), instance.StartedAt)
+
+	_, err = service.GetInstance(ctx, &api.GetInstanceRequest{Id: "foo"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = service.StopInstance(ctx, &api.StopInstanceRequest{Id: "foo"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = service.StopInstance(ctx, &api.StopInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	<-stopped
+
+	// The instance is only removed once the gadget actually stops
+	untrack()
+
+	list, err = service.ListInstances(ctx, &api.ListInstancesRequest{})
+	require.NoError(t, err)
+	require.Empty(t, list.Instances)
+}
+
+func TestAttachToInstance(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+
+	err := service.AttachToInstance(&api.AttachToInstanceRequest{Id: "foo"}, &attachStream{ctx: context.Background()})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	running := newRunningGadget("id1", "", &api.GadgetRunRequest{GadgetCategory: "trace", GadgetName: "exec"})
+	untrack := service.addRunningGadget(running)
+
+	stream := &attachStream{
+		ctx:    context.Background(),
+		events: make(chan *api.GadgetEvent, 1),
+	}
+	done := make(chan error)
+	go func() {
+		done <- service.AttachToInstance(&api.AttachToInstanceRequest{Id: "id1"}, stream)
+	}()
+
+	// Wait for the client to be subscribed
+	require.Eventually(t, func() bool {
+		running.publish([]byte(`{"comm":"ls"}`))
+		return len(stream.events) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	ev := <-stream.events
+	require.Equal(t, api.EventTypeGadgetPayload, ev.Type)
+	require.Equal(t, uint32(1), ev.Seq)
+	require.JSONEq(t, `{"comm":"ls"}`, string(ev.Payload))
+
+	// Attached clients are disconnected when the gadget stops
+	untrack()
+	for {
+		select {
+		case <-stream.events:
+		case err := <-done:
+			require.NoError(t, err)
+			return
+		}
+	}
+}
+
+// fakeClientAuthorizer identifies clients by their bearer token
+type fakeClientAuthorizer struct{}
+
+func (fakeClientAuthorizer) Authorize(ctx context.Context, gadgetCtx *gadgetcontext.GadgetContext) error {
+	return nil
+}
+
+func (fakeClientAuthorizer) ClientID(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(api.AuthorizationMetadataKey)
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "no credentials provided")
+	}
+	return strings.TrimPrefix(values[0], "Bearer "), nil
+}
+
+func clientContext(client string) context.Context {
+	md := metadata.Pairs(api.AuthorizationMetadataKey, "Bearer "+client)
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestInstancesOwnership(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	service.SetAuthorizer(fakeClientAuthorizer{})
+
+	stopped := false
+	running := newRunningGadget("id1", "alice", &api.GadgetRunRequest{GadgetCategory: "trace", GadgetName: "exec"})
+	running.cancel = func() { stopped = true }
+	defer service.addRunningGadget(running)()
+
+	// Gadgets started by the service itself aren't visible to clients either
+	defer service.addRunningGadget(newRunningGadget("id2", "", &api.GadgetRunRequest{GadgetCategory: "trace", GadgetName: "exec"}))()
+
+	alice := clientContext("alice")
+	bob := clientContext("bob")
+
+	list, err := service.ListInstances(alice, &api.ListInstancesRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Instances, 1)
+	require.Equal(t, "id1", list.Instances[0].Id)
+
+	list, err = service.ListInstances(bob, &api.ListInstancesRequest{})
+	require.NoError(t, err)
+	require.Empty(t, list.Instances)
+
+	// The instances of other clients look like they don't exist
+	_, err = service.GetInstance(bob, &api.GetInstanceRequest{Id: "id1"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	err = service.AttachToInstance(&api.AttachToInstanceRequest{Id: "id1"}, &attachStream{ctx: bob})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = service.StopInstance(bob, &api.StopInstanceRequest{Id: "id1"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.False(t, stopped)
+
+	// Unidentified clients are rejected
+	_, err = service.ListInstances(context.Background(), &api.ListInstancesRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = service.GetInstance(alice, &api.GetInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+
+	_, err = service.StopInstance(alice, &api.StopInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.True(t, stopped)
+}
+
+type pausableGadget struct {
+	paused bool
+}
+
+func (g *pausableGadget) Pause() error {
+	g.paused = true
+	return nil
+}
+
+func (g *pausableGadget) Resume() error {
+	g.paused = false
+	return nil
+}
+
+func TestPauseInstance(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	ctx := context.Background()
+
+	gadgetCtx := gadgetcontext.New(ctx, "id1", nil, nil, nil, nil, nil, nil, nil, nil, 0, nil)
+	defer gadgetCtx.Cancel()
+	running := newRunningGadget("id1", "", &api.GadgetRunRequest{GadgetCategory: "", GadgetName: "run"})
+	running.setContext(gadgetCtx)
+	defer service.addRunningGadget(running)()
+
+	// The gadget isn't running yet
+	_, err := service.PauseInstance(ctx, &api.PauseInstanceRequest{Id: "id1"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	gadget := &pausableGadget{}
+	gadgetCtx.SetGadgetInstance(gadget)
+
+	_, err = service.PauseInstance(ctx, &api.PauseInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.True(t, gadget.paused)
+	instance, err := service.GetInstance(ctx, &api.GetInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.True(t, instance.Paused)
+
+	_, err = service.ResumeInstance(ctx, &api.ResumeInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.False(t, gadget.paused)
+	instance, err = service.GetInstance(ctx, &api.GetInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.False(t, instance.Paused)
+
+	// Gadgets that can't be paused
+	gadgetCtx.SetGadgetInstance(struct{}{})
+	_, err = service.PauseInstance(ctx, &api.PauseInstanceRequest{Id: "id1"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = service.PauseInstance(ctx, &api.PauseInstanceRequest{Id: "id2"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+type updatableParamsGadget struct {
+	params map[string]string
+}
+
+func (g *updatableParamsGadget) UpdateParams(values map[string]string) error {
+	for key := range values {
+		if key != "min-latency" {
+			return fmt.Errorf("param %q can't be changed while the gadget runs", key)
+		}
+	}
+	for key, value := range values {
+		g.params[key] = value
+	}
+	return nil
+}
+
+func TestUpdateInstanceParams(t *testing.T) {
+	t.Parallel()
+
+	service := NewService(log.StandardLogger(), 16)
+	ctx := context.Background()
+
+	gadgetCtx := gadgetcontext.New(ctx, "id1", nil, nil, nil, nil, nil, nil, nil, nil, 0, nil)
+	defer gadgetCtx.Cancel()
+	running := newRunningGadget("id1", "", &api.GadgetRunRequest{
+		GadgetName: "run",
+		Params:     map[string]string{"min-latency": "1ms", "runtime.containername": "foo"},
+	})
+	running.setContext(gadgetCtx)
+	defer service.addRunningGadget(running)()
+
+	// The gadget isn't running yet
+	_, err := service.UpdateInstanceParams(ctx, &api.UpdateInstanceParamsRequest{Id: "id1"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	gadget := &updatableParamsGadget{params: map[string]string{}}
+	gadgetCtx.SetGadgetInstance(gadget)
+
+	_, err = service.UpdateInstanceParams(ctx, &api.UpdateInstanceParamsRequest{
+		Id:     "id1",
+		Params: map[string]string{"min-latency": "5ms"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"min-latency": "5ms"}, gadget.params)
+	instance, err := service.GetInstance(ctx, &api.GetInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"min-latency": "5ms", "runtime.containername": "foo"}, instance.Params)
+
+	_, err = service.UpdateInstanceParams(ctx, &api.UpdateInstanceParamsRequest{
+		Id:     "id1",
+		Params: map[string]string{"stream": "foo"},
+	})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Gadgets whose params can't be changed
+	gadgetCtx.SetGadgetInstance(struct{}{})
+	_, err = service.UpdateInstanceParams(ctx, &api.UpdateInstanceParamsRequest{Id: "id1"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = service.UpdateInstanceParams(ctx, &api.UpdateInstanceParamsRequest{Id: "id2"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// fakeTunableGadgetDesc is a gadget whose min-latency param can be changed
+// while it runs
+type fakeTunableGadgetDesc struct {
+	fakeGadgetDesc
+}
+
+func (fakeTunableGadgetDesc) Name() string { return "tunable" }
+func (fakeTunableGadgetDesc) ParamDescs() params.ParamDescs {
+	return params.ParamDescs{{Key: "min-latency", DefaultValue: "1ms"}}
+}
+
+func init() {
+	gadgetregistry.Register(fakeTunableGadgetDesc{})
+}
+
+func TestUpdateInstanceParamsAuthorized(t *testing.T) {
+	t.Parallel()
+
+	authorizer, err := tokenauth.NewAuthorizer(&tokenauth.Policy{
+		Tokens: []tokenauth.StaticToken{{User: "alice", Token: "alice-token"}},
+		Rules: []tokenauth.Rule{{
+			Subjects: []string{"alice"},
+			Gadgets:  []string{"trace/tunable"},
+			Params:   map[string]string{"min-latency": "[1-5]ms"},
+		}},
+	})
+	require.NoError(t, err)
+
+	service := NewService(log.StandardLogger(), 16)
+	service.runtime = &fakeRuntime{}
+	service.SetAuthorizer(authorizer)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs(api.AuthorizationMetadataKey, "Bearer alice-token"))
+
+	gadgetCtx := gadgetcontext.New(ctx, "id1", nil, nil, nil, nil, nil, nil, nil, nil, 0, nil)
+	defer gadgetCtx.Cancel()
+	gadget := &updatableParamsGadget{params: map[string]string{}}
+	gadgetCtx.SetGadgetInstance(gadget)
+	running := newRunningGadget("id1", "alice", &api.GadgetRunRequest{
+		GadgetCategory: "trace",
+		GadgetName:     "tunable",
+		Params:         map[string]string{"min-latency": "2ms"},
+	})
+	running.setContext(gadgetCtx)
+	defer service.addRunningGadget(running)()
+
+	_, err = service.UpdateInstanceParams(ctx, &api.UpdateInstanceParamsRequest{
+		Id:     "id1",
+		Params: map[string]string{"min-latency": "5ms"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"min-latency": "5ms"}, gadget.params)
+
+	// The rule doesn't allow alice to run the gadget with this value, it's
+	// neither applied nor reported
+	_, err = service.UpdateInstanceParams(ctx, &api.UpdateInstanceParamsRequest{
+		Id:     "id1",
+		Params: map[string]string{"min-latency": "10ms"},
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Equal(t, map[string]string{"min-latency": "5ms"}, gadget.params)
+	instance, err := service.GetInstance(ctx, &api.GetInstanceRequest{Id: "id1"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"min-latency": "5ms"}, instance.Params)
+}
@@ -26,6 +26,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/cilium/ebpf"
 	"github.com/google/uuid"
 	"google.golang.org/grpc"
 
@@ -52,6 +53,49 @@ type RunConfig struct {
 	// If SocketGID != 0 and a unix socket is used, the ownership of that socket
 	// will be changed to the given SocketGID
 	SocketGID int
+
+	// SocketMode is the file mode of the unix socket, if used. It defaults to
+	// DefaultSocketMode if 0.
+	SocketMode os.FileMode
+}
+
+// DefaultSocketMode lets the owner and the group of the unix socket connect to
+// it
+const DefaultSocketMode os.FileMode = 0o660
+
+// Authorizer decides whether the client that sent a request is allowed to run
+// a gadget
+type Authorizer interface {
+	// Authorize returns an error if the client the request in ctx comes from
+	// can't run the gadget described by gadgetCtx
+	Authorize(ctx context.Context, gadgetCtx *gadgetcontext.GadgetContext) error
+}
+
+// Authenticator is implemented by authorizers that can also authenticate
+// clients on their own. When the authorizer of the service implements it, it's
+// used to protect the endpoints of the HTTP gateway that don't run gadgets.
+type Authenticator interface {
+	// Authenticate returns an error if the client the request in ctx comes
+	// from can't be authenticated
+	Authenticate(ctx context.Context) error
+}
+
+// ClientImpersonator is implemented by authorizers that can authorize requests
+// on behalf of a client without its credentials. When the authorizer of the
+// service implements it, restored detached instances are authorized again as
+// the client that started them; otherwise they aren't restored.
+type ClientImpersonator interface {
+	// WithClient returns a context whose requests are authorized as client,
+	// as returned by ClientIdentifier
+	WithClient(ctx context.Context, client string) context.Context
+}
+
+// LeaderChecker tells whether this instance of the service is the leader among
+// all the instances in the cluster
+type LeaderChecker interface {
+	IsLeader() bool
+	// GetLeader returns the identity of the current leader
+	GetLeader() string
 }
 
 type Service struct {
@@ -61,14 +105,105 @@ type Service struct {
 	logger            logger.Logger
 	servers           map[*grpc.Server]struct{}
 	eventBufferLength uint64
+	backpressure      Backpressure
+	authorizer        Authorizer
+	leaderChecker     LeaderChecker
+
+	runningMu      sync.Mutex
+	runningGadgets map[string]*runningGadget
+
+	initOnce sync.Once
+	initErr  error
+
+	instanceStore *instanceStore
+	quotas        *quotaTracker
+	auditSink     AuditSink
 }
 
 func NewService(defaultLogger logger.Logger, length uint64) *Service {
 	return &Service{
+		runtime:           local.New(),
 		servers:           map[*grpc.Server]struct{}{},
 		logger:            defaultLogger,
 		eventBufferLength: length,
+		runningGadgets:    map[string]*runningGadget{},
+	}
+}
+
+// SetAuthorizer sets the authorizer used to check whether clients can run the
+// requested gadgets or get information about them. Gadgets run with
+// RunHeadless are authorized using the context passed to it, see
+// rbac.WithServiceAccount.
+func (s *Service) SetAuthorizer(authorizer Authorizer) {
+	s.authorizer = authorizer
+}
+
+// SetBackpressure sets what happens to the events when the clients receive
+// them slower than the gadgets generate them. Events that don't fit in the
+// buffer are dropped by default.
+func (s *Service) SetBackpressure(backpressure Backpressure) error {
+	if _, err := ParseBackpressurePolicy(string(backpressure.Policy)); err != nil {
+		return err
+	}
+	s.backpressure = backpressure
+	return nil
+}
+
+// SetLeaderChecker sets the leader checker used to run cluster-scoped gadgets
+// on a single instance. They run on all instances if it isn't set.
+func (s *Service) SetLeaderChecker(leaderChecker LeaderChecker) {
+	s.leaderChecker = leaderChecker
+}
+
+// SetInstancesDir sets the directory where detached gadget instances are
+// stored. Instances found in it are restored when the service starts running.
+func (s *Service) SetInstancesDir(dir string) error {
+	store, err := newInstanceStore(dir)
+	if err != nil {
+		return fmt.Errorf("creating instance store: %w", err)
+	}
+	s.instanceStore = store
+	return nil
+}
+
+// SetQuota limits the resources each client can use with the gadgets it runs
+// with RunGadget
+func (s *Service) SetQuota(quota Quota) {
+	s.quotas = newQuotaTracker(quota)
+}
+
+// SetAuditSink sets the sink receiving a record each time a gadget starts,
+// stops or is denied
+func (s *Service) SetAuditSink(sink AuditSink) {
+	s.auditSink = sink
+}
+
+// isClusterScoped returns whether the gadget is cluster-scoped, either because
+// its descriptor says so or because the metadata of its image does
+func isClusterScoped(gadgetDesc gadgets.GadgetDesc, gadgetInfo *runTypes.GadgetInfo) bool {
+	if clusterScoped, ok := gadgetDesc.(gadgets.GadgetClusterScoped); ok && clusterScoped.ClusterScoped() {
+		return true
+	}
+	return gadgetInfo != nil && gadgetInfo.GadgetMetadata != nil && gadgetInfo.GadgetMetadata.ClusterScoped
+}
+
+// skipGadget returns whether the gadget must not run on this instance because
+// it's cluster-scoped and this instance isn't the leader. In that case, it
+// logs where the gadget runs instead, so clients know why they don't get any
+// data from this instance.
+func (s *Service) skipGadget(gadgetCtx *gadgetcontext.GadgetContext, logger logger.Logger) bool {
+	if s.leaderChecker == nil {
+		return false
+	}
+	if !isClusterScoped(gadgetCtx.GadgetDesc(), gadgetCtx.GadgetInfo()) {
+		return false
 	}
+	if s.leaderChecker.IsLeader() {
+		return false
+	}
+	logger.Infof("gadget %q is cluster-scoped, it only runs on the leader instance %q",
+		gadgetCtx.GadgetDesc().Name(), s.leaderChecker.GetLeader())
+	return true
 }
 
 func (s *Service) GetInfo(ctx context.Context, request *api.InfoRequest) (*api.InfoResponse, error) {
@@ -89,20 +224,26 @@ func (s *Service) GetInfo(ctx context.Context, request *api.InfoRequest) (*api.I
 }
 
 func (s *Service) GetGadgetInfo(ctx context.Context, req *api.GetGadgetInfoRequest) (*api.GetGadgetInfoResponse, error) {
-	gadgetDesc := gadgetregistry.Get(gadgets.CategoryNone, "run")
-	if gadgetDesc == nil {
-		return nil, errors.New("run gadget not found")
+	client, err := s.clientID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	params := gadgetDesc.ParamDescs().ToParams()
-	params.CopyFromMap(req.Params, "")
-
-	ret, err := s.runtime.GetGadgetInfo(ctx, gadgetDesc, params, req.Args)
+	// Getting the information pulls the image, so clients need to be allowed
+	// to run it
+	request := &api.GadgetRunRequest{
+		GadgetCategory: gadgets.CategoryNone,
+		GadgetName:     "run",
+		Params:         req.Params,
+		Args:           req.Args,
+	}
+	gadgetCtx, err := s.newGadgetContext(ctx, "", request, s.logger, func([]byte) {}, s.authorizeFunc(ctx, client))
 	if err != nil {
-		return nil, fmt.Errorf("getting gadget info: %w", err)
+		return nil, err
 	}
+	defer gadgetCtx.Cancel()
 
-	retJSON, err := json.Marshal(ret)
+	retJSON, err := json.Marshal(gadgetCtx.GadgetInfo())
 	if err != nil {
 		return nil, fmt.Errorf("marshal gadget info response: %w", err)
 	}
@@ -112,35 +253,48 @@ func (s *Service) GetGadgetInfo(ctx context.Context, req *api.GetGadgetInfoReque
 	}, nil
 }
 
-func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
-	ctrl, err := runGadget.Recv()
-	if err != nil {
-		return err
+// authorizeFunc returns a function checking whether client, that sent the
+// request in ctx, can run the gadget described by a gadget context. Denials
+// are audited. It returns nil if there is no authorizer.
+func (s *Service) authorizeFunc(ctx context.Context, client string) func(*gadgetcontext.GadgetContext) error {
+	if s.authorizer == nil {
+		return nil
 	}
-
-	request := ctrl.GetRunRequest()
-	if request == nil {
-		return fmt.Errorf("expected first control message to be gadget request")
+	return func(gadgetCtx *gadgetcontext.GadgetContext) error {
+		if err := s.authorizer.Authorize(ctx, gadgetCtx); err != nil {
+			s.auditDenied(client, gadgetCtx, err)
+			return err
+		}
+		return nil
 	}
+}
 
-	// Create a new logger that logs to gRPC and falls back to the standard logger when it failed to send the message
-	logger := logger.NewFromGenericLogger(&Logger{
-		send:           runGadget.Send,
-		level:          logger.Level(request.LogLevel),
-		fallbackLogger: s.logger,
-	})
-
+// newGadgetContext looks up the gadget described by request, sets up its parameters and parser
+// and returns a gadget context ready to be handed over to the runtime. eventCallback is called
+// with each event marshaled to JSON.
+//
+// If authorize is set, it's called before pulling the image of the gadget, if any, with a gadget
+// context without gadget information. Permissions depending on the content of the image must be
+// checked again with the returned gadget context.
+func (s *Service) newGadgetContext(
+	ctx context.Context,
+	runID string,
+	request *api.GadgetRunRequest,
+	logger logger.Logger,
+	eventCallback func(data []byte),
+	authorize func(*gadgetcontext.GadgetContext) error,
+) (*gadgetcontext.GadgetContext, error) {
 	runtime := s.runtime
 
 	gadgetDesc := gadgetregistry.Get(request.GadgetCategory, request.GadgetName)
 	if gadgetDesc == nil {
-		return fmt.Errorf("gadget not found: %s/%s", request.GadgetCategory, request.GadgetName)
+		return nil, fmt.Errorf("gadget not found: %s/%s", request.GadgetCategory, request.GadgetName)
 	}
 
 	// Initialize Operators
-	err = operators.GetAll().Init(operators.GlobalParamsCollection())
+	err := operators.GetAll().Init(operators.GlobalParamsCollection())
 	if err != nil {
-		return fmt.Errorf("initialize operators: %w", err)
+		return nil, fmt.Errorf("initialize operators: %w", err)
 	}
 
 	ops := operators.GetOperatorsForGadget(gadgetDesc)
@@ -158,19 +312,29 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 	gadgetParams := gadgetParamDescs.ToParams()
 	err = gadgets.ParamsFromMap(request.Params, gadgetParams, runtimeParams, operatorParams)
 	if err != nil {
-		return fmt.Errorf("setting parameters: %w", err)
+		return nil, fmt.Errorf("setting parameters: %w", err)
 	}
 
 	var gadgetInfo *runTypes.GadgetInfo
 
 	if c, ok := gadgetDesc.(runTypes.RunGadgetDesc); ok {
-		gadgetInfo, err = s.runtime.GetGadgetInfo(runGadget.Context(), gadgetDesc, gadgetParams, request.Args)
+		if authorize != nil {
+			preliminaryCtx := gadgetcontext.New(ctx, runID, runtime, runtimeParams, gadgetDesc, gadgetParams,
+				request.Args, operatorParams, nil, logger, time.Duration(request.Timeout), nil)
+			err := authorize(preliminaryCtx)
+			preliminaryCtx.Cancel()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		gadgetInfo, err = s.runtime.GetGadgetInfo(ctx, gadgetDesc, gadgetParams, request.Args)
 		if err != nil {
-			return fmt.Errorf("getting gadget info: %w", err)
+			return nil, fmt.Errorf("getting gadget info: %w", err)
 		}
 		parser, err = c.CustomParser(gadgetInfo)
 		if err != nil {
-			return fmt.Errorf("calling custom parser: %w", err)
+			return nil, fmt.Errorf("calling custom parser: %w", err)
 		}
 
 		// Update gadget parameters to take ebpf params into consideration
@@ -181,64 +345,218 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 		gadgetParams = gadgetParamDescs.ToParams()
 		err = gadgetParams.CopyFromMap(request.Params, "")
 		if err != nil {
-			return fmt.Errorf("setting parameters: %w", err)
+			return nil, fmt.Errorf("setting parameters: %w", err)
+		}
+	}
+
+	if parser != nil {
+		parser.SetLogCallback(logger.Logf)
+		parser.SetEventCallback(func(ev any) {
+			data, _ := json.Marshal(ev)
+			eventCallback(data)
+		})
+		// Events are marshalled right away, so they can be reused
+		parser.EnableEventRelease()
+
+		// The client combines the results of all nodes and limits them again, so only the first
+		// entries of each node are needed. Sort them the same way so they are the right ones.
+		if request.MaxRows > 0 {
+			if sortBy := gadgetParams.Get(gadgets.ParamSortBy); sortBy != nil {
+				if err := parser.SetSorting(sortBy.AsStringSlice()); err != nil {
+					return nil, fmt.Errorf("setting sort order: %w", err)
+				}
+			}
+			parser.SetMaxRows(int(request.MaxRows))
 		}
+	}
+
+	return gadgetcontext.New(
+		ctx,
+		runID,
+		runtime,
+		runtimeParams,
+		gadgetDesc,
+		gadgetParams,
+		request.Args,
+		operatorParams,
+		parser,
+		logger,
+		time.Duration(request.Timeout),
+		gadgetInfo,
+	), nil
+}
+
+// RunHeadless runs the gadget described by request without a client attached. Events are
+// marshaled to JSON and handed over to eventCallback. It blocks until the gadget finishes or ctx
+// is done and returns the results of the gadget, if any.
+func (s *Service) RunHeadless(
+	ctx context.Context,
+	request *api.GadgetRunRequest,
+	logger logger.Logger,
+	eventCallback func(data []byte),
+) ([][]byte, error) {
+	if err := s.Init(); err != nil {
+		return nil, err
+	}
+
+	runID := uuid.New().String()
+	running := newRunningGadget(runID, "", request)
+	authorize := s.authorizeFunc(ctx, "")
+	gadgetCtx, err := s.newGadgetContext(ctx, runID, request, logger, func(data []byte) {
+		running.publish(data)
+		eventCallback(data)
+	}, authorize)
+	if err != nil {
+		return nil, err
+	}
+	defer gadgetCtx.Cancel()
+
+	if authorize != nil {
+		if err := authorize(gadgetCtx); err != nil {
+			return nil, err
+		}
+	}
+	running.setContext(gadgetCtx)
+	defer s.addRunningGadget(running)()
+
+	if s.skipGadget(gadgetCtx, logger) {
+		return nil, nil
+	}
+
+	auditStop, err := s.auditStart("", gadgetCtx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.runtime.RunGadget(gadgetCtx)
+	auditStop(err)
+	if err != nil {
+		return nil, fmt.Errorf("running gadget: %w", err)
+	}
+
+	payloads := make([][]byte, 0, len(results))
+	for _, result := range results {
+		payloads = append(payloads, result.Payload)
+	}
+	return payloads, nil
+}
 
+func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
+	ctrl, err := runGadget.Recv()
+	if err != nil {
+		return err
+	}
+
+	request := ctrl.GetRunRequest()
+	if request == nil {
+		return fmt.Errorf("expected first control message to be gadget request")
 	}
 
+	if request.Detach {
+		return s.runDetached(runGadget, request)
+	}
+
+	// Create a new logger that logs to gRPC and falls back to the standard logger when it failed to send the message
+	logger := logger.NewFromGenericLogger(&Logger{
+		send:           runGadget.Send,
+		level:          logger.Level(request.LogLevel),
+		fallbackLogger: s.logger,
+	})
+
 	// Create payload buffer
-	outputBuffer := make(chan *api.GadgetEvent, s.eventBufferLength)
+	outputBuffer, err := newEventQueue(s.eventBufferLength, s.backpressure)
+	if err != nil {
+		return fmt.Errorf("creating events buffer: %w", err)
+	}
+	defer outputBuffer.close()
 
 	seq := uint32(0)
 	var seqLock sync.Mutex
 
-	if parser != nil {
+	client, err := s.clientID(runGadget.Context())
+	if err != nil {
+		return err
+	}
+
+	// Assign a unique ID - this will be used in the future
+	runID := uuid.New().String()
+	running := newRunningGadget(runID, client, request)
+
+	// Replaced by the event rate limit of the client below, before the gadget
+	// starts
+	allowEvent := func() bool { return true }
+
+	// Create new Gadget Context
+	authorize := s.authorizeFunc(runGadget.Context(), client)
+	gadgetCtx, err := s.newGadgetContext(runGadget.Context(), runID, request, logger, func(data []byte) {
+		if !allowEvent() {
+			return
+		}
+
+		running.publish(data)
+
+		// Normally, it would be better to marshal the events in the pump below rather than marshaling
+		// events that would be dropped anyway. However, we're optimistic that this occurs rarely and
+		// instead prevent using ev in another thread.
+		event := &api.GadgetEvent{
+			Type:    api.EventTypeGadgetPayload,
+			Payload: data,
+		}
+
+		seqLock.Lock()
+		seq++
+		event.Seq = seq
+
+		// If outputBuffer is full, the backpressure policy decides whether
+		// this or an older event is dropped or whether we wait
+		outputBuffer.push(event)
+		seqLock.Unlock()
+	}, authorize)
+	if err != nil {
+		return err
+	}
+	defer gadgetCtx.Cancel()
+
+	if authorize != nil {
+		if err := authorize(gadgetCtx); err != nil {
+			return err
+		}
+	}
+
+	allowEvent, releaseQuota, err := s.acquireQuota(client, gadgetCtx)
+	if err != nil {
+		s.auditDenied(client, gadgetCtx, err)
+		return err
+	}
+	defer releaseQuota()
+
+	running.setContext(gadgetCtx)
+	defer s.addRunningGadget(running)()
+
+	if gadgetCtx.Parser() != nil {
 		outputDone := make(chan bool)
 		defer func() {
 			outputDone <- true
 		}()
 
-		parser.SetLogCallback(logger.Logf)
-		parser.SetEventCallback(func(ev any) {
-			// Marshal messages to JSON
-			// Normally, it would be better to have this in the pump below rather than marshaling events that
-			// would be dropped anyway. However, we're optimistic that this occurs rarely and instead prevent using
-			// ev in another thread.
-			data, _ := json.Marshal(ev)
-			event := &api.GadgetEvent{
-				Type:    api.EventTypeGadgetPayload,
-				Payload: data,
-			}
-
-			seqLock.Lock()
-			seq++
-			event.Seq = seq
-
-			// Try to send event; if outputBuffer is full, it will be dropped by taking
-			// the default path.
-			select {
-			case outputBuffer <- event:
-			default:
-			}
-			seqLock.Unlock()
-		})
-
 		go func() {
 			// Message pump to handle slow readers
 			for {
-				select {
-				case ev := <-outputBuffer:
-					runGadget.Send(ev)
-				case <-outputDone:
+				ev, ok := outputBuffer.pop(outputDone)
+				if !ok {
 					return
 				}
+				runGadget.Send(ev)
+				if dropped := outputBuffer.takeDropped(); dropped > 0 {
+					logger.Warnf("dropped %d events because they weren't received fast enough", dropped)
+				}
 			}
 		}()
+	} else {
+		// Nobody sends the events, don't let the gadget wait for it
+		outputBuffer.close()
 	}
 
-	// Assign a unique ID - this will be used in the future
-	runID := uuid.New().String()
-
 	// Send Job ID to client
 	err = runGadget.Send(&api.GadgetEvent{
 		Type:    api.EventTypeGadgetJobID,
@@ -249,23 +567,6 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 		return nil
 	}
 
-	// Create new Gadget Context
-	gadgetCtx := gadgetcontext.New(
-		runGadget.Context(),
-		runID,
-		runtime,
-		runtimeParams,
-		gadgetDesc,
-		gadgetParams,
-		request.Args,
-		operatorParams,
-		parser,
-		logger,
-		time.Duration(request.Timeout),
-		gadgetInfo,
-	)
-	defer gadgetCtx.Cancel()
-
 	// Handle commands sent by the client
 	go func() {
 		defer func() {
@@ -287,10 +588,29 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 		}
 	}()
 
+	if s.skipGadget(gadgetCtx, logger) {
+		return nil
+	}
+
+	auditStop, err := s.auditStart(client, gadgetCtx, false)
+	if err != nil {
+		return err
+	}
+
 	// Hand over to runtime
-	results, err := runtime.RunGadget(gadgetCtx)
+	results, err := s.runtime.RunGadget(gadgetCtx)
+	auditStop(err)
+	// Attached instances don't restart
+	s.removeInstanceState(runID)
 	if err != nil {
-		return fmt.Errorf("running gadget: %w", err)
+		err = fmt.Errorf("running gadget: %w", err)
+		// Send the whole verifier log so the failure can be investigated
+		// without reproducing it on the node
+		var ve *ebpf.VerifierError
+		if errors.As(err, &ve) {
+			return api.ErrorWithVerifierLog(err, &api.VerifierLog{Lines: ve.Log, Truncated: ve.Truncated})
+		}
+		return err
 	}
 
 	// Send result, if any
@@ -306,7 +626,168 @@ func (s *Service) RunGadget(runGadget api.GadgetManager_RunGadgetServer) error {
 	return nil
 }
 
-func newUnixListener(address string, gid int) (net.Listener, error) {
+// runDetached starts the gadget described by request in the background and
+// only sends its ID to the client. The gadget keeps running until it's done or
+// stopped with StopInstance; its events can be received with AttachToInstance.
+func (s *Service) runDetached(runGadget api.GadgetManager_RunGadgetServer, request *api.GadgetRunRequest) error {
+	client, err := s.clientID(runGadget.Context())
+	if err != nil {
+		return err
+	}
+
+	instance := storedInstance{
+		id:      uuid.New().String(),
+		client:  client,
+		request: request,
+	}
+	if err := s.startDetached(runGadget.Context(), instance); err != nil {
+		return err
+	}
+	return runGadget.Send(&api.GadgetEvent{
+		Type:    api.EventTypeGadgetJobID,
+		Payload: []byte(instance.id),
+	})
+}
+
+// startDetached starts a detached gadget instance. The request is authorized
+// using the client credentials in ctx.
+func (s *Service) startDetached(ctx context.Context, instance storedInstance) error {
+	runID := instance.id
+	running := newRunningGadget(runID, instance.client, instance.request)
+
+	// Replaced by the event rate limit of the client below, before the gadget
+	// starts
+	allowEvent := func() bool { return true }
+
+	authorizeFn := s.authorizeFunc(ctx, instance.client)
+
+	// The gadget must not be stopped when the client disconnects, so don't
+	// derive its context from the one of the stream
+	gadgetCtx, err := s.newGadgetContext(context.Background(), runID, instance.request, s.logger, func(data []byte) {
+		if allowEvent() {
+			running.publish(data)
+		}
+	}, authorizeFn)
+	if err != nil {
+		return err
+	}
+
+	if authorizeFn != nil {
+		if err := authorizeFn(gadgetCtx); err != nil {
+			gadgetCtx.Cancel()
+			return err
+		}
+	}
+
+	if s.skipGadget(gadgetCtx, s.logger) {
+		gadgetCtx.Cancel()
+		return nil
+	}
+
+	allowEvent, releaseQuota, err := s.acquireQuota(instance.client, gadgetCtx)
+	if err != nil {
+		s.auditDenied(instance.client, gadgetCtx, err)
+		gadgetCtx.Cancel()
+		return err
+	}
+
+	if s.instanceStore != nil {
+		if err := s.instanceStore.save(instance); err != nil {
+			releaseQuota()
+			gadgetCtx.Cancel()
+			return fmt.Errorf("storing instance: %w", err)
+		}
+	}
+
+	auditStop, err := s.auditStart(instance.client, gadgetCtx, true)
+	if err != nil {
+		if s.instanceStore != nil {
+			if err := s.instanceStore.remove(runID); err != nil {
+				s.logger.Warnf("removing stored instance: %v", err)
+			}
+		}
+		releaseQuota()
+		gadgetCtx.Cancel()
+		return err
+	}
+
+	running.setContext(gadgetCtx)
+	untrack := s.addRunningGadget(running)
+
+	go func() {
+		defer gadgetCtx.Cancel()
+		defer releaseQuota()
+		defer untrack()
+
+		_, err := s.runtime.RunGadget(gadgetCtx)
+		auditStop(err)
+		if err != nil {
+			s.logger.Warnf("running detached gadget %s: %v", runID, err)
+		}
+
+		// The gadget was stopped or is done, it must not be restored
+		if s.instanceStore != nil {
+			if err := s.instanceStore.remove(runID); err != nil {
+				s.logger.Warnf("removing stored instance: %v", err)
+			}
+		}
+		s.removeInstanceState(runID)
+	}()
+
+	return nil
+}
+
+// removeInstanceState removes the state maps pinned by an instance that won't
+// restart, see the persist-state param of the run gadget
+func (s *Service) removeInstanceState(runID string) {
+	if err := gadgets.RemoveInstanceState(runID); err != nil {
+		s.logger.Warnf("removing state of gadget instance: %v", err)
+	}
+}
+
+// restoreInstances starts again the detached instances that were running when
+// the service stopped
+func (s *Service) restoreInstances() {
+	instances, err := s.instanceStore.load()
+	if err != nil {
+		s.logger.Warnf("loading stored instances: %v", err)
+	}
+	for _, instance := range instances {
+		s.logger.Infof("restoring gadget instance %s (%s/%s)", instance.id,
+			instance.request.GadgetCategory, instance.request.GadgetName)
+		ctx, err := s.restoreContext(instance.client)
+		if err == nil {
+			err = s.startDetached(ctx, instance)
+		}
+		if err != nil {
+			s.logger.Warnf("restoring gadget instance %s: %v", instance.id, err)
+			if err := s.instanceStore.remove(instance.id); err != nil {
+				s.logger.Warnf("removing stored instance: %v", err)
+			}
+			s.removeInstanceState(instance.id)
+		}
+	}
+}
+
+// restoreContext returns the context used to authorize again a restored
+// instance started by client. The policy could have changed since the
+// instance was started.
+func (s *Service) restoreContext(client string) (context.Context, error) {
+	ctx := context.Background()
+	if s.authorizer == nil {
+		return ctx, nil
+	}
+	impersonator, ok := s.authorizer.(ClientImpersonator)
+	if !ok {
+		return nil, errors.New("the authorizer can't authorize restored instances")
+	}
+	if client == "" {
+		return nil, errors.New("the client that started the instance is unknown")
+	}
+	return impersonator.WithClient(ctx, client), nil
+}
+
+func newUnixListener(address string, gid int, mode os.FileMode) (net.Listener, error) {
 	if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("removing existing unix socket at %q: %w", address, err)
 	}
@@ -335,27 +816,46 @@ func newUnixListener(address string, gid int) (net.Listener, error) {
 		listener.Close()
 		return nil, fmt.Errorf("chown unix socket %q: %w", address, err)
 	}
-	if err := os.Chmod(address, 0o660); err != nil {
+	if mode == 0 {
+		mode = DefaultSocketMode
+	}
+	if err := os.Chmod(address, mode); err != nil {
 		listener.Close()
 		return nil, fmt.Errorf("chmod unix socket %q: %w", address, err)
 	}
 	return listener, nil
 }
 
+// Init initializes the runtime and restores the stored gadget instances. It
+// must be called before running gadgets with RunHeadless, e.g. from a
+// controller started before Run. Calling it more than once has no effect.
+func (s *Service) Init() error {
+	s.initOnce.Do(func() {
+		// Use defaults for now - this will become more important when we fan-out requests also to other
+		//  gRPC runtimes
+		err := s.runtime.Init(s.runtime.GlobalParamDescs().ToParams())
+		if err != nil {
+			s.initErr = fmt.Errorf("initializing runtime: %w", err)
+			return
+		}
+
+		if s.instanceStore != nil {
+			s.restoreInstances()
+		}
+	})
+	return s.initErr
+}
+
 func (s *Service) Run(runConfig RunConfig, serverOptions ...grpc.ServerOption) error {
-	s.runtime = local.New()
 	defer s.runtime.Close()
 
-	// Use defaults for now - this will become more important when we fan-out requests also to other
-	//  gRPC runtimes
-	err := s.runtime.Init(s.runtime.GlobalParamDescs().ToParams())
-	if err != nil {
-		return fmt.Errorf("initializing runtime: %w", err)
+	if err := s.Init(); err != nil {
+		return err
 	}
 
 	switch runConfig.SocketType {
 	case "unix":
-		listener, err := newUnixListener(runConfig.SocketPath, runConfig.SocketGID)
+		listener, err := newUnixListener(runConfig.SocketPath, runConfig.SocketGID, runConfig.SocketMode)
 		if err != nil {
 			return fmt.Errorf("creating unix listener: %w", err)
 		}
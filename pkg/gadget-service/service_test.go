@@ -0,0 +1,358 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	runTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/parser"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+)
+
+// newUnixListener changes the umask of the process, so this test can't run in
+// parallel with other ones
+func TestUnixListenerMode(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of the socket requires root")
+	}
+
+	type testDefinition struct {
+		mode     os.FileMode
+		expected os.FileMode
+	}
+
+	tests := map[string]testDefinition{
+		"default": {
+			expected: DefaultSocketMode,
+		},
+		"custom": {
+			mode:     0o666,
+			expected: 0o666,
+		},
+		"owner_only": {
+			mode:     0o600,
+			expected: 0o600,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			address := filepath.Join(t.TempDir(), "ig.socket")
+			listener, err := newUnixListener(address, 0, test.mode)
+			require.NoError(t, err)
+			defer listener.Close()
+
+			info, err := os.Stat(address)
+			require.NoError(t, err)
+			require.Equal(t, os.ModeSocket, info.Mode().Type())
+			require.Equal(t, test.expected, info.Mode().Perm())
+		})
+	}
+}
+
+type fakeLeaderChecker struct {
+	leader bool
+}
+
+func (f fakeLeaderChecker) IsLeader() bool    { return f.leader }
+func (f fakeLeaderChecker) GetLeader() string { return "node1" }
+
+type fakeClusterScopedGadgetDesc struct {
+	fakeGadgetDesc
+}
+
+func (fakeClusterScopedGadgetDesc) ClusterScoped() bool { return true }
+
+func TestSkipGadget(t *testing.T) {
+	t.Parallel()
+
+	clusterScopedInfo := &runTypes.GadgetInfo{
+		GadgetMetadata: &runTypes.GadgetMetadata{ClusterScoped: true},
+	}
+
+	type testDefinition struct {
+		leaderChecker LeaderChecker
+		gadgetDesc    gadgets.GadgetDesc
+		gadgetInfo    *runTypes.GadgetInfo
+		expectedSkip  bool
+	}
+
+	tests := map[string]testDefinition{
+		"no_leader_election": {
+			gadgetDesc: fakeClusterScopedGadgetDesc{},
+		},
+		"node_scoped": {
+			leaderChecker: fakeLeaderChecker{leader: false},
+			gadgetDesc:    fakeGadgetDesc{},
+		},
+		"cluster_scoped_leader": {
+			leaderChecker: fakeLeaderChecker{leader: true},
+			gadgetDesc:    fakeClusterScopedGadgetDesc{},
+		},
+		"cluster_scoped_non_leader": {
+			leaderChecker: fakeLeaderChecker{leader: false},
+			gadgetDesc:    fakeClusterScopedGadgetDesc{},
+			expectedSkip:  true,
+		},
+		"cluster_scoped_image_non_leader": {
+			leaderChecker: fakeLeaderChecker{leader: false},
+			gadgetDesc:    fakeGadgetDesc{},
+			gadgetInfo:    clusterScopedInfo,
+			expectedSkip:  true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			logger := log.New()
+			logger.SetOutput(&out)
+
+			service := NewService(logger, 16)
+			if test.leaderChecker != nil {
+				service.SetLeaderChecker(test.leaderChecker)
+			}
+
+			gadgetCtx := gadgetcontext.New(context.Background(), "id1", nil, nil,
+				test.gadgetDesc, nil, nil, nil, nil, logger, 0, test.gadgetInfo)
+			defer gadgetCtx.Cancel()
+
+			require.Equal(t, test.expectedSkip, service.skipGadget(gadgetCtx, logger))
+			if test.expectedSkip {
+				// The client is told where the gadget runs
+				require.Contains(t, out.String(), "node1")
+			} else {
+				require.Empty(t, out.String())
+			}
+		})
+	}
+}
+
+// fakeRunGadgetDesc is registered as the run gadget
+type fakeRunGadgetDesc struct {
+	fakeGadgetDesc
+}
+
+func (fakeRunGadgetDesc) Name() string     { return "run" }
+func (fakeRunGadgetDesc) Category() string { return gadgets.CategoryNone }
+func (fakeRunGadgetDesc) GetGadgetInfo(*params.Params, []string) (*runTypes.GadgetInfo, error) {
+	return nil, nil
+}
+func (fakeRunGadgetDesc) CustomParser(*runTypes.GadgetInfo) (parser.Parser, error) { return nil, nil }
+func (fakeRunGadgetDesc) JSONConverter(*runTypes.GadgetInfo, runTypes.Printer) func(ev any) {
+	return nil
+}
+func (fakeRunGadgetDesc) JSONPrettyConverter(*runTypes.GadgetInfo, runTypes.Printer) func(ev any) {
+	return nil
+}
+func (fakeRunGadgetDesc) YAMLConverter(*runTypes.GadgetInfo, runTypes.Printer) func(ev any) {
+	return nil
+}
+
+func init() {
+	gadgetregistry.Register(fakeRunGadgetDesc{})
+}
+
+// fakeRuntime counts the images pulled to get the information of the gadgets
+type fakeRuntime struct {
+	runtime.Runtime
+	pulls atomic.Int32
+	inits atomic.Int32
+}
+
+func (f *fakeRuntime) Init(*params.Params) error {
+	f.inits.Add(1)
+	return nil
+}
+
+func (f *fakeRuntime) GlobalParamDescs() params.ParamDescs { return nil }
+func (f *fakeRuntime) ParamDescs() params.ParamDescs       { return nil }
+
+func (f *fakeRuntime) GetGadgetInfo(context.Context, gadgets.GadgetDesc, *params.Params, []string) (*runTypes.GadgetInfo, error) {
+	f.pulls.Add(1)
+	return &runTypes.GadgetInfo{GadgetMetadata: &runTypes.GadgetMetadata{Name: "foo"}}, nil
+}
+
+// RunGadget runs until the gadget is stopped
+func (f *fakeRuntime) RunGadget(gadgetCtx runtime.GadgetContext) (runtime.CombinedGadgetResult, error) {
+	<-gadgetCtx.Context().Done()
+	return nil, nil
+}
+
+// imageAuthorizer only allows to run the given image
+type imageAuthorizer struct {
+	image string
+}
+
+func (a imageAuthorizer) Authorize(ctx context.Context, gadgetCtx *gadgetcontext.GadgetContext) error {
+	if len(gadgetCtx.Args()) == 0 || gadgetCtx.Args()[0] != a.image {
+		return status.Error(codes.PermissionDenied, "image not allowed")
+	}
+	return nil
+}
+
+func TestAuthorizeBeforePull(t *testing.T) {
+	t.Parallel()
+
+	rt := &fakeRuntime{}
+	service := NewService(log.StandardLogger(), 16)
+	service.runtime = rt
+	service.SetAuthorizer(imageAuthorizer{image: "allowed"})
+
+	ctx := context.Background()
+
+	_, err := service.GetGadgetInfo(ctx, &api.GetGadgetInfoRequest{Args: []string{"forbidden"}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Zero(t, rt.pulls.Load())
+
+	request := &api.GadgetRunRequest{
+		GadgetCategory: gadgets.CategoryNone,
+		GadgetName:     "run",
+		Args:           []string{"forbidden"},
+	}
+	_, err = service.RunHeadless(ctx, request, log.StandardLogger(), func([]byte) {})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Zero(t, rt.pulls.Load())
+
+	resp, err := service.GetGadgetInfo(ctx, &api.GetGadgetInfoRequest{Args: []string{"allowed"}})
+	require.NoError(t, err)
+	require.Contains(t, string(resp.Info), `"foo"`)
+	require.Equal(t, int32(1), rt.pulls.Load())
+}
+
+func TestRunHeadlessInitializesRuntime(t *testing.T) {
+	t.Parallel()
+
+	rt := &fakeRuntime{}
+	service := NewService(log.StandardLogger(), 16)
+	service.runtime = rt
+
+	// Controllers can run gadgets before the service is started
+	_, err := service.RunHeadless(context.Background(), &api.GadgetRunRequest{
+		GadgetCategory: "foo",
+		GadgetName:     "bar",
+	}, log.StandardLogger(), func([]byte) {})
+	require.ErrorContains(t, err, "gadget not found")
+	require.Equal(t, int32(1), rt.inits.Load())
+
+	require.NoError(t, service.Init())
+	require.Equal(t, int32(1), rt.inits.Load())
+}
+
+type impersonatedClientKey struct{}
+
+// clientAuthorizer only allows the given clients to run gadgets
+type clientAuthorizer struct {
+	allowed []string
+}
+
+func (a clientAuthorizer) Authorize(ctx context.Context, gadgetCtx *gadgetcontext.GadgetContext) error {
+	client, _ := ctx.Value(impersonatedClientKey{}).(string)
+	for _, allowed := range a.allowed {
+		if client == allowed {
+			return nil
+		}
+	}
+	return status.Error(codes.PermissionDenied, "client not allowed")
+}
+
+func (a clientAuthorizer) WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, impersonatedClientKey{}, client)
+}
+
+func TestRestoreInstances(t *testing.T) {
+	t.Parallel()
+
+	request := &api.GadgetRunRequest{
+		GadgetCategory: gadgets.CategoryNone,
+		GadgetName:     "run",
+		Args:           []string{"image"},
+		Detach:         true,
+	}
+
+	type testDefinition struct {
+		authorizer Authorizer
+		expected   []string
+	}
+
+	tests := map[string]testDefinition{
+		"no_authorizer": {
+			expected: []string{"alice", "bob", "unknown"},
+		},
+		"authorized_again": {
+			authorizer: clientAuthorizer{allowed: []string{"alice"}},
+			// bob isn't allowed anymore and the client of unknown can't be
+			// authorized
+			expected: []string{"alice"},
+		},
+		"authorizer_without_impersonation": {
+			authorizer: imageAuthorizer{image: "image"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			service := NewService(log.StandardLogger(), 16)
+			service.runtime = &fakeRuntime{}
+			if test.authorizer != nil {
+				service.SetAuthorizer(test.authorizer)
+			}
+			require.NoError(t, service.SetInstancesDir(t.TempDir()))
+
+			require.NoError(t, service.instanceStore.save(storedInstance{id: "alice", client: "alice", request: request}))
+			require.NoError(t, service.instanceStore.save(storedInstance{id: "bob", client: "bob", request: request}))
+			require.NoError(t, service.instanceStore.save(storedInstance{id: "unknown", request: request}))
+
+			require.NoError(t, service.Init())
+
+			var running []string
+			for _, gadget := range service.RunningGadgets("") {
+				running = append(running, gadget.ID)
+			}
+			require.ElementsMatch(t, test.expected, running)
+
+			// The instances that weren't restored are dropped
+			stored, err := service.instanceStore.load()
+			require.NoError(t, err)
+			require.Len(t, stored, len(test.expected))
+
+			for _, id := range running {
+				require.NoError(t, service.StopRunningGadget(id, ""))
+			}
+		})
+	}
+}
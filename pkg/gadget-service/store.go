@@ -0,0 +1,147 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+const instanceFileSuffix = ".json"
+
+// instanceStore persists the requests of detached gadget instances, one JSON
+// file per instance named after its ID, so that they can be restored when the
+// service restarts
+type instanceStore struct {
+	dir string
+}
+
+func newInstanceStore(dir string) (*instanceStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating directory %q: %w", dir, err)
+	}
+	return &instanceStore{dir: dir}, nil
+}
+
+func (st *instanceStore) path(id string) string {
+	return filepath.Join(st.dir, id+instanceFileSuffix)
+}
+
+// storedInstance is a detached instance as kept in the store
+type storedInstance struct {
+	id string
+	// client is the identifier of the client that created the instance, see
+	// ClientIdentifier
+	client  string
+	request *api.GadgetRunRequest
+}
+
+// instanceFile is the content of the file of an instance
+type instanceFile struct {
+	Client  string          `json:"client,omitempty"`
+	Request json.RawMessage `json:"request"`
+}
+
+// save stores an instance. The file is replaced atomically so that a crash
+// can't leave a truncated file behind.
+func (st *instanceStore) save(instance storedInstance) error {
+	id := instance.id
+	request, err := protojson.Marshal(instance.request)
+	if err != nil {
+		return fmt.Errorf("marshaling request: %w", err)
+	}
+	data, err := json.MarshalIndent(instanceFile{Client: instance.client, Request: request}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling instance: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(st.dir, "."+id+"-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing %q: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing %q: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing %q: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), st.path(id)); err != nil {
+		return fmt.Errorf("renaming %q: %w", tmp.Name(), err)
+	}
+	return nil
+}
+
+func (st *instanceStore) remove(id string) error {
+	if err := os.Remove(st.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing instance %q: %w", id, err)
+	}
+	return nil
+}
+
+// load returns the stored instances sorted by ID. Files that can't be read are
+// reported in the returned error, the other instances are returned anyway.
+func (st *instanceStore) load() ([]storedInstance, error) {
+	entries, err := os.ReadDir(st.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory %q: %w", st.dir, err)
+	}
+
+	var instances []storedInstance
+	var errs []error
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), instanceFileSuffix)
+		if !ok || entry.IsDir() || strings.HasPrefix(id, ".") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(st.dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading instance %q: %w", id, err))
+			continue
+		}
+		var file instanceFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			errs = append(errs, fmt.Errorf("unmarshaling instance %q: %w", id, err))
+			continue
+		}
+		request := &api.GadgetRunRequest{}
+		if err := protojson.Unmarshal(file.Request, request); err != nil {
+			errs = append(errs, fmt.Errorf("unmarshaling request of instance %q: %w", id, err))
+			continue
+		}
+		instances = append(instances, storedInstance{id: id, client: file.Client, request: request})
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].id < instances[j].id
+	})
+	return instances, errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetservice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestInstanceStore(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "instances")
+	store, err := newInstanceStore(dir)
+	require.NoError(t, err)
+
+	request1 := &api.GadgetRunRequest{
+		GadgetName: "run",
+		Args:       []string{"ghcr.io/inspektor-gadget/gadget/trace_exec:latest"},
+		Params:     map[string]string{"operator.LocalManager.containername": "foo"},
+		Timeout:    1000,
+		Detach:     true,
+	}
+	request2 := &api.GadgetRunRequest{
+		GadgetCategory: "audit",
+		GadgetName:     "seccomp",
+		Detach:         true,
+	}
+
+	require.NoError(t, store.save(storedInstance{id: "id1", client: "alice", request: request1}))
+	require.NoError(t, store.save(storedInstance{id: "id2", request: request2}))
+
+	instances, err := store.load()
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	require.Equal(t, "id1", instances[0].id)
+	require.Equal(t, "alice", instances[0].client)
+	require.True(t, proto.Equal(request1, instances[0].request))
+	require.Equal(t, "id2", instances[1].id)
+	require.Empty(t, instances[1].client)
+	require.True(t, proto.Equal(request2, instances[1].request))
+
+	// Removed instances aren't restored
+	require.NoError(t, store.remove("id1"))
+	require.NoError(t, store.remove("id1"))
+
+	// Broken files are reported without preventing other instances from being
+	// restored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600))
+
+	instances, err = store.load()
+	require.ErrorContains(t, err, `unmarshaling instance "broken"`)
+	require.Len(t, instances, 1)
+	require.Equal(t, "id2", instances[0].id)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
@@ -0,0 +1,371 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// clockSkew is the tolerance used when checking the validity period of
+	// tokens
+	clockSkew = time.Minute
+
+	// keysRefreshInterval is the minimum time between two fetches of the keys
+	// of the issuer
+	keysRefreshInterval = time.Minute
+)
+
+// OIDCConfig configures the verification of OpenID Connect ID tokens
+type OIDCConfig struct {
+	// IssuerURL is the URL of the issuer, its configuration is discovered
+	// from IssuerURL/.well-known/openid-configuration
+	IssuerURL string `yaml:"issuerURL"`
+	// ClientID must be in the audience of the tokens
+	ClientID string `yaml:"clientID"`
+	// UsernameClaim is the claim used as user name, "sub" by default
+	UsernameClaim string `yaml:"usernameClaim,omitempty"`
+	// GroupsClaim is the claim used as groups of the user, if any
+	GroupsClaim string `yaml:"groupsClaim,omitempty"`
+}
+
+// oidcVerifier verifies ID tokens signed by an OpenID Connect issuer
+type oidcVerifier struct {
+	config OIDCConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	lastFetched time.Time
+}
+
+func newOIDCVerifier(config OIDCConfig) (*oidcVerifier, error) {
+	if config.IssuerURL == "" {
+		return nil, errors.New("issuerURL is required")
+	}
+	if config.ClientID == "" {
+		return nil, errors.New("clientID is required")
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "sub"
+	}
+	return &oidcVerifier{
+		config: config,
+		client: &http.Client{Timeout: 10 * time.Second},
+		now:    time.Now,
+	}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks the signature and the claims of token and returns the
+// identity it belongs to
+func (v *oidcVerifier) verify(ctx context.Context, token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("decoding header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("decoding signature: %w", err)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("decoding claims: %w", err)
+	}
+	return v.checkClaims(claims)
+}
+
+func (v *oidcVerifier) checkClaims(claims map[string]any) (*Identity, error) {
+	if iss, _ := claims["iss"].(string); iss != v.config.IssuerURL {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+
+	audienceOK := false
+	switch aud := claims["aud"].(type) {
+	case string:
+		audienceOK = aud == v.config.ClientID
+	case []any:
+		for _, a := range aud {
+			if a == v.config.ClientID {
+				audienceOK = true
+			}
+		}
+	}
+	if !audienceOK {
+		return nil, errors.New("client ID not in the audience of the token")
+	}
+
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("token without expiration time")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(clockSkew)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+
+	username, _ := claims[v.config.UsernameClaim].(string)
+	if username == "" {
+		return nil, fmt.Errorf("claim %q not found in token", v.config.UsernameClaim)
+	}
+	identity := &Identity{User: username}
+
+	if v.config.GroupsClaim != "" {
+		switch groups := claims[v.config.GroupsClaim].(type) {
+		case string:
+			identity.Groups = []string{groups}
+		case []any:
+			for _, group := range groups {
+				if g, ok := group.(string); ok {
+					identity.Groups = append(identity.Groups, g)
+				}
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+// key returns the key with the given ID, fetching the keys of the issuer
+// again if it's unknown
+func (v *oidcVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.lookupKey(kid); ok {
+		return key, nil
+	}
+	if v.keys != nil && v.now().Sub(v.lastFetched) < keysRefreshInterval {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching keys of issuer: %w", err)
+	}
+	v.keys = keys
+	v.lastFetched = v.now()
+
+	if key, ok := v.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// lookupKey returns the key with the given ID. If the token doesn't specify
+// a key, the only key of the issuer is used. It must be called with v.mu held.
+func (v *oidcVerifier) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("getting %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *oidcVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	discoveryURL := strings.TrimSuffix(v.config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := v.getJSON(ctx, discoveryURL, &discovery); err != nil {
+		return nil, fmt.Errorf("discovering configuration: %w", err)
+	}
+	if discovery.Issuer != v.config.IssuerURL {
+		return nil, fmt.Errorf("issuer %q doesn't match configured one", discovery.Issuer)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("getting keys: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			// Ignore keys we don't support
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	return keys, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func parseJWK(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %q can't be used with RSA keys", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, hash, digest, signature); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("algorithm %q can't be used with EC keys", alg)
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return errors.New("unsupported key")
+}
+
+func decodeSegment(segment string, out any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
@@ -0,0 +1,216 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenauth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// newIssuer starts an OpenID Connect issuer serving the given keys
+func newIssuer(t *testing.T, keys []jsonWebKey) string {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+
+	return server.URL
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+
+	return signed + "." + b64(signature)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": kid})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signed + "." + b64(signature)
+}
+
+func TestOIDC(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := newIssuer(t, []jsonWebKey{
+		{
+			Kty: "RSA",
+			Kid: "rsa",
+			N:   b64(rsaKey.N.Bytes()),
+			E:   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		{
+			Kty: "EC",
+			Kid: "ec",
+			Crv: "P-256",
+			X:   b64(ecKey.X.FillBytes(make([]byte, 32))),
+			Y:   b64(ecKey.Y.FillBytes(make([]byte, 32))),
+		},
+	})
+
+	a, err := NewAuthorizer(&Policy{
+		OIDC: &OIDCConfig{
+			IssuerURL:     issuer,
+			ClientID:      "ig",
+			UsernameClaim: "email",
+			GroupsClaim:   "groups",
+		},
+	})
+	require.NoError(t, err)
+
+	validClaims := func() map[string]any {
+		return map[string]any{
+			"iss":    issuer,
+			"aud":    []string{"other", "ig"},
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"email":  "alice@example.com",
+			"groups": []string{"team-a"},
+		}
+	}
+
+	type testDefinition struct {
+		token  func() string
+		errMsg string
+	}
+
+	tests := map[string]testDefinition{
+		"rs256": {
+			token: func() string { return signRS256(t, rsaKey, "rsa", validClaims()) },
+		},
+		"es256": {
+			token: func() string { return signES256(t, ecKey, "ec", validClaims()) },
+		},
+		"wrong_key": {
+			token:  func() string { return signRS256(t, otherKey, "rsa", validClaims()) },
+			errMsg: "invalid signature",
+		},
+		"unknown_key": {
+			token:  func() string { return signRS256(t, rsaKey, "foo", validClaims()) },
+			errMsg: "unknown key",
+		},
+		"wrong_algorithm": {
+			token:  func() string { return signRS256(t, rsaKey, "ec", validClaims()) },
+			errMsg: "can't be used with EC keys",
+		},
+		"expired": {
+			token: func() string {
+				claims := validClaims()
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return signRS256(t, rsaKey, "rsa", claims)
+			},
+			errMsg: "token expired",
+		},
+		"wrong_issuer": {
+			token: func() string {
+				claims := validClaims()
+				claims["iss"] = "https://example.com"
+				return signRS256(t, rsaKey, "rsa", claims)
+			},
+			errMsg: "unexpected issuer",
+		},
+		"wrong_audience": {
+			token: func() string {
+				claims := validClaims()
+				claims["aud"] = "other"
+				return signRS256(t, rsaKey, "rsa", claims)
+			},
+			errMsg: "not in the audience",
+		},
+		"missing_username": {
+			token: func() string {
+				claims := validClaims()
+				delete(claims, "email")
+				return signRS256(t, rsaKey, "rsa", claims)
+			},
+			errMsg: `claim "email" not found`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			identity, err := a.authenticate(contextWithToken(test.token()))
+			if test.errMsg != "" {
+				require.Equal(t, codes.Unauthenticated, status.Code(err))
+				require.ErrorContains(t, err, test.errMsg)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, &Identity{User: "alice@example.com", Groups: []string{"team-a"}}, identity)
+		})
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tokenauth authenticates the clients of the daemon using static
+// tokens or OpenID Connect ID tokens, and authorizes the gadgets they run
+// according to a policy file, e.g.:
+//
+//	tokens:
+//	  - user: alice
+//	    groups: ["team-a"]
+//	    tokenFile: /etc/ig/tokens/alice
+//	oidc:
+//	  issuerURL: https://accounts.example.com
+//	  clientID: ig
+//	  usernameClaim: email
+//	  groupsClaim: groups
+//	rules:
+//	  - subjects: ["group:team-a"]
+//	    gadgets: ["trace/*", "run"]
+//	    images: ["ghcr.io/inspektor-gadget/gadget/*"]
+//	    params:
+//	      operator.LocalManager.containername: "team-a-*"
+package tokenauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/distribution/reference"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+
+	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+)
+
+// groupPrefix is used in the subjects of rules to refer to groups instead of
+// users
+const groupPrefix = "group:"
+
+// Identity is an authenticated user
+type Identity struct {
+	User   string
+	Groups []string
+}
+
+// StaticToken assigns an identity to a token
+type StaticToken struct {
+	User   string   `yaml:"user"`
+	Groups []string `yaml:"groups,omitempty"`
+	// Token is the token itself, TokenFile can be used instead to avoid
+	// storing it in the policy file
+	Token     string `yaml:"token,omitempty"`
+	TokenFile string `yaml:"tokenFile,omitempty"`
+}
+
+// Rule allows some subjects to run some gadgets
+type Rule struct {
+	// Subjects are the users the rule applies to, groups are written as
+	// "group:<name>" and "*" matches all authenticated users
+	Subjects []string `yaml:"subjects"`
+	// Gadgets are shell patterns matching "<category>/<name>" of the allowed
+	// gadgets, or just "<name>" for gadgets without category like "run". All
+	// gadgets are allowed if empty.
+	Gadgets []string `yaml:"gadgets,omitempty"`
+	// Images are shell patterns matching the repository of the images that
+	// can be run with the run gadget. All images are allowed if empty.
+	Images []string `yaml:"images,omitempty"`
+	// Params maps parameters, like "operator.LocalManager.containername", to
+	// shell patterns their values have to match. They can be used to force
+	// filters on the gadgets.
+	Params map[string]string `yaml:"params,omitempty"`
+}
+
+// Policy configures how clients are authenticated and what they can run
+type Policy struct {
+	Tokens []StaticToken `yaml:"tokens,omitempty"`
+	OIDC   *OIDCConfig   `yaml:"oidc,omitempty"`
+	Rules  []Rule        `yaml:"rules"`
+}
+
+// LoadPolicy reads a policy from a YAML file
+func LoadPolicy(filename string) (*Policy, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy file: %w", err)
+	}
+
+	policy := &Policy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("unmarshaling policy file: %w", err)
+	}
+	return policy, nil
+}
+
+// Authorizer authenticates clients and checks the gadgets they run against a
+// policy
+type Authorizer struct {
+	tokens map[string]*Identity
+	oidc   *oidcVerifier
+	rules  []Rule
+}
+
+// NewAuthorizer creates an authorizer enforcing the given policy
+func NewAuthorizer(policy *Policy) (*Authorizer, error) {
+	a := &Authorizer{
+		tokens: map[string]*Identity{},
+		rules:  policy.Rules,
+	}
+
+	for _, t := range policy.Tokens {
+		if t.User == "" {
+			return nil, errors.New("static token without user")
+		}
+		token := t.Token
+		if t.TokenFile != "" {
+			data, err := os.ReadFile(t.TokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading token of user %q: %w", t.User, err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if token == "" {
+			return nil, fmt.Errorf("empty token for user %q", t.User)
+		}
+		if _, ok := a.tokens[token]; ok {
+			return nil, fmt.Errorf("token of user %q is used more than once", t.User)
+		}
+		a.tokens[token] = &Identity{User: t.User, Groups: t.Groups}
+	}
+
+	if policy.OIDC != nil {
+		verifier, err := newOIDCVerifier(*policy.OIDC)
+		if err != nil {
+			return nil, fmt.Errorf("configuring OIDC: %w", err)
+		}
+		a.oidc = verifier
+	}
+
+	if len(a.tokens) == 0 && a.oidc == nil {
+		return nil, errors.New("no static tokens nor OIDC configured")
+	}
+
+	for i, rule := range policy.Rules {
+		if len(rule.Subjects) == 0 {
+			return nil, fmt.Errorf("rule %d: no subjects", i)
+		}
+		patterns := append(append([]string{}, rule.Gadgets...), rule.Images...)
+		for _, pattern := range rule.Params {
+			patterns = append(patterns, pattern)
+		}
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
+
+	return a, nil
+}
+
+// Authenticate returns an error if the client that sent the request in ctx
+// can't be authenticated
+func (a *Authorizer) Authenticate(ctx context.Context) error {
+	_, err := a.authenticate(ctx)
+	return err
+}
+
+// ClientID returns the name of the user that sent the request in ctx, so that
+// quotas apply to each user separately
+func (a *Authorizer) ClientID(ctx context.Context) (string, error) {
+	identity, err := a.authenticate(ctx)
+	if err != nil {
+		return "", err
+	}
+	return identity.User, nil
+}
+
+type clientKey struct{}
+
+// WithClient returns a context whose requests are authorized as the given
+// user, e.g. to authorize again the gadget instances restored by the daemon.
+// The user gets the groups of its static tokens; the groups of OIDC users
+// aren't known without their token.
+func (a *Authorizer) WithClient(ctx context.Context, client string) context.Context {
+	identity := &Identity{User: client}
+	for _, t := range a.tokens {
+		if t.User == client {
+			identity.Groups = append(identity.Groups, t.Groups...)
+		}
+	}
+	return context.WithValue(ctx, clientKey{}, identity)
+}
+
+// authenticate returns the identity of the client that sent the request in
+// ctx
+func (a *Authorizer) authenticate(ctx context.Context) (*Identity, error) {
+	if identity, ok := ctx.Value(clientKey{}).(*Identity); ok {
+		return identity, nil
+	}
+
+	token, err := api.TokenFromContext(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	for t, identity := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return identity, nil
+		}
+	}
+
+	// Static tokens aren't JWTs, don't bother verifying them
+	if a.oidc != nil && strings.Count(token, ".") == 2 {
+		identity, err := a.oidc.verify(ctx, token)
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, "invalid credentials: %s", err)
+		}
+		return identity, nil
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+}
+
+// Authorize returns an error if the client that sent the request in ctx isn't
+// allowed to run the gadget described by gadgetCtx
+func (a *Authorizer) Authorize(ctx context.Context, gadgetCtx *gadgetcontext.GadgetContext) error {
+	identity, err := a.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+
+	paramValues := map[string]string{}
+	gadgets.ParamsToMap(paramValues, gadgetCtx.GadgetParams(), gadgetCtx.RuntimeParams(), gadgetCtx.OperatorsParamCollection())
+
+	desc := gadgetCtx.GadgetDesc()
+	req := &request{
+		gadget: gadgetName(desc.Category(), desc.Name()),
+		params: paramValues,
+	}
+	if desc.Category() == gadgets.CategoryNone && desc.Name() == "run" && len(gadgetCtx.Args()) > 0 {
+		named, err := reference.ParseNormalizedNamed(gadgetCtx.Args()[0])
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "parsing image %q: %s", gadgetCtx.Args()[0], err)
+		}
+		req.image = named.Name()
+	}
+
+	return a.authorize(identity, req)
+}
+
+// request describes what a client wants to run
+type request struct {
+	gadget string
+	// image is the repository of the image for the run gadget
+	image  string
+	params map[string]string
+}
+
+func gadgetName(category, name string) string {
+	if category == gadgets.CategoryNone {
+		return name
+	}
+	return category + "/" + name
+}
+
+func (a *Authorizer) authorize(identity *Identity, req *request) error {
+	for _, rule := range a.rules {
+		if ruleApplies(rule, identity) && ruleAllows(rule, req) {
+			return nil
+		}
+	}
+
+	msg := fmt.Sprintf("user %q is not allowed to run gadget %q", identity.User, req.gadget)
+	if req.image != "" {
+		msg += fmt.Sprintf(" with image %q", req.image)
+	}
+	return status.Error(codes.PermissionDenied, msg+" with the given parameters")
+}
+
+func ruleApplies(rule Rule, identity *Identity) bool {
+	for _, subject := range rule.Subjects {
+		if subject == "*" || subject == identity.User {
+			return true
+		}
+		if group, ok := strings.CutPrefix(subject, groupPrefix); ok {
+			for _, g := range identity.Groups {
+				if g == group {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, s); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleAllows(rule Rule, req *request) bool {
+	if !matchAny(rule.Gadgets, req.gadget) {
+		return false
+	}
+	if req.image != "" && !matchAny(rule.Images, req.image) {
+		return false
+	}
+	for key, pattern := range rule.Params {
+		// Parameters the gadget doesn't have can't be used to filter it
+		value, ok := req.params[key]
+		if !ok {
+			return false
+		}
+		if matched, _ := path.Match(pattern, value); !matched {
+			return false
+		}
+	}
+	return true
+}
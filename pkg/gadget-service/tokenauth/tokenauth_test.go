@@ -0,0 +1,279 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tokenauth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func contextWithToken(token string) context.Context {
+	md := metadata.Pairs(api.AuthorizationMetadataKey, "Bearer "+token)
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestLoadPolicy(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policy.yaml")
+	tokenFile := filepath.Join(dir, "bob")
+
+	require.NoError(t, os.WriteFile(tokenFile, []byte("bob-token\n"), 0o600))
+	require.NoError(t, os.WriteFile(policyFile, []byte(`
+tokens:
+  - user: alice
+    groups: ["team-a"]
+    token: alice-token
+  - user: bob
+    tokenFile: `+tokenFile+`
+rules:
+  - subjects: ["group:team-a"]
+    gadgets: ["trace/*"]
+    params:
+      operator.LocalManager.containername: "team-a-*"
+`), 0o600))
+
+	policy, err := LoadPolicy(policyFile)
+	require.NoError(t, err)
+	require.Len(t, policy.Tokens, 2)
+	require.Len(t, policy.Rules, 1)
+	require.Equal(t, "team-a-*", policy.Rules[0].Params["operator.LocalManager.containername"])
+
+	a, err := NewAuthorizer(policy)
+	require.NoError(t, err)
+
+	identity, err := a.authenticate(contextWithToken("alice-token"))
+	require.NoError(t, err)
+	require.Equal(t, &Identity{User: "alice", Groups: []string{"team-a"}}, identity)
+
+	identity, err = a.authenticate(contextWithToken("bob-token"))
+	require.NoError(t, err)
+	require.Equal(t, "bob", identity.User)
+
+	_, err = a.authenticate(contextWithToken("foo"))
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = a.authenticate(context.Background())
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestNewAuthorizerErrors(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		policy *Policy
+		errMsg string
+	}
+
+	tests := map[string]testDefinition{
+		"no_authentication": {
+			policy: &Policy{},
+			errMsg: "no static tokens nor OIDC configured",
+		},
+		"token_without_user": {
+			policy: &Policy{Tokens: []StaticToken{{Token: "foo"}}},
+			errMsg: "static token without user",
+		},
+		"empty_token": {
+			policy: &Policy{Tokens: []StaticToken{{User: "alice"}}},
+			errMsg: "empty token",
+		},
+		"duplicated_token": {
+			policy: &Policy{Tokens: []StaticToken{{User: "alice", Token: "foo"}, {User: "bob", Token: "foo"}}},
+			errMsg: "used more than once",
+		},
+		"rule_without_subjects": {
+			policy: &Policy{
+				Tokens: []StaticToken{{User: "alice", Token: "foo"}},
+				Rules:  []Rule{{Gadgets: []string{"*"}}},
+			},
+			errMsg: "no subjects",
+		},
+		"invalid_pattern": {
+			policy: &Policy{
+				Tokens: []StaticToken{{User: "alice", Token: "foo"}},
+				Rules:  []Rule{{Subjects: []string{"alice"}, Gadgets: []string{"["}}},
+			},
+			errMsg: "invalid pattern",
+		},
+		"oidc_without_client_id": {
+			policy: &Policy{OIDC: &OIDCConfig{IssuerURL: "https://example.com"}},
+			errMsg: "clientID is required",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewAuthorizer(test.policy)
+			require.ErrorContains(t, err, test.errMsg)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	t.Parallel()
+
+	a, err := NewAuthorizer(&Policy{
+		Tokens: []StaticToken{{User: "alice", Token: "foo"}},
+		Rules: []Rule{
+			{
+				Subjects: []string{"group:team-a"},
+				Gadgets:  []string{"trace/*"},
+				Params: map[string]string{
+					"operator.LocalManager.containername": "team-a-*",
+				},
+			},
+			{
+				Subjects: []string{"group:team-a"},
+				Gadgets:  []string{"run"},
+				Images:   []string{"ghcr.io/inspektor-gadget/gadget/*"},
+			},
+			{
+				Subjects: []string{"admin"},
+			},
+			{
+				Subjects: []string{"*"},
+				Gadgets:  []string{"snapshot/process"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	teamA := &Identity{User: "alice", Groups: []string{"team-a"}}
+
+	type testDefinition struct {
+		identity *Identity
+		request  *request
+		allowed  bool
+	}
+
+	tests := map[string]testDefinition{
+		"filtered_gadget": {
+			identity: teamA,
+			request: &request{
+				gadget: "trace/exec",
+				params: map[string]string{"operator.LocalManager.containername": "team-a-web"},
+			},
+			allowed: true,
+		},
+		"wrong_filter": {
+			identity: teamA,
+			request: &request{
+				gadget: "trace/exec",
+				params: map[string]string{"operator.LocalManager.containername": "team-b-web"},
+			},
+		},
+		"missing_filter": {
+			identity: teamA,
+			request: &request{
+				gadget: "trace/exec",
+				params: map[string]string{"operator.LocalManager.containername": ""},
+			},
+		},
+		"gadget_without_filter_param": {
+			identity: teamA,
+			request:  &request{gadget: "trace/exec"},
+		},
+		"not_allowed_gadget": {
+			identity: teamA,
+			request: &request{
+				gadget: "top/file",
+				params: map[string]string{"operator.LocalManager.containername": "team-a-web"},
+			},
+		},
+		"allowed_image": {
+			identity: teamA,
+			request: &request{
+				gadget: "run",
+				image:  "ghcr.io/inspektor-gadget/gadget/trace_open",
+			},
+			allowed: true,
+		},
+		"not_allowed_image": {
+			identity: teamA,
+			request: &request{
+				gadget: "run",
+				image:  "docker.io/library/foo",
+			},
+		},
+		"other_user": {
+			identity: &Identity{User: "bob"},
+			request: &request{
+				gadget: "trace/exec",
+				params: map[string]string{"operator.LocalManager.containername": "team-a-web"},
+			},
+		},
+		"unrestricted_user": {
+			identity: &Identity{User: "admin"},
+			request: &request{
+				gadget: "run",
+				image:  "docker.io/library/foo",
+			},
+			allowed: true,
+		},
+		"any_user": {
+			identity: &Identity{User: "bob"},
+			request:  &request{gadget: "snapshot/process"},
+			allowed:  true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := a.authorize(test.identity, test.request)
+			if test.allowed {
+				require.NoError(t, err)
+				return
+			}
+			require.Equal(t, codes.PermissionDenied, status.Code(err))
+		})
+	}
+}
+
+func TestWithClient(t *testing.T) {
+	t.Parallel()
+
+	a, err := NewAuthorizer(&Policy{
+		Tokens: []StaticToken{{User: "alice", Groups: []string{"team-a"}, Token: "foo"}},
+		Rules:  []Rule{{Subjects: []string{"group:team-a"}}},
+	})
+	require.NoError(t, err)
+
+	// Users get the groups of their static tokens
+	identity, err := a.authenticate(a.WithClient(context.Background(), "alice"))
+	require.NoError(t, err)
+	require.Equal(t, &Identity{User: "alice", Groups: []string{"team-a"}}, identity)
+	require.NoError(t, a.authorize(identity, &request{gadget: "trace/exec"}))
+
+	identity, err = a.authenticate(a.WithClient(context.Background(), "bob"))
+	require.NoError(t, err)
+	require.Equal(t, codes.PermissionDenied, status.Code(a.authorize(identity, &request{gadget: "trace/exec"})))
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetpolicy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrAttachDenied is returned when a gadget is attached to a target denied by
+// the policy
+var ErrAttachDenied = errors.New("attach denied by policy")
+
+// AttachScope is a kind of target gadgets can be attached to
+type AttachScope string
+
+const (
+	// AttachScopeHostUprobes are uprobes on the binaries and libraries of the
+	// host
+	AttachScopeHostUprobes AttachScope = "hostUprobes"
+	// AttachScopeHostPid1 is the init process of the host, which represents
+	// all the host processes, e.g. when running with --host
+	AttachScopeHostPid1 AttachScope = "hostPid1"
+)
+
+// AttachTarget is where a gadget is about to be attached
+type AttachTarget struct {
+	// Host is whether the target is the host rather than a container
+	Host bool
+	// Pid is the process representing the target
+	Pid uint32
+	// Uprobe is whether uprobes are attached to the binaries of the target
+	Uprobe bool
+}
+
+func parseAttachScopes(scopes []AttachScope) error {
+	for _, scope := range scopes {
+		switch scope {
+		case AttachScopeHostUprobes, AttachScopeHostPid1:
+		default:
+			return fmt.Errorf("invalid attach scope %q, expected %s or %s",
+				scope, AttachScopeHostUprobes, AttachScopeHostPid1)
+		}
+	}
+	return nil
+}
+
+// CheckAttach returns an error wrapping ErrAttachDenied if the policy denies
+// attaching gadgets to target
+func (p *Policy) CheckAttach(target AttachTarget) error {
+	if !target.Host {
+		return nil
+	}
+	if target.Pid == 1 && slices.Contains(p.DenyAttachScopes, AttachScopeHostPid1) {
+		return fmt.Errorf("%w: tracing the host (PID 1) isn't allowed", ErrAttachDenied)
+	}
+	if target.Uprobe && slices.Contains(p.DenyAttachScopes, AttachScopeHostUprobes) {
+		return fmt.Errorf("%w: uprobes on host binaries aren't allowed", ErrAttachDenied)
+	}
+	return nil
+}
+
+// CheckAttach checks attaching the gadget to target against the policy set
+// with SetPolicy. Denials are logged for auditing.
+func CheckAttach(g *Gadget, target AttachTarget) error {
+	p := currentPolicy.Load()
+	if p == nil {
+		return nil
+	}
+	err := p.CheckAttach(target)
+	if err != nil {
+		log.Warnf("Audit: denied attaching gadget %s@%s to pid %d: %v", g.Image, g.Digest, target.Pid, err)
+	}
+	return err
+}
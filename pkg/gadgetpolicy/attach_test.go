@@ -0,0 +1,81 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAttach(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		policy      string
+		target      AttachTarget
+		expectedErr string
+	}
+
+	tests := map[string]testDefinition{
+		"no_scopes": {
+			policy: "deny: []",
+			target: AttachTarget{Host: true, Pid: 1, Uprobe: true},
+		},
+		"container": {
+			policy: "denyAttachScopes: [hostPid1, hostUprobes]",
+			target: AttachTarget{Pid: 1234, Uprobe: true},
+		},
+		"host_pid1": {
+			policy:      "denyAttachScopes: [hostPid1]",
+			target:      AttachTarget{Host: true, Pid: 1},
+			expectedErr: "tracing the host (PID 1) isn't allowed",
+		},
+		"host_uprobes": {
+			policy:      "denyAttachScopes: [hostUprobes]",
+			target:      AttachTarget{Host: true, Pid: 1, Uprobe: true},
+			expectedErr: "uprobes on host binaries aren't allowed",
+		},
+		"host_without_uprobes": {
+			policy: "denyAttachScopes: [hostUprobes]",
+			target: AttachTarget{Host: true, Pid: 1},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			policy, err := ParsePolicy([]byte(test.policy))
+			require.NoError(t, err)
+
+			err = policy.CheckAttach(test.target)
+			if test.expectedErr != "" {
+				require.ErrorIs(t, err, ErrAttachDenied)
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestParseAttachScopes(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParsePolicy([]byte("denyAttachScopes: [hostNetwork]"))
+	require.ErrorContains(t, err, `invalid attach scope "hostNetwork"`)
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gadgetpolicy decides whether a gadget image can run depending on
+// where it comes from and what its eBPF programs do, e.g. to forbid XDP
+// programs or uprobes on host binaries. Denials are logged for auditing.
+package gadgetpolicy
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"github.com/distribution/reference"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrGadgetDenied is returned when running a gadget denied by the policy
+var ErrGadgetDenied = errors.New("gadget denied by policy")
+
+// Rule denies the gadgets matching all its non-empty fields. Patterns can use
+// * to match any sequence of characters.
+type Rule struct {
+	Name string `yaml:"name"`
+	// Registries of the image, e.g. docker.io or *.example.com
+	Registries []string `yaml:"registries,omitempty"`
+	// Digests of the image, e.g. sha256:...
+	Digests []string `yaml:"digests,omitempty"`
+	// ProgramTypes used by the gadget. They match the eBPF program type (e.g.
+	// xdp, kprobe, tracepoint) or the kind of section (e.g. uprobe, fentry).
+	ProgramTypes []string `yaml:"programTypes,omitempty"`
+	// AttachTargets of the programs of the types above, e.g. /* for uprobes on
+	// host paths or security_* for kprobes
+	AttachTargets []string `yaml:"attachTargets,omitempty"`
+
+	registries    []*regexp.Regexp
+	attachTargets []*regexp.Regexp
+}
+
+// Policy decides which gadgets can run
+type Policy struct {
+	// AllowedRegistries are the only registries gadget images can come from.
+	// All of them are allowed if it's empty.
+	AllowedRegistries []string `yaml:"allowedRegistries,omitempty"`
+	// Deny are the rules denying gadgets
+	Deny []Rule `yaml:"deny,omitempty"`
+	// Limits are the resources gadgets can use
+	Limits *Limits `yaml:"limits,omitempty"`
+	// DenyAttachScopes are the kinds of targets gadgets can't be attached to,
+	// e.g. hostUprobes
+	DenyAttachScopes []AttachScope `yaml:"denyAttachScopes,omitempty"`
+
+	allowedRegistries []*regexp.Regexp
+}
+
+// Program is an eBPF program of a gadget
+type Program struct {
+	Name string
+	// Type is the eBPF program type, e.g. xdp or kprobe
+	Type string
+	// Section is the kind of section of the program, e.g. uprobe for
+	// uprobe/libc:malloc
+	Section string
+	// AttachTo is the target of the program, e.g. libc:malloc
+	AttachTo string
+	// Instructions is the number of instructions of the program
+	Instructions int
+}
+
+// Gadget describes a gadget image about to run
+type Gadget struct {
+	Image    string
+	Digest   string
+	Programs []Program
+	Maps     []Map
+}
+
+// compilePattern returns a regular expression matching the whole string
+// against pattern, where * matches any sequence of characters
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+	expr := strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	return regexp.Compile("^" + expr + "$")
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var regexps []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+func matchAny(regexps []*regexp.Regexp, s string) bool {
+	for _, re := range regexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePolicy parses a policy in YAML format
+func ParsePolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("unmarshaling policy: %w", err)
+	}
+
+	var err error
+	policy.allowedRegistries, err = compilePatterns(policy.AllowedRegistries)
+	if err != nil {
+		return nil, fmt.Errorf("allowed registries: %w", err)
+	}
+
+	for i := range policy.Deny {
+		rule := &policy.Deny[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("deny[%d]", i)
+		}
+		if len(rule.Registries) == 0 && len(rule.Digests) == 0 &&
+			len(rule.ProgramTypes) == 0 && len(rule.AttachTargets) == 0 {
+			return nil, fmt.Errorf("rule %q doesn't match anything", rule.Name)
+		}
+		rule.registries, err = compilePatterns(rule.Registries)
+		if err != nil {
+			return nil, fmt.Errorf("registries of rule %q: %w", rule.Name, err)
+		}
+		rule.attachTargets, err = compilePatterns(rule.AttachTargets)
+		if err != nil {
+			return nil, fmt.Errorf("attach targets of rule %q: %w", rule.Name, err)
+		}
+	}
+
+	if policy.Limits != nil {
+		if err := policy.Limits.parse(); err != nil {
+			return nil, fmt.Errorf("limits: %w", err)
+		}
+	}
+
+	if err := parseAttachScopes(policy.DenyAttachScopes); err != nil {
+		return nil, fmt.Errorf("deny attach scopes: %w", err)
+	}
+
+	return policy, nil
+}
+
+// registry returns the registry of image, e.g. docker.io for busybox
+func registry(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("parsing image %q: %w", image, err)
+	}
+	return reference.Domain(named), nil
+}
+
+// matchProgram returns whether the program matches the program types and
+// attach targets of the rule
+func (r *Rule) matchProgram(p *Program) bool {
+	if len(r.ProgramTypes) > 0 && !containsFold(r.ProgramTypes, p.Type) &&
+		!containsFold(r.ProgramTypes, p.Section) {
+		return false
+	}
+	if len(r.attachTargets) > 0 && !matchAny(r.attachTargets, p.AttachTo) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if s != "" && strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns a description of why the rule matches the gadget, or false if
+// it doesn't
+func (r *Rule) match(g *Gadget, reg string) (string, bool) {
+	var reasons []string
+	if len(r.registries) > 0 {
+		if !matchAny(r.registries, reg) {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("registry %q", reg))
+	}
+	if len(r.Digests) > 0 {
+		if !containsFold(r.Digests, g.Digest) {
+			return "", false
+		}
+		reasons = append(reasons, fmt.Sprintf("digest %q", g.Digest))
+	}
+	if len(r.ProgramTypes) > 0 || len(r.attachTargets) > 0 {
+		found := false
+		for i := range g.Programs {
+			p := &g.Programs[i]
+			if r.matchProgram(p) {
+				reasons = append(reasons, fmt.Sprintf("program %q of type %q attached to %q", p.Name, p.Section, p.AttachTo))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", false
+		}
+	}
+	return strings.Join(reasons, ", "), true
+}
+
+// Check returns an error wrapping ErrGadgetDenied if the policy denies the
+// gadget
+func (p *Policy) Check(g *Gadget) error {
+	reg, err := registry(g.Image)
+	if err != nil {
+		return err
+	}
+
+	if len(p.allowedRegistries) > 0 && !matchAny(p.allowedRegistries, reg) {
+		return fmt.Errorf("%w: registry %q isn't allowed", ErrGadgetDenied, reg)
+	}
+
+	for i := range p.Deny {
+		rule := &p.Deny[i]
+		if reason, ok := rule.match(g, reg); ok {
+			return fmt.Errorf("%w: rule %q matches %s", ErrGadgetDenied, rule.Name, reason)
+		}
+	}
+	return nil
+}
+
+var currentPolicy atomic.Pointer[Policy]
+
+// SetPolicy sets the policy used by Check. Passing nil allows all gadgets.
+func SetPolicy(p *Policy) {
+	currentPolicy.Store(p)
+}
+
+// Check checks the gadget against the policy set with SetPolicy. Denials are
+// logged for auditing.
+func Check(g *Gadget) error {
+	p := currentPolicy.Load()
+	if p == nil {
+		return nil
+	}
+	err := p.Check(g)
+	if errors.Is(err, ErrGadgetDenied) {
+		log.Warnf("Audit: denied gadget %s@%s: %v", g.Image, g.Digest, err)
+	}
+	return err
+}
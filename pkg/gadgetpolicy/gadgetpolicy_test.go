@@ -0,0 +1,138 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `
+allowedRegistries:
+- ghcr.io
+- "*.example.com"
+deny:
+- name: no-xdp
+  programTypes: [xdp]
+- name: no-host-uprobes
+  programTypes: [uprobe, uretprobe]
+  attachTargets: ["/*"]
+- name: revoked
+  registries: [registry.example.com]
+  digests: [sha256:1111111111111111111111111111111111111111111111111111111111111111]
+`
+
+func TestParsePolicy(t *testing.T) {
+	t.Parallel()
+
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+	require.Len(t, policy.Deny, 3)
+
+	_, err = ParsePolicy([]byte("deny:\n- name: empty\n"))
+	require.ErrorContains(t, err, `rule "empty" doesn't match anything`)
+
+	_, err = ParsePolicy([]byte("allowedRegistries: [\"\"]\n"))
+	require.ErrorContains(t, err, "empty pattern")
+
+	_, err = ParsePolicy([]byte("deny: foo"))
+	require.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	policy, err := ParsePolicy([]byte(testPolicy))
+	require.NoError(t, err)
+
+	kprobe := Program{Name: "ig_open", Type: "kprobe", Section: "kprobe", AttachTo: "do_sys_openat2"}
+
+	type testDefinition struct {
+		gadget      *Gadget
+		expectedErr string
+	}
+
+	tests := map[string]testDefinition{
+		"allowed": {
+			gadget: &Gadget{
+				Image:    "ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+				Programs: []Program{kprobe},
+			},
+		},
+		"registry_not_allowed": {
+			gadget: &Gadget{
+				Image:    "docker.io/foo/bar:latest",
+				Programs: []Program{kprobe},
+			},
+			expectedErr: `registry "docker.io" isn't allowed`,
+		},
+		"short_name_not_allowed": {
+			gadget:      &Gadget{Image: "trace_open"},
+			expectedErr: `registry "docker.io" isn't allowed`,
+		},
+		"xdp": {
+			gadget: &Gadget{
+				Image: "ghcr.io/foo/xdp:latest",
+				Programs: []Program{
+					kprobe,
+					{Name: "ig_xdp", Type: "xdp", Section: "xdp"},
+				},
+			},
+			expectedErr: `rule "no-xdp" matches program "ig_xdp"`,
+		},
+		"container_uprobe": {
+			gadget: &Gadget{
+				Image:    "ghcr.io/foo/ssl:latest",
+				Programs: []Program{{Name: "ig_ssl", Type: "kprobe", Section: "uprobe", AttachTo: "libssl:SSL_write"}},
+			},
+		},
+		"host_uprobe": {
+			gadget: &Gadget{
+				Image:    "ghcr.io/foo/bash:latest",
+				Programs: []Program{{Name: "ig_bash", Type: "kprobe", Section: "uretprobe", AttachTo: "/bin/bash:readline"}},
+			},
+			expectedErr: `rule "no-host-uprobes"`,
+		},
+		"revoked_digest": {
+			gadget: &Gadget{
+				Image:  "registry.example.com/team/mygadget:v1",
+				Digest: "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+			},
+			expectedErr: `rule "revoked" matches registry "registry.example.com", digest`,
+		},
+		"other_digest": {
+			gadget: &Gadget{
+				Image:  "registry.example.com/team/mygadget:v2",
+				Digest: "sha256:2222222222222222222222222222222222222222222222222222222222222222",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := policy.Check(test.gadget)
+			if test.expectedErr != "" {
+				require.ErrorIs(t, err, ErrGadgetDenied)
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetpolicy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+	log "github.com/sirupsen/logrus"
+)
+
+// ErrLimitExceeded is returned when running a gadget exceeding the limits of
+// the policy
+var ErrLimitExceeded = errors.New("gadget exceeds the limits")
+
+// LimitsAction is what to do with the gadgets exceeding the limits
+type LimitsAction string
+
+const (
+	LimitsActionRefuse LimitsAction = "refuse"
+	LimitsActionWarn   LimitsAction = "warn"
+)
+
+// Limits protect the node from gadgets using too many resources. They're
+// checked with estimations done before loading the gadget, a zero value means
+// no limit.
+type Limits struct {
+	// MaxInstructions is the maximum number of instructions of a program
+	MaxInstructions int `yaml:"maxInstructions,omitempty"`
+	// MaxMapMemory is the maximum memory used by all the maps, e.g. 256MiB
+	MaxMapMemory string `yaml:"maxMapMemory,omitempty"`
+	// MaxLinks is the maximum number of programs to attach
+	MaxLinks int `yaml:"maxLinks,omitempty"`
+	// Action is what to do with the gadgets exceeding the limits: refuse
+	// (default) or warn
+	Action LimitsAction `yaml:"action,omitempty"`
+
+	maxMapMemory uint64
+}
+
+// Map is an eBPF map of a gadget
+type Map struct {
+	Name string
+	// Memory is the estimated memory used by the map, in bytes
+	Memory uint64
+}
+
+func (l *Limits) parse() error {
+	switch l.Action {
+	case "":
+		l.Action = LimitsActionRefuse
+	case LimitsActionRefuse, LimitsActionWarn:
+	default:
+		return fmt.Errorf("invalid action %q, expected %s or %s", l.Action, LimitsActionRefuse, LimitsActionWarn)
+	}
+	if l.MaxInstructions < 0 || l.MaxLinks < 0 {
+		return errors.New("limits can't be negative")
+	}
+	if l.MaxMapMemory != "" {
+		size, err := units.RAMInBytes(l.MaxMapMemory)
+		if err != nil {
+			return fmt.Errorf("parsing max map memory: %w", err)
+		}
+		if size < 0 {
+			return errors.New("limits can't be negative")
+		}
+		l.maxMapMemory = uint64(size)
+	}
+	return nil
+}
+
+// exceeded returns the limits exceeded by the gadget
+func (l *Limits) exceeded(g *Gadget) []string {
+	var problems []string
+
+	if l.MaxInstructions > 0 {
+		for _, p := range g.Programs {
+			if p.Instructions > l.MaxInstructions {
+				problems = append(problems, fmt.Sprintf("program %q has %d instructions, more than %d",
+					p.Name, p.Instructions, l.MaxInstructions))
+			}
+		}
+	}
+
+	if l.maxMapMemory > 0 {
+		var total uint64
+		for _, m := range g.Maps {
+			total += m.Memory
+		}
+		if total > l.maxMapMemory {
+			problems = append(problems, fmt.Sprintf("maps use %s, more than %s",
+				units.BytesSize(float64(total)), units.BytesSize(float64(l.maxMapMemory))))
+		}
+	}
+
+	if l.MaxLinks > 0 && len(g.Programs) > l.MaxLinks {
+		problems = append(problems, fmt.Sprintf("%d programs to attach, more than %d",
+			len(g.Programs), l.MaxLinks))
+	}
+
+	return problems
+}
+
+// CheckLimits returns an error wrapping ErrLimitExceeded if the gadget
+// exceeds the limits of the policy and its action is refuse. When it's warn,
+// the exceeded limits are returned instead.
+func (p *Policy) CheckLimits(g *Gadget) ([]string, error) {
+	if p.Limits == nil {
+		return nil, nil
+	}
+	problems := p.Limits.exceeded(g)
+	if len(problems) == 0 {
+		return nil, nil
+	}
+	if p.Limits.Action == LimitsActionWarn {
+		return problems, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLimitExceeded, strings.Join(problems, "; "))
+}
+
+// CheckLimits checks the gadget against the limits of the policy set with
+// SetPolicy. Refusals are logged for auditing.
+func CheckLimits(g *Gadget) ([]string, error) {
+	p := currentPolicy.Load()
+	if p == nil {
+		return nil, nil
+	}
+	warnings, err := p.CheckLimits(g)
+	if err != nil {
+		log.Warnf("Audit: refused gadget %s@%s: %v", g.Image, g.Digest, err)
+	}
+	return warnings, err
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgetpolicy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLimits(t *testing.T) {
+	t.Parallel()
+
+	small := &Gadget{
+		Image:    "ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+		Programs: []Program{{Name: "ig_open", Instructions: 500}},
+		Maps:     []Map{{Name: "events", Memory: 256 * 1024}},
+	}
+	big := &Gadget{
+		Image: "ghcr.io/foo/big:latest",
+		Programs: []Program{
+			{Name: "ig_a", Instructions: 200000},
+			{Name: "ig_b", Instructions: 100},
+			{Name: "ig_c", Instructions: 100},
+		},
+		Maps: []Map{
+			{Name: "huge", Memory: 1024 * 1024 * 1024},
+			{Name: "events", Memory: 256 * 1024},
+		},
+	}
+
+	type testDefinition struct {
+		policy           string
+		gadget           *Gadget
+		expectedWarnings []string
+		expectedErr      string
+	}
+
+	const limits = `
+limits:
+  maxInstructions: 100000
+  maxMapMemory: 256MiB
+  maxLinks: 2
+`
+
+	tests := map[string]testDefinition{
+		"no_limits": {
+			policy: "deny: []",
+			gadget: big,
+		},
+		"within_limits": {
+			policy: limits,
+			gadget: small,
+		},
+		"refuse": {
+			policy: limits,
+			gadget: big,
+			expectedErr: `program "ig_a" has 200000 instructions, more than 100000; ` +
+				"maps use 1GiB, more than 256MiB; 3 programs to attach, more than 2",
+		},
+		"warn": {
+			policy: limits + "  action: warn\n",
+			gadget: big,
+			expectedWarnings: []string{
+				`program "ig_a" has 200000 instructions, more than 100000`,
+				"maps use 1GiB, more than 256MiB",
+				"3 programs to attach, more than 2",
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			policy, err := ParsePolicy([]byte(test.policy))
+			require.NoError(t, err)
+
+			warnings, err := policy.CheckLimits(test.gadget)
+			if test.expectedErr != "" {
+				require.ErrorIs(t, err, ErrLimitExceeded)
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedWarnings, warnings)
+		})
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	t.Parallel()
+
+	_, err := ParsePolicy([]byte("limits:\n  action: ignore\n"))
+	require.ErrorContains(t, err, `invalid action "ignore"`)
+
+	_, err = ParsePolicy([]byte("limits:\n  maxMapMemory: lots\n"))
+	require.ErrorContains(t, err, "parsing max map memory")
+
+	_, err = ParsePolicy([]byte("limits:\n  maxLinks: -1\n"))
+	require.ErrorContains(t, err, "can't be negative")
+}
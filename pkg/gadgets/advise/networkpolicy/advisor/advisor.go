@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strings"
 
@@ -29,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	k8syaml "sigs.k8s.io/yaml"
 
+	dnstypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/dns/types"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/network/types"
 	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
@@ -42,9 +44,17 @@ var defaultLabelsToIgnore = map[string]struct{}{
 type NetworkPolicyAdvisor struct {
 	Events []types.Event
 
+	// DNSEvents are used to replace the egress rules to external addresses
+	// by FQDN rules, see CiliumPolicies
+	DNSEvents []dnstypes.Event
+
 	LabelsToIgnore map[string]struct{}
 
 	Policies []networkingv1.NetworkPolicy
+
+	// CiliumPolicies contain the egress rules to the external addresses
+	// resolved through DNS, by name instead of by address
+	CiliumPolicies []CiliumNetworkPolicy
 }
 
 func NewAdvisor() *NetworkPolicyAdvisor {
@@ -62,12 +72,38 @@ func (a *NetworkPolicyAdvisor) LoadFile(filename string) error {
 }
 
 func (a *NetworkPolicyAdvisor) LoadBuffer(buf []byte) error {
+	events, err := loadEvents[types.Event](buf)
+	if err != nil {
+		return err
+	}
+	a.Events = events
+	return nil
+}
+
+// LoadDNSFile loads the events of the trace dns gadget, in JSON
+func (a *NetworkPolicyAdvisor) LoadDNSFile(filename string) error {
+	buf, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return a.LoadDNSBuffer(buf)
+}
+
+func (a *NetworkPolicyAdvisor) LoadDNSBuffer(buf []byte) error {
+	events, err := loadEvents[dnstypes.Event](buf)
+	if err != nil {
+		return err
+	}
+	a.DNSEvents = events
+	return nil
+}
+
+func loadEvents[T any](buf []byte) ([]T, error) {
 	/* Try to read the file as an array */
-	events := []types.Event{}
+	events := []T{}
 	err := json.Unmarshal(buf, &events)
 	if err == nil {
-		a.Events = events
-		return nil
+		return events, nil
 	}
 
 	/* If it fails, read by line */
@@ -75,7 +111,7 @@ func (a *NetworkPolicyAdvisor) LoadBuffer(buf []byte) error {
 	line := 0
 	scanner := bufio.NewScanner(bytes.NewReader(buf))
 	for scanner.Scan() {
-		event := types.Event{}
+		var event T
 		text := strings.TrimSpace(scanner.Text())
 		if len(text) == 0 {
 			continue
@@ -83,18 +119,45 @@ func (a *NetworkPolicyAdvisor) LoadBuffer(buf []byte) error {
 		line++
 		err = json.Unmarshal([]byte(text), &event)
 		if err != nil {
-			return fmt.Errorf("parsing line %d: %w", line, err)
+			return nil, fmt.Errorf("parsing line %d: %w", line, err)
 		}
 		events = append(events, event)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return err
+		return nil, err
 	}
 
-	a.Events = events
+	return events, nil
+}
 
-	return nil
+type resolvedAddrKey struct {
+	namespace string
+	podName   string
+	addr      string
+}
+
+/* resolvedNames returns the names that each pod resolved, by address, from
+ * the DNS responses.
+ */
+func (a *NetworkPolicyAdvisor) resolvedNames() map[resolvedAddrKey][]string {
+	names := map[resolvedAddrKey][]string{}
+	for _, e := range a.DNSEvents {
+		if e.Type != eventtypes.NORMAL || e.Qr != dnstypes.DNSPktTypeResponse {
+			continue
+		}
+		name := strings.TrimSuffix(e.DNSName, ".")
+		if name == "" {
+			continue
+		}
+		for _, addr := range e.Addresses {
+			key := resolvedAddrKey{namespace: e.K8s.Namespace, podName: e.K8s.PodName, addr: addr}
+			if !slices.Contains(names[key], name) {
+				names[key] = append(names[key], name)
+			}
+		}
+	}
+	return names
 }
 
 /* labelFilteredKeyList returns a sorted list of label keys but without the labels to
@@ -275,6 +338,8 @@ func sortEgressRules(rules []networkingv1.NetworkPolicyEgressRule) []networkingv
 }
 
 func (a *NetworkPolicyAdvisor) GeneratePolicies() {
+	resolvedNames := a.resolvedNames()
+
 	eventsBySource := map[string][]types.Event{}
 	for _, e := range a.Events {
 		if e.Type != eventtypes.NORMAL {
@@ -323,7 +388,18 @@ func (a *NetworkPolicyAdvisor) GeneratePolicies() {
 			}
 		}
 		egressPolicies := []networkingv1.NetworkPolicyEgressRule{}
+		// names reached by port, e.g. "TCP/443"
+		fqdnsByPort := map[string][]string{}
 		for _, p := range egressNetworkPeer {
+			if p.DstEndpoint.Kind == eventtypes.EndpointKindRaw {
+				key := resolvedAddrKey{namespace: p.K8s.Namespace, podName: p.K8s.PodName, addr: p.DstEndpoint.Addr}
+				if names, ok := resolvedNames[key]; ok {
+					port := fmt.Sprintf("%s/%d", strings.ToUpper(p.Proto), p.Port)
+					fqdnsByPort[port] = append(fqdnsByPort[port], names...)
+					continue
+				}
+			}
+
 			ports, peers := a.eventToRule(p)
 			if len(peers) > 0 {
 				rule := networkingv1.NetworkPolicyEgressRule{
@@ -368,21 +444,85 @@ func (a *NetworkPolicyAdvisor) GeneratePolicies() {
 			},
 		}
 		a.Policies = append(a.Policies, policy)
+
+		if len(fqdnsByPort) > 0 {
+			a.CiliumPolicies = append(a.CiliumPolicies, ciliumFQDNPolicy(
+				strings.TrimSuffix(name, "-network")+"-fqdn",
+				events[0].K8s.Namespace,
+				a.labelFilter(events[0].PodLabels),
+				fqdnsByPort,
+			))
+		}
 	}
 
 	sort.Slice(a.Policies, func(i, j int) bool {
 		return a.Policies[i].Name < a.Policies[j].Name
 	})
+	sort.Slice(a.CiliumPolicies, func(i, j int) bool {
+		return a.CiliumPolicies[i].Name < a.CiliumPolicies[j].Name
+	})
+}
+
+/* ciliumFQDNPolicy returns a CiliumNetworkPolicy allowing the pods with the
+ * given labels to reach the names on the ports they were reached on.
+ */
+func ciliumFQDNPolicy(name, namespace string, labels map[string]string, fqdnsByPort map[string][]string) CiliumNetworkPolicy {
+	ports := make([]string, 0, len(fqdnsByPort))
+	for port := range fqdnsByPort {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+
+	egress := []CiliumEgressRule{ciliumDNSEgressRule()}
+	for _, port := range ports {
+		names := fqdnsByPort[port]
+		sort.Strings(names)
+		names = slices.Compact(names)
+
+		rule := CiliumEgressRule{}
+		for _, name := range names {
+			rule.ToFQDNs = append(rule.ToFQDNs, CiliumFQDNSelector{MatchName: name})
+		}
+		protocol, number, _ := strings.Cut(port, "/")
+		rule.ToPorts = []CiliumPortRule{
+			{Ports: []CiliumPortProtocol{{Port: number, Protocol: protocol}}},
+		}
+		egress = append(egress, rule)
+	}
+
+	return CiliumNetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "cilium.io/v2",
+			Kind:       "CiliumNetworkPolicy",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{},
+		},
+		Spec: CiliumNetworkPolicySpec{
+			EndpointSelector: metav1.LabelSelector{MatchLabels: labels},
+			Egress:           egress,
+		},
+	}
 }
 
 func (a *NetworkPolicyAdvisor) FormatPolicies() (out string) {
-	for i, p := range a.Policies {
+	policies := make([]any, 0, len(a.Policies)+len(a.CiliumPolicies))
+	for _, p := range a.Policies {
+		policies = append(policies, p)
+	}
+	for _, p := range a.CiliumPolicies {
+		policies = append(policies, p)
+	}
+
+	for i, p := range policies {
 		yamlOutput, err := k8syaml.Marshal(p)
 		if err != nil {
 			continue
 		}
 		sep := "---\n"
-		if i == len(a.Policies)-1 {
+		if i == len(policies)-1 {
 			sep = ""
 		}
 		out += fmt.Sprintf("%s%s", string(yamlOutput), sep)
@@ -34,6 +34,14 @@ func TestLoad(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
+
+		// DNS events are optional
+		dnsFile := inputFile[:len(inputFile)-len(".input")] + ".dns"
+		if _, err := os.Stat(dnsFile); err == nil {
+			if err := a.LoadDNSFile(dnsFile); err != nil {
+				t.Fatal(err)
+			}
+		}
 		a.GeneratePolicies()
 		generatedOuput := a.FormatPolicies()
 
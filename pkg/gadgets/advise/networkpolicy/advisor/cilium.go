@@ -0,0 +1,84 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package advisor
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CiliumNetworkPolicy is the subset of the CiliumNetworkPolicy resource
+// needed for the FQDN egress rules, the Cilium API isn't a dependency of
+// Inspektor Gadget.
+// See https://docs.cilium.io/en/stable/security/policy/language/#dns-based
+type CiliumNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec CiliumNetworkPolicySpec `json:"spec"`
+}
+
+type CiliumNetworkPolicySpec struct {
+	EndpointSelector metav1.LabelSelector `json:"endpointSelector"`
+	Egress           []CiliumEgressRule   `json:"egress,omitempty"`
+}
+
+type CiliumEgressRule struct {
+	ToEndpoints []metav1.LabelSelector `json:"toEndpoints,omitempty"`
+	ToFQDNs     []CiliumFQDNSelector   `json:"toFQDNs,omitempty"`
+	ToPorts     []CiliumPortRule       `json:"toPorts,omitempty"`
+}
+
+type CiliumFQDNSelector struct {
+	MatchName    string `json:"matchName,omitempty"`
+	MatchPattern string `json:"matchPattern,omitempty"`
+}
+
+type CiliumPortRule struct {
+	Ports []CiliumPortProtocol `json:"ports"`
+	Rules *CiliumL7Rules       `json:"rules,omitempty"`
+}
+
+type CiliumPortProtocol struct {
+	Port     string `json:"port"`
+	Protocol string `json:"protocol,omitempty"`
+}
+
+type CiliumL7Rules struct {
+	DNS []CiliumFQDNSelector `json:"dns,omitempty"`
+}
+
+// ciliumDNSEgressRule allows the DNS requests to kube-dns. Cilium learns the
+// addresses of the FQDNs from the DNS responses going through its DNS proxy,
+// so the requests must be allowed by a rule with DNS rules.
+func ciliumDNSEgressRule() CiliumEgressRule {
+	return CiliumEgressRule{
+		ToEndpoints: []metav1.LabelSelector{
+			{
+				MatchLabels: map[string]string{
+					"k8s:io.kubernetes.pod.namespace": "kube-system",
+					"k8s:k8s-app":                     "kube-dns",
+				},
+			},
+		},
+		ToPorts: []CiliumPortRule{
+			{
+				Ports: []CiliumPortProtocol{{Port: "53", Protocol: "ANY"}},
+				Rules: &CiliumL7Rules{
+					DNS: []CiliumFQDNSelector{{MatchPattern: "*"}},
+				},
+			},
+		},
+	}
+}
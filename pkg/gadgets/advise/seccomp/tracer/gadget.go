@@ -21,6 +21,15 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/parser"
 )
 
+const (
+	// AggregateParam selects how the syscalls of the containers are merged
+	// into SeccompProfiles: one per pod (AggregatePod, the default) or one
+	// per container of each workload (AggregateWorkload)
+	AggregateParam    = "aggregate"
+	AggregatePod      = "pod"
+	AggregateWorkload = "workload"
+)
+
 type GadgetDesc struct{}
 
 func (g *GadgetDesc) Name() string {
@@ -17,6 +17,14 @@ package gadgets
 const (
 	PinPath = "/sys/fs/bpf/gadget"
 
+	// SharedMapsPinPath is the directory where the maps shared between gadget
+	// instances are pinned, by the name they're shared with
+	SharedMapsPinPath = PinPath + "/shared"
+
+	// InstancesPinPath is the directory where the gadget instances persisting
+	// their state pin their maps, in a directory per instance ID
+	InstancesPinPath = PinPath + "/instances"
+
 	PerfBufferPages = 64
 
 	// bpf_ktime_get_boot_ns()'s func id as defined in Linux API
@@ -30,4 +38,19 @@ const (
 	// Name of the map that stores the mount namespace inode id to filter on.
 	// Keep in syn with name used in pkg/gadgets/common/mntns_filter.h.
 	MntNsFilterMapName = "gadget_mntns_filter_map"
+
+	// Constant used to set the number of bytes pending in the ring buffer
+	// before user space is woken up.
+	// Keep in sync with variable defined in include/gadget/buffer.h.
+	RingbufWakeupBytesName = "gadget_ringbuf_wakeup_bytes"
+
+	// Constants used to set the maximum number of events per second sent by
+	// each container and the number of events it can send at once.
+	// Keep in sync with variables defined in include/gadget/ratelimit.h.
+	RateLimitName      = "gadget_ratelimit"
+	RateLimitBurstName = "gadget_ratelimit_burst"
+
+	// Name of the map that stores the token bucket of each mount namespace.
+	// Keep in sync with name used in include/gadget/ratelimit.h.
+	RateLimitMapName = "gadget_ratelimit_map"
 )
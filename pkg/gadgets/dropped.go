@@ -0,0 +1,54 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// droppedEvents counts the events lost by each gadget. It's registered in the
+// default Prometheus registry, so it's served on the metrics endpoint.
+var droppedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gadget_events_dropped_total",
+	Help: "Number of events lost by the gadgets, e.g. because they weren't read fast enough",
+}, []string{"gadget"})
+
+// throttledEvents counts the events discarded by the rate limiting of each
+// gadget, by container
+var throttledEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "gadget_events_throttled_total",
+	Help: "Number of events discarded because their container exceeded the rate limit of the gadget",
+}, []string{"gadget", "namespace", "pod", "container"})
+
+func init() {
+	prometheus.MustRegister(droppedEvents, throttledEvents)
+}
+
+// EventsDropped records that gadget lost count events and returns the event
+// telling the consumers that their data has gaps. gadget is
+// "<category>/<name>" for built-in gadgets and the image for image-based ones.
+func EventsDropped(gadget string, count uint64) types.Event {
+	droppedEvents.WithLabelValues(gadget).Add(float64(count))
+	return types.EventsDropped(count)
+}
+
+// EventsThrottled records that the rate limiting of gadget discarded count
+// events of a container. namespace and pod are empty for containers not
+// managed by Kubernetes.
+func EventsThrottled(gadget, namespace, pod, container string, count uint64) {
+	throttledEvents.WithLabelValues(gadget, namespace, pod, container).Add(float64(count))
+}
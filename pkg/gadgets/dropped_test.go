@@ -0,0 +1,49 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestEventsDropped(t *testing.T) {
+	t.Parallel()
+
+	counter := droppedEvents.WithLabelValues("test/dropped")
+
+	ev := EventsDropped("test/dropped", 3)
+	require.Equal(t, types.EVENTS_DROPPED, ev.Type)
+	require.Equal(t, uint64(3), ev.Dropped)
+	require.Equal(t, "lost 3 samples", ev.Message)
+
+	EventsDropped("test/dropped", 2)
+	require.Equal(t, float64(5), testutil.ToFloat64(counter))
+}
+
+func TestEventsThrottled(t *testing.T) {
+	t.Parallel()
+
+	counter := throttledEvents.WithLabelValues("test/throttled", "default", "mypod", "mycontainer")
+
+	EventsThrottled("test/throttled", "default", "mypod", "mycontainer", 3)
+	EventsThrottled("test/throttled", "default", "mypod", "othercontainer", 1)
+	EventsThrottled("test/throttled", "default", "mypod", "mycontainer", 2)
+	require.Equal(t, float64(5), testutil.ToFloat64(counter))
+}
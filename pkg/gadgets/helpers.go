@@ -22,7 +22,6 @@ import (
 	"net"
 	"net/netip"
 	"sync"
-	"time"
 	"unsafe"
 
 	"github.com/cilium/ebpf"
@@ -151,42 +150,6 @@ func IPVerFromAF(af uint16) int {
 	}
 }
 
-var timeDiff time.Duration
-
-func init() {
-	var t unix.Timespec
-	err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &t)
-	if err != nil {
-		panic(err)
-	}
-	timeDiff = time.Duration(time.Now().UnixNano() - t.Sec*1000*1000*1000 - t.Nsec)
-}
-
-// WallTimeFromBootTime converts a time from bpf_ktime_get_boot_ns() to the
-// wall time with nano precision.
-//
-// Example:
-//
-//	fmt.Printf("Time: %s\n", WallTimeFromBootTime(ts).String())
-//
-// would display:
-//
-//	Time: 2022-12-15T16:49:00.452371948+01:00
-//
-// Shell command to convert the number to a date:
-//
-//	$ date -d @$(echo 1671447636499110634/1000000000|bc -l) +"%d-%m-%Y %H:%M:%S:%N"
-//	19-12-2022 12:00:36:499110634
-//
-// bpf_ktime_get_boot_ns was added in Linux 5.7. If not available and the BPF
-// program returns 0, just get the timestamp in userspace.
-func WallTimeFromBootTime(ts uint64) types.Time {
-	if ts == 0 {
-		return types.Time(time.Now().UnixNano())
-	}
-	return types.Time(time.Unix(0, int64(ts)).Add(timeDiff).UnixNano())
-}
-
 var (
 	bpfKtimeGetBootNsOnce   sync.Once
 	bpfKtimeGetBootNsExists bool
@@ -84,11 +84,17 @@ type OutputFormats map[string]OutputFormat
 // OutputFormat can hold alternative output formats for a gadget. Whenever
 // such a format is used, the result of the gadget will be passed to the Transform()
 // function and returned to the user.
+// Formats that need all the events before writing anything, like images, can
+// accumulate them in Transform(), returning nothing, and return the output in
+// Finish(), called once the gadget is done.
+// The output of Binary formats is written as is, without adding a newline.
 type OutputFormat struct {
 	Name                   string                    `json:"name"`
 	Description            string                    `json:"description"`
 	RequiresCombinedResult bool                      `json:"requiresCombinedResult"`
+	Binary                 bool                      `json:"binary"`
 	Transform              func(any) ([]byte, error) `json:"-"`
+	Finish                 func() ([]byte, error)    `json:"-"`
 }
 
 // Append appends the OutputFormats given in other to of
@@ -114,6 +120,15 @@ type EventHandlerArraySetter interface {
 	SetEventHandlerArray(handler any)
 }
 
+// EventHandlerBatchSetter can be implemented by gadgets emitting events at
+// high rates. Unlike with EventHandlerArraySetter, the handler receives
+// several independent events at once, not a full snapshot. Gadgets prefer it
+// over the one set by EventHandlerSetter when both are set. The slice passed to
+// the handler must not be retained after it returns.
+type EventHandlerBatchSetter interface {
+	SetEventHandlerBatch(handler any)
+}
+
 type EventEnricherSetter interface {
 	SetEventEnricher(func(ev any) error)
 }
@@ -159,6 +174,27 @@ type InitCloseGadget interface {
 
 type Gadget any
 
+// PausableGadget is an optional interface that can be implemented by gadgets
+// that can stop producing events for a while without losing their state, e.g.
+// by detaching their eBPF programs while keeping their maps.
+type PausableGadget interface {
+	// Pause stops producing events until Resume is called. Pausing a paused
+	// gadget does nothing.
+	Pause() error
+
+	// Resume starts producing events again. Resuming a gadget that isn't
+	// paused does nothing.
+	Resume() error
+}
+
+// UpdatableParamsGadget is an optional interface that can be implemented by
+// gadgets whose params can be changed while they run
+type UpdatableParamsGadget interface {
+	// UpdateParams sets the params in values, by key. None is set if any of
+	// them can't be changed.
+	UpdateParams(values map[string]string) error
+}
+
 // GadgetInstantiate is the same interface as Gadget but adds one call to instantiate an actual
 // tracer
 type GadgetInstantiate interface {
@@ -173,3 +209,11 @@ type GadgetInstantiate interface {
 type GadgetExperimental interface {
 	Experimental() bool
 }
+
+// GadgetClusterScoped allows to mark a gadget as cluster-scoped. The result of
+// such gadgets doesn't depend on the node they run on, e.g. because they query
+// the API server or produce cluster-level advice, so only the leader among the
+// gadget pods runs them.
+type GadgetClusterScoped interface {
+	ClusterScoped() bool
+}
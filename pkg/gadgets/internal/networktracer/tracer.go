@@ -37,6 +37,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"syscall"
 	"unsafe"
@@ -59,7 +60,9 @@ import (
 type attachment struct {
 	dispatcherObjs dispatcherObjects
 
-	sockFd int
+	// sockFds are the raw sockets the dispatcher is attached to, one for
+	// each selected interface or a single one for all of them
+	sockFds []int
 
 	// users keeps track of the users' pid that have called Attach(). This can
 	// happen for two reasons:
@@ -81,6 +84,14 @@ type Tracer[Event any] struct {
 	attachments map[uint64]*attachment
 
 	eventHandler func(ev *Event)
+
+	// gadget is the name of the gadget using the tracer, used to report
+	// the lost events
+	gadget string
+
+	// ifaces selects the interfaces to attach to in each network namespace,
+	// all of them if nil
+	ifaces *regexp.Regexp
 }
 
 func (t *Tracer[Event]) newAttachment(
@@ -88,14 +99,11 @@ func (t *Tracer[Event]) newAttachment(
 	netns uint64,
 ) (_ *attachment, err error) {
 	a := &attachment{
-		sockFd: -1,
-		users:  map[uint32]struct{}{pid: {}},
+		users: map[uint32]struct{}{pid: {}},
 	}
 	defer func() {
 		if err != nil {
-			if a.sockFd != -1 {
-				unix.Close(a.sockFd)
-			}
+			a.closeSockets()
 			a.dispatcherObjs.Close()
 		}
 	}()
@@ -121,20 +129,66 @@ func (t *Tracer[Event]) newAttachment(
 		return nil, fmt.Errorf("loading ebpf program: %w", err)
 	}
 
-	a.sockFd, err = rawsock.OpenRawSock(pid)
-	if err != nil {
-		return nil, fmt.Errorf("opening raw socket: %w", err)
+	if t.ifaces == nil {
+		sockFd, err := rawsock.OpenRawSock(pid)
+		if err != nil {
+			return nil, fmt.Errorf("opening raw socket: %w", err)
+		}
+		a.sockFds = []int{sockFd}
+	} else {
+		a.sockFds, err = rawsock.OpenRawSocks(pid, t.ifaces.MatchString)
+		if err != nil {
+			return nil, fmt.Errorf("opening raw sockets on interfaces matching %q: %w", t.ifaces, err)
+		}
 	}
 
-	if err := syscall.SetsockoptInt(a.sockFd, syscall.SOL_SOCKET, unix.SO_ATTACH_BPF, a.dispatcherObjs.IgNetDisp.FD()); err != nil {
-		return nil, fmt.Errorf("attaching BPF program: %w", err)
+	for _, sockFd := range a.sockFds {
+		if err := syscall.SetsockoptInt(sockFd, syscall.SOL_SOCKET, unix.SO_ATTACH_BPF, a.dispatcherObjs.IgNetDisp.FD()); err != nil {
+			return nil, fmt.Errorf("attaching BPF program: %w", err)
+		}
 	}
 	return a, nil
 }
 
-func NewTracer[Event any]() (_ *Tracer[Event], err error) {
+func (a *attachment) closeSockets() {
+	for _, sockFd := range a.sockFds {
+		unix.Close(sockFd)
+	}
+	a.sockFds = nil
+}
+
+// CompileInterfacePattern returns the regular expression selecting the
+// interfaces named by pattern. It's a name, e.g. eth0, or a regular
+// expression matching the whole name, e.g. eth.*
+func CompileInterfacePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid interface pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// SetInterfaces restricts the interfaces the program is attached to in each
+// network namespace to the ones matching pattern, see
+// CompileInterfacePattern. All of them are used if it's empty. It only
+// affects the network namespaces attached afterwards.
+func (t *Tracer[Event]) SetInterfaces(pattern string) error {
+	if pattern == "" {
+		t.ifaces = nil
+		return nil
+	}
+	re, err := CompileInterfacePattern(pattern)
+	if err != nil {
+		return err
+	}
+	t.ifaces = re
+	return nil
+}
+
+func NewTracer[Event any](gadget string) (_ *Tracer[Event], err error) {
 	t := &Tracer[Event]{
 		attachments: make(map[uint64]*attachment),
+		gadget:      gadget,
 	}
 
 	// Keep in sync with tail_call map in bpf/dispatcher.bpf.c
@@ -252,6 +306,17 @@ func (t *Tracer[Event]) AttachProg(prog *ebpf.Program) error {
 	return t.dispatcherMap.Update(uint32(0), uint32(prog.FD()), ebpf.UpdateAny)
 }
 
+// DetachProg removes the program set with AttachProg, so the dispatcher stops
+// calling it while staying attached to the network namespaces. It's used to
+// pause containerized gadgets.
+func (t *Tracer[Event]) DetachProg() error {
+	err := t.dispatcherMap.Delete(uint32(0))
+	if err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+		return err
+	}
+	return nil
+}
+
 func (t *Tracer[Event]) Attach(pid uint32) error {
 	netns, err := containerutils.GetNetNs(int(pid))
 	if err != nil {
@@ -324,8 +389,7 @@ func (t *Tracer[Event]) listen(
 		}
 
 		if record.LostSamples != 0 {
-			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
-			t.eventHandler(baseEvent(types.Warn(msg)))
+			t.eventHandler(baseEvent(gadgets.EventsDropped(t.gadget, record.LostSamples)))
 			continue
 		}
 
@@ -349,7 +413,7 @@ func (t *Tracer[Event]) listen(
 }
 
 func (t *Tracer[Event]) releaseAttachment(netns uint64, a *attachment) {
-	unix.Close(a.sockFd)
+	a.closeSockets()
 	a.dispatcherObjs.Close()
 	delete(t.attachments, netns)
 }
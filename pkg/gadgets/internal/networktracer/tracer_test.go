@@ -0,0 +1,40 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networktracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileInterfacePattern(t *testing.T) {
+	t.Parallel()
+
+	re, err := CompileInterfacePattern("eth0")
+	require.NoError(t, err)
+	require.True(t, re.MatchString("eth0"))
+	require.False(t, re.MatchString("eth01"))
+	require.False(t, re.MatchString("veth0"))
+
+	re, err = CompileInterfacePattern("eth.*|lo")
+	require.NoError(t, err)
+	require.True(t, re.MatchString("eth1"))
+	require.True(t, re.MatchString("lo"))
+	require.False(t, re.MatchString("veth1"))
+
+	_, err = CompileInterfacePattern("eth[")
+	require.ErrorContains(t, err, "invalid interface pattern")
+}
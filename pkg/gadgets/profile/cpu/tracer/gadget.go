@@ -15,6 +15,12 @@
 package tracer
 
 import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/flamegraph"
 	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/profile/cpu/types"
@@ -74,6 +80,66 @@ func (g *GadgetDesc) EventPrototype() any {
 	return &types.Report{}
 }
 
+// reportsFromEvent returns the reports in an event given to the output formats
+func reportsFromEvent(ev any) ([]*types.Report, error) {
+	switch ev := ev.(type) {
+	case *types.Report:
+		return []*types.Report{ev}, nil
+	case []*types.Report:
+		return ev, nil
+	}
+	return nil, fmt.Errorf("type must be *types.Report or []*types.Report and is: %T", ev)
+}
+
+func (g *GadgetDesc) OutputFormats() (gadgets.OutputFormats, string) {
+	// Events of different nodes can be transformed concurrently
+	var mu sync.Mutex
+	graph := flamegraph.New("CPU profile")
+
+	return gadgets.OutputFormats{
+		"folded": gadgets.OutputFormat{
+			Name:        "Folded",
+			Description: "The stacks folded in one line each, the input of the flame graph tools",
+			Transform: func(ev any) ([]byte, error) {
+				reports, err := reportsFromEvent(ev)
+				if err != nil {
+					return nil, err
+				}
+				lines := make([]string, 0, len(reports))
+				for _, report := range reports {
+					lines = append(lines, flamegraph.FoldedLine(report.Stack(), report.Count))
+				}
+				return []byte(strings.Join(lines, "\n")), nil
+			},
+		},
+		"flamegraph.svg": gadgets.OutputFormat{
+			Name:        "Flame graph",
+			Description: "An SVG flame graph of the stacks, written once the gadget is done",
+			Transform: func(ev any) ([]byte, error) {
+				reports, err := reportsFromEvent(ev)
+				if err != nil {
+					return nil, err
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				for _, report := range reports {
+					graph.Add(report.Stack(), report.Count)
+				}
+				return nil, nil
+			},
+			Finish: func() ([]byte, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				var buf bytes.Buffer
+				if err := graph.WriteSVG(&buf); err != nil {
+					return nil, err
+				}
+				return buf.Bytes(), nil
+			},
+		},
+	}, "columns"
+}
+
 func init() {
 	gadgetregistry.Register(&GadgetDesc{})
 }
@@ -44,6 +44,20 @@ func (r *Report) GetMountNSID() uint64 {
 	return r.MntnsID
 }
 
+// Stack returns the frames of the report going from the root to the leaf, as
+// used by flame graphs: the command, the user stack and the kernel stack
+func (r *Report) Stack() []string {
+	stack := make([]string, 0, 1+len(r.UserStack)+len(r.KernelStack))
+	stack = append(stack, r.Comm)
+	for i := len(r.UserStack) - 1; i >= 0; i-- {
+		stack = append(stack, r.UserStack[i])
+	}
+	for i := len(r.KernelStack) - 1; i >= 0; i-- {
+		stack = append(stack, r.KernelStack[i])
+	}
+	return stack
+}
+
 func (r *Report) ExtraLines() []string {
 	var out []string
 	for i := len(r.KernelStack) - 1; i >= 0; i-- {
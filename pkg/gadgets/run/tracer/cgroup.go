@@ -0,0 +1,169 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+// The cgroup hierarchies a node can use
+const (
+	// cgroupUnified is cgroup v2 only, mounted at /sys/fs/cgroup
+	cgroupUnified = "unified"
+	// cgroupHybrid is cgroup v1 with cgroup v2 mounted at
+	// /sys/fs/cgroup/unified, used by older distributions
+	cgroupHybrid = "hybrid"
+	// cgroupLegacy is cgroup v1 only
+	cgroupLegacy = "legacy"
+)
+
+var (
+	cgroupUnifiedPath = "/sys/fs/cgroup"
+	cgroupHybridPath  = "/sys/fs/cgroup/unified"
+)
+
+// isCgroupProgram returns whether p is attached to a cgroup. They're attached
+// to the root cgroup v2, so they see all the processes of the node, and the
+// gadget filters the containers itself.
+func isCgroupProgram(p *ebpf.ProgramSpec) bool {
+	switch p.Type {
+	case ebpf.CGroupSKB, ebpf.CGroupSock, ebpf.CGroupSockAddr, ebpf.CGroupSockopt,
+		ebpf.CGroupSysctl, ebpf.CGroupDevice, ebpf.SockOps:
+		return true
+	}
+	return false
+}
+
+// fsType returns the type of the filesystem mounted at path, see statfs(2)
+func fsType(path string) (int64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Type), nil
+}
+
+// detectCgroupMode returns the cgroup hierarchy of the node and where cgroup v2
+// is mounted, if it is
+func detectCgroupMode(fsType func(string) (int64, error)) (string, string, error) {
+	typ, err := fsType(cgroupUnifiedPath)
+	if err != nil {
+		return "", "", fmt.Errorf("checking cgroup filesystem: %w", err)
+	}
+	if typ == unix.CGROUP2_SUPER_MAGIC {
+		return cgroupUnified, cgroupUnifiedPath, nil
+	}
+	if typ, err := fsType(cgroupHybridPath); err == nil && typ == unix.CGROUP2_SUPER_MAGIC {
+		return cgroupHybrid, cgroupHybridPath, nil
+	}
+	return cgroupLegacy, "", nil
+}
+
+// haveCgroupV2 returns whether the kernel supports cgroup v2, even when it
+// isn't mounted
+func haveCgroupV2() (bool, error) {
+	f, err := os.Open("/proc/filesystems")
+	if err != nil {
+		return false, fmt.Errorf("reading supported filesystems: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) > 0 && fields[len(fields)-1] == "cgroup2" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// errCgroupV1Only is returned when the kernel can't attach cgroup programs
+// because it only supports cgroup v1
+var errCgroupV1Only = errors.New("cgroup programs need cgroup v2, but the kernel only supports cgroup v1")
+
+// probeCgroupV2 checks cgroup programs can be attached: cgroup v2 is mounted,
+// alone or along cgroup v1, or the kernel supports it and it can be mounted
+func probeCgroupV2() error {
+	mode, _, err := detectCgroupMode(fsType)
+	if err != nil {
+		return err
+	}
+	if mode != cgroupLegacy {
+		return nil
+	}
+	ok, err := haveCgroupV2()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errCgroupV1Only
+	}
+	return nil
+}
+
+// cgroupRoot is the root cgroup v2 cgroup programs are attached to
+type cgroupRoot struct {
+	path string
+	// mounted is set when cgroup v2 was mounted by the gadget, on nodes only
+	// using cgroup v1
+	mounted bool
+}
+
+// openCgroupRoot returns the root cgroup v2. On nodes only using cgroup v1,
+// where all the processes are in the root cgroup v2, it's mounted in a
+// temporary directory until Close is called.
+func openCgroupRoot() (*cgroupRoot, error) {
+	mode, path, err := detectCgroupMode(fsType)
+	if err != nil {
+		return nil, err
+	}
+	if mode != cgroupLegacy {
+		return &cgroupRoot{path: path}, nil
+	}
+
+	ok, err := haveCgroupV2()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errCgroupV1Only
+	}
+	dir, err := os.MkdirTemp("", "ig-cgroup2-")
+	if err != nil {
+		return nil, fmt.Errorf("creating cgroup v2 mount point: %w", err)
+	}
+	if err := unix.Mount("cgroup2", dir, "cgroup2", 0, ""); err != nil {
+		os.Remove(dir)
+		return nil, fmt.Errorf("mounting cgroup v2 on a node using cgroup v1: %w", err)
+	}
+	return &cgroupRoot{path: dir, mounted: true}, nil
+}
+
+func (c *cgroupRoot) Close() {
+	if !c.mounted {
+		return
+	}
+	// The programs stay attached to the cgroup once it's unmounted
+	unix.Unmount(c.path, unix.MNT_DETACH)
+	os.Remove(c.path)
+	c.mounted = false
+}
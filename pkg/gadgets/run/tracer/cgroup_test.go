@@ -0,0 +1,81 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestDetectCgroupMode(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		fsTypes      map[string]int64
+		expectedMode string
+		expectedPath string
+		expectedErr  bool
+	}
+
+	tests := map[string]testDefinition{
+		"unified": {
+			fsTypes:      map[string]int64{"/sys/fs/cgroup": unix.CGROUP2_SUPER_MAGIC},
+			expectedMode: cgroupUnified,
+			expectedPath: "/sys/fs/cgroup",
+		},
+		"hybrid": {
+			fsTypes: map[string]int64{
+				"/sys/fs/cgroup":         unix.TMPFS_MAGIC,
+				"/sys/fs/cgroup/unified": unix.CGROUP2_SUPER_MAGIC,
+			},
+			expectedMode: cgroupHybrid,
+			expectedPath: "/sys/fs/cgroup/unified",
+		},
+		"legacy": {
+			fsTypes:      map[string]int64{"/sys/fs/cgroup": unix.TMPFS_MAGIC},
+			expectedMode: cgroupLegacy,
+		},
+		"not_mounted": {
+			fsTypes:     map[string]int64{},
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			fsType := func(path string) (int64, error) {
+				typ, ok := test.fsTypes[path]
+				if !ok {
+					return 0, errors.New("no such file or directory")
+				}
+				return typ, nil
+			}
+			mode, path, err := detectCgroupMode(fsType)
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedMode, mode)
+			require.Equal(t, test.expectedPath, path)
+		})
+	}
+}
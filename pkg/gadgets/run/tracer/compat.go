@@ -0,0 +1,387 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"github.com/cilium/ebpf/features"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gopkg.in/yaml.v3"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+// CompatStatus tells whether the kernel supports what a gadget needs
+type CompatStatus string
+
+const (
+	CompatSupported   CompatStatus = "supported"
+	CompatUnsupported CompatStatus = "unsupported"
+	// CompatUnknown is used when it can't be checked before running the
+	// gadget, e.g. for uprobes on the binaries of the containers
+	CompatUnknown CompatStatus = "unknown"
+)
+
+// The kinds of things checked by CheckCompat
+const (
+	CompatKindRequirement = "requirement"
+	CompatKindProgramType = "program type"
+	CompatKindMapType     = "map type"
+	CompatKindHelper      = "helper"
+	CompatKindAttach      = "attach point"
+)
+
+// CompatCheck is the result of checking one thing a gadget needs from the
+// kernel
+type CompatCheck struct {
+	Kind string
+	Name string
+	// Programs are the programs needing it, if any
+	Programs []string
+	Status   CompatStatus
+	// Reason explains why it isn't supported or can't be checked
+	Reason string
+}
+
+// kernelFeatures detects what the kernel supports
+type kernelFeatures struct {
+	programType   func(ebpf.ProgramType) error
+	mapType       func(ebpf.MapType) error
+	helper        func(ebpf.ProgramType, asm.BuiltinFunc) error
+	kernelVersion func() (uint32, error)
+	probes        map[string]func() error
+	// kernelSymbol checks whether the kernel has the symbol, for kprobes
+	kernelSymbol func(string) error
+	// tracepoint checks whether the kernel has the tracepoint
+	tracepoint func(category, name string) error
+	// btfFunc checks whether the kernel BTF describes the function, for
+	// fentry, fexit and iter programs
+	btfFunc func(string) error
+	// cgroupV2 checks whether cgroup programs can be attached
+	cgroupV2 func() error
+}
+
+// errCantCheck is returned by the probes of kernelFeatures when they can't
+// tell whether the kernel supports something
+var errCantCheck = errors.New("can't be checked")
+
+func newKernelFeatures() *kernelFeatures {
+	kallsyms := sync.OnceValues(readKallsyms)
+	return &kernelFeatures{
+		programType: func(pt ebpf.ProgramType) error {
+			return featureResult(features.HaveProgramType(pt))
+		},
+		mapType: func(mt ebpf.MapType) error {
+			return featureResult(features.HaveMapType(mt))
+		},
+		helper: func(pt ebpf.ProgramType, fn asm.BuiltinFunc) error {
+			return featureResult(features.HaveProgramHelper(pt, fn))
+		},
+		kernelVersion: features.LinuxVersionCode,
+		probes:        featureProbes,
+		kernelSymbol: func(symbol string) error {
+			symbols, err := kallsyms()
+			if err != nil {
+				return fmt.Errorf("%w: %w", errCantCheck, err)
+			}
+			if _, ok := symbols[symbol]; !ok {
+				return fmt.Errorf("kernel symbol %q not found", symbol)
+			}
+			return nil
+		},
+		tracepoint: tracepointExists,
+		btfFunc: func(name string) error {
+			spec, err := btf.LoadKernelSpec()
+			if err != nil {
+				return fmt.Errorf("%w: kernel BTF information isn't available: %w", errCantCheck, err)
+			}
+			var fn *btf.Func
+			if err := spec.TypeByName(name, &fn); err != nil {
+				return fmt.Errorf("function %q not found in kernel BTF information", name)
+			}
+			return nil
+		},
+		cgroupV2: probeCgroupV2,
+	}
+}
+
+// featureResult converts the result of the probes of cilium/ebpf: only
+// ebpf.ErrNotSupported means the kernel doesn't support the feature, other
+// errors mean it couldn't be probed, e.g. for helpers of tracing programs
+func featureResult(err error) error {
+	if err == nil || errors.Is(err, ebpf.ErrNotSupported) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errCantCheck, err)
+}
+
+// readKallsyms returns the names of the symbols of the kernel and its modules
+func readKallsyms() (map[string]struct{}, error) {
+	f, err := os.Open("/proc/kallsyms")
+	if err != nil {
+		return nil, fmt.Errorf("reading kernel symbols: %w", err)
+	}
+	defer f.Close()
+
+	symbols := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// ffffffff81000000 T _stext [module]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		symbols[fields[2]] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading kernel symbols: %w", err)
+	}
+	return symbols, nil
+}
+
+// tracefsPaths are the usual mount points of tracefs
+var tracefsPaths = []string{"/sys/kernel/tracing", "/sys/kernel/debug/tracing"}
+
+func tracepointExists(category, name string) error {
+	for _, tracefs := range tracefsPaths {
+		if _, err := os.Stat(filepath.Join(tracefs, "events")); err != nil {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(tracefs, "events", category, name)); err != nil {
+			return fmt.Errorf("tracepoint %s/%s not found", category, name)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: tracefs isn't mounted", errCantCheck)
+}
+
+// CheckCompat checks what the gadget needs from the running kernel: its
+// requirements, the types of its programs and maps, the helpers it calls and
+// the points its programs attach to. Nothing is loaded in the kernel except
+// the small programs probing its features.
+func CheckCompat(gadget *oci.GadgetImage) ([]CompatCheck, error) {
+	spec, err := loadSpec(gadget.EbpfObject)
+	if err != nil {
+		return nil, err
+	}
+
+	var requirements types.Requirements
+	if len(gadget.Metadata) > 0 && !bytes.Equal(gadget.Metadata, ocispec.DescriptorEmptyJSON.Data) {
+		metadata := &types.GadgetMetadata{}
+		if err := yaml.Unmarshal(gadget.Metadata, metadata); err != nil {
+			return nil, fmt.Errorf("unmarshaling metadata: %w", err)
+		}
+		requirements = metadata.Requirements
+	}
+
+	return checkCompat(spec, requirements, newKernelFeatures()), nil
+}
+
+// compatChecks collects the checks, once per kind and name
+type compatChecks struct {
+	checks []*CompatCheck
+	byKey  map[string]*CompatCheck
+}
+
+// add adds a check, running it only the first time a kind and name are seen
+func (c *compatChecks) add(kind, name, program string, check func() (CompatStatus, string)) {
+	key := kind + "/" + name
+	cc, ok := c.byKey[key]
+	if !ok {
+		status, reason := check()
+		cc = &CompatCheck{Kind: kind, Name: name, Status: status, Reason: reason}
+		c.byKey[key] = cc
+		c.checks = append(c.checks, cc)
+	}
+	if program != "" {
+		cc.Programs = append(cc.Programs, program)
+	}
+}
+
+// compatStatus converts the result of a probe to a status
+func compatStatus(err error) (CompatStatus, string) {
+	switch {
+	case err == nil:
+		return CompatSupported, ""
+	case errors.Is(err, errCantCheck):
+		return CompatUnknown, err.Error()
+	}
+	return CompatUnsupported, err.Error()
+}
+
+func checkCompat(spec *ebpf.CollectionSpec, requirements types.Requirements, kf *kernelFeatures) []CompatCheck {
+	checks := &compatChecks{byKey: make(map[string]*CompatCheck)}
+
+	if requirements.KernelVersion != "" {
+		checks.add(CompatKindRequirement, "kernel "+requirements.KernelVersion, "", func() (CompatStatus, string) {
+			return compatStatus(checkRequirementsWith(types.Requirements{KernelVersion: requirements.KernelVersion},
+				kf.kernelVersion, kf.probes))
+		})
+	}
+	for _, feature := range requirements.Features {
+		feature := feature
+		checks.add(CompatKindRequirement, feature, "", func() (CompatStatus, string) {
+			probe, ok := kf.probes[feature]
+			if !ok {
+				return CompatUnknown, fmt.Sprintf("unknown kernel feature %q", feature)
+			}
+			return compatStatus(probe())
+		})
+	}
+
+	mapNames := make([]string, 0, len(spec.Maps))
+	for name := range spec.Maps {
+		mapNames = append(mapNames, name)
+	}
+	sort.Strings(mapNames)
+	for _, name := range mapNames {
+		m := spec.Maps[name]
+		if m.Type == ebpf.UnspecifiedMap || strings.HasPrefix(name, ".") {
+			// Global variables
+			continue
+		}
+		checks.add(CompatKindMapType, m.Type.String(), "", func() (CompatStatus, string) {
+			return compatStatus(kf.mapType(m.Type))
+		})
+	}
+
+	progNames := make([]string, 0, len(spec.Programs))
+	for name := range spec.Programs {
+		progNames = append(progNames, name)
+	}
+	sort.Strings(progNames)
+	for _, name := range progNames {
+		p := spec.Programs[name]
+
+		checks.add(CompatKindProgramType, p.Type.String(), name, func() (CompatStatus, string) {
+			return compatStatus(kf.programType(p.Type))
+		})
+
+		for _, fn := range programHelpers(p) {
+			fn := fn
+			checks.add(CompatKindHelper, helperName(fn), name, func() (CompatStatus, string) {
+				status, reason := compatStatus(kf.helper(p.Type, fn))
+				if status == CompatUnsupported && fn == asm.FnKtimeGetBootNs {
+					// See gadgets.FixBpfKtimeGetBootNs()
+					return CompatSupported, "replaced by bpf_ktime_get_ns"
+				}
+				return status, reason
+			})
+		}
+
+		if target, check := attachCheck(p, kf); check != nil {
+			checks.add(CompatKindAttach, target, name, check)
+		}
+	}
+
+	ret := make([]CompatCheck, 0, len(checks.checks))
+	for _, check := range checks.checks {
+		ret = append(ret, *check)
+	}
+	return ret
+}
+
+// programHelpers returns the helpers called by p
+func programHelpers(p *ebpf.ProgramSpec) []asm.BuiltinFunc {
+	var helpers []asm.BuiltinFunc
+	seen := make(map[asm.BuiltinFunc]struct{})
+	for _, ins := range p.Instructions {
+		if !ins.IsBuiltinCall() {
+			continue
+		}
+		fn := asm.BuiltinFunc(ins.Constant)
+		if _, ok := seen[fn]; ok {
+			continue
+		}
+		seen[fn] = struct{}{}
+		helpers = append(helpers, fn)
+	}
+	return helpers
+}
+
+// helperName returns the name of the helper in C, e.g. bpf_ringbuf_output for
+// asm.FnRingbufOutput
+func helperName(fn asm.BuiltinFunc) string {
+	name := strings.TrimPrefix(fn.String(), "Fn")
+	var b strings.Builder
+	b.WriteString("bpf")
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i == 0 || !unicode.IsUpper(rune(name[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// attachCheck returns the point p attaches to and a function checking the
+// kernel has it, or a nil function if there's nothing to check
+func attachCheck(p *ebpf.ProgramSpec, kf *kernelFeatures) (string, func() (CompatStatus, string)) {
+	kind := sectionKind(p)
+	target := kind + "/" + p.AttachTo
+
+	switch {
+	case isUprobe(p):
+		return target, func() (CompatStatus, string) {
+			return CompatUnknown, "depends on the binaries of the containers"
+		}
+	case isCgroupProgram(p):
+		return p.SectionName, func() (CompatStatus, string) {
+			return compatStatus(kf.cgroupV2())
+		}
+	case kind == "kprobe" || kind == "kretprobe":
+		return target, func() (CompatStatus, string) {
+			return compatStatus(kf.kernelSymbol(p.AttachTo))
+		}
+	case kind == "tracepoint" || kind == "tp":
+		return target, func() (CompatStatus, string) {
+			category, name, ok := strings.Cut(p.AttachTo, "/")
+			if !ok {
+				return CompatUnsupported, fmt.Sprintf("invalid tracepoint %q", p.AttachTo)
+			}
+			return compatStatus(kf.tracepoint(category, name))
+		}
+	case kind == "raw_tracepoint" || kind == "raw_tp":
+		return target, func() (CompatStatus, string) {
+			return compatStatus(kf.kernelSymbol("__tracepoint_" + p.AttachTo))
+		}
+	case kind == "fentry" || kind == "fexit":
+		return target, func() (CompatStatus, string) {
+			return compatStatus(kf.btfFunc(p.AttachTo))
+		}
+	case kind == "iter":
+		return target, func() (CompatStatus, string) {
+			return compatStatus(kf.btfFunc("bpf_iter_" + p.AttachTo))
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+func TestCheckCompat(t *testing.T) {
+	t.Parallel()
+
+	instructions := func(helpers ...asm.BuiltinFunc) asm.Instructions {
+		var insns asm.Instructions
+		for _, fn := range helpers {
+			insns = append(insns, fn.Call())
+		}
+		return append(insns, asm.Mov.Imm(asm.R0, 0), asm.Return())
+	}
+
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			"events":  {Name: "events", Type: ebpf.RingBuf},
+			"stats":   {Name: "stats", Type: ebpf.Hash},
+			".rodata": {Name: ".rodata", Type: ebpf.Array},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{
+			"ig_open": {
+				Name:         "ig_open",
+				Type:         ebpf.Kprobe,
+				SectionName:  "kprobe/do_sys_openat2",
+				AttachTo:     "do_sys_openat2",
+				Instructions: instructions(asm.FnKtimeGetBootNs, asm.FnRingbufOutput, asm.FnRingbufOutput),
+			},
+			"ig_close": {
+				Name:         "ig_close",
+				Type:         ebpf.Kprobe,
+				SectionName:  "kprobe/close_fd_new",
+				AttachTo:     "close_fd_new",
+				Instructions: instructions(asm.FnRingbufOutput),
+			},
+			"ig_exec": {
+				Name:         "ig_exec",
+				Type:         ebpf.TracePoint,
+				SectionName:  "tracepoint/syscalls/sys_enter_execve",
+				AttachTo:     "syscalls/sys_enter_execve",
+				Instructions: instructions(asm.FnGetCurrentPidTgid),
+			},
+			"ig_ssl": {
+				Name:         "ig_ssl",
+				Type:         ebpf.Kprobe,
+				SectionName:  "uprobe/libssl:SSL_write",
+				AttachTo:     "libssl:SSL_write",
+				Instructions: instructions(),
+			},
+			"ig_fentry": {
+				Name:         "ig_fentry",
+				Type:         ebpf.Tracing,
+				SectionName:  "fentry/tcp_connect",
+				AttachTo:     "tcp_connect",
+				Instructions: instructions(),
+			},
+			"ig_skb": {
+				Name:         "ig_skb",
+				Type:         ebpf.CGroupSKB,
+				AttachType:   ebpf.AttachCGroupInetIngress,
+				SectionName:  "cgroup_skb/ingress",
+				Instructions: instructions(),
+			},
+		},
+	}
+
+	kf := &kernelFeatures{
+		programType: func(pt ebpf.ProgramType) error {
+			if pt == ebpf.Tracing {
+				return ebpf.ErrNotSupported
+			}
+			return nil
+		},
+		mapType: func(mt ebpf.MapType) error {
+			if mt == ebpf.RingBuf {
+				return ebpf.ErrNotSupported
+			}
+			return nil
+		},
+		helper: func(pt ebpf.ProgramType, fn asm.BuiltinFunc) error {
+			switch fn {
+			case asm.FnKtimeGetBootNs, asm.FnRingbufOutput:
+				return ebpf.ErrNotSupported
+			}
+			return nil
+		},
+		kernelVersion: func() (uint32, error) { return 5<<16 | 4<<8, nil },
+		probes: map[string]func() error{
+			types.FeatureBTF: func() error { return nil },
+		},
+		kernelSymbol: func(symbol string) error {
+			if symbol == "do_sys_openat2" {
+				return nil
+			}
+			return fmt.Errorf("kernel symbol %q not found", symbol)
+		},
+		tracepoint: func(category, name string) error {
+			return fmt.Errorf("%w: tracefs isn't mounted", errCantCheck)
+		},
+		btfFunc: func(name string) error {
+			return fmt.Errorf("%w: kernel BTF information isn't available", errCantCheck)
+		},
+		cgroupV2: func() error {
+			return errCgroupV1Only
+		},
+	}
+
+	requirements := types.Requirements{
+		KernelVersion: "5.8",
+		Features:      []string{types.FeatureBTF},
+	}
+
+	checks := checkCompat(spec, requirements, kf)
+
+	type result struct {
+		status   CompatStatus
+		programs []string
+	}
+	results := make(map[string]result)
+	for _, check := range checks {
+		results[check.Kind+" "+check.Name] = result{status: check.Status, programs: check.Programs}
+	}
+
+	require.Equal(t, map[string]result{
+		"requirement kernel 5.8":                            {status: CompatUnsupported},
+		"requirement btf":                                   {status: CompatSupported},
+		"map type RingBuf":                                  {status: CompatUnsupported},
+		"map type Hash":                                     {status: CompatSupported},
+		"program type Kprobe":                               {status: CompatSupported, programs: []string{"ig_close", "ig_open", "ig_ssl"}},
+		"program type TracePoint":                           {status: CompatSupported, programs: []string{"ig_exec"}},
+		"program type Tracing":                              {status: CompatUnsupported, programs: []string{"ig_fentry"}},
+		"program type CGroupSKB":                            {status: CompatSupported, programs: []string{"ig_skb"}},
+		"helper bpf_ringbuf_output":                         {status: CompatUnsupported, programs: []string{"ig_close", "ig_open"}},
+		"helper bpf_ktime_get_boot_ns":                      {status: CompatSupported, programs: []string{"ig_open"}},
+		"helper bpf_get_current_pid_tgid":                   {status: CompatSupported, programs: []string{"ig_exec"}},
+		"attach point kprobe/do_sys_openat2":                {status: CompatSupported, programs: []string{"ig_open"}},
+		"attach point kprobe/close_fd_new":                  {status: CompatUnsupported, programs: []string{"ig_close"}},
+		"attach point tracepoint/syscalls/sys_enter_execve": {status: CompatUnknown, programs: []string{"ig_exec"}},
+		"attach point uprobe/libssl:SSL_write":              {status: CompatUnknown, programs: []string{"ig_ssl"}},
+		"attach point fentry/tcp_connect":                   {status: CompatUnknown, programs: []string{"ig_fentry"}},
+		"attach point cgroup_skb/ingress":                   {status: CompatUnsupported, programs: []string{"ig_skb"}},
+	}, results)
+
+	// The requirements are first, then the maps and the programs
+	require.Equal(t, CompatKindRequirement, checks[0].Kind)
+	require.Equal(t, CompatKindMapType, checks[2].Kind)
+}
+
+func TestHelperName(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "bpf_ringbuf_output", helperName(asm.FnRingbufOutput))
+	require.Equal(t, "bpf_get_current_pid_tgid", helperName(asm.FnGetCurrentPidTgid))
+	require.Equal(t, "bpf_ktime_get_boot_ns", helperName(asm.FnKtimeGetBootNs))
+}
+
+func TestFeatureResult(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, featureResult(nil))
+	require.ErrorIs(t, featureResult(fmt.Errorf("probing: %w", ebpf.ErrNotSupported)), ebpf.ErrNotSupported)
+	require.ErrorIs(t, featureResult(errors.New("no probe for tracing programs")), errCantCheck)
+}
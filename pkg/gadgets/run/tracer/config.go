@@ -0,0 +1,132 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+// encodeConfig returns the value of the gadget_config map with the fields of
+// config set from the params in fields, by field name
+func encodeConfig(config *btf.Struct, fields map[string]*params.Param) ([]byte, error) {
+	buf := make([]byte, config.Size)
+	for _, member := range config.Members {
+		p, ok := fields[member.Name]
+		if !ok {
+			continue
+		}
+		value, err := ebpfConstValue(member.Type, p)
+		if err != nil {
+			return nil, fmt.Errorf("setting param %q: %w", p.Key, err)
+		}
+
+		size, err := btf.Sizeof(member.Type)
+		if err != nil {
+			return nil, fmt.Errorf("getting size of field %q: %w", member.Name, err)
+		}
+		var fieldBuf bytes.Buffer
+		if err := binary.Write(&fieldBuf, binary.NativeEndian, value); err != nil {
+			return nil, fmt.Errorf("encoding param %q: %w", p.Key, err)
+		}
+		if fieldBuf.Len() != size {
+			return nil, fmt.Errorf("param %q has %d bytes, field %q has %d", p.Key, fieldBuf.Len(), member.Name, size)
+		}
+
+		offset := member.Offset.Bytes()
+		copy(buf[offset:offset+uint32(size)], fieldBuf.Bytes())
+	}
+	return buf, nil
+}
+
+// writeConfig puts the current values of the params of the gadget_config map
+// in it
+func (t *Tracer) writeConfig() error {
+	if len(t.configParams) == 0 {
+		return nil
+	}
+	config, err := types.GetConfigStruct(t.spec)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]*params.Param, len(t.configParams))
+	for field, key := range t.configParams {
+		fields[field] = t.gadgetParams.Get(key)
+	}
+	value, err := encodeConfig(config, fields)
+	if err != nil {
+		return err
+	}
+
+	m, ok := t.collection.Maps[types.ConfigMapName]
+	if !ok {
+		return fmt.Errorf("map %q not found", types.ConfigMapName)
+	}
+	if err := m.Put(uint32(0), value); err != nil {
+		return fmt.Errorf("updating map %q: %w", types.ConfigMapName, err)
+	}
+	return nil
+}
+
+// UpdateParams changes the params of the running gadget. Only the params of
+// the gadget_config map can be changed, none is changed if any of values is
+// invalid.
+func (t *Tracer) UpdateParams(values map[string]string) error {
+	t.pauseMu.Lock()
+	defer t.pauseMu.Unlock()
+
+	if t.gadgetCtx == nil {
+		return errNotRunning
+	}
+
+	configKeys := make(map[string]struct{}, len(t.configParams))
+	for _, key := range t.configParams {
+		configKeys[key] = struct{}{}
+	}
+	for key, value := range values {
+		if _, ok := configKeys[key]; !ok {
+			return fmt.Errorf("param %q can't be changed while the gadget runs", key)
+		}
+		if err := t.gadgetParams.Get(key).Validate(value); err != nil {
+			return fmt.Errorf("invalid value %q for param %q: %w", value, key, err)
+		}
+	}
+
+	previous := make(map[string]string, len(values))
+	for key, value := range values {
+		p := t.gadgetParams.Get(key)
+		previous[key] = p.String()
+		if err := p.Set(value); err != nil {
+			return fmt.Errorf("setting param %q: %w", key, err)
+		}
+	}
+	if err := t.writeConfig(); err != nil {
+		// Keep the params in sync with the map
+		for key, value := range previous {
+			t.gadgetParams.Get(key).Set(value)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+func TestEncodeConfig(t *testing.T) {
+	t.Parallel()
+
+	duration := &btf.Typedef{Name: "gadget_duration", Type: &btf.Int{Name: "__u64", Size: 8}}
+	u8 := &btf.Int{Name: "unsigned char", Size: 1}
+	config := &btf.Struct{
+		Name: "config",
+		Size: 24,
+		Members: []btf.Member{
+			{Name: "min_latency", Type: duration, Offset: 0},
+			{Name: "verbose", Type: &btf.Int{Name: "bool", Size: 1, Encoding: btf.Bool}, Offset: 64},
+			{Name: "comm", Type: &btf.Array{Type: u8, Nelems: 4}, Offset: 72},
+			{Name: "pid", Type: &btf.Int{Name: "__u32", Size: 4}, Offset: 128},
+		},
+	}
+
+	newParam := func(key string, typ btf.Type, value string) *params.Param {
+		p := (&params.ParamDesc{Key: key, TypeHint: getTypeHint(typ)}).ToParam()
+		require.NoError(t, p.Set(value))
+		return p
+	}
+
+	fields := map[string]*params.Param{
+		"min_latency": newParam("min-latency", duration, "5ms"),
+		"verbose":     newParam("verbose", config.Members[1].Type, "true"),
+		"comm":        newParam("comm", config.Members[2].Type, "sh"),
+	}
+	value, err := encodeConfig(config, fields)
+	require.NoError(t, err)
+
+	expected := make([]byte, 24)
+	binary.NativeEndian.PutUint64(expected[0:], uint64(5*time.Millisecond))
+	expected[8] = 1
+	copy(expected[9:], "sh")
+	// pid isn't set by any param
+	require.Equal(t, expected, value)
+
+	fields["comm"] = newParam("comm", config.Members[2].Type, "bash")
+	_, err = encodeConfig(config, fields)
+	require.ErrorContains(t, err, "\"bash\" is too long")
+}
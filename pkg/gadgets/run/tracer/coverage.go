@@ -0,0 +1,81 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/cilium/ebpf"
+	"golang.org/x/sys/unix"
+)
+
+// programCoverage counts how many times the programs of a gadget ran, to
+// report the ones attached to points that never trigger on this kernel. The
+// kernel only counts the runs while the statistics are enabled, for all the
+// programs of the node.
+type programCoverage struct {
+	stats    io.Closer
+	programs map[string]*ebpf.Program
+}
+
+func newProgramCoverage() (*programCoverage, error) {
+	stats, err := ebpf.EnableStats(uint32(unix.BPF_STATS_RUN_TIME))
+	if err != nil {
+		return nil, fmt.Errorf("enabling statistics of eBPF programs: %w", err)
+	}
+	return &programCoverage{
+		stats:    stats,
+		programs: make(map[string]*ebpf.Program),
+	}, nil
+}
+
+// add counts the runs of prog
+func (c *programCoverage) add(name string, prog *ebpf.Program) {
+	c.programs[name] = prog
+}
+
+// runCounts returns how many times each program ran. The programs whose
+// count can't be read are left out.
+func (c *programCoverage) runCounts() map[string]uint64 {
+	counts := make(map[string]uint64, len(c.programs))
+	for name, prog := range c.programs {
+		info, err := prog.Info()
+		if err != nil {
+			continue
+		}
+		if count, ok := info.RunCount(); ok {
+			counts[name] = count
+		}
+	}
+	return counts
+}
+
+func (c *programCoverage) Close() {
+	c.stats.Close()
+}
+
+// zeroHitPrograms returns the programs that never ran, sorted by name
+func zeroHitPrograms(counts map[string]uint64) []string {
+	var programs []string
+	for name, count := range counts {
+		if count == 0 {
+			programs = append(programs, name)
+		}
+	}
+	sort.Strings(programs)
+	return programs
+}
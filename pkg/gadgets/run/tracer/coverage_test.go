@@ -0,0 +1,33 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestZeroHitPrograms(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, zeroHitPrograms(nil))
+	require.Empty(t, zeroHitPrograms(map[string]uint64{"ig_open": 3}))
+	require.Equal(t, []string{"ig_close", "ig_exec"}, zeroHitPrograms(map[string]uint64{
+		"ig_open":  3,
+		"ig_exec":  0,
+		"ig_close": 0,
+	}))
+}
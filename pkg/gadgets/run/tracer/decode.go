@@ -0,0 +1,188 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"sync"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+const (
+	// maxDecodeWorkers is the maximum number of goroutines decoding events
+	maxDecodeWorkers = 128
+
+	// decodeQueueLen is the number of raw samples that can wait to be
+	// decoded before the reader blocks
+	decodeQueueLen = maxEventBatchSize
+)
+
+type decodeJob struct {
+	rawSample []byte
+	// stream tags the decoded event if set
+	stream string
+	// result receives the decoded event in ordered mode
+	result chan *types.Event
+}
+
+// decodePool decodes raw samples on several goroutines. The decoded events are
+// delivered from a single goroutine, so deliver doesn't need to be safe for
+// concurrent use. In ordered mode, events are delivered in the order the raw
+// samples were submitted; otherwise, as soon as they are decoded.
+type decodePool struct {
+	decode  func([]byte) *types.Event
+	deliver func([]*types.Event)
+	ordered bool
+
+	jobs chan decodeJob
+	// events contains the decoded events in unordered mode
+	events chan *types.Event
+	// pending contains the results of the jobs in submission order in
+	// ordered mode
+	pending chan chan *types.Event
+
+	workers sync.WaitGroup
+	done    chan struct{}
+}
+
+func newDecodePool(
+	workers int,
+	ordered bool,
+	decode func([]byte) *types.Event,
+	deliver func([]*types.Event),
+) *decodePool {
+	p := &decodePool{
+		decode:  decode,
+		deliver: deliver,
+		ordered: ordered,
+		jobs:    make(chan decodeJob, decodeQueueLen),
+		done:    make(chan struct{}),
+	}
+	if ordered {
+		p.pending = make(chan chan *types.Event, decodeQueueLen)
+	} else {
+		p.events = make(chan *types.Event, decodeQueueLen)
+	}
+
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	go p.emit()
+
+	return p
+}
+
+func (p *decodePool) work() {
+	defer p.workers.Done()
+
+	for job := range p.jobs {
+		ev := p.decode(job.rawSample)
+		if job.stream != "" {
+			ev.Stream = job.stream
+		}
+		if job.result != nil {
+			job.result <- ev
+		} else {
+			p.events <- ev
+		}
+	}
+}
+
+// emit delivers the decoded events. Events that are already decoded when
+// one is delivered are grouped with it, up to maxEventBatchSize.
+func (p *decodePool) emit() {
+	defer close(p.done)
+
+	batch := make([]*types.Event, 0, maxEventBatchSize)
+
+	if p.ordered {
+		for result := range p.pending {
+			batch = append(batch, <-result)
+		drainOrdered:
+			for len(batch) < maxEventBatchSize {
+				select {
+				case result, ok := <-p.pending:
+					if !ok {
+						break drainOrdered
+					}
+					// The job was already submitted, wait for it to keep the
+					// order
+					batch = append(batch, <-result)
+				default:
+					break drainOrdered
+				}
+			}
+			p.deliver(batch)
+			batch = batch[:0]
+		}
+		return
+	}
+
+	for ev := range p.events {
+		batch = append(batch, ev)
+	drainUnordered:
+		for len(batch) < maxEventBatchSize {
+			select {
+			case ev, ok := <-p.events:
+				if !ok {
+					break drainUnordered
+				}
+				batch = append(batch, ev)
+			default:
+				break drainUnordered
+			}
+		}
+		p.deliver(batch)
+		batch = batch[:0]
+	}
+}
+
+// submit queues rawSample to be decoded, its event is tagged with stream if
+// it's set. It blocks if the queue is full.
+func (p *decodePool) submit(rawSample []byte, stream string) {
+	job := decodeJob{rawSample: rawSample, stream: stream}
+	if p.ordered {
+		job.result = make(chan *types.Event, 1)
+		p.pending <- job.result
+	}
+	p.jobs <- job
+}
+
+// submitEvent queues ev, an event that doesn't need to be decoded, like the
+// notices of lost events. In ordered mode, it's delivered after the events of
+// the samples submitted before it.
+func (p *decodePool) submitEvent(ev *types.Event) {
+	if !p.ordered {
+		p.events <- ev
+		return
+	}
+	result := make(chan *types.Event, 1)
+	result <- ev
+	p.pending <- result
+}
+
+// close waits until all the submitted samples are decoded and delivered.
+// submit must not be called afterwards.
+func (p *decodePool) close() {
+	close(p.jobs)
+	p.workers.Wait()
+	if p.ordered {
+		close(p.pending)
+	} else {
+		close(p.events)
+	}
+	<-p.done
+}
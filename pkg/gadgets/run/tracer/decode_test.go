@@ -0,0 +1,133 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+func TestDecodePool(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		workers int
+		ordered bool
+	}
+
+	tests := map[string]testDefinition{
+		"single_worker": {
+			workers: 1,
+			ordered: true,
+		},
+		"ordered": {
+			workers: 8,
+			ordered: true,
+		},
+		"unordered": {
+			workers: 8,
+			ordered: false,
+		},
+	}
+
+	const samples = 1000
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			decode := func(data []byte) *types.Event {
+				seq := binary.LittleEndian.Uint32(data)
+				// Make later samples decode faster to mix the completion order
+				time.Sleep(time.Duration(seq%4) * 10 * time.Microsecond)
+				return &types.Event{MountNsID: uint64(seq)}
+			}
+
+			// deliver is only called from a single goroutine, no locking is needed
+			got := []uint64{}
+			deliver := func(events []*types.Event) {
+				require.LessOrEqual(t, len(events), maxEventBatchSize)
+				for _, ev := range events {
+					got = append(got, ev.MountNsID)
+				}
+			}
+
+			pool := newDecodePool(test.workers, test.ordered, decode, deliver)
+			for i := 0; i < samples; i++ {
+				data := make([]byte, 4)
+				binary.LittleEndian.PutUint32(data, uint32(i))
+				pool.submit(data, "")
+			}
+			pool.close()
+
+			require.Len(t, got, samples)
+			if !test.ordered {
+				sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			}
+			for i, seq := range got {
+				require.Equal(t, uint64(i), seq)
+			}
+		})
+	}
+}
+
+func TestDecodePoolSubmitEvent(t *testing.T) {
+	t.Parallel()
+
+	decode := func(data []byte) *types.Event {
+		seq := binary.LittleEndian.Uint32(data)
+		time.Sleep(time.Duration(seq%4) * 10 * time.Microsecond)
+		return &types.Event{MountNsID: uint64(seq)}
+	}
+
+	got := []*types.Event{}
+	deliver := func(events []*types.Event) {
+		got = append(got, events...)
+	}
+
+	pool := newDecodePool(8, true, decode, deliver)
+	notices := map[int]*types.Event{}
+	for i := 0; i < 100; i++ {
+		// Notices are submitted before every tenth sample
+		if i%10 == 0 {
+			notices[i] = &types.Event{Dropped: uint64(i)}
+			pool.submitEvent(notices[i])
+		}
+		data := make([]byte, 4)
+		binary.LittleEndian.PutUint32(data, uint32(i))
+		pool.submit(data, "")
+	}
+	pool.close()
+
+	require.Len(t, got, 110)
+	i := 0
+	for _, ev := range got {
+		if notice, ok := notices[i]; ok && ev == notice {
+			delete(notices, i)
+			continue
+		}
+		require.Empty(t, notices[i], "notice %d delivered after its sample", i)
+		require.Equal(t, uint64(i), ev.MountNsID)
+		i++
+	}
+	require.Empty(t, notices)
+}
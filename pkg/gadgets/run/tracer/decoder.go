@@ -0,0 +1,131 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cilium/ebpf/btf"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
+)
+
+// Decoder decodes the raw samples sent by the eBPF programs of a gadget into
+// events, the same way as when the gadget runs, but without loading it. It's
+// meant to test gadgets where there is no kernel to run them.
+type Decoder struct {
+	info      *types.GadgetInfo
+	eventType *btf.Struct
+	columns   *columns.Columns[types.Event]
+	decode    func(data []byte) *types.Event
+}
+
+// NewDecoder returns a decoder for the gadget with the given eBPF object and
+// metadata, which can be empty to generate it from the eBPF object.
+// paramValues are the values of the parameters of the run gadget, e.g. stream
+// or redact, the default ones are used for the others.
+func NewDecoder(progContent, metadata []byte, paramValues map[string]string, logger logger.Logger) (*Decoder, error) {
+	g := &GadgetDesc{}
+	params := g.ParamDescs().ToParams()
+	for key, value := range paramValues {
+		if err := params.Set(key, value); err != nil {
+			return nil, fmt.Errorf("setting parameter %q: %w", key, err)
+		}
+	}
+
+	if len(metadata) == 0 {
+		// Like images without metadata
+		metadata = ocispec.DescriptorEmptyJSON.Data
+	}
+
+	info, err := gadgetInfoFromImage(params, &oci.GadgetImage{
+		EbpfObject: progContent,
+		Metadata:   metadata,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("getting gadget info: %w", err)
+	}
+
+	cols, err := g.getColumns(info)
+	if err != nil {
+		return nil, fmt.Errorf("getting columns: %w", err)
+	}
+
+	t := &Tracer{
+		config: &Config{
+			ProgContent: info.ProgContent,
+			Metadata:    info.GadgetMetadata,
+		},
+		eventFactory: info.EventFactory,
+		stampCPU:     params.Get(stampCPUParam).AsBool(),
+	}
+	t.eventType, err = getEventTypeBTF(info.ProgContent, info.GadgetMetadata, params.Get(streamParam).AsString())
+	if err != nil {
+		return nil, err
+	}
+	t.redactors, err = newFieldRedactors(t.eventType, info.GadgetMetadata, params.Get(redactParam).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("redacting fields: %w", err)
+	}
+	if len(t.redactors) > 0 {
+		t.redactKey, err = newRedactKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.redactionConfig = redaction.CurrentConfig(); t.redactionConfig != nil {
+		t.stringFields = newStringFields(t.eventType)
+	}
+
+	return &Decoder{
+		info:      info,
+		eventType: t.eventType,
+		columns:   cols,
+		decode:    t.processEventFunc(logger),
+	}, nil
+}
+
+// Info returns the information of the gadget, as sent to the clients
+func (d *Decoder) Info() *types.GadgetInfo {
+	return d.info
+}
+
+// EventType returns the type of the samples sent by the eBPF programs
+func (d *Decoder) EventType() *btf.Struct {
+	return d.eventType
+}
+
+// Columns returns the columns of the events, as used by the clients
+func (d *Decoder) Columns() *columns.Columns[types.Event] {
+	return d.columns
+}
+
+// Decode decodes sample into an event. sample isn't modified.
+func (d *Decoder) Decode(sample []byte) (*types.Event, error) {
+	if len(sample) != int(d.eventType.Size) {
+		return nil, fmt.Errorf("sample has %d bytes, expected %d for struct %s",
+			len(sample), d.eventType.Size, d.eventType.Name)
+	}
+	// The event keeps the data, which is redacted in place
+	return d.decode(slices.Clone(sample)), nil
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/btf"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,7 +35,9 @@ func getAnyMapElem[K comparable, V any](m map[K]V) (*K, *V) {
 	return nil, nil
 }
 
-func getEventTypeBTF(progContent []byte, metadata *types.GadgetMetadata) (*btf.Struct, error) {
+// getEventTypeBTF returns the struct of the events sent by the gadget, for gadgets
+// with several tracers the one of stream
+func getEventTypeBTF(progContent []byte, metadata *types.GadgetMetadata, stream string) (*btf.Struct, error) {
 	spec, err := loadSpec(progContent)
 	if err != nil {
 		return nil, err
@@ -42,10 +45,14 @@ func getEventTypeBTF(progContent []byte, metadata *types.GadgetMetadata) (*btf.S
 
 	switch {
 	case len(metadata.Tracers) > 0:
-		_, tracer := getAnyMapElem(metadata.Tracers)
+		// All the streams read at once send the same struct
+		_, tracers, err := metadata.SelectTracers(stream)
+		if err != nil {
+			return nil, err
+		}
 		var valueStruct *btf.Struct
-		if err := spec.Types.TypeByName(tracer.StructName, &valueStruct); err != nil {
-			return nil, fmt.Errorf("finding struct %q in eBPF object: %w", tracer.StructName, err)
+		if err := spec.Types.TypeByName(tracers[0].StructName, &valueStruct); err != nil {
+			return nil, fmt.Errorf("finding struct %q in eBPF object: %w", tracers[0].StructName, err)
 		}
 
 		return valueStruct, nil
@@ -56,6 +63,13 @@ func getEventTypeBTF(progContent []byte, metadata *types.GadgetMetadata) (*btf.S
 			return nil, err
 		}
 		return btfStruct, nil
+	case len(metadata.Aggregators) > 0:
+		var btfStruct *btf.Struct
+		_, aggregator := getAnyMapElem(metadata.Aggregators)
+		if err := spec.Types.TypeByName(aggregator.StructName, &btfStruct); err != nil {
+			return nil, fmt.Errorf("finding struct %q in eBPF object: %w", aggregator.StructName, err)
+		}
+		return btfStruct, nil
 	default:
 		return nil, fmt.Errorf("the gadget doesn't provide any compatible way to show information")
 	}
@@ -75,3 +89,22 @@ func getPullSecret(pullSecretString string, gadgetNamespace string) ([]byte, err
 	}
 	return gps.Data[corev1.DockerConfigJsonKey], nil
 }
+
+// hasConstant returns whether the eBPF program declares the constant name in
+// its .rodata section.
+func hasConstant(spec *ebpf.CollectionSpec, name string) bool {
+	rodata, ok := spec.Maps[".rodata"]
+	if !ok {
+		return false
+	}
+	ds, ok := rodata.Value.(*btf.Datasec)
+	if !ok {
+		return false
+	}
+	for _, v := range ds.Vars {
+		if v.Type.TypeName() == name {
+			return true
+		}
+	}
+	return false
+}
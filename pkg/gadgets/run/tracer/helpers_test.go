@@ -0,0 +1,46 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasConstant(t *testing.T) {
+	t.Parallel()
+
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			".rodata": {
+				Name: ".rodata",
+				Type: ebpf.Array,
+				Value: &btf.Datasec{
+					Name: ".rodata",
+					Vars: []btf.VarSecinfo{
+						{Type: &btf.Var{Name: "gadget_ringbuf_wakeup_bytes"}},
+					},
+				},
+			},
+		},
+	}
+
+	require.True(t, hasConstant(spec, "gadget_ringbuf_wakeup_bytes"))
+	require.False(t, hasConstant(spec, "gadget_filter_by_mntns"))
+	require.False(t, hasConstant(&ebpf.CollectionSpec{}, "gadget_ringbuf_wakeup_bytes"))
+}
@@ -0,0 +1,264 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cilium/ebpf/perf"
+	"github.com/cilium/ebpf/ringbuf"
+)
+
+// bufferReader reads the samples of the ring buffer or perf buffer of a
+// stream
+type bufferReader interface {
+	// read returns the next sample, or the number of samples lost before it
+	// with a nil sample
+	read() ([]byte, uint64, error)
+	setDeadline(deadline time.Time)
+	close() error
+}
+
+type ringbufBufferReader struct {
+	reader *ringbuf.Reader
+}
+
+func (r *ringbufBufferReader) read() ([]byte, uint64, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("read ring buffer: %w", err)
+	}
+	return record.RawSample, 0, nil
+}
+
+func (r *ringbufBufferReader) setDeadline(deadline time.Time) {
+	r.reader.SetDeadline(deadline)
+}
+
+func (r *ringbufBufferReader) close() error {
+	return r.reader.Close()
+}
+
+type perfBufferReader struct {
+	reader *perf.Reader
+}
+
+func (r *perfBufferReader) read() ([]byte, uint64, error) {
+	record, err := r.reader.Read()
+	if err != nil {
+		return nil, 0, fmt.Errorf("read perf ring buffer: %w", err)
+	}
+	if record.LostSamples != 0 {
+		return nil, record.LostSamples, nil
+	}
+	return record.RawSample, 0, nil
+}
+
+func (r *perfBufferReader) setDeadline(deadline time.Time) {
+	r.reader.SetDeadline(deadline)
+}
+
+func (r *perfBufferReader) close() error {
+	return r.reader.Close()
+}
+
+// muxRecord is a sample read by a readerMux
+type muxRecord struct {
+	// stream is the name of the tracer the sample comes from
+	stream    string
+	rawSample []byte
+	// lost is the number of samples of stream lost before this record, whose
+	// rawSample is nil then
+	lost uint64
+}
+
+// readerMux reads the buffers of several streams as if they were a single
+// one. A single buffer is read directly; several buffers are read on a
+// goroutine each, their samples are returned in the order they're read. The
+// samples lost by all the streams are counted in the same place.
+type readerMux struct {
+	streams []string
+	readers []bufferReader
+	// pollInterval bounds the time the goroutines wait for samples, for the
+	// ring buffers that don't wake user space up on each sample
+	pollInterval time.Duration
+
+	startOnce sync.Once
+	records   chan muxRecord
+	errs      chan error
+	done      chan struct{}
+	closeOnce sync.Once
+
+	deadlineMu sync.Mutex
+	deadline   time.Time
+
+	lostMu sync.Mutex
+	lost   map[string]uint64
+}
+
+func newReaderMux(streams []string, readers []bufferReader, pollInterval time.Duration) *readerMux {
+	return &readerMux{
+		streams:      streams,
+		readers:      readers,
+		pollInterval: pollInterval,
+		records:      make(chan muxRecord, maxEventBatchSize),
+		errs:         make(chan error, len(readers)),
+		done:         make(chan struct{}),
+		lost:         make(map[string]uint64),
+	}
+}
+
+// start starts the goroutines reading the buffers when there are several
+func (m *readerMux) start() {
+	if len(m.readers) < 2 {
+		return
+	}
+	for i := range m.readers {
+		go m.readStream(m.streams[i], m.readers[i])
+	}
+}
+
+func (m *readerMux) readStream(stream string, reader bufferReader) {
+	for {
+		if m.pollInterval > 0 {
+			reader.setDeadline(time.Now().Add(m.pollInterval))
+		}
+		rawSample, lost, err := reader.read()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			m.errs <- fmt.Errorf("stream %q: %w", stream, err)
+			return
+		}
+
+		select {
+		case m.records <- muxRecord{stream: stream, rawSample: rawSample, lost: lost}:
+		case <-m.done:
+			return
+		}
+	}
+}
+
+// read returns the next record. It returns os.ErrDeadlineExceeded if there
+// isn't any before the deadline set with setDeadline. The samples reported as
+// lost by the buffers are counted.
+func (m *readerMux) read() (muxRecord, error) {
+	record, err := m.next()
+	if err == nil && record.lost != 0 {
+		m.addLost(record.stream, record.lost)
+	}
+	return record, err
+}
+
+func (m *readerMux) next() (muxRecord, error) {
+	if len(m.readers) == 1 {
+		rawSample, lost, err := m.readers[0].read()
+		if err != nil {
+			return muxRecord{}, err
+		}
+		return muxRecord{stream: m.streams[0], rawSample: rawSample, lost: lost}, nil
+	}
+
+	m.startOnce.Do(m.start)
+
+	m.deadlineMu.Lock()
+	deadline := m.deadline
+	m.deadlineMu.Unlock()
+
+	// Records are returned before errors, so the samples read before a
+	// buffer is closed aren't lost
+	select {
+	case record := <-m.records:
+		return record, nil
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return muxRecord{}, os.ErrDeadlineExceeded
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case record := <-m.records:
+		return record, nil
+	case err := <-m.errs:
+		return muxRecord{}, err
+	case <-m.done:
+		return muxRecord{}, os.ErrClosed
+	case <-timeout:
+		return muxRecord{}, os.ErrDeadlineExceeded
+	}
+}
+
+// setDeadline sets how long read blocks waiting for records, a zero deadline
+// blocks until a record is available
+func (m *readerMux) setDeadline(deadline time.Time) {
+	if len(m.readers) == 1 {
+		m.readers[0].setDeadline(deadline)
+		return
+	}
+
+	m.deadlineMu.Lock()
+	m.deadline = deadline
+	m.deadlineMu.Unlock()
+}
+
+// addLost counts count samples of stream as lost, e.g. the ones detected with
+// their sequence numbers
+func (m *readerMux) addLost(stream string, count uint64) {
+	m.lostMu.Lock()
+	defer m.lostMu.Unlock()
+	m.lost[stream] += count
+}
+
+// lostSamples returns the number of samples lost by the streams that lost
+// some, sorted by stream, and their total
+func (m *readerMux) lostSamples() ([]string, uint64) {
+	m.lostMu.Lock()
+	defer m.lostMu.Unlock()
+
+	var total uint64
+	summary := make([]string, 0, len(m.lost))
+	for stream, count := range m.lost {
+		summary = append(summary, fmt.Sprintf("%s: %d", stream, count))
+		total += count
+	}
+	sort.Strings(summary)
+	return summary, total
+}
+
+// close closes the buffers, the pending and following reads return an error
+func (m *readerMux) close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+		for _, reader := range m.readers {
+			reader.close()
+		}
+	})
+}
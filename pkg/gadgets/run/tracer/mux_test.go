@@ -0,0 +1,155 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSample is a sample, or a number of lost samples, read by a fakeReader
+type fakeSample struct {
+	rawSample []byte
+	lost      uint64
+}
+
+// fakeReader returns the samples sent to its channel, like a buffer reader
+type fakeReader struct {
+	samples chan fakeSample
+	closed  chan struct{}
+	once    sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func newFakeReader() *fakeReader {
+	return &fakeReader{
+		samples: make(chan fakeSample, 16),
+		closed:  make(chan struct{}),
+	}
+}
+
+func (r *fakeReader) read() ([]byte, uint64, error) {
+	r.mu.Lock()
+	deadline := r.deadline
+	r.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case s := <-r.samples:
+		return s.rawSample, s.lost, nil
+	case <-r.closed:
+		return nil, 0, os.ErrClosed
+	case <-timeout:
+		return nil, 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (r *fakeReader) setDeadline(deadline time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deadline = deadline
+}
+
+func (r *fakeReader) close() error {
+	r.once.Do(func() { close(r.closed) })
+	return nil
+}
+
+func TestReaderMuxSingle(t *testing.T) {
+	t.Parallel()
+
+	reader := newFakeReader()
+	mux := newReaderMux([]string{"dns"}, []bufferReader{reader}, 0)
+	defer mux.close()
+
+	reader.samples <- fakeSample{rawSample: []byte("a")}
+	reader.samples <- fakeSample{lost: 3}
+
+	record, err := mux.read()
+	require.NoError(t, err)
+	require.Equal(t, muxRecord{stream: "dns", rawSample: []byte("a")}, record)
+
+	record, err = mux.read()
+	require.NoError(t, err)
+	require.Equal(t, muxRecord{stream: "dns", lost: 3}, record)
+
+	mux.setDeadline(time.Now())
+	_, err = mux.read()
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	summary, total := mux.lostSamples()
+	require.Equal(t, []string{"dns: 3"}, summary)
+	require.Equal(t, uint64(3), total)
+}
+
+func TestReaderMuxSeveral(t *testing.T) {
+	t.Parallel()
+
+	dns := newFakeReader()
+	mdns := newFakeReader()
+	mux := newReaderMux([]string{"dns", "mdns"}, []bufferReader{dns, mdns}, 10*time.Millisecond)
+
+	dns.samples <- fakeSample{rawSample: []byte("a")}
+	mdns.samples <- fakeSample{rawSample: []byte("b")}
+	mdns.samples <- fakeSample{lost: 2}
+	dns.samples <- fakeSample{lost: 1}
+
+	got := map[string][]string{}
+	for i := 0; i < 4; i++ {
+		record, err := mux.read()
+		require.NoError(t, err)
+		if record.rawSample != nil {
+			got[record.stream] = append(got[record.stream], string(record.rawSample))
+		}
+	}
+	require.Equal(t, map[string][]string{"dns": {"a"}, "mdns": {"b"}}, got)
+
+	// Lost events detected another way are counted with the ones reported by
+	// the buffers
+	mux.addLost("dns", 4)
+	summary, total := mux.lostSamples()
+	require.Equal(t, []string{"dns: 5", "mdns: 2"}, summary)
+	require.Equal(t, uint64(7), total)
+
+	mux.setDeadline(time.Now().Add(20 * time.Millisecond))
+	_, err := mux.read()
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// The goroutines keep reading while read isn't called
+	mux.setDeadline(time.Time{})
+	mdns.samples <- fakeSample{rawSample: []byte("c")}
+	record, err := mux.read()
+	require.NoError(t, err)
+	require.Equal(t, muxRecord{stream: "mdns", rawSample: []byte("c")}, record)
+
+	mux.close()
+	_, err = mux.read()
+	require.True(t, errors.Is(err, os.ErrClosed))
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+)
+
+// errNotRunning is returned when pausing or resuming a gadget whose programs
+// aren't attached yet, or anymore
+var errNotRunning = errors.New("gadget isn't running")
+
+// isPausedWithLink returns whether p is paused by closing its link. Uprobes
+// and socket filters are paused by their tracers, and iterators and syscall
+// programs aren't paused as they only run when the gadget starts.
+func isPausedWithLink(p *ebpf.ProgramSpec) bool {
+	switch {
+	case isUprobe(p), p.Type == ebpf.SocketFilter:
+		return false
+	case p.Type == ebpf.Tracing && sectionKind(p) == "iter", isSyscallProgram(p):
+		return false
+	}
+	return true
+}
+
+// Pause detaches the programs of the gadget, so it stops producing events.
+// Its maps and readers are kept, so the events already sent and the state of
+// the gadget aren't lost.
+func (t *Tracer) Pause() error {
+	t.pauseMu.Lock()
+	defer t.pauseMu.Unlock()
+
+	if t.gadgetCtx == nil {
+		return errNotRunning
+	}
+	if t.paused {
+		return nil
+	}
+
+	for name, l := range t.links {
+		if !isPausedWithLink(t.spec.Programs[name]) {
+			continue
+		}
+		gadgets.CloseLink(l)
+		delete(t.links, name)
+	}
+	for name, networkTracer := range t.networkTracers {
+		if err := networkTracer.DetachProg(); err != nil {
+			return fmt.Errorf("detaching socket filter %q: %w", name, err)
+		}
+	}
+	if t.uprobeTracer != nil {
+		t.uprobeTracer.Pause()
+	}
+
+	t.paused = true
+	t.gadgetCtx.Logger().Infof("Gadget paused")
+	return nil
+}
+
+// Resume attaches again the programs detached by Pause
+func (t *Tracer) Resume() error {
+	t.pauseMu.Lock()
+	defer t.pauseMu.Unlock()
+
+	if t.gadgetCtx == nil {
+		return errNotRunning
+	}
+	if !t.paused {
+		return nil
+	}
+
+	for name, p := range t.spec.Programs {
+		if _, ok := t.links[name]; ok || !isPausedWithLink(p) || t.isTailCallTarget(name) {
+			continue
+		}
+		l, err := t.attachProgram(t.gadgetCtx, p, t.collection.Programs[name])
+		if err != nil {
+			return fmt.Errorf("attaching eBPF program %q: %w", name, err)
+		}
+		if l != nil {
+			t.links[name] = l
+		}
+	}
+	for name, networkTracer := range t.networkTracers {
+		if err := networkTracer.AttachProg(t.collection.Programs[name]); err != nil {
+			return fmt.Errorf("attaching socket filter %q: %w", name, err)
+		}
+	}
+	if t.uprobeTracer != nil {
+		if err := t.uprobeTracer.Resume(); err != nil {
+			return err
+		}
+	}
+
+	t.paused = false
+	t.gadgetCtx.Logger().Infof("Gadget resumed")
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPausedWithLink(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		spec     *ebpf.ProgramSpec
+		expected bool
+	}
+
+	tests := map[string]testDefinition{
+		"kprobe": {
+			spec:     &ebpf.ProgramSpec{Type: ebpf.Kprobe, SectionName: "kprobe/do_sys_openat2"},
+			expected: true,
+		},
+		"tracepoint": {
+			spec:     &ebpf.ProgramSpec{Type: ebpf.TracePoint, SectionName: "tracepoint/syscalls/sys_enter_execve"},
+			expected: true,
+		},
+		"fentry": {
+			spec:     &ebpf.ProgramSpec{Type: ebpf.Tracing, SectionName: "fentry/tcp_connect"},
+			expected: true,
+		},
+		"cgroup": {
+			spec:     &ebpf.ProgramSpec{Type: ebpf.CGroupSKB, SectionName: "cgroup_skb/ingress"},
+			expected: true,
+		},
+		"uprobe": {
+			spec: &ebpf.ProgramSpec{Type: ebpf.Kprobe, SectionName: "uprobe/libc:malloc"},
+		},
+		"socket": {
+			spec: &ebpf.ProgramSpec{Type: ebpf.SocketFilter, SectionName: "socket1"},
+		},
+		"iter": {
+			spec: &ebpf.ProgramSpec{Type: ebpf.Tracing, SectionName: "iter/task"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, test.expected, isPausedWithLink(test.spec))
+		})
+	}
+}
+
+func TestPauseNotRunning(t *testing.T) {
+	t.Parallel()
+
+	tracer := &Tracer{}
+	require.ErrorIs(t, tracer.Pause(), errNotRunning)
+	require.ErrorIs(t, tracer.Resume(), errNotRunning)
+}
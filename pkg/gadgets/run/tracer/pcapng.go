@@ -0,0 +1,80 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/pcapng"
+)
+
+const OutputModePcapng = "pcapng"
+
+// packetComment returns the comment of the packets of ev, telling where they
+// were captured
+func packetComment(ev *types.Event) string {
+	switch {
+	case ev.K8s.PodName != "":
+		return fmt.Sprintf("node=%s namespace=%s pod=%s container=%s",
+			ev.K8s.Node, ev.K8s.Namespace, ev.K8s.PodName, ev.K8s.ContainerName)
+	case ev.Runtime.ContainerName != "":
+		return fmt.Sprintf("container=%s", ev.Runtime.ContainerName)
+	}
+	return ""
+}
+
+// newPcapngTransform returns a function writing the packets of the events
+// in the pcapng format. The first call also returns the header of the file.
+func newPcapngTransform() func(any) ([]byte, error) {
+	var buf bytes.Buffer
+	// The packets can be truncated by the gadget
+	w := pcapng.NewWriter(&buf, pcapng.LinkTypeEthernet, 0)
+
+	return func(data any) ([]byte, error) {
+		ev, ok := data.(*types.Event)
+		if !ok {
+			return nil, fmt.Errorf("type must be *types.Event and is: %T", data)
+		}
+
+		ts := time.Now()
+		if len(ev.Timestamps) > 0 {
+			ts = time.Unix(0, int64(ev.Timestamps[0]))
+		}
+		comment := packetComment(ev)
+
+		buf.Reset()
+		for _, packet := range ev.Packets {
+			if err := w.WritePacket(ts, packet.Data, packet.Len, comment); err != nil {
+				return nil, err
+			}
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+func (g *GadgetDesc) OutputFormats() (gadgets.OutputFormats, string) {
+	return gadgets.OutputFormats{
+		OutputModePcapng: gadgets.OutputFormat{
+			Name:        "pcapng",
+			Description: "The packets captured by the gadget in the pcapng format, e.g. to be opened with Wireshark",
+			Binary:      true,
+			Transform:   newPcapngTransform(),
+		},
+	}, "columns"
+}
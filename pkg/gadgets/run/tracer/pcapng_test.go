@@ -0,0 +1,76 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestPacketComment(t *testing.T) {
+	t.Parallel()
+
+	ev := &types.Event{}
+	require.Equal(t, "", packetComment(ev))
+
+	ev.Runtime.ContainerName = "test"
+	require.Equal(t, "container=test", packetComment(ev))
+
+	ev.K8s.Node = "node1"
+	ev.K8s.Namespace = "default"
+	ev.K8s.PodName = "mypod"
+	ev.K8s.ContainerName = "nginx"
+	require.Equal(t, "node=node1 namespace=default pod=mypod container=nginx", packetComment(ev))
+}
+
+func TestPcapngTransform(t *testing.T) {
+	t.Parallel()
+
+	transform := newPcapngTransform()
+
+	_, err := transform("not an event")
+	require.Error(t, err)
+
+	ev := &types.Event{
+		Timestamps: []eventtypes.Time{1700000000123456789},
+		Packets: []types.Packet{
+			{Name: "packet", Len: 1500, Data: bytes.Repeat([]byte{0xab}, 96)},
+		},
+	}
+
+	// Section header, interface description and enhanced packet blocks
+	out, err := transform(ev)
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x0A0D0D0A), binary.LittleEndian.Uint32(out))
+
+	// Only the enhanced packet block
+	out, err = transform(ev)
+	require.NoError(t, err)
+	require.Equal(t, uint32(6), binary.LittleEndian.Uint32(out))
+	require.Equal(t, uint32(1700000000123456789>>32), binary.LittleEndian.Uint32(out[12:]))
+	require.Equal(t, uint32(96), binary.LittleEndian.Uint32(out[20:]))
+	require.Equal(t, uint32(1500), binary.LittleEndian.Uint32(out[24:]))
+
+	// Events without packets don't write anything
+	out, err = transform(&types.Event{})
+	require.NoError(t, err)
+	require.Empty(t, out)
+}
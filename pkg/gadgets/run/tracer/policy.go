@@ -0,0 +1,105 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgetpolicy"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/internal/socketenricher"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// sectionKind returns the kind of section of the program, e.g. uprobe for
+// uprobe/libc:malloc or socket for socket1
+func sectionKind(p *ebpf.ProgramSpec) string {
+	kind, _, _ := strings.Cut(p.SectionName, "/")
+	return strings.TrimRight(kind, "0123456789")
+}
+
+// policyProgram describes p for the gadget policy
+func policyProgram(p *ebpf.ProgramSpec) gadgetpolicy.Program {
+	return gadgetpolicy.Program{
+		Name:         p.Name,
+		Type:         strings.ToLower(p.Type.String()),
+		Section:      sectionKind(p),
+		AttachTo:     p.AttachTo,
+		Instructions: len(p.Instructions),
+	}
+}
+
+// mapMemory estimates the memory used by the entries of m. The buffers of
+// perf event arrays aren't counted, their size is set by the user.
+func mapMemory(m *ebpf.MapSpec, possibleCPUs int) uint64 {
+	entries := uint64(m.MaxEntries)
+	value := uint64(m.ValueSize)
+
+	switch m.Type {
+	case ebpf.RingBuf:
+		// max_entries is the size of the ring buffer
+		return entries
+	case ebpf.PerfEventArray:
+		return 0
+	case ebpf.PerCPUArray, ebpf.PerCPUHash, ebpf.LRUCPUHash:
+		value *= uint64(possibleCPUs)
+	}
+
+	switch m.Type {
+	case ebpf.Array, ebpf.PerCPUArray:
+		// Arrays don't store their keys
+		return entries * value
+	default:
+		return entries * (uint64(m.KeySize) + value)
+	}
+}
+
+// policyGadget describes the gadget of image for the gadget policy. The maps
+// shared by all the gadgets aren't included.
+func policyGadget(image, digest string, spec *ebpf.CollectionSpec, possibleCPUs int) *gadgetpolicy.Gadget {
+	g := &gadgetpolicy.Gadget{
+		Image:  image,
+		Digest: digest,
+	}
+	for _, p := range spec.Programs {
+		g.Programs = append(g.Programs, policyProgram(p))
+	}
+	for name, m := range spec.Maps {
+		switch name {
+		case socketenricher.SocketsMapName, gadgets.MntNsFilterMapName:
+			continue
+		}
+		g.Maps = append(g.Maps, gadgetpolicy.Map{Name: name, Memory: mapMemory(m, possibleCPUs)})
+	}
+	// Programs and maps are in maps, sort them to always report the same
+	// problems
+	sort.Slice(g.Programs, func(i, j int) bool {
+		return g.Programs[i].Name < g.Programs[j].Name
+	})
+	sort.Slice(g.Maps, func(i, j int) bool {
+		return g.Maps[i].Name < g.Maps[j].Name
+	})
+	return g
+}
+
+// isHostContainer returns whether container represents the processes of the
+// host, like the host pseudo-container
+func isHostContainer(container *containercollection.Container) bool {
+	return container.Runtime.RuntimeName == eventtypes.RuntimeNameHost || container.Pid == 1
+}
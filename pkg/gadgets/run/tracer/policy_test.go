@@ -0,0 +1,115 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/require"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgetpolicy"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestPolicyProgram(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		spec     *ebpf.ProgramSpec
+		expected gadgetpolicy.Program
+	}
+
+	tests := map[string]testDefinition{
+		"uprobe": {
+			spec:     &ebpf.ProgramSpec{Name: "ig_ssl", Type: ebpf.Kprobe, SectionName: "uprobe/libssl:SSL_write", AttachTo: "libssl:SSL_write"},
+			expected: gadgetpolicy.Program{Name: "ig_ssl", Type: "kprobe", Section: "uprobe", AttachTo: "libssl:SSL_write"},
+		},
+		"socket": {
+			spec:     &ebpf.ProgramSpec{Name: "ig_trace_dns", Type: ebpf.SocketFilter, SectionName: "socket1"},
+			expected: gadgetpolicy.Program{Name: "ig_trace_dns", Type: "socketfilter", Section: "socket"},
+		},
+		"xdp": {
+			spec:     &ebpf.ProgramSpec{Name: "ig_xdp", Type: ebpf.XDP, SectionName: "xdp"},
+			expected: gadgetpolicy.Program{Name: "ig_xdp", Type: "xdp", Section: "xdp"},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.expected, policyProgram(test.spec))
+		})
+	}
+}
+
+func TestMapMemory(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		spec     *ebpf.MapSpec
+		expected uint64
+	}
+
+	tests := map[string]testDefinition{
+		"hash": {
+			spec:     &ebpf.MapSpec{Type: ebpf.Hash, KeySize: 8, ValueSize: 24, MaxEntries: 1024},
+			expected: 1024 * 32,
+		},
+		"percpu_hash": {
+			spec:     &ebpf.MapSpec{Type: ebpf.PerCPUHash, KeySize: 8, ValueSize: 24, MaxEntries: 1024},
+			expected: 1024 * (8 + 24*4),
+		},
+		"percpu_array": {
+			spec:     &ebpf.MapSpec{Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: 512, MaxEntries: 1},
+			expected: 512 * 4,
+		},
+		"ringbuf": {
+			spec:     &ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: 256 * 1024},
+			expected: 256 * 1024,
+		},
+		"perf": {
+			spec:     &ebpf.MapSpec{Type: ebpf.PerfEventArray, KeySize: 4, ValueSize: 4},
+			expected: 0,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.expected, mapMemory(test.spec, 4))
+		})
+	}
+}
+
+func TestIsHostContainer(t *testing.T) {
+	t.Parallel()
+
+	host := &containercollection.Container{Pid: 1}
+	host.Runtime.RuntimeName = eventtypes.RuntimeNameHost
+	require.True(t, isHostContainer(host))
+
+	// Attached by the local manager when there is no container collection
+	require.True(t, isHostContainer(&containercollection.Container{Pid: 1}))
+
+	container := &containercollection.Container{Pid: 1234}
+	container.Runtime.RuntimeName = eventtypes.RuntimeNameContainerd
+	require.False(t, isHostContainer(container))
+}
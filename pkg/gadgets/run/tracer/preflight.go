@@ -0,0 +1,128 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+// featureProbes check whether the kernel supports the features a gadget can
+// require, they return an error explaining why it doesn't
+var featureProbes = map[string]func() error{
+	types.FeatureRingbuf: func() error {
+		if !isRingbufAvailable() {
+			return errors.New("ring buffers aren't supported")
+		}
+		return nil
+	},
+	types.FeatureBTF: func() error {
+		if _, err := btf.LoadKernelSpec(); err != nil {
+			return fmt.Errorf("kernel BTF information isn't available: %w", err)
+		}
+		return nil
+	},
+	types.FeatureFentry: func() error {
+		if _, err := btf.LoadKernelSpec(); err != nil {
+			return fmt.Errorf("fentry programs require kernel BTF information: %w", err)
+		}
+		if err := features.HaveProgramType(ebpf.Tracing); err != nil {
+			return fmt.Errorf("fentry programs aren't supported: %w", err)
+		}
+		return nil
+	},
+	types.FeatureKprobeMulti: probeKprobeMulti,
+	types.FeatureCgroupV2:    probeCgroupV2,
+	types.FeatureBPFTimer:    probeBPFTimer,
+}
+
+// probeKprobeMulti attaches a program doing nothing to a kprobe.multi link, as
+// cilium/ebpf doesn't expose its probe
+func probeKprobeMulti() error {
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:       ebpf.Kprobe,
+		AttachType: ebpf.AttachTraceKprobeMulti,
+		License:    "GPL",
+		Instructions: asm.Instructions{
+			asm.Mov.Imm(asm.R0, 0),
+			asm.Return(),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("kprobe.multi links aren't supported: %w", err)
+	}
+	defer prog.Close()
+
+	l, err := link.KprobeMulti(prog, link.KprobeMultiOptions{Symbols: []string{"vprintk"}})
+	if err != nil {
+		return fmt.Errorf("kprobe.multi links aren't supported: %w", err)
+	}
+	l.Close()
+	return nil
+}
+
+// checkRequirements checks the running kernel meets the requirements of the
+// gadget, it returns a single error with all the ones that aren't met
+func checkRequirements(requirements types.Requirements) error {
+	return checkRequirementsWith(requirements, features.LinuxVersionCode, featureProbes)
+}
+
+func checkRequirementsWith(
+	requirements types.Requirements,
+	kernelVersion func() (uint32, error),
+	probes map[string]func() error,
+) error {
+	var problems []string
+
+	if requirements.KernelVersion != "" {
+		required, err := types.ParseKernelVersion(requirements.KernelVersion)
+		if err != nil {
+			return err
+		}
+		current, err := kernelVersion()
+		if err != nil {
+			return fmt.Errorf("getting kernel version: %w", err)
+		}
+		if current < required {
+			problems = append(problems, fmt.Sprintf("kernel %s is older than the required %s",
+				types.KernelVersionString(current), requirements.KernelVersion))
+		}
+	}
+
+	for _, feature := range requirements.Features {
+		probe, ok := probes[feature]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown kernel feature %q", feature))
+			continue
+		}
+		if err := probe(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("kernel doesn't meet the requirements of the gadget: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
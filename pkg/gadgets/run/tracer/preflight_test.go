@@ -0,0 +1,91 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+func TestCheckRequirements(t *testing.T) {
+	t.Parallel()
+
+	kernelVersion := func() (uint32, error) { return 5<<16 | 4<<8 | 120, nil }
+	probes := map[string]func() error{
+		types.FeatureBTF:     func() error { return nil },
+		types.FeatureRingbuf: func() error { return errors.New("ring buffers aren't supported") },
+		types.FeatureFentry:  func() error { return errors.New("fentry programs aren't supported") },
+	}
+
+	type testDefinition struct {
+		requirements      types.Requirements
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"none": {},
+		"met": {
+			requirements: types.Requirements{
+				KernelVersion: "5.4",
+				Features:      []string{types.FeatureBTF},
+			},
+		},
+		"old_kernel": {
+			requirements: types.Requirements{
+				KernelVersion: "5.8",
+			},
+			expectedErrString: "kernel doesn't meet the requirements of the gadget: kernel 5.4.120 is older than the required 5.8",
+		},
+		"all_problems_at_once": {
+			requirements: types.Requirements{
+				KernelVersion: "5.10",
+				Features:      []string{types.FeatureRingbuf, types.FeatureBTF, types.FeatureFentry},
+			},
+			expectedErrString: "kernel doesn't meet the requirements of the gadget: " +
+				"kernel 5.4.120 is older than the required 5.10; " +
+				"ring buffers aren't supported; fentry programs aren't supported",
+		},
+		"unknown_feature": {
+			requirements: types.Requirements{
+				Features: []string{"foo"},
+			},
+			expectedErrString: "unknown kernel feature \"foo\"",
+		},
+		"invalid_version": {
+			requirements: types.Requirements{
+				KernelVersion: "latest",
+			},
+			expectedErrString: "invalid kernel version \"latest\"",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkRequirementsWith(test.requirements, kernelVersion, probes)
+			if test.expectedErrString != "" {
+				require.ErrorContains(t, err, test.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cilium/ebpf"
+)
+
+// rateLimitReportInterval is the interval at which the throttled events are
+// reported
+const rateLimitReportInterval = time.Second
+
+// rateLimitBucket is the token bucket of a mount namespace. Keep in sync with
+// struct gadget_ratelimit_bucket in include/gadget/ratelimit.h.
+type rateLimitBucket struct {
+	Tokens    uint64
+	Last      uint64
+	Throttled uint64
+}
+
+// rateLimiter reads the number of events throttled by
+// gadget_ratelimit_should_throttle() for each mount namespace
+type rateLimiter struct {
+	buckets *ebpf.Map
+	// reported is the number of throttled events already reported for each
+	// mount namespace
+	reported map[uint64]uint64
+}
+
+func newRateLimiter(buckets *ebpf.Map) *rateLimiter {
+	return &rateLimiter{
+		buckets:  buckets,
+		reported: make(map[uint64]uint64),
+	}
+}
+
+// throttled returns the number of events throttled for each mount namespace
+// since the last call
+func (r *rateLimiter) throttled() (map[uint64]uint64, error) {
+	current := make(map[uint64]uint64, len(r.reported))
+
+	var mntns uint64
+	var bucket rateLimitBucket
+	iter := r.buckets.Iterate()
+	for iter.Next(&mntns, &bucket) {
+		current[mntns] = bucket.Throttled
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("iterating rate limit buckets: %w", err)
+	}
+
+	deltas := throttledDeltas(r.reported, current)
+	r.reported = current
+	return deltas, nil
+}
+
+// throttledDeltas returns the number of events throttled for each mount
+// namespace between the counts of reported and current. A count lower than the
+// reported one means the bucket was evicted and created again, all its events
+// are new then.
+func throttledDeltas(reported, current map[uint64]uint64) map[uint64]uint64 {
+	deltas := make(map[uint64]uint64)
+	for mntns, count := range current {
+		prev := reported[mntns]
+		if count < prev {
+			prev = 0
+		}
+		if count > prev {
+			deltas[mntns] = count - prev
+		}
+	}
+	return deltas
+}
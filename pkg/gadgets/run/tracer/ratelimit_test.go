@@ -0,0 +1,60 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottledDeltas(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		reported map[uint64]uint64
+		current  map[uint64]uint64
+		expected map[uint64]uint64
+	}
+
+	tests := map[string]testDefinition{
+		"empty": {
+			expected: map[uint64]uint64{},
+		},
+		"new": {
+			current:  map[uint64]uint64{1: 5, 2: 0},
+			expected: map[uint64]uint64{1: 5},
+		},
+		"increased": {
+			reported: map[uint64]uint64{1: 5, 2: 3},
+			current:  map[uint64]uint64{1: 8, 2: 3},
+			expected: map[uint64]uint64{1: 3},
+		},
+		"evicted": {
+			reported: map[uint64]uint64{1: 5, 2: 3},
+			current:  map[uint64]uint64{1: 2},
+			expected: map[uint64]uint64{1: 2},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.expected, throttledDeltas(test.reported, test.current))
+		})
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"unsafe"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
+)
+
+type redactedKind int
+
+const (
+	redactedRaw redactedKind = iota
+	redactedString
+	// Only the address of endpoints is redacted, so they can still be
+	// enriched
+	redactedEndpoint
+)
+
+// fieldRedactor redacts a sensitive field of the events sent by the eBPF
+// program
+type fieldRedactor struct {
+	offset    uint32
+	size      uint32
+	kind      redactedKind
+	redaction types.Redaction
+}
+
+// newFieldRedactors returns the redactors of the fields of typ marked as
+// sensitive in the metadata or in the redact parameter, which overrides the
+// metadata
+func newFieldRedactors(typ *btf.Struct, metadata *types.GadgetMetadata, param string) ([]fieldRedactor, error) {
+	redactions, err := types.ParseRedactions(param)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range metadata.Structs[typ.Name].Fields {
+		if field.Redact == "" {
+			continue
+		}
+		if _, ok := redactions[field.Name]; ok {
+			continue
+		}
+		redaction, err := types.ParseRedaction(field.Redact)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field.Name, err)
+		}
+		redactions[field.Name] = redaction
+	}
+
+	redactors := make([]fieldRedactor, 0, len(redactions))
+	for name, redaction := range redactions {
+		idx := slices.IndexFunc(typ.Members, func(m btf.Member) bool { return m.Name == name })
+		if idx == -1 {
+			return nil, fmt.Errorf("field %q not found in eBPF struct %q", name, typ.Name)
+		}
+		member := typ.Members[idx]
+		if err := types.CheckRedaction(redaction, member.Type); err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+
+		redactor := fieldRedactor{
+			offset:    member.Offset.Bytes(),
+			redaction: redaction,
+		}
+		switch member.Type.TypeName() {
+		case types.L3EndpointTypeName, types.L4EndpointTypeName:
+			redactor.kind = redactedEndpoint
+			redactor.size = uint32(unsafe.Sizeof(l3EndpointT{}))
+		default:
+			size, err := btf.Sizeof(member.Type)
+			if err != nil {
+				return nil, fmt.Errorf("getting size of field %q: %w", name, err)
+			}
+			redactor.size = uint32(size)
+			if types.GetCharArray(member.Type) != nil {
+				redactor.kind = redactedString
+			}
+		}
+		redactors = append(redactors, redactor)
+	}
+
+	// Keep the order stable, maps aren't
+	slices.SortFunc(redactors, func(a, b fieldRedactor) int { return int(a.offset) - int(b.offset) })
+	return redactors, nil
+}
+
+// newRedactKey returns a random key for the hashes of the redacted fields, so
+// they can't be reversed by hashing well known values
+func newRedactKey() ([]byte, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("generating redaction key: %w", err)
+	}
+	return key, nil
+}
+
+func redactHash(key, value []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(value)
+	return mac.Sum(nil)
+}
+
+// redact redacts the field in the event in data, in place
+func (r *fieldRedactor) redact(data []byte, key []byte) {
+	if int(r.offset+r.size) > len(data) {
+		return
+	}
+	field := data[r.offset : r.offset+r.size]
+
+	switch r.kind {
+	case redactedString:
+		value := field
+		if i := bytes.IndexByte(value, 0); i != -1 {
+			value = value[:i]
+		}
+		switch r.redaction.Mode {
+		case types.RedactDrop:
+			clear(field)
+		case types.RedactHash:
+			if len(value) == 0 {
+				return
+			}
+			hash := hex.EncodeToString(redactHash(key, value))
+			n := copy(field[:len(field)-1], hash)
+			clear(field[n:])
+		case types.RedactTruncate:
+			clear(field[r.redaction.Length:])
+		}
+	case redactedEndpoint:
+		endpoint := (*l3EndpointT)(unsafe.Pointer(&field[0]))
+		addr := endpoint.addr[:]
+		if endpoint.version == 4 {
+			addr = addr[:4]
+		}
+		switch r.redaction.Mode {
+		case types.RedactDrop:
+			clear(addr)
+		case types.RedactHash:
+			copy(addr, redactHash(key, addr))
+		}
+	default:
+		switch r.redaction.Mode {
+		case types.RedactDrop:
+			clear(field)
+		case types.RedactHash:
+			n := copy(field, redactHash(key, field))
+			clear(field[n:])
+		}
+	}
+}
+
+// stringField is a string field of the events sent by the eBPF program, where
+// the values looking like secrets are masked according to the redaction
+// configuration of the daemon
+type stringField struct {
+	name   string
+	offset uint32
+	size   uint32
+}
+
+// newStringFields returns the string fields of typ
+func newStringFields(typ *btf.Struct) []stringField {
+	var fields []stringField
+	for _, member := range typ.Members {
+		array := types.GetCharArray(member.Type)
+		if array == nil || array.Nelems == 0 {
+			continue
+		}
+		fields = append(fields, stringField{
+			name:   member.Name,
+			offset: member.Offset.Bytes(),
+			size:   array.Nelems,
+		})
+	}
+	return fields
+}
+
+// redact masks the secrets in the field in the event in data, in place
+func (f *stringField) redact(data []byte, config *redaction.Config) {
+	if int(f.offset+f.size) > len(data) {
+		return
+	}
+	field := data[f.offset : f.offset+f.size]
+	value := field
+	if i := bytes.IndexByte(value, 0); i != -1 {
+		value = value[:i]
+	}
+	redacted := config.RedactString(f.name, string(value))
+	if redacted == string(value) {
+		return
+	}
+	n := copy(field[:len(field)-1], redacted)
+	clear(field[n:])
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
+)
+
+// redactEventType returns the type of an event with a pid at offset 0, a comm
+// at offset 4 and an address at offset 20
+func redactEventType() *btf.Struct {
+	u8 := &btf.Int{Name: "u8", Size: 1}
+	u32 := &btf.Int{Name: "u32", Size: 4}
+	endpoint := &btf.Struct{Name: types.L3EndpointTypeName, Size: 20}
+	return &btf.Struct{
+		Name: "event",
+		Size: 40,
+		Members: []btf.Member{
+			{Name: "pid", Type: u32, Offset: 0},
+			{Name: "comm", Type: &btf.Array{Type: u8, Nelems: 16}, Offset: 4 * 8},
+			{Name: "addr", Type: endpoint, Offset: 20 * 8},
+		},
+	}
+}
+
+func redactEvent() []byte {
+	data := make([]byte, 40)
+	binary.NativeEndian.PutUint32(data, 1234)
+	copy(data[4:], "cat")
+	copy(data[20:], []byte{10, 0, 0, 1})
+	data[36] = 4
+	return data
+}
+
+func TestFieldRedactors(t *testing.T) {
+	t.Parallel()
+
+	typ := redactEventType()
+	key := []byte("key")
+
+	type testDefinition struct {
+		fields            []types.Field
+		param             string
+		check             func(t *testing.T, original, data []byte)
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"none": {
+			check: func(t *testing.T, original, data []byte) {
+				require.Equal(t, original, data)
+			},
+		},
+		"drop": {
+			fields: []types.Field{{Name: "pid", Redact: "drop"}, {Name: "addr", Redact: "drop"}},
+			check: func(t *testing.T, original, data []byte) {
+				require.Equal(t, make([]byte, 4), data[:4])
+				require.Equal(t, original[4:20], data[4:20])
+				require.Equal(t, make([]byte, 4), data[20:24])
+				// The version of the endpoint is kept
+				require.Equal(t, uint8(4), data[36])
+			},
+		},
+		"hash": {
+			fields: []types.Field{{Name: "comm", Redact: "hash"}, {Name: "addr", Redact: "hash"}},
+			check: func(t *testing.T, original, data []byte) {
+				require.Equal(t, original[:4], data[:4])
+				comm := string(data[4:19])
+				require.Regexp(t, "^[0-9a-f]{15}$", comm)
+				require.Equal(t, uint8(0), data[19])
+				require.NotEqual(t, original[20:24], data[20:24])
+				require.Equal(t, original[24:], data[24:])
+
+				// The same values get the same hash
+				again := redactEvent()
+				for _, r := range mustRedactors(t, typ, []types.Field{{Name: "comm", Redact: "hash"}}, "") {
+					r.redact(again, key)
+				}
+				require.Equal(t, comm, string(again[4:19]))
+			},
+		},
+		"param_overrides_metadata": {
+			fields: []types.Field{{Name: "comm", Redact: "drop"}},
+			param:  "comm=truncate:2",
+			check: func(t *testing.T, original, data []byte) {
+				require.Equal(t, "ca", string(data[4:6]))
+				require.Equal(t, make([]byte, 14), data[6:20])
+			},
+		},
+		"param_unknown_field": {
+			param:             "foo=drop",
+			expectedErrString: "field \"foo\" not found in eBPF struct \"event\"",
+		},
+		"param_truncate_not_string": {
+			param:             "pid=truncate:1",
+			expectedErrString: "field \"pid\": only strings can be truncated",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			metadata := &types.GadgetMetadata{
+				Structs: map[string]types.Struct{"event": {Fields: test.fields}},
+			}
+			redactors, err := newFieldRedactors(typ, metadata, test.param)
+			if test.expectedErrString != "" {
+				require.ErrorContains(t, err, test.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+
+			data := redactEvent()
+			for _, r := range redactors {
+				r.redact(data, key)
+			}
+			test.check(t, redactEvent(), data)
+		})
+	}
+}
+
+func mustRedactors(t *testing.T, typ *btf.Struct, fields []types.Field, param string) []fieldRedactor {
+	metadata := &types.GadgetMetadata{
+		Structs: map[string]types.Struct{"event": {Fields: fields}},
+	}
+	redactors, err := newFieldRedactors(typ, metadata, param)
+	require.NoError(t, err)
+	return redactors
+}
+
+func TestStringFields(t *testing.T) {
+	t.Parallel()
+
+	typ := redactEventType()
+	fields := newStringFields(typ)
+	require.Equal(t, []stringField{{name: "comm", offset: 4, size: 16}}, fields)
+
+	config, err := redaction.ParseConfig([]byte("patterns: ['secret[0-9]+']\n"))
+	require.NoError(t, err)
+
+	data := redactEvent()
+	copy(data[4:20], "secret1234567890")
+	fields[0].redact(data, config)
+	// The value isn't NUL terminated as it takes the whole array
+	require.Equal(t, "***", string(data[4:7]))
+	require.Equal(t, make([]byte, 13), data[7:20])
+	require.Equal(t, redactEvent()[:4], data[:4])
+	require.Equal(t, redactEvent()[20:], data[20:])
+
+	data = redactEvent()
+	fields[0].redact(data, config)
+	require.Equal(t, redactEvent(), data)
+
+	config, err = redaction.ParseConfig([]byte("fields: [comm]\nmask: averyveryverylongmask\n"))
+	require.NoError(t, err)
+	fields[0].redact(data, config)
+	// The mask is truncated to keep the NUL terminator
+	require.Equal(t, "averyveryverylo\x00", string(data[4:20]))
+}
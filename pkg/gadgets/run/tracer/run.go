@@ -17,9 +17,17 @@ package tracer
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"os"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 	"unsafe"
 
 	"github.com/cilium/ebpf"
@@ -32,6 +40,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns/ellipsis"
 	columns_json "github.com/inspektor-gadget/inspektor-gadget/pkg/columns/formatter/json"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/environment"
 	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
@@ -44,13 +53,37 @@ import (
 )
 
 const (
-	validateMetadataParam = "validate-metadata"
-	authfileParam         = "authfile"
-	insecureParam         = "insecure"
-	pullParam             = "pull"
-	pullSecret            = "pull-secret"
+	validateMetadataParam    = "validate-metadata"
+	authfileParam            = "authfile"
+	insecureParam            = "insecure"
+	pullParam                = "pull"
+	pullSecret               = "pull-secret"
+	decodeWorkersParam       = "decode-workers"
+	decodeOrderedParam       = "decode-ordered"
+	perfBufferPagesParam     = "perf-buffer-pages"
+	ringbufSizeParam         = "ringbuf-size"
+	wakeupBytesParam         = "ringbuf-wakeup-bytes"
+	flushTimeoutParam        = "ringbuf-flush-timeout"
+	aggregationIntervalParam = "aggregation-interval"
+	stampCPUParam            = "stamp-cpu"
+	perCPUOrderParam         = "per-cpu-order"
+	redactParam              = "redact"
+	streamParam              = "stream"
+	verboseVerifierParam     = "verbose-verifier"
+	rateLimitParam           = "rate-limit"
+	rateLimitBurstParam      = "rate-limit-burst"
+	probeCoverageParam       = "probe-coverage"
+	ifaceParam               = "iface"
+	persistStateParam        = "persist-state"
 )
 
+// cpuColumnName is the name of the column added with stamp-cpu
+const cpuColumnName = "cpu"
+
+// maxPerfBufferPages is the maximum number of pages of the perf buffer of each
+// CPU
+const maxPerfBufferPages = 4096
+
 type GadgetDesc struct{}
 
 func (g *GadgetDesc) Name() string {
@@ -87,6 +120,13 @@ func (g *GadgetDesc) ParamDescs() params.ParamDescs {
 			DefaultValue: "true",
 			TypeHint:     params.TypeBool,
 		},
+		{
+			Key:          verboseVerifierParam,
+			Title:        "Verbose verifier",
+			Description:  "Request the verifier log of all the instructions and its statistics. It's reported when the gadget fails to load and, with debug logs, when it loads",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
 		{
 			Key:          insecureParam,
 			Title:        "Insecure connection",
@@ -98,7 +138,7 @@ func (g *GadgetDesc) ParamDescs() params.ParamDescs {
 			Key:          pullParam,
 			Title:        "Pull policy",
 			Description:  "Specify when the gadget image should be pulled",
-			DefaultValue: oci.PullImageMissing,
+			DefaultValue: defaultPullPolicy(),
 			PossibleValues: []string{
 				oci.PullImageAlways,
 				oci.PullImageMissing,
@@ -112,9 +152,179 @@ func (g *GadgetDesc) ParamDescs() params.ParamDescs {
 			Description: "Secret to use when pulling the gadget image",
 			TypeHint:    params.TypeString,
 		},
+		{
+			Key:          decodeWorkersParam,
+			Title:        "Decode workers",
+			Description:  "Number of goroutines decoding the events read from the perf or ring buffer. 0 decodes them in the reader goroutine",
+			DefaultValue: "0",
+			TypeHint:     params.TypeUint,
+			Validator:    params.ValidateUintRange(0, maxDecodeWorkers),
+		},
+		{
+			Key:          decodeOrderedParam,
+			Title:        "Decode ordered",
+			Description:  "Deliver the decoded events in the order they were read. Only used when decode-workers is greater than 0",
+			DefaultValue: "true",
+			TypeHint:     params.TypeBool,
+		},
+		{
+			Key:          stampCPUParam,
+			Title:        "Stamp CPU",
+			Description:  "Add the CPU the events were generated on in the cpu column. Only used by gadgets stamping their events with gadget_seq_next()",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
+		{
+			Key:          perCPUOrderParam,
+			Title:        "Per-CPU order",
+			Description:  "Deliver the events read at once grouped by CPU and in the order they were generated on it. Only used by gadgets stamping their events with gadget_seq_next()",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
+		{
+			Key:         streamParam,
+			Title:       "Stream",
+			Description: "Comma separated names of the tracers whose events are emitted, for gadgets with several tracers. Tracers sending the same struct can be read at once, their events are tagged with their stream. Defaults to the first one in alphabetical order",
+			TypeHint:    params.TypeString,
+		},
+		{
+			Key:         redactParam,
+			Title:       "Redact",
+			Description: "Comma separated list of field=redaction redacting sensitive fields on the node, overriding the ones of the gadget metadata. Redactions: drop, hash or truncate:<length>",
+			TypeHint:    params.TypeString,
+			Validator: func(value string) error {
+				_, err := types.ParseRedactions(value)
+				return err
+			},
+		},
+		{
+			Key:          perfBufferPagesParam,
+			Title:        "Perf buffer pages",
+			Description:  "Number of pages of the perf buffer of each CPU. Only used when ring buffers aren't available",
+			DefaultValue: fmt.Sprint(gadgets.PerfBufferPages),
+			TypeHint:     params.TypeUint,
+			Validator:    params.ValidateUintRange(1, maxPerfBufferPages),
+		},
+		{
+			Key:          ringbufSizeParam,
+			Title:        "Ring buffer size",
+			Description:  "Size in bytes of the ring buffer, a power of 2 multiple of the page size. 0 uses the size defined by the gadget",
+			DefaultValue: "0",
+			TypeHint:     params.TypeUint,
+			Validator:    validateRingbufSize,
+		},
+		{
+			Key:          wakeupBytesParam,
+			Title:        "Ring buffer wakeup bytes",
+			Description:  "Number of bytes pending in the ring buffer before the events are read. 0 reads each event as soon as it's sent",
+			DefaultValue: "0",
+			TypeHint:     params.TypeUint64,
+		},
+		{
+			Key:          flushTimeoutParam,
+			Title:        "Ring buffer flush timeout",
+			Description:  "Maximum time events wait in the ring buffer when ringbuf-wakeup-bytes is set",
+			DefaultValue: "100ms",
+			TypeHint:     params.TypeDuration,
+			Validator: func(value string) error {
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return err
+				}
+				if d <= 0 {
+					return fmt.Errorf("flush timeout must be greater than 0")
+				}
+				return nil
+			},
+		},
+		{
+			Key:          aggregationIntervalParam,
+			Title:        "Aggregation interval",
+			Description:  "Interval at which the data aggregated by the gadget on the kernel side is sent. Only used by gadgets with aggregators",
+			DefaultValue: "1s",
+			TypeHint:     params.TypeDuration,
+			Validator: func(value string) error {
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return err
+				}
+				if d <= 0 {
+					return fmt.Errorf("aggregation interval must be greater than 0")
+				}
+				return nil
+			},
+		},
+		{
+			Key:          rateLimitParam,
+			Title:        "Rate limit",
+			Description:  "Maximum number of events per second sent by each container, the others are discarded. 0 disables it",
+			DefaultValue: "0",
+			TypeHint:     params.TypeUint64,
+		},
+		{
+			Key:          rateLimitBurstParam,
+			Title:        "Rate limit burst",
+			Description:  "Number of events each container can send at once when rate-limit is set, rate-limit if 0",
+			DefaultValue: "0",
+			TypeHint:     params.TypeUint64,
+		},
+		{
+			Key:          probeCoverageParam,
+			Title:        "Probe coverage",
+			Description:  "Report the programs of the gadget that never ran when it stops. The kernel counts the runs of all the eBPF programs of the node meanwhile",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
+		{
+			Key:          persistStateParam,
+			Title:        "Persist state",
+			Description:  "Pin the maps keeping the state of the gadget, e.g. the one of an aggregator, and reuse them when the instance restarts with the same ID, like the detached instances restored by the daemon. Only supported by the instances run by the daemon",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
+		{
+			Key:         ifaceParam,
+			Title:       "Interfaces",
+			Description: "Interfaces to attach the socket filter programs to in the network namespace of each container, by name (eth0) or by a regular expression matching the whole name (eth.*). All of them by default",
+			TypeHint:    params.TypeString,
+			Validator: func(value string) error {
+				if _, err := regexp.Compile(value); err != nil {
+					return fmt.Errorf("invalid interface pattern: %w", err)
+				}
+				return nil
+			},
+		},
 	}
 }
 
+// validateRingbufSize checks that value is a valid ring buffer size, i.e. a
+// power of 2 multiple of the page size, or 0.
+func validateRingbufSize(value string) error {
+	size, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("expected numeric value: %w", err)
+	}
+	if size == 0 {
+		return nil
+	}
+	pageSize := uint64(os.Getpagesize())
+	if size < pageSize || size%pageSize != 0 || size&(size-1) != 0 {
+		return fmt.Errorf("ring buffer size must be a power of 2 multiple of the page size (%d), got %d", pageSize, size)
+	}
+	return nil
+}
+
+// defaultPullPolicy returns the pull policy to use when the user doesn't
+// specify one. On Kubernetes the local stores of the nodes can't be managed by
+// the user, hence images are always pulled to avoid running stale versions of
+// moving tags. Locally, the image is only pulled if it's not present.
+func defaultPullPolicy() string {
+	if environment.Environment == environment.Kubernetes {
+		return oci.PullImageAlways
+	}
+	return oci.PullImageMissing
+}
+
 func (g *GadgetDesc) Parser() parser.Parser {
 	return nil
 }
@@ -132,6 +342,8 @@ func getGadgetType(spec *ebpf.CollectionSpec,
 		return gadgets.TypeTrace, nil
 	case len(gadgetMetadata.Snapshotters) > 0:
 		return gadgets.TypeOneShot, nil
+	case len(gadgetMetadata.Aggregators) > 0:
+		return gadgets.TypeTrace, nil
 	default:
 		return gadgets.TypeUnknown, fmt.Errorf("unknown gadget type")
 	}
@@ -148,9 +360,19 @@ func getGadgetInfo(params *params.Params, args []string, secretBytes []byte, log
 		return nil, fmt.Errorf("getting gadget image: %w", err)
 	}
 
+	return gadgetInfoFromImage(params, gadget, logger)
+}
+
+// gadgetInfoFromImage returns the information of the gadget in the image
+// according to the parameters of the run gadget
+func gadgetInfoFromImage(params *params.Params, gadget *oci.GadgetImage, logger logger.Logger) (*types.GadgetInfo, error) {
+	var err error
+
 	ret := &types.GadgetInfo{
 		ProgContent:    gadget.EbpfObject,
+		BTFGen:         gadget.BTFGen,
 		GadgetMetadata: &types.GadgetMetadata{},
+		ImageDigest:    gadget.Digest,
 	}
 
 	spec, err := loadSpec(ret.ProgContent)
@@ -166,6 +388,13 @@ func getGadgetInfo(params *params.Params, args []string, secretBytes []byte, log
 	} else {
 		validate := params.Get(validateMetadataParam).AsBool()
 
+		if err := types.CheckMetadataAPIVersion(gadget.Metadata); err != nil {
+			if validate {
+				return nil, err
+			}
+			logger.Warnf("%v: trying to run the gadget anyway", err)
+		}
+
 		if err := yaml.Unmarshal(gadget.Metadata, &ret.GadgetMetadata); err != nil {
 			return nil, fmt.Errorf("unmarshaling metadata: %w", err)
 		}
@@ -188,8 +417,15 @@ func getGadgetInfo(params *params.Params, args []string, secretBytes []byte, log
 		return nil, err
 	}
 
+	eventType, err := getEventTypeBTF(ret.ProgContent, ret.GadgetMetadata, params.Get(streamParam).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("getting value struct: %w", err)
+	}
+	ret.EventStruct = eventType.Name
+
 	ret.EventFactory = types.NewEventFactory()
-	ret.Columns, err = calculateColumnsForClient(ret.EventFactory, ret.GadgetMetadata, gadget.EbpfObject, logger)
+	stampCPU := params.Get(stampCPUParam).AsBool()
+	ret.Columns, err = calculateColumnsForClient(ret.EventFactory, ret.GadgetMetadata, eventType, stampCPU, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -235,12 +471,32 @@ func getTypeHint(typ btf.Type) params.TypeHint {
 		case 8:
 			return params.TypeFloat64
 		}
+	case *btf.Struct:
+		switch typedMember.Name {
+		case types.L3EndpointTypeName:
+			return params.TypeIP
+		case types.CIDRTypeName:
+			return params.TypeCIDR
+		}
 	case *btf.Typedef:
+		switch typedMember.Name {
+		case types.DurationTypeName:
+			return params.TypeDuration
+		case types.SizeTypeName:
+			return params.TypeSize
+		}
 		typ, err := getUnderlyingType(typedMember)
 		if err != nil {
 			return params.TypeUnknown
 		}
 		return getTypeHint(typ)
+	case *btf.Enum:
+		return params.TypeEnum
+	case *btf.Array:
+		// Arrays of integers are set with comma separated lists
+		if types.GetCharArray(typedMember) != nil || getIntArray(typedMember) != nil {
+			return params.TypeString
+		}
 	case *btf.Volatile:
 		return getTypeHint(typedMember.Type)
 	}
@@ -248,23 +504,197 @@ func getTypeHint(typ btf.Type) params.TypeHint {
 	return params.TypeUnknown
 }
 
+// getEnum returns the enum typ is, if any
+func getEnum(typ btf.Type) *btf.Enum {
+	for {
+		switch typedMember := typ.(type) {
+		case *btf.Enum:
+			return typedMember
+		case *btf.Typedef:
+			typ = typedMember.Type
+		case *btf.Volatile:
+			typ = typedMember.Type
+		case *btf.Const:
+			typ = typedMember.Type
+		default:
+			return nil
+		}
+	}
+}
+
+// getInt returns the integer typ is, if any
+func getInt(typ btf.Type) *btf.Int {
+	for {
+		switch typedMember := typ.(type) {
+		case *btf.Int:
+			return typedMember
+		case *btf.Typedef:
+			typ = typedMember.Type
+		case *btf.Volatile:
+			typ = typedMember.Type
+		case *btf.Const:
+			typ = typedMember.Type
+		default:
+			return nil
+		}
+	}
+}
+
+// getIntArray returns the array of integers typ is, if any. Arrays of chars
+// aren't included as they're used as strings.
+func getIntArray(typ btf.Type) *btf.Array {
+	for {
+		switch typedMember := typ.(type) {
+		case *btf.Array:
+			if i := getInt(typedMember.Type); i != nil && i.Size > 1 {
+				return typedMember
+			}
+			return nil
+		case *btf.Typedef:
+			typ = typedMember.Type
+		case *btf.Volatile:
+			typ = typedMember.Type
+		case *btf.Const:
+			typ = typedMember.Type
+		default:
+			return nil
+		}
+	}
+}
+
+// encodeIntList parses the comma separated list of integers value and encodes
+// each one with the size of typ. It fails if there are more than maxLen values.
+func encodeIntList(value string, typ *btf.Int, maxLen uint32) ([][]byte, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	entries := strings.Split(value, ",")
+	if len(entries) > int(maxLen) {
+		return nil, fmt.Errorf("too many values: got %d, at most %d are allowed", len(entries), maxLen)
+	}
+
+	encoded := make([][]byte, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+
+		var n uint64
+		var err error
+		if typ.Encoding == btf.Signed {
+			var signed int64
+			signed, err = strconv.ParseInt(entry, 0, int(typ.Size*8))
+			n = uint64(signed)
+		} else {
+			n, err = strconv.ParseUint(entry, 0, int(typ.Size*8))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q: expected an integer of %d bytes", entry, typ.Size)
+		}
+
+		buf := make([]byte, typ.Size)
+		switch typ.Size {
+		case 1:
+			buf[0] = uint8(n)
+		case 2:
+			binary.NativeEndian.PutUint16(buf, uint16(n))
+		case 4:
+			binary.NativeEndian.PutUint32(buf, uint32(n))
+		case 8:
+			binary.NativeEndian.PutUint64(buf, n)
+		default:
+			return nil, fmt.Errorf("unsupported size %d of integer %s", typ.Size, typ.Name)
+		}
+		encoded = append(encoded, buf)
+	}
+	return encoded, nil
+}
+
+// validateStringLength checks value fits in array with its NUL terminator
+func validateStringLength(array *btf.Array, value string) error {
+	if len(value) >= int(array.Nelems) {
+		return fmt.Errorf("%q is too long, it can have up to %d characters", value, array.Nelems-1)
+	}
+	return nil
+}
+
+// fillEnumValues sets the possible values of an enum parameter to the names of
+// the enum values, or checks they're valid if they're set in the metadata
+func fillEnumValues(p *types.EBPFParam, enum *btf.Enum) error {
+	names := make([]string, 0, len(enum.Values))
+	for _, v := range enum.Values {
+		names = append(names, v.Name)
+	}
+
+	if len(p.PossibleValues) == 0 {
+		p.PossibleValues = names
+		return nil
+	}
+	for _, value := range p.PossibleValues {
+		if !slices.Contains(names, value) {
+			return fmt.Errorf("possible value %q of %s is not a value of enum %s: valid values are: %s",
+				value, p.Key, enum.Name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
 // fillTypeHints fills the TypeHint field in the ebpf parameters according to the BTF information
 // about those constants.
-func fillTypeHints(spec *ebpf.CollectionSpec, params map[string]types.EBPFParam) error {
-	for varName, p := range params {
-		var btfVar *btf.Var
-		err := spec.Types.TypeByName(varName, &btfVar)
-		if err != nil {
-			return fmt.Errorf("no BTF type found for: %s: %w", p.Key, err)
+func fillTypeHints(spec *ebpf.CollectionSpec, ebpfParams map[string]types.EBPFParam) error {
+	for varName, p := range ebpfParams {
+		// Params defined with GADGET_MAP_PARAM() fill the keys of a map
+		if m, ok := spec.Maps[varName]; ok {
+			key := getInt(m.Key)
+			if key == nil {
+				return fmt.Errorf("key of map %s of param %s is not an integer", varName, p.Key)
+			}
+			p.TypeHint = params.TypeString
+			p.Validator = func(value string) error {
+				_, err := encodeIntList(value, key, m.MaxEntries)
+				return err
+			}
+			ebpfParams[varName] = p
+			continue
 		}
 
-		btfConst, ok := btfVar.Type.(*btf.Const)
-		if !ok {
-			return fmt.Errorf("type for %s is not a constant, got %s", p.Key, btfVar.Type)
+		var typ btf.Type
+		if member, ok := types.GetConfigField(spec, varName); ok {
+			// Params in the gadget_config map can be changed while the
+			// gadget runs
+			typ = member.Type
+		} else {
+			var btfVar *btf.Var
+			err := spec.Types.TypeByName(varName, &btfVar)
+			if err != nil {
+				return fmt.Errorf("no BTF type found for: %s: %w", p.Key, err)
+			}
+
+			btfConst, ok := btfVar.Type.(*btf.Const)
+			if !ok {
+				return fmt.Errorf("type for %s is not a constant, got %s", p.Key, btfVar.Type)
+			}
+			typ = btfConst.Type
 		}
 
-		p.TypeHint = getTypeHint(btfConst.Type)
-		params[varName] = p
+		p.TypeHint = getTypeHint(typ)
+		if enum := getEnum(typ); enum != nil {
+			if err := fillEnumValues(&p, enum); err != nil {
+				return err
+			}
+		}
+		if array := types.GetCharArray(typ); array != nil {
+			p.Validator = func(value string) error {
+				return validateStringLength(array, value)
+			}
+		}
+		if array := getIntArray(typ); array != nil {
+			elem := getInt(array.Type)
+			p.Validator = func(value string) error {
+				_, err := encodeIntList(value, elem, array.Nelems)
+				return err
+			}
+		}
+		ebpfParams[varName] = p
 	}
 
 	return nil
@@ -415,6 +845,100 @@ func addL4EndpointColumns(
 	})
 }
 
+// numericFieldGetters returns functions getting the values of the numeric columns of an
+// event, to be used in the expressions of computed fields
+func numericFieldGetters(cols []types.ColumnDesc) types.NumberGetters {
+	getters := make(types.NumberGetters)
+	timestampsCounter := 0
+
+	for _, col := range cols {
+		if col.BlobIndex == types.IndexVirtual {
+			if col.Type.Kind == types.KindTimestamp {
+				index := timestampsCounter
+				getters[col.Name] = func(e *types.Event) float64 {
+					if len(e.Timestamps) <= index {
+						return 0
+					}
+					return float64(e.Timestamps[index])
+				}
+				timestampsCounter++
+			}
+			continue
+		}
+
+		read, size := numericReader(col.Type.Kind)
+		if read == nil {
+			continue
+		}
+		blobIndex, offset := col.BlobIndex, int(col.Offset)
+		getters[col.Name] = func(e *types.Event) float64 {
+			if len(e.Blob) <= blobIndex || len(e.Blob[blobIndex]) < offset+size {
+				return 0
+			}
+			return read(e.Blob[blobIndex][offset:])
+		}
+	}
+
+	return getters
+}
+
+// histogramFieldGetters returns functions getting the slots of the columns that are
+// arrays of integers, to be used as histograms in the expressions of computed fields
+func histogramFieldGetters(cols []types.ColumnDesc) types.HistogramGetters {
+	getters := make(types.HistogramGetters)
+
+	for _, col := range cols {
+		if col.BlobIndex == types.IndexVirtual || col.Type.Kind != types.KindArray || col.Type.ArrayType == nil {
+			continue
+		}
+		read, size := numericReader(col.Type.ArrayType.Kind)
+		if read == nil || col.Type.ArrayType.Kind == types.KindFloat32 || col.Type.ArrayType.Kind == types.KindFloat64 {
+			continue
+		}
+		blobIndex, offset, n := col.BlobIndex, int(col.Offset), col.Type.ArrayNElements
+		getters[col.Name] = func(e *types.Event) []uint64 {
+			if len(e.Blob) <= blobIndex || len(e.Blob[blobIndex]) < offset+n*size {
+				return nil
+			}
+			slots := make([]uint64, n)
+			for i := range slots {
+				slots[i] = uint64(max(read(e.Blob[blobIndex][offset+i*size:]), 0))
+			}
+			return slots
+		}
+	}
+
+	return getters
+}
+
+// numericReader returns a function decoding a value of kind and its size, or nil if it
+// isn't numeric
+func numericReader(kind types.Kind) (func([]byte) float64, int) {
+	switch kind {
+	case types.KindUint8:
+		return func(b []byte) float64 { return float64(b[0]) }, 1
+	case types.KindInt8:
+		return func(b []byte) float64 { return float64(int8(b[0])) }, 1
+	case types.KindUint16:
+		return func(b []byte) float64 { return float64(binary.NativeEndian.Uint16(b)) }, 2
+	case types.KindInt16:
+		return func(b []byte) float64 { return float64(int16(binary.NativeEndian.Uint16(b))) }, 2
+	case types.KindUint32:
+		return func(b []byte) float64 { return float64(binary.NativeEndian.Uint32(b)) }, 4
+	case types.KindInt32:
+		return func(b []byte) float64 { return float64(int32(binary.NativeEndian.Uint32(b))) }, 4
+	case types.KindUint64:
+		return func(b []byte) float64 { return float64(binary.NativeEndian.Uint64(b)) }, 8
+	case types.KindInt64:
+		return func(b []byte) float64 { return float64(int64(binary.NativeEndian.Uint64(b))) }, 8
+	case types.KindFloat32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(binary.NativeEndian.Uint32(b))) }, 4
+	case types.KindFloat64:
+		return func(b []byte) float64 { return math.Float64frombits(binary.NativeEndian.Uint64(b)) }, 8
+	}
+	return nil, 0
+}
+
 func field2ColumnAttrs(field *types.Field) columns.Attributes {
 	fieldAttrs := field.Attributes
 
@@ -440,6 +964,9 @@ func field2ColumnAttrs(field *types.Field) columns.Attributes {
 	if fieldAttrs.Template != "" {
 		attrs.Template = fieldAttrs.Template
 	}
+	if fieldAttrs.Unit != "" {
+		attrs.Unit = fieldAttrs.Unit
+	}
 
 	switch fieldAttrs.Alignment {
 	case types.AlignmentLeft:
@@ -461,7 +988,16 @@ func field2ColumnAttrs(field *types.Field) columns.Attributes {
 }
 
 func (g *GadgetDesc) getColumns(info *types.GadgetInfo) (*columns.Columns[types.Event], error) {
-	_, eventStruct := getAnyMapElem(info.GadgetMetadata.Structs)
+	var eventStruct *types.Struct
+	if info.EventStruct != "" {
+		if s, ok := info.GadgetMetadata.Structs[info.EventStruct]; ok {
+			eventStruct = &s
+		}
+	} else {
+		// Older versions don't send the name of the struct, they only support
+		// gadgets with a single one
+		_, eventStruct = getAnyMapElem(info.GadgetMetadata.Structs)
+	}
 	if eventStruct == nil {
 		return nil, fmt.Errorf("struct not found in gadget metadata")
 	}
@@ -480,6 +1016,9 @@ func (g *GadgetDesc) getColumns(info *types.GadgetInfo) (*columns.Columns[types.
 		fields[field.Name] = field
 	}
 
+	getters := numericFieldGetters(info.Columns)
+	histograms := histogramFieldGetters(info.Columns)
+
 	for i, col := range info.Columns {
 		var attrs columns.Attributes
 
@@ -553,6 +1092,22 @@ func (g *GadgetDesc) getColumns(info *types.GadgetInfo) (*columns.Columns[types.
 				}
 				timestampsCounter++
 				continue
+			case types.KindComputed:
+				expr, err := types.ParseExpression(fields[col.Name].Expression)
+				if err != nil {
+					return nil, fmt.Errorf("parsing expression of %q: %w", col.Name, err)
+				}
+				eval, err := expr.Bind(getters, histograms)
+				if err != nil {
+					return nil, fmt.Errorf("binding expression of %q: %w", col.Name, err)
+				}
+				err = cols.AddColumn(attrs, func(e *types.Event) any {
+					return eval(e)
+				})
+				if err != nil {
+					return nil, fmt.Errorf("adding computed column: %w", err)
+				}
+				continue
 
 			}
 		case types.IndexEBPF:
@@ -775,14 +1330,10 @@ func simpleTypeFromBTF(typ btf.Type) *types.Type {
 func calculateColumnsForClient(
 	eventFactory *types.EventFactory,
 	gadgetMetadata *types.GadgetMetadata,
-	progContent []byte,
+	eventType *btf.Struct,
+	stampCPU bool,
 	logger logger.Logger,
 ) ([]types.ColumnDesc, error) {
-	eventType, err := getEventTypeBTF(progContent, gadgetMetadata)
-	if err != nil {
-		return nil, fmt.Errorf("getting value struct: %w", err)
-	}
-
 	colNames := map[string]struct{}{}
 
 	eventStruct, ok := gadgetMetadata.Structs[eventType.Name]
@@ -831,6 +1382,24 @@ func calculateColumnsForClient(
 			}
 			columns = append(columns, col)
 			continue
+		case types.SyscallTypeName:
+			// Like enums, the name and the raw number
+			columns = append(columns, types.FactoryAddString(eventFactory, member.Name))
+			columns = append(columns, types.ColumnDesc{
+				Name:   member.Name + "_raw",
+				Type:   types.Type{Kind: types.KindUint64},
+				Offset: uintptr(member.Offset.Bytes()),
+			})
+			continue
+		case types.PacketTypeName:
+			// The original length of the packet, the first field. The bytes
+			// are only written by the pcapng output mode.
+			columns = append(columns, types.ColumnDesc{
+				Name:   member.Name,
+				Type:   types.Type{Kind: types.KindUint32},
+				Offset: uintptr(member.Offset.Bytes()),
+			})
+			continue
 		}
 
 		rType := typeFromBTF(member.Type)
@@ -865,5 +1434,25 @@ func calculateColumnsForClient(
 		columns = append(columns, col)
 	}
 
+	// Computed fields aren't part of the eBPF struct
+	for _, field := range eventStruct.Fields {
+		if field.Expression == "" {
+			continue
+		}
+		columns = append(columns, types.ColumnDesc{
+			Name:      field.Name,
+			BlobIndex: types.IndexVirtual,
+			Type:      types.Type{Kind: types.KindComputed},
+		})
+	}
+
+	if stampCPU {
+		if hasSeqMember(eventType) {
+			columns = append(columns, types.FactoryAddField[uint32](eventFactory, cpuColumnName))
+		} else {
+			logger.Warnf("Gadget doesn't stamp its events with gadget_seq_next(), %s is ignored", stampCPUParam)
+		}
+	}
+
 	return columns, nil
 }
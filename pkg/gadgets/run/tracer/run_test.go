@@ -0,0 +1,381 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestValidateRingbufSize(t *testing.T) {
+	t.Parallel()
+
+	pageSize := os.Getpagesize()
+
+	type testDefinition struct {
+		value       string
+		expectedErr bool
+	}
+
+	tests := map[string]testDefinition{
+		"zero": {
+			value: "0",
+		},
+		"one_page": {
+			value: fmt.Sprint(pageSize),
+		},
+		"power_of_2_pages": {
+			value: fmt.Sprint(pageSize * 1024),
+		},
+		"not_power_of_2_pages": {
+			value:       fmt.Sprint(pageSize * 3),
+			expectedErr: true,
+		},
+		"smaller_than_page": {
+			value:       "512",
+			expectedErr: true,
+		},
+		"not_a_number": {
+			value:       "foo",
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateRingbufSize(test.value)
+			if test.expectedErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGetTypeHint(t *testing.T) {
+	t.Parallel()
+
+	u64 := &btf.Int{Name: "__u64", Size: 8, Encoding: btf.Unsigned}
+
+	type testDefinition struct {
+		typ      btf.Type
+		expected params.TypeHint
+	}
+
+	tests := map[string]testDefinition{
+		"u64": {
+			typ:      &btf.Typedef{Name: "__u64", Type: u64},
+			expected: params.TypeUint64,
+		},
+		"duration": {
+			typ:      &btf.Volatile{Type: &btf.Typedef{Name: types.DurationTypeName, Type: u64}},
+			expected: params.TypeDuration,
+		},
+		"size": {
+			typ:      &btf.Typedef{Name: types.SizeTypeName, Type: u64},
+			expected: params.TypeSize,
+		},
+		"ip": {
+			typ:      &btf.Struct{Name: types.L3EndpointTypeName, Size: 20},
+			expected: params.TypeIP,
+		},
+		"cidr": {
+			typ:      &btf.Struct{Name: types.CIDRTypeName, Size: 20},
+			expected: params.TypeCIDR,
+		},
+		"enum": {
+			typ:      &btf.Volatile{Type: &btf.Enum{Name: "mode", Size: 4}},
+			expected: params.TypeEnum,
+		},
+		"char_array": {
+			typ:      &btf.Volatile{Type: &btf.Array{Type: &btf.Int{Name: "char", Size: 1}, Nelems: 16}},
+			expected: params.TypeString,
+		},
+		"int_array": {
+			typ:      &btf.Array{Type: &btf.Int{Name: "int", Size: 4}, Nelems: 16},
+			expected: params.TypeString,
+		},
+		"struct_array": {
+			typ:      &btf.Array{Type: &btf.Struct{Name: "foo"}, Nelems: 16},
+			expected: params.TypeUnknown,
+		},
+		"other_struct": {
+			typ:      &btf.Struct{Name: "foo"},
+			expected: params.TypeUnknown,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.expected, getTypeHint(test.typ))
+		})
+	}
+}
+
+func TestEBPFParamValue(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		typeHint params.TypeHint
+		value    string
+		expected any
+	}
+
+	tests := map[string]testDefinition{
+		"uint32": {
+			typeHint: params.TypeUint32,
+			value:    "42",
+			expected: uint32(42),
+		},
+		"duration": {
+			typeHint: params.TypeDuration,
+			value:    "500ms",
+			expected: uint64(500 * time.Millisecond),
+		},
+		"size": {
+			typeHint: params.TypeSize,
+			value:    "64MiB",
+			expected: uint64(64 * 1024 * 1024),
+		},
+		"ipv4": {
+			typeHint: params.TypeIP,
+			value:    "10.1.2.3",
+			expected: l3EndpointT{addr: [16]byte{10, 1, 2, 3}, version: 4},
+		},
+		"ipv6": {
+			typeHint: params.TypeIP,
+			value:    "fd00::1",
+			expected: l3EndpointT{addr: [16]byte{0xfd, 15: 1}, version: 6},
+		},
+		"cidr": {
+			typeHint: params.TypeCIDR,
+			value:    "10.1.2.3/8",
+			expected: cidrT{addr: [16]byte{10}, version: 4, prefixlen: 8},
+		},
+		"empty_cidr": {
+			typeHint: params.TypeCIDR,
+			expected: cidrT{},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			p := (&params.ParamDesc{TypeHint: test.typeHint}).ToParam()
+			require.NoError(t, p.Set(test.value))
+			require.Equal(t, test.expected, ebpfParamValue(p))
+		})
+	}
+
+	// Same size as in include/gadget/types.h
+	require.Equal(t, uintptr(20), unsafe.Sizeof(l3EndpointT{}))
+	require.Equal(t, uintptr(20), unsafe.Sizeof(cidrT{}))
+}
+
+func TestEnumParams(t *testing.T) {
+	t.Parallel()
+
+	enum := &btf.Enum{
+		Name: "mode",
+		Size: 4,
+		Values: []btf.EnumValue{
+			{Name: "MODE_FAST", Value: 1},
+			{Name: "MODE_SLOW", Value: 2},
+		},
+	}
+	typ := &btf.Volatile{Type: &btf.Typedef{Name: "mode_t", Type: enum}}
+	require.Equal(t, enum, getEnum(typ))
+	require.Nil(t, getEnum(&btf.Volatile{Type: &btf.Int{Size: 4}}))
+
+	// The possible values default to the names of the enum values
+	p := &types.EBPFParam{ParamDesc: params.ParamDesc{Key: "mode"}}
+	require.NoError(t, fillEnumValues(p, enum))
+	require.Equal(t, []string{"MODE_FAST", "MODE_SLOW"}, p.PossibleValues)
+
+	// The ones of the metadata have to be values of the enum
+	p = &types.EBPFParam{ParamDesc: params.ParamDesc{Key: "mode", PossibleValues: []string{"MODE_SLOW"}}}
+	require.NoError(t, fillEnumValues(p, enum))
+	require.Equal(t, []string{"MODE_SLOW"}, p.PossibleValues)
+
+	p = &types.EBPFParam{ParamDesc: params.ParamDesc{Key: "mode", PossibleValues: []string{"MODE_FOO"}}}
+	require.ErrorContains(t, fillEnumValues(p, enum), "\"MODE_FOO\" of mode is not a value of enum mode")
+
+	value, err := enumParamValue(enum, "MODE_SLOW")
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), value)
+
+	_, err = enumParamValue(enum, "MODE_FOO")
+	require.Error(t, err)
+}
+
+func TestStringParams(t *testing.T) {
+	t.Parallel()
+
+	u8 := &btf.Typedef{Name: "__u8", Type: &btf.Int{Name: "unsigned char", Size: 1}}
+	array := &btf.Array{Type: u8, Nelems: 4}
+	typ := &btf.Volatile{Type: array}
+	require.Equal(t, array, types.GetCharArray(typ))
+	require.Nil(t, types.GetCharArray(&btf.Array{Type: &btf.Int{Size: 4}, Nelems: 4}))
+
+	p := (&params.ParamDesc{Key: "comm", TypeHint: params.TypeString}).ToParam()
+	require.NoError(t, p.Set("sh"))
+	value, err := ebpfConstValue(typ, p)
+	require.NoError(t, err)
+	require.Equal(t, []byte{'s', 'h', 0, 0}, value)
+
+	// There must be room for the NUL terminator
+	require.NoError(t, p.Set("bash"))
+	_, err = ebpfConstValue(typ, p)
+	require.ErrorContains(t, err, "\"bash\" is too long, it can have up to 3 characters")
+}
+
+func TestIntArrayParams(t *testing.T) {
+	t.Parallel()
+
+	u16 := &btf.Typedef{Name: "__u16", Type: &btf.Int{Name: "unsigned short", Size: 2}}
+	array := &btf.Array{Type: u16, Nelems: 3}
+	typ := &btf.Volatile{Type: array}
+	require.Equal(t, array, getIntArray(typ))
+	require.Equal(t, params.TypeString, getTypeHint(typ))
+	// Arrays of chars are strings
+	require.Nil(t, getIntArray(&btf.Array{Type: &btf.Int{Size: 1}, Nelems: 4}))
+
+	p := (&params.ParamDesc{Key: "ports", TypeHint: params.TypeString}).ToParam()
+	require.NoError(t, p.Set("80, 0x1bb"))
+	value, err := ebpfConstValue(typ, p)
+	require.NoError(t, err)
+	expected := make([]byte, 6)
+	binary.NativeEndian.PutUint16(expected[0:], 80)
+	binary.NativeEndian.PutUint16(expected[2:], 443)
+	require.Equal(t, expected, value)
+
+	require.NoError(t, p.Set("1,2,3,4"))
+	_, err = ebpfConstValue(typ, p)
+	require.ErrorContains(t, err, "too many values: got 4, at most 3 are allowed")
+
+	require.NoError(t, p.Set("70000"))
+	_, err = ebpfConstValue(typ, p)
+	require.ErrorContains(t, err, "invalid value \"70000\": expected an integer of 2 bytes")
+}
+
+func TestMapParamKeys(t *testing.T) {
+	t.Parallel()
+
+	m := &ebpf.MapSpec{
+		Name:       "pids",
+		Type:       ebpf.Hash,
+		Key:        &btf.Int{Name: "int", Size: 4, Encoding: btf.Signed},
+		MaxEntries: 2,
+	}
+
+	keys, err := mapParamKeys(m, "-1,42")
+	require.NoError(t, err)
+	minusOne := make([]byte, 4)
+	binary.NativeEndian.PutUint32(minusOne, uint32(0xffffffff))
+	fortyTwo := make([]byte, 4)
+	binary.NativeEndian.PutUint32(fortyTwo, 42)
+	require.Equal(t, [][]byte{minusOne, fortyTwo}, keys)
+
+	keys, err = mapParamKeys(m, "")
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	_, err = mapParamKeys(m, "1,2,3")
+	require.ErrorContains(t, err, "too many values")
+
+	m.Key = &btf.Struct{Name: "key"}
+	_, err = mapParamKeys(m, "1")
+	require.ErrorContains(t, err, "key of map pids is not an integer")
+}
+
+func TestNumericFieldGetters(t *testing.T) {
+	t.Parallel()
+
+	cols := []types.ColumnDesc{
+		{Name: "pid", Type: types.Type{Kind: types.KindUint32}, Offset: 0},
+		{Name: "delta", Type: types.Type{Kind: types.KindInt16}, Offset: 4},
+		{Name: "comm", Type: types.Type{Kind: types.KindString}, BlobIndex: types.IndexFixed + 1},
+		{Name: "start", BlobIndex: types.IndexVirtual, Type: types.Type{Kind: types.KindTimestamp}},
+		{Name: "end", BlobIndex: types.IndexVirtual, Type: types.Type{Kind: types.KindTimestamp}},
+	}
+	getters := numericFieldGetters(cols)
+	require.NotContains(t, getters, "comm")
+
+	blob := make([]byte, 6)
+	binary.NativeEndian.PutUint32(blob, 42)
+	var delta int16 = -3
+	binary.NativeEndian.PutUint16(blob[4:], uint16(delta))
+	ev := &types.Event{
+		Blob:       [][]byte{blob},
+		Timestamps: []eventtypes.Time{100, 350},
+	}
+
+	expr, err := types.ParseExpression("(end - start) * delta + pid")
+	require.NoError(t, err)
+	eval, err := expr.Bind(getters, nil)
+	require.NoError(t, err)
+	require.Equal(t, float64(-708), eval(ev))
+
+	// Missing data evaluates to zero instead of panicking
+	require.Equal(t, float64(0), eval(&types.Event{}))
+}
+
+func TestHistogramFieldGetters(t *testing.T) {
+	t.Parallel()
+
+	u32 := &types.Type{Kind: types.KindUint32}
+	cols := []types.ColumnDesc{
+		{Name: "pid", Type: *u32, Offset: 0},
+		{Name: "slots", Type: types.Type{Kind: types.KindArray, ArrayNElements: 3, ArrayType: u32}, Offset: 4},
+	}
+	getters := histogramFieldGetters(cols)
+	require.NotContains(t, getters, "pid")
+
+	blob := make([]byte, 16)
+	for i, count := range []uint32{42, 1, 0, 3} {
+		binary.NativeEndian.PutUint32(blob[i*4:], count)
+	}
+	ev := &types.Event{Blob: [][]byte{blob}}
+
+	expr, err := types.ParseExpression("percentile(slots, 25)")
+	require.NoError(t, err)
+	eval, err := expr.Bind(numericFieldGetters(cols), getters)
+	require.NoError(t, err)
+	require.Equal(t, float64(2), eval(ev))
+
+	// Missing data evaluates to zero instead of panicking
+	require.Equal(t, float64(0), eval(&types.Event{}))
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// Keep this aligned with include/gadget/types.h
+const (
+	seqCPUShift    = 48
+	seqCounterMask = (1 << seqCPUShift) - 1
+)
+
+// findSeqMember returns the member of the event storing the sequence number
+// returned by gadget_seq_next(), if any
+func findSeqMember(typ *btf.Struct) (btf.Member, bool) {
+	for _, member := range typ.Members {
+		if member.Type.TypeName() != types.SeqTypeName {
+			continue
+		}
+		if err := verifyGadgetUint64Typedef(member.Type); err != nil {
+			continue
+		}
+		return member, true
+	}
+	return btf.Member{}, false
+}
+
+func hasSeqMember(typ *btf.Struct) bool {
+	_, ok := findSeqMember(typ)
+	return ok
+}
+
+// seqCPU returns the CPU stored in a sequence number
+func seqCPU(seq uint64) uint32 {
+	return uint32(seq >> seqCPUShift)
+}
+
+// compareSeq orders two sequence numbers by CPU and then by the order they
+// were generated on it, taking into account that the counter wraps around
+func compareSeq(a, b uint64) int {
+	if c := cmp.Compare(seqCPU(a), seqCPU(b)); c != 0 {
+		return c
+	}
+	diff := (a - b) & seqCounterMask
+	switch {
+	case diff == 0:
+		return 0
+	case diff > seqCounterMask/2:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// sortByCPU sorts the events, stamped with a sequence number at offset, by CPU
+// and by the order they were generated on it. The notices of lost events don't
+// have a sequence number, they stay where they are and the events are sorted
+// between them.
+func sortByCPU(events []*types.Event, offset uint32) {
+	for len(events) > 0 {
+		end := slices.IndexFunc(events, func(ev *types.Event) bool {
+			return ev.Type == eventtypes.EVENTS_DROPPED
+		})
+		if end == -1 {
+			end = len(events)
+		}
+		slices.SortStableFunc(events[:end], func(a, b *types.Event) int {
+			return compareSeq(
+				getAsInteger[uint64](a.Blob[types.IndexEBPF], offset),
+				getAsInteger[uint64](b.Blob[types.IndexEBPF], offset),
+			)
+		})
+		if end == len(events) {
+			return
+		}
+		events = events[end+1:]
+	}
+}
+
+// seqGap describes events lost on a CPU, detected because the sequence
+// numbers of the events received from it aren't consecutive
+type seqGap struct {
+	cpu uint32
+	// seq is the sequence number of the first event received after the gap
+	seq  uint64
+	lost uint64
+}
+
+// seqChecker verifies the sequence numbers stamped by gadget_seq_next() in the
+// events of a gadget. It isn't safe for concurrent use, it has to be called
+// in the order the events are read.
+type seqChecker struct {
+	// offset of the gadget_seq field in the event
+	offset uint32
+	// last sequence number received from each CPU
+	last map[uint32]uint64
+}
+
+func newSeqChecker(offset uint32) *seqChecker {
+	return &seqChecker{
+		offset: offset,
+		last:   make(map[uint32]uint64),
+	}
+}
+
+// check returns the events lost before the one in data, if any
+func (c *seqChecker) check(data []byte) (seqGap, bool) {
+	if int(c.offset)+8 > len(data) {
+		return seqGap{}, false
+	}
+	seq := getAsInteger[uint64](data, c.offset)
+	if seq == 0 {
+		// gadget_seq_next() failed
+		return seqGap{}, false
+	}
+
+	cpu := seqCPU(seq)
+	counter := seq & seqCounterMask
+
+	last, ok := c.last[cpu]
+	if !ok {
+		// Events generated before we started reading aren't lost
+		c.last[cpu] = counter
+		return seqGap{}, false
+	}
+
+	// The counter wraps around, it has seqCPUShift bits
+	diff := (counter - last) & seqCounterMask
+	switch {
+	case diff == 1:
+		c.last[cpu] = counter
+		return seqGap{}, false
+	case diff == 0 || diff > seqCounterMask/2:
+		// Duplicated or late event, e.g. because a nested program reserved
+		// its buffer first. It was already counted as lost.
+		return seqGap{}, false
+	}
+
+	c.last[cpu] = counter
+	return seqGap{cpu: cpu, seq: counter, lost: diff - 1}, true
+}
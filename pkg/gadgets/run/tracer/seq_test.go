@@ -0,0 +1,154 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func seqSample(cpu uint32, counter uint64) []byte {
+	// The sequence number is the second field of the event
+	data := make([]byte, 16)
+	binary.NativeEndian.PutUint64(data[8:], uint64(cpu)<<seqCPUShift|counter&seqCounterMask)
+	return data
+}
+
+func TestSeqChecker(t *testing.T) {
+	t.Parallel()
+
+	type sample struct {
+		cpu     uint32
+		counter uint64
+	}
+
+	type testDefinition struct {
+		samples      []sample
+		expectedGaps []seqGap
+	}
+
+	tests := map[string]testDefinition{
+		"consecutive": {
+			samples: []sample{{0, 5}, {0, 6}, {1, 1}, {0, 7}, {1, 2}},
+		},
+		"gap": {
+			samples:      []sample{{0, 1}, {0, 2}, {0, 5}, {0, 6}},
+			expectedGaps: []seqGap{{cpu: 0, seq: 5, lost: 2}},
+		},
+		"gaps_per_cpu": {
+			samples: []sample{{0, 1}, {1, 10}, {1, 12}, {0, 4}},
+			expectedGaps: []seqGap{
+				{cpu: 1, seq: 12, lost: 1},
+				{cpu: 0, seq: 4, lost: 2},
+			},
+		},
+		"late_event": {
+			samples:      []sample{{0, 1}, {0, 3}, {0, 2}, {0, 4}},
+			expectedGaps: []seqGap{{cpu: 0, seq: 3, lost: 1}},
+		},
+		"wrap_around": {
+			samples:      []sample{{1, seqCounterMask - 1}, {1, seqCounterMask}, {1, 0}, {1, 2}},
+			expectedGaps: []seqGap{{cpu: 1, seq: 2, lost: 1}},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newSeqChecker(8)
+			var gaps []seqGap
+			for _, s := range test.samples {
+				if gap, ok := c.check(seqSample(s.cpu, s.counter)); ok {
+					gaps = append(gaps, gap)
+				}
+			}
+			require.Equal(t, test.expectedGaps, gaps)
+		})
+	}
+}
+
+func TestSeqCheckerIgnoresMissingSeq(t *testing.T) {
+	t.Parallel()
+
+	c := newSeqChecker(8)
+
+	// gadget_seq_next() returns 0 on errors
+	_, ok := c.check(make([]byte, 16))
+	require.False(t, ok)
+
+	// Short samples are ignored
+	_, ok = c.check(make([]byte, 4))
+	require.False(t, ok)
+}
+
+func TestSortByCPU(t *testing.T) {
+	t.Parallel()
+
+	type sample struct {
+		cpu     uint32
+		counter uint64
+	}
+
+	samples := []sample{
+		{1, 10}, {0, 5}, {1, 9}, {2, 1}, {0, 6}, {1, 11},
+		// The counter of CPU 3 wraps around
+		{3, 0}, {3, seqCounterMask},
+	}
+	events := make([]*types.Event, 0, len(samples))
+	for _, s := range samples {
+		events = append(events, &types.Event{Blob: [][]byte{seqSample(s.cpu, s.counter)}})
+	}
+
+	sortByCPU(events, 8)
+
+	var sorted []sample
+	for _, ev := range events {
+		seq := binary.NativeEndian.Uint64(ev.Blob[types.IndexEBPF][8:])
+		sorted = append(sorted, sample{seqCPU(seq), seq & seqCounterMask})
+	}
+	require.Equal(t, []sample{
+		{0, 5}, {0, 6},
+		{1, 9}, {1, 10}, {1, 11},
+		{2, 1},
+		{3, seqCounterMask}, {3, 0},
+	}, sorted)
+}
+
+func TestSortByCPUWithNotices(t *testing.T) {
+	t.Parallel()
+
+	event := func(cpu uint32, counter uint64) *types.Event {
+		return &types.Event{Blob: [][]byte{seqSample(cpu, counter)}}
+	}
+	notice := &types.Event{Type: eventtypes.EVENTS_DROPPED, Dropped: 1}
+
+	events := []*types.Event{
+		event(1, 2), event(0, 1), notice, event(1, 4), event(0, 3),
+	}
+	sortByCPU(events, 8)
+
+	// The events are only sorted with the ones read on the same side of the
+	// notice
+	require.Equal(t, []*types.Event{
+		event(0, 1), event(1, 2), notice, event(0, 3), event(1, 4),
+	}, events)
+}
@@ -0,0 +1,131 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// errIncompatibleMap is returned when a pinned map doesn't match the spec of
+// the gadget
+var errIncompatibleMap = errors.New("map was created with a different definition")
+
+// errNoInstanceID is returned when the state of a gadget that doesn't run as
+// an instance of the daemon is persisted. Only those instances keep their ID
+// when they restart.
+var errNoInstanceID = fmt.Errorf("%s is only supported by the gadget instances run by ig daemon", persistStateParam)
+
+// stateDir returns the directory where the instance with the given ID pins its
+// state maps
+func stateDir(id string) (string, error) {
+	if id == "" {
+		return "", errNoInstanceID
+	}
+	return gadgets.InstanceStatePinPath(id)
+}
+
+// openSharedMap returns the map pinned in dir with the given name, creating it
+// from spec and pinning it if it doesn't exist yet. The map has to match spec,
+// so the gadgets sharing it agree on its definition.
+func openSharedMap(dir, name string, spec *ebpf.MapSpec) (*ebpf.Map, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating directory for shared maps: %w", err)
+	}
+	path := filepath.Join(dir, name)
+
+	// Another instance can pin the map between the load and the pin, the
+	// second attempt loads it
+	for attempt := 0; attempt < 2; attempt++ {
+		m, err := ebpf.LoadPinnedMap(path, nil)
+		if err == nil {
+			if err := spec.Compatible(m); err != nil {
+				m.Close()
+				return nil, fmt.Errorf("shared map %q: %w: %w", name, errIncompatibleMap, err)
+			}
+			return m, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("loading shared map %q: %w", name, err)
+		}
+
+		spec := spec.Copy()
+		spec.Pinning = ebpf.PinNone
+		m, err = ebpf.NewMap(spec)
+		if err != nil {
+			return nil, fmt.Errorf("creating shared map %q: %w", name, err)
+		}
+		err = m.Pin(path)
+		if err == nil {
+			return m, nil
+		}
+		m.Close()
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("pinning shared map %q: %w", name, err)
+		}
+	}
+	return nil, fmt.Errorf("shared map %q keeps being replaced", name)
+}
+
+// stateMapNames returns the maps of spec keeping the state of the gadget, e.g.
+// the ones of an aggregator, sorted by name. The maps of the helpers, whose
+// names start with gadget_, the ones in exclude, the internal maps holding
+// the global variables and the ones holding timers, which would keep running
+// once pinned, aren't part of the state.
+func stateMapNames(spec *ebpf.CollectionSpec, exclude map[string]struct{}) []string {
+	var names []string
+	for name, m := range spec.Maps {
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "gadget_") {
+			continue
+		}
+		if _, ok := exclude[name]; ok || types.MapHasTimer(m) {
+			continue
+		}
+		switch m.Type {
+		case ebpf.Hash, ebpf.Array, ebpf.PerCPUHash, ebpf.PerCPUArray,
+			ebpf.LRUHash, ebpf.LRUCPUHash, ebpf.LPMTrie:
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openStateMap returns the state map pinned in dir, creating it if it doesn't
+// exist yet. A map pinned by a previous version of the gadget with a different
+// definition is replaced, its state is lost.
+func openStateMap(dir, name string, spec *ebpf.MapSpec, logger logger.Logger) (*ebpf.Map, error) {
+	m, err := openSharedMap(dir, name, spec)
+	if !errors.Is(err, errIncompatibleMap) {
+		return m, err
+	}
+	logger.Warnf("Discarding the state kept in map %q: %v", name, err)
+	if err := os.Remove(filepath.Join(dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("removing state map %q: %w", name, err)
+	}
+	return openSharedMap(dir, name, spec)
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenSharedMap(t *testing.T) {
+	t.Parallel()
+
+	bpffs, err := os.MkdirTemp("/sys/fs/bpf", "shared-maps-test-")
+	if err != nil {
+		t.Skipf("bpffs isn't available: %v", err)
+	}
+	defer os.RemoveAll(bpffs)
+
+	spec := &ebpf.MapSpec{
+		Name:       "cache",
+		Type:       ebpf.Hash,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: 16,
+	}
+
+	producer, err := openSharedMap(bpffs, "cache", spec)
+	if err != nil {
+		t.Skipf("can't create maps: %v", err)
+	}
+	defer producer.Close()
+	require.NoError(t, producer.Put(uint32(1), uint64(42)))
+
+	consumer, err := openSharedMap(bpffs, "cache", spec)
+	require.NoError(t, err)
+	defer consumer.Close()
+	var value uint64
+	require.NoError(t, consumer.Lookup(uint32(1), &value))
+	require.Equal(t, uint64(42), value)
+
+	other := spec.Copy()
+	other.ValueSize = 4
+	_, err = openSharedMap(bpffs, "cache", other)
+	require.ErrorIs(t, err, errIncompatibleMap)
+}
+
+func TestStateDir(t *testing.T) {
+	t.Parallel()
+
+	_, err := stateDir("")
+	require.ErrorIs(t, err, errNoInstanceID)
+
+	dir, err := stateDir("0123456789abcdef")
+	require.NoError(t, err)
+	require.Equal(t, "/sys/fs/bpf/gadget/instances/0123456789abcdef", dir)
+}
+
+func TestStateMapNames(t *testing.T) {
+	t.Parallel()
+
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			"counts":                  {Type: ebpf.Hash},
+			"per_cpu":                 {Type: ebpf.PerCPUArray},
+			"prefixes":                {Type: ebpf.LPMTrie},
+			"events":                  {Type: ebpf.RingBuf},
+			"perf_events":             {Type: ebpf.PerfEventArray},
+			"ports":                   {Type: ebpf.Hash},
+			"gadget_heap":             {Type: ebpf.PerCPUArray},
+			"gadget_mntns_filter_map": {Type: ebpf.Hash},
+			".rodata":                 {Type: ebpf.Array},
+			"conn_timers": {Type: ebpf.Hash, Value: &btf.Struct{Name: "conn", Members: []btf.Member{
+				{Name: "timer", Type: &btf.Struct{Name: "bpf_timer"}},
+			}}},
+		},
+	}
+
+	names := stateMapNames(spec, map[string]struct{}{"ports": {}})
+	require.Equal(t, []string{"counts", "per_cpu", "prefixes"}, names)
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"fmt"
+)
+
+// isTailCallTarget returns whether the program is put in a program array by
+// fillTailCalls. It's only run through tail calls, so it isn't attached.
+func (t *Tracer) isTailCallTarget(progName string) bool {
+	_, ok := t.tailCallTargets[progName]
+	return ok
+}
+
+// fillTailCalls puts the programs of the gadget in its program arrays, as
+// declared in the metadata. It has to be done before the programs calling them
+// are attached, so no tail call fails.
+func (t *Tracer) fillTailCalls() error {
+	for mapName, programArray := range t.config.Metadata.TailCalls {
+		m, ok := t.collection.Maps[mapName]
+		if !ok {
+			return fmt.Errorf("program array %q not found", mapName)
+		}
+		for index, progName := range programArray.Programs {
+			prog, ok := t.collection.Programs[progName]
+			if !ok {
+				return fmt.Errorf("program %q of program array %q not found", progName, mapName)
+			}
+			if err := m.Put(index, prog); err != nil {
+				return fmt.Errorf("putting program %q at index %d of program array %q: %w",
+					progName, index, mapName, err)
+			}
+		}
+	}
+	return nil
+}
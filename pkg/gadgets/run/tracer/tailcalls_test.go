@@ -0,0 +1,78 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+func TestFillTailCalls(t *testing.T) {
+	t.Parallel()
+
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			"dissectors": {Name: "dissectors", Type: ebpf.ProgramArray, KeySize: 4, ValueSize: 4, MaxEntries: 2},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{
+			"parse_http": {
+				Name:    "parse_http",
+				Type:    ebpf.SocketFilter,
+				License: "GPL",
+				Instructions: asm.Instructions{
+					asm.Mov.Imm(asm.R0, 0),
+					asm.Return(),
+				},
+			},
+		},
+	}
+	collection, err := ebpf.NewCollection(spec)
+	if err != nil {
+		t.Skipf("can't load eBPF objects: %v", err)
+	}
+	defer collection.Close()
+
+	tracer := &Tracer{
+		config: &Config{Metadata: &types.GadgetMetadata{
+			TailCalls: map[string]types.ProgramArray{
+				"dissectors": {Programs: map[uint32]string{1: "parse_http"}},
+			},
+		}},
+		collection: collection,
+	}
+	tracer.tailCallTargets = tracer.config.Metadata.TailCallTargets()
+	require.True(t, tracer.isTailCallTarget("parse_http"))
+	require.False(t, tracer.isTailCallTarget("ig_execve"))
+
+	require.NoError(t, tracer.fillTailCalls())
+
+	var id ebpf.ProgramID
+	require.NoError(t, collection.Maps["dissectors"].Lookup(uint32(1), &id))
+	info, err := collection.Programs["parse_http"].Info()
+	require.NoError(t, err)
+	expected, ok := info.ID()
+	require.True(t, ok)
+	require.Equal(t, expected, id)
+
+	tracer.config.Metadata.TailCalls["dissectors"] = types.ProgramArray{Programs: map[uint32]string{0: "parse_dns"}}
+	require.ErrorContains(t, tracer.fillTailCalls(), "program \"parse_dns\" of program array \"dissectors\" not found")
+}
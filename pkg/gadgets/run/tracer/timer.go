@@ -0,0 +1,122 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/features"
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+// usesTimers returns whether p calls the bpf_timer helpers, e.g. to emit
+// periodic events from a timer callback
+func usesTimers(p *ebpf.ProgramSpec) bool {
+	for _, fn := range programHelpers(p) {
+		switch fn {
+		case asm.FnTimerInit, asm.FnTimerSetCallback, asm.FnTimerStart, asm.FnTimerCancel:
+			return true
+		}
+	}
+	return false
+}
+
+// probeBPFTimer checks whether the kernel supports bpf_timer, added in 5.15
+func probeBPFTimer() error {
+	if err := features.HaveProgramHelper(ebpf.SocketFilter, asm.FnTimerInit); err != nil {
+		return fmt.Errorf("bpf_timer isn't supported, it requires Linux 5.15: %w", err)
+	}
+	return nil
+}
+
+// isTimerForbidden returns whether programs of type pt can't use maps holding
+// a bpf_timer. The kernel refuses them for the tracing programs that can run
+// in any context, fentry and fexit programs can use them.
+func isTimerForbidden(pt ebpf.ProgramType) bool {
+	switch pt {
+	case ebpf.Kprobe, ebpf.TracePoint, ebpf.PerfEvent, ebpf.RawTracepoint:
+		return true
+	}
+	return false
+}
+
+// checkTimers checks the programs of spec can use the maps holding a
+// bpf_timer they reference, it returns a clear error instead of a verifier
+// one otherwise
+func checkTimers(spec *ebpf.CollectionSpec) error {
+	timerMaps := make(map[string]struct{})
+	for name, m := range spec.Maps {
+		if types.MapHasTimer(m) {
+			timerMaps[name] = struct{}{}
+		}
+	}
+	if len(timerMaps) == 0 {
+		return nil
+	}
+
+	progNames := make([]string, 0, len(spec.Programs))
+	for name := range spec.Programs {
+		progNames = append(progNames, name)
+	}
+	sort.Strings(progNames)
+
+	for _, name := range progNames {
+		p := spec.Programs[name]
+		if !isTimerForbidden(p.Type) {
+			continue
+		}
+		for _, ins := range p.Instructions {
+			if !ins.IsLoadFromMap() {
+				continue
+			}
+			if _, ok := timerMaps[ins.Reference()]; ok {
+				return fmt.Errorf("program %q of type %s can't use map %q holding a bpf_timer, "+
+					"use a fentry, fexit or syscall program instead", name, p.Type, ins.Reference())
+			}
+		}
+	}
+	return nil
+}
+
+// isSyscallProgram returns whether p is a syscall program. They aren't
+// attached, they're run once when the gadget starts, e.g. to initialize the
+// state of the gadget or to start its timers.
+func isSyscallProgram(p *ebpf.ProgramSpec) bool {
+	return p.Type == ebpf.Syscall
+}
+
+// prepareSyscallProgram marks p as sleepable, the kernel only loads sleepable
+// syscall programs
+func prepareSyscallProgram(p *ebpf.ProgramSpec) {
+	p.Flags |= unix.BPF_F_SLEEPABLE
+}
+
+// runSyscallProgram runs a syscall program once, it fails if the program
+// doesn't return 0
+func runSyscallProgram(prog *ebpf.Program) error {
+	ret, err := prog.Run(&ebpf.RunOptions{})
+	if err != nil {
+		return err
+	}
+	if ret != 0 {
+		return fmt.Errorf("program returned %d", int32(ret))
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTimers(t *testing.T) {
+	t.Parallel()
+
+	timer := &btf.Struct{Name: "bpf_timer", Size: 16}
+	value := &btf.Struct{Name: "conn", Size: 16, Members: []btf.Member{{Name: "timer", Type: timer}}}
+
+	program := func(typ ebpf.ProgramType, mapName string) *ebpf.ProgramSpec {
+		return &ebpf.ProgramSpec{
+			Type: typ,
+			Instructions: asm.Instructions{
+				asm.LoadMapPtr(asm.R1, 0).WithReference(mapName),
+				asm.FnTimerInit.Call(),
+				asm.Mov.Imm(asm.R0, 0),
+				asm.Return(),
+			},
+		}
+	}
+
+	type testDefinition struct {
+		program     *ebpf.ProgramSpec
+		expectedErr string
+	}
+
+	tests := map[string]testDefinition{
+		"fentry_with_timer": {
+			program: program(ebpf.Tracing, "timers"),
+		},
+		"syscall_with_timer": {
+			program: program(ebpf.Syscall, "timers"),
+		},
+		"kprobe_without_timer": {
+			program: program(ebpf.Kprobe, "counters"),
+		},
+		"kprobe_with_timer": {
+			program:     program(ebpf.Kprobe, "timers"),
+			expectedErr: "program \"prog\" of type Kprobe can't use map \"timers\" holding a bpf_timer",
+		},
+		"tracepoint_with_timer": {
+			program:     program(ebpf.TracePoint, "timers"),
+			expectedErr: "program \"prog\" of type TracePoint can't use map \"timers\" holding a bpf_timer",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			spec := &ebpf.CollectionSpec{
+				Maps: map[string]*ebpf.MapSpec{
+					"timers":   {Type: ebpf.Hash, Value: value},
+					"counters": {Type: ebpf.Hash, Value: &btf.Int{Size: 8}},
+				},
+				Programs: map[string]*ebpf.ProgramSpec{"prog": test.program},
+			}
+			require.True(t, usesTimers(test.program))
+
+			err := checkTimers(spec)
+			if test.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.expectedErr)
+		})
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/cilium/ebpf"
@@ -32,21 +33,27 @@ import (
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/perf"
 	"github.com/cilium/ebpf/ringbuf"
+	"github.com/tklauser/numcpus"
 	"golang.org/x/exp/constraints"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/btfgen"
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgetpolicy"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/internal/networktracer"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/internal/socketenricher"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/netnsenter"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
 	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 	bpfiterns "github.com/inspektor-gadget/inspektor-gadget/pkg/utils/bpf-iter-ns"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/experimental"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/syscalls"
 )
 
 // keep aligned with pkg/gadgets/common/types.h
@@ -62,8 +69,23 @@ type l4EndpointT struct {
 	proto uint16
 }
 
+type cidrT struct {
+	addr      [16]byte
+	version   uint8
+	prefixlen uint8
+	pad       [2]uint8 // manual padding to avoid issues between C and Go
+}
+
+// packetHeaderT is the header of struct gadget_packet_t, the data of the
+// packet follows it
+type packetHeaderT struct {
+	len    uint32
+	caplen uint32
+}
+
 type Config struct {
 	ProgContent []byte
+	BTFGen      []byte
 	Metadata    *types.GadgetMetadata
 	MountnsMap  *ebpf.Map
 
@@ -78,29 +100,108 @@ type linkSnapshotter struct {
 
 type Tracer struct {
 	config             *Config
+	image              string
+	imageDigest        string
 	eventCallback      func(*types.Event)
 	eventArrayCallback func([]*types.Event)
+	eventBatchCallback func([]*types.Event)
 	mu                 sync.Mutex
+	// deliverMu serializes the calls to the event callbacks, the events are
+	// delivered from the reading goroutine, the decode pool and the aggregator
+	deliverMu sync.Mutex
 
 	spec       *ebpf.CollectionSpec
 	collection *ebpf.Collection
+	// Keys to put in the maps of params defined with GADGET_MAP_PARAM()
+	paramMapKeys map[string][][]byte
+	// Keys of the params of the fields of the gadget_config map, by field
+	// name
+	configParams map[string]string
+	gadgetParams *params.Params
 	// Type describing the format the gadget uses
 	eventType *btf.Struct
 
 	socketEnricher *socketenricher.SocketEnricher
 	networkTracers map[string]*networktracer.Tracer[types.Event]
-
-	// Tracers related
-	ringbufReader *ringbuf.Reader
-	perfReader    *perf.Reader
+	// uprobeTracer attaches the uprobe programs to the libraries of the
+	// containers, it's nil if the gadget doesn't have any
+	uprobeTracer *uprobeTracer
+
+	// Tracers related: reader reads the buffers of the selected streams
+	streams []string
+	reader  *readerMux
+	// seqChecker detects lost events when the gadget stamps them with
+	// gadget_seq_next()
+	seqChecker *seqChecker
+	// droppedNotices contains the notices of lost events found by the
+	// reading goroutine, they are delivered with the samples read along
+	// with them to keep the order
+	droppedNotices []*types.Event
 
 	// Snapshotters related
 	linksSnapshotters []*linkSnapshotter
 
+	// Aggregators related
+	aggregatorMap       *ebpf.Map
+	aggregationInterval time.Duration
+
 	containers map[string]*containercollection.Container
-	links      []link.Link
+	// links of the attached programs, by program name
+	links map[string]link.Link
+	// tailCallTargets are the programs only run through tail calls
+	tailCallTargets map[string]struct{}
+
+	// pauseMu protects the attachment of the programs and the params once the
+	// gadget is running, i.e. when gadgetCtx is set, against Pause, Resume
+	// and UpdateParams
+	pauseMu   sync.Mutex
+	gadgetCtx gadgets.GadgetContext
+	paused    bool
 
 	eventFactory *types.EventFactory
+
+	// Number of goroutines decoding the samples read from the perf or ring
+	// buffer and whether their events keep the read order
+	decodeWorkers int
+	decodeOrdered bool
+
+	// Whether the CPU is added to the events and whether the events read at
+	// once are delivered grouped by CPU
+	stampCPU    bool
+	perCPUOrder bool
+
+	// Redactions of the sensitive fields applied to the events before they
+	// leave the node, and the key of their hashes
+	redactors []fieldRedactor
+	redactKey []byte
+	// String fields where the values looking like secrets are masked
+	// according to redactionConfig, the configuration of the daemon
+	redactionConfig *redaction.Config
+	stringFields    []stringField
+
+	// Size of the buffers used to send the events to user space, a 0
+	// ringbufSize keeps the one defined by the gadget
+	perfBufferPages int
+	ringbufSize     uint32
+
+	// The ring buffer wakes user space up once ringbufWakeupBytes are
+	// pending, the events are read at least every ringbufFlushTimeout.
+	ringbufWakeupBytes  uint64
+	ringbufFlushTimeout time.Duration
+
+	// Maximum number of events per second sent by each container and number
+	// of events it can send at once, rateLimiter reads how many were
+	// throttled
+	rateLimit      uint64
+	rateLimitBurst uint64
+	rateLimiter    *rateLimiter
+
+	// coverage counts the runs of the programs with probe-coverage, to report
+	// the ones that never ran
+	coverage *programCoverage
+
+	// cgroupRoot is the cgroup the cgroup programs are attached to
+	cgroupRoot *cgroupRoot
 }
 
 func (g *GadgetDesc) NewInstance() (gadgets.Gadget, error) {
@@ -118,6 +219,21 @@ func (t *Tracer) Init(gadgetCtx gadgets.GadgetContext) error {
 
 	params := gadgetCtx.GadgetParams()
 	args := gadgetCtx.Args()
+	if len(args) > 0 {
+		t.image = args[0]
+	}
+
+	t.decodeWorkers = int(params.Get(decodeWorkersParam).AsUint())
+	t.decodeOrdered = params.Get(decodeOrderedParam).AsBool()
+	t.stampCPU = params.Get(stampCPUParam).AsBool()
+	t.perCPUOrder = params.Get(perCPUOrderParam).AsBool()
+	t.perfBufferPages = int(params.Get(perfBufferPagesParam).AsUint())
+	t.ringbufSize = params.Get(ringbufSizeParam).AsUint32()
+	t.ringbufWakeupBytes = params.Get(wakeupBytesParam).AsUint64()
+	t.ringbufFlushTimeout = params.Get(flushTimeoutParam).AsDuration()
+	t.aggregationInterval = params.Get(aggregationIntervalParam).AsDuration()
+	t.rateLimit = params.Get(rateLimitParam).AsUint64()
+	t.rateLimitBurst = params.Get(rateLimitBurstParam).AsUint64()
 
 	pullSecretString := params.Get(pullSecret).AsString()
 	var secretBytes []byte
@@ -136,31 +252,80 @@ func (t *Tracer) Init(gadgetCtx gadgets.GadgetContext) error {
 	}
 
 	t.eventFactory = info.EventFactory
+	t.imageDigest = info.ImageDigest
 	t.config.ProgContent = info.ProgContent
+	t.config.BTFGen = info.BTFGen
 	t.spec, err = loadSpec(t.config.ProgContent)
 	if err != nil {
 		return err
 	}
 
 	t.config.Metadata = info.GadgetMetadata
+	t.tailCallTargets = t.config.Metadata.TailCallTargets()
+
+	// Fail with a clear error before loading the gadget if the kernel is
+	// missing something it needs
+	if err := checkRequirements(t.config.Metadata.Requirements); err != nil {
+		return err
+	}
+	for _, p := range t.spec.Programs {
+		if isCgroupProgram(p) {
+			if err := probeCgroupV2(); err != nil {
+				return fmt.Errorf("program %q: %w", p.Name, err)
+			}
+			break
+		}
+	}
+	for _, p := range t.spec.Programs {
+		if usesTimers(p) {
+			if err := probeBPFTimer(); err != nil {
+				return fmt.Errorf("program %q: %w", p.Name, err)
+			}
+			break
+		}
+	}
+	if err := checkTimers(t.spec); err != nil {
+		return err
+	}
+	for _, p := range t.spec.Programs {
+		if isSyscallProgram(p) {
+			prepareSyscallProgram(p)
+		}
+	}
 
 	// Create network tracers, one for each socket filter program.
 	// We need to make this in Init() because AttachContainer() is called before Run().
 	for _, p := range t.spec.Programs {
+		if t.isTailCallTarget(p.Name) {
+			continue
+		}
 		if p.Type == ebpf.SocketFilter && strings.HasPrefix(p.SectionName, "socket") {
-			networkTracer, err := networktracer.NewTracer[types.Event]()
+			networkTracer, err := networktracer.NewTracer[types.Event](t.image)
 			if err != nil {
 				t.Close()
 				return fmt.Errorf("creating network tracer: %w", err)
 			}
+			if err := networkTracer.SetInterfaces(params.Get(ifaceParam).AsString()); err != nil {
+				networkTracer.Close()
+				t.Close()
+				return err
+			}
 			t.networkTracers[p.Name] = networkTracer
 		}
+		if isUprobe(p) && t.uprobeTracer == nil {
+			t.uprobeTracer = newUprobeTracer(gadgetCtx.Logger())
+		}
 	}
 
 	return nil
 }
 
 func (t *Tracer) Close() {
+	// Pause and Resume can't be used anymore
+	t.pauseMu.Lock()
+	t.gadgetCtx = nil
+	t.pauseMu.Unlock()
+
 	if t.collection != nil {
 		t.collection.Close()
 		t.collection = nil
@@ -170,11 +335,8 @@ func (t *Tracer) Close() {
 	}
 	t.links = nil
 
-	if t.ringbufReader != nil {
-		t.ringbufReader.Close()
-	}
-	if t.perfReader != nil {
-		t.perfReader.Close()
+	if t.reader != nil {
+		t.reader.close()
 	}
 	if t.socketEnricher != nil {
 		t.socketEnricher.Close()
@@ -182,6 +344,17 @@ func (t *Tracer) Close() {
 	for _, networkTracer := range t.networkTracers {
 		networkTracer.Close()
 	}
+	if t.uprobeTracer != nil {
+		t.uprobeTracer.Close()
+	}
+	if t.coverage != nil {
+		t.coverage.Close()
+		t.coverage = nil
+	}
+	if t.cgroupRoot != nil {
+		t.cgroupRoot.Close()
+		t.cgroupRoot = nil
+	}
 }
 
 var (
@@ -253,54 +426,151 @@ func (t *Tracer) handleTracerMapDefinition(tracerMapName string) error {
 
 type loadingOptions struct {
 	collectionOptions ebpf.CollectionOptions
-	tracerMapName     string
+	// streams are the names of the tracers to read and tracerMapNames the
+	// maps they send their events to
+	streams        []string
+	tracerMapNames []string
+	// verboseVerifier requests the log of all the instructions and the
+	// statistics of the verifier
+	verboseVerifier bool
+	logger          logger.Logger
 }
 
 func (t *Tracer) loadeBPFObjects(opts loadingOptions) error {
 	var err error
 
-	tracerMapName := opts.tracerMapName
+	for _, tracerMapName := range opts.tracerMapNames {
+		if t.createdByTracerMapMacro(tracerMapName) {
+			err = t.handleTracerMapDefinition(tracerMapName)
+			if err != nil {
+				return fmt.Errorf("handling tracer map definition through GADGET_TRACER_MAP: %w", err)
+			}
+		}
 
-	if t.createdByTracerMapMacro(tracerMapName) {
-		err = t.handleTracerMapDefinition(tracerMapName)
-		if err != nil {
-			return fmt.Errorf("handling tracer map definition through GADGET_TRACER_MAP: %w", err)
+		if bufMap, ok := t.spec.Maps[tracerMapName]; ok && bufMap.Type == ebpf.RingBuf && t.ringbufSize != 0 {
+			bufMap.MaxEntries = t.ringbufSize
 		}
 	}
 
 	gadgets.FixBpfKtimeGetBootNs(t.spec.Programs)
 
-	t.collection, err = ebpf.NewCollectionWithOptions(t.spec, opts.collectionOptions)
+	t.collection, err = newCollection(t.spec, opts.collectionOptions, opts.verboseVerifier)
 	if err != nil {
 		return fmt.Errorf("create BPF collection: %w", err)
 	}
+	if opts.verboseVerifier {
+		for name, prog := range t.collection.Programs {
+			opts.logger.Debugf("Verifier log of program %q:\n%s", name, prog.VerifierLog)
+		}
+	}
+
+	if err := t.fillParamMaps(); err != nil {
+		return err
+	}
+	if err := t.writeConfig(); err != nil {
+		return err
+	}
 
 	// Some logic before loading the programs
-	if tracerMapName != "" {
-		m := t.collection.Maps[tracerMapName]
-		switch m.Type() {
-		case ebpf.RingBuf:
-			t.ringbufReader, err = ringbuf.NewReader(t.collection.Maps[tracerMapName])
-		case ebpf.PerfEventArray:
-			t.perfReader, err = perf.NewReader(t.collection.Maps[tracerMapName], gadgets.PerfBufferPages*os.Getpagesize())
+	if len(opts.tracerMapNames) > 0 {
+		readers := make([]bufferReader, 0, len(opts.tracerMapNames))
+		var pollInterval time.Duration
+		for _, tracerMapName := range opts.tracerMapNames {
+			reader, err := t.newBufferReader(t.collection.Maps[tracerMapName])
+			if err != nil {
+				for _, r := range readers {
+					r.close()
+				}
+				return fmt.Errorf("create BPF map reader: %w", err)
+			}
+			readers = append(readers, reader)
+
+			// Ring buffers that don't wake user space up on each event are
+			// read at least every ringbufFlushTimeout
+			if _, ok := reader.(*ringbufBufferReader); ok && t.ringbufWakeupBytes != 0 {
+				pollInterval = t.ringbufFlushTimeout
+			}
 		}
+		t.reader = newReaderMux(opts.streams, readers, pollInterval)
+	}
+
+	return nil
+}
+
+// newBufferReader returns a reader of the ring buffer or perf buffer m
+func (t *Tracer) newBufferReader(m *ebpf.Map) (bufferReader, error) {
+	switch m.Type() {
+	case ebpf.RingBuf:
+		reader, err := ringbuf.NewReader(m)
+		if err != nil {
+			return nil, err
+		}
+		return &ringbufBufferReader{reader: reader}, nil
+	case ebpf.PerfEventArray:
+		reader, err := perf.NewReader(m, t.perfBufferPages*os.Getpagesize())
 		if err != nil {
-			return fmt.Errorf("create BPF map reader: %w", err)
+			return nil, err
+		}
+		return &perfBufferReader{reader: reader}, nil
+	}
+	return nil, fmt.Errorf("map %q has type %s, expected %s or %s", m.String(), m.Type(), ebpf.RingBuf, ebpf.PerfEventArray)
+}
+
+// handleTracers returns the selected streams, from the comma separated list
+// stream, and the maps of their tracers. The maps of the other tracers aren't
+// read.
+func (t *Tracer) handleTracers(stream string) ([]string, []string, error) {
+	streams, tracers, err := t.config.Metadata.SelectTracers(stream)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mapNames := make([]string, 0, len(tracers))
+	for _, tracer := range tracers {
+		if t.spec.Maps[tracer.MapName] == nil {
+			return nil, nil, fmt.Errorf("map %q not found", tracer.MapName)
 		}
+		mapNames = append(mapNames, tracer.MapName)
 	}
 
-	return err
+	return streams, mapNames, nil
 }
 
-func (t *Tracer) handleTracers() (string, error) {
-	_, tracer := getAnyMapElem(t.config.Metadata.Tracers)
+// newSeqChecker returns a seqChecker if the events contain a sequence number,
+// nil otherwise
+func (t *Tracer) newSeqChecker(gadgetCtx gadgets.GadgetContext) *seqChecker {
+	member, ok := findSeqMember(t.eventType)
+	if ok && len(t.streams) > 1 {
+		// The sequence numbers are shared by all the streams, but each
+		// stream only sees its own events
+		gadgetCtx.Logger().Debugf("Sequence numbers aren't checked when reading several streams")
+		ok = false
+	}
+	if !ok {
+		if t.perCPUOrder {
+			gadgetCtx.Logger().Warnf("Gadget doesn't stamp its events with gadget_seq_next(), %s is ignored",
+				perCPUOrderParam)
+			t.perCPUOrder = false
+		}
+		return nil
+	}
+	return newSeqChecker(member.Offset.Bytes())
+}
 
-	traceMap := t.spec.Maps[tracer.MapName]
-	if traceMap == nil {
-		return "", fmt.Errorf("map %q not found", tracer.MapName)
+func (t *Tracer) handleAggregators() error {
+	_, aggregator := getAnyMapElem(t.config.Metadata.Aggregators)
+
+	m, ok := t.collection.Maps[aggregator.MapName]
+	if !ok {
+		return fmt.Errorf("map %q not found", aggregator.MapName)
+	}
+	if m.ValueSize() != t.eventType.Size {
+		return fmt.Errorf("map %q has a wrong value size, expected %d, got %d",
+			aggregator.MapName, t.eventType.Size, m.ValueSize())
 	}
 
-	return tracer.MapName, nil
+	t.aggregatorMap = m
+	return nil
 }
 
 func (t *Tracer) attachProgram(gadgetCtx gadgets.GadgetContext, p *ebpf.ProgramSpec, prog *ebpf.Program) (link.Link, error) {
@@ -309,6 +579,20 @@ func (t *Tracer) attachProgram(gadgetCtx gadgets.GadgetContext, p *ebpf.ProgramS
 	switch p.Type {
 	case ebpf.Kprobe:
 		switch {
+		case isUprobe(p):
+			// Attached to the libraries of each container
+			if host := t.hostContainer(); host != nil {
+				err := gadgetpolicy.CheckAttach(t.policyTarget(), gadgetpolicy.AttachTarget{
+					Host:   true,
+					Pid:    host.Pid,
+					Uprobe: true,
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+			logger.Debugf("Attaching uprobe %q to %q", p.Name, p.AttachTo)
+			return nil, t.uprobeTracer.AttachProg(p, prog)
 		case strings.HasPrefix(p.SectionName, "kprobe/"):
 			logger.Debugf("Attaching kprobe %q to %q", p.Name, p.AttachTo)
 			return link.Kprobe(p.AttachTo, prog, nil)
@@ -356,6 +640,24 @@ func (t *Tracer) attachProgram(gadgetCtx gadgets.GadgetContext, p *ebpf.ProgramS
 			Name:    p.AttachTo,
 			Program: prog,
 		})
+	case ebpf.CGroupSKB, ebpf.CGroupSock, ebpf.CGroupSockAddr, ebpf.CGroupSockopt,
+		ebpf.CGroupSysctl, ebpf.CGroupDevice, ebpf.SockOps:
+		if t.cgroupRoot == nil {
+			root, err := openCgroupRoot()
+			if err != nil {
+				return nil, err
+			}
+			if root.mounted {
+				logger.Debugf("Node only uses cgroup v1, mounted cgroup v2 at %q", root.path)
+			}
+			t.cgroupRoot = root
+		}
+		logger.Debugf("Attaching cgroup program %q to %q", p.Name, t.cgroupRoot.path)
+		return link.AttachCgroup(link.CgroupOptions{
+			Path:    t.cgroupRoot.path,
+			Attach:  p.AttachType,
+			Program: prog,
+		})
 	}
 
 	return nil, fmt.Errorf("unsupported program %q of type %q", p.Name, p.Type)
@@ -365,24 +667,41 @@ func (t *Tracer) installTracer(gadgetCtx gadgets.GadgetContext) error {
 	params := gadgetCtx.GadgetParams()
 
 	var err error
-	var tracerMapName string
+	var tracerMapNames []string
 
 	mapReplacements := map[string]*ebpf.Map{}
 
-	t.eventType, err = getEventTypeBTF(t.config.ProgContent, t.config.Metadata)
+	t.eventType, err = getEventTypeBTF(t.config.ProgContent, t.config.Metadata, params.Get(streamParam).AsString())
 	if err != nil {
 		return err
 	}
 
+	t.redactors, err = newFieldRedactors(t.eventType, t.config.Metadata, params.Get(redactParam).AsString())
+	if err != nil {
+		return fmt.Errorf("redacting fields: %w", err)
+	}
+	if len(t.redactors) > 0 {
+		t.redactKey, err = newRedactKey()
+		if err != nil {
+			return err
+		}
+	}
+	if t.redactionConfig = redaction.CurrentConfig(); t.redactionConfig != nil {
+		t.stringFields = newStringFields(t.eventType)
+	}
+
 	switch {
 	case len(t.config.Metadata.Tracers) > 0:
-		tracerMapName, err = t.handleTracers()
+		t.streams, tracerMapNames, err = t.handleTracers(params.Get(streamParam).AsString())
 		if err != nil {
 			return fmt.Errorf("handling trace programs: %w", err)
 		}
+		t.seqChecker = t.newSeqChecker(gadgetCtx)
 	}
 
-	t.setEBPFParameters(t.config.Metadata.EBPFParams, params)
+	if err := t.setEBPFParameters(t.config.Metadata.EBPFParams, params); err != nil {
+		return err
+	}
 	consts := t.config.Consts
 
 	// Handle special maps like mount ns filter, socket enricher, etc.
@@ -407,27 +726,147 @@ func (t *Tracer) installTracer(gadgetCtx gadgets.GadgetContext) error {
 		}
 	}
 
+	// The collection uses clones of the shared maps
+	for mapName, shared := range t.config.Metadata.SharedMaps {
+		mapSpec, ok := t.spec.Maps[mapName]
+		if !ok {
+			return fmt.Errorf("shared map %q not found", mapName)
+		}
+		m, err := openSharedMap(gadgets.SharedMapsPinPath, shared.Name, mapSpec)
+		if err != nil {
+			return err
+		}
+		defer m.Close()
+		mapReplacements[mapName] = m
+	}
+
+	// The state maps are pinned so the instance finds them again when it
+	// restarts
+	if params.Get(persistStateParam).AsBool() {
+		dir, err := stateDir(gadgetCtx.ID())
+		if err != nil {
+			return fmt.Errorf("persisting state: %w", err)
+		}
+		exclude := make(map[string]struct{}, len(mapReplacements)+len(t.paramMapKeys))
+		for name := range mapReplacements {
+			exclude[name] = struct{}{}
+		}
+		for name := range t.paramMapKeys {
+			exclude[name] = struct{}{}
+		}
+		for _, mapName := range stateMapNames(t.spec, exclude) {
+			m, err := openStateMap(dir, mapName, t.spec.Maps[mapName], gadgetCtx.Logger())
+			if err != nil {
+				return fmt.Errorf("persisting state: %w", err)
+			}
+			defer m.Close()
+			mapReplacements[mapName] = m
+		}
+	}
+
+	if t.ringbufWakeupBytes != 0 {
+		if hasConstant(t.spec, gadgets.RingbufWakeupBytesName) {
+			consts[gadgets.RingbufWakeupBytesName] = t.ringbufWakeupBytes
+		} else {
+			gadgetCtx.Logger().Warnf("Gadget doesn't support %s, events are read as soon as they are sent",
+				wakeupBytesParam)
+			t.ringbufWakeupBytes = 0
+		}
+	}
+
+	if t.rateLimit != 0 {
+		if hasConstant(t.spec, gadgets.RateLimitName) && t.spec.Maps[gadgets.RateLimitMapName] != nil {
+			consts[gadgets.RateLimitName] = t.rateLimit
+			consts[gadgets.RateLimitBurstName] = t.rateLimitBurst
+		} else {
+			gadgetCtx.Logger().Warnf("Gadget doesn't support %s, events aren't rate limited",
+				rateLimitParam)
+			t.rateLimit = 0
+		}
+	}
+
 	if err := t.spec.RewriteConstants(consts); err != nil {
 		return fmt.Errorf("rewriting constants: %w", err)
 	}
 
+	var kernelTypes *btf.Spec
+	if len(t.config.BTFGen) > 0 {
+		kernelTypes, err = btfgen.LoadSpecFromArchive(t.config.BTFGen)
+		if err != nil {
+			return fmt.Errorf("loading BTF from btfgen archive: %w", err)
+		}
+		if kernelTypes != nil {
+			gadgetCtx.Logger().Debugf("Using BTF information shipped with the gadget image")
+		}
+	}
+
 	// Load the ebpf objects
 	err = t.loadeBPFObjects(loadingOptions{
-		collectionOptions: ebpf.CollectionOptions{MapReplacements: mapReplacements},
-		tracerMapName:     tracerMapName,
+		collectionOptions: ebpf.CollectionOptions{
+			MapReplacements: mapReplacements,
+			Programs: ebpf.ProgramOptions{
+				KernelTypes: kernelTypes,
+			},
+		},
+		streams:         t.streams,
+		tracerMapNames:  tracerMapNames,
+		verboseVerifier: params.Get(verboseVerifierParam).AsBool(),
+		logger:          gadgetCtx.Logger(),
 	})
 	if err != nil {
 		return fmt.Errorf("loading eBPF objects: %w", err)
 	}
 
+	if t.rateLimit != 0 {
+		t.rateLimiter = newRateLimiter(t.collection.Maps[gadgets.RateLimitMapName])
+	}
+
+	if len(t.config.Metadata.Aggregators) > 0 {
+		if err := t.handleAggregators(); err != nil {
+			return fmt.Errorf("handling aggregators: %w", err)
+		}
+	}
+
+	if params.Get(probeCoverageParam).AsBool() {
+		coverage, err := newProgramCoverage()
+		if err != nil {
+			gadgetCtx.Logger().Warnf("Probe coverage isn't available: %v", err)
+		} else {
+			t.coverage = coverage
+		}
+	}
+
+	if err := t.fillTailCalls(); err != nil {
+		return fmt.Errorf("filling program arrays: %w", err)
+	}
+
+	// Syscall programs initialize the state of the gadget, e.g. start its
+	// timers, before the other programs see any event
+	for progName, p := range t.spec.Programs {
+		if !isSyscallProgram(p) {
+			continue
+		}
+		gadgetCtx.Logger().Debugf("Running syscall program %q", progName)
+		if err := runSyscallProgram(t.collection.Programs[progName]); err != nil {
+			return fmt.Errorf("running eBPF program %q: %w", progName, err)
+		}
+	}
+
 	// Attach programs
+	t.links = make(map[string]link.Link)
 	for progName, p := range t.spec.Programs {
+		if isSyscallProgram(p) || t.isTailCallTarget(progName) {
+			continue
+		}
 		l, err := t.attachProgram(gadgetCtx, p, t.collection.Programs[progName])
 		if err != nil {
 			return fmt.Errorf("attaching eBPF program %q: %w", progName, err)
 		}
+		if t.coverage != nil {
+			t.coverage.add(progName, t.collection.Programs[progName])
+		}
 		if l != nil {
-			t.links = append(t.links, l)
+			t.links[progName] = l
 		}
 
 		// we need to store links to iterators on a separated list because we need them to run the programs.
@@ -443,6 +882,15 @@ func (t *Tracer) installTracer(gadgetCtx gadgets.GadgetContext) error {
 	return nil
 }
 
+// syscallName returns the name of the syscall with the given number, or its
+// number like strace does if it's unknown
+func syscallName(nr uint64) string {
+	if name, ok := syscalls.GetSyscallNameByNumber(int(nr)); ok {
+		return name
+	}
+	return fmt.Sprintf("syscall_%x", nr)
+}
+
 func verifyGadgetUint64Typedef(t btf.Type) error {
 	typDef, ok := t.(*btf.Typedef)
 	if !ok {
@@ -472,9 +920,8 @@ func getAsInteger[OT constraints.Integer](data []byte, offset uint32) OT {
 
 // processEventFunc returns a callback that parses a binary encoded event in data, enriches and
 // returns it.
-func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []byte) *types.Event {
+func (t *Tracer) processEventFunc(logger logger.Logger) func(data []byte) *types.Event {
 	typ := t.eventType
-	logger := gadgetCtx.Logger()
 
 	var mntNsIdstart uint32
 	mountNsIdFound := false
@@ -496,7 +943,28 @@ func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []b
 	endpointDefs := []endpointDef{}
 	timestampsOffsets := []uint32{}
 
+	type packetDef struct {
+		name  string
+		start uint32
+		// maximum number of bytes of the packet
+		size uint32
+	}
+
+	packetDefs := []packetDef{}
+
 	enumSetters := []func(ev *types.Event, data []byte){}
+	syscallSetters := []func(ev *types.Event, data []byte){}
+
+	// The cpu column is only added if the events contain a sequence number,
+	// see calculateColumnsForClient()
+	var cpuSetter func(ev *types.Event, data []byte)
+	if seqMember, ok := findSeqMember(typ); ok && t.stampCPU {
+		setter := types.GetSetter[uint32](t.eventFactory, cpuColumnName)
+		seqOffset := seqMember.Offset.Bytes()
+		cpuSetter = func(ev *types.Event, data []byte) {
+			setter(ev, seqCPU(getAsInteger[uint64](data, seqOffset)))
+		}
+	}
 
 	// The same same data structure is always sent, so we can precalculate the offsets for
 	// different fields like mount ns id, endpoints, etc.
@@ -544,6 +1012,29 @@ func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []b
 				continue
 			}
 			timestampsOffsets = append(timestampsOffsets, member.Offset.Bytes())
+		case types.SyscallTypeName:
+			if err := verifyGadgetUint64Typedef(member.Type); err != nil {
+				logger.Warn("%s is not a uint64: %s", member.Name, err)
+				continue
+			}
+			offset := member.Offset.Bytes()
+			setter := types.GetSetter[string](t.eventFactory, member.Name)
+			syscallSetters = append(syscallSetters, func(ev *types.Event, data []byte) {
+				setter(ev, syscallName(getAsInteger[uint64](data, offset)))
+			})
+		case types.PacketTypeName:
+			typ, ok := member.Type.(*btf.Struct)
+			if !ok {
+				logger.Warn("%s is not a struct", member.Name)
+				continue
+			}
+			headerSize := uint32(unsafe.Sizeof(packetHeaderT{}))
+			if typ.Size <= headerSize {
+				logger.Warn("%s is too small: %d bytes", member.Name, typ.Size)
+				continue
+			}
+			p := packetDef{name: member.Name, start: member.Offset.Bytes(), size: typ.Size - headerSize}
+			packetDefs = append(packetDefs, p)
 		}
 
 		btfSpec, err := btf.LoadKernelSpec()
@@ -620,6 +1111,14 @@ func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []b
 	}
 
 	return func(data []byte) *types.Event {
+		// redact the sensitive fields before anything reads them
+		for _, redactor := range t.redactors {
+			redactor.redact(data, t.redactKey)
+		}
+		for _, field := range t.stringFields {
+			field.redact(data, t.redactionConfig)
+		}
+
 		// get mntNsId for enriching the event
 		mntNsId := uint64(0)
 		if mountNsIdFound {
@@ -638,17 +1137,20 @@ func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []b
 				size = 4
 			case 6:
 				size = 16
+			case 0:
+				// Not set, e.g. for the events not related to the network.
+				// It's kept empty so the next endpoints keep their index.
 			default:
 				logger.Warnf("bad IP version received: %d", endpointC.version)
 				continue
 			}
 
-			ipBytes := make(net.IP, size)
-			copy(ipBytes, endpointC.addr[:])
-
-			l3endpoint := eventtypes.L3Endpoint{
-				Addr:    ipBytes.String(),
-				Version: endpointC.version,
+			l3endpoint := eventtypes.L3Endpoint{}
+			if size != 0 {
+				ipBytes := make(net.IP, size)
+				copy(ipBytes, endpointC.addr[:])
+				l3endpoint.Addr = ipBytes.String()
+				l3endpoint.Version = endpointC.version
 			}
 
 			switch endpoint.typ {
@@ -680,6 +1182,18 @@ func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []b
 			timestamps = append(timestamps, t)
 		}
 
+		// handle packets, the data isn't copied
+		var packets []types.Packet
+		for _, packet := range packetDefs {
+			header := (*packetHeaderT)(unsafe.Pointer(&data[packet.start]))
+			start := packet.start + uint32(unsafe.Sizeof(packetHeaderT{}))
+			packets = append(packets, types.Packet{
+				Name: packet.name,
+				Len:  header.len,
+				Data: data[start : start+min(header.caplen, packet.size)],
+			})
+		}
+
 		ev := t.eventFactory.NewEvent()
 
 		ev.Type = eventtypes.NORMAL
@@ -687,12 +1201,21 @@ func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []b
 		ev.L3Endpoints = l3endpoints
 		ev.L4Endpoints = l4endpoints
 		ev.Timestamps = timestamps
+		ev.Packets = packets
 
 		// handle enums
 		for _, setter := range enumSetters {
 			setter(ev, data)
 		}
 
+		for _, setter := range syscallSetters {
+			setter(ev, data)
+		}
+
+		if cpuSetter != nil {
+			cpuSetter(ev, data)
+		}
+
 		// set ebpf data
 		ev.Blob[types.IndexEBPF] = data
 
@@ -700,53 +1223,420 @@ func (t *Tracer) processEventFunc(gadgetCtx gadgets.GadgetContext) func(data []b
 	}
 }
 
+// maxEventBatchSize is the maximum number of events delivered at once to the
+// batch event handler
+const maxEventBatchSize = 256
+
+// readSample reads the next sample from the ring buffers or the perf buffers
+// of the streams. It returns a record with a nil sample if samples were lost.
+func (t *Tracer) readSample(gadgetCtx gadgets.GadgetContext) (muxRecord, error) {
+	record, err := t.reader.read()
+	if err != nil {
+		return muxRecord{}, err
+	}
+	if record.lost != 0 {
+		// The sequence numbers tell exactly which events were lost, don't
+		// count them twice
+		if t.seqChecker == nil {
+			t.queueEventsDropped(record.stream, record.lost, "")
+		}
+		return record, nil
+	}
+	t.checkSeq(record)
+	return record, nil
+}
+
+// eventStream returns the stream the events of record are tagged with, only
+// when several streams are read
+func (t *Tracer) eventStream(record muxRecord) string {
+	if len(t.streams) < 2 {
+		return ""
+	}
+	return record.stream
+}
+
+// decodeRecord decodes the sample of record with cb, tagging its event with
+// its stream
+func (t *Tracer) decodeRecord(cb func([]byte) *types.Event, record muxRecord) *types.Event {
+	ev := cb(record.rawSample)
+	if stream := t.eventStream(record); stream != "" {
+		ev.Stream = stream
+	}
+	return ev
+}
+
+// queueEventsDropped queues a notice telling the consumers that count samples
+// of stream were lost. message replaces the default one if set. The notice is
+// delivered before the samples read afterwards, see appendDroppedNotices.
+func (t *Tracer) queueEventsDropped(stream string, count uint64, message string) {
+	base := gadgets.EventsDropped(t.image, count)
+	ev := &types.Event{
+		CommonData: base.CommonData,
+		Type:       base.Type,
+		Message:    base.Message,
+		Dropped:    base.Dropped,
+		Stream:     t.eventStream(muxRecord{stream: stream}),
+	}
+	if message != "" {
+		ev.Message = message
+	}
+	t.droppedNotices = append(t.droppedNotices, ev)
+}
+
+// appendDroppedNotices appends the queued notices of lost events to events
+// and empties the queue
+func (t *Tracer) appendDroppedNotices(events []*types.Event) []*types.Event {
+	events = append(events, t.droppedNotices...)
+	clear(t.droppedNotices)
+	t.droppedNotices = t.droppedNotices[:0]
+	return events
+}
+
+// waitSample blocks until a sample is available. When a ring buffer doesn't
+// wake user space up on each event, it checks for samples every
+// ringbufFlushTimeout to bound the time they wait.
+func (t *Tracer) waitSample(gadgetCtx gadgets.GadgetContext) (muxRecord, error) {
+	if t.reader.pollInterval == 0 {
+		t.reader.setDeadline(time.Time{})
+		return t.readSample(gadgetCtx)
+	}
+
+	for {
+		t.reader.setDeadline(time.Now().Add(t.reader.pollInterval))
+		record, err := t.readSample(gadgetCtx)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		return record, err
+	}
+}
+
+// checkSeq reports the events lost before the sample of record according to
+// its sequence number
+func (t *Tracer) checkSeq(record muxRecord) {
+	if t.seqChecker == nil || record.rawSample == nil {
+		return
+	}
+	gap, ok := t.seqChecker.check(record.rawSample)
+	if !ok {
+		return
+	}
+	t.reader.addLost(record.stream, gap.lost)
+	t.queueEventsDropped(record.stream, gap.lost, fmt.Sprintf("lost %d events on CPU %d before sequence number %d",
+		gap.lost, gap.cpu, gap.seq))
+}
+
+// reportLostSamples logs how many samples each stream lost while the gadget
+// ran
+func (t *Tracer) reportLostSamples(gadgetCtx gadgets.GadgetContext) {
+	summary, total := t.reader.lostSamples()
+	if total == 0 {
+		return
+	}
+	gadgetCtx.Logger().Warnf("Lost %d events (%s)", total, strings.Join(summary, ", "))
+}
+
+// handleReadError logs the error returned by readSample, unless the reader was
+// closed because the gadget is done
+func handleReadError(gadgetCtx gadgets.GadgetContext, err error) {
+	if errors.Is(err, ringbuf.ErrClosed) || errors.Is(err, perf.ErrClosed) {
+		// nothing to do, we're done
+		return
+	}
+	gadgetCtx.Logger().Errorf("%s", err)
+}
+
 func (t *Tracer) runTracers(gadgetCtx gadgets.GadgetContext) {
-	cb := t.processEventFunc(gadgetCtx)
+	cb := t.processEventFunc(gadgetCtx.Logger())
+	defer t.reportLostSamples(gadgetCtx)
+
+	if t.decodeWorkers > 0 {
+		t.runTracersParallel(gadgetCtx, cb)
+		return
+	}
+
+	// Events are grouped by CPU within the batches
+	if t.eventBatchCallback != nil || t.perCPUOrder {
+		t.runTracersBatch(gadgetCtx, cb)
+		return
+	}
 
 	for {
-		var rawSample []byte
+		record, err := t.waitSample(gadgetCtx)
+		if err != nil {
+			handleReadError(gadgetCtx, err)
+			return
+		}
+		if notices := t.appendDroppedNotices(nil); len(notices) > 0 {
+			t.deliverBatch(notices)
+		}
+		if record.rawSample == nil {
+			continue
+		}
 
-		if t.ringbufReader != nil {
-			record, err := t.ringbufReader.Read()
-			if err != nil {
-				if errors.Is(err, ringbuf.ErrClosed) {
-					// nothing to do, we're done
-					return
-				}
-				gadgetCtx.Logger().Errorf("read ring buffer: %w", err)
-				return
+		ev := t.decodeRecord(cb, record)
+		t.deliverMu.Lock()
+		t.eventCallback(ev)
+		t.deliverMu.Unlock()
+	}
+}
+
+// runTracersBatch waits for samples and then reads all the ones already
+// available, up to maxEventBatchSize, to deliver them in a single call.
+func (t *Tracer) runTracersBatch(gadgetCtx gadgets.GadgetContext, cb func([]byte) *types.Event) {
+	batch := make([]*types.Event, 0, maxEventBatchSize)
+
+	for {
+		record, err := t.waitSample(gadgetCtx)
+		if err != nil {
+			handleReadError(gadgetCtx, err)
+			return
+		}
+		batch = t.appendDroppedNotices(batch)
+		if record.rawSample != nil {
+			batch = append(batch, t.decodeRecord(cb, record))
+		}
+
+		// A deadline in the past makes the reader return the samples that are
+		// already available without waiting for new ones
+		t.reader.setDeadline(time.Now())
+		for len(batch) < maxEventBatchSize {
+			record, err := t.readSample(gadgetCtx)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
 			}
-			rawSample = record.RawSample
-		} else if t.perfReader != nil {
-			record, err := t.perfReader.Read()
 			if err != nil {
-				if errors.Is(err, perf.ErrClosed) {
-					return
+				if len(batch) > 0 {
+					t.deliverBatch(batch)
 				}
-				gadgetCtx.Logger().Errorf("read perf ring buffer: %w", err)
+				handleReadError(gadgetCtx, err)
 				return
 			}
-
-			if record.LostSamples != 0 {
-				gadgetCtx.Logger().Warnf("lost %d samples", record.LostSamples)
-				continue
+			batch = t.appendDroppedNotices(batch)
+			if record.rawSample != nil {
+				batch = append(batch, t.decodeRecord(cb, record))
 			}
-			rawSample = record.RawSample
 		}
 
-		ev := cb(rawSample)
+		if len(batch) > 0 {
+			t.deliverBatch(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// deliverBatch delivers the events read at once, grouped by CPU if
+// perCPUOrder is set
+func (t *Tracer) deliverBatch(events []*types.Event) {
+	if t.perCPUOrder {
+		sortByCPU(events, t.seqChecker.offset)
+	}
+
+	t.deliverMu.Lock()
+	defer t.deliverMu.Unlock()
+
+	if t.eventBatchCallback != nil {
+		t.eventBatchCallback(events)
+		return
+	}
+	for _, ev := range events {
 		t.eventCallback(ev)
 	}
 }
 
-func (t *Tracer) setEBPFParameters(ebpfParams map[string]types.EBPFParam, gadgetParams *params.Params) {
+// runTracersParallel reads the samples and hands them to a decodePool, so they
+// are decoded on t.decodeWorkers goroutines.
+func (t *Tracer) runTracersParallel(gadgetCtx gadgets.GadgetContext, cb func([]byte) *types.Event) {
+	pool := newDecodePool(t.decodeWorkers, t.decodeOrdered, cb, t.deliverBatch)
+	defer pool.close()
+
+	for {
+		record, err := t.waitSample(gadgetCtx)
+		if err != nil {
+			handleReadError(gadgetCtx, err)
+			return
+		}
+		for _, notice := range t.appendDroppedNotices(nil) {
+			pool.submitEvent(notice)
+		}
+		if record.rawSample == nil {
+			continue
+		}
+
+		pool.submit(record.rawSample, t.eventStream(record))
+	}
+}
+
+func (t *Tracer) setEBPFParameters(ebpfParams map[string]types.EBPFParam, gadgetParams *params.Params) error {
 	t.config.Consts = make(map[string]interface{})
+	t.paramMapKeys = make(map[string][][]byte)
+	t.configParams = make(map[string]string)
+	t.gadgetParams = gadgetParams
 	for varName, paramDef := range ebpfParams {
 		p := gadgetParams.Get(paramDef.Key)
+
+		// Fields of the gadget_config map are written once it's created, and
+		// every time they change
+		if _, ok := types.GetConfigField(t.spec, varName); ok {
+			t.configParams[varName] = paramDef.Key
+			continue
+		}
+
+		// Maps are always filled as they don't have a default in the eBPF
+		// code, the value of p is its default if it isn't set
+		if m, ok := t.spec.Maps[varName]; ok {
+			keys, err := mapParamKeys(m, p.AsString())
+			if err != nil {
+				return fmt.Errorf("setting param %q: %w", p.Key, err)
+			}
+			t.paramMapKeys[varName] = keys
+			continue
+		}
+
 		if !p.IsSet() {
 			continue
 		}
-		t.config.Consts[varName] = p.AsAny()
+
+		value := ebpfParamValue(p)
+
+		var btfVar *btf.Var
+		if err := t.spec.Types.TypeByName(varName, &btfVar); err == nil {
+			value, err = ebpfConstValue(btfVar.Type, p)
+			if err != nil {
+				return fmt.Errorf("setting param %q: %w", p.Key, err)
+			}
+		}
+
+		t.config.Consts[varName] = value
+	}
+	return nil
+}
+
+// ebpfConstValue returns the value of p for a constant of type typ, which
+// handles the types the type hint isn't enough for
+func ebpfConstValue(typ btf.Type, p *params.Param) (any, error) {
+	if enum := getEnum(typ); enum != nil {
+		return enumParamValue(enum, p.AsString())
+	}
+	if array := types.GetCharArray(typ); array != nil {
+		return stringParamValue(array, p.AsString())
+	}
+	if array := getIntArray(typ); array != nil {
+		return intArrayParamValue(array, p.AsString())
+	}
+	return ebpfParamValue(p), nil
+}
+
+// mapParamKeys returns the keys to put in the map m from the list of integers
+// value
+func mapParamKeys(m *ebpf.MapSpec, value string) ([][]byte, error) {
+	key := getInt(m.Key)
+	if key == nil {
+		return nil, fmt.Errorf("key of map %s is not an integer", m.Name)
+	}
+	return encodeIntList(value, key, m.MaxEntries)
+}
+
+// fillParamMaps puts the keys set by params in their maps
+func (t *Tracer) fillParamMaps() error {
+	for name, keys := range t.paramMapKeys {
+		m, ok := t.collection.Maps[name]
+		if !ok {
+			return fmt.Errorf("map %q of param not found", name)
+		}
+		value := make([]byte, m.ValueSize())
+		for _, key := range keys {
+			if err := m.Put(key, value); err != nil {
+				return fmt.Errorf("filling map %q of param: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// intArrayParamValue returns the list of integers value as the contents of
+// array, the elements that aren't set are zero
+func intArrayParamValue(array *btf.Array, value string) ([]byte, error) {
+	elem := getInt(array.Type)
+	values, err := encodeIntList(value, elem, array.Nelems)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, array.Nelems*elem.Size)
+	for _, v := range values {
+		buf = append(buf, v...)
+	}
+	return append(buf, make([]byte, cap(buf)-len(buf))...), nil
+}
+
+// stringParamValue returns value as a NUL terminated char array
+func stringParamValue(array *btf.Array, value string) ([]byte, error) {
+	if err := validateStringLength(array, value); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, array.Nelems)
+	copy(buf, value)
+	return buf, nil
+}
+
+// enumParamValue returns the value of the enum named name, with the size of the
+// enum
+func enumParamValue(enum *btf.Enum, name string) (any, error) {
+	for _, v := range enum.Values {
+		if v.Name != name {
+			continue
+		}
+		switch enum.Size {
+		case 1:
+			return uint8(v.Value), nil
+		case 2:
+			return uint16(v.Value), nil
+		case 4:
+			return uint32(v.Value), nil
+		case 8:
+			return v.Value, nil
+		default:
+			return nil, fmt.Errorf("unsupported size %d of enum %s", enum.Size, enum.Name)
+		}
+	}
+	return nil, fmt.Errorf("%q is not a value of enum %s", name, enum.Name)
+}
+
+// ipAddr returns the representation of ip in union gadget_ip_addr_t and its
+// version
+func ipAddr(ip net.IP) (addr [16]byte, version uint8) {
+	if ip4 := ip.To4(); ip4 != nil {
+		copy(addr[:], ip4)
+		return addr, 4
+	}
+	copy(addr[:], ip.To16())
+	return addr, 6
+}
+
+// ebpfParamValue returns the value of p with the representation of its eBPF
+// constant
+func ebpfParamValue(p *params.Param) any {
+	switch p.TypeHint {
+	case params.TypeDuration:
+		return uint64(p.AsDuration())
+	case params.TypeSize:
+		return p.AsSize()
+	case params.TypeIP:
+		var endpoint l3EndpointT
+		if ip := p.AsIP(); ip != nil {
+			endpoint.addr, endpoint.version = ipAddr(ip)
+		}
+		return endpoint
+	case params.TypeCIDR:
+		var cidr cidrT
+		if ipNet := p.AsCIDR(); ipNet != nil {
+			cidr.addr, cidr.version = ipAddr(ipNet.IP)
+			ones, _ := ipNet.Mask.Size()
+			cidr.prefixlen = uint8(ones)
+		}
+		return cidr
+	default:
+		return p.AsAny()
 	}
 }
 
@@ -800,7 +1690,7 @@ func splitAndConvert(data []byte, size int, cb func([]byte) *types.Event) []*typ
 }
 
 func (t *Tracer) runSnapshotter(gadgetCtx gadgets.GadgetContext) error {
-	cb := t.processEventFunc(gadgetCtx)
+	cb := t.processEventFunc(gadgetCtx.Logger())
 
 	events := []*types.Event{}
 
@@ -830,24 +1720,247 @@ func (t *Tracer) runSnapshotter(gadgetCtx gadgets.GadgetContext) error {
 	return nil
 }
 
+// runAggregator sends the entries of the aggregator map every
+// aggregationInterval, and once more when the gadget is done, deleting them so
+// the gadget starts aggregating from scratch.
+func (t *Tracer) runAggregator(gadgetCtx gadgets.GadgetContext) {
+	cb := t.processEventFunc(gadgetCtx.Logger())
+
+	ctx, cancel := gadgetcontext.WithTimeoutOrCancel(gadgetCtx.Context(), gadgetCtx.Timeout())
+	defer cancel()
+
+	ticker := time.NewTicker(t.aggregationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.flushAggregator(gadgetCtx, cb)
+			return
+		case <-ticker.C:
+			t.flushAggregator(gadgetCtx, cb)
+		}
+	}
+}
+
+// flushAggregator reads and deletes the entries of the aggregator map and
+// sends them as events
+func (t *Tracer) flushAggregator(gadgetCtx gadgets.GadgetContext, cb func([]byte) *types.Event) {
+	keys, err := aggregatorKeys(t.aggregatorMap)
+	if err != nil {
+		gadgetCtx.Logger().Warnf("listing aggregated entries: %s", err)
+	}
+
+	events := make([]*types.Event, 0, len(keys))
+	for _, key := range keys {
+		value := make([]byte, t.aggregatorMap.ValueSize())
+		if err := lookupAndDelete(t.aggregatorMap, key, value); err != nil {
+			if !errors.Is(err, ebpf.ErrKeyNotExist) {
+				gadgetCtx.Logger().Warnf("reading aggregated entry: %s", err)
+			}
+			continue
+		}
+		if ev := cb(value); ev != nil {
+			events = append(events, ev)
+		}
+	}
+
+	if len(events) == 0 {
+		return
+	}
+
+	t.deliverMu.Lock()
+	defer t.deliverMu.Unlock()
+
+	if t.eventBatchCallback != nil {
+		t.eventBatchCallback(events)
+		return
+	}
+	for _, ev := range events {
+		t.eventCallback(ev)
+	}
+}
+
+// aggregatorKeys returns the keys of the entries currently in m
+func aggregatorKeys(m *ebpf.Map) ([][]byte, error) {
+	var keys [][]byte
+	key := make([]byte, m.KeySize())
+	var value []byte
+
+	iter := m.Iterate()
+	for iter.Next(&key, &value) {
+		keys = append(keys, slices.Clone(key))
+	}
+	return keys, iter.Err()
+}
+
+// lookupAndDelete reads the entry of key into value and deletes it. Kernels
+// that can't do it atomically for hash maps (< 5.14) might lose the updates
+// done between both operations.
+func lookupAndDelete(m *ebpf.Map, key, value []byte) error {
+	err := m.LookupAndDelete(key, value)
+	if !errors.Is(err, ebpf.ErrNotSupported) {
+		return err
+	}
+
+	if err := m.Lookup(key, value); err != nil {
+		return err
+	}
+	return m.Delete(key)
+}
+
+// runRateLimiter reports the events throttled by the rate limiting every
+// rateLimitReportInterval, and once more when the gadget is done
+func (t *Tracer) runRateLimiter(gadgetCtx gadgets.GadgetContext) {
+	ctx, cancel := gadgetcontext.WithTimeoutOrCancel(gadgetCtx.Context(), gadgetCtx.Timeout())
+	defer cancel()
+
+	ticker := time.NewTicker(rateLimitReportInterval)
+	defer ticker.Stop()
+
+	warned := make(map[uint64]struct{})
+	for {
+		select {
+		case <-ctx.Done():
+			t.reportThrottled(gadgetCtx, warned)
+			return
+		case <-ticker.C:
+			t.reportThrottled(gadgetCtx, warned)
+		}
+	}
+}
+
+// reportThrottled updates the metric of the throttled events and warns the
+// first time the events of a container are throttled
+func (t *Tracer) reportThrottled(gadgetCtx gadgets.GadgetContext, warned map[uint64]struct{}) {
+	throttled, err := t.rateLimiter.throttled()
+	if err != nil {
+		gadgetCtx.Logger().Warnf("reading throttled events: %s", err)
+		return
+	}
+
+	for mntns, count := range throttled {
+		var namespace, pod, name string
+		t.mu.Lock()
+		for _, c := range t.containers {
+			if c.Mntns == mntns {
+				namespace, pod, name = c.K8s.Namespace, c.K8s.PodName, c.K8s.ContainerName
+				if name == "" {
+					name = c.Runtime.ContainerName
+				}
+				break
+			}
+		}
+		t.mu.Unlock()
+
+		gadgets.EventsThrottled(t.image, namespace, pod, name, count)
+
+		if _, ok := warned[mntns]; ok {
+			continue
+		}
+		warned[mntns] = struct{}{}
+		source := fmt.Sprintf("mount namespace %d", mntns)
+		if name != "" {
+			source = fmt.Sprintf("container %q", name)
+		}
+		gadgetCtx.Logger().Warnf("Events of %s are throttled, it exceeds the rate limit of %d events per second",
+			source, t.rateLimit)
+	}
+}
+
 func (t *Tracer) Run(gadgetCtx gadgets.GadgetContext) error {
+	possibleCPUs, err := numcpus.GetPossible()
+	if err != nil {
+		t.Close()
+		return fmt.Errorf("getting number of possible CPUs: %w", err)
+	}
+	policyGadget := policyGadget(t.image, t.imageDigest, t.spec, possibleCPUs)
+	if err := gadgetpolicy.Check(policyGadget); err != nil {
+		t.Close()
+		return err
+	}
+	warnings, err := gadgetpolicy.CheckLimits(policyGadget)
+	if err != nil {
+		t.Close()
+		return err
+	}
+	for _, warning := range warnings {
+		gadgetCtx.Logger().Warnf("Gadget exceeds the limits: %s", warning)
+	}
+
 	if err := t.installTracer(gadgetCtx); err != nil {
 		t.Close()
 		return fmt.Errorf("install tracer: %w", err)
 	}
+	t.pauseMu.Lock()
+	t.gadgetCtx = gadgetCtx
+	t.pauseMu.Unlock()
+	if t.coverage != nil {
+		defer t.reportCoverage(gadgetCtx)
+	}
 
-	if t.perfReader != nil || t.ringbufReader != nil {
+	if t.reader != nil {
 		go t.runTracers(gadgetCtx)
 	}
+	if t.rateLimiter != nil {
+		go t.runRateLimiter(gadgetCtx)
+	}
 	if len(t.linksSnapshotters) > 0 {
 		return t.runSnapshotter(gadgetCtx)
 	}
+	if t.aggregatorMap != nil {
+		t.runAggregator(gadgetCtx)
+		return nil
+	}
 	gadgetcontext.WaitForTimeoutOrDone(gadgetCtx)
 
 	return nil
 }
 
+// reportCoverage warns about the programs that never ran, e.g. because they're
+// attached to a function that isn't called on this kernel
+func (t *Tracer) reportCoverage(gadgetCtx gadgets.GadgetContext) {
+	logger := gadgetCtx.Logger()
+	counts := t.coverage.runCounts()
+	for name, count := range counts {
+		logger.Debugf("Program %q ran %d times", name, count)
+	}
+	for _, name := range zeroHitPrograms(counts) {
+		logger.Warnf("Program %q attached to %q never ran", name, t.spec.Programs[name].SectionName)
+	}
+}
+
+// hostContainer returns the attached container representing the host, if any
+func (t *Tracer) hostContainer() *containercollection.Container {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, c := range t.containers {
+		if isHostContainer(c) {
+			return c
+		}
+	}
+	return nil
+}
+
+// policyTarget returns the gadget as identified in the audit logs of the
+// gadget policy
+func (t *Tracer) policyTarget() *gadgetpolicy.Gadget {
+	return &gadgetpolicy.Gadget{Image: t.image, Digest: t.imageDigest}
+}
+
 func (t *Tracer) AttachContainer(container *containercollection.Container) error {
+	if isHostContainer(container) {
+		err := gadgetpolicy.CheckAttach(t.policyTarget(), gadgetpolicy.AttachTarget{
+			Host:   true,
+			Pid:    container.Pid,
+			Uprobe: t.uprobeTracer != nil,
+		})
+		if err != nil {
+			return fmt.Errorf("attaching to the host: %w", err)
+		}
+	}
+
 	t.mu.Lock()
 	t.containers[container.Runtime.ContainerID] = container
 	t.mu.Unlock()
@@ -857,6 +1970,11 @@ func (t *Tracer) AttachContainer(container *containercollection.Container) error
 			return err
 		}
 	}
+	if t.uprobeTracer != nil {
+		if err := t.uprobeTracer.Attach(container.Pid); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -871,6 +1989,9 @@ func (t *Tracer) DetachContainer(container *containercollection.Container) error
 			return err
 		}
 	}
+	if t.uprobeTracer != nil {
+		t.uprobeTracer.Detach(container.Pid)
+	}
 
 	return nil
 }
@@ -894,3 +2015,11 @@ func (t *Tracer) SetEventHandlerArray(handler any) {
 	}
 	t.eventArrayCallback = nh
 }
+
+func (t *Tracer) SetEventHandlerBatch(handler any) {
+	nh, ok := handler.(func(ev []*types.Event))
+	if !ok {
+		panic("event handler invalid")
+	}
+	t.eventBatchCallback = nh
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+func TestFlushAggregator(t *testing.T) {
+	t.Parallel()
+
+	m, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Hash, KeySize: 4, ValueSize: 4, MaxEntries: 4})
+	if err != nil {
+		t.Skipf("can't create eBPF map: %v", err)
+	}
+	defer m.Close()
+
+	for _, k := range []uint32{1, 2, 3} {
+		require.NoError(t, m.Put(k, k*10))
+	}
+
+	var delivered []*types.Event
+	tracer := &Tracer{aggregatorMap: m}
+	tracer.eventBatchCallback = func(events []*types.Event) {
+		// Fails if the callback is called without holding deliverMu
+		require.False(t, tracer.deliverMu.TryLock())
+		delivered = append(delivered, events...)
+	}
+
+	// The entry whose value is 20 is dropped by the callback
+	cb := func(value []byte) *types.Event {
+		if value[0] == 20 {
+			return nil
+		}
+		return &types.Event{Message: string(rune('0' + value[0]/10))}
+	}
+	tracer.flushAggregator(nil, cb)
+
+	require.Len(t, delivered, 2)
+	for _, ev := range delivered {
+		require.NotNil(t, ev)
+		require.Contains(t, []string{"1", "3"}, ev.Message)
+	}
+
+	// The entries are deleted once they're sent
+	var key, value uint32
+	require.False(t, m.Iterate().Next(&key, &value))
+}
+
+func TestDroppedNotices(t *testing.T) {
+	t.Parallel()
+
+	tracer := &Tracer{image: "trace_exec"}
+	tracer.queueEventsDropped("", 3, "")
+	tracer.queueEventsDropped("", 2, "lost 2 events on CPU 1 before sequence number 10")
+
+	sample := &types.Event{}
+	batch := tracer.appendDroppedNotices([]*types.Event{sample})
+	require.Len(t, batch, 3)
+	require.Same(t, sample, batch[0])
+	require.Equal(t, uint64(3), batch[1].Dropped)
+	require.Equal(t, "lost 2 events on CPU 1 before sequence number 10", batch[2].Message)
+
+	// The notices are only delivered once
+	require.Empty(t, tracer.appendDroppedNotices(nil))
+}
@@ -0,0 +1,310 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// Directories where the libraries are looked for when the container isn't
+// using them yet
+var libraryDirs = []string{
+	"/lib",
+	"/lib64",
+	"/usr/lib",
+	"/usr/lib64",
+	"/usr/local/lib",
+	"/lib/x86_64-linux-gnu",
+	"/usr/lib/x86_64-linux-gnu",
+	"/lib/aarch64-linux-gnu",
+	"/usr/lib/aarch64-linux-gnu",
+}
+
+// uprobeTarget is where a uprobe program is attached, it's defined by the
+// section name of the program: uprobe/<library>:<symbol>, e.g.
+// uprobe/libssl:SSL_write
+type uprobeTarget struct {
+	library string
+	symbol  string
+	ret     bool
+}
+
+func isUprobe(p *ebpf.ProgramSpec) bool {
+	return p.Type == ebpf.Kprobe &&
+		(strings.HasPrefix(p.SectionName, "uprobe/") || strings.HasPrefix(p.SectionName, "uretprobe/"))
+}
+
+func parseUprobeTarget(p *ebpf.ProgramSpec) (uprobeTarget, error) {
+	library, symbol, ok := strings.Cut(p.AttachTo, ":")
+	if !ok || library == "" || symbol == "" {
+		return uprobeTarget{}, fmt.Errorf("invalid uprobe %q of program %q, expected <library>:<symbol>",
+			p.AttachTo, p.Name)
+	}
+	return uprobeTarget{
+		library: library,
+		symbol:  symbol,
+		ret:     strings.HasPrefix(p.SectionName, "uretprobe/"),
+	}, nil
+}
+
+// isLibraryFile returns whether name is the file of library, e.g. libssl.so
+// or libssl.so.3 for libssl
+func isLibraryFile(name, library string) bool {
+	return name == library+".so" || strings.HasPrefix(name, library+".so.")
+}
+
+// findLibrary returns the path of library in the filesystem of the process
+// with the given root, looking first at the files it has mapped and then at
+// the usual library directories
+func findLibrary(root, mapsPath, library string) (string, error) {
+	if f, err := os.Open(mapsPath); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 6 {
+				continue
+			}
+			path := fields[5]
+			if isLibraryFile(filepath.Base(path), library) {
+				return filepath.Join(root, path), nil
+			}
+		}
+	}
+
+	for _, dir := range libraryDirs {
+		matches, err := filepath.Glob(filepath.Join(root, dir, library+".so*"))
+		if err != nil {
+			return "", err
+		}
+		for _, match := range matches {
+			if !isLibraryFile(filepath.Base(match), library) {
+				continue
+			}
+			// Symlinks could point outside the root of the process, the
+			// file they point to is usually in the same directory
+			fi, err := os.Lstat(match)
+			if err != nil || !fi.Mode().IsRegular() {
+				continue
+			}
+			return match, nil
+		}
+	}
+
+	return "", os.ErrNotExist
+}
+
+type uprobeProg struct {
+	name   string
+	target uprobeTarget
+	prog   *ebpf.Program
+}
+
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+type uprobeAttachmentKey struct {
+	prog  string
+	inode inode
+}
+
+type uprobeAttachment struct {
+	link link.Link
+	// pids of the containers using the library
+	users map[uint32]struct{}
+}
+
+// uprobeTracer attaches the uprobe programs of a gadget to the libraries used
+// by the containers. Uprobes are attached to files, containers using the same
+// library file share the attachment.
+type uprobeTracer struct {
+	logger logger.Logger
+
+	mu    sync.Mutex
+	progs []*uprobeProg
+	// pids of the attached containers
+	pids        map[uint32]struct{}
+	attachments map[uprobeAttachmentKey]*uprobeAttachment
+	// paused is set while the programs are detached, the containers attached
+	// meanwhile are attached on resume
+	paused bool
+}
+
+func newUprobeTracer(logger logger.Logger) *uprobeTracer {
+	return &uprobeTracer{
+		logger:      logger,
+		pids:        make(map[uint32]struct{}),
+		attachments: make(map[uprobeAttachmentKey]*uprobeAttachment),
+	}
+}
+
+// AttachProg attaches prog to the containers already attached and to the ones
+// attached later
+func (t *uprobeTracer) AttachProg(p *ebpf.ProgramSpec, prog *ebpf.Program) error {
+	target, err := parseUprobeTarget(p)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	up := &uprobeProg{name: p.Name, target: target, prog: prog}
+	t.progs = append(t.progs, up)
+	if t.paused {
+		return nil
+	}
+	for pid := range t.pids {
+		if err := t.attach(up, pid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *uprobeTracer) Attach(pid uint32) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pids[pid] = struct{}{}
+	if t.paused {
+		return nil
+	}
+	for _, up := range t.progs {
+		if err := t.attach(up, pid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pause detaches the programs from all the containers
+func (t *uprobeTracer) Pause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.paused = true
+	for key, a := range t.attachments {
+		gadgets.CloseLink(a.link)
+		delete(t.attachments, key)
+	}
+}
+
+// Resume attaches the programs again to the containers attached meanwhile
+func (t *uprobeTracer) Resume() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.paused = false
+	for pid := range t.pids {
+		for _, up := range t.progs {
+			if err := t.attach(up, pid); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *uprobeTracer) attach(up *uprobeProg, pid uint32) error {
+	root := fmt.Sprintf("/proc/%d/root", pid)
+	path, err := findLibrary(root, fmt.Sprintf("/proc/%d/maps", pid), up.target.library)
+	if err != nil {
+		t.logger.Debugf("Library %q not found for pid %d, not attaching %q", up.target.library, pid, up.name)
+		return nil
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("getting info of %q: %w", path, err)
+	}
+	stat, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("getting inode of %q", path)
+	}
+	key := uprobeAttachmentKey{prog: up.name, inode: inode{dev: stat.Dev, ino: stat.Ino}}
+	if a, ok := t.attachments[key]; ok {
+		a.users[pid] = struct{}{}
+		return nil
+	}
+
+	ex, err := link.OpenExecutable(path)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", path, err)
+	}
+
+	var l link.Link
+	if up.target.ret {
+		t.logger.Debugf("Attaching uretprobe %q to %q in %q", up.name, up.target.symbol, path)
+		l, err = ex.Uretprobe(up.target.symbol, up.prog, nil)
+	} else {
+		t.logger.Debugf("Attaching uprobe %q to %q in %q", up.name, up.target.symbol, path)
+		l, err = ex.Uprobe(up.target.symbol, up.prog, nil)
+	}
+	if errors.Is(err, link.ErrNoSymbol) {
+		// Gadgets can support several implementations of a library, like
+		// OpenSSL and BoringSSL, that don't provide the same symbols
+		t.logger.Debugf("Symbol %q not found in %q, not attaching %q", up.target.symbol, path, up.name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("attaching %q to %q in %q: %w", up.name, up.target.symbol, path, err)
+	}
+
+	t.attachments[key] = &uprobeAttachment{
+		link:  l,
+		users: map[uint32]struct{}{pid: {}},
+	}
+	return nil
+}
+
+func (t *uprobeTracer) Detach(pid uint32) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pids, pid)
+	for key, a := range t.attachments {
+		delete(a.users, pid)
+		if len(a.users) == 0 {
+			gadgets.CloseLink(a.link)
+			delete(t.attachments, key)
+		}
+	}
+}
+
+func (t *uprobeTracer) Close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, a := range t.attachments {
+		gadgets.CloseLink(a.link)
+		delete(t.attachments, key)
+	}
+}
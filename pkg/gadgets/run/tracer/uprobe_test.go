@@ -0,0 +1,134 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUprobeTarget(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		sectionName       string
+		attachTo          string
+		expected          uprobeTarget
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"uprobe": {
+			sectionName: "uprobe/libssl:SSL_write",
+			attachTo:    "libssl:SSL_write",
+			expected:    uprobeTarget{library: "libssl", symbol: "SSL_write"},
+		},
+		"uretprobe": {
+			sectionName: "uretprobe/libgnutls:gnutls_record_recv",
+			attachTo:    "libgnutls:gnutls_record_recv",
+			expected:    uprobeTarget{library: "libgnutls", symbol: "gnutls_record_recv", ret: true},
+		},
+		"missing_symbol": {
+			sectionName:       "uprobe/libssl",
+			attachTo:          "libssl",
+			expectedErrString: "invalid uprobe \"libssl\" of program \"prog\", expected <library>:<symbol>",
+		},
+		"empty_library": {
+			sectionName:       "uprobe/:SSL_write",
+			attachTo:          ":SSL_write",
+			expectedErrString: "invalid uprobe \":SSL_write\" of program \"prog\", expected <library>:<symbol>",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &ebpf.ProgramSpec{
+				Name:        "prog",
+				Type:        ebpf.Kprobe,
+				SectionName: test.sectionName,
+				AttachTo:    test.attachTo,
+			}
+			require.True(t, isUprobe(p))
+
+			target, err := parseUprobeTarget(p)
+			if test.expectedErrString != "" {
+				require.EqualError(t, err, test.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, target)
+		})
+	}
+}
+
+func TestFindLibrary(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	libDir := filepath.Join(root, "usr/lib/x86_64-linux-gnu")
+	require.NoError(t, os.MkdirAll(libDir, 0o755))
+	for _, name := range []string{"libssl.so.3", "libssl3.so", "libgnutls.so.30"} {
+		require.NoError(t, os.WriteFile(filepath.Join(libDir, name), nil, 0o644))
+	}
+	require.NoError(t, os.Symlink("libssl.so.3", filepath.Join(libDir, "libssl.so")))
+
+	maps := filepath.Join(t.TempDir(), "maps")
+	require.NoError(t, os.WriteFile(maps, []byte(
+		"7f0000000000-7f0000001000 r--p 00000000 00:1f 1234 /opt/app/lib/libgnutls.so.30.36.0\n"+
+			"7ffd00000000-7ffd00021000 rw-p 00000000 00:00 0 [stack]\n"), 0o644))
+
+	type testDefinition struct {
+		library  string
+		expected string
+		notFound bool
+	}
+
+	tests := map[string]testDefinition{
+		"mapped": {
+			library:  "libgnutls",
+			expected: filepath.Join(root, "/opt/app/lib/libgnutls.so.30.36.0"),
+		},
+		"library_dirs": {
+			library:  "libssl",
+			expected: filepath.Join(libDir, "libssl.so.3"),
+		},
+		"not_found": {
+			library:  "libcrypto",
+			notFound: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path, err := findLibrary(root, maps, test.library)
+			if test.notFound {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, path)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/cilium/ebpf"
+)
+
+const (
+	// verboseVerifierLogSize is the initial size of the verifier log buffer
+	// when the log of all the instructions is requested
+	verboseVerifierLogSize = 1024 * 1024
+	// maxVerifierLogSize is the size of the verifier log buffer from which a
+	// truncated log is reported as is
+	maxVerifierLogSize = 64 * 1024 * 1024
+)
+
+// newCollection loads the collection of spec. When the verifier rejects a
+// program and its log is truncated, the collection is loaded again with bigger
+// log buffers, so the whole log is reported.
+func newCollection(spec *ebpf.CollectionSpec, opts ebpf.CollectionOptions, verbose bool) (*ebpf.Collection, error) {
+	if verbose {
+		opts.Programs.LogLevel = ebpf.LogLevelInstruction | ebpf.LogLevelStats
+		opts.Programs.LogSize = verboseVerifierLogSize
+	}
+
+	for {
+		collection, err := ebpf.NewCollectionWithOptions(spec, opts)
+		var ve *ebpf.VerifierError
+		if err == nil || !errors.As(err, &ve) || !ve.Truncated {
+			return collection, err
+		}
+
+		size := opts.Programs.LogSize
+		if size == 0 {
+			size = ebpf.DefaultVerifierLogSize
+		}
+		if size >= maxVerifierLogSize {
+			return nil, err
+		}
+		opts.Programs.LogSize = min(size*2, maxVerifierLogSize)
+	}
+}
+
+// verifierStats returns the statistics at the end of the log of the verifier,
+// like "processed 42 insns (limit 1000000) ..."
+func verifierStats(log string) string {
+	for _, line := range strings.Split(log, "\n") {
+		if strings.HasPrefix(line, "processed ") {
+			return strings.TrimSpace(line)
+		}
+	}
+	return ""
+}
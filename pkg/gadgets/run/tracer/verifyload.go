@@ -0,0 +1,123 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/btfgen"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/oci"
+)
+
+// ProgramLoadResult is the result of loading a program of a gadget through
+// the verifier
+type ProgramLoadResult struct {
+	Name    string
+	Type    ebpf.ProgramType
+	Section string
+	// Err is the error loading the program, an *ebpf.VerifierError if the
+	// verifier rejected it
+	Err error
+	// Stats are the statistics of the verifier, e.g. the number of processed
+	// instructions
+	Stats string
+	// VerifierLog is the whole log of the verifier, only with verbose
+	VerifierLog string
+}
+
+// VerifyLoad loads the programs of the gadget through the verifier of the
+// current kernel, one by one, and unloads them right away. Nothing is
+// attached. The maps are created once and shared by all the programs, with the
+// same adjustments as when the gadget runs. verbose requests the log of all
+// the instructions.
+func VerifyLoad(gadget *oci.GadgetImage, verbose bool) ([]ProgramLoadResult, error) {
+	spec, err := loadSpec(gadget.EbpfObject)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tracer{spec: spec}
+	tracerMaps, _ := types.GetGadgetIdentByPrefix(spec, types.TracerMapPrefix)
+	for _, name := range tracerMaps {
+		if err := t.handleTracerMapDefinition(name); err != nil {
+			return nil, fmt.Errorf("handling tracer map %q: %w", name, err)
+		}
+	}
+	gadgets.FixBpfKtimeGetBootNs(spec.Programs)
+
+	var kernelTypes *btf.Spec
+	if len(gadget.BTFGen) > 0 {
+		kernelTypes, err = btfgen.LoadSpecFromArchive(gadget.BTFGen)
+		if err != nil {
+			return nil, fmt.Errorf("loading BTF from btfgen archive: %w", err)
+		}
+	}
+
+	mapsSpec := spec.Copy()
+	mapsSpec.Programs = nil
+	maps, err := ebpf.NewCollection(mapsSpec)
+	if err != nil {
+		return nil, fmt.Errorf("creating maps: %w", err)
+	}
+	defer maps.Close()
+
+	names := make([]string, 0, len(spec.Programs))
+	for name := range spec.Programs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]ProgramLoadResult, 0, len(names))
+	for _, name := range names {
+		p := spec.Programs[name]
+		result := ProgramLoadResult{
+			Name:    name,
+			Type:    p.Type,
+			Section: p.SectionName,
+		}
+
+		progSpec := spec.Copy()
+		progSpec.Programs = map[string]*ebpf.ProgramSpec{name: progSpec.Programs[name]}
+		opts := ebpf.CollectionOptions{
+			MapReplacements: maps.Maps,
+			Programs: ebpf.ProgramOptions{
+				KernelTypes: kernelTypes,
+				LogLevel:    ebpf.LogLevelStats,
+			},
+		}
+
+		collection, err := newCollection(progSpec, opts, verbose)
+		if err != nil {
+			result.Err = err
+		} else {
+			log := collection.Programs[name].VerifierLog
+			result.Stats = verifierStats(log)
+			if verbose {
+				result.VerifierLog = log
+			}
+			collection.Close()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// ConfigMapName is the name of the map holding the params of a gadget that
+// can be changed while it runs, see include/gadget/config.h. Its only entry,
+// with key 0, is a struct whose fields are set from the ebpfParams with the
+// same name.
+const ConfigMapName = "gadget_config"
+
+// GetConfigStruct returns the struct stored in the gadget_config map, or nil
+// if the gadget doesn't have one
+func GetConfigStruct(spec *ebpf.CollectionSpec) (*btf.Struct, error) {
+	m, ok := spec.Maps[ConfigMapName]
+	if !ok {
+		return nil, nil
+	}
+	if m.Type != ebpf.Hash && m.Type != ebpf.Array {
+		return nil, fmt.Errorf("map %q has type %s, expected %s or %s", ConfigMapName, m.Type, ebpf.Hash, ebpf.Array)
+	}
+	if m.MaxEntries != 1 {
+		return nil, fmt.Errorf("map %q has %d entries, expected 1", ConfigMapName, m.MaxEntries)
+	}
+	if m.KeySize != 4 {
+		return nil, fmt.Errorf("map %q has a key of %d bytes, expected a __u32", ConfigMapName, m.KeySize)
+	}
+	if m.Value == nil {
+		return nil, fmt.Errorf("map %q doesn't have BTF information about its value", ConfigMapName)
+	}
+	s, ok := btf.UnderlyingType(m.Value).(*btf.Struct)
+	if !ok {
+		return nil, fmt.Errorf("value of map %q is %s, expected a struct", ConfigMapName, m.Value)
+	}
+	return s, nil
+}
+
+// GetConfigField returns the field of the struct stored in the gadget_config
+// map with the given name, if any
+func GetConfigField(spec *ebpf.CollectionSpec, name string) (btf.Member, bool) {
+	s, err := GetConfigStruct(spec)
+	if err != nil || s == nil {
+		return btf.Member{}, false
+	}
+	for _, member := range s.Members {
+		if member.Name == name {
+			return member, true
+		}
+	}
+	return btf.Member{}, false
+}
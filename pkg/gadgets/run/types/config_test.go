@@ -0,0 +1,109 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfigStruct(t *testing.T) {
+	t.Parallel()
+
+	u32 := &btf.Int{Name: "__u32", Size: 4}
+	config := &btf.Struct{
+		Name: "config",
+		Size: 8,
+		Members: []btf.Member{
+			{Name: "min_latency", Type: &btf.Int{Name: "__u64", Size: 8}},
+		},
+	}
+
+	type testDefinition struct {
+		mapSpec     *ebpf.MapSpec
+		expected    *btf.Struct
+		expectedErr string
+	}
+
+	tests := map[string]testDefinition{
+		"no_map": {},
+		"array": {
+			mapSpec: &ebpf.MapSpec{
+				Type: ebpf.Array, KeySize: 4, MaxEntries: 1,
+				Key: u32, Value: &btf.Typedef{Name: "config_t", Type: config},
+			},
+			expected: config,
+		},
+		"wrong_type": {
+			mapSpec: &ebpf.MapSpec{
+				Type: ebpf.PerCPUArray, KeySize: 4, MaxEntries: 1,
+				Key: u32, Value: config,
+			},
+			expectedErr: "has type PerCPUArray",
+		},
+		"too_many_entries": {
+			mapSpec: &ebpf.MapSpec{
+				Type: ebpf.Hash, KeySize: 4, MaxEntries: 2,
+				Key: u32, Value: config,
+			},
+			expectedErr: "has 2 entries",
+		},
+		"wrong_key": {
+			mapSpec: &ebpf.MapSpec{
+				Type: ebpf.Array, KeySize: 8, MaxEntries: 1,
+				Key: u32, Value: config,
+			},
+			expectedErr: "expected a __u32",
+		},
+		"not_a_struct": {
+			mapSpec: &ebpf.MapSpec{
+				Type: ebpf.Array, KeySize: 4, MaxEntries: 1,
+				Key: u32, Value: u32,
+			},
+			expectedErr: "expected a struct",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			spec := &ebpf.CollectionSpec{Maps: map[string]*ebpf.MapSpec{}}
+			if test.mapSpec != nil {
+				spec.Maps[ConfigMapName] = test.mapSpec
+			}
+
+			s, err := GetConfigStruct(spec)
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, s)
+
+			member, ok := GetConfigField(spec, "min_latency")
+			require.Equal(t, test.expected != nil, ok)
+			if ok {
+				require.Equal(t, "min_latency", member.Name)
+			}
+			_, ok = GetConfigField(spec, "foo")
+			require.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"unicode"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/histogram"
+)
+
+// Expression is an arithmetic expression over the fields of an event, like
+// "exit_ts - entry_ts" or "bytes / (interval * 1000)". It supports numbers, the
+// names of fields, parentheses and the +, -, * and / operators. It's used to
+// compute the value of fields that aren't sent by the eBPF program.
+//
+// percentile(hist, p) estimates the p-th percentile of the values counted in
+// hist, a log2 histogram where the slot i counts the values in [2^i, 2^(i+1)),
+// the first one counting 0 and 1 too. The estimate is interpolated linearly
+// within the slot.
+type Expression struct {
+	root       exprNode
+	fields     []string
+	histograms []string
+}
+
+// Getters returning the values of the fields of an event used in expressions
+type (
+	NumberGetters    map[string]func(*Event) float64
+	HistogramGetters map[string]func(*Event) []uint64
+)
+
+type exprNode interface {
+	bind(numbers NumberGetters, histograms HistogramGetters) (func(*Event) float64, error)
+}
+
+type numberNode float64
+
+type fieldNode string
+
+type negNode struct {
+	operand exprNode
+}
+
+type binaryNode struct {
+	op          byte
+	left, right exprNode
+}
+
+type percentileNode struct {
+	histogram  string
+	percentile float64
+}
+
+// ParseExpression parses an arithmetic expression
+func ParseExpression(s string) (*Expression, error) {
+	p := &exprParser{input: s}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	root, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "" {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.token, p.tokenPos)
+	}
+
+	slices.Sort(p.fields)
+	slices.Sort(p.histograms)
+	return &Expression{
+		root:       root,
+		fields:     slices.Compact(p.fields),
+		histograms: slices.Compact(p.histograms),
+	}, nil
+}
+
+// Fields returns the names of the numeric fields used by the expression
+func (e *Expression) Fields() []string {
+	return e.fields
+}
+
+// Histograms returns the names of the histogram fields used by the expression
+func (e *Expression) Histograms() []string {
+	return e.histograms
+}
+
+// Bind returns a function evaluating the expression for an event, the getters
+// return the values of the fields of the event. Divisions by zero evaluate to
+// zero.
+func (e *Expression) Bind(numbers NumberGetters, histograms HistogramGetters) (func(*Event) float64, error) {
+	return e.root.bind(numbers, histograms)
+}
+
+func (n numberNode) bind(NumberGetters, HistogramGetters) (func(*Event) float64, error) {
+	return func(*Event) float64 { return float64(n) }, nil
+}
+
+func (n fieldNode) bind(numbers NumberGetters, _ HistogramGetters) (func(*Event) float64, error) {
+	getter, ok := numbers[string(n)]
+	if !ok {
+		return nil, fmt.Errorf("field %q not found", string(n))
+	}
+	return getter, nil
+}
+
+func (n *negNode) bind(numbers NumberGetters, histograms HistogramGetters) (func(*Event) float64, error) {
+	operand, err := n.operand.bind(numbers, histograms)
+	if err != nil {
+		return nil, err
+	}
+	return func(ev *Event) float64 { return -operand(ev) }, nil
+}
+
+func (n *percentileNode) bind(_ NumberGetters, histograms HistogramGetters) (func(*Event) float64, error) {
+	getter, ok := histograms[n.histogram]
+	if !ok {
+		return nil, fmt.Errorf("histogram %q not found", n.histogram)
+	}
+	return func(ev *Event) float64 { return histogram.PercentileFromExp2Slots(getter(ev), n.percentile) }, nil
+}
+
+func (n *binaryNode) bind(numbers NumberGetters, histograms HistogramGetters) (func(*Event) float64, error) {
+	left, err := n.left.bind(numbers, histograms)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.bind(numbers, histograms)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case '+':
+		return func(ev *Event) float64 { return left(ev) + right(ev) }, nil
+	case '-':
+		return func(ev *Event) float64 { return left(ev) - right(ev) }, nil
+	case '*':
+		return func(ev *Event) float64 { return left(ev) * right(ev) }, nil
+	case '/':
+		return func(ev *Event) float64 {
+			divisor := right(ev)
+			if divisor == 0 {
+				return 0
+			}
+			return left(ev) / divisor
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+type exprParser struct {
+	input      string
+	pos        int
+	token      string
+	tokenPos   int
+	fields     []string
+	histograms []string
+}
+
+// next reads the next token, it's empty at the end of the input
+func (p *exprParser) next() error {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	p.tokenPos = p.pos
+	if p.pos == len(p.input) {
+		p.token = ""
+		return nil
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case isIdentChar(c) || c == '.':
+		for p.pos < len(p.input) && (isIdentChar(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+	case c == '+' || c == '-' || c == '*' || c == '/' || c == '(' || c == ')' || c == ',':
+		p.pos++
+	default:
+		return fmt.Errorf("unexpected character %q at position %d", c, p.pos)
+	}
+	p.token = p.input[p.tokenPos:p.pos]
+	return nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for p.token == "+" || p.token == "-" {
+		op := p.token[0]
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.token == "*" || p.token == "/" {
+		op := p.token[0]
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.token != "-" {
+		return p.parsePrimary()
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &negNode{operand: operand}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	token, pos := p.token, p.tokenPos
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case token == "(":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		node, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, fmt.Errorf("missing ')' at position %d", p.tokenPos)
+		}
+		return node, p.next()
+	case token[0] >= '0' && token[0] <= '9' || token[0] == '.':
+		n, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", token, pos)
+		}
+		return numberNode(n), p.next()
+	case isIdentChar(token[0]):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.token == "(" {
+			return p.parseCall(token, pos)
+		}
+		p.fields = append(p.fields, token)
+		return fieldNode(token), nil
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", token, pos)
+}
+
+// parseCall parses the arguments of a call to function, the current token is
+// the opening parenthesis
+func (p *exprParser) parseCall(function string, pos int) (exprNode, error) {
+	if function != "percentile" {
+		return nil, fmt.Errorf("unknown function %q at position %d", function, pos)
+	}
+
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	histogram := p.token
+	if histogram == "" || !isIdentChar(histogram[0]) || (histogram[0] >= '0' && histogram[0] <= '9') {
+		return nil, fmt.Errorf("percentile expects a histogram field as first argument at position %d", p.tokenPos)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.token != "," {
+		return nil, fmt.Errorf("missing ',' at position %d", p.tokenPos)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	percentile, err := strconv.ParseFloat(p.token, 64)
+	if err != nil || percentile < 0 || percentile > 100 {
+		return nil, fmt.Errorf("percentile expects a number between 0 and 100 as second argument at position %d", p.tokenPos)
+	}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.token != ")" {
+		return nil, fmt.Errorf("missing ')' at position %d", p.tokenPos)
+	}
+
+	p.histograms = append(p.histograms, histogram)
+	return &percentileNode{histogram: histogram, percentile: percentile}, p.next()
+}
@@ -0,0 +1,170 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpression(t *testing.T) {
+	getters := map[string]func(*Event) float64{
+		"entry_ts": func(*Event) float64 { return 1000 },
+		"exit_ts":  func(*Event) float64 { return 3500 },
+		"bytes":    func(*Event) float64 { return 300 },
+		"zero":     func(*Event) float64 { return 0 },
+	}
+	histograms := map[string]func(*Event) []uint64{
+		// 2 values in [0, 2), 4 in [4, 8) and 4 in [8, 16)
+		"lat_slots": func(*Event) []uint64 { return []uint64{2, 0, 4, 4} },
+		"empty":     func(*Event) []uint64 { return nil },
+	}
+
+	type testDefinition struct {
+		expression        string
+		expectedFields    []string
+		expected          float64
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"difference": {
+			expression:     "exit_ts - entry_ts",
+			expectedFields: []string{"entry_ts", "exit_ts"},
+			expected:       2500,
+		},
+		"precedence": {
+			expression:     "bytes + bytes * 2 - 1",
+			expectedFields: []string{"bytes"},
+			expected:       899,
+		},
+		"parentheses": {
+			expression:     "(exit_ts - entry_ts) / 1e3",
+			expectedFields: []string{"entry_ts", "exit_ts"},
+			expected:       2.5,
+		},
+		"unary_minus": {
+			expression:     "-bytes / -3",
+			expectedFields: []string{"bytes"},
+			expected:       100,
+		},
+		"division_by_zero": {
+			expression:     "bytes / zero",
+			expectedFields: []string{"bytes", "zero"},
+			expected:       0,
+		},
+		"constant": {
+			expression:     "0.5 * 4",
+			expectedFields: nil,
+			expected:       2,
+		},
+		"missing_parenthesis": {
+			expression:        "(bytes + 1",
+			expectedErrString: "missing ')' at position 10",
+		},
+		"trailing_token": {
+			expression:        "bytes bytes",
+			expectedErrString: "unexpected \"bytes\" at position 6",
+		},
+		"invalid_character": {
+			expression:        "bytes % 2",
+			expectedErrString: "unexpected character '%' at position 6",
+		},
+		"invalid_number": {
+			expression:        "10ms",
+			expectedErrString: "invalid number \"10ms\"",
+		},
+		"empty": {
+			expression:        "",
+			expectedErrString: "unexpected end of expression",
+		},
+		"percentile": {
+			expression: "percentile(lat_slots, 50)",
+			expected:   7,
+		},
+		"percentile_first_slot": {
+			expression: "percentile(lat_slots, 10)",
+			expected:   1,
+		},
+		"percentile_max": {
+			expression: "percentile(lat_slots, 100)",
+			expected:   16,
+		},
+		"percentile_arithmetic": {
+			expression:     "percentile(lat_slots, 50) * bytes",
+			expectedFields: []string{"bytes"},
+			expected:       2100,
+		},
+		"percentile_empty": {
+			expression: "percentile(empty, 99)",
+			expected:   0,
+		},
+		"percentile_out_of_range": {
+			expression:        "percentile(lat_slots, 101)",
+			expectedErrString: "percentile expects a number between 0 and 100 as second argument at position 22",
+		},
+		"percentile_missing_comma": {
+			expression:        "percentile(lat_slots 50)",
+			expectedErrString: "missing ',' at position 21",
+		},
+		"percentile_not_field": {
+			expression:        "percentile(1, 50)",
+			expectedErrString: "percentile expects a histogram field as first argument at position 11",
+		},
+		"unknown_function": {
+			expression:        "median(lat_slots)",
+			expectedErrString: "unknown function \"median\" at position 0",
+		},
+		"unknown_histogram": {
+			expression:        "percentile(foo, 50)",
+			expectedErrString: "histogram \"foo\" not found",
+		},
+		"unknown_field": {
+			expression:        "foo + 1",
+			expectedFields:    []string{"foo"},
+			expectedErrString: "field \"foo\" not found",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := ParseExpression(test.expression)
+			if err == nil {
+				require.Equal(t, test.expectedFields, expr.Fields())
+				var eval func(*Event) float64
+				eval, err = expr.Bind(getters, histograms)
+				if err == nil && test.expectedErrString == "" {
+					require.Equal(t, test.expected, eval(&Event{}))
+					return
+				}
+			}
+			require.Error(t, err)
+			require.ErrorContains(t, err, test.expectedErrString)
+		})
+	}
+}
+
+func TestExpressionHistograms(t *testing.T) {
+	t.Parallel()
+
+	expr, err := ParseExpression("percentile(wlat_slots, 99) - percentile(rlat_slots, 50) + percentile(rlat_slots, 99) * bytes")
+	require.NoError(t, err)
+	require.Equal(t, []string{"rlat_slots", "wlat_slots"}, expr.Histograms())
+	require.Equal(t, []string{"bytes"}, expr.Fields())
+}
@@ -16,6 +16,7 @@ package types
 
 import (
 	"reflect"
+	"sync"
 	"unsafe"
 
 	"golang.org/x/exp/constraints"
@@ -26,6 +27,9 @@ type EventFactory struct {
 	nextIndex  int
 
 	setters map[string]any
+
+	// pool contains the events given back with Event.Release()
+	pool sync.Pool
 }
 
 func NewEventFactory() *EventFactory {
@@ -35,9 +39,19 @@ func NewEventFactory() *EventFactory {
 	}
 }
 
+// NewEvent returns an event with room for the fields added to the factory. It
+// reuses the events given back with Event.Release() when possible.
 func (f *EventFactory) NewEvent() *Event {
+	if ev, ok := f.pool.Get().(*Event); ok {
+		// Fields could have been added after the event was created
+		if len(ev.Blob) == f.nextIndex && len(ev.Blob[IndexFixed]) == int(f.nextOffset) {
+			return ev
+		}
+	}
+
 	ev := &Event{
 		Blob: make([][]byte, f.nextIndex),
+		pool: &f.pool,
 	}
 
 	ev.Blob[IndexFixed] = make([]byte, f.nextOffset)
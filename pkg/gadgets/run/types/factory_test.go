@@ -0,0 +1,49 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventRelease(t *testing.T) {
+	t.Parallel()
+
+	f := NewEventFactory()
+	FactoryAddField[uint32](f, "pid")
+	FactoryAddString(f, "comm")
+	setPid := GetSetter[uint32](f, "pid")
+	setComm := GetSetter[string](f, "comm")
+
+	ev := f.NewEvent()
+	setPid(ev, 1234)
+	setComm(ev, "cat")
+	ev.MountNsID = 5678
+	ev.Blob[IndexEBPF] = []byte{1, 2, 3}
+	ev.Release()
+
+	// Released events are reset before being reused
+	ev = f.NewEvent()
+	require.Zero(t, ev.MountNsID)
+	require.Len(t, ev.Blob, 3)
+	require.Nil(t, ev.Blob[IndexEBPF])
+	require.Equal(t, []byte{0, 0, 0, 0}, ev.Blob[IndexFixed])
+	require.Nil(t, ev.Blob[IndexFixed+1])
+
+	// Events not created by a factory can't be released
+	(&Event{}).Release()
+}
@@ -15,14 +15,17 @@
 package types
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/btf"
 	"github.com/hashicorp/go-multierror"
 	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
 
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
@@ -36,9 +39,15 @@ const (
 	// Prefix used to mark eBPF params
 	paramPrefix = "gadget_param_"
 
+	// Prefix used to mark maps filled by eBPF params
+	mapParamPrefix = "gadget_map_param_"
+
 	// Prefix used to mark snapshotters structs
 	snapshottersPrefix = "gadget_snapshotter_"
 
+	// Prefix used to mark aggregator maps
+	aggregatorsPrefix = "gadget_aggregator_"
+
 	// Prefix used to mark tracer map created with GADGET_TRACER_MAP() defined in
 	// include/gadget/buffer.h.
 	TracerMapPrefix = "gadget_map_tracer_"
@@ -57,6 +66,24 @@ const (
 
 	// Name of the type to store a timestamp
 	TimestampTypeName = "gadget_timestamp"
+
+	// Name of the type to store a sequence number
+	SeqTypeName = "gadget_seq"
+
+	// Name of the type to store a syscall number, shown as the syscall name
+	SyscallTypeName = "gadget_syscall"
+
+	// Name of the type to store a captured packet
+	PacketTypeName = "gadget_packet_t"
+
+	// Name of the type of the duration params
+	DurationTypeName = "gadget_duration"
+
+	// Name of the type of the size params
+	SizeTypeName = "gadget_size"
+
+	// Name of the type of the CIDR params
+	CIDRTypeName = "gadget_cidr_t"
 )
 
 type EBPFParam struct {
@@ -104,6 +131,29 @@ type FieldAttributes struct {
 	// Template defines the template that will be used.
 	// TODO: add a link to existing templates
 	Template string `yaml:"template,omitempty"`
+	// Unit of the values of this field, like ns or bytes. It's shown next to the name in
+	// the header of the column.
+	Unit string `yaml:"unit,omitempty"`
+}
+
+func (a *FieldAttributes) validate() error {
+	switch a.Alignment {
+	case AlignmenNone, AlignmentLeft, AlignmentRight:
+	default:
+		return fmt.Errorf("invalid alignment %q, expected: %s or %s", a.Alignment, AlignmentLeft, AlignmentRight)
+	}
+
+	switch a.Ellipsis {
+	case EllipsisNone, EllipsisStart, EllipsisMiddle, EllipsisEnd:
+	default:
+		return fmt.Errorf("invalid ellipsis %q, expected: %s, %s or %s", a.Ellipsis, EllipsisStart, EllipsisMiddle, EllipsisEnd)
+	}
+
+	if a.MinWidth != 0 && a.MaxWidth != 0 && a.MinWidth > a.MaxWidth {
+		return fmt.Errorf("minWidth %d is greater than maxWidth %d", a.MinWidth, a.MaxWidth)
+	}
+
+	return nil
 }
 
 type Field struct {
@@ -113,6 +163,12 @@ type Field struct {
 	Description string `yaml:"description,omitempty"`
 	// Attributes defines how the field should be formatted
 	Attributes FieldAttributes `yaml:"attributes"`
+	// Expression computes the value of a field that isn't part of the eBPF struct from
+	// other fields, e.g. "exit_ts - entry_ts". See Expression for the syntax.
+	Expression string `yaml:"expression,omitempty"`
+	// Redact marks the field as sensitive, it's redacted on the node before the events are
+	// sent: "drop", "hash" or "truncate:<length>". See Redaction.
+	Redact string `yaml:"redact,omitempty"`
 	// Annotations represents extra information that is not relevant to Inspektor Gadget, but
 	// for other applications, like color font for instance.
 	Annotations map[string]interface{} `yaml:"annotations,omitempty"`
@@ -137,7 +193,35 @@ type Snapshotter struct {
 	StructName string `yaml:"structName"`
 }
 
+// Aggregator describes the behavior of a gadget that accumulates data in a map
+// on the kernel side and sends the aggregated records to user space
+// periodically instead of sending an event each time
+type Aggregator struct {
+	// Name of the hash map where the gadget aggregates the data
+	MapName string `yaml:"mapName"`
+	// Name of the structure stored as value in the map
+	StructName string `yaml:"structName"`
+}
+
+// Preset is a named set of values of the params of the gadget, e.g. to share a
+// common invocation. It's applied with --preset.
+type Preset struct {
+	Description string `yaml:"description,omitempty"`
+	// Params maps the keys of the params, or the names of the flags, to their
+	// values
+	Params map[string]string `yaml:"params"`
+}
+
+// MetadataAPIVersion is the newest version of the metadata format supported. Metadata
+// without apiVersion uses the first version.
+const MetadataAPIVersion = 1
+
+var ErrUnsupportedMetadataVersion = errors.New("unsupported metadata version")
+
 type GadgetMetadata struct {
+	// APIVersion is the version of the metadata format. It has to be increased when
+	// the format changes in a way older versions can't handle.
+	APIVersion int `yaml:"apiVersion,omitempty"`
 	// Gadget name
 	Name string `yaml:"name"`
 	// Gadget description
@@ -147,10 +231,76 @@ type GadgetMetadata struct {
 	Tracers map[string]Tracer `yaml:"tracers,omitempty"`
 	// Snapshotters implemented by the gadget
 	Snapshotters map[string]Snapshotter `yaml:"snapshotters,omitempty"`
+	// Aggregators implemented by the gadget
+	Aggregators map[string]Aggregator `yaml:"aggregators,omitempty"`
 	// Types generated by the gadget
 	Structs map[string]Struct `yaml:"structs,omitempty"`
 	// Params exposed by the gadget
 	EBPFParams map[string]EBPFParam `yaml:"ebpfParams,omitempty"`
+	// Presets of the params of the gadget
+	Presets map[string]Preset `yaml:"presets,omitempty"`
+	// ClusterScoped marks gadgets whose result doesn't depend on the node they
+	// run on. When leader election is enabled, they only run on the leader.
+	ClusterScoped bool `yaml:"clusterScoped,omitempty"`
+	// Requirements the kernel has to meet to run the gadget
+	Requirements Requirements `yaml:"requirements,omitempty"`
+	// SharedMaps are the maps shared with other gadgets, by name of the map
+	// in the eBPF object
+	SharedMaps map[string]SharedMap `yaml:"sharedMaps,omitempty"`
+	// TailCalls are the program arrays filled with programs of the gadget, by
+	// name of the map in the eBPF object
+	TailCalls map[string]ProgramArray `yaml:"tailCalls,omitempty"`
+}
+
+// CheckMetadataAPIVersion checks the version of the metadata format data uses is
+// supported. It has to be checked before decoding the metadata, as newer versions could
+// fail to be decoded for reasons that don't point to the actual problem.
+func CheckMetadataAPIVersion(data []byte) error {
+	var versioned struct {
+		APIVersion int `yaml:"apiVersion"`
+	}
+	if err := yaml.Unmarshal(data, &versioned); err != nil {
+		return fmt.Errorf("decoding metadata apiVersion: %w", err)
+	}
+	if versioned.APIVersion < 0 {
+		return fmt.Errorf("%w: invalid apiVersion %d", ErrUnsupportedMetadataVersion, versioned.APIVersion)
+	}
+	if versioned.APIVersion > MetadataAPIVersion {
+		return fmt.Errorf("%w: the gadget requires apiVersion %d but this version of Inspektor Gadget supports up to %d, please upgrade it",
+			ErrUnsupportedMetadataVersion, versioned.APIVersion, MetadataAPIVersion)
+	}
+	return nil
+}
+
+// ParseMetadata decodes the metadata of a gadget. Unlike when running a gadget, unknown
+// fields aren't ignored as they're usually typos.
+func ParseMetadata(data []byte) (*GadgetMetadata, error) {
+	if err := CheckMetadataAPIVersion(data); err != nil {
+		return nil, err
+	}
+
+	metadata := &GadgetMetadata{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(metadata); err != nil {
+		return nil, fmt.Errorf("decoding metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// ValidateMetadata checks the metadata of a gadget is valid and matches its eBPF object
+func ValidateMetadata(metadata, ebpfObject []byte) error {
+	m, err := ParseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	spec, err := ebpf.LoadCollectionSpecFromReader(bytes.NewReader(ebpfObject))
+	if err != nil {
+		return fmt.Errorf("loading eBPF object: %w", err)
+	}
+
+	return m.Validate(spec)
 }
 
 func (m *GadgetMetadata) Validate(spec *ebpf.CollectionSpec) error {
@@ -164,10 +314,18 @@ func (m *GadgetMetadata) Validate(spec *ebpf.CollectionSpec) error {
 		result = multierror.Append(result, errors.New("gadget cannot have tracers and snapshotters"))
 	}
 
+	if len(m.Aggregators) > 0 && (len(m.Tracers) > 0 || len(m.Snapshotters) > 0) {
+		result = multierror.Append(result, errors.New("gadget cannot have aggregators and tracers or snapshotters"))
+	}
+
 	if err := m.validateParams(spec); err != nil {
 		result = multierror.Append(result, err)
 	}
 
+	if err := m.Requirements.validate(); err != nil {
+		result = multierror.Append(result, fmt.Errorf("requirements: %w", err))
+	}
+
 	if err := m.validateTracers(spec); err != nil {
 		result = multierror.Append(result, err)
 	}
@@ -176,22 +334,59 @@ func (m *GadgetMetadata) Validate(spec *ebpf.CollectionSpec) error {
 		result = multierror.Append(result, err)
 	}
 
+	if err := m.validateAggregators(spec); err != nil {
+		result = multierror.Append(result, err)
+	}
+
 	if err := m.validateStructs(spec); err != nil {
 		result = multierror.Append(result, err)
 	}
 
+	if err := m.validateSharedMaps(spec); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	if err := m.validateTailCalls(spec); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	for name, preset := range m.Presets {
+		if len(preset.Params) == 0 {
+			result = multierror.Append(result, fmt.Errorf("preset %q doesn't set any param", name))
+		}
+	}
+
 	return result
 }
 
 func (m *GadgetMetadata) validateTracers(spec *ebpf.CollectionSpec) error {
 	var result error
 
-	// Temporary limitation
-	if len(m.Tracers) > 1 {
-		result = multierror.Append(result, errors.New("only one tracer is allowed"))
+	infos, err := getTracersInfo(spec)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
+	infoByMap := make(map[string]*tracerInfo, len(infos))
+	for _, info := range infos {
+		infoByMap[info.mapName] = info
+	}
+
+	// Each tracer is decoded independently, so they can't share a map
+	tracerByMap := make(map[string]string, len(m.Tracers))
+	for _, name := range m.TracerNames() {
+		mapName := m.Tracers[name].MapName
+		if other, ok := tracerByMap[mapName]; ok && mapName != "" {
+			result = multierror.Append(result, fmt.Errorf("tracers %q and %q use the same map %q", other, name, mapName))
+		}
+		tracerByMap[mapName] = name
 	}
 
 	for name, tracer := range m.Tracers {
+		if info, ok := infoByMap[tracer.MapName]; ok && tracer.StructName != info.eventType {
+			result = multierror.Append(result, fmt.Errorf("tracer %q uses struct %q but GADGET_TRACER() sends struct %q through map %q",
+				name, tracer.StructName, info.eventType, info.mapName))
+		}
+
 		if tracer.MapName == "" {
 			result = multierror.Append(result, fmt.Errorf("tracer %q is missing mapName", name))
 		}
@@ -219,6 +414,58 @@ func (m *GadgetMetadata) validateTracers(spec *ebpf.CollectionSpec) error {
 	return result
 }
 
+// TracerNames returns the names of the tracers of the gadget in alphabetical order
+func (m *GadgetMetadata) TracerNames() []string {
+	names := make([]string, 0, len(m.Tracers))
+	for name := range m.Tracers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SelectTracer returns the tracer called name, the first one of TracerNames() if name
+// is empty. Gadgets can have several tracers sending different events, they're the
+// streams of events that can be selected when running the gadget.
+func (m *GadgetMetadata) SelectTracer(name string) (string, *Tracer, error) {
+	names := m.TracerNames()
+	if len(names) == 0 {
+		return "", nil, errors.New("gadget doesn't have tracers")
+	}
+	if name == "" {
+		name = names[0]
+	}
+	tracer, ok := m.Tracers[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown stream %q, available streams are: %s", name, strings.Join(names, ", "))
+	}
+	return name, &tracer, nil
+}
+
+// SelectTracers returns the tracers in the comma separated list of names, like
+// SelectTracer for each of them. Their streams are read at once, so the tracers
+// must send the same struct.
+func (m *GadgetMetadata) SelectTracers(names string) ([]string, []*Tracer, error) {
+	var selected []string
+	var tracers []*Tracer
+	for _, name := range strings.Split(names, ",") {
+		name, tracer, err := m.SelectTracer(strings.TrimSpace(name))
+		if err != nil {
+			return nil, nil, err
+		}
+		if slices.Contains(selected, name) {
+			continue
+		}
+		if len(tracers) > 0 && tracer.StructName != tracers[0].StructName {
+			return nil, nil, fmt.Errorf("streams %q and %q can't be read at once: they send different structs, %q and %q",
+				selected[0], name, tracers[0].StructName, tracer.StructName)
+		}
+		selected = append(selected, name)
+		tracers = append(tracers, tracer)
+	}
+	return selected, tracers, nil
+}
+
 func validateTraceMap(traceMap *ebpf.MapSpec) error {
 	if traceMap.Type != ebpf.RingBuf && traceMap.Type != ebpf.PerfEventArray {
 		return fmt.Errorf("map %q has a wrong type, expected: ringbuf or perf event array, got: %s",
@@ -250,6 +497,50 @@ func (m *GadgetMetadata) validateSnapshotters(spec *ebpf.CollectionSpec) error {
 	return result
 }
 
+func (m *GadgetMetadata) validateAggregators(spec *ebpf.CollectionSpec) error {
+	var result error
+
+	// Temporary limitation
+	if len(m.Aggregators) > 1 {
+		result = multierror.Append(result, errors.New("only one aggregator is allowed"))
+	}
+
+	for name, aggregator := range m.Aggregators {
+		if aggregator.MapName == "" {
+			result = multierror.Append(result, fmt.Errorf("aggregator %q is missing mapName", name))
+		}
+
+		if aggregator.StructName == "" {
+			result = multierror.Append(result, fmt.Errorf("aggregator %q is missing structName", name))
+		}
+
+		if _, ok := m.Structs[aggregator.StructName]; !ok {
+			result = multierror.Append(result, fmt.Errorf("aggregator %q references unknown struct %q", name, aggregator.StructName))
+		}
+
+		ebpfm, ok := spec.Maps[aggregator.MapName]
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("map %q not found in eBPF object", aggregator.MapName))
+			continue
+		}
+
+		if err := validateAggregatorMap(ebpfm); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	return result
+}
+
+func validateAggregatorMap(aggregatorMap *ebpf.MapSpec) error {
+	if aggregatorMap.Type != ebpf.Hash && aggregatorMap.Type != ebpf.LRUHash {
+		return fmt.Errorf("map %q has a wrong type, expected: hash or lru hash, got: %s",
+			aggregatorMap.Name, aggregatorMap.Type.String())
+	}
+
+	return nil
+}
+
 func (m *GadgetMetadata) validateStructs(spec *ebpf.CollectionSpec) error {
 	var result error
 
@@ -270,25 +561,194 @@ func (m *GadgetMetadata) validateStructs(spec *ebpf.CollectionSpec) error {
 			btfStructFields[m.Name] = m
 		}
 
-		for fieldName := range mapStructFields {
-			if _, ok := btfStructFields[fieldName]; !ok {
+		for fieldName, field := range mapStructFields {
+			if field.Expression != "" {
+				if err := validateExpression(field.Expression, mapStructFields, btfStructFields); err != nil {
+					result = multierror.Append(result, fmt.Errorf("expression of field %q of struct %q: %w", fieldName, name, err))
+				}
+				if _, ok := btfStructFields[fieldName]; ok {
+					result = multierror.Append(result, fmt.Errorf("field %q of struct %q has an expression but it's part of the eBPF struct", fieldName, name))
+				}
+			} else if _, ok := btfStructFields[fieldName]; !ok {
 				result = multierror.Append(result, fmt.Errorf("field %q not found in eBPF struct %q", fieldName, name))
 			}
+			if err := field.Attributes.validate(); err != nil {
+				result = multierror.Append(result, fmt.Errorf("field %q of struct %q: %w", fieldName, name, err))
+			}
+			if field.Redact != "" {
+				if err := validateRedaction(field, btfStructFields); err != nil {
+					result = multierror.Append(result, fmt.Errorf("redaction of field %q of struct %q: %w", fieldName, name, err))
+				}
+			}
 		}
 	}
 
 	return result
 }
 
+// validateExpression checks the expression of a computed field only uses numeric fields
+// and histograms (arrays of integers) of the eBPF struct
+func validateExpression(expression string, fields map[string]Field, btfFields map[string]btf.Member) error {
+	expr, err := ParseExpression(expression)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range expr.Fields() {
+		member, ok := btfFields[name]
+		if !ok {
+			if f, ok := fields[name]; ok && f.Expression != "" {
+				return fmt.Errorf("field %q is computed, expressions can only use fields of the eBPF struct", name)
+			}
+			return fmt.Errorf("field %q not found in eBPF struct", name)
+		}
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("field %q is not in the metadata", name)
+		}
+		if !isNumeric(member.Type) {
+			return fmt.Errorf("field %q is not numeric", name)
+		}
+	}
+
+	for _, name := range expr.Histograms() {
+		member, ok := btfFields[name]
+		if !ok {
+			return fmt.Errorf("histogram %q not found in eBPF struct", name)
+		}
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("histogram %q is not in the metadata", name)
+		}
+		if !isHistogram(member.Type) {
+			return fmt.Errorf("field %q is not a histogram, it must be an array of integers", name)
+		}
+	}
+	return nil
+}
+
+// isHistogram returns true if typ is an array of integers, that can be used as a log2
+// histogram in an expression. Arrays of chars are strings.
+func isHistogram(typ btf.Type) bool {
+	if GetCharArray(typ) != nil {
+		return false
+	}
+	if typedef, ok := typ.(*btf.Typedef); ok {
+		typ, _ = getUnderlyingType(typedef)
+	}
+	array, ok := typ.(*btf.Array)
+	if !ok {
+		return false
+	}
+	elem := array.Type
+	if typedef, ok := elem.(*btf.Typedef); ok {
+		elem, _ = getUnderlyingType(typedef)
+	}
+	i, ok := elem.(*btf.Int)
+	return ok && i.Encoding != btf.Bool
+}
+
+// validateRedaction checks the redaction of a field can be applied to its eBPF member
+func validateRedaction(field Field, btfFields map[string]btf.Member) error {
+	redaction, err := ParseRedaction(field.Redact)
+	if err != nil {
+		return err
+	}
+	member, ok := btfFields[field.Name]
+	if !ok {
+		return fmt.Errorf("only fields of the eBPF struct can be redacted")
+	}
+	return CheckRedaction(redaction, member.Type)
+}
+
+// CheckRedaction checks redaction can be applied to a member of type typ, only strings
+// can be truncated
+func CheckRedaction(redaction Redaction, typ btf.Type) error {
+	if redaction.Mode != RedactTruncate {
+		return nil
+	}
+	array := GetCharArray(typ)
+	if array == nil {
+		return fmt.Errorf("only strings can be truncated")
+	}
+	if redaction.Length >= array.Nelems {
+		return fmt.Errorf("length %d must be lower than the size of the string (%d)", redaction.Length, array.Nelems)
+	}
+	return nil
+}
+
+// GetCharArray returns the array of chars typ is, if any
+func GetCharArray(typ btf.Type) *btf.Array {
+	for {
+		switch t := typ.(type) {
+		case *btf.Typedef:
+			typ = t.Type
+		case *btf.Volatile:
+			typ = t.Type
+		case *btf.Const:
+			typ = t.Type
+		case *btf.Array:
+			elem := t.Type
+			if typedef, ok := elem.(*btf.Typedef); ok {
+				var err error
+				elem, err = getUnderlyingType(typedef)
+				if err != nil {
+					return nil
+				}
+			}
+			if i, ok := elem.(*btf.Int); ok && i.Size == 1 {
+				return t
+			}
+			return nil
+		default:
+			return nil
+		}
+	}
+}
+
+// isNumeric returns true if the values of typ can be used in an expression
+func isNumeric(typ btf.Type) bool {
+	if typedef, ok := typ.(*btf.Typedef); ok {
+		typ, _ = getUnderlyingType(typedef)
+	}
+	switch typ := typ.(type) {
+	case *btf.Int:
+		return typ.Encoding != btf.Bool
+	case *btf.Float:
+		return true
+	}
+	return false
+}
+
 func (m *GadgetMetadata) validateParams(spec *ebpf.CollectionSpec) error {
 	var result error
+
+	keys := make(map[string]struct{}, len(m.EBPFParams))
+	for _, p := range m.EBPFParams {
+		keys[p.Key] = struct{}{}
+	}
+
 	for varName := range m.EBPFParams {
-		if err := checkParamVar(spec, varName); err != nil {
+		check := checkParamVar
+		if _, ok := spec.Maps[varName]; ok {
+			check = checkParamMap
+		} else if _, ok := GetConfigField(spec, varName); ok {
+			check = checkConfigField
+		}
+		if err := check(spec, varName); err != nil {
 			result = multierror.Append(result, err)
 		}
 		if len(m.EBPFParams[varName].Key) == 0 {
 			result = multierror.Append(result, fmt.Errorf("param %q has an empty key", varName))
 		}
+		for _, key := range m.EBPFParams[varName].RequiredOneOf {
+			if _, ok := keys[key]; !ok {
+				result = multierror.Append(result, fmt.Errorf("param %q requires unknown param %q", varName, key))
+			}
+		}
+		for _, key := range m.EBPFParams[varName].ConflictsWith {
+			if _, ok := keys[key]; !ok {
+				result = multierror.Append(result, fmt.Errorf("param %q conflicts with unknown param %q", varName, key))
+			}
+		}
 	}
 	return result
 }
@@ -311,6 +771,10 @@ func (m *GadgetMetadata) Populate(spec *ebpf.CollectionSpec) error {
 		return fmt.Errorf("handling snapshotters: %w", err)
 	}
 
+	if err := m.populateAggregators(spec); err != nil {
+		return fmt.Errorf("handling aggregators: %w", err)
+	}
+
 	if err := m.populateParams(spec); err != nil {
 		return fmt.Errorf("handling params: %w", err)
 	}
@@ -368,11 +832,11 @@ func getColumnSize(typ btf.Type) uint {
 }
 
 func (m *GadgetMetadata) populateTracers(spec *ebpf.CollectionSpec) error {
-	tracerInfo, err := getTracerInfo(spec)
+	tracersInfo, err := getTracersInfo(spec)
 	if err != nil {
 		return err
 	}
-	if tracerInfo == nil {
+	if len(tracersInfo) == 0 {
 		log.Debug("No tracer found in eBPF object")
 		return nil
 	}
@@ -381,6 +845,16 @@ func (m *GadgetMetadata) populateTracers(spec *ebpf.CollectionSpec) error {
 		m.Tracers = make(map[string]Tracer)
 	}
 
+	for _, tracerInfo := range tracersInfo {
+		if err := m.populateTracer(spec, tracerInfo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *GadgetMetadata) populateTracer(spec *ebpf.CollectionSpec, tracerInfo *tracerInfo) error {
 	tracerMap := spec.Maps[tracerInfo.mapName]
 	if tracerMap == nil {
 		return fmt.Errorf("map %q not found in eBPF object", tracerInfo.mapName)
@@ -395,7 +869,16 @@ func (m *GadgetMetadata) populateTracers(spec *ebpf.CollectionSpec) error {
 		return fmt.Errorf("finding struct %q in eBPF object: %w", tracerInfo.eventType, err)
 	}
 
-	if _, found := m.Tracers[tracerInfo.name]; !found {
+	// The tracer can be defined with a different name, look it up by its map
+	found := false
+	for _, tracer := range m.Tracers {
+		if tracer.MapName == tracerMap.Name {
+			found = true
+			break
+		}
+	}
+
+	if !found {
 		log.Debugf("Adding tracer %q", tracerMap.Name)
 		m.Tracers[tracerMap.Name] = Tracer{
 			MapName:    tracerMap.Name,
@@ -457,31 +940,27 @@ type tracerInfo struct {
 	eventType string
 }
 
-// getTracerInfo returns the tracer info generated with GADGET_TRACER().
-// If there are multiple annotations only the first one is returned.
-func getTracerInfo(spec *ebpf.CollectionSpec) (*tracerInfo, error) {
-	tracersInfo, err := GetGadgetIdentByPrefix(spec, tracerInfoPrefix)
+// getTracersInfo returns the tracers info generated with GADGET_TRACER()
+func getTracersInfo(spec *ebpf.CollectionSpec) ([]*tracerInfo, error) {
+	idents, err := GetGadgetIdentByPrefix(spec, tracerInfoPrefix)
 	if err != nil {
 		return nil, err
 	}
-	if len(tracersInfo) == 0 {
-		return nil, nil
-	}
 
-	if len(tracersInfo) > 1 {
-		log.Warnf("multiple tracers found, using %q", tracersInfo[0])
-	}
-
-	parts := strings.Split(tracersInfo[0], "___")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("invalid tracer info: %q", tracersInfo[0])
+	tracersInfo := make([]*tracerInfo, 0, len(idents))
+	for _, ident := range idents {
+		parts := strings.Split(ident, "___")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid tracer info: %q", ident)
+		}
+		tracersInfo = append(tracersInfo, &tracerInfo{
+			name:      parts[0],
+			mapName:   parts[1],
+			eventType: parts[2],
+		})
 	}
 
-	return &tracerInfo{
-		name:      parts[0],
-		mapName:   parts[1],
-		eventType: parts[2],
-	}, nil
+	return tracersInfo, nil
 }
 
 func (m *GadgetMetadata) populateStruct(btfStruct *btf.Struct) error {
@@ -503,22 +982,134 @@ func (m *GadgetMetadata) populateStruct(btfStruct *btf.Struct) error {
 		}
 
 		log.Debugf("Adding field %q", member.Name)
-		field := Field{
+		gadgetStruct.Fields = append(gadgetStruct.Fields, fieldFromMember(member))
+	}
+
+	m.Structs[btfStruct.Name] = gadgetStruct
+
+	return nil
+}
+
+// wellKnownField describes a field commonly used by gadgets, it's detected by its name
+type wellKnownField struct {
+	description string
+	template    string
+	// isString is true if the field is a string, otherwise it's an integer
+	isString bool
+}
+
+var wellKnownFields = map[string]wellKnownField{
+	"pid":     {description: "Process ID", template: "pid"},
+	"tid":     {description: "Thread ID", template: "pid"},
+	"ppid":    {description: "Parent process ID", template: "pid"},
+	"uid":     {description: "User ID", template: "uid"},
+	"gid":     {description: "Group ID", template: "gid"},
+	"comm":    {description: "Command name", template: "comm", isString: true},
+	"pcomm":   {description: "Parent command name", template: "comm", isString: true},
+	"task":    {description: "Command name", template: "comm", isString: true},
+	"syscall": {description: "System call", template: "syscall", isString: true},
+}
+
+// fieldFromMember returns the metadata of a field of a struct. The description and the
+// attributes are guessed from the type and the name of the member, and they use a
+// placeholder if it's not possible.
+func fieldFromMember(member btf.Member) Field {
+	field := Field{
+		Name:        member.Name,
+		Description: "TODO: Fill field description",
+		Attributes: FieldAttributes{
+			Width:     getColumnSize(member.Type),
+			Alignment: AlignmentLeft,
+			Ellipsis:  EllipsisEnd,
+		},
+	}
+
+	templateField := func(description, template string) Field {
+		return Field{
 			Name:        member.Name,
-			Description: "TODO: Fill field description",
+			Description: description,
 			Attributes: FieldAttributes{
-				Width:     getColumnSize(member.Type),
-				Alignment: AlignmentLeft,
-				Ellipsis:  EllipsisEnd,
+				Template: template,
 			},
 		}
+	}
 
-		gadgetStruct.Fields = append(gadgetStruct.Fields, field)
+	// Types provided by include/gadget/types.h
+	switch typ := member.Type.(type) {
+	case *btf.Typedef:
+		switch typ.Name {
+		case MntNsIdTypeName:
+			return templateField("Mount namespace inode id", "ns")
+		case TimestampTypeName:
+			return templateField("Timestamp of the event", "timestamp")
+		case SeqTypeName:
+			field.Description = "Sequence number used to detect lost events"
+			field.Attributes.Hidden = true
+			return field
+		case SyscallTypeName:
+			return templateField("System call", "syscall")
+		}
+	case *btf.Struct:
+		switch typ.Name {
+		case L3EndpointTypeName:
+			return templateField("IP address", "ipaddr")
+		case L4EndpointTypeName:
+			return templateField("IP address and port", "ipaddrport")
+		case PacketTypeName:
+			field.Description = "Length of the packet, its bytes are written by the pcapng output mode"
+			field.Attributes.Width = 8
+			field.Attributes.Alignment = AlignmentRight
+			return field
+		}
 	}
 
-	m.Structs[btfStruct.Name] = gadgetStruct
+	typ := member.Type
+	if typedef, ok := typ.(*btf.Typedef); ok {
+		typ, _ = getUnderlyingType(typedef)
+	}
 
-	return nil
+	switch typ := typ.(type) {
+	case *btf.Enum:
+		// Enums are shown with the names of their values
+		names := make([]string, 0, len(typ.Values))
+		width := uint(len(member.Name))
+		for _, v := range typ.Values {
+			names = append(names, v.Name)
+			width = max(width, uint(len(v.Name)))
+		}
+		field.Attributes.Width = width
+		if isFlagsEnum(typ) {
+			field.Description = "Combination of the flags: " + strings.Join(names, ", ")
+		} else {
+			field.Description = "One of: " + strings.Join(names, ", ")
+		}
+		return field
+	case *btf.Int, *btf.Array:
+		known, ok := wellKnownFields[member.Name]
+		if !ok {
+			break
+		}
+		_, isArray := typ.(*btf.Array)
+		if known.isString != isArray {
+			break
+		}
+		return templateField(known.description, known.template)
+	}
+
+	return field
+}
+
+// isFlagsEnum returns true if the values of enum are bits that can be combined
+func isFlagsEnum(enum *btf.Enum) bool {
+	if len(enum.Values) < 2 {
+		return false
+	}
+	for _, v := range enum.Values {
+		if v.Value == 0 || v.Value&(v.Value-1) != 0 {
+			return false
+		}
+	}
+	return true
 }
 
 func (m *GadgetMetadata) populateParams(spec *ebpf.CollectionSpec) error {
@@ -543,27 +1134,82 @@ func (m *GadgetMetadata) populateParams(spec *ebpf.CollectionSpec) error {
 			continue
 		}
 
-		if m.EBPFParams == nil {
-			m.EBPFParams = make(map[string]EBPFParam)
-		}
+		m.addParam(name)
+	}
+
+	mapNames, err := GetGadgetIdentByPrefix(spec, mapParamPrefix)
+	if err != nil {
+		result = multierror.Append(result, err)
+	}
 
-		if _, found := m.EBPFParams[name]; found {
-			log.Debugf("Param %q already defined, skipping", name)
+	for _, name := range mapNames {
+		if err := checkParamMap(spec, name); err != nil {
+			result = multierror.Append(result, err)
 			continue
 		}
 
-		log.Debugf("Adding param %q", name)
-		m.EBPFParams[name] = EBPFParam{
-			ParamDesc: params.ParamDesc{
-				Key:         name,
-				Description: "TODO: Fill parameter description",
-			},
+		m.addParam(name)
+	}
+
+	configStruct, err := GetConfigStruct(spec)
+	if err != nil {
+		result = multierror.Append(result, err)
+	} else if configStruct != nil {
+		for _, member := range configStruct.Members {
+			m.addParam(member.Name)
 		}
 	}
 
 	return result
 }
 
+func (m *GadgetMetadata) addParam(name string) {
+	if m.EBPFParams == nil {
+		m.EBPFParams = make(map[string]EBPFParam)
+	}
+
+	if _, found := m.EBPFParams[name]; found {
+		log.Debugf("Param %q already defined, skipping", name)
+		return
+	}
+
+	log.Debugf("Adding param %q", name)
+	m.EBPFParams[name] = EBPFParam{
+		ParamDesc: params.ParamDesc{
+			Key:         name,
+			Description: "TODO: Fill parameter description",
+		},
+	}
+}
+
+// checkParamMap checks name is a hash map whose keys are set by a param
+func checkParamMap(spec *ebpf.CollectionSpec, name string) error {
+	m, ok := spec.Maps[name]
+	if !ok {
+		return fmt.Errorf("map %q not found in eBPF object", name)
+	}
+	if m.Type != ebpf.Hash {
+		return fmt.Errorf("map %q of param has type %s, expected %s", name, m.Type, ebpf.Hash)
+	}
+	if m.Key == nil {
+		return fmt.Errorf("map %q of param doesn't have BTF information about its key", name)
+	}
+	return nil
+}
+
+// checkConfigField checks name is a field of the gadget_config map that can be
+// set by a param
+func checkConfigField(spec *ebpf.CollectionSpec, name string) error {
+	if _, err := GetConfigStruct(spec); err != nil {
+		return err
+	}
+	member, _ := GetConfigField(spec, name)
+	if member.BitfieldSize != 0 {
+		return fmt.Errorf("field %q of map %q is a bitfield", name, ConfigMapName)
+	}
+	return nil
+}
+
 func checkParamVar(spec *ebpf.CollectionSpec, name string) error {
 	var result error
 
@@ -637,3 +1283,60 @@ func (m *GadgetMetadata) populateSnapshotters(spec *ebpf.CollectionSpec) error {
 
 	return nil
 }
+
+func (m *GadgetMetadata) populateAggregators(spec *ebpf.CollectionSpec) error {
+	aggregatorsInfo, err := GetGadgetIdentByPrefix(spec, aggregatorsPrefix)
+	if err != nil {
+		return err
+	}
+	if len(aggregatorsInfo) == 0 {
+		log.Debug("No aggregators found")
+		return nil
+	}
+
+	if len(aggregatorsInfo) > 1 {
+		log.Warnf("Multiple aggregators found, using %q", aggregatorsInfo[0])
+	}
+
+	parts := strings.Split(aggregatorsInfo[0], "___")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid aggregator annotation: %q", aggregatorsInfo[0])
+	}
+	aname := parts[0]
+	mapName := parts[1]
+	atype := parts[2]
+
+	aggregatorMap := spec.Maps[mapName]
+	if aggregatorMap == nil {
+		return fmt.Errorf("map %q not found in eBPF object", mapName)
+	}
+
+	if err := validateAggregatorMap(aggregatorMap); err != nil {
+		return fmt.Errorf("aggregator map is invalid: %w", err)
+	}
+
+	var btfStruct *btf.Struct
+	if err := spec.Types.TypeByName(atype, &btfStruct); err != nil {
+		return fmt.Errorf("finding struct %q in eBPF object: %w", atype, err)
+	}
+
+	if m.Aggregators == nil {
+		m.Aggregators = make(map[string]Aggregator)
+	}
+
+	if _, ok := m.Aggregators[aname]; !ok {
+		log.Debugf("Adding aggregator %q", aname)
+		m.Aggregators[aname] = Aggregator{
+			MapName:    mapName,
+			StructName: btfStruct.Name,
+		}
+	} else {
+		log.Debugf("Aggregator %q already defined, skipping", aname)
+	}
+
+	if err := m.populateStruct(btfStruct); err != nil {
+		return fmt.Errorf("populating struct: %w", err)
+	}
+
+	return nil
+}
@@ -15,9 +15,12 @@
 package types
 
 import (
+	"os"
 	"testing"
 
 	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/stretchr/testify/require"
 )
@@ -47,15 +50,100 @@ func TestValidate(t *testing.T) {
 			},
 			expectedErrString: "gadget cannot have tracers and snapshotters",
 		},
-		"tracers_more_than_one": {
+		"aggregators_and_tracers": {
 			metadata: &GadgetMetadata{
 				Name: "foo",
 				Tracers: map[string]Tracer{
 					"foo": {},
+				},
+				Aggregators: map[string]Aggregator{
 					"bar": {},
 				},
 			},
-			expectedErrString: "only one tracer is allowed",
+			expectedErrString: "gadget cannot have aggregators and tracers or snapshotters",
+		},
+		"shared_map_not_found": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				SharedMaps: map[string]SharedMap{
+					"nonexistent": {Name: "cache"},
+				},
+			},
+			expectedErrString: "shared map \"nonexistent\" not found in eBPF object",
+		},
+		"shared_map_invalid_name": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				SharedMaps: map[string]SharedMap{
+					"myhashmap": {Name: "../cache"},
+				},
+			},
+			expectedErrString: "shared map \"myhashmap\" has an invalid name \"../cache\"",
+		},
+		"shared_map_same_name": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				SharedMaps: map[string]SharedMap{
+					"myhashmap":               {Name: "cache"},
+					"gadget_mntns_filter_map": {Name: "cache"},
+				},
+			},
+			expectedErrString: "maps \"gadget_mntns_filter_map\" and \"myhashmap\" are shared with the same name \"cache\"",
+		},
+		"shared_map_wrong_type": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				SharedMaps: map[string]SharedMap{
+					"events": {Name: "events"},
+				},
+			},
+			expectedErrString: "map \"events\" of type PerfEventArray can't be shared",
+		},
+		"preset_without_params": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Presets: map[string]Preset{
+					"noisy": {Description: "foo"},
+				},
+			},
+			expectedErrString: "preset \"noisy\" doesn't set any param",
+		},
+		"tracers_more_than_one": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Tracers: map[string]Tracer{
+					"foo": {
+						MapName:    "events",
+						StructName: "event",
+					},
+					"bar": {
+						MapName:    "map_without_btf",
+						StructName: "event",
+					},
+				},
+				Structs: map[string]Struct{
+					"event": {},
+				},
+			},
+		},
+		"tracers_same_map": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Tracers: map[string]Tracer{
+					"foo": {
+						MapName:    "events",
+						StructName: "event",
+					},
+					"bar": {
+						MapName:    "events",
+						StructName: "event",
+					},
+				},
+				Structs: map[string]Struct{
+					"event": {},
+				},
+			},
+			expectedErrString: "tracers \"bar\" and \"foo\" use the same map \"events\"",
 		},
 		"tracers_missing_map_name": {
 			metadata: &GadgetMetadata{
@@ -149,6 +237,21 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		"tracers_wrong_struct": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Tracers: map[string]Tracer{
+					"foo": {
+						MapName:    "events",
+						StructName: "other",
+					},
+				},
+				Structs: map[string]Struct{
+					"other": {},
+				},
+			},
+			expectedErrString: "tracer \"foo\" uses struct \"other\" but GADGET_TRACER() sends struct \"event\" through map \"events\"",
+		},
 		"structs_nonexistent": {
 			metadata: &GadgetMetadata{
 				Name: "foo",
@@ -173,6 +276,178 @@ func TestValidate(t *testing.T) {
 			},
 			expectedErrString: "field \"nonexistent\" not found in eBPF struct",
 		},
+		"structs_field_bad_alignment": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{
+								Name: "pid",
+								Attributes: FieldAttributes{
+									Alignment: "center",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErrString: "field \"pid\" of struct \"event\": invalid alignment \"center\"",
+		},
+		"structs_computed_field": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "pid"},
+							{Name: "mntns_id"},
+							{Name: "sum", Expression: "(pid + mntns_id) / 2"},
+						},
+					},
+				},
+			},
+		},
+		"structs_computed_field_not_numeric": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "comm"},
+							{Name: "foo", Expression: "comm * 2"},
+						},
+					},
+				},
+			},
+			expectedErrString: "expression of field \"foo\" of struct \"event\": field \"comm\" is not numeric",
+		},
+		"structs_computed_field_unknown": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "foo", Expression: "bar + 1"},
+						},
+					},
+				},
+			},
+			expectedErrString: "field \"bar\" not found in eBPF struct",
+		},
+		"structs_computed_field_percentile_not_histogram": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "comm"},
+							{Name: "p99", Expression: "percentile(comm, 99)"},
+						},
+					},
+				},
+			},
+			expectedErrString: "expression of field \"p99\" of struct \"event\": field \"comm\" is not a histogram, it must be an array of integers",
+		},
+		"structs_computed_field_percentile_unknown": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "p99", Expression: "percentile(lat_slots, 99)"},
+						},
+					},
+				},
+			},
+			expectedErrString: "histogram \"lat_slots\" not found in eBPF struct",
+		},
+		"structs_computed_field_in_ebpf": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "pid", Expression: "1"},
+						},
+					},
+				},
+			},
+			expectedErrString: "field \"pid\" of struct \"event\" has an expression but it's part of the eBPF struct",
+		},
+		"structs_redacted_fields": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "pid", Redact: "hash"},
+							{Name: "comm", Redact: "truncate:4"},
+							{Name: "filename", Redact: "drop"},
+						},
+					},
+				},
+			},
+		},
+		"structs_redact_truncate_not_string": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "pid", Redact: "truncate:4"},
+						},
+					},
+				},
+			},
+			expectedErrString: "redaction of field \"pid\" of struct \"event\": only strings can be truncated",
+		},
+		"structs_redact_truncate_too_long": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "comm", Redact: "truncate:16"},
+						},
+					},
+				},
+			},
+			expectedErrString: "length 16 must be lower than the size of the string (16)",
+		},
+		"structs_redact_computed_field": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Structs: map[string]Struct{
+					"event": {
+						Fields: []Field{
+							{Name: "pid"},
+							{Name: "double", Expression: "pid * 2", Redact: "drop"},
+						},
+					},
+				},
+			},
+			expectedErrString: "only fields of the eBPF struct can be redacted",
+		},
+		"requirements_good": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Requirements: Requirements{
+					KernelVersion: "5.8",
+					Features:      []string{FeatureRingbuf, FeatureBTF},
+				},
+			},
+		},
+		"requirements_bad": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				Requirements: Requirements{
+					KernelVersion: "five",
+					Features:      []string{"bpf_loop"},
+				},
+			},
+			expectedErrString: "unknown kernel feature \"bpf_loop\"",
+		},
 		"structs_good": {
 			metadata: &GadgetMetadata{
 				Name: "foo",
@@ -217,6 +492,45 @@ func TestValidate(t *testing.T) {
 				},
 			},
 		},
+		"param_unknown_constraint": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				EBPFParams: map[string]EBPFParam{
+					"param": {
+						ParamDesc: params.ParamDesc{
+							Key:           "param",
+							ConflictsWith: []string{"foo"},
+						},
+					},
+				},
+			},
+			expectedErrString: "param \"param\" conflicts with unknown param \"foo\"",
+		},
+		"param_map_good": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				EBPFParams: map[string]EBPFParam{
+					"myhashmap": {
+						ParamDesc: params.ParamDesc{
+							Key: "ids",
+						},
+					},
+				},
+			},
+		},
+		"param_map_not_hash": {
+			metadata: &GadgetMetadata{
+				Name: "foo",
+				EBPFParams: map[string]EBPFParam{
+					"events": {
+						ParamDesc: params.ParamDesc{
+							Key: "events",
+						},
+					},
+				},
+			},
+			expectedErrString: "map \"events\" of param has type PerfEventArray, expected Hash",
+		},
 		"param2_not_volatile": {
 			metadata: &GadgetMetadata{
 				Name: "foo",
@@ -288,6 +602,90 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateAggregators(t *testing.T) {
+	type testCase struct {
+		metadata          *GadgetMetadata
+		expectedErrString string
+	}
+
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			"counters": {Name: "counters", Type: ebpf.Hash},
+			"events":   {Name: "events", Type: ebpf.RingBuf},
+		},
+	}
+
+	tests := map[string]testCase{
+		"good": {
+			metadata: &GadgetMetadata{
+				Aggregators: map[string]Aggregator{
+					"foo": {MapName: "counters", StructName: "counter"},
+				},
+				Structs: map[string]Struct{"counter": {}},
+			},
+		},
+		"more_than_one": {
+			metadata: &GadgetMetadata{
+				Aggregators: map[string]Aggregator{
+					"foo": {MapName: "counters", StructName: "counter"},
+					"bar": {MapName: "counters", StructName: "counter"},
+				},
+				Structs: map[string]Struct{"counter": {}},
+			},
+			expectedErrString: "only one aggregator is allowed",
+		},
+		"missing_map_name": {
+			metadata: &GadgetMetadata{
+				Aggregators: map[string]Aggregator{
+					"foo": {StructName: "counter"},
+				},
+				Structs: map[string]Struct{"counter": {}},
+			},
+			expectedErrString: "aggregator \"foo\" is missing mapName",
+		},
+		"unknown_struct": {
+			metadata: &GadgetMetadata{
+				Aggregators: map[string]Aggregator{
+					"foo": {MapName: "counters", StructName: "nonexistent"},
+				},
+			},
+			expectedErrString: "aggregator \"foo\" references unknown struct \"nonexistent\"",
+		},
+		"map_not_found": {
+			metadata: &GadgetMetadata{
+				Aggregators: map[string]Aggregator{
+					"foo": {MapName: "nonexistent", StructName: "counter"},
+				},
+				Structs: map[string]Struct{"counter": {}},
+			},
+			expectedErrString: "map \"nonexistent\" not found in eBPF object",
+		},
+		"wrong_map_type": {
+			metadata: &GadgetMetadata{
+				Aggregators: map[string]Aggregator{
+					"foo": {MapName: "events", StructName: "counter"},
+				},
+				Structs: map[string]Struct{"counter": {}},
+			},
+			expectedErrString: "map \"events\" has a wrong type, expected: hash or lru hash, got: RingBuf",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.metadata.validateAggregators(spec)
+			if test.expectedErrString == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, test.expectedErrString)
+			}
+		})
+	}
+}
+
 func TestPopulate(t *testing.T) {
 	type testCase struct {
 		initialMetadata   *GadgetMetadata
@@ -313,20 +711,16 @@ func TestPopulate(t *testing.T) {
 						Fields: []Field{
 							{
 								Name:        "pid",
-								Description: "TODO: Fill field description",
+								Description: "Process ID",
 								Attributes: FieldAttributes{
-									Width:     10,
-									Alignment: AlignmentLeft,
-									Ellipsis:  EllipsisEnd,
+									Template: "pid",
 								},
 							},
 							{
 								Name:        "comm",
-								Description: "TODO: Fill field description",
+								Description: "Command name",
 								Attributes: FieldAttributes{
-									Width:     16,
-									Alignment: AlignmentLeft,
-									Ellipsis:  EllipsisEnd,
+									Template: "comm",
 								},
 							},
 							{
@@ -456,20 +850,16 @@ func TestPopulate(t *testing.T) {
 						Fields: []Field{
 							{
 								Name:        "pid",
-								Description: "TODO: Fill field description",
+								Description: "Process ID",
 								Attributes: FieldAttributes{
-									Width:     10,
-									Alignment: AlignmentLeft,
-									Ellipsis:  EllipsisEnd,
+									Template: "pid",
 								},
 							},
 							{
 								Name:        "comm",
-								Description: "TODO: Fill field description",
+								Description: "Command name",
 								Attributes: FieldAttributes{
-									Width:     16,
-									Alignment: AlignmentLeft,
-									Ellipsis:  EllipsisEnd,
+									Template: "comm",
 								},
 							},
 							{
@@ -551,20 +941,16 @@ func TestPopulate(t *testing.T) {
 						Fields: []Field{
 							{
 								Name:        "pid",
-								Description: "TODO: Fill field description",
+								Description: "Process ID",
 								Attributes: FieldAttributes{
-									Width:     10,
-									Alignment: AlignmentLeft,
-									Ellipsis:  EllipsisEnd,
+									Template: "pid",
 								},
 							},
 							{
 								Name:        "comm",
-								Description: "TODO: Fill field description",
+								Description: "Command name",
 								Attributes: FieldAttributes{
-									Width:     16,
-									Alignment: AlignmentLeft,
-									Ellipsis:  EllipsisEnd,
+									Template: "comm",
 								},
 							},
 							{
@@ -615,3 +1001,304 @@ func TestPopulate(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMetadata(t *testing.T) {
+	object, err := os.ReadFile("../../../../testdata/validate_metadata1.o")
+	require.NoError(t, err)
+
+	type testDefinition struct {
+		metadata          string
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"good": {
+			metadata: `
+name: foo
+tracers:
+  foo:
+    mapName: events
+    structName: event
+structs:
+  event:
+    fields:
+    - name: pid
+`,
+		},
+		"unknown_field": {
+			metadata: `
+name: foo
+tracer:
+  foo:
+    mapName: events
+`,
+			expectedErrString: "field tracer not found in type types.GadgetMetadata",
+		},
+		"unknown_param_field": {
+			metadata: `
+name: foo
+ebpfParams:
+  param:
+    key: param
+    defaultvalue: 1
+`,
+			expectedErrString: "field defaultvalue not found",
+		},
+		"wrong_btf": {
+			metadata: `
+name: foo
+ebpfParams:
+  bar:
+    key: bar
+`,
+			expectedErrString: "variable \"bar\" not found in eBPF object",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateMetadata([]byte(test.metadata), object)
+			if test.expectedErrString == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, test.expectedErrString)
+			}
+		})
+	}
+}
+
+func TestCheckMetadataAPIVersion(t *testing.T) {
+	type testDefinition struct {
+		metadata          string
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"no_version": {
+			metadata: "name: foo\n",
+		},
+		"current_version": {
+			metadata: "apiVersion: 1\nname: foo\n",
+		},
+		"newer_version": {
+			// Fields of newer versions could have a different type
+			metadata:          "apiVersion: 2\nname:\n  short: foo\n",
+			expectedErrString: "the gadget requires apiVersion 2 but this version of Inspektor Gadget supports up to 1",
+		},
+		"negative_version": {
+			metadata:          "apiVersion: -1\n",
+			expectedErrString: "invalid apiVersion -1",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := CheckMetadataAPIVersion([]byte(test.metadata))
+			if test.expectedErrString == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrUnsupportedMetadataVersion)
+				require.ErrorContains(t, err, test.expectedErrString)
+			}
+
+			// ParseMetadata reports the version instead of the decoding error
+			_, err = ParseMetadata([]byte(test.metadata))
+			if test.expectedErrString != "" {
+				require.ErrorContains(t, err, test.expectedErrString)
+			}
+		})
+	}
+}
+
+func TestFieldFromMember(t *testing.T) {
+	u32 := &btf.Int{Name: "unsigned int", Size: 4}
+	u64 := &btf.Typedef{Name: "__u64", Type: &btf.Int{Name: "unsigned long long", Size: 8}}
+
+	type testDefinition struct {
+		member   btf.Member
+		expected Field
+	}
+
+	tests := map[string]testDefinition{
+		"unknown": {
+			member: btf.Member{Name: "foo", Type: u32},
+			expected: Field{
+				Name:        "foo",
+				Description: "TODO: Fill field description",
+				Attributes: FieldAttributes{
+					Width:     columns.MaxCharsUint32,
+					Alignment: AlignmentLeft,
+					Ellipsis:  EllipsisEnd,
+				},
+			},
+		},
+		"well_known_name": {
+			member: btf.Member{Name: "ppid", Type: u32},
+			expected: Field{
+				Name:        "ppid",
+				Description: "Parent process ID",
+				Attributes:  FieldAttributes{Template: "pid"},
+			},
+		},
+		"well_known_name_wrong_type": {
+			member: btf.Member{Name: "comm", Type: u32},
+			expected: Field{
+				Name:        "comm",
+				Description: "TODO: Fill field description",
+				Attributes: FieldAttributes{
+					Width:     columns.MaxCharsUint32,
+					Alignment: AlignmentLeft,
+					Ellipsis:  EllipsisEnd,
+				},
+			},
+		},
+		"mntns_id": {
+			member: btf.Member{Name: "mntns", Type: &btf.Typedef{Name: MntNsIdTypeName, Type: u64}},
+			expected: Field{
+				Name:        "mntns",
+				Description: "Mount namespace inode id",
+				Attributes:  FieldAttributes{Template: "ns"},
+			},
+		},
+		"timestamp": {
+			member: btf.Member{Name: "ts", Type: &btf.Typedef{Name: TimestampTypeName, Type: u64}},
+			expected: Field{
+				Name:        "ts",
+				Description: "Timestamp of the event",
+				Attributes:  FieldAttributes{Template: "timestamp"},
+			},
+		},
+		"syscall": {
+			member: btf.Member{Name: "nr", Type: &btf.Typedef{Name: SyscallTypeName, Type: u64}},
+			expected: Field{
+				Name:        "nr",
+				Description: "System call",
+				Attributes:  FieldAttributes{Template: "syscall"},
+			},
+		},
+		"endpoint": {
+			member: btf.Member{Name: "dst", Type: &btf.Struct{Name: L4EndpointTypeName}},
+			expected: Field{
+				Name:        "dst",
+				Description: "IP address and port",
+				Attributes:  FieldAttributes{Template: "ipaddrport"},
+			},
+		},
+		"packet": {
+			member: btf.Member{Name: "packet", Type: &btf.Struct{Name: PacketTypeName, Size: 1522}},
+			expected: Field{
+				Name:        "packet",
+				Description: "Length of the packet, its bytes are written by the pcapng output mode",
+				Attributes: FieldAttributes{
+					Width:     8,
+					Alignment: AlignmentRight,
+					Ellipsis:  EllipsisEnd,
+				},
+			},
+		},
+		"enum": {
+			member: btf.Member{Name: "op", Type: &btf.Enum{Name: "op", Size: 4, Values: []btf.EnumValue{
+				{Name: "OP_READ", Value: 0},
+				{Name: "OP_WRITE", Value: 1},
+			}}},
+			expected: Field{
+				Name:        "op",
+				Description: "One of: OP_READ, OP_WRITE",
+				Attributes: FieldAttributes{
+					Width:     8,
+					Alignment: AlignmentLeft,
+					Ellipsis:  EllipsisEnd,
+				},
+			},
+		},
+		"flags": {
+			member: btf.Member{Name: "mode", Type: &btf.Typedef{Name: "mode_t", Type: &btf.Enum{Name: "mode", Size: 4, Values: []btf.EnumValue{
+				{Name: "R", Value: 1},
+				{Name: "W", Value: 2},
+				{Name: "X", Value: 4},
+			}}}},
+			expected: Field{
+				Name:        "mode",
+				Description: "Combination of the flags: R, W, X",
+				Attributes: FieldAttributes{
+					Width:     4,
+					Alignment: AlignmentLeft,
+					Ellipsis:  EllipsisEnd,
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.expected, fieldFromMember(test.member))
+		})
+	}
+}
+
+func TestSelectTracer(t *testing.T) {
+	t.Parallel()
+
+	metadata := &GadgetMetadata{
+		Tracers: map[string]Tracer{
+			"dns":     {MapName: "dns_events", StructName: "dns_event"},
+			"connect": {MapName: "connect_events", StructName: "connect_event"},
+		},
+	}
+
+	require.Equal(t, []string{"connect", "dns"}, metadata.TracerNames())
+
+	name, tracer, err := metadata.SelectTracer("")
+	require.NoError(t, err)
+	require.Equal(t, "connect", name)
+	require.Equal(t, "connect_event", tracer.StructName)
+
+	name, tracer, err = metadata.SelectTracer("dns")
+	require.NoError(t, err)
+	require.Equal(t, "dns", name)
+	require.Equal(t, "dns_events", tracer.MapName)
+
+	_, _, err = metadata.SelectTracer("http")
+	require.EqualError(t, err, "unknown stream \"http\", available streams are: connect, dns")
+
+	_, _, err = (&GadgetMetadata{}).SelectTracer("")
+	require.Error(t, err)
+}
+
+func TestSelectTracers(t *testing.T) {
+	t.Parallel()
+
+	metadata := &GadgetMetadata{
+		Tracers: map[string]Tracer{
+			"dns":     {MapName: "dns_events", StructName: "dns_event"},
+			"mdns":    {MapName: "mdns_events", StructName: "dns_event"},
+			"connect": {MapName: "connect_events", StructName: "connect_event"},
+		},
+	}
+
+	names, tracers, err := metadata.SelectTracers("")
+	require.NoError(t, err)
+	require.Equal(t, []string{"connect"}, names)
+	require.Len(t, tracers, 1)
+
+	names, tracers, err = metadata.SelectTracers("dns, mdns,dns")
+	require.NoError(t, err)
+	require.Equal(t, []string{"dns", "mdns"}, names)
+	require.Equal(t, "dns_events", tracers[0].MapName)
+	require.Equal(t, "mdns_events", tracers[1].MapName)
+
+	_, _, err = metadata.SelectTracers("dns,connect")
+	require.ErrorContains(t, err, "they send different structs")
+
+	_, _, err = metadata.SelectTracers("dns,http")
+	require.ErrorContains(t, err, "unknown stream \"http\"")
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type RedactMode string
+
+const (
+	// RedactDrop zeroes the field
+	RedactDrop RedactMode = "drop"
+	// RedactHash replaces the field with a keyed hash of its value, the same
+	// values get the same hash while the gadget runs
+	RedactHash RedactMode = "hash"
+	// RedactTruncate keeps the first characters of a string
+	RedactTruncate RedactMode = "truncate"
+)
+
+// Redaction describes how a sensitive field is redacted before the events
+// leave the node
+type Redaction struct {
+	Mode RedactMode
+	// Length is the number of characters kept by RedactTruncate
+	Length uint32
+}
+
+// ParseRedaction parses a redaction like "drop", "hash" or "truncate:16"
+func ParseRedaction(s string) (Redaction, error) {
+	mode, length, hasLength := strings.Cut(s, ":")
+	switch RedactMode(mode) {
+	case RedactDrop, RedactHash:
+		if hasLength {
+			return Redaction{}, fmt.Errorf("redaction %q doesn't take a length", mode)
+		}
+		return Redaction{Mode: RedactMode(mode)}, nil
+	case RedactTruncate:
+		if !hasLength {
+			return Redaction{}, fmt.Errorf("redaction %q requires a length, e.g. %s:16", mode, mode)
+		}
+		n, err := strconv.ParseUint(length, 10, 32)
+		if err != nil {
+			return Redaction{}, fmt.Errorf("invalid length %q of redaction %q", length, mode)
+		}
+		return Redaction{Mode: RedactTruncate, Length: uint32(n)}, nil
+	}
+	return Redaction{}, fmt.Errorf("unknown redaction %q, expected %s, %s or %s:<length>",
+		s, RedactDrop, RedactHash, RedactTruncate)
+}
+
+func (r Redaction) String() string {
+	if r.Mode == RedactTruncate {
+		return fmt.Sprintf("%s:%d", r.Mode, r.Length)
+	}
+	return string(r.Mode)
+}
+
+// ParseRedactions parses a comma separated list of field=redaction, like
+// "comm=hash,filename=truncate:8"
+func ParseRedactions(s string) (map[string]Redaction, error) {
+	redactions := make(map[string]Redaction)
+	if s == "" {
+		return redactions, nil
+	}
+	for _, entry := range strings.Split(s, ",") {
+		field, redaction, ok := strings.Cut(entry, "=")
+		if !ok || field == "" {
+			return nil, fmt.Errorf("invalid redaction %q, expected <field>=<redaction>", entry)
+		}
+		r, err := ParseRedaction(redaction)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field, err)
+		}
+		redactions[field] = r
+	}
+	return redactions, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedactions(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		value             string
+		expected          map[string]Redaction
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"empty": {
+			value:    "",
+			expected: map[string]Redaction{},
+		},
+		"all_modes": {
+			value: "pid=drop,comm=hash,filename=truncate:8",
+			expected: map[string]Redaction{
+				"pid":      {Mode: RedactDrop},
+				"comm":     {Mode: RedactHash},
+				"filename": {Mode: RedactTruncate, Length: 8},
+			},
+		},
+		"missing_redaction": {
+			value:             "pid",
+			expectedErrString: "invalid redaction \"pid\", expected <field>=<redaction>",
+		},
+		"unknown_mode": {
+			value:             "pid=mask",
+			expectedErrString: "field \"pid\": unknown redaction \"mask\"",
+		},
+		"truncate_without_length": {
+			value:             "comm=truncate",
+			expectedErrString: "redaction \"truncate\" requires a length",
+		},
+		"truncate_bad_length": {
+			value:             "comm=truncate:-1",
+			expectedErrString: "invalid length \"-1\" of redaction \"truncate\"",
+		},
+		"drop_with_length": {
+			value:             "comm=drop:3",
+			expectedErrString: "redaction \"drop\" doesn't take a length",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			redactions, err := ParseRedactions(test.value)
+			if test.expectedErrString != "" {
+				require.ErrorContains(t, err, test.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, redactions)
+		})
+	}
+}
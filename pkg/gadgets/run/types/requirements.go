@@ -0,0 +1,88 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Kernel features a gadget can require
+const (
+	FeatureRingbuf     = "ringbuf"
+	FeatureBTF         = "btf"
+	FeatureFentry      = "fentry"
+	FeatureKprobeMulti = "kprobe.multi"
+	FeatureCgroupV2    = "cgroup-v2"
+	FeatureBPFTimer    = "bpf_timer"
+)
+
+// KernelFeatures are the kernel features a gadget can require
+var KernelFeatures = []string{FeatureRingbuf, FeatureBTF, FeatureFentry, FeatureKprobeMulti, FeatureCgroupV2, FeatureBPFTimer}
+
+// Requirements describes what the kernel needs to run a gadget. They're checked before
+// loading it, so a single error tells what's missing instead of a verifier failure.
+type Requirements struct {
+	// KernelVersion is the minimum version of the kernel, like "5.8"
+	KernelVersion string `yaml:"kernelVersion,omitempty"`
+	// Features the kernel has to support, see KernelFeatures
+	Features []string `yaml:"features,omitempty"`
+}
+
+// ParseKernelVersion parses a kernel version like "5.8" or "5.10.2" into the format of
+// the KERNEL_VERSION macro of linux/version.h
+func ParseKernelVersion(s string) (uint32, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid kernel version %q, expected <major>.<minor>[.<patch>]", s)
+	}
+	var version [3]uint64
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid kernel version %q, expected <major>.<minor>[.<patch>]", s)
+		}
+		version[i] = n
+	}
+	return uint32(version[0]<<16 | version[1]<<8 | version[2]), nil
+}
+
+// KernelVersionString returns the version in the format of KERNEL_VERSION as a string
+func KernelVersionString(version uint32) string {
+	return fmt.Sprintf("%d.%d.%d", version>>16, (version>>8)&0xff, version&0xff)
+}
+
+func (r *Requirements) validate() error {
+	var result error
+
+	if r.KernelVersion != "" {
+		if _, err := ParseKernelVersion(r.KernelVersion); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	for _, feature := range r.Features {
+		if !slices.Contains(KernelFeatures, feature) {
+			result = multierror.Append(result, fmt.Errorf("unknown kernel feature %q, expected one of: %s",
+				feature, strings.Join(KernelFeatures, ", ")))
+		}
+	}
+
+	return result
+}
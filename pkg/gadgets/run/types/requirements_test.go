@@ -0,0 +1,71 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKernelVersion(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		version           string
+		expected          uint32
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"major_minor": {
+			version:  "5.8",
+			expected: 5<<16 | 8<<8,
+		},
+		"with_patch": {
+			version:  "5.10.2",
+			expected: 5<<16 | 10<<8 | 2,
+		},
+		"major_only": {
+			version:           "5",
+			expectedErrString: "invalid kernel version \"5\"",
+		},
+		"not_a_number": {
+			version:           "5.x",
+			expectedErrString: "invalid kernel version \"5.x\"",
+		},
+		"too_many_parts": {
+			version:           "5.10.2.1",
+			expectedErrString: "invalid kernel version \"5.10.2.1\"",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			version, err := ParseKernelVersion(test.version)
+			if test.expectedErrString != "" {
+				require.ErrorContains(t, err, test.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, version)
+		})
+	}
+
+	require.Equal(t, "5.10.2", KernelVersionString(5<<16|10<<8|2))
+}
@@ -0,0 +1,86 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/cilium/ebpf"
+	"github.com/hashicorp/go-multierror"
+)
+
+// SharedMap describes a map of the gadget shared with the other gadget
+// instances requesting the same name, e.g. a cache filled by a gadget and read
+// by another one. It's pinned in the bpffs, so it outlives the instances using
+// it.
+type SharedMap struct {
+	// Name the map is shared with, it's pinned with this name
+	Name string `yaml:"name"`
+	// Description of the contents of the map, for the other gadgets
+	Description string `yaml:"description,omitempty"`
+}
+
+func (m *GadgetMetadata) isAggregatorMap(mapName string) bool {
+	for _, aggregator := range m.Aggregators {
+		if aggregator.MapName == mapName {
+			return true
+		}
+	}
+	return false
+}
+
+var sharedMapNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+func (m *GadgetMetadata) validateSharedMaps(spec *ebpf.CollectionSpec) error {
+	var result error
+
+	mapNames := make(map[string]string, len(m.SharedMaps))
+	for mapName, shared := range m.SharedMaps {
+		if !sharedMapNameRegexp.MatchString(shared.Name) || shared.Name == "." || shared.Name == ".." {
+			result = multierror.Append(result, fmt.Errorf("shared map %q has an invalid name %q: it can only contain letters, digits, '_', '.' and '-'",
+				mapName, shared.Name))
+			continue
+		}
+		if other, ok := mapNames[shared.Name]; ok {
+			result = multierror.Append(result, fmt.Errorf("maps %q and %q are shared with the same name %q",
+				min(mapName, other), max(mapName, other), shared.Name))
+		}
+		mapNames[shared.Name] = mapName
+
+		mapSpec, ok := spec.Maps[mapName]
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("shared map %q not found in eBPF object", mapName))
+			continue
+		}
+		switch mapSpec.Type {
+		case ebpf.RingBuf, ebpf.PerfEventArray:
+			result = multierror.Append(result, fmt.Errorf("map %q of type %s can't be shared: its events would be read by a single gadget",
+				mapName, mapSpec.Type))
+		}
+		if MapHasTimer(mapSpec) {
+			result = multierror.Append(result, fmt.Errorf("map %q can't be shared: its timers would keep running when no gadget uses it",
+				mapName))
+		}
+		// The params of an instance, the records it flushes and the programs
+		// it calls aren't the ones of other instances
+		_, isProgramArray := m.TailCalls[mapName]
+		if mapName == ConfigMapName || m.isAggregatorMap(mapName) || isProgramArray {
+			result = multierror.Append(result, fmt.Errorf("map %q can't be shared", mapName))
+		}
+	}
+
+	return result
+}
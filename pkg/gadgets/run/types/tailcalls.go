@@ -0,0 +1,97 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cilium/ebpf"
+	"github.com/hashicorp/go-multierror"
+)
+
+// ProgramArray describes a program array of the gadget filled with its own
+// programs, which call each other with bpf_tail_call(), e.g. the stages of a
+// protocol dissector
+type ProgramArray struct {
+	// Programs are the names of the programs to put at each index of the map
+	Programs map[uint32]string `yaml:"programs"`
+}
+
+// TailCallTargets returns the names of the programs put in the program
+// arrays. They're only run through tail calls, so they aren't attached.
+func (m *GadgetMetadata) TailCallTargets() map[string]struct{} {
+	targets := make(map[string]struct{})
+	for _, programArray := range m.TailCalls {
+		for _, progName := range programArray.Programs {
+			targets[progName] = struct{}{}
+		}
+	}
+	return targets
+}
+
+func (m *GadgetMetadata) validateTailCalls(spec *ebpf.CollectionSpec) error {
+	var result error
+
+	for mapName, programArray := range m.TailCalls {
+		mapSpec, ok := spec.Maps[mapName]
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("program array %q not found in eBPF object", mapName))
+			continue
+		}
+		if mapSpec.Type != ebpf.ProgramArray {
+			result = multierror.Append(result, fmt.Errorf("map %q has a wrong type, expected: %s, got: %s",
+				mapName, ebpf.ProgramArray, mapSpec.Type))
+			continue
+		}
+		if len(programArray.Programs) == 0 {
+			result = multierror.Append(result, fmt.Errorf("program array %q doesn't have any program", mapName))
+			continue
+		}
+
+		indexes := make([]uint32, 0, len(programArray.Programs))
+		for index := range programArray.Programs {
+			indexes = append(indexes, index)
+		}
+		sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+		// The kernel only calls programs of the same type as the first one
+		// put in the map
+		var first *ebpf.ProgramSpec
+		for _, index := range indexes {
+			progName := programArray.Programs[index]
+			if index >= mapSpec.MaxEntries {
+				result = multierror.Append(result, fmt.Errorf("index %d of program array %q is out of range, the map has %d entries",
+					index, mapName, mapSpec.MaxEntries))
+			}
+			p, ok := spec.Programs[progName]
+			if !ok {
+				result = multierror.Append(result, fmt.Errorf("program %q of program array %q not found in eBPF object",
+					progName, mapName))
+				continue
+			}
+			if first == nil {
+				first = p
+				continue
+			}
+			if p.Type != first.Type {
+				result = multierror.Append(result, fmt.Errorf("program %q of program array %q has type %s, expected %s like %q",
+					progName, mapName, p.Type, first.Type, first.Name))
+			}
+		}
+	}
+
+	return result
+}
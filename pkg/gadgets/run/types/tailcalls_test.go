@@ -0,0 +1,125 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTailCalls(t *testing.T) {
+	type testCase struct {
+		metadata          *GadgetMetadata
+		expectedErrString string
+	}
+
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			"dissectors": {Name: "dissectors", Type: ebpf.ProgramArray, MaxEntries: 4},
+			"counters":   {Name: "counters", Type: ebpf.Hash},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{
+			"parse_http": {Name: "parse_http", Type: ebpf.SocketFilter},
+			"parse_dns":  {Name: "parse_dns", Type: ebpf.SocketFilter},
+			"ig_execve":  {Name: "ig_execve", Type: ebpf.TracePoint},
+		},
+	}
+
+	tests := map[string]testCase{
+		"good": {
+			metadata: &GadgetMetadata{
+				TailCalls: map[string]ProgramArray{
+					"dissectors": {Programs: map[uint32]string{0: "parse_http", 1: "parse_dns"}},
+				},
+			},
+		},
+		"map_not_found": {
+			metadata: &GadgetMetadata{
+				TailCalls: map[string]ProgramArray{
+					"nonexistent": {Programs: map[uint32]string{0: "parse_http"}},
+				},
+			},
+			expectedErrString: "program array \"nonexistent\" not found in eBPF object",
+		},
+		"wrong_map_type": {
+			metadata: &GadgetMetadata{
+				TailCalls: map[string]ProgramArray{
+					"counters": {Programs: map[uint32]string{0: "parse_http"}},
+				},
+			},
+			expectedErrString: "map \"counters\" has a wrong type, expected: ProgramArray, got: Hash",
+		},
+		"no_programs": {
+			metadata: &GadgetMetadata{
+				TailCalls: map[string]ProgramArray{
+					"dissectors": {},
+				},
+			},
+			expectedErrString: "program array \"dissectors\" doesn't have any program",
+		},
+		"index_out_of_range": {
+			metadata: &GadgetMetadata{
+				TailCalls: map[string]ProgramArray{
+					"dissectors": {Programs: map[uint32]string{4: "parse_http"}},
+				},
+			},
+			expectedErrString: "index 4 of program array \"dissectors\" is out of range, the map has 4 entries",
+		},
+		"program_not_found": {
+			metadata: &GadgetMetadata{
+				TailCalls: map[string]ProgramArray{
+					"dissectors": {Programs: map[uint32]string{0: "parse_tls"}},
+				},
+			},
+			expectedErrString: "program \"parse_tls\" of program array \"dissectors\" not found in eBPF object",
+		},
+		"different_types": {
+			metadata: &GadgetMetadata{
+				TailCalls: map[string]ProgramArray{
+					"dissectors": {Programs: map[uint32]string{0: "parse_http", 1: "ig_execve"}},
+				},
+			},
+			expectedErrString: "program \"ig_execve\" of program array \"dissectors\" has type TracePoint, expected SocketFilter like \"parse_http\"",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := test.metadata.validateTailCalls(spec)
+			if test.expectedErrString == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, test.expectedErrString)
+			}
+		})
+	}
+}
+
+func TestTailCallTargets(t *testing.T) {
+	t.Parallel()
+
+	metadata := &GadgetMetadata{
+		TailCalls: map[string]ProgramArray{
+			"dissectors": {Programs: map[uint32]string{0: "parse_http", 1: "parse_dns"}},
+			"stages":     {Programs: map[uint32]string{0: "parse_http"}},
+		},
+	}
+	require.Equal(t, map[string]struct{}{"parse_http": {}, "parse_dns": {}}, metadata.TailCallTargets())
+}
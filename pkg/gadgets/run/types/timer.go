@@ -0,0 +1,60 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+)
+
+// timerTypeName is the name of the struct the kernel uses for timers in the
+// values of maps
+const timerTypeName = "bpf_timer"
+
+// MapHasTimer returns whether the values of the map hold a bpf_timer. Its
+// timers run until the last reference to the map is dropped, even once the
+// gadget is done.
+func MapHasTimer(m *ebpf.MapSpec) bool {
+	return m.Value != nil && hasTimer(m.Value, make(map[btf.Type]struct{}))
+}
+
+func hasTimer(typ btf.Type, seen map[btf.Type]struct{}) bool {
+	typ = btf.UnderlyingType(typ)
+	if _, ok := seen[typ]; ok {
+		return false
+	}
+	seen[typ] = struct{}{}
+
+	switch typ := typ.(type) {
+	case *btf.Struct:
+		if typ.Name == timerTypeName {
+			return true
+		}
+		for _, member := range typ.Members {
+			if hasTimer(member.Type, seen) {
+				return true
+			}
+		}
+	case *btf.Union:
+		for _, member := range typ.Members {
+			if hasTimer(member.Type, seen) {
+				return true
+			}
+		}
+	case *btf.Array:
+		return hasTimer(typ.Type, seen)
+	}
+	return false
+}
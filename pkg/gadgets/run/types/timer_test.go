@@ -0,0 +1,70 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapHasTimer(t *testing.T) {
+	t.Parallel()
+
+	u64 := &btf.Int{Name: "__u64", Size: 8}
+	timer := &btf.Struct{Name: "bpf_timer", Size: 16, Members: []btf.Member{
+		{Name: "opaque", Type: &btf.Array{Type: u64, Nelems: 2}},
+	}}
+
+	type testDefinition struct {
+		value    btf.Type
+		expected bool
+	}
+
+	tests := map[string]testDefinition{
+		"no_btf": {},
+		"integer": {
+			value: u64,
+		},
+		"struct_without_timer": {
+			value: &btf.Struct{Name: "stats", Members: []btf.Member{{Name: "bytes", Type: u64}}},
+		},
+		"struct_with_timer": {
+			value: &btf.Struct{Name: "conn", Members: []btf.Member{
+				{Name: "bytes", Type: u64},
+				{Name: "timer", Type: timer, Offset: 64},
+			}},
+			expected: true,
+		},
+		"typedef_of_nested_timer": {
+			value: &btf.Typedef{Name: "conn_t", Type: &btf.Struct{Name: "conn", Members: []btf.Member{
+				{Name: "timers", Type: &btf.Array{Type: &btf.Volatile{Type: timer}, Nelems: 2}},
+			}}},
+			expected: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			m := &ebpf.MapSpec{Type: ebpf.Hash, Value: test.value}
+			require.Equal(t, test.expected, MapHasTimer(m))
+		})
+	}
+}
@@ -15,6 +15,8 @@
 package types
 
 import (
+	"sync"
+
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
@@ -39,6 +41,15 @@ type L4Endpoint struct {
 	Name string
 }
 
+// Packet is a packet captured by the gadget, see struct gadget_packet_t in
+// include/gadget/types.h
+type Packet struct {
+	Name string `json:"name"`
+	// Len is the original length of the packet, Data can be shorter
+	Len  uint32 `json:"len"`
+	Data []byte `json:"data"`
+}
+
 type Event struct {
 	// Do not use eventtypes.Event because we don't want to have the timestamp column.
 	eventtypes.CommonData
@@ -46,12 +57,20 @@ type Event struct {
 	// Type indicates the kind of this event
 	Type eventtypes.EventType `json:"type"`
 
-	// Message when Type is ERR, WARN, DEBUG or INFO
+	// Message when Type is ERR, WARN, DEBUG, INFO or EVENTS_DROPPED
 	Message string `json:"message,omitempty"`
 
+	// Dropped is the number of events lost when Type is EVENTS_DROPPED
+	Dropped uint64 `json:"dropped,omitempty"`
+
+	// Stream is the name of the tracer that sent the event, only set when
+	// the events of several tracers are read at once
+	Stream string `json:"stream,omitempty"`
+
 	L3Endpoints []L3Endpoint      `json:"l3endpoints,omitempty"`
 	L4Endpoints []L4Endpoint      `json:"l4endpoints,omitempty"`
 	Timestamps  []eventtypes.Time `json:"timestamps,omitempty"`
+	Packets     []Packet          `json:"packets,omitempty"`
 
 	MountNsID uint64 `json:"-"`
 	NetNsID   uint64 `json:"-"`
@@ -61,6 +80,40 @@ type Event struct {
 	// [1] is used for fixed-size members
 	// [1+] is used for variable size members
 	Blob [][]byte `json:"blob,omitempty"`
+
+	// pool is the pool of the factory that created the event, if any
+	pool *sync.Pool
+}
+
+// Release gives the event back to the factory that created it, so it can be
+// reused by EventFactory.NewEvent(). The event must not be used afterwards.
+// It's a no-op for events that weren't created by a factory, e.g. the ones
+// unmarshalled from JSON.
+func (ev *Event) Release() {
+	pool := ev.pool
+	if pool == nil {
+		return
+	}
+
+	blob := ev.Blob
+	fixed := blob[IndexFixed]
+	clear(fixed)
+	clear(blob)
+	blob[IndexFixed] = fixed
+
+	*ev = Event{
+		Blob: blob,
+		pool: pool,
+	}
+	pool.Put(ev)
+}
+
+func (ev *Event) GetType() eventtypes.EventType {
+	return ev.Type
+}
+
+func (ev *Event) GetMessage() string {
+	return ev.Message
 }
 
 func (ev *Event) GetMountNSID() uint64 {
@@ -117,6 +170,8 @@ const (
 	KindL3Endpoint
 	KindL4Endpoint
 	KindTimestamp
+	// KindComputed is used by fields computed from others with an expression
+	KindComputed
 )
 
 type Type struct {
@@ -140,8 +195,15 @@ type GadgetInfo struct {
 	GadgetMetadata *GadgetMetadata
 	Columns        []ColumnDesc
 	ProgContent    []byte
-	GadgetType     gadgets.GadgetType
-	EventFactory   *EventFactory
+	// BTFGen isn't sent to the client as it's only needed to load the eBPF program
+	BTFGen       []byte `json:"-"`
+	GadgetType   gadgets.GadgetType
+	EventFactory *EventFactory
+	// ImageDigest is the digest of the image the gadget was loaded from
+	ImageDigest string
+	// EventStruct is the name of the struct of the events sent by the gadget, gadgets
+	// with several tracers send the one of the selected stream
+	EventStruct string
 }
 
 // RunGadgetDesc represents the different methods implemented by the run gadget descriptor.
@@ -0,0 +1,115 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	processcollectortypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/snapshot/process/types"
+)
+
+// userHZ is the frequency of the clock ticks used in /proc/<pid>/stat, it's
+// fixed to 100 by the kernel ABI
+const userHZ = 100
+
+type processDetails struct {
+	threads int
+	fds     int
+	// rss is in bytes
+	rss uint64
+	// startTime is the time since boot in nanoseconds
+	startTime uint64
+}
+
+// readProcessDetails reads the details of a process from procFs. See proc(5)
+// for the format of /proc/<pid>/stat.
+func readProcessDetails(procFs string, pid int) (*processDetails, error) {
+	pidPath := filepath.Join(procFs, fmt.Sprint(pid))
+
+	stat, err := os.ReadFile(filepath.Join(pidPath, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// The command can contain spaces and parentheses, the fields start
+	// after the last parenthesis
+	idx := strings.LastIndexByte(string(stat), ')')
+	if idx == -1 {
+		return nil, fmt.Errorf("invalid stat file of pid %d", pid)
+	}
+	// fields[0] is the third field, the state
+	fields := strings.Fields(string(stat[idx+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("invalid stat file of pid %d: %d fields", pid, len(fields))
+	}
+
+	details := &processDetails{}
+	details.threads, err = strconv.Atoi(fields[17])
+	if err != nil {
+		return nil, fmt.Errorf("parsing number of threads: %w", err)
+	}
+	startTicks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	details.startTime = startTicks * uint64(time.Second/userHZ)
+	rssPages, err := strconv.ParseUint(fields[21], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing rss: %w", err)
+	}
+	details.rss = rssPages * uint64(os.Getpagesize())
+
+	fds, err := os.ReadDir(filepath.Join(pidPath, "fd"))
+	if err != nil {
+		return nil, fmt.Errorf("reading file descriptors: %w", err)
+	}
+	details.fds = len(fds)
+
+	return details, nil
+}
+
+// addProcessDetails adds the details of the processes to the events. Threads
+// of the same process get the details of the process.
+func addProcessDetails(procFs string, events []*processcollectortypes.Event) {
+	cache := make(map[int]*processDetails)
+	for _, event := range events {
+		details, ok := cache[event.Pid]
+		if !ok {
+			var err error
+			details, err = readProcessDetails(procFs, event.Pid)
+			if err != nil {
+				// The process could have exited in the meantime
+				details = nil
+			}
+			cache[event.Pid] = details
+		}
+		if details == nil {
+			continue
+		}
+
+		event.Threads = details.threads
+		event.Fds = details.fds
+		event.RSS = details.rss
+		// WallTimeFromBootTime() returns the current time for 0
+		if details.startTime != 0 {
+			event.StartTime = gadgets.WallTimeFromBootTime(details.startTime)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	processcollectortypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/snapshot/process/types"
+)
+
+func TestAddProcessDetails(t *testing.T) {
+	t.Parallel()
+
+	procFs := t.TempDir()
+	pidPath := filepath.Join(procFs, "42")
+	require.NoError(t, os.MkdirAll(filepath.Join(pidPath, "fd"), 0o755))
+	for _, fd := range []string{"0", "1", "2"} {
+		require.NoError(t, os.Symlink("/dev/null", filepath.Join(pidPath, "fd", fd)))
+	}
+	// The command contains spaces and a parenthesis
+	stat := "42 (my (app) x) S 1 42 42 0 -1 4194560 1000 0 0 0 10 5 0 0 20 0 4 0 250 123456 300 18446744073709551615\n"
+	require.NoError(t, os.WriteFile(filepath.Join(pidPath, "stat"), []byte(stat), 0o644))
+
+	events := []*processcollectortypes.Event{
+		{Pid: 42, Tid: 42},
+		{Pid: 42, Tid: 43},
+		// Exited process
+		{Pid: 44, Tid: 44},
+	}
+	addProcessDetails(procFs, events)
+
+	for _, event := range events[:2] {
+		require.Equal(t, 4, event.Threads)
+		require.Equal(t, 3, event.Fds)
+		require.Equal(t, uint64(300*os.Getpagesize()), event.RSS)
+		// 250 ticks are 2.5 seconds. The conversion to wall time can be
+		// recalibrated between both calls.
+		require.InDelta(t, int64(gadgets.WallTimeFromBootTime(2500000000)), int64(event.StartTime), float64(time.Millisecond))
+	}
+	require.Equal(t, processcollectortypes.Event{Pid: 44, Tid: 44}, *events[2])
+}
+
+func TestReadProcessDetailsInvalid(t *testing.T) {
+	t.Parallel()
+
+	procFs := t.TempDir()
+	pidPath := filepath.Join(procFs, "42")
+	require.NoError(t, os.MkdirAll(pidPath, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(pidPath, "stat"), []byte("42 (app) S 1 42\n"), 0o644))
+
+	_, err := readProcessDetails(procFs, 42)
+	require.EqualError(t, err, "invalid stat file of pid 42: 3 fields")
+}
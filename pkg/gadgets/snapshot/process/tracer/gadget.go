@@ -27,6 +27,7 @@ import (
 
 const (
 	ParamThreads = "threads"
+	ParamDetails = "details"
 )
 
 type GadgetDesc struct{}
@@ -57,6 +58,13 @@ func (g *GadgetDesc) ParamDescs() params.ParamDescs {
 			DefaultValue: "false",
 			TypeHint:     params.TypeBool,
 		},
+		{
+			Key:          ParamDetails,
+			Title:        "Details",
+			Description:  "Show the number of threads, open file descriptors, resident set size and start time of the processes",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
 	}
 }
 
@@ -44,9 +44,25 @@ import (
 type Config struct {
 	MountnsMap  *ebpf.Map
 	ShowThreads bool
+	ShowDetails bool
 }
 
 func RunCollector(config *Config, enricher gadgets.DataEnricherByMntNs) ([]*processcollectortypes.Event, error) {
+	events, err := runCollector(config, enricher)
+	if err != nil {
+		return nil, err
+	}
+
+	// Neither the eBPF iterator nor the procfs collector get the details,
+	// they are read from procfs for both of them
+	if config.ShowDetails {
+		addProcessDetails(host.HostProcFs, events)
+	}
+
+	return events, nil
+}
+
+func runCollector(config *Config, enricher gadgets.DataEnricherByMntNs) ([]*processcollectortypes.Event, error) {
 	events, err := runeBPFCollector(config, enricher)
 	if err == nil {
 		return events, nil
@@ -276,6 +292,7 @@ func (t *Tracer) SetMountNsMap(mntnsMap *ebpf.Map) {
 
 func (t *Tracer) Run(gadgetCtx gadgets.GadgetContext) error {
 	t.config.ShowThreads = gadgetCtx.GadgetParams().Get(ParamThreads).AsBool()
+	t.config.ShowDetails = gadgetCtx.GadgetParams().Get(ParamDetails).AsBool()
 
 	processes, err := RunCollector(t.config, nil)
 	if err != nil {
@@ -20,12 +20,15 @@ import (
 	"os"
 	"strings"
 
+	"github.com/docker/go-units"
+
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
 const (
 	ShowThreadsParam = "show-threads"
+	ShowDetailsParam = "show-details"
 )
 
 type Event struct {
@@ -38,10 +41,22 @@ type Event struct {
 	Uid       uint32 `json:"uid" column:"uid,template:uid"`
 	Gid       uint32 `json:"gid" column:"gid,template:gid"`
 	ParentPid int    `json:"ppid" column:"ppid,template:pid,hide"`
+
+	// Details of the process, only set if requested
+	Threads   int             `json:"threads,omitempty" column:"threads,width:7,align:right" columnTags:"param:details"`
+	Fds       int             `json:"fds,omitempty" column:"fds,width:6,align:right" columnTags:"param:details"`
+	RSS       uint64          `json:"rss,omitempty" column:"rss,width:10,align:right" columnTags:"param:details"`
+	StartTime eventtypes.Time `json:"startTime,omitempty" column:"starttime,width:35,maxWidth:35,stringer" columnTags:"param:details"`
 }
 
 func GetColumns() *columns.Columns[Event] {
-	return columns.MustCreateColumns[Event]()
+	cols := columns.MustCreateColumns[Event]()
+
+	cols.MustSetExtractor("rss", func(event *Event) any {
+		return units.BytesSize(float64(event.RSS))
+	})
+
+	return cols
 }
 
 type processTree struct {
@@ -0,0 +1,44 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InstanceStatePinPath returns the directory where the gadget instance with
+// the given ID pins its state maps, to find them again when it restarts
+func InstanceStatePinPath(id string) (string, error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsRune(id, filepath.Separator) {
+		return "", fmt.Errorf("invalid instance ID %q", id)
+	}
+	return filepath.Join(InstancesPinPath, id), nil
+}
+
+// RemoveInstanceState removes the state maps pinned by the gadget instance
+// with the given ID. It has to be called once the instance won't restart.
+func RemoveInstanceState(id string) error {
+	dir, err := InstanceStatePinPath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing state of instance %q: %w", id, err)
+	}
+	return nil
+}
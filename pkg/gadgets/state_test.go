@@ -0,0 +1,34 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceStatePinPath(t *testing.T) {
+	t.Parallel()
+
+	dir, err := InstanceStatePinPath("0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0")
+	require.NoError(t, err)
+	require.Equal(t, "/sys/fs/bpf/gadget/instances/0b1c2d3e-4f50-6172-8394-a5b6c7d8e9f0", dir)
+
+	for _, id := range []string{"", ".", "..", "../shared", "a/b"} {
+		_, err := InstanceStatePinPath(id)
+		require.Error(t, err, "id %q", id)
+	}
+}
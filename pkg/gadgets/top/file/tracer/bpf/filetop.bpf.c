@@ -6,6 +6,7 @@
 #include <bpf/bpf_tracing.h>
 #include "filetop.h"
 #include "stat.h"
+#include <gadget/bits.bpf.h>
 #include <gadget/mntns_filter.h>
 
 #define MAX_ENTRIES 10240
@@ -21,6 +22,20 @@ struct {
 	__type(value, struct file_stat);
 } entries SEC(".maps");
 
+struct start_t {
+	__u64 ts;
+	struct file_id key;
+	enum op op;
+};
+
+/* Reads and writes in progress, by thread */
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, MAX_ENTRIES);
+	__type(key, __u32);
+	__type(value, struct start_t);
+} starts SEC(".maps");
+
 static void get_file_path(struct file *file, __u8 *buf, size_t size)
 {
 	struct qstr dname;
@@ -37,6 +52,7 @@ static int probe_entry(struct pt_regs *ctx, struct file *file, size_t count,
 	__u32 tid = (__u32)pid_tgid;
 	int mode;
 	struct file_id key = {};
+	struct start_t start = {};
 	struct file_stat *valuep;
 	u64 mntns_id;
 
@@ -82,9 +98,43 @@ static int probe_entry(struct pt_regs *ctx, struct file *file, size_t count,
 		valuep->writes++;
 		valuep->write_bytes += count;
 	}
+
+	start.ts = bpf_ktime_get_ns();
+	start.key = key;
+	start.op = op;
+	bpf_map_update_elem(&starts, &tid, &start, BPF_ANY);
 	return 0;
 };
 
+static int probe_return(void)
+{
+	__u32 tid = (__u32)bpf_get_current_pid_tgid();
+	struct file_stat *valuep;
+	struct start_t *start;
+	__u64 slot;
+
+	start = bpf_map_lookup_elem(&starts, &tid);
+	if (!start)
+		return 0;
+
+	/* The entry could have been sent to user space meanwhile */
+	valuep = bpf_map_lookup_elem(&entries, &start->key);
+	if (!valuep)
+		goto cleanup;
+
+	slot = log2l((bpf_ktime_get_ns() - start->ts) / 1000);
+	if (slot >= MAX_SLOTS)
+		slot = MAX_SLOTS - 1;
+	if (start->op == READ)
+		__sync_fetch_and_add(&valuep->rlat_slots[slot], 1);
+	else
+		__sync_fetch_and_add(&valuep->wlat_slots[slot], 1);
+
+cleanup:
+	bpf_map_delete_elem(&starts, &tid);
+	return 0;
+}
+
 SEC("kprobe/vfs_read")
 int BPF_KPROBE(ig_topfile_rd_e, struct file *file, char *buf, size_t count,
 	       loff_t *pos)
@@ -92,6 +142,12 @@ int BPF_KPROBE(ig_topfile_rd_e, struct file *file, char *buf, size_t count,
 	return probe_entry(ctx, file, count, READ);
 }
 
+SEC("kretprobe/vfs_read")
+int BPF_KRETPROBE(ig_topfile_rd_x)
+{
+	return probe_return();
+}
+
 SEC("kprobe/vfs_write")
 int BPF_KPROBE(ig_topfile_wr_e, struct file *file, const char *buf,
 	       size_t count, loff_t *pos)
@@ -99,4 +155,10 @@ int BPF_KPROBE(ig_topfile_wr_e, struct file *file, const char *buf,
 	return probe_entry(ctx, file, count, WRITE);
 }
 
+SEC("kretprobe/vfs_write")
+int BPF_KRETPROBE(ig_topfile_wr_x)
+{
+	return probe_return();
+}
+
 char LICENSE[] SEC("license") = "Dual BSD/GPL";
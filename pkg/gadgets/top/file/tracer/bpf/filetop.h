@@ -4,6 +4,9 @@
 
 #define PATH_MAX 4096
 #define TASK_COMM_LEN 16
+/* Latencies are counted in log2 slots of microseconds, the last one counts
+ * the ones longer than 2^26 us (~67s) too */
+#define MAX_SLOTS 27
 
 enum op {
 	READ,
@@ -28,6 +31,9 @@ struct file_stat {
 	__u8 filename[PATH_MAX];
 	__u8 comm[TASK_COMM_LEN];
 	char type_;
+	/* Histograms of the latencies of the reads and writes */
+	__u32 rlat_slots[MAX_SLOTS];
+	__u32 wlat_slots[MAX_SLOTS];
 };
 
 #endif /* __FILETOP_H */
@@ -31,7 +31,10 @@ type filetopFileStat struct {
 	Filename   [4096]uint8
 	Comm       [16]uint8
 	Type       int8
-	_          [7]byte
+	_          [3]byte
+	RlatSlots  [27]uint32
+	WlatSlots  [27]uint32
+	_          [4]byte
 }
 
 // loadFiletop returns the embedded CollectionSpec for filetop.
@@ -76,7 +79,9 @@ type filetopSpecs struct {
 // It can be passed ebpf.CollectionSpec.Assign.
 type filetopProgramSpecs struct {
 	IgTopfileRdE *ebpf.ProgramSpec `ebpf:"ig_topfile_rd_e"`
+	IgTopfileRdX *ebpf.ProgramSpec `ebpf:"ig_topfile_rd_x"`
 	IgTopfileWrE *ebpf.ProgramSpec `ebpf:"ig_topfile_wr_e"`
+	IgTopfileWrX *ebpf.ProgramSpec `ebpf:"ig_topfile_wr_x"`
 }
 
 // filetopMapSpecs contains maps before they are loaded into the kernel.
@@ -85,6 +90,7 @@ type filetopProgramSpecs struct {
 type filetopMapSpecs struct {
 	Entries              *ebpf.MapSpec `ebpf:"entries"`
 	GadgetMntnsFilterMap *ebpf.MapSpec `ebpf:"gadget_mntns_filter_map"`
+	Starts               *ebpf.MapSpec `ebpf:"starts"`
 }
 
 // filetopObjects contains all objects after they have been loaded into the kernel.
@@ -108,12 +114,14 @@ func (o *filetopObjects) Close() error {
 type filetopMaps struct {
 	Entries              *ebpf.Map `ebpf:"entries"`
 	GadgetMntnsFilterMap *ebpf.Map `ebpf:"gadget_mntns_filter_map"`
+	Starts               *ebpf.Map `ebpf:"starts"`
 }
 
 func (m *filetopMaps) Close() error {
 	return _FiletopClose(
 		m.Entries,
 		m.GadgetMntnsFilterMap,
+		m.Starts,
 	)
 }
 
@@ -122,13 +130,17 @@ func (m *filetopMaps) Close() error {
 // It can be passed to loadFiletopObjects or ebpf.CollectionSpec.LoadAndAssign.
 type filetopPrograms struct {
 	IgTopfileRdE *ebpf.Program `ebpf:"ig_topfile_rd_e"`
+	IgTopfileRdX *ebpf.Program `ebpf:"ig_topfile_rd_x"`
 	IgTopfileWrE *ebpf.Program `ebpf:"ig_topfile_wr_e"`
+	IgTopfileWrX *ebpf.Program `ebpf:"ig_topfile_wr_x"`
 }
 
 func (p *filetopPrograms) Close() error {
 	return _FiletopClose(
 		p.IgTopfileRdE,
+		p.IgTopfileRdX,
 		p.IgTopfileWrE,
+		p.IgTopfileWrX,
 	)
 }
 
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 	"unsafe"
 
@@ -30,6 +31,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/top"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/top/file/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/histogram"
 	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
@@ -49,7 +51,9 @@ type Tracer struct {
 	config        *Config
 	objs          filetopObjects
 	readLink      link.Link
+	readRetLink   link.Link
 	writeLink     link.Link
+	writeRetLink  link.Link
 	enricher      gadgets.DataEnricherByMntNs
 	eventCallback func(*top.Event[types.Stats])
 	done          chan bool
@@ -93,7 +97,9 @@ func (t *Tracer) close() {
 	close(t.done)
 
 	t.readLink = gadgets.CloseLink(t.readLink)
+	t.readRetLink = gadgets.CloseLink(t.readRetLink)
 	t.writeLink = gadgets.CloseLink(t.writeLink)
+	t.writeRetLink = gadgets.CloseLink(t.writeRetLink)
 
 	t.objs.Close()
 }
@@ -125,9 +131,27 @@ func (t *Tracer) install() error {
 	}
 	t.writeLink = kpwrite
 
+	krpread, err := link.Kretprobe("vfs_read", t.objs.IgTopfileRdX, nil)
+	if err != nil {
+		return fmt.Errorf("attaching kretprobe: %w", err)
+	}
+	t.readRetLink = krpread
+
+	krpwrite, err := link.Kretprobe("vfs_write", t.objs.IgTopfileWrX, nil)
+	if err != nil {
+		return fmt.Errorf("attaching kretprobe: %w", err)
+	}
+	t.writeRetLink = krpwrite
+
 	return nil
 }
 
+// latencyPercentile estimates the p-th percentile of the latencies counted in
+// slots, rounded to microseconds
+func latencyPercentile(slots []uint32, p float64) uint64 {
+	return uint64(math.Round(histogram.PercentileFromExp2Slots(slots, p)))
+}
+
 func (t *Tracer) nextStats() ([]*types.Stats, error) {
 	stats := []*types.Stats{}
 
@@ -180,6 +204,13 @@ func (t *Tracer) nextStats() ([]*types.Stats, error) {
 			Comm:          gadgets.FromCString(fileStat.Comm[:]),
 			FileType:      byte(fileStat.Type),
 			WithMountNsID: eventtypes.WithMountNsID{MountNsID: fileStat.MntnsId},
+
+			ReadLatencyP50:  latencyPercentile(fileStat.RlatSlots[:], 50),
+			ReadLatencyP95:  latencyPercentile(fileStat.RlatSlots[:], 95),
+			ReadLatencyP99:  latencyPercentile(fileStat.RlatSlots[:], 99),
+			WriteLatencyP50: latencyPercentile(fileStat.WlatSlots[:], 50),
+			WriteLatencyP95: latencyPercentile(fileStat.WlatSlots[:], 95),
+			WriteLatencyP99: latencyPercentile(fileStat.WlatSlots[:], 99),
 		}
 
 		if t.enricher != nil {
@@ -47,6 +47,15 @@ type Stats struct {
 	WriteBytes uint64 `json:"wbytes,omitempty" column:"wbytes"`
 	FileType   byte   `json:"fileType,omitempty" column:"T,maxWidth:1"` // R = Regular File, S = Socket, O = Other
 	Filename   string `json:"filename,omitempty" column:"file"`
+
+	// Percentiles of the latencies of the reads and writes in microseconds,
+	// estimated from their log2 histograms
+	ReadLatencyP50  uint64 `json:"rlatP50,omitempty" column:"rlat_p50,hide"`
+	ReadLatencyP95  uint64 `json:"rlatP95,omitempty" column:"rlat_p95,hide"`
+	ReadLatencyP99  uint64 `json:"rlatP99,omitempty" column:"rlat_p99,hide"`
+	WriteLatencyP50 uint64 `json:"wlatP50,omitempty" column:"wlat_p50,hide"`
+	WriteLatencyP95 uint64 `json:"wlatP95,omitempty" column:"wlat_p95,hide"`
+	WriteLatencyP99 uint64 `json:"wlatP99,omitempty" column:"wlat_p99,hide"`
 }
 
 func GetColumns() *columns.Columns[Stats] {
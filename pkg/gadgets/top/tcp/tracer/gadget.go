@@ -56,6 +56,13 @@ func (g *GadgetDesc) ParamDescs() params.ParamDescs {
 			Description:    "Show only TCP events for this IP version: either 4 or 6 (by default all will be printed)",
 			PossibleValues: []string{"all", "4", "6"},
 		},
+		{
+			Key:          types.TCPInfoParam,
+			Title:        "TCP info",
+			Description:  "Show the smoothed round trip time, the number of retransmissions and the congestion window of the connections",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
 	}
 }
 
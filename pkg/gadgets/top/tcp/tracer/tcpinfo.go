@@ -0,0 +1,124 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/vishvananda/netlink"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/top/tcp/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/netnsenter"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
+)
+
+// connKey identifies a TCP connection in a network namespace. IPv4-mapped
+// IPv6 addresses are unmapped, as they are reported differently by the eBPF
+// program and by sock_diag.
+type connKey struct {
+	src   netip.AddrPort
+	dst   netip.AddrPort
+	valid bool
+}
+
+func newConnKey(src, dst netip.Addr, srcPort, dstPort uint16) connKey {
+	return connKey{
+		src:   netip.AddrPortFrom(src.Unmap(), srcPort),
+		dst:   netip.AddrPortFrom(dst.Unmap(), dstPort),
+		valid: src.IsValid() && dst.IsValid(),
+	}
+}
+
+func statsConnKey(stat *types.Stats) connKey {
+	src, _ := netip.ParseAddr(stat.SrcEndpoint.Addr)
+	dst, _ := netip.ParseAddr(stat.DstEndpoint.Addr)
+	return newConnKey(src, dst, stat.SrcEndpoint.Port, stat.DstEndpoint.Port)
+}
+
+// indexTCPInfo indexes the TCP info of the sockets dumped with sock_diag by
+// connection
+func indexTCPInfo(resps []*netlink.InetDiagTCPInfoResp) map[connKey]*netlink.TCPInfo {
+	infos := make(map[connKey]*netlink.TCPInfo, len(resps))
+	for _, resp := range resps {
+		if resp.InetDiagMsg == nil || resp.TCPInfo == nil {
+			continue
+		}
+		id := resp.InetDiagMsg.ID
+		src, _ := netip.AddrFromSlice(id.Source)
+		dst, _ := netip.AddrFromSlice(id.Destination)
+		key := newConnKey(src, dst, id.SourcePort, id.DestinationPort)
+		if key.valid {
+			infos[key] = resp.TCPInfo
+		}
+	}
+	return infos
+}
+
+// dumpTCPInfo returns the TCP info of the sockets in the network namespace of
+// pid
+func dumpTCPInfo(pid int) (map[connKey]*netlink.TCPInfo, error) {
+	var resps []*netlink.InetDiagTCPInfoResp
+	err := netnsenter.NetnsEnter(pid, func() error {
+		for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+			r, err := netlink.SocketDiagTCPInfo(family)
+			if err != nil {
+				return fmt.Errorf("dumping TCP sockets: %w", err)
+			}
+			resps = append(resps, r...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return indexTCPInfo(resps), nil
+}
+
+func applyTCPInfo(stat *types.Stats, info *netlink.TCPInfo) {
+	stat.SRTT = info.Rtt
+	stat.Retransmits = info.Total_retrans
+	stat.Cwnd = info.Snd_cwnd
+}
+
+// addTCPInfo fills the TCP info of the connections. The sockets are dumped
+// once per network namespace, the connections that were closed in the
+// meantime aren't filled.
+func addTCPInfo(stats []*types.Stats) {
+	byNetns := make(map[string]map[connKey]*netlink.TCPInfo)
+	for _, stat := range stats {
+		netns, err := os.Readlink(filepath.Join(host.HostProcFs, fmt.Sprint(stat.Pid), "ns", "net"))
+		if err != nil {
+			log.Debugf("getting network namespace of pid %d: %s", stat.Pid, err)
+			continue
+		}
+		infos, ok := byNetns[netns]
+		if !ok {
+			infos, err = dumpTCPInfo(int(stat.Pid))
+			if err != nil {
+				log.Debugf("getting TCP info of pid %d: %s", stat.Pid, err)
+			}
+			// Don't retry on errors, a nil map has no connections
+			byNetns[netns] = infos
+		}
+		if info, ok := infos[statsConnKey(stat)]; ok {
+			applyTCPInfo(stat, info)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vishvananda/netlink"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/top/tcp/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func newResp(src, dst string, srcPort, dstPort uint16, info *netlink.TCPInfo) *netlink.InetDiagTCPInfoResp {
+	return &netlink.InetDiagTCPInfoResp{
+		InetDiagMsg: &netlink.Socket{
+			ID: netlink.SocketID{
+				Source:          net.ParseIP(src),
+				Destination:     net.ParseIP(dst),
+				SourcePort:      srcPort,
+				DestinationPort: dstPort,
+			},
+		},
+		TCPInfo: info,
+	}
+}
+
+func newStats(src, dst string, srcPort, dstPort uint16) *types.Stats {
+	return &types.Stats{
+		SrcEndpoint: eventtypes.L4Endpoint{L3Endpoint: eventtypes.L3Endpoint{Addr: src}, Port: srcPort},
+		DstEndpoint: eventtypes.L4Endpoint{L3Endpoint: eventtypes.L3Endpoint{Addr: dst}, Port: dstPort},
+	}
+}
+
+func TestIndexTCPInfo(t *testing.T) {
+	t.Parallel()
+
+	v4 := &netlink.TCPInfo{Rtt: 1500, Total_retrans: 2, Snd_cwnd: 10}
+	v6 := &netlink.TCPInfo{Rtt: 80, Snd_cwnd: 20}
+	mapped := &netlink.TCPInfo{Rtt: 300, Total_retrans: 1, Snd_cwnd: 5}
+
+	infos := indexTCPInfo([]*netlink.InetDiagTCPInfoResp{
+		newResp("10.0.0.1", "1.1.1.1", 47228, 443, v4),
+		newResp("fd00::1", "fd00::2", 8080, 52000, v6),
+		newResp("::ffff:127.0.0.1", "::ffff:127.0.0.1", 80, 53130, mapped),
+		// Without TCP info
+		newResp("10.0.0.1", "1.1.1.1", 42604, 80, nil),
+	})
+	require.Len(t, infos, 3)
+
+	type testDefinition struct {
+		stats    *types.Stats
+		expected *types.Stats
+	}
+
+	tests := map[string]testDefinition{
+		"ipv4": {
+			stats:    newStats("10.0.0.1", "1.1.1.1", 47228, 443),
+			expected: &types.Stats{SRTT: 1500, Retransmits: 2, Cwnd: 10},
+		},
+		"ipv6": {
+			stats:    newStats("fd00::1", "fd00::2", 8080, 52000),
+			expected: &types.Stats{SRTT: 80, Cwnd: 20},
+		},
+		"ipv4_mapped": {
+			stats:    newStats("::ffff:127.0.0.1", "::ffff:127.0.0.1", 80, 53130),
+			expected: &types.Stats{SRTT: 300, Retransmits: 1, Cwnd: 5},
+		},
+		"closed": {
+			stats:    newStats("10.0.0.1", "1.1.1.1", 42604, 80),
+			expected: &types.Stats{},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if info, ok := infos[statsConnKey(test.stats)]; ok {
+				applyTCPInfo(test.stats, info)
+			}
+			require.Equal(t, test.expected.SRTT, test.stats.SRTT)
+			require.Equal(t, test.expected.Retransmits, test.stats.Retransmits)
+			require.Equal(t, test.expected.Cwnd, test.stats.Cwnd)
+		})
+	}
+}
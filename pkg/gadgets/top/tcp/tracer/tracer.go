@@ -43,6 +43,7 @@ type Config struct {
 	Interval     time.Duration
 	Iterations   int
 	SortBy       []string
+	TCPInfo      bool
 }
 
 type Tracer struct {
@@ -207,6 +208,10 @@ func (t *Tracer) nextStats() ([]*types.Stats, error) {
 		}
 	}
 
+	if t.config.TCPInfo {
+		addTCPInfo(stats)
+	}
+
 	top.SortStats(stats, t.config.SortBy, &t.colMap)
 
 	return stats, nil
@@ -301,6 +306,7 @@ func (t *Tracer) init(gadgetCtx gadgets.GadgetContext) error {
 	t.config.Interval = time.Second * time.Duration(params.Get(gadgets.ParamInterval).AsInt())
 	t.config.TargetFamily, _ = types.ParseFilterByFamily(params.Get(types.FamilyParam).AsString())
 	t.config.TargetPid = params.Get(types.PidParam).AsInt32()
+	t.config.TCPInfo = params.Get(types.TCPInfoParam).AsBool()
 
 	var err error
 	if t.config.Iterations, err = top.ComputeIterations(t.config.Interval, gadgetCtx.Timeout()); err != nil {
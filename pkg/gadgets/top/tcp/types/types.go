@@ -17,6 +17,7 @@ package types
 import (
 	"fmt"
 	"syscall"
+	"time"
 
 	"github.com/docker/go-units"
 
@@ -27,8 +28,9 @@ import (
 var SortByDefault = []string{"-sent", "-recv"}
 
 const (
-	PidParam    = "pid"
-	FamilyParam = "family"
+	PidParam     = "pid"
+	FamilyParam  = "family"
+	TCPInfoParam = "tcp-info"
 )
 
 func ParseFilterByFamily(family string) (int32, error) {
@@ -56,6 +58,12 @@ type Stats struct {
 
 	Sent     uint64 `json:"sent,omitempty" column:"sent,order:1002"`
 	Received uint64 `json:"received,omitempty" column:"recv,order:1003"`
+
+	// Smoothed round trip time in microseconds
+	SRTT        uint32 `json:"srtt,omitempty" column:"srtt,order:1004,width:10,align:right" columnTags:"param:tcp-info"`
+	Retransmits uint32 `json:"retransmits,omitempty" column:"retrans,order:1005,width:7,align:right" columnTags:"param:tcp-info"`
+	// Congestion window in segments
+	Cwnd uint32 `json:"cwnd,omitempty" column:"cwnd,order:1006,width:6,align:right" columnTags:"param:tcp-info"`
 }
 
 func (e *Stats) GetEndpoints() []*eventtypes.L3Endpoint {
@@ -77,6 +85,9 @@ func GetColumns() *columns.Columns[Stats] {
 	cols.MustSetExtractor("recv", func(stats *Stats) any {
 		return fmt.Sprint(units.BytesSize(float64(stats.Received)))
 	})
+	cols.MustSetExtractor("srtt", func(stats *Stats) any {
+		return fmt.Sprint(time.Duration(stats.SRTT) * time.Microsecond)
+	})
 
 	eventtypes.MustAddVirtualL4EndpointColumn(
 		cols,
@@ -316,7 +316,7 @@ func (t *Tracer) install() error {
 		return event, nil
 	}
 
-	networkTracer, err := networktracer.NewTracer[types.Event]()
+	networkTracer, err := networktracer.NewTracer[types.Event]("trace/dns")
 	if err != nil {
 		return fmt.Errorf("creating network tracer: %w", err)
 	}
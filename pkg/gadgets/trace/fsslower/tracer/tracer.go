@@ -226,8 +226,7 @@ func (t *Tracer) run() {
 		}
 
 		if record.LostSamples > 0 {
-			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
-			t.eventCallback(types.Base(eventtypes.Warn(msg)))
+			t.eventCallback(types.Base(gadgets.EventsDropped("trace/fsslower", record.LostSamples)))
 			continue
 		}
 
@@ -129,7 +129,7 @@ func (t *Tracer) install() error {
 		return fmt.Errorf("loading asset: %w", err)
 	}
 
-	networkTracer, err := networktracer.NewTracer[types.Event]()
+	networkTracer, err := networktracer.NewTracer[types.Event]("trace/network")
 	if err != nil {
 		return fmt.Errorf("creating network tracer: %w", err)
 	}
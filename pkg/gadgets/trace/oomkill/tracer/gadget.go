@@ -15,6 +15,8 @@
 package tracer
 
 import (
+	"fmt"
+
 	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/oomkill/types"
@@ -22,6 +24,14 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/parser"
 )
 
+const (
+	ParamTopProcesses = "top-processes"
+)
+
+// DefaultTopProcesses is the default number of processes using the most
+// memory reported with each event
+const DefaultTopProcesses = 5
+
 type GadgetDesc struct{}
 
 func (g *GadgetDesc) Name() string {
@@ -41,7 +51,15 @@ func (g *GadgetDesc) Description() string {
 }
 
 func (g *GadgetDesc) ParamDescs() params.ParamDescs {
-	return nil
+	return params.ParamDescs{
+		{
+			Key:          ParamTopProcesses,
+			Title:        "Top processes",
+			DefaultValue: fmt.Sprint(DefaultTopProcesses),
+			Description:  "Number of processes using the most memory in the cgroup of the killed process to report. 0 disables it",
+			TypeHint:     params.TypeUint,
+		},
+	}
 }
 
 func (g *GadgetDesc) Parser() parser.Parser {
@@ -0,0 +1,131 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/oomkill/types"
+)
+
+// readCgroupValue reads a single value file of a cgroup v2, like memory.current.
+// "max" is returned as 0.
+func readCgroupValue(path string) (uint64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// readProcessMemory returns the name and the resident set size in bytes of a
+// process from its /proc/<pid>/status file
+func readProcessMemory(procFs string, pid uint32) (types.ProcessMemory, error) {
+	p := types.ProcessMemory{Pid: pid}
+
+	f, err := os.Open(filepath.Join(procFs, fmt.Sprint(pid), "status"))
+	if err != nil {
+		return p, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			p.Comm = value
+		case "VmRSS":
+			// e.g. "1234 kB"
+			rss, err := strconv.ParseUint(strings.TrimSuffix(value, " kB"), 10, 64)
+			if err != nil {
+				return p, fmt.Errorf("parsing VmRSS %q: %w", value, err)
+			}
+			p.RSS = rss * 1024
+		}
+	}
+	return p, scanner.Err()
+}
+
+// topMemoryProcesses returns the n processes of the cgroup v2 at cgroupDir
+// using the most memory, ordered by descending RSS
+func topMemoryProcesses(procFs, cgroupDir string, n int) ([]types.ProcessMemory, error) {
+	f, err := os.Open(filepath.Join(cgroupDir, "cgroup.procs"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var procs []types.ProcessMemory
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pid, err := strconv.ParseUint(scanner.Text(), 10, 32)
+		if err != nil {
+			continue
+		}
+		p, err := readProcessMemory(procFs, uint32(pid))
+		if err != nil {
+			// The process could have exited in the meantime
+			continue
+		}
+		procs = append(procs, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(procs, func(i, j int) bool { return procs[i].RSS > procs[j].RSS })
+	if len(procs) > n {
+		procs = procs[:n]
+	}
+	return procs, nil
+}
+
+// addMemoryStats adds the memory statistics of the cgroup v2 at cgroupDir and
+// its topProcesses processes using the most memory to event
+func addMemoryStats(event *types.Event, procFs, cgroupDir string, topProcesses int) error {
+	var err error
+	event.MemoryCurrent, err = readCgroupValue(filepath.Join(cgroupDir, "memory.current"))
+	if err != nil {
+		return fmt.Errorf("reading memory.current: %w", err)
+	}
+	event.MemoryMax, err = readCgroupValue(filepath.Join(cgroupDir, "memory.max"))
+	if err != nil {
+		return fmt.Errorf("reading memory.max: %w", err)
+	}
+
+	if topProcesses == 0 {
+		return nil
+	}
+	event.TopProcesses, err = topMemoryProcesses(procFs, cgroupDir, topProcesses)
+	if err != nil {
+		return fmt.Errorf("getting processes of cgroup: %w", err)
+	}
+	return nil
+}
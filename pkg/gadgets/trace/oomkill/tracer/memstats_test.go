@@ -0,0 +1,108 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/oomkill/types"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestAddMemoryStats(t *testing.T) {
+	t.Parallel()
+
+	procFs := t.TempDir()
+	writeFile(t, filepath.Join(procFs, "10/status"), "Name:\tsh\nVmRSS:\t    1024 kB\n")
+	writeFile(t, filepath.Join(procFs, "11/status"), "Name:\ttail\nVmRSS:\t  524288 kB\n")
+	writeFile(t, filepath.Join(procFs, "12/status"), "Name:\tsleep\nVmRSS:\t     512 kB\n")
+	// Kernel threads don't have VmRSS
+	writeFile(t, filepath.Join(procFs, "13/status"), "Name:\tkthread\n")
+
+	limited := t.TempDir()
+	writeFile(t, filepath.Join(limited, "memory.current"), "536870912\n")
+	writeFile(t, filepath.Join(limited, "memory.max"), "536870912\n")
+	// 14 exited before being read
+	writeFile(t, filepath.Join(limited, "cgroup.procs"), "10\n11\n12\n13\n14\n")
+
+	unlimited := t.TempDir()
+	writeFile(t, filepath.Join(unlimited, "memory.current"), "4096\n")
+	writeFile(t, filepath.Join(unlimited, "memory.max"), "max\n")
+	writeFile(t, filepath.Join(unlimited, "cgroup.procs"), "")
+
+	type testDefinition struct {
+		cgroupDir         string
+		topProcesses      int
+		expected          types.Event
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"top_processes": {
+			cgroupDir:    limited,
+			topProcesses: 2,
+			expected: types.Event{
+				MemoryCurrent: 536870912,
+				MemoryMax:     536870912,
+				TopProcesses: []types.ProcessMemory{
+					{Pid: 11, Comm: "tail", RSS: 536870912},
+					{Pid: 10, Comm: "sh", RSS: 1048576},
+				},
+			},
+		},
+		"no_top_processes": {
+			cgroupDir: limited,
+			expected: types.Event{
+				MemoryCurrent: 536870912,
+				MemoryMax:     536870912,
+			},
+		},
+		"no_limit": {
+			cgroupDir:    unlimited,
+			topProcesses: 5,
+			expected: types.Event{
+				MemoryCurrent: 4096,
+			},
+		},
+		"no_cgroup": {
+			cgroupDir:         filepath.Join(limited, "missing"),
+			expectedErrString: "reading memory.current",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var event types.Event
+			err := addMemoryStats(&event, procFs, test.cgroupDir, test.topProcesses)
+			if test.expectedErrString != "" {
+				require.ErrorContains(t, err, test.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, event)
+		})
+	}
+}
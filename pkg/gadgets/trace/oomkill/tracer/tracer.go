@@ -25,17 +25,24 @@ import (
 	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/perf"
+	log "github.com/sirupsen/logrus"
 
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/cgroups"
 	gadgetcontext "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-context"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/oomkill/types"
 	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
 )
 
 //go:generate go run github.com/cilium/ebpf/cmd/bpf2go -target $TARGET -cc clang -cflags ${CFLAGS} -type data_t oomkill ./bpf/oomkill.bpf.c -- -I./bpf/
 
 type Config struct {
 	MountnsMap *ebpf.Map
+
+	// TopProcesses is the number of processes using the most memory in the
+	// cgroup of the killed process to report
+	TopProcesses int
 }
 
 type Tracer struct {
@@ -140,13 +147,36 @@ func (t *Tracer) run() {
 			t.enricher.EnrichByMntNs(&event.CommonData, event.MountNsID)
 		}
 
+		// The event is sent before the process is killed, so its cgroup
+		// can usually still be read
+		if err := t.addMemoryStats(&event); err != nil {
+			log.Debugf("adding memory statistics of pid %d: %v", event.KilledPid, err)
+		}
+
 		t.eventCallback(&event)
 	}
 }
 
+func (t *Tracer) addMemoryStats(event *types.Event) error {
+	_, cgroupPathV2, err := cgroups.GetCgroupPaths(int(event.KilledPid))
+	if err != nil {
+		return err
+	}
+	if cgroupPathV2 == "" {
+		return fmt.Errorf("cgroup v2 path not found")
+	}
+	cgroupDir, err := cgroups.CgroupPathV2AddMountpoint(cgroupPathV2)
+	if err != nil {
+		return err
+	}
+	return addMemoryStats(event, host.HostProcFs, cgroupDir, t.config.TopProcesses)
+}
+
 // --- Registry changes
 
 func (t *Tracer) Run(gadgetCtx gadgets.GadgetContext) error {
+	t.config.TopProcesses = int(gadgetCtx.GadgetParams().Get(ParamTopProcesses).AsUint())
+
 	defer t.close()
 	if err := t.install(); err != nil {
 		return fmt.Errorf("installing tracer: %w", err)
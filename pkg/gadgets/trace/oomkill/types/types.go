@@ -15,10 +15,24 @@
 package types
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
 	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 )
 
+// ProcessMemory is the memory used by a process of the cgroup of the killed
+// process
+type ProcessMemory struct {
+	Pid  uint32 `json:"pid"`
+	Comm string `json:"comm"`
+	// RSS is the resident set size in bytes
+	RSS uint64 `json:"rss"`
+}
+
 type Event struct {
 	eventtypes.Event
 	eventtypes.WithMountNsID
@@ -30,10 +44,42 @@ type Event struct {
 	TriggeredUid  uint32 `json:"tuid" column:"tuid,template:uid,hide"`
 	TriggeredGid  uint32 `json:"tgid" column:"tgid,template:gid,hide"`
 	TriggeredComm string `json:"tcomm,omitempty" column:"tcomm,template:comm"`
+
+	// Memory statistics of the cgroup of the killed process when it was
+	// killed, only available with cgroup v2. MemoryMax is 0 if the cgroup
+	// has no limit.
+	MemoryCurrent uint64          `json:"memoryCurrent,omitempty" column:"memcurrent,width:10,hide"`
+	MemoryMax     uint64          `json:"memoryMax,omitempty" column:"memmax,width:10,hide"`
+	TopProcesses  []ProcessMemory `json:"topProcesses,omitempty" column:"topprocs,width:40,hide"`
 }
 
 func GetColumns() *columns.Columns[Event] {
-	return columns.MustCreateColumns[Event]()
+	cols := columns.MustCreateColumns[Event]()
+
+	cols.MustSetExtractor("memcurrent", func(event *Event) any {
+		if event.MemoryCurrent == 0 {
+			return ""
+		}
+		return units.BytesSize(float64(event.MemoryCurrent))
+	})
+	cols.MustSetExtractor("memmax", func(event *Event) any {
+		if event.MemoryCurrent == 0 {
+			return ""
+		}
+		if event.MemoryMax == 0 {
+			return "max"
+		}
+		return units.BytesSize(float64(event.MemoryMax))
+	})
+	cols.MustSetExtractor("topprocs", func(event *Event) any {
+		procs := make([]string, 0, len(event.TopProcesses))
+		for _, p := range event.TopProcesses {
+			procs = append(procs, fmt.Sprintf("%s(%d)=%s", p.Comm, p.Pid, units.BytesSize(float64(p.RSS))))
+		}
+		return strings.Join(procs, ", ")
+	})
+
+	return cols
 }
 
 func Base(ev eventtypes.Event) *Event {
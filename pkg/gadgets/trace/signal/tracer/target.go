@@ -0,0 +1,79 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/trace/signal/types"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/host"
+)
+
+// readTargetProcess returns the comm and the mount namespace of the process
+// pid. The mount namespace is 0 when it can't be read, e.g. for zombie
+// processes.
+func readTargetProcess(procFs string, pid uint32) (string, uint64, error) {
+	dir := filepath.Join(procFs, fmt.Sprint(pid))
+
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return "", 0, fmt.Errorf("reading comm of pid %d: %w", pid, err)
+	}
+
+	var mntns uint64
+	if fi, err := os.Stat(filepath.Join(dir, "ns", "mnt")); err == nil {
+		if stat, ok := fi.Sys().(*syscall.Stat_t); ok {
+			mntns = stat.Ino
+		}
+	}
+
+	return strings.TrimSuffix(string(comm), "\n"), mntns, nil
+}
+
+// enrichTarget adds the details of the process receiving the signal, looked up
+// by its pid. Signals sent to process groups, with a negative pid, aren't
+// enriched.
+func (t *Tracer) enrichTarget(event *types.Event) {
+	if event.TargetPid == 0 || int32(event.TargetPid) < 0 {
+		return
+	}
+
+	comm, mntns, err := readTargetProcess(host.HostProcFs, event.TargetPid)
+	if err != nil {
+		// The process already exited
+		return
+	}
+	event.TargetComm = comm
+	event.TargetMountNsID = mntns
+
+	if t.enricher == nil || mntns == 0 {
+		return
+	}
+	var target eventtypes.CommonData
+	t.enricher.EnrichByMntNs(&target, mntns)
+	event.TargetNamespace = target.K8s.Namespace
+	event.TargetPod = target.K8s.PodName
+	event.TargetContainer = target.K8s.ContainerName
+	if event.TargetContainer == "" {
+		event.TargetContainer = target.Runtime.ContainerName
+	}
+}
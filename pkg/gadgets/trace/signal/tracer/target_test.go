@@ -0,0 +1,54 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package tracer
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTargetProcess(t *testing.T) {
+	t.Parallel()
+
+	procFs := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procFs, "42", "ns"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(procFs, "42", "comm"), []byte("nginx\n"), 0o644))
+	mntns := filepath.Join(procFs, "42", "ns", "mnt")
+	require.NoError(t, os.WriteFile(mntns, nil, 0o644))
+	fi, err := os.Stat(mntns)
+	require.NoError(t, err)
+
+	comm, ino, err := readTargetProcess(procFs, 42)
+	require.NoError(t, err)
+	require.Equal(t, "nginx", comm)
+	require.Equal(t, fi.Sys().(*syscall.Stat_t).Ino, ino)
+
+	// Zombie processes have a comm but their namespaces can't be read
+	require.NoError(t, os.MkdirAll(filepath.Join(procFs, "43"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(procFs, "43", "comm"), []byte("sleep\n"), 0o644))
+	comm, ino, err = readTargetProcess(procFs, 43)
+	require.NoError(t, err)
+	require.Equal(t, "sleep", comm)
+	require.Zero(t, ino)
+
+	_, _, err = readTargetProcess(procFs, 44)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -193,8 +193,7 @@ func (t *Tracer) run() {
 		}
 
 		if record.LostSamples > 0 {
-			msg := fmt.Sprintf("lost %d samples", record.LostSamples)
-			t.eventCallback(types.Base(eventtypes.Warn(msg)))
+			t.eventCallback(types.Base(gadgets.EventsDropped("trace/signal", record.LostSamples)))
 			continue
 		}
 
@@ -218,6 +217,7 @@ func (t *Tracer) run() {
 		if t.enricher != nil {
 			t.enricher.EnrichByMntNs(&event.CommonData, event.MountNsID)
 		}
+		t.enrichTarget(&event)
 
 		t.eventCallback(&event)
 	}
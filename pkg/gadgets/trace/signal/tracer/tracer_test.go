@@ -154,6 +154,9 @@ func TestSignalTracer(t *testing.T) {
 			eventCallback := func(event *types.Event) {
 				// normalize
 				event.Timestamp = 0
+				// The target could have been killed before being looked up
+				event.TargetComm = ""
+				event.TargetMountNsID = 0
 
 				events = append(events, *event)
 			}
@@ -29,9 +29,16 @@ type Event struct {
 	// longest is SIGRTMIN+XX (11 chars).
 	Signal    string `json:"signal,omitempty" column:"signal,minWidth:6,maxWidth:11,ellipsis:start"`
 	TargetPid uint32 `json:"tpid,omitempty" column:"tpid,template:pid"`
-	Retval    int    `json:"ret,omitempty" column:"ret,width:3,fixed"`
-	Uid       uint32 `json:"uid" column:"uid,template:uid,hide"`
-	Gid       uint32 `json:"gid" column:"gid,template:gid,hide"`
+	// The target process is looked up when the event is received, it could
+	// have exited in the meantime
+	TargetComm      string `json:"tcomm,omitempty" column:"tcomm,template:comm"`
+	TargetMountNsID uint64 `json:"tmntns,omitempty" column:"tmntns,template:ns,hide"`
+	TargetNamespace string `json:"tnamespace,omitempty" column:"tnamespace,template:namespace,hide"`
+	TargetPod       string `json:"tpod,omitempty" column:"tpod,template:pod,hide"`
+	TargetContainer string `json:"tcontainer,omitempty" column:"tcontainer,template:container,hide"`
+	Retval          int    `json:"ret,omitempty" column:"ret,width:3,fixed"`
+	Uid             uint32 `json:"uid" column:"uid,template:uid,hide"`
+	Gid             uint32 `json:"gid" column:"gid,template:gid,hide"`
 }
 
 func GetColumns() *columns.Columns[Event] {
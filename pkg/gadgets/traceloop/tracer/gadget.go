@@ -15,13 +15,26 @@
 package tracer
 
 import (
+	"fmt"
+	"strings"
+
 	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/traceloop/types"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/parser"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/syscalls"
+)
+
+const (
+	ParamSyscallFilters  = "syscall-filters"
+	ParamPerfBufferPages = "perf-buffer-pages"
 )
 
+// maxPerfBufferPages is the maximum number of pages of the perf buffer of each
+// container and CPU
+const maxPerfBufferPages = 4096
+
 type GadgetDesc struct{}
 
 func (g *GadgetDesc) Name() string {
@@ -41,7 +54,46 @@ func (g *GadgetDesc) Description() string {
 }
 
 func (g *GadgetDesc) ParamDescs() params.ParamDescs {
-	return nil
+	return params.ParamDescs{
+		{
+			Key:         ParamSyscallFilters,
+			Title:       "Syscall filters",
+			Description: "Comma-separated list of syscalls to show, e.g. open,openat,execve. All syscalls are shown if empty",
+			TypeHint:    params.TypeString,
+			Validator: func(value string) error {
+				_, err := parseSyscallFilters(value)
+				return err
+			},
+		},
+		{
+			Key:          ParamPerfBufferPages,
+			Title:        "Perf buffer pages",
+			Description:  "Number of pages of the perf buffer of each container and CPU. The older syscalls are overwritten when it's full",
+			DefaultValue: fmt.Sprint(gadgets.PerfBufferPages),
+			TypeHint:     params.TypeUint,
+			Validator:    params.ValidateUintRange(1, maxPerfBufferPages),
+		},
+	}
+}
+
+// parseSyscallFilters parses a comma-separated list of syscall names. It
+// returns nil if the list is empty, i.e. all syscalls are shown.
+func parseSyscallFilters(value string) (map[string]struct{}, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	filters := make(map[string]struct{})
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := syscalls.GetSyscallNumberByName(name); !ok {
+			return nil, fmt.Errorf("unknown syscall %q", name)
+		}
+		filters[name] = struct{}{}
+	}
+	return filters, nil
 }
 
 func (g *GadgetDesc) Parser() parser.Parser {
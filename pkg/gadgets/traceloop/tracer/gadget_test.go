@@ -0,0 +1,64 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSyscallFilters(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		value             string
+		expected          map[string]struct{}
+		expectedErrString string
+	}
+
+	tests := map[string]testDefinition{
+		"empty": {
+			value: "",
+		},
+		"single": {
+			value:    "openat",
+			expected: map[string]struct{}{"openat": {}},
+		},
+		"multiple_with_spaces": {
+			value:    "openat, execve,,read",
+			expected: map[string]struct{}{"openat": {}, "execve": {}, "read": {}},
+		},
+		"unknown": {
+			value:             "openat,foo",
+			expectedErrString: "unknown syscall \"foo\"",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			filters, err := parseSyscallFilters(test.value)
+			if test.expectedErrString != "" {
+				require.EqualError(t, err, test.expectedErrString)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expected, filters)
+		})
+	}
+}
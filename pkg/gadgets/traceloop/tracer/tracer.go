@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"sync"
@@ -81,6 +82,14 @@ type Tracer struct {
 	// The keys of this map are containerID.
 	readers sync.Map
 
+	// perfBufferPages is the number of pages of the perf buffer of each
+	// container and CPU.
+	perfBufferPages int
+	// syscallFilters contains the names of the syscalls to report, all of
+	// them are reported if nil. The eBPF program records all syscalls, they
+	// are filtered when reading the perf buffers.
+	syscallFilters map[string]struct{}
+
 	gadgetCtx     gadgets.GadgetContext
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -110,7 +119,8 @@ type syscallEventContinued struct {
 
 func NewTracer(enricher gadgets.DataEnricherByMntNs) (*Tracer, error) {
 	t := &Tracer{
-		enricher: enricher,
+		enricher:        enricher,
+		perfBufferPages: gadgets.PerfBufferPages,
 	}
 	if err := t.install(); err != nil {
 		t.close()
@@ -212,7 +222,7 @@ func (t *Tracer) Attach(containerID string, mntnsID uint64) error {
 	}
 
 	// 2. Use this inner Map to create the perf reader.
-	perfReader, err := perf.NewReaderWithOptions(innerBuffer, gadgets.PerfBufferPages*os.Getpagesize(), perf.ReaderOptions{Overwritable: true})
+	perfReader, err := perf.NewReaderWithOptions(innerBuffer, t.perfBufferPages*os.Getpagesize(), perf.ReaderOptions{Overwritable: true})
 	if err != nil {
 		innerBuffer.Close()
 
@@ -614,6 +624,13 @@ func (t *Tracer) Read(containerID string) ([]*types.Event, error) {
 		}
 	}
 
+	if t.syscallFilters != nil {
+		events = slices.DeleteFunc(events, func(event *types.Event) bool {
+			_, ok := t.syscallFilters[event.Syscall]
+			return !ok
+		})
+	}
+
 	// Sort all events by ascending timestamp.
 	sort.Slice(events, func(i, j int) bool {
 		return events[i].Timestamp < events[j].Timestamp
@@ -658,6 +675,15 @@ func (g *GadgetDesc) NewInstance() (gadgets.Gadget, error) {
 }
 
 func (t *Tracer) Init(gadgetCtx gadgets.GadgetContext) error {
+	params := gadgetCtx.GadgetParams()
+	t.perfBufferPages = int(params.Get(ParamPerfBufferPages).AsUint())
+
+	var err error
+	t.syscallFilters, err = parseSyscallFilters(params.Get(ParamSyscallFilters).AsString())
+	if err != nil {
+		return fmt.Errorf("parsing syscall filters: %w", err)
+	}
+
 	if err := t.install(); err != nil {
 		t.close()
 		return fmt.Errorf("installing tracer: %w", err)
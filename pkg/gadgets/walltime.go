@@ -0,0 +1,162 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package gadgets
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+// timeRecalibrationInterval is how often the offset between the boot time and
+// the wall time is computed again, to follow the adjustments done by NTP
+const timeRecalibrationInterval = 10 * time.Second
+
+var (
+	// timeDiff is the offset, in nanoseconds, between the boot time and the
+	// wall time
+	timeDiff atomic.Int64
+
+	timeRecalibrationOnce sync.Once
+)
+
+func init() {
+	if err := calibrateTimeDiff(); err != nil {
+		panic(err)
+	}
+}
+
+// calibrateTimeDiff computes the offset between the boot time and the wall
+// time. Both clocks are read twice and the closest pair is used, so the offset
+// isn't skewed if we're preempted in between.
+func calibrateTimeDiff() error {
+	best := time.Duration(math.MaxInt64)
+	var diff int64
+
+	for i := 0; i < 3; i++ {
+		var t unix.Timespec
+		before := time.Now()
+		if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &t); err != nil {
+			return err
+		}
+		after := time.Now()
+
+		if elapsed := after.Sub(before); elapsed < best {
+			best = elapsed
+			wall := before.UnixNano() + int64(elapsed/2)
+			diff = wall - t.Nano()
+		}
+	}
+
+	timeDiff.Store(diff)
+	return nil
+}
+
+// startTimeRecalibration recalibrates the offset between the boot time and the
+// wall time periodically and each time the wall clock is set, e.g. by an NTP
+// step or after a suspend
+func startTimeRecalibration() {
+	go func() {
+		ticker := time.NewTicker(timeRecalibrationInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := calibrateTimeDiff(); err != nil {
+				log.Warnf("recalibrating wall time: %s", err)
+			}
+		}
+	}()
+
+	go func() {
+		if err := watchClockChanges(func() {
+			if err := calibrateTimeDiff(); err != nil {
+				log.Warnf("recalibrating wall time: %s", err)
+			}
+		}); err != nil {
+			log.Debugf("watching wall clock changes: %s", err)
+		}
+	}()
+}
+
+// watchClockChanges calls cb each time the wall clock is set. It only returns
+// on error.
+func watchClockChanges(cb func()) error {
+	fd, err := unix.TimerfdCreate(unix.CLOCK_REALTIME, unix.TFD_CLOEXEC)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	// A timer that never expires but whose reads are canceled when the
+	// clock is set
+	spec := unix.ItimerSpec{
+		Value: unix.Timespec{Sec: math.MaxInt32},
+	}
+
+	buf := make([]byte, 8)
+	for {
+		if err := unix.TimerfdSettime(fd, unix.TFD_TIMER_ABSTIME|unix.TFD_TIMER_CANCEL_ON_SET, &spec, nil); err != nil {
+			return err
+		}
+
+		_, err := unix.Read(fd, buf)
+		switch {
+		case errors.Is(err, unix.ECANCELED):
+			cb()
+		case errors.Is(err, unix.EINTR):
+		case err != nil:
+			return err
+		}
+	}
+}
+
+// WallTimeFromBootTime converts a time from bpf_ktime_get_boot_ns() to the
+// wall time with nano precision.
+//
+// Example:
+//
+//	fmt.Printf("Time: %s\n", WallTimeFromBootTime(ts).String())
+//
+// would display:
+//
+//	Time: 2022-12-15T16:49:00.452371948+01:00
+//
+// Shell command to convert the number to a date:
+//
+//	$ date -d @$(echo 1671447636499110634/1000000000|bc -l) +"%d-%m-%Y %H:%M:%S:%N"
+//	19-12-2022 12:00:36:499110634
+//
+// bpf_ktime_get_boot_ns was added in Linux 5.7. If not available and the BPF
+// program returns 0, just get the timestamp in userspace.
+//
+// The offset between both clocks is recalibrated periodically and when the
+// wall clock is set, so timestamps don't drift in long traces.
+func WallTimeFromBootTime(ts uint64) types.Time {
+	timeRecalibrationOnce.Do(startTimeRecalibration)
+
+	if ts == 0 {
+		return types.Time(time.Now().UnixNano())
+	}
+	return types.Time(int64(ts) + timeDiff.Load())
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package gadgets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func bootTimeNow(t *testing.T) uint64 {
+	t.Helper()
+
+	var ts unix.Timespec
+	require.NoError(t, unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts))
+	return uint64(ts.Nano())
+}
+
+func TestWallTimeFromBootTime(t *testing.T) {
+	wallTime := time.Unix(0, int64(WallTimeFromBootTime(bootTimeNow(t))))
+	require.WithinDuration(t, time.Now(), wallTime, 100*time.Millisecond)
+
+	// Without a timestamp, the current time is used
+	wallTime = time.Unix(0, int64(WallTimeFromBootTime(0)))
+	require.WithinDuration(t, time.Now(), wallTime, 100*time.Millisecond)
+}
+
+func TestCalibrateTimeDiff(t *testing.T) {
+	// Simulate the wall clock being stepped an hour after calibrating
+	timeDiff.Add(int64(time.Hour))
+	wallTime := time.Unix(0, int64(WallTimeFromBootTime(bootTimeNow(t))))
+	require.WithinDuration(t, time.Now().Add(time.Hour), wallTime, 100*time.Millisecond)
+
+	require.NoError(t, calibrateTimeDiff())
+	wallTime = time.Unix(0, int64(WallTimeFromBootTime(bootTimeNow(t))))
+	require.WithinDuration(t, time.Now(), wallTime, 100*time.Millisecond)
+}
@@ -64,6 +64,13 @@ func (g *GadgetTracerManager) AddTracer(tracerID string, containerSelector conta
 	return g.tracerCollection.AddTracer(tracerID, containerSelector)
 }
 
+func (g *GadgetTracerManager) UpdateTracer(tracerID string, containerSelector containercollection.ContainerSelector) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.tracerCollection.UpdateTracer(tracerID, containerSelector)
+}
+
 func (g *GadgetTracerManager) RemoveTracer(tracerID string) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
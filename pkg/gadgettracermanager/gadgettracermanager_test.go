@@ -16,6 +16,7 @@ package gadgettracermanager
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
@@ -87,3 +88,61 @@ func TestTracer(t *testing.T) {
 		t.Fatalf("Error while checking tracer %s: not found", "my_tracer_id2")
 	}
 }
+
+func TestUpdateTracer(t *testing.T) {
+	g, err := NewServer(&Conf{NodeName: "fake-node", HookMode: "none", TestOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to create new server: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		g.ContainerCollection.AddContainer(&containercollection.Container{
+			Runtime: containercollection.RuntimeMetadata{
+				BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+					ContainerID: fmt.Sprintf("container%d", i),
+				},
+			},
+			K8s: containercollection.K8sMetadata{
+				BasicK8sMetadata: types.BasicK8sMetadata{
+					Namespace:     fmt.Sprintf("this-namespace%d", i),
+					PodName:       fmt.Sprintf("pod%d", i),
+					ContainerName: "container",
+				},
+			},
+		})
+	}
+
+	selector := func(namespace string) containercollection.ContainerSelector {
+		return containercollection.ContainerSelector{
+			K8s: containercollection.K8sSelector{
+				BasicK8sMetadata: types.BasicK8sMetadata{
+					Namespace: namespace,
+				},
+			},
+		}
+	}
+
+	if err := g.AddTracer("my_tracer_id", selector("this-namespace0")); err != nil {
+		t.Fatalf("Failed to add tracer: %v", err)
+	}
+	dump := g.tracerCollection.TracerDump()
+	if !strings.Contains(dump, "this-namespace0/pod0") || strings.Contains(dump, "this-namespace1/pod1") {
+		t.Fatalf("Error while checking tracer matches before update:\n%s", dump)
+	}
+
+	// Update the tracer to match several namespaces
+	if err := g.UpdateTracer("my_tracer_id", selector("this-namespace1,this-namespace2")); err != nil {
+		t.Fatalf("Failed to update tracer: %v", err)
+	}
+	dump = g.tracerCollection.TracerDump()
+	if strings.Contains(dump, "this-namespace0/pod0") ||
+		!strings.Contains(dump, "this-namespace1/pod1") ||
+		!strings.Contains(dump, "this-namespace2/pod2") {
+		t.Fatalf("Error while checking tracer matches after update:\n%s", dump)
+	}
+
+	// Update non-existent Tracer
+	if err := g.UpdateTracer("my_tracer_id99", selector("this-namespace0")); err == nil {
+		t.Fatal("Error while updating non-existent tracer: no error detected")
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hardening
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/syndtr/gocapability/capability"
+	"golang.org/x/sys/unix"
+)
+
+// RequiredCapabilities are the capabilities kept with LevelRestricted
+var RequiredCapabilities = []capability.Cap{
+	// bpf() on kernels without CAP_BPF, setns(), fanotify and mounting the
+	// bpf and tracing filesystems
+	capability.CAP_SYS_ADMIN,
+	capability.CAP_BPF,
+	capability.CAP_PERFMON,
+	// Networking programs and sock_diag in the network namespaces of the
+	// containers
+	capability.CAP_NET_ADMIN,
+	// Raw sockets of the socket filters
+	capability.CAP_NET_RAW,
+	// Reading /proc/<pid>/{root,ns,maps} of the containers
+	capability.CAP_SYS_PTRACE,
+	// Raising the memlock limit on kernels older than 5.11
+	capability.CAP_SYS_RESOURCE,
+	capability.CAP_IPC_LOCK,
+	// Reading the files of the containers and name_to_handle_at()
+	capability.CAP_DAC_OVERRIDE,
+	capability.CAP_DAC_READ_SEARCH,
+	// Kernel addresses in /proc/kallsyms
+	capability.CAP_SYSLOG,
+	// Setting the group of the unix socket of the daemon and listening on
+	// privileged ports
+	capability.CAP_CHOWN,
+	capability.CAP_NET_BIND_SERVICE,
+}
+
+func capabilityNames(caps []capability.Cap) []string {
+	names := make([]string, len(caps))
+	for i, c := range caps {
+		names[i] = c.String()
+	}
+	return names
+}
+
+// capabilityMask returns the bitmask of caps in the two 32 bits words used by
+// capset()
+func capabilityMask(caps []capability.Cap) [2]uint32 {
+	var mask [2]uint32
+	for _, c := range caps {
+		mask[c>>5] |= 1 << (uint(c) & 31)
+	}
+	return mask
+}
+
+func isKept(c capability.Cap, keep []capability.Cap) bool {
+	mask := capabilityMask(keep)
+	return mask[c>>5]&(1<<(uint(c)&31)) != 0
+}
+
+// allThreadsSyscall runs a syscall changing the credentials in all the
+// threads of the process, as they are per thread
+func allThreadsSyscall(trap, a1, a2, a3 uintptr) error {
+	_, _, errno := syscall.AllThreadsSyscall(trap, a1, a2, a3)
+	if errno == syscall.ENOTSUP {
+		return errors.New("not supported in binaries built with cgo")
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// dropCapabilities removes all the capabilities but keep from the bounding,
+// ambient, inheritable, permitted and effective sets of all threads
+func dropCapabilities(keep []capability.Cap) error {
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("getting capabilities: %w", err)
+	}
+
+	// The bounding set can only be changed with CAP_SETPCAP, so before
+	// changing the other sets. Without it, e.g. in a container, the
+	// bounding set was already restricted by the runtime.
+	hasSetPCap := data[0].Effective&(1<<capability.CAP_SETPCAP) != 0
+	for c := capability.Cap(0); hasSetPCap && c <= capability.CAP_LAST_CAP; c++ {
+		if isKept(c, keep) {
+			continue
+		}
+		err := allThreadsSyscall(unix.SYS_PRCTL, unix.PR_CAPBSET_DROP, uintptr(c), 0)
+		// The capability isn't supported by the kernel
+		if errors.Is(err, syscall.EINVAL) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("dropping %s from the bounding set: %w", c, err)
+		}
+	}
+
+	if err := allThreadsSyscall(unix.SYS_PRCTL, unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0); err != nil &&
+		!errors.Is(err, syscall.EINVAL) {
+		return fmt.Errorf("clearing the ambient set: %w", err)
+	}
+
+	mask := capabilityMask(keep)
+	for i := range data {
+		data[i].Effective &= mask[i]
+		data[i].Permitted &= mask[i]
+		data[i].Inheritable &= mask[i]
+	}
+	err := allThreadsSyscall(unix.SYS_CAPSET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if err != nil {
+		return fmt.Errorf("setting capabilities: %w", err)
+	}
+	return nil
+}
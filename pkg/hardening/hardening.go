@@ -0,0 +1,108 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hardening restricts what the Inspektor Gadget process itself can
+// do: it drops the capabilities it doesn't need and installs a seccomp filter
+// denying the syscalls it never uses, so a compromised process can do less
+// harm on the node.
+package hardening
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Level is how much the process is restricted
+type Level string
+
+const (
+	// LevelFull keeps all the privileges of the process
+	LevelFull Level = "full"
+	// LevelRestricted keeps only the capabilities required to run gadgets
+	// and installs the seccomp filter
+	LevelRestricted Level = "restricted"
+)
+
+var Levels = []Level{LevelFull, LevelRestricted}
+
+func ParseLevel(s string) (Level, error) {
+	for _, level := range Levels {
+		if string(level) == s {
+			return level, nil
+		}
+	}
+	levels := make([]string, len(Levels))
+	for i, level := range Levels {
+		levels[i] = string(level)
+	}
+	return "", fmt.Errorf("invalid privileged level %q, expected one of %s", s, strings.Join(levels, ", "))
+}
+
+var (
+	privilegedLevelFlag = string(LevelFull)
+
+	applied bool
+)
+
+// AddFlags adds the --privileged-level flag
+func AddFlags(command *cobra.Command) {
+	command.PersistentFlags().StringVar(
+		&privilegedLevelFlag,
+		"privileged-level",
+		string(LevelFull),
+		fmt.Sprintf("Privileges kept by the process once initialized. %q drops the capabilities not required to run gadgets and denies the syscalls never used with seccomp. One of %v", LevelRestricted, Levels),
+	)
+}
+
+// Apply restricts the process according to the --privileged-level flag. It
+// must be called after the initialization requiring more privileges, e.g.
+// host.Init(). Calling it more than once has no effect.
+func Apply() error {
+	if applied {
+		return nil
+	}
+
+	level, err := ParseLevel(privilegedLevelFlag)
+	if err != nil {
+		return err
+	}
+	if err := ApplyLevel(level); err != nil {
+		return err
+	}
+
+	applied = true
+	return nil
+}
+
+// ApplyLevel restricts the process according to level
+func ApplyLevel(level Level) error {
+	if level == LevelFull {
+		return nil
+	}
+
+	log.Debugf("Keeping only capabilities %s", strings.Join(capabilityNames(RequiredCapabilities), ", "))
+	if err := dropCapabilities(RequiredCapabilities); err != nil {
+		return fmt.Errorf("dropping capabilities: %w", err)
+	}
+
+	log.Debugf("Denying syscalls %s", strings.Join(DeniedSyscalls, ", "))
+	if err := installSeccompFilter(DeniedSyscalls); err != nil {
+		return fmt.Errorf("installing seccomp filter: %w", err)
+	}
+
+	return nil
+}
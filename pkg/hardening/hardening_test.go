@@ -0,0 +1,134 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hardening
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/gocapability/capability"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	level, err := ParseLevel("restricted")
+	require.NoError(t, err)
+	require.Equal(t, LevelRestricted, level)
+
+	_, err = ParseLevel("none")
+	require.EqualError(t, err, `invalid privileged level "none", expected one of full, restricted`)
+}
+
+func TestCapabilityMask(t *testing.T) {
+	t.Parallel()
+
+	mask := capabilityMask([]capability.Cap{capability.CAP_CHOWN, capability.CAP_SYS_ADMIN, capability.CAP_BPF})
+	require.Equal(t, [2]uint32{1<<0 | 1<<21, 1 << 7}, mask)
+	require.True(t, isKept(capability.CAP_BPF, RequiredCapabilities))
+	require.False(t, isKept(capability.CAP_SYS_MODULE, RequiredCapabilities))
+}
+
+// runFilter runs the classic BPF instructions used by buildSeccompFilter on a
+// struct seccomp_data
+func runFilter(t *testing.T, filter []unix.SockFilter, arch, nr uint32) uint32 {
+	data := make([]byte, 8)
+	binary.NativeEndian.PutUint32(data[seccompDataNrOffset:], nr)
+	binary.NativeEndian.PutUint32(data[seccompDataArchOffset:], arch)
+
+	var acc uint32
+	for pc := 0; pc < len(filter); pc++ {
+		ins := filter[pc]
+		switch ins.Code {
+		case unix.BPF_LD | unix.BPF_W | unix.BPF_ABS:
+			acc = binary.NativeEndian.Uint32(data[ins.K:])
+		case unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K:
+			if acc == ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K:
+			if acc >= ins.K {
+				pc += int(ins.Jt)
+			} else {
+				pc += int(ins.Jf)
+			}
+		case unix.BPF_RET | unix.BPF_K:
+			return ins.K
+		default:
+			t.Fatalf("unexpected instruction %#x", ins.Code)
+		}
+	}
+	t.Fatal("filter didn't return")
+	return 0
+}
+
+func TestBuildSeccompFilter(t *testing.T) {
+	t.Parallel()
+
+	const arch = 0xc000003e
+	filter, err := buildSeccompFilter(arch, []uint32{101, 246, 169})
+	require.NoError(t, err)
+
+	deny := uint32(seccompRetErrno | uint32(unix.EPERM))
+
+	type testDefinition struct {
+		arch     uint32
+		nr       uint32
+		expected uint32
+	}
+
+	tests := map[string]testDefinition{
+		"allowed": {
+			arch:     arch,
+			nr:       0,
+			expected: seccompRetAllow,
+		},
+		"denied_first": {
+			arch:     arch,
+			nr:       101,
+			expected: deny,
+		},
+		"denied_last": {
+			arch:     arch,
+			nr:       169,
+			expected: deny,
+		},
+		"other_arch": {
+			arch:     0x40000003,
+			nr:       0,
+			expected: deny,
+		},
+	}
+	if x32SyscallBit != 0 {
+		tests["x32"] = testDefinition{
+			arch:     arch,
+			nr:       x32SyscallBit | 1,
+			expected: deny,
+		}
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, test.expected, runFilter(t, filter, test.arch, test.nr))
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hardening
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/syscalls"
+)
+
+// DeniedSyscalls are the syscalls denied with LevelRestricted. Inspektor
+// Gadget never uses them and they would allow taking over the node.
+var DeniedSyscalls = []string{
+	"kexec_load",
+	"kexec_file_load",
+	"init_module",
+	"finit_module",
+	"delete_module",
+	"reboot",
+	"swapon",
+	"swapoff",
+	"acct",
+	"settimeofday",
+	"clock_settime",
+	"clock_adjtime",
+	"adjtimex",
+	"pivot_root",
+	"chroot",
+	"open_by_handle_at",
+	"ptrace",
+	"process_vm_writev",
+	"userfaultfd",
+	"add_key",
+	"keyctl",
+	"request_key",
+	"setuid",
+	"setgid",
+	"setreuid",
+	"setregid",
+	"setresuid",
+	"setresgid",
+	"setgroups",
+}
+
+// Not defined in golang.org/x/sys/unix, see linux/seccomp.h
+const (
+	seccompSetModeFilter   = 1
+	seccompFilterFlagTsync = 1
+	seccompRetAllow        = 0x7fff0000
+	seccompRetErrno        = 0x00050000
+
+	// Offsets in struct seccomp_data
+	seccompDataNrOffset   = 0
+	seccompDataArchOffset = 4
+)
+
+func stmt(code uint16, k uint32) unix.SockFilter {
+	return unix.SockFilter{Code: code, K: k}
+}
+
+func jump(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+	return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+}
+
+// buildSeccompFilter returns a classic BPF program denying the given syscalls
+// with EPERM. Syscalls of other architectures, like the 32 bits ones, are
+// denied too.
+func buildSeccompFilter(arch uint32, nrs []uint32) ([]unix.SockFilter, error) {
+	if len(nrs) > 255 {
+		return nil, errors.New("too many syscalls")
+	}
+
+	// Jumps are relative to the next instruction, the instructions denying
+	// the syscall are after the ALLOW return
+	filter := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataArchOffset),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, seccompDataNrOffset),
+	}
+	if x32SyscallBit != 0 {
+		filter = append(filter, jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, uint8(len(nrs))+1, 0))
+	}
+	for i, nr := range nrs {
+		filter = append(filter, jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nr, uint8(len(nrs)-i), 0))
+	}
+	filter = append(filter,
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetAllow),
+		stmt(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+	)
+	return filter, nil
+}
+
+// installSeccompFilter installs a seccomp filter denying names in all the
+// threads of the process. Names unknown on this architecture are ignored.
+func installSeccompFilter(names []string) error {
+	if auditArch == 0 {
+		return fmt.Errorf("seccomp filter not supported on %s", runtime.GOARCH)
+	}
+
+	nrs := make([]uint32, 0, len(names))
+	for _, name := range names {
+		if nr, ok := syscalls.GetSyscallNumberByName(name); ok {
+			nrs = append(nrs, uint32(nr))
+		}
+	}
+
+	filter, err := buildSeccompFilter(auditArch, nrs)
+	if err != nil {
+		return err
+	}
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	// no_new_privs is required to install a filter without CAP_SYS_ADMIN,
+	// TSYNC then sets it in all threads
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("setting no_new_privs: %w", err)
+	}
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter, seccompFilterFlagTsync, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -20,6 +20,7 @@ package histogram
 
 import (
 	"fmt"
+	"math"
 	"strings"
 )
 
@@ -76,6 +77,34 @@ func NewIntervalsFromExp2Slots(slots []uint32) []Interval {
 	return intervals[:indexMax+1]
 }
 
+// PercentileFromExp2Slots estimates the p-th percentile, between 0 and 100, of
+// the values counted in an exp-2 histogram represented in slots. The estimate
+// is interpolated linearly within the interval of the slot it falls in.
+func PercentileFromExp2Slots[T uint32 | uint64](slots []T, p float64) float64 {
+	var total uint64
+	for _, count := range slots {
+		total += uint64(count)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	target := p / 100 * float64(total)
+	var cumulative float64
+	for i, count := range slots {
+		if count == 0 || cumulative+float64(count) < target {
+			cumulative += float64(count)
+			continue
+		}
+		start, end := math.Exp2(float64(i)), math.Exp2(float64(i+1))
+		if i == 0 {
+			start = 0
+		}
+		return start + (end-start)*(target-cumulative)/float64(count)
+	}
+	return math.Exp2(float64(len(slots)))
+}
+
 // String returns a string representation of the histogram. It is a golang
 // adaption of iovisor/bcc print_log2_hist():
 // https://github.com/iovisor/bcc/blob/13b5563c11f7722a61a17c6ca0a1a387d2fa7788/libbpf-tools/trace_helpers.c#L895-L932
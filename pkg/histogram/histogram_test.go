@@ -230,3 +230,64 @@ func TestHistogram_String(t *testing.T) {
 		})
 	}
 }
+
+func TestHistogram_PercentileFromExp2Slots(t *testing.T) {
+	t.Parallel()
+
+	// 2 values in [0, 2), 4 in [4, 8) and 4 in [8, 16)
+	slots := []uint32{2, 0, 4, 4}
+
+	testTable := []struct {
+		description string
+		slots       []uint32
+		percentile  float64
+		expected    float64
+	}{
+		{
+			description: "Nil slots",
+			slots:       nil,
+			percentile:  99,
+			expected:    0,
+		},
+		{
+			description: "Empty slots",
+			slots:       []uint32{0, 0, 0},
+			percentile:  50,
+			expected:    0,
+		},
+		{
+			description: "First slot",
+			slots:       slots,
+			percentile:  10,
+			expected:    1,
+		},
+		{
+			description: "Skips empty slots",
+			slots:       slots,
+			percentile:  50,
+			expected:    7,
+		},
+		{
+			description: "Last slot",
+			slots:       slots,
+			percentile:  90,
+			expected:    14,
+		},
+		{
+			description: "Maximum",
+			slots:       slots,
+			percentile:  100,
+			expected:    16,
+		},
+	}
+
+	for _, test := range testTable {
+		test := test
+		t.Run(test.description, func(t *testing.T) {
+			t.Parallel()
+
+			actual := PercentileFromExp2Slots(test.slots, test.percentile)
+			require.Equal(t, test.expected, actual, "percentile")
+		})
+	}
+}
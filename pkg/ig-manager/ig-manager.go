@@ -94,6 +94,7 @@ func NewManager(runtimes []*containerutilsTypes.RuntimeConfig) (*IGManager, erro
 
 	opts := []containercollection.ContainerCollectionOption{
 		containercollection.WithPubSub(l.containersMap.ContainersMapUpdater()),
+		containercollection.WithHost(),
 		containercollection.WithOCIConfigEnrichment(),
 		containercollection.WithCgroupEnrichment(),
 		containercollection.WithLinuxNamespaceEnrichment(),
@@ -126,7 +127,7 @@ func (l *IGManager) Close() {
 }
 
 func isDefaultContainerRuntimeConfig(runtimes []*containerutilsTypes.RuntimeConfig) bool {
-	if len(runtimes) != len(containerutils.AvailableRuntimes) {
+	if len(runtimes) != len(containerutils.DefaultRuntimes) {
 		return false
 	}
 
@@ -24,6 +24,7 @@ import (
 
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	containerutilsTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/container-utils/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
 
 	// Yes, not the better naming for these two.
 	utilstest "github.com/inspektor-gadget/inspektor-gadget/internal/test"
@@ -89,6 +90,35 @@ func TestMountNsMap(t *testing.T) {
 	}
 }
 
+func TestHostContainer(t *testing.T) {
+	utilstest.RequireRoot(t)
+
+	igManager, err := NewManager(nil)
+	if err != nil {
+		t.Fatalf("Failed to start ig manager: %s", err)
+	}
+	defer igManager.Close()
+
+	if containers := igManager.GetContainersBySelector(&containercollection.ContainerSelector{}); len(containers) != 0 {
+		t.Fatalf("host pseudo-container selected without asking for it: %+v", containers[0])
+	}
+
+	containers := igManager.GetContainersBySelector(&containercollection.ContainerSelector{Host: true})
+	if len(containers) != 1 {
+		t.Fatalf("host pseudo-container not found")
+	}
+	host := containers[0]
+	if host.Runtime.RuntimeName != types.RuntimeNameHost || host.Runtime.ContainerName != containercollection.HostContainerName {
+		t.Fatalf("unexpected host pseudo-container: %+v", host)
+	}
+	if host.Mntns == 0 || host.Netns == 0 {
+		t.Fatalf("host pseudo-container not enriched with its namespaces: %+v", host)
+	}
+	if c := igManager.LookupContainerByMntns(host.Mntns); c != host {
+		t.Fatalf("host pseudo-container not found by its mount namespace")
+	}
+}
+
 func currentFdList(t *testing.T) (ret string) {
 	files, err := os.ReadDir("/proc/self/fd")
 	if err != nil {
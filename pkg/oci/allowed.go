@@ -0,0 +1,211 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/distribution/reference"
+	"oras.land/oras-go/v2"
+)
+
+// ErrImageNotAllowed is returned when running an image that isn't in the list
+// of allowed images
+var ErrImageNotAllowed = errors.New("image not allowed")
+
+// AllowedImage describes gadget images that are allowed to run
+type AllowedImage struct {
+	// Repository is the repository of the image, e.g.
+	// "ghcr.io/inspektor-gadget/gadget/trace_open". It can contain shell
+	// patterns like "ghcr.io/inspektor-gadget/gadget/*".
+	Repository string
+	// Digests restricts the images of Repository to the ones with these
+	// digests. All of them are allowed if empty.
+	Digests []string
+}
+
+var allowedImages atomic.Pointer[[]AllowedImage]
+
+var (
+	loadedMu sync.Mutex
+	// loaded is closed once the allowed images are known, see
+	// WaitForAllowedImages
+	loaded = closedChan()
+)
+
+// allowedImagesTimeout is how long running an image waits for the allowed
+// images to be loaded before it's denied
+const allowedImagesTimeout = 30 * time.Second
+
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
+// WaitForAllowedImages denies all the images until SetAllowedImages is called.
+// It's used when the allowed images are loaded asynchronously, e.g. from the
+// gadget catalogs, so that no image runs before the restrictions are known.
+func WaitForAllowedImages() {
+	loadedMu.Lock()
+	defer loadedMu.Unlock()
+
+	select {
+	case <-loaded:
+		loaded = make(chan struct{})
+	default:
+	}
+}
+
+// waitForAllowedImages waits until the allowed images are loaded
+func waitForAllowedImages(ctx context.Context) error {
+	loadedMu.Lock()
+	c := loaded
+	loadedMu.Unlock()
+
+	select {
+	case <-c:
+		return nil
+	default:
+	}
+
+	timer := time.NewTimer(allowedImagesTimeout)
+	defer timer.Stop()
+	select {
+	case <-c:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%w: the allowed images haven't been loaded yet", ErrImageNotAllowed)
+	case <-ctx.Done():
+		return fmt.Errorf("%w: waiting for the allowed images: %w", ErrImageNotAllowed, ctx.Err())
+	}
+}
+
+// SetAllowedImages restricts the gadget images that can be run to the given
+// ones. Passing nil removes any restriction.
+func SetAllowedImages(images []AllowedImage) {
+	defer func() {
+		loadedMu.Lock()
+		defer loadedMu.Unlock()
+		select {
+		case <-loaded:
+		default:
+			close(loaded)
+		}
+	}()
+
+	if images == nil {
+		allowedImages.Store(nil)
+		return
+	}
+
+	normalized := make([]AllowedImage, 0, len(images))
+	for _, image := range images {
+		// Patterns can't be normalized, they have to be written in full
+		if !strings.ContainsAny(image.Repository, "*?[") {
+			if named, err := reference.ParseNormalizedNamed(image.Repository); err == nil {
+				image.Repository = named.Name()
+			}
+		}
+		normalized = append(normalized, image)
+	}
+	allowedImages.Store(&normalized)
+}
+
+// checkImageAllowed returns an error wrapping ErrImageNotAllowed if the image
+// with the given digest can't be run according to the allowed images.
+func checkImageAllowed(image string, digest string) error {
+	allowed := allowedImages.Load()
+	if allowed == nil {
+		return nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("parsing normalized image %q: %w", image, err)
+	}
+	repository := named.Name()
+
+	for _, a := range *allowed {
+		if matched, _ := path.Match(a.Repository, repository); !matched {
+			continue
+		}
+		if len(a.Digests) == 0 || slices.Contains(a.Digests, digest) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s@%s", ErrImageNotAllowed, repository, digest)
+}
+
+// checkReferenceAllowed checks, before pulling it, whether the image can be
+// allowed to run: its repository has to be allowed and, if the reference
+// contains a digest, the digest too. The digest of images referenced by tag is
+// only known once pulled, see checkImageAllowedInStore.
+func checkReferenceAllowed(ctx context.Context, image string) error {
+	if err := waitForAllowedImages(ctx); err != nil {
+		return err
+	}
+	allowed := allowedImages.Load()
+	if allowed == nil {
+		return nil
+	}
+
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("parsing normalized image %q: %w", image, err)
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		return checkImageAllowed(image, digested.Digest().String())
+	}
+
+	repository := named.Name()
+	for _, a := range *allowed {
+		if matched, _ := path.Match(a.Repository, repository); matched {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrImageNotAllowed, repository)
+}
+
+// checkImageAllowedInStore checks whether the image, already present in
+// imageStore, is allowed to run.
+func checkImageAllowedInStore(ctx context.Context, imageStore oras.ReadOnlyTarget, image string) error {
+	if err := waitForAllowedImages(ctx); err != nil {
+		return err
+	}
+	if allowedImages.Load() == nil {
+		return nil
+	}
+
+	targetImage, err := normalizeImageName(image)
+	if err != nil {
+		return fmt.Errorf("normalizing image: %w", err)
+	}
+	desc, err := imageStore.Resolve(ctx, targetImage.String())
+	if err != nil {
+		return fmt.Errorf("resolving image %q: %w", targetImage.String(), err)
+	}
+
+	return checkImageAllowed(image, desc.Digest.String())
+}
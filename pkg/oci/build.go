@@ -41,6 +41,7 @@ const (
 	eBPFObjectMediaType = "application/vnd.gadget.ebpf.program.v1+binary"
 	wasmObjectMediaType = "application/vnd.gadget.wasm.program.v1+binary"
 	metadataMediaType   = "application/vnd.gadget.config.v1+yaml"
+	btfgenMediaType     = "application/vnd.gadget.btfgen.v1+binary"
 )
 
 type BuildGadgetImageOpts struct {
@@ -53,6 +54,9 @@ type BuildGadgetImageOpts struct {
 	MetadataPath string
 	// Optional path to the Wasm file
 	WasmObjectPath string
+	// Optional list of archives with btfgen-generated BTFs to include in the image. The key is
+	// the architecture and the value is the path to the gzip-compressed tarball.
+	BTFGenPaths map[string]string
 	// If true, the metadata is updated to follow changes in the eBPF objects.
 	UpdateMetadata bool
 	// If true, the metadata is validated before creating the image.
@@ -143,6 +147,22 @@ func createEbpfProgramDesc(ctx context.Context, target oras.Target, progFilePath
 	return progDesc, nil
 }
 
+func createBTFGenDesc(ctx context.Context, target oras.Target, btfgenFilePath string) (ocispec.Descriptor, error) {
+	btfgenBytes, err := os.ReadFile(btfgenFilePath)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("reading btfgen file: %w", err)
+	}
+	btfgenDesc := content.NewDescriptorFromBytes(btfgenMediaType, btfgenBytes)
+	btfgenDesc.Annotations = map[string]string{
+		ocispec.AnnotationTitle: "btfs.tar.gz",
+	}
+	err = pushDescriptorIfNotExists(ctx, target, btfgenDesc, bytes.NewReader(btfgenBytes))
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("pushing btfgen file: %w", err)
+	}
+	return btfgenDesc, nil
+}
+
 func createMetadataDesc(ctx context.Context, target oras.Target, metadataFilePath string) (ocispec.Descriptor, error) {
 	metadataBytes, err := os.ReadFile(metadataFilePath)
 	if err != nil {
@@ -169,12 +189,22 @@ func createEmptyDesc(ctx context.Context, target oras.Target) (ocispec.Descripto
 	return emptyDesc, nil
 }
 
-func createManifestForTarget(ctx context.Context, target oras.Target, metadataFilePath, progFilePath, arch string) (ocispec.Descriptor, error) {
+func createManifestForTarget(ctx context.Context, target oras.Target, metadataFilePath, progFilePath, btfgenFilePath, arch string) (ocispec.Descriptor, error) {
 	progDesc, err := createEbpfProgramDesc(ctx, target, progFilePath, arch)
 	if err != nil {
 		return ocispec.Descriptor{}, fmt.Errorf("creating and pushing eBPF descriptor: %w", err)
 	}
 
+	layers := []ocispec.Descriptor{progDesc}
+
+	if btfgenFilePath != "" {
+		btfgenDesc, err := createBTFGenDesc(ctx, target, btfgenFilePath)
+		if err != nil {
+			return ocispec.Descriptor{}, fmt.Errorf("creating and pushing btfgen descriptor: %w", err)
+		}
+		layers = append(layers, btfgenDesc)
+	}
+
 	var defDesc ocispec.Descriptor
 
 	if _, err := os.Stat(metadataFilePath); err == nil {
@@ -197,7 +227,7 @@ func createManifestForTarget(ctx context.Context, target oras.Target, metadataFi
 			SchemaVersion: 2, // historical value. does not pertain to OCI or docker version
 		},
 		Config: defDesc,
-		Layers: []ocispec.Descriptor{progDesc},
+		Layers: layers,
 	}
 	manifestJson, err := json.Marshal(manifest)
 	if err != nil {
@@ -229,14 +259,14 @@ func createImageIndex(ctx context.Context, target oras.Target, o *BuildGadgetIma
 	layers := []ocispec.Descriptor{}
 
 	for arch, path := range o.EBPFObjectPaths {
-		manifestDesc, err := createManifestForTarget(ctx, target, o.MetadataPath, path, arch)
+		manifestDesc, err := createManifestForTarget(ctx, target, o.MetadataPath, path, o.BTFGenPaths[arch], arch)
 		if err != nil {
 			return ocispec.Descriptor{}, fmt.Errorf("creating %s manifest: %w", arch, err)
 		}
 		layers = append(layers, manifestDesc)
 	}
 	if o.WasmObjectPath != "" {
-		manifestDesc, err := createManifestForTarget(ctx, target, o.MetadataPath, o.WasmObjectPath, ArchWasm)
+		manifestDesc, err := createManifestForTarget(ctx, target, o.MetadataPath, o.WasmObjectPath, "", ArchWasm)
 		if err != nil {
 			return ocispec.Descriptor{}, fmt.Errorf("creating %s manifest: %w", ArchWasm, err)
 		}
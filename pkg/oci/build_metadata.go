@@ -62,15 +62,9 @@ func getAnySpec(opts *BuildGadgetImageOpts) (*ebpf.CollectionSpec, error) {
 }
 
 func validateMetadataFile(ctx context.Context, opts *BuildGadgetImageOpts) error {
-	metadataFile, err := os.Open(opts.MetadataPath)
+	metadata, err := os.ReadFile(opts.MetadataPath)
 	if err != nil {
-		return fmt.Errorf("opening metadata file: %w", err)
-	}
-	defer metadataFile.Close()
-
-	metadata := &types.GadgetMetadata{}
-	if err := yaml.NewDecoder(metadataFile).Decode(metadata); err != nil {
-		return fmt.Errorf("decoding metadata file: %w", err)
+		return fmt.Errorf("reading metadata file: %w", err)
 	}
 
 	spec, err := getAnySpec(opts)
@@ -78,7 +72,12 @@ func validateMetadataFile(ctx context.Context, opts *BuildGadgetImageOpts) error
 		return fmt.Errorf("loading spec: %w", err)
 	}
 
-	return metadata.Validate(spec)
+	m, err := types.ParseMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	return m.Validate(spec)
 }
 
 func createOrUpdateMetadataFile(ctx context.Context, opts *BuildGadgetImageOpts) error {
@@ -94,13 +93,16 @@ func createOrUpdateMetadataFile(ctx context.Context, opts *BuildGadgetImageOpts)
 
 	if update {
 		// load metadata file
-		metadataFile, err := os.Open(opts.MetadataPath)
+		metadataBytes, err := os.ReadFile(opts.MetadataPath)
 		if err != nil {
-			return fmt.Errorf("opening metadata file: %w", err)
+			return fmt.Errorf("reading metadata file: %w", err)
+		}
+
+		if err := types.CheckMetadataAPIVersion(metadataBytes); err != nil {
+			return err
 		}
-		defer metadataFile.Close()
 
-		if err := yaml.NewDecoder(metadataFile).Decode(metadata); err != nil {
+		if err := yaml.Unmarshal(metadataBytes, metadata); err != nil {
 			return fmt.Errorf("decoding metadata file: %w", err)
 		}
 
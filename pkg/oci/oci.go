@@ -35,6 +35,8 @@ import (
 	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/registry/remote"
 	oras_auth "oras.land/oras-go/v2/registry/remote/auth"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
 )
 
 type AuthOptions struct {
@@ -56,6 +58,10 @@ const (
 type GadgetImage struct {
 	EbpfObject []byte
 	Metadata   []byte
+	// BTFGen is an optional gzip-compressed tarball with btfgen-generated BTFs
+	BTFGen []byte
+	// Digest is the digest of the image index
+	Digest string
 }
 
 // GadgetImageDesc is the description of a gadget image.
@@ -87,6 +93,11 @@ func GetGadgetImage(ctx context.Context, image string, authOpts *AuthOptions, pu
 		return nil, fmt.Errorf("getting local oci store: %w", err)
 	}
 
+	// Don't pull images that can't run anyway
+	if err := checkReferenceAllowed(ctx, image); err != nil {
+		return nil, err
+	}
+
 	switch pullPolicy {
 	case PullImageAlways:
 		_, err := pullGadgetImageToStore(ctx, imageStore, image, authOpts)
@@ -106,6 +117,25 @@ func GetGadgetImage(ctx context.Context, image string, authOpts *AuthOptions, pu
 		if _, err := imageStore.Resolve(ctx, targetImage.String()); err != nil {
 			return nil, fmt.Errorf("resolving image %q on local registry: %w", targetImage.String(), err)
 		}
+	default:
+		return nil, fmt.Errorf("unsupported pull policy %q", pullPolicy)
+	}
+
+	if err := checkImageAllowedInStore(ctx, imageStore, image); err != nil {
+		return nil, err
+	}
+
+	imageRef, err := normalizeImageName(image)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing image: %w", err)
+	}
+	desc, err := imageStore.Resolve(ctx, imageRef.String())
+	if err != nil {
+		return nil, fmt.Errorf("resolving image %q: %w", imageRef.String(), err)
+	}
+
+	if err := checkImagePolicy(ctx, imageStore, image, desc.Digest.String(), authOpts, pullPolicy); err != nil {
+		return nil, err
 	}
 
 	manifest, err := getImageManifestForArch(ctx, imageStore, image, authOpts)
@@ -123,12 +153,32 @@ func GetGadgetImage(ctx context.Context, image string, authOpts *AuthOptions, pu
 		return nil, fmt.Errorf("getting metadata: %w", err)
 	}
 
+	btfgen, err := getBTFGenFromManifest(ctx, imageStore, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("getting btfgen: %w", err)
+	}
+
 	return &GadgetImage{
 		EbpfObject: prog,
 		Metadata:   metadata,
+		BTFGen:     btfgen,
+		Digest:     desc.Digest.String(),
 	}, nil
 }
 
+// ValidateGadgetImage checks the metadata of the local gadget image is valid and matches its
+// eBPF object for the host architecture
+func ValidateGadgetImage(ctx context.Context, image string) error {
+	gadget, err := GetGadgetImage(ctx, image, &AuthOptions{}, PullImageNever)
+	if err != nil {
+		return fmt.Errorf("getting gadget image: %w", err)
+	}
+	if len(gadget.Metadata) == 0 {
+		return fmt.Errorf("image %q doesn't have metadata", image)
+	}
+	return types.ValidateMetadata(gadget.Metadata, gadget.EbpfObject)
+}
+
 // PullGadgetImage pulls the gadget image into the local oci store and returns its descriptor.
 func PullGadgetImage(ctx context.Context, image string, authOpts *AuthOptions) (*GadgetImageDesc, error) {
 	ociStore, err := getLocalOciStore()
@@ -477,11 +527,23 @@ func getMetadataFromManifest(ctx context.Context, target oras.Target, manifest *
 	return metadata, nil
 }
 
+// getLayersByMediaType returns the layers of the manifest having the given media type.
+func getLayersByMediaType(manifest *ocispec.Manifest, mediaType string) []ocispec.Descriptor {
+	layers := []ocispec.Descriptor{}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == mediaType {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
 func getEbpfProgramFromManifest(ctx context.Context, target oras.Target, manifest *ocispec.Manifest) ([]byte, error) {
-	if len(manifest.Layers) != 1 {
-		return nil, fmt.Errorf("expected exactly one layer, got %d", len(manifest.Layers))
+	layers := getLayersByMediaType(manifest, eBPFObjectMediaType)
+	if len(layers) != 1 {
+		return nil, fmt.Errorf("expected exactly one eBPF program layer, got %d", len(layers))
 	}
-	prog, err := getContentFromDescriptor(ctx, target, manifest.Layers[0])
+	prog, err := getContentFromDescriptor(ctx, target, layers[0])
 	if err != nil {
 		return nil, fmt.Errorf("getting ebpf program from descriptor: %w", err)
 	}
@@ -491,6 +553,24 @@ func getEbpfProgramFromManifest(ctx context.Context, target oras.Target, manifes
 	return prog, nil
 }
 
+func getBTFGenFromManifest(ctx context.Context, target oras.Target, manifest *ocispec.Manifest) ([]byte, error) {
+	// btfgen is optional
+	layers := getLayersByMediaType(manifest, btfgenMediaType)
+	switch len(layers) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("expected at most one btfgen layer, got %d", len(layers))
+	}
+
+	btfgen, err := getContentFromDescriptor(ctx, target, layers[0])
+	if err != nil {
+		return nil, fmt.Errorf("getting btfgen from descriptor: %w", err)
+	}
+	return btfgen, nil
+}
+
 func getContentFromDescriptor(ctx context.Context, imageStore oras.ReadOnlyTarget, desc ocispec.Descriptor) ([]byte, error) {
 	reader, err := imageStore.Fetch(ctx, desc)
 	if err != nil {
@@ -15,7 +15,9 @@
 package oci
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -279,3 +281,134 @@ func TestGetHostString(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckImageAllowed(t *testing.T) {
+	// Not parallel: the allowed images are global
+	t.Cleanup(func() { SetAllowedImages(nil) })
+
+	const (
+		digest1 = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		digest2 = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+
+	type testDefinition struct {
+		allowed []AllowedImage
+		image   string
+		digest  string
+		err     bool
+	}
+
+	tests := map[string]testDefinition{
+		"no_restrictions": {
+			allowed: nil,
+			image:   "ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+			digest:  digest1,
+		},
+		"empty_catalog": {
+			allowed: []AllowedImage{},
+			image:   "ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+			digest:  digest1,
+			err:     true,
+		},
+		"repository": {
+			allowed: []AllowedImage{{Repository: "ghcr.io/inspektor-gadget/gadget/trace_open"}},
+			image:   "ghcr.io/inspektor-gadget/gadget/trace_open:v0.25.0",
+			digest:  digest1,
+		},
+		"other_repository": {
+			allowed: []AllowedImage{{Repository: "ghcr.io/inspektor-gadget/gadget/trace_open"}},
+			image:   "ghcr.io/inspektor-gadget/gadget/trace_exec:latest",
+			digest:  digest1,
+			err:     true,
+		},
+		"pattern": {
+			allowed: []AllowedImage{{Repository: "ghcr.io/inspektor-gadget/gadget/*"}},
+			image:   "ghcr.io/inspektor-gadget/gadget/trace_exec:latest",
+			digest:  digest1,
+		},
+		"pattern_other_registry": {
+			allowed: []AllowedImage{{Repository: "ghcr.io/inspektor-gadget/gadget/*"}},
+			image:   "example.com/inspektor-gadget/gadget/trace_exec:latest",
+			digest:  digest1,
+			err:     true,
+		},
+		"normalized": {
+			allowed: []AllowedImage{{Repository: "mygadget"}},
+			image:   "docker.io/library/mygadget:latest",
+			digest:  digest1,
+		},
+		"digest": {
+			allowed: []AllowedImage{{Repository: "ghcr.io/inspektor-gadget/gadget/trace_open", Digests: []string{digest1}}},
+			image:   "ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+			digest:  digest1,
+		},
+		"other_digest": {
+			allowed: []AllowedImage{{Repository: "ghcr.io/inspektor-gadget/gadget/trace_open", Digests: []string{digest1}}},
+			image:   "ghcr.io/inspektor-gadget/gadget/trace_open:latest",
+			digest:  digest2,
+			err:     true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			SetAllowedImages(test.allowed)
+
+			err := checkImageAllowed(test.image, test.digest)
+			if test.err {
+				require.ErrorIs(t, err, ErrImageNotAllowed)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckReferenceAllowed(t *testing.T) {
+	// Not parallel: the allowed images are global
+	t.Cleanup(func() { SetAllowedImages(nil) })
+
+	const (
+		digest1 = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		digest2 = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+
+	SetAllowedImages([]AllowedImage{
+		{Repository: "ghcr.io/inspektor-gadget/gadget/trace_open", Digests: []string{digest1}},
+	})
+	ctx := context.Background()
+
+	// The digest of images referenced by tag is checked after pulling them
+	require.NoError(t, checkReferenceAllowed(ctx, "ghcr.io/inspektor-gadget/gadget/trace_open:latest"))
+	require.NoError(t, checkReferenceAllowed(ctx, "ghcr.io/inspektor-gadget/gadget/trace_open@"+digest1))
+	require.ErrorIs(t, checkReferenceAllowed(ctx, "ghcr.io/inspektor-gadget/gadget/trace_open@"+digest2), ErrImageNotAllowed)
+	require.ErrorIs(t, checkReferenceAllowed(ctx, "ghcr.io/inspektor-gadget/gadget/trace_exec:latest"), ErrImageNotAllowed)
+}
+
+func TestWaitForAllowedImages(t *testing.T) {
+	// Not parallel: the allowed images are global
+	t.Cleanup(func() { SetAllowedImages(nil) })
+
+	const image = "ghcr.io/inspektor-gadget/gadget/trace_open:latest"
+
+	// Images are denied until the allowed images are loaded
+	WaitForAllowedImages()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, checkReferenceAllowed(ctx, image), ErrImageNotAllowed)
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- checkReferenceAllowed(context.Background(), image)
+	}()
+	SetAllowedImages(nil)
+	require.NoError(t, <-errCh)
+
+	// Loading them again doesn't block
+	WaitForAllowedImages()
+	SetAllowedImages(nil)
+	SetAllowedImages(nil)
+	require.NoError(t, checkReferenceAllowed(context.Background(), image))
+}
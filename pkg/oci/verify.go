@@ -0,0 +1,247 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/errdef"
+)
+
+const (
+	// cosignSignatureAnnotation is the annotation of the layers of a cosign
+	// signature containing the base64 encoded signature of the layer
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	cosignSignatureSuffix     = ".sig"
+)
+
+// ImagePolicy restricts the gadget images that can run on this node. It's
+// configured when Inspektor Gadget starts, unlike the allowed images that come
+// from the gadget catalogs of the cluster. Built-in gadgets aren't affected.
+type ImagePolicy struct {
+	// AllowedDigests are the digests of the images allowed to run, whatever
+	// their signatures
+	AllowedDigests []string
+	// PublicKeys verify the cosign signatures of the images. Images signed by
+	// any of them are allowed to run.
+	PublicKeys []crypto.PublicKey
+}
+
+var imagePolicy atomic.Pointer[ImagePolicy]
+
+// SetImagePolicy restricts the images that can run to the ones allowed by
+// policy. Passing nil removes any restriction.
+func SetImagePolicy(policy *ImagePolicy) {
+	imagePolicy.Store(policy)
+}
+
+// NewImagePolicy returns a policy allowing the images with the given digests
+// or signed by the PEM encoded public keys in keys, or nil if both are empty
+func NewImagePolicy(digests []string, keys []byte) (*ImagePolicy, error) {
+	policy := &ImagePolicy{}
+	for _, d := range digests {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		if !isSHA256Digest(d) {
+			return nil, fmt.Errorf("invalid digest %q, expected sha256:<hex>", d)
+		}
+		policy.AllowedDigests = append(policy.AllowedDigests, d)
+	}
+
+	publicKeys, err := ParsePublicKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+	policy.PublicKeys = publicKeys
+
+	if len(policy.AllowedDigests) == 0 && len(policy.PublicKeys) == 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+func isSHA256Digest(d string) bool {
+	encoded, ok := strings.CutPrefix(d, "sha256:")
+	if !ok || len(encoded) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil && strings.ToLower(encoded) == encoded
+}
+
+// ParsePublicKeys parses the PEM encoded public keys in data, like the ones
+// generated by "cosign generate-key-pair"
+func ParsePublicKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unexpected PEM block %q, expected PUBLIC KEY", block.Type)
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing public key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if len(strings.TrimSpace(string(data))) != 0 {
+		return nil, errors.New("parsing public keys: invalid PEM data")
+	}
+	return keys, nil
+}
+
+// verifySignature verifies the signature of payload by key, with the
+// algorithms used by cosign
+func verifySignature(key crypto.PublicKey, payload, signature []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, hash[:], signature)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, payload, signature)
+	default:
+		return false
+	}
+}
+
+// simpleSigning is the payload signed by cosign, see
+// https://github.com/containers/image/blob/main/docs/containers-signature.5.md
+type simpleSigning struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// signatureTag returns the tag where cosign stores the signatures of the
+// image with the given digest, e.g. sha256-<hex>.sig
+func signatureTag(d string) string {
+	return strings.Replace(d, ":", "-", 1) + cosignSignatureSuffix
+}
+
+// verifySignatureManifest returns whether one of the layers of the cosign
+// signature manifest is a signature of imageDigest by one of keys
+func verifySignatureManifest(ctx context.Context, store oras.ReadOnlyTarget, manifest *ocispec.Manifest, imageDigest string, keys []crypto.PublicKey) bool {
+	for _, layer := range manifest.Layers {
+		encoded, ok := layer.Annotations[cosignSignatureAnnotation]
+		if !ok {
+			continue
+		}
+		signature, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			continue
+		}
+		payload, err := getContentFromDescriptor(ctx, store, layer)
+		if err != nil {
+			continue
+		}
+		var s simpleSigning
+		if err := json.Unmarshal(payload, &s); err != nil || s.Critical.Image.DockerManifestDigest != imageDigest {
+			continue
+		}
+		for _, key := range keys {
+			if verifySignature(key, payload, signature) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkImageSigned checks whether the image with the given digest, already
+// present in imageStore, is signed by one of keys. The signature is pulled
+// into imageStore like the image, according to pullPolicy.
+func checkImageSigned(ctx context.Context, imageStore oras.Target, image, imageDigest string, keys []crypto.PublicKey, authOpts *AuthOptions, pullPolicy string) error {
+	named, err := normalizeImageName(image)
+	if err != nil {
+		return fmt.Errorf("normalizing image: %w", err)
+	}
+	sigRef := named.Name() + ":" + signatureTag(imageDigest)
+
+	_, err = imageStore.Resolve(ctx, sigRef)
+	if pullPolicy == PullImageAlways || (errors.Is(err, errdef.ErrNotFound) && pullPolicy == PullImageMissing) {
+		repo, rerr := NewRepository(image, authOpts)
+		if rerr != nil {
+			return fmt.Errorf("creating remote repository: %w", rerr)
+		}
+		_, err = oras.Copy(ctx, repo, sigRef, imageStore, sigRef, oras.DefaultCopyOptions)
+	}
+	if errors.Is(err, errdef.ErrNotFound) {
+		return fmt.Errorf("%w: %s@%s isn't signed", ErrImageNotAllowed, named.Name(), imageDigest)
+	}
+	if err != nil {
+		return fmt.Errorf("getting signature of %s@%s: %w", named.Name(), imageDigest, err)
+	}
+
+	desc, err := imageStore.Resolve(ctx, sigRef)
+	if err != nil {
+		return fmt.Errorf("resolving signature %q: %w", sigRef, err)
+	}
+	content, err := getContentFromDescriptor(ctx, imageStore, desc)
+	if err != nil {
+		return fmt.Errorf("getting signature %q: %w", sigRef, err)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return fmt.Errorf("decoding signature manifest %q: %w", sigRef, err)
+	}
+
+	if !verifySignatureManifest(ctx, imageStore, &manifest, imageDigest, keys) {
+		return fmt.Errorf("%w: %s@%s isn't signed by a trusted key", ErrImageNotAllowed, named.Name(), imageDigest)
+	}
+	return nil
+}
+
+// checkImagePolicy checks whether the image with the given digest, already
+// present in imageStore, is allowed to run by the image policy
+func checkImagePolicy(ctx context.Context, imageStore oras.Target, image, imageDigest string, authOpts *AuthOptions, pullPolicy string) error {
+	policy := imagePolicy.Load()
+	if policy == nil {
+		return nil
+	}
+	if slices.Contains(policy.AllowedDigests, imageDigest) {
+		return nil
+	}
+	if len(policy.PublicKeys) == 0 {
+		return fmt.Errorf("%w: %s isn't in the allowed digests", ErrImageNotAllowed, imageDigest)
+	}
+	return checkImageSigned(ctx, imageStore, image, imageDigest, policy.PublicKeys, authOpts, pullPolicy)
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+const (
+	testImage       = "ghcr.io/inspektor-gadget/gadget/trace_open:latest"
+	testImageDigest = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	otherDigest     = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+)
+
+func newKey(t *testing.T) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// pushSignature stores in store a cosign signature of signedDigest by key, as
+// the signature of testImage with digest testImageDigest
+func pushSignature(t *testing.T, store *memory.Store, key *ecdsa.PrivateKey, signedDigest string) {
+	ctx := context.Background()
+
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"ghcr.io/inspektor-gadget/gadget/trace_open"},`+
+		`"image":{"docker-manifest-digest":%q},"type":"cosign container image signature"},"optional":null}`, signedDigest))
+	hash := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	layer := content.NewDescriptorFromBytes("application/vnd.dev.cosign.simplesigning.v1+json", payload)
+	layer.Annotations = map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(signature)}
+	require.NoError(t, store.Push(ctx, layer, bytes.NewReader(payload)))
+
+	config := []byte("{}")
+	configDesc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageConfig, config)
+	require.NoError(t, store.Push(ctx, configDesc, bytes.NewReader(config)))
+
+	manifest := ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layer},
+	}
+	manifest.SchemaVersion = 2
+	manifestBytes, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	manifestDesc := content.NewDescriptorFromBytes(ocispec.MediaTypeImageManifest, manifestBytes)
+	require.NoError(t, store.Push(ctx, manifestDesc, bytes.NewReader(manifestBytes)))
+
+	require.NoError(t, store.Tag(ctx, manifestDesc, "ghcr.io/inspektor-gadget/gadget/trace_open:"+signatureTag(testImageDigest)))
+}
+
+func TestNewImagePolicy(t *testing.T) {
+	t.Parallel()
+
+	_, pubKey := newKey(t)
+
+	policy, err := NewImagePolicy(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, policy)
+
+	policy, err = NewImagePolicy([]string{testImageDigest, " "}, append(pubKey, pubKey...))
+	require.NoError(t, err)
+	require.Equal(t, []string{testImageDigest}, policy.AllowedDigests)
+	require.Len(t, policy.PublicKeys, 2)
+
+	_, err = NewImagePolicy([]string{"sha256:1234"}, nil)
+	require.ErrorContains(t, err, "invalid digest")
+
+	_, err = NewImagePolicy(nil, []byte("not a key"))
+	require.ErrorContains(t, err, "invalid PEM data")
+}
+
+func TestCheckImageSigned(t *testing.T) {
+	t.Parallel()
+
+	key, pubKey := newKey(t)
+	_, otherPubKey := newKey(t)
+	trusted, err := ParsePublicKeys(pubKey)
+	require.NoError(t, err)
+	untrusted, err := ParsePublicKeys(otherPubKey)
+	require.NoError(t, err)
+
+	type testDefinition struct {
+		signedDigest string
+		keys         []crypto.PublicKey
+		unsigned     bool
+		expectedErr  string
+	}
+
+	tests := map[string]testDefinition{
+		"signed": {
+			signedDigest: testImageDigest,
+			keys:         trusted,
+		},
+		"untrusted_key": {
+			signedDigest: testImageDigest,
+			keys:         untrusted,
+			expectedErr:  "isn't signed by a trusted key",
+		},
+		"other_digest": {
+			signedDigest: otherDigest,
+			keys:         trusted,
+			expectedErr:  "isn't signed by a trusted key",
+		},
+		"unsigned": {
+			unsigned:    true,
+			keys:        trusted,
+			expectedErr: "isn't signed",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			store := memory.New()
+			if !test.unsigned {
+				pushSignature(t, store, key, test.signedDigest)
+			}
+
+			err := checkImageSigned(context.Background(), store, testImage, testImageDigest, test.keys, &AuthOptions{}, PullImageNever)
+			if test.expectedErr != "" {
+				require.ErrorIs(t, err, ErrImageNotAllowed)
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCheckImagePolicy(t *testing.T) {
+	// Not parallel: the image policy is global
+	t.Cleanup(func() { SetImagePolicy(nil) })
+
+	ctx := context.Background()
+	store := memory.New()
+
+	require.NoError(t, checkImagePolicy(ctx, store, testImage, testImageDigest, &AuthOptions{}, PullImageNever))
+
+	SetImagePolicy(&ImagePolicy{AllowedDigests: []string{testImageDigest}})
+	require.NoError(t, checkImagePolicy(ctx, store, testImage, testImageDigest, &AuthOptions{}, PullImageNever))
+	err := checkImagePolicy(ctx, store, testImage, otherDigest, &AuthOptions{}, PullImageNever)
+	require.ErrorIs(t, err, ErrImageNotAllowed)
+}
@@ -36,6 +36,8 @@ type K8sInventoryCache struct {
 
 	pods atomic.Pointer[v1.PodList]
 	svcs atomic.Pointer[v1.ServiceList]
+	// podsByKey indexes pods by "namespace/name"
+	podsByKey atomic.Pointer[map[string]*v1.Pod]
 
 	exit           chan struct{}
 	ticker         *time.Ticker
@@ -123,7 +125,13 @@ func (cache *K8sInventoryCache) update() {
 		log.Errorf("listing pods: %v", err)
 		return
 	}
+	podsByKey := make(map[string]*v1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		podsByKey[pod.Namespace+"/"+pod.Name] = pod
+	}
 	cache.pods.Store(pods)
+	cache.podsByKey.Store(&podsByKey)
 
 	svcs, err := cache.clientset.CoreV1().Services("").List(context.TODO(), metav1.ListOptions{})
 	if err != nil {
@@ -137,6 +145,16 @@ func (cache *K8sInventoryCache) GetPods() *v1.PodList {
 	return cache.pods.Load()
 }
 
+// GetPodByName returns the pod with the given namespace and name, or nil if
+// it's not in the cache
+func (cache *K8sInventoryCache) GetPodByName(namespace, name string) *v1.Pod {
+	podsByKey := cache.podsByKey.Load()
+	if podsByKey == nil {
+		return nil
+	}
+	return (*podsByKey)[namespace+"/"+name]
+}
+
 func (cache *K8sInventoryCache) GetSvcs() *v1.ServiceList {
 	return cache.svcs.Load()
 }
@@ -0,0 +1,313 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kubeevents provides an operator that converts gadget events into
+// Kubernetes Events attached to the pod that generated them, so they can be
+// picked up by existing event-based alerting.
+package kubeevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/time/rate"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/common"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators/kubemanager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+const (
+	OperatorName = "KubeEvents"
+
+	ParamEmitEvents = "emit-k8s-events"
+	ParamEventType  = "k8s-event-type"
+	ParamReason     = "k8s-event-reason"
+	ParamSelector   = "k8s-event-selector"
+	ParamMaxRate    = "k8s-event-max-rate"
+
+	// component is used as source of the emitted events
+	component = "inspektor-gadget"
+
+	// maxMessageLength is the maximum length of the message of an event;
+	// longer messages are truncated.
+	maxMessageLength = 1024
+)
+
+type KubeEvents struct {
+	initOnce    sync.Once
+	initErr     error
+	broadcaster record.EventBroadcaster
+	recorders   map[string]record.EventRecorder
+	mu          sync.Mutex
+
+	k8sInventory *common.K8sInventoryCache
+}
+
+func (k *KubeEvents) Name() string {
+	return OperatorName
+}
+
+func (k *KubeEvents) Description() string {
+	return "KubeEvents emits Kubernetes Events attached to the pods that generated gadget events"
+}
+
+func (k *KubeEvents) GlobalParamDescs() params.ParamDescs {
+	return nil
+}
+
+func (k *KubeEvents) ParamDescs() params.ParamDescs {
+	return params.ParamDescs{
+		{
+			Key:          ParamEmitEvents,
+			Description:  "Emit a Kubernetes Event attached to the pod for each event generated by the gadget",
+			DefaultValue: "false",
+			TypeHint:     params.TypeBool,
+		},
+		{
+			Key:            ParamEventType,
+			Description:    "Type of the emitted Kubernetes Events",
+			DefaultValue:   v1.EventTypeWarning,
+			PossibleValues: []string{v1.EventTypeNormal, v1.EventTypeWarning},
+		},
+		{
+			Key:         ParamReason,
+			Description: "Reason of the emitted Kubernetes Events. Defaults to Gadget<GadgetName>, e.g. GadgetOomkill",
+		},
+		{
+			Key:         ParamSelector,
+			Description: "Only emit Kubernetes Events for the pods matching this label selector, e.g. app=web",
+			Validator: func(value string) error {
+				_, err := labels.Parse(value)
+				return err
+			},
+		},
+		{
+			Key:          ParamMaxRate,
+			Description:  "Maximum number of Kubernetes Events emitted per second, the others are dropped",
+			DefaultValue: "10",
+			TypeHint:     params.TypeFloat64,
+			Validator: func(value string) error {
+				r, err := strconv.ParseFloat(value, 64)
+				if err != nil || r <= 0 {
+					return fmt.Errorf("expected a positive number, got %q", value)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+func (k *KubeEvents) Dependencies() []string {
+	return []string{kubemanager.OperatorName}
+}
+
+func (k *KubeEvents) CanOperateOn(gadget gadgets.GadgetDesc) bool {
+	km := kubemanager.KubeManager{}
+	if !km.CanOperateOn(gadget) {
+		return false
+	}
+	_, hasContainerInfoGetters := gadget.EventPrototype().(operators.ContainerInfoGetters)
+	return hasContainerInfoGetters
+}
+
+func (k *KubeEvents) Init(params *params.Params) error {
+	return nil
+}
+
+// init sets up the resources needed to emit events. It's done lazily as most
+// of the gadget runs don't use this operator.
+func (k *KubeEvents) init() error {
+	k.initOnce.Do(func() {
+		clientset, err := k8sutil.NewClientset("")
+		if err != nil {
+			k.initErr = fmt.Errorf("creating new k8s clientset: %w", err)
+			return
+		}
+
+		k8sInventory, err := common.GetK8sInventoryCache()
+		if err != nil {
+			k.initErr = fmt.Errorf("creating k8s inventory cache: %w", err)
+			return
+		}
+		k.k8sInventory = k8sInventory
+
+		k.broadcaster = record.NewBroadcaster()
+		k.broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+			Interface: clientset.CoreV1().Events(""),
+		})
+		k.recorders = make(map[string]record.EventRecorder)
+	})
+	return k.initErr
+}
+
+// recorder returns the recorder for events happening on the given node
+func (k *KubeEvents) recorder(node string) record.EventRecorder {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	recorder, ok := k.recorders[node]
+	if !ok {
+		recorder = k.broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{
+			Component: component,
+			Host:      node,
+		})
+		k.recorders[node] = recorder
+	}
+	return recorder
+}
+
+func (k *KubeEvents) Close() error {
+	if k.broadcaster != nil {
+		k.broadcaster.Shutdown()
+	}
+	if k.k8sInventory != nil {
+		k.k8sInventory.Close()
+	}
+	return nil
+}
+
+func (k *KubeEvents) Instantiate(gadgetCtx operators.GadgetContext, gadgetInstance any, params *params.Params) (operators.OperatorInstance, error) {
+	enabled := params.Get(ParamEmitEvents).AsBool()
+	if enabled {
+		if err := k.init(); err != nil {
+			return nil, err
+		}
+	}
+
+	reason := params.Get(ParamReason).AsString()
+	if reason == "" {
+		reason = defaultReason(gadgetCtx.GadgetDesc().Name())
+	}
+
+	selector, err := labels.Parse(params.Get(ParamSelector).AsString())
+	if err != nil {
+		return nil, fmt.Errorf("parsing selector: %w", err)
+	}
+
+	maxRate := params.Get(ParamMaxRate).AsFloat64()
+
+	return &KubeEventsInstance{
+		gadgetCtx: gadgetCtx,
+		manager:   k,
+		enabled:   enabled,
+		eventType: params.Get(ParamEventType).AsString(),
+		reason:    reason,
+		selector:  selector,
+		limiter:   rate.NewLimiter(rate.Limit(maxRate), max(1, int(maxRate))),
+	}, nil
+}
+
+// defaultReason generates a CamelCase reason from the name of the gadget,
+// e.g. "trace_oomkill" becomes "GadgetTraceOomkill"
+func defaultReason(gadgetName string) string {
+	var sb strings.Builder
+	sb.WriteString("Gadget")
+	upper := true
+	for _, r := range gadgetName {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+type KubeEventsInstance struct {
+	gadgetCtx operators.GadgetContext
+	manager   *KubeEvents
+	enabled   bool
+	eventType string
+	reason    string
+	selector  labels.Selector
+	// limiter limits the number of Kubernetes Events emitted per second
+	limiter *rate.Limiter
+}
+
+func (m *KubeEventsInstance) Name() string {
+	return "KubeEventsInstance"
+}
+
+func (m *KubeEventsInstance) PreGadgetRun() error {
+	if m.enabled {
+		m.manager.k8sInventory.Start()
+	}
+	return nil
+}
+
+func (m *KubeEventsInstance) PostGadgetRun() error {
+	if m.enabled {
+		m.manager.k8sInventory.Stop()
+	}
+	return nil
+}
+
+func (m *KubeEventsInstance) EnrichEvent(ev any) error {
+	if !m.enabled {
+		return nil
+	}
+
+	containerInfo, ok := ev.(operators.ContainerInfoGetters)
+	if !ok || containerInfo.GetPod() == "" {
+		return nil
+	}
+
+	pod := m.manager.k8sInventory.GetPodByName(containerInfo.GetNamespace(), containerInfo.GetPod())
+	if pod == nil || !m.selector.Matches(labels.Set(pod.Labels)) {
+		return nil
+	}
+
+	// Check the rate before paying for marshaling the event
+	if !m.limiter.Allow() {
+		return nil
+	}
+
+	m.manager.recorder(containerInfo.GetNode()).Event(pod, m.eventType, m.reason, m.message(ev, containerInfo))
+	return nil
+}
+
+// message returns the message of the Kubernetes Event for the given gadget
+// event: the name of the gadget and container followed by the event as JSON.
+func (m *KubeEventsInstance) message(ev any, containerInfo operators.ContainerInfoGetters) string {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		data = []byte(fmt.Sprintf("marshaling event: %s", err))
+	}
+	msg := fmt.Sprintf("%s: container %q: %s", m.gadgetCtx.GadgetDesc().Name(), containerInfo.GetContainer(), data)
+	if len(msg) > maxMessageLength {
+		msg = msg[:maxMessageLength-3] + "..."
+	}
+	return msg
+}
+
+func init() {
+	operators.Register(&KubeEvents{})
+}
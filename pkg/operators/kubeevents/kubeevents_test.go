@@ -0,0 +1,52 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubeevents
+
+import "testing"
+
+func TestDefaultReason(t *testing.T) {
+	tests := map[string]string{
+		"oomkill":       "GadgetOomkill",
+		"trace_oomkill": "GadgetTraceOomkill",
+		"audit-seccomp": "GadgetAuditSeccomp",
+		"":              "Gadget",
+	}
+	for name, expected := range tests {
+		if reason := defaultReason(name); reason != expected {
+			t.Errorf("defaultReason(%q) = %q, expected %q", name, reason, expected)
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	descs := (&KubeEvents{}).ParamDescs()
+	p := descs.ToParams()
+
+	if err := p.Set(ParamSelector, "app in (web,api)"); err != nil {
+		t.Errorf("setting valid selector: %s", err)
+	}
+	if err := p.Set(ParamSelector, "app in ("); err == nil {
+		t.Errorf("expected error setting invalid selector")
+	}
+
+	if err := p.Set(ParamMaxRate, "0.5"); err != nil {
+		t.Errorf("setting valid rate: %s", err)
+	}
+	for _, value := range []string{"0", "-1", "foo"} {
+		if err := p.Set(ParamMaxRate, value); err == nil {
+			t.Errorf("expected error setting rate %q", value)
+		}
+	}
+}
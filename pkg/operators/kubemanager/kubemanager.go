@@ -18,10 +18,12 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/cilium/ebpf"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/labels"
 
 	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
@@ -38,6 +40,9 @@ const (
 	ParamAllNamespaces = "all-namespaces"
 	ParamPodName       = "podname"
 	ParamNamespace     = "namespace"
+
+	ParamNamespaceSelector = "namespace-selector"
+	ParamEphemeral         = "ephemeral-containers"
 )
 
 type MountNsMapSetter interface {
@@ -118,6 +123,25 @@ func (k *KubeManager) ParamDescs() params.ParamDescs {
 			Description: "Show only data from pods in a given namespace",
 			ValueHint:   gadgets.K8SNamespace,
 		},
+		{
+			Key:         ParamNamespaceSelector,
+			Description: "Show only data from pods in namespaces matching this label selector (e.g. team=payments). The set of namespaces is updated while the gadget is running. Takes precedence over --namespace and --all-namespaces",
+			Validator: func(value string) error {
+				if value == "" {
+					return nil
+				}
+				if _, err := labels.Parse(value); err != nil {
+					return fmt.Errorf("invalid label selector: %w", err)
+				}
+				return nil
+			},
+		},
+		{
+			Key:          ParamEphemeral,
+			Description:  "Show only data from ephemeral containers, e.g. the ones created by kubectl debug",
+			TypeHint:     params.TypeBool,
+			DefaultValue: "false",
+		},
 	}
 }
 
@@ -187,11 +211,19 @@ type KubeManagerInstance struct {
 	mountnsmap   *ebpf.Map
 	subscribed   bool
 
-	attachedContainers map[string]*containercollection.Container
-	attacher           Attacher
-	params             *params.Params
-	gadgetInstance     any
-	gadgetCtx          operators.GadgetContext
+	// mu protects containerSelector and running, which can be changed by the
+	// namespace watcher while the gadget is running
+	mu                sync.Mutex
+	containerSelector containercollection.ContainerSelector
+	running           bool
+	namespaceWatcher  *namespaceWatcher
+
+	attachedContainersMu sync.Mutex
+	attachedContainers   map[string]*containercollection.Container
+	attacher             Attacher
+	params               *params.Params
+	gadgetInstance       any
+	gadgetCtx            operators.GadgetContext
 }
 
 func (m *KubeManagerInstance) Name() string {
@@ -208,24 +240,39 @@ func (m *KubeManagerInstance) PreGadgetRun() error {
 		labels[kv[0]] = kv[1]
 	}
 
-	containerSelector := containercollection.ContainerSelector{
+	m.containerSelector = containercollection.ContainerSelector{
 		K8s: containercollection.K8sSelector{
 			BasicK8sMetadata: types.BasicK8sMetadata{
 				Namespace:     m.params.Get(ParamNamespace).AsString(),
 				PodName:       m.params.Get(ParamPodName).AsString(),
 				ContainerName: m.params.Get(ParamContainerName).AsString(),
 			},
-			PodLabels: labels,
+			PodLabels:     labels,
+			EphemeralOnly: m.params.Get(ParamEphemeral).AsBool(),
 		},
 	}
 
 	if m.params.Get(ParamAllNamespaces).AsBool() {
-		containerSelector.K8s.Namespace = ""
+		m.containerSelector.K8s.Namespace = ""
+	}
+
+	if namespaceSelector := m.params.Get(ParamNamespaceSelector).AsString(); namespaceSelector != "" {
+		// The initial set of namespaces is set synchronously, before the
+		// tracer is created below.
+		watcher, err := newNamespaceWatcher(namespaceSelector, m.setNamespaces)
+		if err != nil {
+			return fmt.Errorf("watching namespaces: %w", err)
+		}
+		m.namespaceWatcher = watcher
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	if setter, ok := m.gadgetInstance.(MountNsMapSetter); ok {
-		err := m.manager.gadgetTracerManager.AddTracer(m.id, containerSelector)
+		err := m.manager.gadgetTracerManager.AddTracer(m.id, m.containerSelector)
 		if err != nil {
+			m.stopNamespaceWatcher()
 			return fmt.Errorf("adding tracer: %w", err)
 		}
 
@@ -233,6 +280,7 @@ func (m *KubeManagerInstance) PreGadgetRun() error {
 		mountnsmap, err := m.manager.gadgetTracerManager.TracerMountNsMap(m.id)
 		if err != nil {
 			m.manager.gadgetTracerManager.RemoveTracer(m.id)
+			m.stopNamespaceWatcher()
 			return fmt.Errorf("creating mountns map: %w", err)
 		}
 
@@ -246,64 +294,153 @@ func (m *KubeManagerInstance) PreGadgetRun() error {
 		m.attacher = attacher
 		m.attachedContainers = make(map[string]*containercollection.Container)
 
-		attachContainerFunc := func(container *containercollection.Container) {
-			log.Debugf("calling gadget.AttachContainer()")
-			err := attacher.AttachContainer(container)
-			if err != nil {
-				var ve *ebpf.VerifierError
-				if errors.As(err, &ve) {
-					m.gadgetCtx.Logger().Debugf("start tracing container %q: verifier error: %+v\n", container.K8s.ContainerName, ve)
-				}
+		m.subscribed = true
+
+		log.Debugf("add subscription")
+		containers := m.subscribe()
+		for _, container := range containers {
+			m.attachContainer(container)
+		}
+	}
+
+	m.running = true
+
+	return nil
+}
 
-				log.Warnf("start tracing container %q: %s", container.K8s.ContainerName, err)
-				return
+// subscribe (re)registers the subscription for container events using the
+// current container selector and returns the containers matching it. It must
+// be called with m.mu held.
+func (m *KubeManagerInstance) subscribe() []*containercollection.Container {
+	log := m.gadgetCtx.Logger()
+
+	return m.manager.gadgetTracerManager.Subscribe(
+		m.id,
+		m.containerSelector,
+		func(event containercollection.PubSubEvent) {
+			log.Debugf("%s: %s", event.Type.String(), event.Container.Runtime.ContainerID)
+			switch event.Type {
+			case containercollection.EventTypeAddContainer:
+				m.attachContainer(event.Container)
+			case containercollection.EventTypeRemoveContainer:
+				m.detachContainer(event.Container)
 			}
+		},
+	)
+}
 
-			m.attachedContainers[container.Runtime.ContainerID] = container
+func (m *KubeManagerInstance) attachContainer(container *containercollection.Container) {
+	log := m.gadgetCtx.Logger()
 
-			log.Debugf("tracer attached: container %q pid %d mntns %d netns %d",
-				container.K8s.ContainerName, container.Pid, container.Mntns, container.Netns)
+	m.attachedContainersMu.Lock()
+	defer m.attachedContainersMu.Unlock()
+
+	if _, ok := m.attachedContainers[container.Runtime.ContainerID]; ok {
+		return
+	}
+
+	log.Debugf("calling gadget.AttachContainer()")
+	err := m.attacher.AttachContainer(container)
+	if err != nil {
+		var ve *ebpf.VerifierError
+		if errors.As(err, &ve) {
+			log.Debugf("start tracing container %q: verifier error: %+v\n", container.K8s.ContainerName, ve)
 		}
 
-		detachContainerFunc := func(container *containercollection.Container) {
-			log.Debugf("calling gadget.Detach()")
-			delete(m.attachedContainers, container.Runtime.ContainerID)
+		log.Warnf("start tracing container %q: %s", container.K8s.ContainerName, err)
+		return
+	}
 
-			err := attacher.DetachContainer(container)
-			if err != nil {
-				log.Warnf("stop tracing container %q: %s", container.K8s.ContainerName, err)
-				return
-			}
-			log.Debugf("tracer detached: container %q pid %d mntns %d netns %d",
-				container.K8s.ContainerName, container.Pid, container.Mntns, container.Netns)
+	m.attachedContainers[container.Runtime.ContainerID] = container
+
+	log.Debugf("tracer attached: container %q pid %d mntns %d netns %d",
+		container.K8s.ContainerName, container.Pid, container.Mntns, container.Netns)
+}
+
+func (m *KubeManagerInstance) detachContainer(container *containercollection.Container) {
+	log := m.gadgetCtx.Logger()
+
+	m.attachedContainersMu.Lock()
+	defer m.attachedContainersMu.Unlock()
+
+	log.Debugf("calling gadget.Detach()")
+	delete(m.attachedContainers, container.Runtime.ContainerID)
+
+	err := m.attacher.DetachContainer(container)
+	if err != nil {
+		log.Warnf("stop tracing container %q: %s", container.K8s.ContainerName, err)
+		return
+	}
+	log.Debugf("tracer detached: container %q pid %d mntns %d netns %d",
+		container.K8s.ContainerName, container.Pid, container.Mntns, container.Netns)
+}
+
+// setNamespaces is called by the namespace watcher when the set of namespaces
+// matching the namespace selector changes. It updates the container selector
+// and, if the gadget is already running, the containers being traced.
+func (m *KubeManagerInstance) setNamespaces(namespaces string) {
+	log := m.gadgetCtx.Logger()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log.Debugf("namespaces matching selector: %q", namespaces)
+	m.containerSelector.K8s.Namespace = namespaces
+
+	if !m.running {
+		return
+	}
+
+	if m.mountnsmap != nil {
+		err := m.manager.gadgetTracerManager.UpdateTracer(m.id, m.containerSelector)
+		if err != nil {
+			log.Warnf("updating tracer: %s", err)
 		}
+	}
 
-		m.subscribed = true
+	if m.subscribed {
+		containers := m.subscribe()
 
-		log.Debugf("add subscription")
-		containers := m.manager.gadgetTracerManager.Subscribe(
-			m.id,
-			containerSelector,
-			func(event containercollection.PubSubEvent) {
-				log.Debugf("%s: %s", event.Type.String(), event.Container.Runtime.ContainerID)
-				switch event.Type {
-				case containercollection.EventTypeAddContainer:
-					attachContainerFunc(event.Container)
-				case containercollection.EventTypeRemoveContainer:
-					detachContainerFunc(event.Container)
-				}
-			},
-		)
+		matching := make(map[string]struct{}, len(containers))
+		for _, container := range containers {
+			matching[container.Runtime.ContainerID] = struct{}{}
+		}
 
+		m.attachedContainersMu.Lock()
+		var toDetach []*containercollection.Container
+		for id, container := range m.attachedContainers {
+			if _, ok := matching[id]; !ok {
+				toDetach = append(toDetach, container)
+			}
+		}
+		m.attachedContainersMu.Unlock()
+
+		for _, container := range toDetach {
+			m.detachContainer(container)
+		}
 		for _, container := range containers {
-			attachContainerFunc(container)
+			m.attachContainer(container)
 		}
 	}
+}
 
-	return nil
+func (m *KubeManagerInstance) stopNamespaceWatcher() {
+	if m.namespaceWatcher != nil {
+		m.namespaceWatcher.Stop()
+		m.namespaceWatcher = nil
+	}
 }
 
 func (m *KubeManagerInstance) PostGadgetRun() error {
+	// Stop the watcher before taking the lock: it could be waiting for it
+	// to deliver an update.
+	m.stopNamespaceWatcher()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.running = false
+
 	if m.mountnsmap != nil {
 		m.gadgetCtx.Logger().Debugf("calling RemoveTracer()")
 		m.manager.gadgetTracerManager.RemoveTracer(m.id)
@@ -313,9 +450,11 @@ func (m *KubeManagerInstance) PostGadgetRun() error {
 		m.manager.gadgetTracerManager.Unsubscribe(m.id)
 
 		// emit detach for all remaining containers
+		m.attachedContainersMu.Lock()
 		for _, container := range m.attachedContainers {
 			m.attacher.DetachContainer(container)
 		}
+		m.attachedContainersMu.Unlock()
 	}
 	return nil
 }
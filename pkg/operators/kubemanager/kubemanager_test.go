@@ -0,0 +1,136 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubemanager
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	containercollection "github.com/inspektor-gadget/inspektor-gadget/pkg/container-collection"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgettracermanager"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+type fakeGadgetContext struct{}
+
+func (f *fakeGadgetContext) ID() string                     { return "test" }
+func (f *fakeGadgetContext) Context() context.Context       { return context.Background() }
+func (f *fakeGadgetContext) GadgetDesc() gadgets.GadgetDesc { return nil }
+func (f *fakeGadgetContext) Logger() logger.Logger          { return logger.DefaultLogger() }
+
+// fakeAttacher records the containers currently attached
+type fakeAttacher struct {
+	mu       sync.Mutex
+	attached map[string]struct{}
+}
+
+func (f *fakeAttacher) AttachContainer(container *containercollection.Container) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attached[container.Runtime.ContainerID] = struct{}{}
+	return nil
+}
+
+func (f *fakeAttacher) DetachContainer(container *containercollection.Container) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.attached, container.Runtime.ContainerID)
+	return nil
+}
+
+func (f *fakeAttacher) ids() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make([]string, 0, len(f.attached))
+	for id := range f.attached {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func newTestContainer(id, namespace string) *containercollection.Container {
+	return &containercollection.Container{
+		Runtime: containercollection.RuntimeMetadata{
+			BasicRuntimeMetadata: types.BasicRuntimeMetadata{
+				ContainerID: id,
+			},
+		},
+		K8s: containercollection.K8sMetadata{
+			BasicK8sMetadata: types.BasicK8sMetadata{
+				Namespace:     namespace,
+				PodName:       "pod-" + id,
+				ContainerName: "container",
+			},
+		},
+	}
+}
+
+func TestSetNamespacesResubscribes(t *testing.T) {
+	g, err := gadgettracermanager.NewServer(&gadgettracermanager.Conf{
+		NodeName: "fake-node",
+		HookMode: "none",
+		TestOnly: true,
+	})
+	require.NoError(t, err)
+	// The test mode doesn't enable the pubsub used to notify about containers
+	require.NoError(t, containercollection.WithPubSub()(&g.ContainerCollection))
+
+	g.ContainerCollection.AddContainer(newTestContainer("c1", "ns1"))
+	g.ContainerCollection.AddContainer(newTestContainer("c2", "ns2"))
+
+	attacher := &fakeAttacher{attached: make(map[string]struct{})}
+	m := &KubeManagerInstance{
+		id:      "test",
+		manager: &KubeManager{gadgetTracerManager: g},
+		containerSelector: containercollection.ContainerSelector{
+			K8s: containercollection.K8sSelector{
+				BasicK8sMetadata: types.BasicK8sMetadata{Namespace: "ns1"},
+			},
+		},
+		attacher:           attacher,
+		attachedContainers: make(map[string]*containercollection.Container),
+		subscribed:         true,
+		running:            true,
+		gadgetCtx:          &fakeGadgetContext{},
+	}
+	m.mu.Lock()
+	for _, container := range m.subscribe() {
+		m.attachContainer(container)
+	}
+	m.mu.Unlock()
+	require.Equal(t, []string{"c1"}, attacher.ids())
+
+	// The labels of the namespaces changed, only ns2 matches now
+	m.setNamespaces("ns2")
+	require.Equal(t, []string{"c2"}, attacher.ids())
+
+	// New containers are attached according to the new subscription
+	g.ContainerCollection.AddContainer(newTestContainer("c3", "ns1"))
+	g.ContainerCollection.AddContainer(newTestContainer("c4", "ns2"))
+	require.Equal(t, []string{"c2", "c4"}, attacher.ids())
+
+	// No namespace matching at all
+	m.setNamespaces(noNamespace)
+	require.Empty(t, attacher.ids())
+
+	require.NoError(t, m.PostGadgetRun())
+}
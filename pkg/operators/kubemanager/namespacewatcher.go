@@ -0,0 +1,149 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubemanager
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/k8sutil"
+)
+
+// noNamespace is used as namespace filter when no namespace matches the
+// namespace selector. It isn't a valid namespace name, so it doesn't match
+// any container, while an empty filter would match all of them.
+const noNamespace = "-"
+
+// namespaceWatcher keeps track of the namespaces matching a label selector and
+// notifies about changes in that set.
+type namespaceWatcher struct {
+	stop chan struct{}
+}
+
+// newNamespaceWatcher starts watching the namespaces matching selector. It
+// blocks until the initial list of namespaces is known and calls onChange with
+// it. onChange is then called with the updated list every time a matching
+// namespace is created or deleted, or its labels change.
+func newNamespaceWatcher(selector string, onChange func(namespaces string)) (*namespaceWatcher, error) {
+	clientset, err := k8sutil.NewClientset("")
+	if err != nil {
+		return nil, fmt.Errorf("creating new k8s clientset: %w", err)
+	}
+	return newNamespaceWatcherWithClientset(clientset, selector, onChange)
+}
+
+func newNamespaceWatcherWithClientset(
+	clientset kubernetes.Interface,
+	selector string,
+	onChange func(namespaces string),
+) (*namespaceWatcher, error) {
+	parsedSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, fmt.Errorf("parsing namespace selector: %w", err)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0,
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = selector
+		}),
+	)
+	informer := factory.Core().V1().Namespaces()
+	lister := informer.Lister()
+
+	w := &namespaceWatcher{
+		stop: make(chan struct{}),
+	}
+
+	// mu serializes the calls to onChange
+	var mu sync.Mutex
+	synced := false
+	last := ""
+	update := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Ignore the events generated while filling the cache, the initial
+		// list is handled below.
+		if !synced {
+			return
+		}
+		namespaces, err := lister.List(parsedSelector)
+		if err != nil {
+			return
+		}
+		current := joinNamespaces(namespaces)
+		if current == last {
+			return
+		}
+		last = current
+		onChange(current)
+	}
+
+	informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { update() },
+		UpdateFunc: func(oldObj, newObj any) { update() },
+		DeleteFunc: func(obj any) { update() },
+	})
+
+	factory.Start(w.stop)
+	for _, ok := range factory.WaitForCacheSync(w.stop) {
+		if !ok {
+			w.Stop()
+			return nil, fmt.Errorf("syncing namespaces cache")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	namespaces, err := lister.List(parsedSelector)
+	if err != nil {
+		w.Stop()
+		return nil, fmt.Errorf("listing namespaces: %w", err)
+	}
+	last = joinNamespaces(namespaces)
+	onChange(last)
+	synced = true
+
+	return w, nil
+}
+
+// Stop stops watching namespaces
+func (w *namespaceWatcher) Stop() {
+	close(w.stop)
+}
+
+// joinNamespaces returns the names of the given namespaces in the format
+// expected by the namespace filter of a container selector
+func joinNamespaces(namespaces []*v1.Namespace) string {
+	if len(namespaces) == 0 {
+		return noNamespace
+	}
+	names := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubemanager
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+	}
+}
+
+func TestNamespaceWatcher(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset(
+		newNamespace("ns1", map[string]string{"team": "a"}),
+		newNamespace("ns2", map[string]string{"team": "b"}),
+	)
+
+	changes := make(chan string, 10)
+	w, err := newNamespaceWatcherWithClientset(clientset, "team=a", func(namespaces string) {
+		changes <- namespaces
+	})
+	require.NoError(t, err)
+	defer w.Stop()
+
+	next := func() string {
+		select {
+		case namespaces := <-changes:
+			return namespaces
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for namespace change")
+			return ""
+		}
+	}
+
+	// The initial list is delivered synchronously
+	require.Equal(t, "ns1", next())
+
+	// A new matching namespace
+	_, err = clientset.CoreV1().Namespaces().Create(ctx, newNamespace("ns3", map[string]string{"team": "a"}), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "ns1,ns3", next())
+
+	// A namespace whose labels start matching
+	_, err = clientset.CoreV1().Namespaces().Update(ctx, newNamespace("ns2", map[string]string{"team": "a"}), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "ns1,ns2,ns3", next())
+
+	// A namespace whose labels stop matching
+	_, err = clientset.CoreV1().Namespaces().Update(ctx, newNamespace("ns1", map[string]string{"team": "b"}), metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "ns2,ns3", next())
+
+	// No namespace matching at all
+	require.NoError(t, clientset.CoreV1().Namespaces().Delete(ctx, "ns2", metav1.DeleteOptions{}))
+	require.Equal(t, "ns3", next())
+	require.NoError(t, clientset.CoreV1().Namespaces().Delete(ctx, "ns3", metav1.DeleteOptions{}))
+	require.Equal(t, noNamespace, next())
+}
+
+func TestNamespaceWatcherInvalidSelector(t *testing.T) {
+	t.Parallel()
+
+	_, err := newNamespaceWatcherWithClientset(fake.NewSimpleClientset(), "team in (a", func(string) {})
+	require.Error(t, err)
+}
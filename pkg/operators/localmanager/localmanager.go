@@ -47,6 +47,9 @@ const (
 	CrioSocketPath       = "crio-socketpath"
 	PodmanSocketPath     = "podman-socketpath"
 	ContainerdNamespace  = "containerd-namespace"
+	DockerTLSCAFile      = "docker-tls-ca-file"
+	DockerTLSCertFile    = "docker-tls-cert-file"
+	DockerTLSKeyFile     = "docker-tls-key-file"
 )
 
 type MountNsMapSetter interface {
@@ -80,15 +83,15 @@ func (l *LocalManager) GlobalParamDescs() params.ParamDescs {
 		{
 			Key:          Runtimes,
 			Alias:        "r",
-			DefaultValue: strings.Join(containerutils.AvailableRuntimes, ","),
-			Description: fmt.Sprintf("Container runtimes to be used separated by comma. Supported values are: %s",
+			DefaultValue: strings.Join(containerutils.DefaultRuntimes, ","),
+			Description: fmt.Sprintf("Container runtimes to be used separated by comma, in order of priority. Each runtime can be followed by its socket path, e.g. containerd=/path/to/containerd.sock. Supported values are: %s",
 				strings.Join(containerutils.AvailableRuntimes, ", ")),
 			// PossibleValues: containerutils.AvailableRuntimes, // TODO
 		},
 		{
 			Key:          DockerSocketPath,
 			DefaultValue: runtimeclient.DockerDefaultSocketPath,
-			Description:  "Docker Engine API Unix socket path",
+			Description:  "Docker Engine API Unix socket path or tcp:// address of a remote engine",
 		},
 		{
 			Key:          ContainerdSocketPath,
@@ -108,7 +111,19 @@ func (l *LocalManager) GlobalParamDescs() params.ParamDescs {
 		{
 			Key:          ContainerdNamespace,
 			DefaultValue: constants.K8sContainerdNamespace,
-			Description:  "Containerd namespace to use",
+			Description:  "Comma-separated list of containerd namespaces to use (e.g. k8s.io,default,moby)",
+		},
+		{
+			Key:         DockerTLSCAFile,
+			Description: "CA certificate used to verify a remote Docker engine listening on a tcp:// docker-socketpath. TLS isn't used if not set",
+		},
+		{
+			Key:         DockerTLSCertFile,
+			Description: "Client certificate used to authenticate against a remote Docker engine",
+		},
+		{
+			Key:         DockerTLSKeyFile,
+			Description: "Key of the client certificate used to authenticate against a remote Docker engine",
 		},
 	}
 }
@@ -123,7 +138,7 @@ func (l *LocalManager) ParamDescs() params.ParamDescs {
 		},
 		{
 			Key:          Host,
-			Description:  "Show data from both the host and containers",
+			Description:  "Show data from both the host and containers. Use --containername host to show data only from the host",
 			DefaultValue: "false",
 			TypeHint:     params.TypeBool,
 		},
@@ -166,27 +181,51 @@ func (l *LocalManager) Init(operatorParams *params.Params) error {
 
 partsLoop:
 	for _, p := range parts {
-		runtimeName := types.String2RuntimeName(strings.TrimSpace(p))
+		runtimeName, explicitSocketPath := containerutils.ParseRuntimeSpec(p)
 		socketPath := ""
-		namespace := ""
+		var extra *containerutilsTypes.ExtraConfig
 
 		switch runtimeName {
 		case types.RuntimeNameDocker:
 			socketPath = operatorParams.Get(DockerSocketPath).AsString()
+			if caFile := operatorParams.Get(DockerTLSCAFile).AsString(); caFile != "" {
+				extra = &containerutilsTypes.ExtraConfig{
+					TLSCAFile:   caFile,
+					TLSCertFile: operatorParams.Get(DockerTLSCertFile).AsString(),
+					TLSKeyFile:  operatorParams.Get(DockerTLSKeyFile).AsString(),
+				}
+			}
 		case types.RuntimeNameContainerd:
 			socketPath = operatorParams.Get(ContainerdSocketPath).AsString()
-			namespace = operatorParams.Get(ContainerdNamespace).AsString()
+			if namespace := operatorParams.Get(ContainerdNamespace).AsString(); namespace != "" {
+				extra = &containerutilsTypes.ExtraConfig{
+					Namespace: namespace,
+				}
+			}
 		case types.RuntimeNameCrio:
 			socketPath = operatorParams.Get(CrioSocketPath).AsString()
 		case types.RuntimeNamePodman:
 			socketPath = operatorParams.Get(PodmanSocketPath).AsString()
+		case types.RuntimeNameLXC, types.RuntimeNameSystemdNspawn:
+			// Found without socket, see containerutils.AvailableRuntimes
+			if explicitSocketPath != "" {
+				return commonutils.WrapInErrInvalidArg("--runtime / -r",
+					fmt.Errorf("runtime %q doesn't use a socket", runtimeName))
+			}
 		default:
 			return commonutils.WrapInErrInvalidArg("--runtime / -r",
 				fmt.Errorf("runtime %q is not supported", p))
 		}
 
+		// A socket path given along with the runtime takes precedence
+		if explicitSocketPath != "" {
+			socketPath = explicitSocketPath
+		}
+
 		for _, r := range rc {
-			if r.Name == runtimeName {
+			// The same runtime listed with another socket path is a fallback, see
+			// containercollection.WithMultipleContainerRuntimesEnrichment()
+			if r.Name == runtimeName && r.SocketPath == socketPath {
 				log.Infof("Ignoring duplicated runtime %q from %v",
 					runtimeName, parts)
 				continue partsLoop
@@ -196,11 +235,7 @@ partsLoop:
 		r := &containerutilsTypes.RuntimeConfig{
 			Name:       runtimeName,
 			SocketPath: socketPath,
-		}
-		if namespace != "" {
-			r.Extra = &containerutilsTypes.ExtraConfig{
-				Namespace: namespace,
-			}
+			Extra:      extra,
 		}
 
 		rc = append(rc, r)
@@ -274,12 +309,25 @@ func (l *localManagerTrace) PreGadgetRun() error {
 		Runtime: containercollection.RuntimeSelector{
 			ContainerName: l.params.Get(ContainerName).AsString(),
 		},
+		Host: host,
 	}
 
-	// If --host is set, we do not want to create the below map because we do not
-	// want any filtering.
+	// Processes of containers running in a virtual machine aren't visible from
+	// the host. Let the user know instead of silently not tracing them.
+	if containerSelector.Runtime.ContainerName != "" && l.manager.igManager != nil {
+		for _, c := range l.manager.igManager.GetContainersBySelector(&containerSelector) {
+			if c.Runtime.VMIsolated {
+				log.Warnf("container %q runs in a virtual machine: only its activity visible from the host, like its network traffic, can be traced",
+					c.Runtime.ContainerName)
+			}
+		}
+	}
+
+	// If --host is set without selecting containers, we do not want to create
+	// the below map because we do not want any filtering: host processes don't
+	// necessarily share the mount namespace of the init process.
 	if setter, ok := l.gadgetInstance.(MountNsMapSetter); ok {
-		if !host {
+		if !host || containerSelector.Runtime.ContainerName != "" {
 			if l.manager.igManager == nil {
 				return fmt.Errorf("container-collection isn't available")
 			}
@@ -359,9 +407,11 @@ func (l *localManagerTrace) PreGadgetRun() error {
 			)
 		}
 
-		if host {
-			// We need to attach this fake container for gadget which rely only on the
-			// Attacher concept.
+		if host && l.manager.igManager == nil {
+			// The host pseudo-container is part of the container-collection,
+			// see containercollection.WithHost(). Without it, we need to attach
+			// this fake container for gadgets which rely only on the Attacher
+			// concept.
 			containers = append(containers, &containercollection.Container{Pid: 1})
 		}
 
@@ -379,8 +429,6 @@ func (l *localManagerTrace) PostGadgetRun() error {
 		l.manager.igManager.RemoveMountNsMap(l.subscriptionKey)
 	}
 	if l.subscriptionKey != "" {
-		host := l.params.Get(Host).AsBool()
-
 		log.Debugf("calling Unsubscribe()")
 		l.manager.igManager.Unsubscribe(l.subscriptionKey)
 
@@ -389,12 +437,6 @@ func (l *localManagerTrace) PostGadgetRun() error {
 			for container := range l.attachedContainers {
 				l.attacher.DetachContainer(container)
 			}
-
-			if host {
-				// Reciprocal operation of attaching fake container with PID 1 which is
-				// needed by gadgets relying on the Attacher concept.
-				l.attacher.DetachContainer(&containercollection.Container{Pid: 1})
-			}
 		}
 	}
 	return nil
@@ -109,6 +109,10 @@ type NodeSetter interface {
 	SetNode(string)
 }
 
+type ClusterSetter interface {
+	SetCluster(string)
+}
+
 type ContainerInfoGetters interface {
 	GetNode() string
 	GetPod() string
@@ -0,0 +1,96 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package redactor provides an operator that masks the values looking like
+// secrets in the events, according to the configuration of the redaction
+// package, before they're sent off the node.
+package redactor
+
+import (
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/redaction"
+)
+
+const (
+	OperatorName = "Redactor"
+)
+
+type Redactor struct{}
+
+func (r *Redactor) Name() string {
+	return OperatorName
+}
+
+func (r *Redactor) Description() string {
+	return "Redactor masks the values looking like secrets in the events"
+}
+
+func (r *Redactor) GlobalParamDescs() params.ParamDescs {
+	return nil
+}
+
+func (r *Redactor) ParamDescs() params.ParamDescs {
+	return nil
+}
+
+func (r *Redactor) Dependencies() []string {
+	return nil
+}
+
+func (r *Redactor) CanOperateOn(gadget gadgets.GadgetDesc) bool {
+	return redaction.CurrentConfig() != nil && gadget.EventPrototype() != nil
+}
+
+func (r *Redactor) Init(params *params.Params) error {
+	return nil
+}
+
+func (r *Redactor) Close() error {
+	return nil
+}
+
+func (r *Redactor) Instantiate(gadgetCtx operators.GadgetContext, gadgetInstance any, params *params.Params) (operators.OperatorInstance, error) {
+	return &RedactorInstance{
+		config: redaction.CurrentConfig(),
+	}, nil
+}
+
+type RedactorInstance struct {
+	config *redaction.Config
+}
+
+func (i *RedactorInstance) Name() string {
+	return "RedactorInstance"
+}
+
+func (i *RedactorInstance) PreGadgetRun() error {
+	return nil
+}
+
+func (i *RedactorInstance) PostGadgetRun() error {
+	return nil
+}
+
+func (i *RedactorInstance) EnrichEvent(ev any) error {
+	if i.config != nil {
+		i.config.Redact(ev)
+	}
+	return nil
+}
+
+func init() {
+	operators.Register(&Redactor{})
+}
@@ -89,6 +89,14 @@ type ParamDesc struct {
 	// PossibleValues holds all possible values for this parameter and will be considered
 	// when validating
 	PossibleValues []string `json:"possibleValues" yaml:"possibleValues,omitempty"`
+
+	// RequiredOneOf holds the keys of other parameters; if none of them and this parameter
+	// are set, validating the constraints will fail
+	RequiredOneOf []string `json:"requiredOneOf" yaml:"requiredOneOf,omitempty"`
+
+	// ConflictsWith holds the keys of other parameters that can't be set together with this
+	// parameter
+	ConflictsWith []string `json:"conflictsWith" yaml:"conflictsWith,omitempty"`
 }
 
 // Param holds a ParamDesc but can additionally store a value
@@ -244,6 +252,47 @@ func (p *Params) ValidateStringMap(cfg map[string]string) error {
 	return nil
 }
 
+// ValidateConstraints checks the RequiredOneOf and ConflictsWith constraints of the
+// params. It has to be called once all the values are set, as only the params that were
+// explicitly set are taken into account.
+func (p *Params) ValidateConstraints() error {
+	for _, param := range *p {
+		if len(param.RequiredOneOf) > 0 {
+			found := param.IsSet()
+			for _, key := range param.RequiredOneOf {
+				other := p.Get(key)
+				if other == nil {
+					return fmt.Errorf("param %q requires unknown param %q", param.Key, key)
+				}
+				found = found || other.IsSet()
+			}
+			if !found {
+				keys := append([]string{param.Key}, param.RequiredOneOf...)
+				return fmt.Errorf("expected value for at least one of %s", quoteKeys(keys))
+			}
+		}
+
+		for _, key := range param.ConflictsWith {
+			other := p.Get(key)
+			if other == nil {
+				return fmt.Errorf("param %q conflicts with unknown param %q", param.Key, key)
+			}
+			if param.IsSet() && other.IsSet() {
+				return fmt.Errorf("%q and %q can't be set at the same time", param.Key, key)
+			}
+		}
+	}
+	return nil
+}
+
+func quoteKeys(keys []string) string {
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		quoted = append(quoted, strconv.Quote(key))
+	}
+	return strings.Join(quoted, ", ")
+}
+
 func compressAndB64Encode(s string) string {
 	// Create a new zlib.Writer, which will write to a bytes.Buffer
 	var b bytes.Buffer
@@ -400,6 +449,10 @@ func (p *Param) AsAny() any {
 		return p.AsDuration()
 	case TypeIP:
 		return p.AsIP()
+	case TypeSize:
+		return p.AsSize()
+	case TypeCIDR:
+		return p.AsCIDR()
 	default:
 		return p.value
 	}
@@ -529,3 +582,14 @@ func (p *Param) AsDuration() time.Duration {
 func (p *Param) AsIP() net.IP {
 	return net.ParseIP(p.value)
 }
+
+// AsSize returns the size in bytes, see ParseSize
+func (p *Param) AsSize() uint64 {
+	n, _ := ParseSize(p.value)
+	return n
+}
+
+func (p *Param) AsCIDR() *net.IPNet {
+	_, ipNet, _ := net.ParseCIDR(p.value)
+	return ipNet
+}
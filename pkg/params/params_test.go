@@ -221,6 +221,27 @@ func TestParamAs(t *testing.T) {
 			expected: net.IP{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
 			getter:   func(p *Param) any { return p.AsIP() },
 		},
+		{
+			name:     "Size()",
+			value:    "64MiB",
+			typeHint: TypeSize,
+			expected: uint64(64 * 1024 * 1024),
+			getter:   func(p *Param) any { return p.AsSize() },
+		},
+		{
+			name:     "Size()_decimal",
+			value:    "1.5k",
+			typeHint: TypeSize,
+			expected: uint64(1500),
+			getter:   func(p *Param) any { return p.AsSize() },
+		},
+		{
+			name:     "CIDR()",
+			value:    "10.1.2.3/8",
+			typeHint: TypeCIDR,
+			expected: &net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+			getter:   func(p *Param) any { return p.AsCIDR() },
+		},
 	}
 
 	for _, test := range tests {
@@ -483,3 +504,75 @@ func TestIsDefault(t *testing.T) {
 	p.Set("bar")
 	require.False(t, p.IsDefault())
 }
+
+func TestValidateConstraints(t *testing.T) {
+	type testDefinition struct {
+		descs       ParamDescs
+		set         map[string]string
+		expectedErr string
+	}
+
+	tests := map[string]testDefinition{
+		"required_one_of_missing": {
+			descs: ParamDescs{
+				{Key: "pid", RequiredOneOf: []string{"comm"}},
+				{Key: "comm"},
+			},
+			expectedErr: `expected value for at least one of "pid", "comm"`,
+		},
+		"required_one_of_set": {
+			descs: ParamDescs{
+				{Key: "pid", RequiredOneOf: []string{"comm"}},
+				{Key: "comm"},
+			},
+			set: map[string]string{"comm": "bash"},
+		},
+		"required_one_of_default_not_enough": {
+			descs: ParamDescs{
+				{Key: "pid", DefaultValue: "1", RequiredOneOf: []string{"comm"}},
+				{Key: "comm"},
+			},
+			expectedErr: `expected value for at least one of "pid", "comm"`,
+		},
+		"conflicts_with": {
+			descs: ParamDescs{
+				{Key: "all", TypeHint: TypeBool, ConflictsWith: []string{"pid"}},
+				{Key: "pid"},
+			},
+			set:         map[string]string{"all": "true", "pid": "1"},
+			expectedErr: `"all" and "pid" can't be set at the same time`,
+		},
+		"conflicts_with_one_set": {
+			descs: ParamDescs{
+				{Key: "all", TypeHint: TypeBool, ConflictsWith: []string{"pid"}},
+				{Key: "pid"},
+			},
+			set: map[string]string{"pid": "1"},
+		},
+		"unknown_param": {
+			descs: ParamDescs{
+				{Key: "all", ConflictsWith: []string{"foo"}},
+			},
+			expectedErr: `param "all" conflicts with unknown param "foo"`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			params := test.descs.ToParams()
+			for key, value := range test.set {
+				require.NoError(t, params.Set(key, value))
+			}
+
+			err := params.ValidateConstraints()
+			if test.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, test.expectedErr)
+			}
+		})
+	}
+}
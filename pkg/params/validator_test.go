@@ -539,3 +539,79 @@ func TestValidateIP(t *testing.T) {
 		ValidateIP,
 	)
 }
+
+func TestValidateCIDR(t *testing.T) {
+	testValidate(t,
+		[]validateTest{
+			{
+				name:          "IPv4_no_error",
+				value:         "10.0.0.0/8",
+				expectedError: false,
+			},
+			{
+				name:          "IPv6_no_error",
+				value:         "fd00::/64",
+				expectedError: false,
+			},
+			{
+				name:          "empty_no_error",
+				value:         "",
+				expectedError: false,
+			},
+			{
+				name:          "no_prefix_length",
+				value:         "10.0.0.1",
+				expectedError: true,
+			},
+			{
+				name:          "bad_prefix_length",
+				value:         "10.0.0.0/33",
+				expectedError: true,
+			},
+		},
+		ValidateCIDR,
+	)
+}
+
+func TestValidateSize(t *testing.T) {
+	testValidate(t,
+		[]validateTest{
+			{
+				name:          "bytes_no_error",
+				value:         "4096",
+				expectedError: false,
+			},
+			{
+				name:          "binary_unit_no_error",
+				value:         "64MiB",
+				expectedError: false,
+			},
+			{
+				name:          "decimal_unit_no_error",
+				value:         "1.5 GB",
+				expectedError: false,
+			},
+			{
+				name:          "empty_error",
+				value:         "",
+				expectedError: true,
+			},
+			{
+				name:          "bad_unit",
+				value:         "1XB",
+				expectedError: true,
+			},
+			{
+				name:          "negative",
+				value:         "-1",
+				expectedError: true,
+			},
+			{
+				name:          "overflow",
+				value:         "20000000TiB",
+				expectedError: true,
+			},
+		},
+		ValidateSize,
+	)
+}
@@ -15,7 +15,10 @@
 package params
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/bits"
 	"net"
 	"strconv"
 	"strings"
@@ -43,6 +46,10 @@ const (
 	TypeFloat64  TypeHint = "float64"
 	TypeDuration TypeHint = "duration"
 	TypeIP       TypeHint = "ip"
+	TypeSize     TypeHint = "size"
+	TypeCIDR     TypeHint = "cidr"
+	// TypeEnum values are validated against the PossibleValues
+	TypeEnum TypeHint = "enum"
 )
 
 var typeHintValidators = map[TypeHint]ParamValidator{
@@ -61,6 +68,8 @@ var typeHintValidators = map[TypeHint]ParamValidator{
 	TypeFloat64:  ValidateFloat(64),
 	TypeDuration: ValidateDuration,
 	TypeIP:       ValidateIP,
+	TypeSize:     ValidateSize,
+	TypeCIDR:     ValidateCIDR,
 }
 
 type ValueHint string
@@ -157,3 +166,83 @@ func ValidateIP(value string) error {
 	}
 	return nil
 }
+
+func ValidateCIDR(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(value); err != nil {
+		return fmt.Errorf("%q is not a valid CIDR", value)
+	}
+	return nil
+}
+
+func ValidateSize(value string) error {
+	_, err := ParseSize(value)
+	return err
+}
+
+var sizeUnits = map[string]uint64{
+	"":    1,
+	"B":   1,
+	"K":   1000,
+	"KB":  1000,
+	"M":   1000 * 1000,
+	"MB":  1000 * 1000,
+	"G":   1000 * 1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"T":   1000 * 1000 * 1000 * 1000,
+	"TB":  1000 * 1000 * 1000 * 1000,
+	"KI":  1 << 10,
+	"KIB": 1 << 10,
+	"MI":  1 << 20,
+	"MIB": 1 << 20,
+	"GI":  1 << 30,
+	"GIB": 1 << 30,
+	"TI":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses a size in bytes with an optional decimal (K, M, G, T) or
+// binary (Ki, Mi, Gi, Ti) unit, optionally followed by B, e.g. "64MiB" or
+// "1.5G"
+func ParseSize(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	unitStart := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if unitStart == -1 {
+		unitStart = len(value)
+	}
+	number, unit := value[:unitStart], strings.TrimSpace(value[unitStart:])
+	if number == "" {
+		return 0, fmt.Errorf("expected size, got %q", value)
+	}
+
+	multiplier, ok := sizeUnits[strings.ToUpper(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q", unit)
+	}
+
+	if !strings.Contains(number, ".") {
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected size: %w", err)
+		}
+		hi, size := bits.Mul64(n, multiplier)
+		if hi != 0 {
+			return 0, errors.New("size out of range")
+		}
+		return size, nil
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected size: %w", err)
+	}
+	size := math.Round(n * float64(multiplier))
+	if size >= math.MaxUint64 {
+		return 0, errors.New("size out of range")
+	}
+	return uint64(size), nil
+}
@@ -71,7 +71,7 @@ func (oh *outputHelper[T]) EventHandlerFunc() any {
 			case types.ERR:
 				oh.parser.writeLogMessage(logger.ErrorLevel, getter.GetMessage())
 				return
-			case types.WARN:
+			case types.WARN, types.EVENTS_DROPPED:
 				oh.parser.writeLogMessage(logger.WarnLevel, getter.GetMessage())
 				return
 			case types.DEBUG:
@@ -82,6 +82,10 @@ type Parser interface {
 	EventHandlerFunc(enrichers ...func(any) error) any
 	EventHandlerFuncArray(enrichers ...func(any) error) any
 
+	// EventHandlerFuncBatch returns a function that accepts a slice of independent events of type *T and pushes
+	// each of them downstream after applying enrichers and filters, like EventHandlerFunc does
+	EventHandlerFuncBatch(enrichers ...func(any) error) any
+
 	// JSONHandlerFunc returns a function that accepts a JSON encoded event, unmarshal it into *T and pushes it
 	// downstream after applying enrichers and filters
 	JSONHandlerFunc(enrichers ...func(any) error) func([]byte)
@@ -104,6 +108,16 @@ type Parser interface {
 	// Flush sends the events downstream that were collected after EnableCombiner() was called.
 	Flush()
 
+	// EnableEventRelease makes the parser call Release() on the events implementing it once they are filtered out
+	// or the downstream callback returns, so they can be reused. It must only be used if the downstream callback
+	// doesn't retain the events.
+	EnableEventRelease()
+
+	// SetMaxRows limits the number of entries that are sent downstream after combining, sorting and
+	// filtering the results of all sources, i.e. by Flush() and by the snapshot combiner, and after
+	// sorting and filtering each array of events. 0 means no limit.
+	SetMaxRows(maxRows int)
+
 	// Things related to Prometheus. TODO: move to a separate interface / file?
 
 	// AttrsGetter returns a function that accepts an instance of type *T and returns a list of
@@ -133,9 +147,11 @@ type parser[T any] struct {
 	filterSpecs        *filter.FilterSpecs[T] // TODO: filter collection(!)
 	eventCallback      func(*T)
 	eventCallbackArray func([]*T)
+	releaseEvents      bool
 	logCallback        LogCallback
 	snapshotCombiner   *snapshotcombiner.SnapshotCombiner[T]
 	columnFilters      []columns.ColumnFilter
+	maxRows            int
 
 	// event combiner related fields
 	eventCombinerEnabled bool
@@ -176,7 +192,7 @@ func (p *parser[T]) flushSnapshotCombiner() {
 	if p.sortSpec != nil {
 		p.sortSpec.Sort(out)
 	}
-	p.eventCallbackArray(out)
+	p.eventCallbackArray(p.limit(out))
 }
 
 func (p *parser[T]) EnableCombiner() {
@@ -196,7 +212,19 @@ func (p *parser[T]) Flush() {
 	if p.sortSpec != nil {
 		p.sortSpec.Sort(p.combinedEvents)
 	}
-	p.eventCallbackArray(p.combinedEvents)
+	p.eventCallbackArray(p.limit(p.combinedEvents))
+}
+
+func (p *parser[T]) SetMaxRows(maxRows int) {
+	p.maxRows = maxRows
+}
+
+// limit returns the first maxRows entries of events
+func (p *parser[T]) limit(events []*T) []*T {
+	if p.maxRows > 0 && len(events) > p.maxRows {
+		return events[:p.maxRows]
+	}
+	return events
 }
 
 func (p *parser[T]) SetColumnFilters(filters ...columns.ColumnFilter) {
@@ -237,9 +265,38 @@ func (p *parser[T]) eventHandler(cb func(*T), enrichers ...func(any) error) func
 			enricher(ev)
 		}
 		if p.filterSpecs != nil && !p.filterSpecs.MatchAll(ev) {
+			p.release(ev)
 			return
 		}
 		cb(ev)
+		p.release(ev)
+	}
+}
+
+// releaser is implemented by events that can be reused, see EnableEventRelease()
+type releaser interface {
+	Release()
+}
+
+func (p *parser[T]) EnableEventRelease() {
+	p.releaseEvents = true
+}
+
+func (p *parser[T]) release(ev *T) {
+	if !p.releaseEvents {
+		return
+	}
+	if r, ok := any(ev).(releaser); ok {
+		r.Release()
+	}
+}
+
+func (p *parser[T]) eventHandlerBatch(cb func(*T), enrichers ...func(any) error) func([]*T) {
+	handler := p.eventHandler(cb, enrichers...)
+	return func(events []*T) {
+		for _, ev := range events {
+			handler(ev)
+		}
 	}
 }
 
@@ -266,7 +323,7 @@ func (p *parser[T]) eventHandlerArray(cb func([]*T), enrichers ...func(any) erro
 		if p.sortSpec != nil {
 			p.sortSpec.Sort(events)
 		}
-		cb(events)
+		cb(p.limit(events))
 	}
 }
 
@@ -340,6 +397,10 @@ func (p *parser[T]) EventHandlerFuncArray(enrichers ...func(any) error) any {
 	return p.eventHandlerArray(p.eventCallbackArray, enrichers...)
 }
 
+func (p *parser[T]) EventHandlerFuncBatch(enrichers ...func(any) error) any {
+	return p.eventHandlerBatch(p.eventCallback, enrichers...)
+}
+
 func (p *parser[T]) GetTextColumnsFormatter(options ...textcolumns.Option) TextColumnsFormatter {
 	return &outputHelper[T]{
 		parser:               p,
@@ -0,0 +1,124 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+)
+
+type testEntry struct {
+	Node  string `column:"node"`
+	Value int    `column:"value"`
+}
+
+func TestCombinerSortAndLimit(t *testing.T) {
+	p := NewParser[testEntry](columns.MustCreateColumns[testEntry]())
+
+	var out []*testEntry
+	p.SetEventCallback(func(events []*testEntry) {
+		out = events
+	})
+	require.NoError(t, p.SetSorting([]string{"-value"}))
+	p.SetMaxRows(3)
+	p.EnableCombiner()
+
+	p.JSONHandlerFuncArray("node1")([]byte(`[{"Node":"node1","Value":1},{"Node":"node1","Value":5}]`))
+	p.JSONHandlerFuncArray("node2")([]byte(`[{"Node":"node2","Value":4},{"Node":"node2","Value":2}]`))
+	p.Flush()
+
+	require.Equal(t, []*testEntry{
+		{Node: "node1", Value: 5},
+		{Node: "node2", Value: 4},
+		{Node: "node2", Value: 2},
+	}, out)
+}
+
+func TestEventHandlerFuncArrayLimit(t *testing.T) {
+	p := NewParser[testEntry](columns.MustCreateColumns[testEntry]())
+
+	var out []*testEntry
+	p.SetEventCallback(func(events []*testEntry) {
+		out = events
+	})
+	require.NoError(t, p.SetSorting([]string{"-value"}))
+	p.SetMaxRows(2)
+
+	handler := p.EventHandlerFuncArray().(func([]*testEntry))
+	handler([]*testEntry{{Value: 1}, {Value: 3}, {Value: 2}})
+
+	require.Equal(t, []*testEntry{
+		{Value: 3},
+		{Value: 2},
+	}, out)
+}
+
+func TestEventHandlerFuncBatch(t *testing.T) {
+	p := NewParser[testEntry](columns.MustCreateColumns[testEntry]())
+
+	var out []*testEntry
+	p.SetEventCallback(func(event *testEntry) {
+		out = append(out, event)
+	})
+	require.NoError(t, p.SetFilters([]string{"value:>1"}))
+
+	enricher := func(ev any) error {
+		ev.(*testEntry).Node = "node1"
+		return nil
+	}
+	handler := p.EventHandlerFuncBatch(enricher).(func([]*testEntry))
+	handler([]*testEntry{{Value: 1}, {Value: 2}, {Value: 3}})
+
+	require.Equal(t, []*testEntry{
+		{Node: "node1", Value: 2},
+		{Node: "node1", Value: 3},
+	}, out)
+}
+
+type releasableEntry struct {
+	testEntry
+	released bool
+}
+
+func (e *releasableEntry) Release() {
+	e.released = true
+}
+
+func TestEnableEventRelease(t *testing.T) {
+	p := NewParser[releasableEntry](columns.MustCreateColumns[releasableEntry]())
+
+	p.SetEventCallback(func(event *releasableEntry) {
+		require.False(t, event.released)
+	})
+	require.NoError(t, p.SetFilters([]string{"value:>1"}))
+
+	handler := p.EventHandlerFunc().(func(*releasableEntry))
+
+	kept := &releasableEntry{testEntry: testEntry{Value: 2}}
+	filtered := &releasableEntry{testEntry: testEntry{Value: 1}}
+	handler(kept)
+	handler(filtered)
+	require.False(t, kept.released)
+	require.False(t, filtered.released)
+
+	p.EnableEventRelease()
+	handler(kept)
+	handler(filtered)
+	require.True(t, kept.released)
+	require.True(t, filtered.released)
+}
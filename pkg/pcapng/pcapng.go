@@ -0,0 +1,131 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pcapng writes packets in the pcapng format, the default one of
+// Wireshark. See https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-01.html.
+package pcapng
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	blockTypeSectionHeader    = 0x0A0D0D0A
+	blockTypeInterfaceDesc    = 0x00000001
+	blockTypeEnhancedPacket   = 0x00000006
+	byteOrderMagic            = 0x1A2B3C4D
+	optionEndOfOpt            = 0
+	optionComment             = 1
+	optionInterfaceTSResol    = 9
+	nanosecondsTSResol        = 9
+	blockHeaderAndTrailerSize = 12
+)
+
+// LinkTypeEthernet is the link type of the packets starting at the Ethernet
+// header
+const LinkTypeEthernet = 1
+
+// Writer writes the packets in a pcapng section with a single interface. The
+// section header and the interface description are written before the first
+// packet.
+type Writer struct {
+	w             io.Writer
+	linkType      uint16
+	snaplen       uint32
+	headerWritten bool
+}
+
+// NewWriter returns a writer of packets of the given link type, a snaplen of
+// 0 means the packets aren't truncated
+func NewWriter(w io.Writer, linkType uint16, snaplen uint32) *Writer {
+	return &Writer{
+		w:        w,
+		linkType: linkType,
+		snaplen:  snaplen,
+	}
+}
+
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
+
+// appendOption appends an option, padding its value to 32 bits
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	b = binary.LittleEndian.AppendUint16(b, code)
+	b = binary.LittleEndian.AppendUint16(b, uint16(len(value)))
+	b = append(b, value...)
+	return append(b, make([]byte, pad4(len(value))-len(value))...)
+}
+
+// writeBlock writes a block with the given body, adding the type and the
+// length before and after it
+func (w *Writer) writeBlock(blockType uint32, body []byte) error {
+	length := uint32(blockHeaderAndTrailerSize + len(body))
+
+	block := make([]byte, 0, length)
+	block = binary.LittleEndian.AppendUint32(block, blockType)
+	block = binary.LittleEndian.AppendUint32(block, length)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint32(block, length)
+
+	_, err := w.w.Write(block)
+	return err
+}
+
+func (w *Writer) writeHeader() error {
+	shb := binary.LittleEndian.AppendUint32(nil, byteOrderMagic)
+	shb = binary.LittleEndian.AppendUint16(shb, 1) // major version
+	shb = binary.LittleEndian.AppendUint16(shb, 0) // minor version
+	// The length of the section is unknown, -1
+	shb = binary.LittleEndian.AppendUint64(shb, math.MaxUint64)
+	if err := w.writeBlock(blockTypeSectionHeader, shb); err != nil {
+		return err
+	}
+
+	idb := binary.LittleEndian.AppendUint16(nil, w.linkType)
+	idb = binary.LittleEndian.AppendUint16(idb, 0) // reserved
+	idb = binary.LittleEndian.AppendUint32(idb, w.snaplen)
+	idb = appendOption(idb, optionInterfaceTSResol, []byte{nanosecondsTSResol})
+	idb = appendOption(idb, optionEndOfOpt, nil)
+	return w.writeBlock(blockTypeInterfaceDesc, idb)
+}
+
+// WritePacket writes a packet captured at ts. origLen is the length of the
+// packet before being truncated to data. The comment, if any, is shown by
+// Wireshark with the packet.
+func (w *Writer) WritePacket(ts time.Time, data []byte, origLen uint32, comment string) error {
+	if !w.headerWritten {
+		if err := w.writeHeader(); err != nil {
+			return err
+		}
+		w.headerWritten = true
+	}
+
+	nsec := uint64(ts.UnixNano())
+	epb := binary.LittleEndian.AppendUint32(nil, 0) // interface id
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(nsec>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(nsec))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(data)))
+	epb = binary.LittleEndian.AppendUint32(epb, origLen)
+	epb = append(epb, data...)
+	epb = append(epb, make([]byte, pad4(len(data))-len(data))...)
+	if comment != "" {
+		epb = appendOption(epb, optionComment, []byte(comment))
+		epb = appendOption(epb, optionEndOfOpt, nil)
+	}
+	return w.writeBlock(blockTypeEnhancedPacket, epb)
+}
@@ -0,0 +1,89 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pcapng
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type block struct {
+	typ  uint32
+	body []byte
+}
+
+// readBlocks splits a pcapng stream in blocks, checking their lengths
+func readBlocks(t *testing.T, data []byte) []block {
+	var blocks []block
+	for len(data) > 0 {
+		require.GreaterOrEqual(t, len(data), blockHeaderAndTrailerSize)
+		length := binary.LittleEndian.Uint32(data[4:])
+		require.Zero(t, length%4, "blocks must be 32-bit aligned")
+		require.LessOrEqual(t, int(length), len(data))
+		require.Equal(t, length, binary.LittleEndian.Uint32(data[length-4:]))
+		blocks = append(blocks, block{
+			typ:  binary.LittleEndian.Uint32(data),
+			body: data[8 : length-4],
+		})
+		data = data[length:]
+	}
+	return blocks
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf, LinkTypeEthernet, 96)
+
+	ts := time.Unix(1700000000, 123456789)
+	require.NoError(t, w.WritePacket(ts, []byte{1, 2, 3, 4, 5}, 60, "pod=mypod"))
+	require.NoError(t, w.WritePacket(ts.Add(time.Second), []byte{6, 7, 8, 9}, 4, ""))
+
+	blocks := readBlocks(t, buf.Bytes())
+	require.Len(t, blocks, 4)
+
+	require.Equal(t, uint32(blockTypeSectionHeader), blocks[0].typ)
+	require.Equal(t, uint32(byteOrderMagic), binary.LittleEndian.Uint32(blocks[0].body))
+	require.Equal(t, uint16(1), binary.LittleEndian.Uint16(blocks[0].body[4:]))
+
+	require.Equal(t, uint32(blockTypeInterfaceDesc), blocks[1].typ)
+	require.Equal(t, uint16(LinkTypeEthernet), binary.LittleEndian.Uint16(blocks[1].body))
+	require.Equal(t, uint32(96), binary.LittleEndian.Uint32(blocks[1].body[4:]))
+	// if_tsresol option set to nanoseconds
+	require.Equal(t, []byte{9, 0, 1, 0, 9, 0, 0, 0, 0, 0, 0, 0}, blocks[1].body[8:])
+
+	epb := blocks[2].body
+	require.Equal(t, uint32(blockTypeEnhancedPacket), blocks[2].typ)
+	require.Equal(t, uint32(0), binary.LittleEndian.Uint32(epb))
+	nsec := uint64(binary.LittleEndian.Uint32(epb[4:]))<<32 | uint64(binary.LittleEndian.Uint32(epb[8:]))
+	require.Equal(t, uint64(ts.UnixNano()), nsec)
+	require.Equal(t, uint32(5), binary.LittleEndian.Uint32(epb[12:]))
+	require.Equal(t, uint32(60), binary.LittleEndian.Uint32(epb[16:]))
+	require.Equal(t, []byte{1, 2, 3, 4, 5, 0, 0, 0}, epb[20:28])
+	// opt_comment and opt_endofopt
+	require.Equal(t, uint16(optionComment), binary.LittleEndian.Uint16(epb[28:]))
+	require.Equal(t, uint16(len("pod=mypod")), binary.LittleEndian.Uint16(epb[30:]))
+	require.Equal(t, "pod=mypod", string(epb[32:41]))
+	require.Equal(t, []byte{0, 0, 0, 0}, epb[len(epb)-4:])
+
+	// No options without comment
+	require.Equal(t, uint32(4), binary.LittleEndian.Uint32(blocks[3].body[12:]))
+	require.Len(t, blocks[3].body, 20+4)
+}
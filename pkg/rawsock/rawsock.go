@@ -16,6 +16,8 @@ package rawsock
 
 import (
 	"encoding/binary"
+	"fmt"
+	"net"
 	"runtime"
 	"syscall"
 	"unsafe"
@@ -40,6 +42,56 @@ func htons(i uint16) uint16 {
 // passed as parameter.
 // Returns the sock fd and an error.
 func OpenRawSock(pid uint32) (int, error) {
+	var sock int
+	err := inNetns(pid, func() error {
+		var err error
+		sock, err = openRawSock(0)
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+	return sock, nil
+}
+
+// OpenRawSocks opens a raw socket bound to each interface whose name matches
+// match in the network namespace used by the pid passed as parameter. It fails
+// if no interface matches.
+// Returns the sock fds and an error.
+func OpenRawSocks(pid uint32, match func(name string) bool) ([]int, error) {
+	var socks []int
+	err := inNetns(pid, func() error {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			return fmt.Errorf("listing interfaces: %w", err)
+		}
+		for _, iface := range ifaces {
+			if !match(iface.Name) {
+				continue
+			}
+			sock, err := openRawSock(iface.Index)
+			if err != nil {
+				return fmt.Errorf("opening raw socket on interface %q: %w", iface.Name, err)
+			}
+			socks = append(socks, sock)
+		}
+		if len(socks) == 0 {
+			return fmt.Errorf("no matching interface")
+		}
+		return nil
+	})
+	if err != nil {
+		for _, sock := range socks {
+			syscall.Close(sock)
+		}
+		return nil, err
+	}
+	return socks, nil
+}
+
+// inNetns calls f in the network namespace used by pid, or in the current one
+// if pid is 0
+func inNetns(pid uint32, f func() error) error {
 	if pid != 0 {
 		// Lock the OS Thread so we don't accidentally switch namespaces
 		runtime.LockOSThread()
@@ -51,27 +103,33 @@ func OpenRawSock(pid uint32) (int, error) {
 
 		netnsHandle, err := netns.GetFromPidWithAltProcfs(int(pid), host.HostProcFs)
 		if err != nil {
-			return -1, err
+			return err
 		}
 		defer netnsHandle.Close()
 		err = netns.Set(netnsHandle)
 		if err != nil {
-			return -1, err
+			return err
 		}
 
 		// Switch back to the original namespace
 		defer netns.Set(origns)
 	}
+	return f()
+}
 
+// openRawSock opens a raw socket bound to the interface with the given index,
+// 0 matches any interface
+func openRawSock(ifindex int) (int, error) {
 	sock, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, int(htons(syscall.ETH_P_ALL)))
 	if err != nil {
 		return -1, err
 	}
 	sll := syscall.SockaddrLinklayer{
-		Ifindex:  0, // 0 matches any interface
+		Ifindex:  ifindex,
 		Protocol: htons(syscall.ETH_P_ALL),
 	}
 	if err := syscall.Bind(sock, &sll); err != nil {
+		syscall.Close(sock)
 		return -1, err
 	}
 	return sock, nil
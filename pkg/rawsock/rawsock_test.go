@@ -0,0 +1,39 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rawsock
+
+import (
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenRawSocks(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("opening raw sockets requires root")
+	}
+
+	socks, err := OpenRawSocks(0, func(name string) bool { return name == "lo" })
+	require.NoError(t, err)
+	require.Len(t, socks, 1)
+	for _, sock := range socks {
+		syscall.Close(sock)
+	}
+
+	_, err = OpenRawSocks(0, func(name string) bool { return false })
+	require.ErrorContains(t, err, "no matching interface")
+}
@@ -21,5 +21,11 @@ import (
 //go:embed crd/bases/gadget.kinvolk.io_traces.yaml
 var TracesCustomResource string
 
+//go:embed crd/bases/gadget.kinvolk.io_gadgetinstances.yaml
+var GadgetInstancesCustomResource string
+
+//go:embed crd/bases/gadget.kinvolk.io_gadgetcatalogs.yaml
+var GadgetCatalogsCustomResource string
+
 //go:embed manifests/deploy.yaml
 var GadgetDeployment string
@@ -561,7 +561,15 @@ func (n *RuncNotifier) parseConmonCmdline(cmdlineArr []string) {
 	n.futureMu.Unlock()
 }
 
-func (n *RuncNotifier) parseOCIRuntime(comm string, cmdlineArr []string) {
+// procRootPath returns the path to access path in the mount namespace of the
+// process pid. Rootless container runtimes run in the mount namespace of the
+// user, where the runtime directories aren't necessarily the same as on the
+// host.
+func procRootPath(pid int, path string) string {
+	return filepath.Join(host.HostProcFs, strconv.Itoa(pid), "root", path)
+}
+
+func (n *RuncNotifier) parseOCIRuntime(comm string, pid int, cmdlineArr []string) {
 	// Parse oci-runtime (runc/crun) command line
 	createFound := false
 	startFound := false
@@ -606,7 +614,9 @@ func (n *RuncNotifier) parseOCIRuntime(comm string, cmdlineArr []string) {
 			log.Warnf("cannot lookup container for %s\n", containerID)
 			return
 		}
-		bundleConfigJSON, err := os.ReadFile(filepath.Join(fc.bundleDir, "config.json"))
+		// The paths given to conmon are only valid in the mount namespace of
+		// the runtime, see procRootPath
+		bundleConfigJSON, err := os.ReadFile(procRootPath(pid, filepath.Join(fc.bundleDir, "config.json")))
 		if err != nil {
 			log.Errorf("error reading bundle config: %v\n", err)
 			return
@@ -618,7 +628,7 @@ func (n *RuncNotifier) parseOCIRuntime(comm string, cmdlineArr []string) {
 			return
 		}
 
-		pidFileContent, err := os.ReadFile(fc.pidFile)
+		pidFileContent, err := os.ReadFile(procRootPath(pid, fc.pidFile))
 		if err != nil {
 			log.Errorf("error reading pid file: %v\n", err)
 			return
@@ -695,7 +705,7 @@ func (n *RuncNotifier) watchRuncIterate() (bool, error) {
 		// Also, the calling sequence is podman -> conmon -> runc
 		n.parseConmonCmdline(cmdlineArr)
 	case "runc", "crun":
-		n.parseOCIRuntime(comm, cmdlineArr)
+		n.parseOCIRuntime(comm, pid, cmdlineArr)
 	default:
 		return false, nil
 	}
@@ -24,14 +24,17 @@ import (
 	"math"
 	"net"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -43,6 +46,7 @@ import (
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/utils/tlsconfig"
 )
 
 type ConnectionMode int
@@ -60,9 +64,21 @@ const (
 
 const (
 	ParamNode              = "node"
+	ParamNodeSelector      = "node-selector"
+	ParamContexts          = "contexts"
 	ParamRemoteAddress     = "remote-address"
 	ParamConnectionMethod  = "connection-method"
 	ParamConnectionTimeout = "connection-timeout"
+	ParamTLSCertFile       = "tls-cert-file"
+	ParamTLSKeyFile        = "tls-key-file"
+	ParamTLSCAFile         = "tls-ca-file"
+	ParamTLSServerName     = "tls-server-name"
+	ParamTokenFile         = "token-file"
+
+	// remoteParamPrefix is prepended to the parameters used to connect
+	// directly to remote ig daemons in Kubernetes connection mode, to
+	// distinguish them from the ones used to connect to the cluster
+	remoteParamPrefix = "remote-"
 
 	// ParamGadgetServiceTCPPort is only used in combination with KubernetesProxyConnectionMethodTCP
 	ParamGadgetServiceTCPPort = "tcp-port"
@@ -79,12 +95,17 @@ const (
 	DefaultGadgetNamespace string = "gadget"
 )
 
+// RestConfigGetter returns the configuration to connect to the cluster of the
+// given kubeconfig context
+type RestConfigGetter func(kubeContext string) (*rest.Config, error)
+
 type Runtime struct {
-	info           *deployinfo.DeployInfo
-	defaultValues  map[string]string
-	globalParams   *params.Params
-	restConfig     *rest.Config
-	connectionMode ConnectionMode
+	info             *deployinfo.DeployInfo
+	defaultValues    map[string]string
+	globalParams     *params.Params
+	restConfig       *rest.Config
+	restConfigGetter RestConfigGetter
+	connectionMode   ConnectionMode
 }
 
 type RunClient interface {
@@ -117,6 +138,12 @@ func (r *Runtime) SetRestConfig(config *rest.Config) {
 	r.restConfig = config
 }
 
+// SetRestConfigGetter sets the function used to connect to other clusters
+// when running gadgets on several kubeconfig contexts
+func (r *Runtime) SetRestConfigGetter(getter RestConfigGetter) {
+	r.restConfigGetter = getter
+}
+
 func (r *Runtime) Close() error {
 	return nil
 }
@@ -147,6 +174,21 @@ func (r *Runtime) ParamDescs() params.ParamDescs {
 				Description: "Comma-separated list of nodes to run the gadget on",
 				Validator:   checkForDuplicates("node"),
 			},
+			{
+				Key:         ParamNodeSelector,
+				Description: "Label selector to choose the nodes to run the gadget on (e.g. key1=value1,key2=value2). If used together with --node, only the listed nodes matching the selector are used",
+				Validator:   validateNodeSelector,
+			},
+			{
+				Key:         ParamContexts,
+				Description: "Comma-separated list of kubeconfig contexts to run the gadget on. Events of all clusters are merged and tagged with the name of the context",
+				Validator:   checkForDuplicates("context"),
+			},
+			{
+				Key:         ParamRemoteAddress,
+				Description: "Comma-separated list of addresses of ig daemons running outside of Kubernetes (e.g. tcp://vm1:1234) to run the gadget on, in addition to the nodes",
+				Validator:   checkForDuplicates("address"),
+			},
 		}...)
 		return p
 	}
@@ -172,8 +214,11 @@ func (r *Runtime) GlobalParamDescs() params.ParamDescs {
 				Validator:    checkForDuplicates("address"),
 			},
 		}...)
+		p.Add(remoteParamDescs("")...)
 		return p
 	case ConnectionModeKubernetesProxy:
+		// Used to connect to the targets given with --remote-address
+		p.Add(remoteParamDescs(remoteParamPrefix)...)
 		p.Add(params.ParamDescs{
 			{
 				Key:          ParamGadgetServiceTCPPort,
@@ -193,9 +238,89 @@ func (r *Runtime) GlobalParamDescs() params.ParamDescs {
 	panic("invalid connection mode set for grpc-runtime")
 }
 
+// remoteParamDescs returns the parameters used to connect directly to remote
+// ig daemons, with their keys prefixed by prefix
+func remoteParamDescs(prefix string) params.ParamDescs {
+	return params.ParamDescs{
+		{
+			Key:         prefix + ParamTLSCertFile,
+			Description: "Client certificate used to connect to the remote using TLS",
+		},
+		{
+			Key:         prefix + ParamTLSKeyFile,
+			Description: "Key of the client certificate used to connect to the remote using TLS",
+		},
+		{
+			Key:         prefix + ParamTLSCAFile,
+			Description: "CA certificates used to verify the certificate of the remote. Connections use TLS if set",
+		},
+		{
+			Key:         prefix + ParamTLSServerName,
+			Description: "Name used to verify the certificate of the remote, by default the host of the remote address",
+		},
+		{
+			Key:         prefix + ParamTokenFile,
+			Description: "File containing the bearer token used to authenticate to the remote. Requires TLS, except for unix sockets",
+		},
+	}
+}
+
+func validateNodeSelector(value string) error {
+	if _, err := labels.Parse(value); err != nil {
+		return fmt.Errorf("invalid node selector: %w", err)
+	}
+	return nil
+}
+
+// getNodesBySelector returns the names of the nodes matching nodeSelector. If nodes is not empty,
+// only the nodes in that list are considered.
+func getNodesBySelector(ctx context.Context, config *rest.Config, nodes []string, nodeSelector string) ([]string, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("setting up kubernetes client: %w", err)
+	}
+
+	nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: nodeSelector})
+	if err != nil {
+		return nil, fmt.Errorf("listing nodes: %w", err)
+	}
+
+	res := make([]string, 0, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		if len(nodes) > 0 && !slices.Contains(nodes, node.Name) {
+			continue
+		}
+		res = append(res, node.Name)
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no nodes match the node selector %q", nodeSelector)
+	}
+
+	return res, nil
+}
+
 type target struct {
 	addressOrPod string
 	node         string
+
+	// cluster is the kubeconfig context of the target, only set when running
+	// on several clusters
+	cluster string
+	// restConfig is used to connect to the target in Kubernetes connection mode
+	restConfig *rest.Config
+	// direct is set for targets that are connected to directly instead of
+	// through the Kubernetes API server, like ig daemons running outside of
+	// Kubernetes
+	direct bool
+}
+
+// name returns the name of the target used to identify its results and logs
+func (t *target) name() string {
+	if t.cluster != "" {
+		return t.cluster + "/" + t.node
+	}
+	return t.node
 }
 
 func getGadgetPods(ctx context.Context, config *rest.Config, nodes []string, gadgetNamespace string) ([]target, error) {
@@ -218,7 +343,7 @@ func getGadgetPods(ctx context.Context, config *rest.Config, nodes []string, gad
 		res := make([]target, 0, len(pods.Items))
 
 		for _, pod := range pods.Items {
-			res = append(res, target{addressOrPod: pod.Name, node: pod.Spec.NodeName})
+			res = append(res, target{addressOrPod: pod.Name, node: pod.Spec.NodeName, restConfig: config})
 		}
 
 		return res, nil
@@ -229,7 +354,7 @@ nodesLoop:
 	for _, node := range nodes {
 		for _, pod := range pods.Items {
 			if node == pod.Spec.NodeName {
-				res = append(res, target{addressOrPod: pod.Name, node: node})
+				res = append(res, target{addressOrPod: pod.Name, node: node, restConfig: config})
 				continue nodesLoop
 			}
 		}
@@ -239,40 +364,104 @@ nodesLoop:
 	return res, nil
 }
 
+// getClusterTargets returns the gadget pods of the cluster described by config
+// that run on the requested nodes
+func (r *Runtime) getClusterTargets(ctx context.Context, config *rest.Config, params *params.Params) ([]target, error) {
+	// Get nodes to run on
+	nodes := params.Get(ParamNode).AsStringSlice()
+	if nodeSelector := params.Get(ParamNodeSelector).AsString(); nodeSelector != "" {
+		var err error
+		nodes, err = getNodesBySelector(ctx, config, nodes, nodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("get nodes: %w", err)
+		}
+	}
+	gadgetNamespace := r.globalParams.Get(ParamGadgetNamespace).AsString()
+	pods, err := getGadgetPods(ctx, config, nodes, gadgetNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("get gadget pods: %w", err)
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("get gadget pods: Inspektor Gadget is not running on the requested node(s): %v", nodes)
+	}
+	return pods, nil
+}
+
+// getMultiClusterTargets returns the gadget pods of all the clusters of the
+// given kubeconfig contexts
+func (r *Runtime) getMultiClusterTargets(ctx context.Context, kubeContexts []string, params *params.Params) ([]target, error) {
+	if r.restConfigGetter == nil {
+		return nil, fmt.Errorf("running on several clusters is not supported")
+	}
+	if len(params.Get(ParamNode).AsStringSlice()) > 0 {
+		return nil, fmt.Errorf("--%s can't be used together with --%s, use --%s instead",
+			ParamNode, ParamContexts, ParamNodeSelector)
+	}
+
+	var targets []target
+	for _, kubeContext := range kubeContexts {
+		config, err := r.restConfigGetter(kubeContext)
+		if err != nil {
+			return nil, fmt.Errorf("getting config for context %q: %w", kubeContext, err)
+		}
+		clusterTargets, err := r.getClusterTargets(ctx, config, params)
+		if err != nil {
+			return nil, fmt.Errorf("context %q: %w", kubeContext, err)
+		}
+		for i := range clusterTargets {
+			clusterTargets[i].cluster = kubeContext
+		}
+		targets = append(targets, clusterTargets...)
+	}
+	return targets, nil
+}
+
+// getRemoteTargets returns the targets of the given addresses of ig daemons,
+// which are connected to directly
+func getRemoteTargets(addresses []string) ([]target, error) {
+	targets := make([]target, 0, len(addresses))
+	for _, t := range addresses {
+		purl, err := url.Parse(t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote address %q: %w", t, err)
+		}
+		tg := target{
+			addressOrPod: purl.Host,
+			node:         purl.Hostname(),
+			direct:       true,
+		}
+		if purl.Scheme == "unix" {
+			// use the whole url in case of a unix socket and "local" as node
+			tg.addressOrPod = t
+			tg.node = "local"
+		}
+		targets = append(targets, tg)
+	}
+	return targets, nil
+}
+
 func (r *Runtime) getTargets(ctx context.Context, params *params.Params) ([]target, error) {
 	switch r.connectionMode {
 	case ConnectionModeKubernetesProxy:
-		// Get nodes to run on
-		nodes := params.Get(ParamNode).AsStringSlice()
-		gadgetNamespace := r.globalParams.Get(ParamGadgetNamespace).AsString()
-		pods, err := getGadgetPods(ctx, r.restConfig, nodes, gadgetNamespace)
+		var targets []target
+		var err error
+		if kubeContexts := params.Get(ParamContexts).AsStringSlice(); len(kubeContexts) > 0 {
+			targets, err = r.getMultiClusterTargets(ctx, kubeContexts, params)
+		} else {
+			targets, err = r.getClusterTargets(ctx, r.restConfig, params)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("get gadget pods: %w", err)
+			return nil, err
 		}
-		if len(pods) == 0 {
-			return nil, fmt.Errorf("get gadget pods: Inspektor Gadget is not running on the requested node(s): %v", nodes)
+
+		// Add the ig daemons running outside of Kubernetes
+		remoteTargets, err := getRemoteTargets(params.Get(ParamRemoteAddress).AsStringSlice())
+		if err != nil {
+			return nil, err
 		}
-		return pods, nil
+		return append(targets, remoteTargets...), nil
 	case ConnectionModeDirect:
-		inTargets := r.globalParams.Get(ParamRemoteAddress).AsStringSlice()
-		targets := make([]target, 0)
-		for _, t := range inTargets {
-			purl, err := url.Parse(t)
-			if err != nil {
-				return nil, fmt.Errorf("invalid remote address %q: %w", t, err)
-			}
-			tg := target{
-				addressOrPod: purl.Host,
-				node:         purl.Hostname(),
-			}
-			if purl.Scheme == "unix" {
-				// use the whole url in case of a unix socket and "local" as node
-				tg.addressOrPod = t
-				tg.node = "local"
-			}
-			targets = append(targets, tg)
-		}
-		return targets, nil
+		return getRemoteTargets(r.globalParams.Get(ParamRemoteAddress).AsStringSlice())
 	}
 	return nil, fmt.Errorf("unsupported connection mode")
 }
@@ -332,9 +521,42 @@ func (r *Runtime) RunGadget(gadgetCtx runtime.GadgetContext) (runtime.CombinedGa
 	if err != nil {
 		return nil, fmt.Errorf("getting target nodes: %w", err)
 	}
+	if err := checkKubernetesFilters(targets, gadgetCtx.OperatorsParamCollection()); err != nil {
+		return nil, err
+	}
 	return r.runGadgetOnTargets(gadgetCtx, paramMap, targets)
 }
 
+const (
+	// Keep in sync with pkg/operators/kubemanager, which isn't imported to
+	// avoid depending on it in the clients
+	kubeManagerOperatorName = "KubeManager"
+)
+
+// kubernetesFilterParams are the parameters of the KubeManager operator that
+// ig daemons running outside of Kubernetes can't apply
+var kubernetesFilterParams = []string{"namespace", "podname", "selector", "namespace-selector"}
+
+// checkKubernetesFilters returns an error if Kubernetes filters are set while
+// running on targets connected to directly, as those would silently ignore
+// them and send the events of all containers
+func checkKubernetesFilters(targets []target, operatorParams params.Collection) error {
+	if !slices.ContainsFunc(targets, func(t target) bool { return t.direct }) {
+		return nil
+	}
+	kubeManagerParams, ok := operatorParams[kubeManagerOperatorName]
+	if !ok {
+		return nil
+	}
+	for _, key := range kubernetesFilterParams {
+		if p := kubeManagerParams.Get(key); p != nil && p.IsSet() {
+			return fmt.Errorf("--%s can't be used with --%s: it isn't supported by ig daemons running outside of Kubernetes",
+				key, ParamRemoteAddress)
+		}
+	}
+	return nil
+}
+
 func (r *Runtime) getConnToRandomTarget(ctx context.Context, runtimeParams *params.Params) (*grpc.ClientConn, error) {
 	targets, err := r.getTargets(ctx, runtimeParams)
 	if err != nil {
@@ -372,6 +594,14 @@ func (r *Runtime) runGadgetOnTargets(
 		gType = gadgetCtx.GadgetDesc().Type()
 	}
 
+	// Results of all nodes are merged and sorted globally by the parser. The limit of rows is
+	// sent to the nodes, so they only send their first entries, and applied to the combined result
+	// as well, so the output is a single ranked list instead of maxRows entries per node.
+	maxRows := combinedMaxRows(gadgetCtx, gType)
+	if maxRows > 0 {
+		gadgetCtx.Parser().SetMaxRows(int(maxRows))
+	}
+
 	if gType == gadgets.TypeTraceIntervals {
 		gadgetCtx.Parser().EnableSnapshots(
 			gadgetCtx.Context(),
@@ -393,10 +623,10 @@ func (r *Runtime) runGadgetOnTargets(
 	for _, t := range targets {
 		wg.Add(1)
 		go func(target target) {
-			gadgetCtx.Logger().Debugf("running gadget on node %q", target.node)
-			res, err := r.runGadget(gadgetCtx, target, paramMap)
+			gadgetCtx.Logger().Debugf("running gadget on node %q", target.name())
+			res, err := r.runGadget(gadgetCtx, target, paramMap, maxRows)
 			resultsLock.Lock()
-			results[target.node] = &runtime.GadgetResult{
+			results[target.name()] = &runtime.GadgetResult{
 				Payload: res,
 				Error:   err,
 			}
@@ -409,19 +639,45 @@ func (r *Runtime) runGadgetOnTargets(
 	return results, results.Err()
 }
 
+// combinedMaxRows returns the limit of rows of the results of gadgets of type gType, or 0 if they
+// aren't limited. Snapshot gadgets are only limited if it's explicitly requested.
+func combinedMaxRows(gadgetCtx runtime.GadgetContext, gType gadgets.GadgetType) uint32 {
+	maxRows := gadgetCtx.GadgetParams().Get(gadgets.ParamMaxRows)
+	if maxRows == nil || gadgetCtx.Parser() == nil {
+		return 0
+	}
+	if gType == gadgets.TypeTraceIntervals || (gType == gadgets.TypeOneShot && maxRows.IsSet()) {
+		return maxRows.AsUint32()
+	}
+	return 0
+}
+
 func (r *Runtime) dialContext(dialCtx context.Context, target target, timeout time.Duration) (*grpc.ClientConn, error) {
+	transportCredentials, err := r.transportCredentials(target)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(transportCredentials),
 		grpc.WithBlock(),
 	}
 
 	// If we're in Kubernetes connection mode, we need a custom dialer
-	if r.connectionMode == ConnectionModeKubernetesProxy {
+	if r.connectionMode == ConnectionModeKubernetesProxy && !target.direct {
 		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
 			port := r.globalParams.Get(ParamGadgetServiceTCPPort).AsUint16()
 			gadgetNamespace := r.globalParams.Get(ParamGadgetNamespace).AsString()
-			return NewK8SPortFwdConn(ctx, r.restConfig, gadgetNamespace, target, port, timeout)
+			return NewK8SPortFwdConn(ctx, target.restConfig, gadgetNamespace, target, port, timeout)
 		}))
+		opts = append(opts, grpc.WithPerRPCCredentials(&k8sCredentials{config: target.restConfig}))
+	} else if tokenFile := r.remoteParam(ParamTokenFile); tokenFile != "" {
+		tokenCredentials := newTokenFileCredentials(tokenFile, target)
+		if tokenCredentials.requireTLS && transportCredentials.Info().SecurityProtocol != "tls" {
+			return nil, fmt.Errorf("sending a token to %q requires TLS, set a CA file to enable it",
+				target.addressOrPod)
+		}
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials))
 	}
 
 	conn, err := grpc.DialContext(dialCtx, "passthrough:///"+target.addressOrPod, opts...)
@@ -431,7 +687,57 @@ func (r *Runtime) dialContext(dialCtx context.Context, target target, timeout ti
 	return conn, nil
 }
 
-func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allParams map[string]string) ([]byte, error) {
+// transportCredentials returns the credentials used to connect to target. TLS
+// is only used for targets connected to directly if configured, connections
+// through the Kubernetes API server are already secured by it.
+func (r *Runtime) transportCredentials(target target) (credentials.TransportCredentials, error) {
+	if !target.direct {
+		return insecure.NewCredentials(), nil
+	}
+
+	certFile := r.remoteParam(ParamTLSCertFile)
+	keyFile := r.remoteParam(ParamTLSKeyFile)
+	caFile := r.remoteParam(ParamTLSCAFile)
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	tlsConfig, err := tlsconfig.NewClientConfig(certFile, keyFile, caFile, r.remoteParam(ParamTLSServerName))
+	if err != nil {
+		return nil, fmt.Errorf("creating TLS config: %w", err)
+	}
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// remoteParam returns the value of the global parameter key used to connect
+// directly to remote ig daemons
+func (r *Runtime) remoteParam(key string) string {
+	if r.connectionMode == ConnectionModeKubernetesProxy {
+		key = remoteParamPrefix + key
+	}
+	return r.globalParams.Get(key).AsString()
+}
+
+// targetEnrichers returns the enrichers tagging the events like ev with the
+// node and the cluster of target
+func targetEnrichers(ev any, target target) []func(any) error {
+	var enrichers []func(any) error
+	if _, ok := ev.(operators.NodeSetter); ok {
+		enrichers = append(enrichers, func(ev any) error {
+			ev.(operators.NodeSetter).SetNode(target.node)
+			return nil
+		})
+	}
+	if _, ok := ev.(operators.ClusterSetter); ok && target.cluster != "" {
+		enrichers = append(enrichers, func(ev any) error {
+			ev.(operators.ClusterSetter).SetCluster(target.cluster)
+			return nil
+		})
+	}
+	return enrichers
+}
+
+func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allParams map[string]string, maxRows uint32) ([]byte, error) {
 	// Notice that we cannot use gadgetCtx.Context() here, as that would - when cancelled by the user - also cancel the
 	// underlying gRPC connection. That would then lead to results not being received anymore (mostly for profile
 	// gadgets.)
@@ -444,7 +750,7 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 
 	conn, err := r.dialContext(dialCtx, target, timeout)
 	if err != nil {
-		return nil, fmt.Errorf("dialing target on node %q: %w", target.node, err)
+		return nil, fmt.Errorf("dialing target on node %q: %w", target.name(), err)
 	}
 	defer conn.Close()
 	client := api.NewGadgetManagerClient(conn)
@@ -458,6 +764,7 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 		FanOut:         false,
 		LogLevel:       uint32(gadgetCtx.Logger().GetLevel()),
 		Timeout:        int64(gadgetCtx.Timeout()),
+		MaxRows:        maxRows,
 	}
 
 	runClient, err := client.RunGadget(connCtx)
@@ -477,17 +784,9 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 	jsonArrayHandler := func([]byte) {}
 
 	if parser != nil {
-		var enrichers []func(any) error
-		ev := gadgetCtx.GadgetDesc().EventPrototype()
-		if _, ok := ev.(operators.NodeSetter); ok {
-			enrichers = append(enrichers, func(ev any) error {
-				ev.(operators.NodeSetter).SetNode(target.node)
-				return nil
-			})
-		}
-
+		enrichers := targetEnrichers(gadgetCtx.GadgetDesc().EventPrototype(), target)
 		jsonHandler = parser.JSONHandlerFunc(enrichers...)
-		jsonArrayHandler = parser.JSONHandlerFuncArray(target.node, enrichers...)
+		jsonArrayHandler = parser.JSONHandlerFuncArray(target.name(), enrichers...)
 	}
 
 	doneChan := make(chan error)
@@ -499,7 +798,14 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 		for {
 			ev, err := runClient.Recv()
 			if err != nil {
-				gadgetCtx.Logger().Debugf("%-20s | runClient returned with %v", target.node, err)
+				gadgetCtx.Logger().Debugf("%-20s | runClient returned with %v", target.name(), err)
+				if verifierLog, ok := api.VerifierLogFromError(err); ok {
+					header := "verifier log"
+					if verifierLog.Truncated {
+						header += " (truncated)"
+					}
+					gadgetCtx.Logger().Errorf("%-20s | %s:\n%s", target.name(), header, strings.Join(verifierLog.Lines, "\n"))
+				}
 				if !errors.Is(err, io.EOF) {
 					doneChan <- err
 					return
@@ -510,7 +816,7 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 			switch ev.Type {
 			case api.EventTypeGadgetPayload:
 				if expectedSeq != ev.Seq {
-					gadgetCtx.Logger().Warnf("%-20s | expected seq %d, got %d, %d messages dropped", target.node, expectedSeq, ev.Seq, ev.Seq-expectedSeq)
+					gadgetCtx.Logger().Warnf("%-20s | expected seq %d, got %d, %d messages dropped", target.name(), expectedSeq, ev.Seq, ev.Seq-expectedSeq)
 				}
 				expectedSeq = ev.Seq + 1
 				if len(ev.Payload) > 0 && ev.Payload[0] == '[' {
@@ -519,12 +825,12 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 				}
 				jsonHandler(ev.Payload)
 			case api.EventTypeGadgetResult:
-				gadgetCtx.Logger().Debugf("%-20s | got result from server", target.node)
+				gadgetCtx.Logger().Debugf("%-20s | got result from server", target.name())
 				result = ev.Payload
 			case api.EventTypeGadgetJobID: // not needed right now
 			default:
 				if ev.Type >= 1<<api.EventLogShift {
-					gadgetCtx.Logger().Log(logger.Level(ev.Type>>api.EventLogShift), fmt.Sprintf("%-20s | %s", target.node, string(ev.Payload)))
+					gadgetCtx.Logger().Log(logger.Level(ev.Type>>api.EventLogShift), fmt.Sprintf("%-20s | %s", target.name(), string(ev.Payload)))
 					continue
 				}
 				gadgetCtx.Logger().Warnf("unknown payload type %d: %s", ev.Type, ev.Payload)
@@ -535,18 +841,18 @@ func (r *Runtime) runGadget(gadgetCtx runtime.GadgetContext, target target, allP
 	var runErr error
 	select {
 	case doneErr := <-doneChan:
-		gadgetCtx.Logger().Debugf("%-20s | done from server side (%v)", target.node, doneErr)
+		gadgetCtx.Logger().Debugf("%-20s | done from server side (%v)", target.name(), doneErr)
 		runErr = doneErr
 	case <-gadgetCtx.Context().Done():
 		// Send stop request
-		gadgetCtx.Logger().Debugf("%-20s | sending stop request", target.node)
+		gadgetCtx.Logger().Debugf("%-20s | sending stop request", target.name())
 		controlRequest := &api.GadgetControlRequest{Event: &api.GadgetControlRequest_StopRequest{StopRequest: &api.GadgetStopRequest{}}}
 		runClient.Send(controlRequest)
 
 		// Wait for done or timeout
 		select {
 		case doneErr := <-doneChan:
-			gadgetCtx.Logger().Debugf("%-20s | done after cancel request (%v)", target.node, doneErr)
+			gadgetCtx.Logger().Debugf("%-20s | done after cancel request (%v)", target.name(), doneErr)
 			runErr = doneErr
 		case <-time.After(ResultTimeout * time.Second):
 			return nil, fmt.Errorf("timed out while getting result")
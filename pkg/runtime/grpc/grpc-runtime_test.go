@@ -0,0 +1,397 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/rest"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	eventtypes "github.com/inspektor-gadget/inspektor-gadget/pkg/types"
+)
+
+func TestGetRemoteTargets(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		addresses       []string
+		expectedTargets []target
+		expectedErr     bool
+	}
+
+	tests := map[string]testDefinition{
+		"tcp": {
+			addresses: []string{"tcp://vm1:1234", "tcp://10.0.0.2:1234"},
+			expectedTargets: []target{
+				{addressOrPod: "vm1:1234", node: "vm1", direct: true},
+				{addressOrPod: "10.0.0.2:1234", node: "10.0.0.2", direct: true},
+			},
+		},
+		"unix": {
+			addresses: []string{"unix:///run/gadgetservice.socket"},
+			expectedTargets: []target{
+				{addressOrPod: "unix:///run/gadgetservice.socket", node: "local", direct: true},
+			},
+		},
+		"invalid": {
+			addresses:   []string{"://vm1:1234"},
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			targets, err := getRemoteTargets(test.addresses)
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedTargets, targets)
+		})
+	}
+}
+
+// writeTestCA writes a self-signed CA certificate to a file and returns its
+// path
+func writeTestCA(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return path
+}
+
+func newTestRuntime(t *testing.T, options []Option, values map[string]string) *Runtime {
+	r := New(options...)
+	globalParams := r.GlobalParamDescs().ToParams()
+	for k, v := range values {
+		require.NoError(t, globalParams.Set(k, v))
+	}
+	require.NoError(t, r.Init(globalParams))
+	return r
+}
+
+func TestTransportCredentials(t *testing.T) {
+	t.Parallel()
+
+	caFile := writeTestCA(t)
+	direct := target{addressOrPod: "vm1:1234", node: "vm1", direct: true}
+	pod := target{addressOrPod: "gadget-abcde", node: "node1"}
+
+	type testDefinition struct {
+		options          []Option
+		values           map[string]string
+		target           target
+		expectedProtocol string
+	}
+
+	tests := map[string]testDefinition{
+		"direct_without_tls": {
+			target:           direct,
+			expectedProtocol: "insecure",
+		},
+		"direct_with_tls": {
+			values:           map[string]string{ParamTLSCAFile: caFile},
+			target:           direct,
+			expectedProtocol: "tls",
+		},
+		"k8s_remote_with_tls": {
+			options:          []Option{WithConnectUsingK8SProxy},
+			values:           map[string]string{remoteParamPrefix + ParamTLSCAFile: caFile},
+			target:           direct,
+			expectedProtocol: "tls",
+		},
+		"k8s_pod": {
+			options:          []Option{WithConnectUsingK8SProxy},
+			values:           map[string]string{remoteParamPrefix + ParamTLSCAFile: caFile},
+			target:           pod,
+			expectedProtocol: "insecure",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newTestRuntime(t, test.options, test.values)
+			creds, err := r.transportCredentials(test.target)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedProtocol, creds.Info().SecurityProtocol)
+		})
+	}
+}
+
+func TestTokenRequiresTLS(t *testing.T) {
+	t.Parallel()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret"), 0o600))
+
+	r := newTestRuntime(t, nil, map[string]string{ParamTokenFile: tokenFile})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := r.dialContext(ctx, target{addressOrPod: "vm1:1234", node: "vm1", direct: true}, time.Second)
+	require.ErrorContains(t, err, "requires TLS")
+}
+
+func TestCheckKubernetesFilters(t *testing.T) {
+	t.Parallel()
+
+	newCollection := func(key, value string) params.Collection {
+		descs := params.ParamDescs{}
+		for _, k := range kubernetesFilterParams {
+			descs = append(descs, &params.ParamDesc{Key: k})
+		}
+		p := descs.ToParams()
+		if key != "" {
+			require.NoError(t, p.Set(key, value))
+		}
+		return params.Collection{kubeManagerOperatorName: p}
+	}
+
+	pod := target{addressOrPod: "gadget-abcde", node: "node1"}
+	direct := target{addressOrPod: "vm1:1234", node: "vm1", direct: true}
+
+	require.NoError(t, checkKubernetesFilters([]target{pod}, newCollection("podname", "mypod")))
+	require.NoError(t, checkKubernetesFilters([]target{pod, direct}, newCollection("", "")))
+	require.NoError(t, checkKubernetesFilters([]target{direct}, params.Collection{}))
+	require.Error(t, checkKubernetesFilters([]target{pod, direct}, newCollection("podname", "mypod")))
+	require.Error(t, checkKubernetesFilters([]target{direct}, newCollection("namespace", "default")))
+}
+
+func TestTargetEnrichers(t *testing.T) {
+	t.Parallel()
+
+	ev := &eventtypes.Event{}
+	for _, enricher := range targetEnrichers(ev, target{node: "vm1"}) {
+		require.NoError(t, enricher(ev))
+	}
+	require.Equal(t, "vm1", ev.K8s.Node)
+	require.Empty(t, ev.K8s.Cluster)
+
+	ev = &eventtypes.Event{}
+	for _, enricher := range targetEnrichers(ev, target{node: "node1", cluster: "prod"}) {
+		require.NoError(t, enricher(ev))
+	}
+	require.Equal(t, "node1", ev.K8s.Node)
+	require.Equal(t, "prod", ev.K8s.Cluster)
+
+	// Events without the node or the cluster aren't tagged
+	require.Empty(t, targetEnrichers(struct{}{}, target{node: "vm1"}))
+}
+
+// newFakeCluster starts an API server serving the gadget pods running on the
+// given nodes
+func newFakeCluster(t *testing.T, nodes ...string) *rest.Config {
+	podList := corev1.PodList{
+		TypeMeta: metav1.TypeMeta{Kind: "PodList", APIVersion: "v1"},
+	}
+	for i, node := range nodes {
+		podList.Items = append(podList.Items, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("gadget-%d", i), Namespace: DefaultGadgetNamespace},
+			Spec:       corev1.PodSpec{NodeName: node},
+		})
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/"+DefaultGadgetNamespace+"/pods" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(podList)
+	}))
+	t.Cleanup(server.Close)
+
+	return &rest.Config{Host: server.URL}
+}
+
+// newFakeNodesCluster starts an API server serving the given nodes, identified by
+// their name and labels, filtered by the label selector of the request
+func newFakeNodesCluster(t *testing.T, nodes map[string]map[string]string) *rest.Config {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/nodes" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Message:  err.Error(),
+				Reason:   metav1.StatusReasonBadRequest,
+				Code:     http.StatusBadRequest,
+			})
+			return
+		}
+
+		nodeList := corev1.NodeList{
+			TypeMeta: metav1.TypeMeta{Kind: "NodeList", APIVersion: "v1"},
+		}
+		for name, nodeLabels := range nodes {
+			if !selector.Matches(labels.Set(nodeLabels)) {
+				continue
+			}
+			nodeList.Items = append(nodeList.Items, corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nodeLabels},
+			})
+		}
+		json.NewEncoder(w).Encode(nodeList)
+	}))
+	t.Cleanup(server.Close)
+
+	return &rest.Config{Host: server.URL}
+}
+
+func TestGetNodesBySelector(t *testing.T) {
+	t.Parallel()
+
+	config := newFakeNodesCluster(t, map[string]map[string]string{
+		"node1": {"pool": "gpu", "zone": "a"},
+		"node2": {"pool": "gpu", "zone": "b"},
+		"node3": {"pool": "cpu", "zone": "a"},
+	})
+
+	type testDefinition struct {
+		nodes         []string
+		nodeSelector  string
+		expectedNodes []string
+		expectedErr   string
+	}
+
+	tests := map[string]testDefinition{
+		"matching": {
+			nodeSelector:  "pool=gpu",
+			expectedNodes: []string{"node1", "node2"},
+		},
+		"matching_set_based": {
+			nodeSelector:  "zone in (a),pool!=gpu",
+			expectedNodes: []string{"node3"},
+		},
+		"matching_restricted_to_nodes": {
+			nodes:         []string{"node2", "node3"},
+			nodeSelector:  "pool=gpu",
+			expectedNodes: []string{"node2"},
+		},
+		"non_matching": {
+			nodeSelector: "pool=tpu",
+			expectedErr:  `no nodes match the node selector "pool=tpu"`,
+		},
+		"non_matching_nodes": {
+			nodes:        []string{"node3"},
+			nodeSelector: "pool=gpu",
+			expectedErr:  `no nodes match the node selector "pool=gpu"`,
+		},
+		"invalid": {
+			nodeSelector: "pool in (gpu",
+			expectedErr:  "listing nodes",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes, err := getNodesBySelector(context.Background(), config, test.nodes, test.nodeSelector)
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.ElementsMatch(t, test.expectedNodes, nodes)
+		})
+	}
+}
+
+func TestGetMultiClusterTargets(t *testing.T) {
+	t.Parallel()
+
+	clusters := map[string]*rest.Config{
+		"prod":    newFakeCluster(t, "node1", "node2"),
+		"staging": newFakeCluster(t, "node1"),
+	}
+
+	r := newTestRuntime(t, []Option{WithConnectUsingK8SProxy}, nil)
+	r.SetRestConfigGetter(func(kubeContext string) (*rest.Config, error) {
+		config, ok := clusters[kubeContext]
+		if !ok {
+			return nil, fmt.Errorf("context %q not found", kubeContext)
+		}
+		return config, nil
+	})
+
+	runtimeParams := r.ParamDescs().ToParams()
+
+	targets, err := r.getMultiClusterTargets(context.Background(), []string{"prod", "staging"}, runtimeParams)
+	require.NoError(t, err)
+
+	names := []string{}
+	for _, target := range targets {
+		names = append(names, target.name())
+	}
+	require.ElementsMatch(t, []string{"prod/node1", "prod/node2", "staging/node1"}, names)
+
+	_, err = r.getMultiClusterTargets(context.Background(), []string{"prod", "unknown"}, runtimeParams)
+	require.ErrorContains(t, err, "unknown")
+
+	// --node can't be used with several clusters
+	require.NoError(t, runtimeParams.Set(ParamNode, "node1"))
+	_, err = r.getMultiClusterTargets(context.Background(), []string{"prod", "staging"}, runtimeParams)
+	require.ErrorContains(t, err, ParamNode)
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"k8s.io/client-go/rest"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// k8sCredentials sends the authorization header used with the Kubernetes API
+// server along with each gRPC request, so that the gadget service can
+// authorize the requests using the RBAC permissions of the user
+type k8sCredentials struct {
+	config *rest.Config
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func (c *k8sCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	header, err := authorizationHeader(ctx, c.config)
+	if err != nil {
+		return nil, fmt.Errorf("getting authorization header: %w", err)
+	}
+	if header == "" {
+		return nil, nil
+	}
+	return map[string]string{api.AuthorizationMetadataKey: header}, nil
+}
+
+// RequireTransportSecurity returns false as the connection to the gadget
+// service is tunneled through the (secure) port forward connection of the API
+// server.
+func (c *k8sCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// authorizationHeader returns the authorization header that would be sent to
+// the API server, handling static tokens, token files and exec plugins.
+// It returns an empty string when another authentication method, like client
+// certificates, is used.
+func authorizationHeader(ctx context.Context, config *rest.Config) (string, error) {
+	header := ""
+	capture := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		header = req.Header.Get("Authorization")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	rt, err := rest.HTTPWrappersForConfig(config, capture)
+	if err != nil {
+		return "", fmt.Errorf("creating round tripper: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.Host, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	return header, nil
+}
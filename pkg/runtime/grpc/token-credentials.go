@@ -0,0 +1,60 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+// tokenFileCredentials sends the bearer token stored in a file along with each
+// gRPC request. The file is read for each request, so that short-lived tokens
+// can be refreshed by an external tool.
+type tokenFileCredentials struct {
+	filename   string
+	requireTLS bool
+}
+
+// newTokenFileCredentials returns the credentials sending the token stored in
+// filename to target. Tokens are only sent without TLS over unix sockets, as
+// they can't be intercepted on the network.
+func newTokenFileCredentials(filename string, target target) *tokenFileCredentials {
+	return &tokenFileCredentials{
+		filename:   filename,
+		requireTLS: !strings.HasPrefix(target.addressOrPod, "unix://"),
+	}
+}
+
+func (c *tokenFileCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	data, err := os.ReadFile(c.filename)
+	if err != nil {
+		return nil, fmt.Errorf("reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("token file %q is empty", c.filename)
+	}
+	return map[string]string{api.AuthorizationMetadataKey: "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity makes gRPC refuse to send the token over a
+// connection without TLS, except for unix sockets.
+func (c *tokenFileCredentials) RequireTransportSecurity() bool {
+	return c.requireTLS
+}
@@ -0,0 +1,47 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcruntime
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-service/api"
+)
+
+func TestTokenFileCredentials(t *testing.T) {
+	t.Parallel()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret\n"), 0o600))
+
+	tcpCredentials := newTokenFileCredentials(tokenFile, target{addressOrPod: "ig-host:1234"})
+	require.True(t, tcpCredentials.RequireTransportSecurity())
+
+	unixCredentials := newTokenFileCredentials(tokenFile, target{addressOrPod: "unix:///run/gadgetservice.socket"})
+	require.False(t, unixCredentials.RequireTransportSecurity())
+
+	md, err := tcpCredentials.GetRequestMetadata(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Bearer secret", md[api.AuthorizationMetadataKey])
+
+	require.NoError(t, os.WriteFile(tokenFile, []byte(" "), 0o600))
+	_, err = tcpCredentials.GetRequestMetadata(context.Background())
+	require.Error(t, err)
+}
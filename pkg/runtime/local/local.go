@@ -26,6 +26,7 @@ import (
 	gadgetregistry "github.com/inspektor-gadget/inspektor-gadget/pkg/gadget-registry"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
 	runTypes "github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/hardening"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
 	"github.com/inspektor-gadget/inspektor-gadget/pkg/runtime"
@@ -67,6 +68,10 @@ func (r *Runtime) Init(globalRuntimeParams *params.Params) error {
 		return err
 	}
 
+	if err := hardening.Apply(); err != nil {
+		return fmt.Errorf("restricting privileges: %w", err)
+	}
+
 	return nil
 }
 
@@ -143,12 +148,23 @@ func (r *Runtime) RunGadget(gadgetCtx runtime.GadgetContext) (runtime.CombinedGa
 		setter.SetEventHandlerArray(gadgetCtx.Parser().EventHandlerFuncArray(operatorInstances.Enrich))
 	}
 
+	// Set event handler for batches of events
+	if setter, ok := gadgetInstance.(gadgets.EventHandlerBatchSetter); ok {
+		log.Debugf("set event handler for batches")
+		setter.SetEventHandlerBatch(gadgetCtx.Parser().EventHandlerFuncBatch(operatorInstances.Enrich))
+	}
+
 	// Set event enricher (currently only used by profile/cpu)
 	if setter, ok := gadgetInstance.(gadgets.EventEnricherSetter); ok {
 		log.Debugf("set event enricher")
 		setter.SetEventEnricher(operatorInstances.Enrich)
 	}
 
+	if setter, ok := gadgetCtx.(runtime.GadgetInstanceSetter); ok {
+		setter.SetGadgetInstance(gadgetInstance)
+		defer setter.SetGadgetInstance(nil)
+	}
+
 	log.Debug("calling operator.PreGadgetRun()")
 	err = operatorInstances.PreGadgetRun()
 	if err != nil {
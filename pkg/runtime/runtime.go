@@ -41,6 +41,14 @@ type GadgetContext interface {
 	GadgetInfo() *runTypes.GadgetInfo
 }
 
+// GadgetInstanceSetter is implemented by the gadget contexts keeping track of
+// the instance of the gadget that is running, e.g. to pause it
+type GadgetInstanceSetter interface {
+	// SetGadgetInstance is called with the instance once it's initialized,
+	// and with nil once it stops
+	SetGadgetInstance(gadgets.Gadget)
+}
+
 // GadgetResult contains the (optional) payload and error of a gadget run for a node
 type GadgetResult struct {
 	Payload []byte
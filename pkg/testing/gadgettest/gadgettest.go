@@ -0,0 +1,175 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+// Package gadgettest is a harness to unit test image-based gadgets without a
+// kernel. Synthetic samples of the event struct of a gadget are decoded like
+// when the gadget runs and enriched by operators, so tests can assert on the
+// resulting events:
+//
+//	h := gadgettest.New(t, "program.o", "gadget.yaml")
+//	ev := h.Emit(map[string]any{"pid": 1234, "comm": "cat"})
+//	h.RequireFields(ev, map[string]any{"pid": uint32(1234), "comm": "cat"})
+package gadgettest
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/columns"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/tracer"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+// Harness decodes and enriches synthetic samples of a gadget
+type Harness struct {
+	tb        testing.TB
+	decoder   *tracer.Decoder
+	operators operators.OperatorInstances
+}
+
+type options struct {
+	params    map[string]string
+	operators operators.OperatorInstances
+}
+
+// Option configures a Harness
+type Option func(*options)
+
+// WithParams sets parameters of the run gadget, e.g. stream or redact
+func WithParams(params map[string]string) Option {
+	return func(o *options) {
+		o.params = params
+	}
+}
+
+// WithOperators enriches the events with the given operator instances, in
+// order
+func WithOperators(instances ...operators.OperatorInstance) Option {
+	return func(o *options) {
+		o.operators = append(o.operators, instances...)
+	}
+}
+
+// New returns a harness for the gadget with the eBPF object and the metadata
+// in the given files. metadataPath can be empty to generate the metadata from
+// the eBPF object.
+func New(tb testing.TB, objectPath, metadataPath string, opts ...Option) *Harness {
+	tb.Helper()
+
+	progContent, err := os.ReadFile(objectPath)
+	require.NoError(tb, err, "reading eBPF object")
+
+	var metadata []byte
+	if metadataPath != "" {
+		metadata, err = os.ReadFile(metadataPath)
+		require.NoError(tb, err, "reading metadata")
+	}
+
+	return NewFromContent(tb, progContent, metadata, opts...)
+}
+
+// NewFromContent returns a harness for the gadget with the given eBPF object
+// and metadata
+func NewFromContent(tb testing.TB, progContent, metadata []byte, opts ...Option) *Harness {
+	tb.Helper()
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	decoder, err := tracer.NewDecoder(progContent, metadata, o.params, logger.DefaultLogger())
+	require.NoError(tb, err, "creating decoder")
+
+	return &Harness{
+		tb:        tb,
+		decoder:   decoder,
+		operators: o.operators,
+	}
+}
+
+// Decoder returns the decoder of the gadget, e.g. to get its columns
+func (h *Harness) Decoder() *tracer.Decoder {
+	return h.decoder
+}
+
+// Sample returns a raw sample of the event struct with the given values of its
+// fields, see BuildSample
+func (h *Harness) Sample(fields map[string]any) []byte {
+	h.tb.Helper()
+
+	sample, err := BuildSample(h.decoder.EventType(), fields)
+	require.NoError(h.tb, err, "building sample")
+	return sample
+}
+
+// Process decodes sample and enriches the event with the operators
+func (h *Harness) Process(sample []byte) *types.Event {
+	h.tb.Helper()
+
+	ev, err := h.decoder.Decode(sample)
+	require.NoError(h.tb, err, "decoding sample")
+	require.NoError(h.tb, h.operators.Enrich(ev), "enriching event")
+	return ev
+}
+
+// Emit processes a sample with the given values of its fields, like if the
+// gadget sent it
+func (h *Harness) Emit(fields map[string]any) *types.Event {
+	h.tb.Helper()
+
+	return h.Process(h.Sample(fields))
+}
+
+// Field returns the value of the column of ev with the given name. Numbers
+// have the type of the field, e.g. uint32 for a __u32, and char arrays are
+// returned as strings.
+func (h *Harness) Field(ev *types.Event, column string) any {
+	h.tb.Helper()
+
+	col, ok := h.decoder.Columns().GetColumn(column)
+	require.True(h.tb, ok, "column %q not found, available: %v", column,
+		h.decoder.Columns().GetColumnNames())
+
+	switch col.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := columns.GetFieldAsNumberFunc[int64, types.Event](col)(ev)
+		return reflect.ValueOf(n).Convert(col.Type()).Interface()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := columns.GetFieldAsNumberFunc[uint64, types.Event](col)(ev)
+		return reflect.ValueOf(n).Convert(col.Type()).Interface()
+	case reflect.Float32, reflect.Float64:
+		n := columns.GetFieldAsNumberFunc[float64, types.Event](col)(ev)
+		return reflect.ValueOf(n).Convert(col.Type()).Interface()
+	case reflect.Bool:
+		return columns.GetFieldFunc[bool, types.Event](col)(ev)
+	}
+	return columns.GetFieldAsString[types.Event](col)(ev)
+}
+
+// RequireFields asserts that the columns of ev have the expected values
+func (h *Harness) RequireFields(ev *types.Event, expected map[string]any) {
+	h.tb.Helper()
+
+	for column, value := range expected {
+		require.Equal(h.tb, value, h.Field(ev, column), "column %q", column)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !withoutebpf
+
+package gadgettest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/operators"
+)
+
+const objectPath = "../../../testdata/validate_metadata1.o"
+
+type nodeSetter struct{}
+
+func (n *nodeSetter) Name() string         { return "nodeSetter" }
+func (n *nodeSetter) PreGadgetRun() error  { return nil }
+func (n *nodeSetter) PostGadgetRun() error { return nil }
+func (n *nodeSetter) EnrichEvent(ev any) error {
+	ev.(operators.NodeSetter).SetNode("mynode")
+	return nil
+}
+
+func TestHarness(t *testing.T) {
+	t.Parallel()
+
+	h := New(t, objectPath, "", WithOperators(&nodeSetter{}))
+
+	ev := h.Emit(map[string]any{
+		"mntns_id": 4026531840,
+		"pid":      1234,
+		"comm":     "cat",
+		"filename": "/etc/passwd",
+	})
+	h.RequireFields(ev, map[string]any{
+		"pid":      uint32(1234),
+		"comm":     "cat",
+		"filename": "/etc/passwd",
+	})
+	require.Equal(t, uint64(4026531840), ev.MountNsID)
+	require.Equal(t, "mynode", ev.K8s.Node)
+}
+
+func TestHarnessRedact(t *testing.T) {
+	t.Parallel()
+
+	h := New(t, objectPath, "", WithParams(map[string]string{"redact": "filename=drop"}))
+
+	sample := h.Sample(map[string]any{"filename": "/etc/shadow"})
+	ev := h.Process(sample)
+	require.Equal(t, "", h.Field(ev, "filename"))
+	// The sample isn't modified
+	require.Equal(t, h.Sample(map[string]any{"filename": "/etc/shadow"}), sample)
+}
+
+func TestBuildSampleErrors(t *testing.T) {
+	t.Parallel()
+
+	h := New(t, objectPath, "")
+	typ := h.Decoder().EventType()
+
+	type testDefinition struct {
+		fields      map[string]any
+		expectedErr string
+	}
+
+	tests := map[string]testDefinition{
+		"unknown_field": {
+			fields:      map[string]any{"foo": 1},
+			expectedErr: `has no field "foo"`,
+		},
+		"not_an_integer": {
+			fields:      map[string]any{"pid": "1234"},
+			expectedErr: "expected an integer",
+		},
+		"too_long": {
+			fields:      map[string]any{"comm": "averyveryverylongcomm"},
+			expectedErr: "doesn't fit in 16 characters",
+		},
+		"raw_too_long": {
+			fields:      map[string]any{"pid": []byte{1, 2, 3, 4, 5}},
+			expectedErr: "5 bytes don't fit in 4",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := BuildSample(typ, test.fields)
+			require.ErrorContains(t, err, test.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgettest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"reflect"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+// L4Endpoint is the value of a gadget_l4endpoint_t field
+type L4Endpoint struct {
+	Addr  netip.Addr
+	Port  uint16
+	Proto uint16
+}
+
+// Sizes of gadget_l3endpoint_t and gadget_l4endpoint_t, see
+// include/gadget/types.h
+const (
+	l3EndpointSize = 20
+	l4EndpointSize = 24
+)
+
+// BuildSample returns a raw sample of typ, as sent by the eBPF programs, with
+// the given values of its fields by name. The other fields are zero. Integers
+// and enums accept any Go integer or bool, char arrays a string and
+// gadget_l3endpoint_t and gadget_l4endpoint_t a netip.Addr and an L4Endpoint.
+// Any field can be set with a []byte of its raw data.
+func BuildSample(typ *btf.Struct, fields map[string]any) ([]byte, error) {
+	for name := range fields {
+		if !hasMember(typ, name) {
+			return nil, fmt.Errorf("struct %s has no field %q", typ.Name, name)
+		}
+	}
+
+	data := make([]byte, typ.Size)
+	for _, member := range typ.Members {
+		value, ok := fields[member.Name]
+		if !ok {
+			continue
+		}
+		size, err := btf.Sizeof(member.Type)
+		if err != nil {
+			return nil, fmt.Errorf("getting size of field %q: %w", member.Name, err)
+		}
+		offset := member.Offset.Bytes()
+		if err := encodeField(data[offset:offset+uint32(size)], member.Type, value); err != nil {
+			return nil, fmt.Errorf("setting field %q: %w", member.Name, err)
+		}
+	}
+	return data, nil
+}
+
+func hasMember(typ *btf.Struct, name string) bool {
+	for _, member := range typ.Members {
+		if member.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeField writes value, of a field of type typ, into data
+func encodeField(data []byte, typ btf.Type, value any) error {
+	if raw, ok := value.([]byte); ok {
+		if len(raw) > len(data) {
+			return fmt.Errorf("%d bytes don't fit in %d", len(raw), len(data))
+		}
+		copy(data, raw)
+		return nil
+	}
+
+	switch typ.TypeName() {
+	case types.L3EndpointTypeName:
+		addr, ok := value.(netip.Addr)
+		if !ok || len(data) != l3EndpointSize {
+			return fmt.Errorf("expected a netip.Addr, got %T", value)
+		}
+		encodeL3Endpoint(data, addr)
+		return nil
+	case types.L4EndpointTypeName:
+		endpoint, ok := value.(L4Endpoint)
+		if !ok || len(data) != l4EndpointSize {
+			return fmt.Errorf("expected an L4Endpoint, got %T", value)
+		}
+		encodeL3Endpoint(data, endpoint.Addr)
+		binary.NativeEndian.PutUint16(data[l3EndpointSize:], endpoint.Port)
+		binary.NativeEndian.PutUint16(data[l3EndpointSize+2:], endpoint.Proto)
+		return nil
+	}
+
+	switch t := btf.UnderlyingType(typ).(type) {
+	case *btf.Int, *btf.Enum:
+		n, err := toUint64(value)
+		if err != nil {
+			return err
+		}
+		return putUint(data, n)
+	case *btf.Array:
+		if types.GetCharArray(t) == nil {
+			return fmt.Errorf("array fields can only be set with []byte, got %T", value)
+		}
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %T", value)
+		}
+		if len(s) > len(data) {
+			return fmt.Errorf("%q doesn't fit in %d characters", s, len(data))
+		}
+		copy(data, s)
+		return nil
+	}
+	return fmt.Errorf("fields of type %s can only be set with []byte, got %T", typ, value)
+}
+
+func encodeL3Endpoint(data []byte, addr netip.Addr) {
+	switch {
+	case addr.Is4():
+		a := addr.As4()
+		copy(data, a[:])
+		data[16] = 4
+	case addr.IsValid():
+		a := addr.As16()
+		copy(data, a[:])
+		data[16] = 6
+	}
+}
+
+func toUint64(value any) (uint64, error) {
+	v := reflect.ValueOf(value)
+	switch {
+	case v.CanInt():
+		return uint64(v.Int()), nil
+	case v.CanUint():
+		return v.Uint(), nil
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", value)
+}
+
+func putUint(data []byte, n uint64) error {
+	switch len(data) {
+	case 1:
+		data[0] = uint8(n)
+	case 2:
+		binary.NativeEndian.PutUint16(data, uint16(n))
+	case 4:
+		binary.NativeEndian.PutUint32(data, uint32(n))
+	case 8:
+		binary.NativeEndian.PutUint64(data, n)
+	default:
+		return fmt.Errorf("unsupported integer size %d", len(data))
+	}
+	return nil
+}
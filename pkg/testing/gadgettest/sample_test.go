@@ -0,0 +1,74 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gadgettest
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets/run/types"
+)
+
+func TestBuildSample(t *testing.T) {
+	t.Parallel()
+
+	u8 := &btf.Int{Name: "u8", Size: 1}
+	u16 := &btf.Int{Name: "u16", Size: 2}
+	s64 := &btf.Int{Name: "s64", Size: 8, Encoding: btf.Signed}
+	typ := &btf.Struct{
+		Name: "event",
+		Size: 64,
+		Members: []btf.Member{
+			{Name: "ret", Type: &btf.Typedef{Name: "ret_t", Type: s64}, Offset: 0},
+			{Name: "flag", Type: u8, Offset: 8 * 8},
+			{Name: "port", Type: u16, Offset: 10 * 8},
+			{Name: "src", Type: &btf.Struct{Name: types.L3EndpointTypeName, Size: l3EndpointSize}, Offset: 12 * 8},
+			{Name: "dst", Type: &btf.Struct{Name: types.L4EndpointTypeName, Size: l4EndpointSize}, Offset: 32 * 8},
+			{Name: "raw", Type: &btf.Array{Type: u16, Nelems: 4}, Offset: 56 * 8},
+		},
+	}
+
+	data, err := BuildSample(typ, map[string]any{
+		"ret":  -2,
+		"flag": true,
+		"port": uint16(443),
+		"src":  netip.MustParseAddr("10.0.0.1"),
+		"dst":  L4Endpoint{Addr: netip.MustParseAddr("::1"), Port: 53, Proto: 17},
+		"raw":  []byte{1, 2},
+	})
+	require.NoError(t, err)
+	require.Len(t, data, 64)
+
+	require.Equal(t, int64(-2), int64(binary.NativeEndian.Uint64(data[0:])))
+	require.Equal(t, uint8(1), data[8])
+	require.Equal(t, uint16(443), binary.NativeEndian.Uint16(data[10:]))
+	require.Equal(t, []byte{10, 0, 0, 1}, data[12:16])
+	require.Equal(t, uint8(4), data[28])
+	require.Equal(t, netip.MustParseAddr("::1").AsSlice(), data[32:48])
+	require.Equal(t, uint8(6), data[48])
+	require.Equal(t, uint16(53), binary.NativeEndian.Uint16(data[52:]))
+	require.Equal(t, uint16(17), binary.NativeEndian.Uint16(data[54:]))
+	require.Equal(t, []byte{1, 2, 0, 0}, data[56:60])
+
+	_, err = BuildSample(typ, map[string]any{"raw": "foo"})
+	require.ErrorContains(t, err, "array fields can only be set with []byte")
+
+	_, err = BuildSample(typ, map[string]any{"src": "10.0.0.1"})
+	require.ErrorContains(t, err, "expected a netip.Addr")
+}
@@ -0,0 +1,334 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package golden compares the JSON output of a gadget with golden files. The
+// fields changing from a run to another, like timestamps and pids, are
+// normalized before the comparison, so the golden files are stable:
+//
+//	output := runGadget(t, "-o", "json")
+//	golden.RequireMatch(t, output, "testdata/trace_open.golden.json",
+//		golden.WithVolatileFields("fd"), golden.WithSortedEntries())
+//
+// The golden files are (re)generated by running the tests with the
+// IG_UPDATE_GOLDEN environment variable set to 1.
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// UpdateEnv is the environment variable that, set to 1, makes the tests
+	// write the golden files instead of comparing them with the output
+	UpdateEnv = "IG_UPDATE_GOLDEN"
+
+	// Volatile replaces the values of the volatile fields
+	Volatile = "<volatile>"
+)
+
+// DefaultVolatileFields are the fields of the events of the built-in gadgets
+// that change from a run to another. They are normalized unless
+// WithoutDefaultVolatileFields is used.
+var DefaultVolatileFields = []string{
+	"timestamp",
+	"pid",
+	"ppid",
+	"tid",
+	"mntns",
+	"mountnsid",
+	"mntns_id",
+	"netns",
+	"netnsid",
+	"netns_id",
+	"containerId",
+	"containerStartedAt",
+	"runtime.containerPid",
+	"k8s.node",
+}
+
+type options struct {
+	volatile     []string
+	ignored      []string
+	sorted       bool
+	noDefaults   bool
+	updateGolden bool
+}
+
+// Option configures the comparison with a golden file
+type Option func(*options)
+
+// WithVolatileFields normalizes the given fields too. A field is either the
+// name of a key, matched at any depth, or a path of keys separated by dots,
+// e.g. k8s.node, matched from the root of the event.
+func WithVolatileFields(fields ...string) Option {
+	return func(o *options) {
+		o.volatile = append(o.volatile, fields...)
+	}
+}
+
+// WithoutDefaultVolatileFields doesn't normalize DefaultVolatileFields
+func WithoutDefaultVolatileFields() Option {
+	return func(o *options) {
+		o.noDefaults = true
+	}
+}
+
+// WithIgnoredFields removes the given fields from the events, with the same
+// syntax as WithVolatileFields. Unlike volatile fields, they don't need to be
+// present in the output.
+func WithIgnoredFields(fields ...string) Option {
+	return func(o *options) {
+		o.ignored = append(o.ignored, fields...)
+	}
+}
+
+// WithSortedEntries sorts the events, for gadgets whose events aren't emitted
+// in a deterministic order
+func WithSortedEntries() Option {
+	return func(o *options) {
+		o.sorted = true
+	}
+}
+
+// WithUpdate writes the golden file when update is true, whatever UpdateEnv
+func WithUpdate(update bool) Option {
+	return func(o *options) {
+		o.updateGolden = update
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{updateGolden: os.Getenv(UpdateEnv) == "1"}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if !o.noDefaults {
+		o.volatile = append(slices.Clone(DefaultVolatileFields), o.volatile...)
+	}
+	return o
+}
+
+// ParseOutput parses the JSON output of a gadget: either one JSON object per
+// line or JSON arrays of objects, e.g. for top gadgets.
+func ParseOutput(output []byte) ([]map[string]any, error) {
+	var entries []map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	decoder.UseNumber()
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("decoding output: %w", err)
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) > 0 && raw[0] == '[' {
+			var array []map[string]any
+			if err := unmarshal(raw, &array); err != nil {
+				return nil, fmt.Errorf("decoding array: %w", err)
+			}
+			entries = append(entries, array...)
+			continue
+		}
+		var entry map[string]any
+		if err := unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("decoding entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func unmarshal(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// visitField calls fn with the objects containing field and the name of its
+// last key
+func visitField(entry map[string]any, field string, fn func(obj map[string]any, key string)) {
+	if path := strings.Split(field, "."); len(path) > 1 {
+		obj := entry
+		for _, key := range path[:len(path)-1] {
+			next, ok := obj[key].(map[string]any)
+			if !ok {
+				return
+			}
+			obj = next
+		}
+		if _, ok := obj[path[len(path)-1]]; ok {
+			fn(obj, path[len(path)-1])
+		}
+		return
+	}
+
+	var visit func(v any)
+	visit = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if _, ok := v[field]; ok {
+				fn(v, field)
+			}
+			for _, child := range v {
+				visit(child)
+			}
+		case []any:
+			for _, child := range v {
+				visit(child)
+			}
+		}
+	}
+	visit(entry)
+}
+
+// Normalize replaces the values of the volatile fields of the events with
+// Volatile and removes the ignored fields, in place. Fields with a zero value
+// aren't normalized, so a field wrongly left empty by a gadget is caught.
+func Normalize(entries []map[string]any, opts ...Option) {
+	o := newOptions(opts)
+	normalize(entries, o)
+}
+
+func normalize(entries []map[string]any, o *options) {
+	for _, entry := range entries {
+		for _, field := range o.ignored {
+			visitField(entry, field, func(obj map[string]any, key string) {
+				delete(obj, key)
+			})
+		}
+		for _, field := range o.volatile {
+			visitField(entry, field, func(obj map[string]any, key string) {
+				if !isZero(obj[key]) {
+					obj[key] = Volatile
+				}
+			})
+		}
+	}
+	if o.sorted {
+		slices.SortStableFunc(entries, func(a, b map[string]any) int {
+			return strings.Compare(marshalCompact(a), marshalCompact(b))
+		})
+	}
+}
+
+func isZero(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case json.Number:
+		return v.String() == "0"
+	case bool:
+		return !v
+	}
+	return false
+}
+
+func marshalCompact(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// Format returns the events as an indented JSON array, the format of the
+// golden files
+func Format(entries []map[string]any) ([]byte, error) {
+	if entries == nil {
+		entries = []map[string]any{}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(entries); err != nil {
+		return nil, fmt.Errorf("marshaling entries: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Diff returns the differences between the normalized output of a gadget and
+// the golden file at path, or an empty string if they match. The golden file
+// is written instead when updating, see UpdateEnv.
+func Diff(output []byte, path string, opts ...Option) (string, error) {
+	o := newOptions(opts)
+
+	entries, err := ParseOutput(output)
+	if err != nil {
+		return "", err
+	}
+	normalize(entries, o)
+	actual, err := Format(entries)
+	if err != nil {
+		return "", err
+	}
+
+	if o.updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", fmt.Errorf("creating golden file directory: %w", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			return "", fmt.Errorf("writing golden file: %w", err)
+		}
+		return "", nil
+	}
+
+	goldenContent, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading golden file (set %s=1 to create it): %w", UpdateEnv, err)
+	}
+	// Format the golden file again, so it can be edited by hand
+	goldenEntries, err := ParseOutput(goldenContent)
+	if err != nil {
+		return "", fmt.Errorf("golden file %q: %w", path, err)
+	}
+	expected, err := Format(goldenEntries)
+	if err != nil {
+		return "", err
+	}
+
+	return cmp.Diff(string(expected), string(actual)), nil
+}
+
+// RequireMatch fails the test if the normalized output of a gadget doesn't
+// match the golden file at path. The golden file is written instead when
+// updating, see UpdateEnv.
+func RequireMatch(tb testing.TB, output string, path string, opts ...Option) {
+	tb.Helper()
+
+	diff, err := Diff([]byte(output), path, opts...)
+	require.NoError(tb, err, "comparing with golden file")
+	if diff != "" {
+		tb.Fatalf("output doesn't match golden file %s (-golden +output), set %s=1 to update it:\n%s",
+			path, UpdateEnv, diff)
+	}
+}
+
+// Validate returns a function validating the output of a command against the
+// golden file at path, e.g. for the ValidateOutput field of the commands of
+// the integration tests
+func Validate(path string, opts ...Option) func(t *testing.T, output string) {
+	return func(t *testing.T, output string) {
+		t.Helper()
+		RequireMatch(t, output, path, opts...)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const output = `{"timestamp": 1700000000000000000, "pid": 4242, "comm": "touch", "fd": 4, "k8s": {"namespace": "default", "node": "node-1"}}
+{"timestamp": 1700000000000001000, "pid": 4243, "comm": "cat", "fd": 3, "k8s": {"namespace": "default", "node": "node-1"}}
+`
+
+func TestParseOutput(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		output        string
+		expectedComms []any
+		expectedErr   bool
+	}
+
+	tests := map[string]testDefinition{
+		"lines": {
+			output:        output,
+			expectedComms: []any{"touch", "cat"},
+		},
+		"arrays": {
+			output:        "[{\"comm\": \"a\"}, {\"comm\": \"b\"}]\n[{\"comm\": \"c\"}]\n",
+			expectedComms: []any{"a", "b", "c"},
+		},
+		"empty": {
+			output: "",
+		},
+		"invalid": {
+			output:      "{\"comm\": ",
+			expectedErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			entries, err := ParseOutput([]byte(test.output))
+			if test.expectedErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var comms []any
+			for _, entry := range entries {
+				comms = append(comms, entry["comm"])
+			}
+			require.Equal(t, test.expectedComms, comms)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	entries, err := ParseOutput([]byte(`{"pid": 12, "tid": 0, "fd": 3, "ret": 5, "k8s": {"node": "n", "pod": "p"}, "runtime": {"containerId": ""}}`))
+	require.NoError(t, err)
+
+	Normalize(entries, WithVolatileFields("fd", "k8s.pod"), WithIgnoredFields("ret"))
+	require.Equal(t, []map[string]any{{
+		"pid":     Volatile,
+		"tid":     entries[0]["tid"],
+		"fd":      Volatile,
+		"k8s":     map[string]any{"node": Volatile, "pod": Volatile},
+		"runtime": map[string]any{"containerId": ""},
+	}}, entries)
+
+	entries, err = ParseOutput([]byte(`{"pid": 12}`))
+	require.NoError(t, err)
+	Normalize(entries, WithoutDefaultVolatileFields())
+	require.Equal(t, "12", entries[0]["pid"].(interface{ String() string }).String())
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	opts := []Option{WithSortedEntries(), WithUpdate(false)}
+
+	diff, err := Diff([]byte(output), "testdata/events.golden.json", opts...)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	changed := []byte(`{"timestamp": 1, "pid": 1, "comm": "cat", "fd": 5, "k8s": {"namespace": "default", "node": "node-1"}}`)
+	diff, err = Diff(changed, "testdata/events.golden.json", opts...)
+	require.NoError(t, err)
+	// The format of the diff isn't stable, only check the changed lines
+	require.Contains(t, diff, `"fd": 3,`)
+	require.Contains(t, diff, `"fd": 5,`)
+
+	_, err = Diff([]byte(output), "testdata/missing.golden.json", opts...)
+	require.ErrorContains(t, err, UpdateEnv)
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "testdata", "events.golden.json")
+	diff, err := Diff([]byte(output), path, WithSortedEntries(), WithUpdate(true))
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	expected, err := os.ReadFile("testdata/events.golden.json")
+	require.NoError(t, err)
+	actual, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+
+	RequireMatch(t, output, path, WithSortedEntries(), WithUpdate(false))
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mocks provides implementations of the interfaces the gadgets use to
+// interact with the runtime, to unit test the code embedding the tracer
+// packages without the full runtime:
+//
+//	logger := mocks.NewLogger()
+//	gadgetCtx := mocks.NewGadgetContext(
+//		mocks.WithGadgetParams(mocks.GadgetParams(t, gadgetDesc, map[string]string{"interval": "2"})),
+//		mocks.WithLogger(logger),
+//	)
+//	require.NoError(t, tracer.Init(gadgetCtx))
+//	require.Empty(t, logger.Messages(mocks.WarnLevel))
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+var _ gadgets.GadgetContext = (*GadgetContext)(nil)
+
+// GadgetContext implements gadgets.GadgetContext with fixed values
+type GadgetContext struct {
+	ctx           context.Context
+	cancel        context.CancelFunc
+	id            string
+	gadgetParams  *params.Params
+	runtimeParams *params.Params
+	args          []string
+	logger        logger.Logger
+	timeout       time.Duration
+}
+
+// GadgetContextOption configures a GadgetContext
+type GadgetContextOption func(*GadgetContext)
+
+// WithContext sets the parent of the context of the gadget,
+// context.Background() by default
+func WithContext(ctx context.Context) GadgetContextOption {
+	return func(c *GadgetContext) {
+		c.ctx = ctx
+	}
+}
+
+// WithID sets the ID of the gadget instance
+func WithID(id string) GadgetContextOption {
+	return func(c *GadgetContext) {
+		c.id = id
+	}
+}
+
+// WithGadgetParams sets the params of the gadget, empty by default
+func WithGadgetParams(p *params.Params) GadgetContextOption {
+	return func(c *GadgetContext) {
+		c.gadgetParams = p
+	}
+}
+
+// WithRuntimeParams sets the params of the runtime, empty by default
+func WithRuntimeParams(p *params.Params) GadgetContextOption {
+	return func(c *GadgetContext) {
+		c.runtimeParams = p
+	}
+}
+
+// WithArgs sets the arguments of the gadget, e.g. the image of a run gadget
+func WithArgs(args ...string) GadgetContextOption {
+	return func(c *GadgetContext) {
+		c.args = args
+	}
+}
+
+// WithLogger sets the logger of the gadget, a Logger discarding the messages
+// by default
+func WithLogger(l logger.Logger) GadgetContextOption {
+	return func(c *GadgetContext) {
+		c.logger = l
+	}
+}
+
+// WithTimeout sets the timeout of the gadget. The context of the gadget isn't
+// canceled after the timeout, like in the runtime.
+func WithTimeout(timeout time.Duration) GadgetContextOption {
+	return func(c *GadgetContext) {
+		c.timeout = timeout
+	}
+}
+
+// NewGadgetContext returns a GadgetContext configured with opts. Its context
+// is canceled by Cancel.
+func NewGadgetContext(opts ...GadgetContextOption) *GadgetContext {
+	c := &GadgetContext{
+		ctx:           context.Background(),
+		gadgetParams:  &params.Params{},
+		runtimeParams: &params.Params{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger == nil {
+		c.logger = NewLogger()
+	}
+	c.ctx, c.cancel = context.WithCancel(c.ctx)
+	return c
+}
+
+func (c *GadgetContext) ID() string {
+	return c.id
+}
+
+func (c *GadgetContext) Context() context.Context {
+	return c.ctx
+}
+
+// Cancel cancels the context of the gadget, like when it's stopped
+func (c *GadgetContext) Cancel() {
+	c.cancel()
+}
+
+func (c *GadgetContext) GadgetParams() *params.Params {
+	return c.gadgetParams
+}
+
+func (c *GadgetContext) RuntimeParams() *params.Params {
+	return c.runtimeParams
+}
+
+func (c *GadgetContext) Args() []string {
+	return c.args
+}
+
+func (c *GadgetContext) Logger() logger.Logger {
+	return c.logger
+}
+
+func (c *GadgetContext) Timeout() time.Duration {
+	return c.timeout
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/parser"
+)
+
+type testGadget struct{}
+
+func (g *testGadget) Name() string             { return "test" }
+func (g *testGadget) Description() string      { return "" }
+func (g *testGadget) Category() string         { return gadgets.CategoryTop }
+func (g *testGadget) Type() gadgets.GadgetType { return gadgets.TypeTraceIntervals }
+func (g *testGadget) Parser() parser.Parser    { return nil }
+func (g *testGadget) EventPrototype() any      { return nil }
+func (g *testGadget) ParamDescs() params.ParamDescs {
+	return params.ParamDescs{
+		{
+			Key:          "threshold",
+			DefaultValue: "10",
+			TypeHint:     params.TypeUint32,
+		},
+	}
+}
+
+func TestGadgetContext(t *testing.T) {
+	t.Parallel()
+
+	type ctxKey struct{}
+	logger := NewLogger()
+	gadgetParams := GadgetParams(t, &testGadget{}, map[string]string{gadgets.ParamInterval: "5"})
+
+	gadgetCtx := NewGadgetContext(
+		WithContext(context.WithValue(context.Background(), ctxKey{}, "value")),
+		WithID("id"),
+		WithGadgetParams(gadgetParams),
+		WithArgs("arg1", "arg2"),
+		WithLogger(logger),
+		WithTimeout(time.Second),
+	)
+
+	require.Equal(t, "id", gadgetCtx.ID())
+	require.Equal(t, "value", gadgetCtx.Context().Value(ctxKey{}))
+	require.Equal(t, []string{"arg1", "arg2"}, gadgetCtx.Args())
+	require.Equal(t, time.Second, gadgetCtx.Timeout())
+	require.Equal(t, uint32(5), gadgetCtx.GadgetParams().Get(gadgets.ParamInterval).AsUint32())
+	require.Equal(t, uint32(10), gadgetCtx.GadgetParams().Get("threshold").AsUint32())
+	require.Empty(t, *gadgetCtx.RuntimeParams())
+
+	gadgetCtx.Logger().Warnf("warning %d", 1)
+	require.True(t, logger.Contains(WarnLevel, "warning 1"))
+
+	require.NoError(t, gadgetCtx.Context().Err())
+	gadgetCtx.Cancel()
+	require.ErrorIs(t, gadgetCtx.Context().Err(), context.Canceled)
+}
+
+func TestDefaultGadgetContext(t *testing.T) {
+	t.Parallel()
+
+	gadgetCtx := NewGadgetContext()
+	require.NotNil(t, gadgetCtx.Context())
+	require.NotNil(t, gadgetCtx.GadgetParams())
+	require.NotNil(t, gadgetCtx.RuntimeParams())
+	require.NotNil(t, gadgetCtx.Logger())
+}
+
+func TestParams(t *testing.T) {
+	t.Parallel()
+
+	descs := params.ParamDescs{
+		{Key: "name", DefaultValue: "foo"},
+		{Key: "count", TypeHint: params.TypeInt},
+	}
+
+	p := Params(t, descs, map[string]string{"count": "3"})
+	require.Equal(t, "foo", p.Get("name").AsString())
+	require.Equal(t, 3, p.Get("count").AsInt())
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/logger"
+)
+
+// The log levels, to check the messages of a Logger
+const (
+	PanicLevel = logger.PanicLevel
+	FatalLevel = logger.FatalLevel
+	ErrorLevel = logger.ErrorLevel
+	WarnLevel  = logger.WarnLevel
+	InfoLevel  = logger.InfoLevel
+	DebugLevel = logger.DebugLevel
+	TraceLevel = logger.TraceLevel
+)
+
+var _ logger.Logger = (*Logger)(nil)
+
+// Entry is a message logged by a Logger
+type Entry struct {
+	Level   logger.Level
+	Message string
+}
+
+// Logger records the messages logged at its level or a more severe one,
+// instead of printing them. Panic and Fatal don't stop the test, they're
+// recorded like the other levels.
+type Logger struct {
+	logger.StandardDedicatedLogger
+
+	mu      sync.Mutex
+	level   logger.Level
+	entries []Entry
+}
+
+// NewLogger returns a Logger recording all the messages
+func NewLogger() *Logger {
+	l := &Logger{level: logger.TraceLevel}
+	l.StandardDedicatedLogger.GenericLoggerWithLevelSetter = l
+	return l
+}
+
+func (l *Logger) Log(severity logger.Level, params ...any) {
+	l.record(severity, fmt.Sprint(params...))
+}
+
+func (l *Logger) Logf(severity logger.Level, format string, params ...any) {
+	l.record(severity, fmt.Sprintf(format, params...))
+}
+
+func (l *Logger) record(severity logger.Level, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if severity > l.level {
+		return
+	}
+	l.entries = append(l.entries, Entry{Level: severity, Message: message})
+}
+
+func (l *Logger) SetLevel(level logger.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = level
+}
+
+func (l *Logger) GetLevel() logger.Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+// Entries returns the recorded messages, in order
+func (l *Logger) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// Messages returns the recorded messages logged at the given level
+func (l *Logger) Messages(level logger.Level) []string {
+	var messages []string
+	for _, entry := range l.Entries() {
+		if entry.Level == level {
+			messages = append(messages, entry.Message)
+		}
+	}
+	return messages
+}
+
+// Contains returns whether a message containing s was logged at the given
+// level
+func (l *Logger) Contains(level logger.Level, s string) bool {
+	for _, message := range l.Messages(level) {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset forgets the recorded messages
+func (l *Logger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
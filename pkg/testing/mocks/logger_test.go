@@ -0,0 +1,46 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	l := NewLogger()
+	l.Info("hello ", "world")
+	l.Debugf("value: %d", 42)
+	l.Error("failed")
+
+	require.Equal(t, []Entry{
+		{Level: InfoLevel, Message: "hello world"},
+		{Level: DebugLevel, Message: "value: 42"},
+		{Level: ErrorLevel, Message: "failed"},
+	}, l.Entries())
+	require.Equal(t, []string{"value: 42"}, l.Messages(DebugLevel))
+	require.True(t, l.Contains(ErrorLevel, "fail"))
+	require.False(t, l.Contains(WarnLevel, "fail"))
+
+	l.Reset()
+	l.SetLevel(WarnLevel)
+	require.Equal(t, WarnLevel, l.GetLevel())
+	l.Info("ignored")
+	l.Warn("kept")
+	require.Equal(t, []Entry{{Level: WarnLevel, Message: "kept"}}, l.Entries())
+}
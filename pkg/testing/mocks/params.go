@@ -0,0 +1,47 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mocks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/gadgets"
+	"github.com/inspektor-gadget/inspektor-gadget/pkg/params"
+)
+
+// Params returns the params described by descs, with their default values
+// overridden by values. The test fails if a value is invalid or isn't
+// described by descs.
+func Params(tb testing.TB, descs params.ParamDescs, values map[string]string) *params.Params {
+	tb.Helper()
+
+	p := descs.ToParams()
+	for key, value := range values {
+		require.NoError(tb, p.Set(key, value), "setting param %q", key)
+	}
+	return p
+}
+
+// GadgetParams returns the params of gadget, including the ones depending on
+// its type like interval, with their default values overridden by values
+func GadgetParams(tb testing.TB, gadget gadgets.GadgetDesc, values map[string]string) *params.Params {
+	tb.Helper()
+
+	descs := gadget.ParamDescs()
+	descs.Add(gadgets.GadgetParams(gadget, gadget.Type(), gadget.Parser())...)
+	return Params(tb, descs, values)
+}
@@ -0,0 +1,285 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package workload generates deterministic workloads for the end-to-end tests
+// of gadgets: execs, file opens, connects and DNS queries. The workload is a
+// shell script working with busybox, so it can be the command of the test
+// containers, and its steps describe the events the gadgets should report:
+//
+//	w := workload.New(workload.WithKinds(workload.KindOpen), workload.WithCount(3))
+//	container := containerFactory.NewContainer("test-workload", w.Script())
+//	for _, step := range w.Steps() {
+//		// step.Comm and step.Target are the expected comm and filename
+//	}
+//
+// No network access is needed: connections and DNS queries go to the loopback
+// address by default, the events are generated even if nothing answers.
+package workload
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is a kind of step of a workload
+type Kind string
+
+const (
+	// KindExec executes a program
+	KindExec Kind = "exec"
+	// KindOpen creates a file
+	KindOpen Kind = "open"
+	// KindConnect connects to a TCP port
+	KindConnect Kind = "connect"
+	// KindDNS sends a DNS query
+	KindDNS Kind = "dns"
+)
+
+// AllKinds are all the kinds of steps, in the order they are generated
+var AllKinds = []Kind{KindExec, KindOpen, KindConnect, KindDNS}
+
+const (
+	// Prefix is the prefix of the files and DNS names of the workloads, to
+	// filter their events
+	Prefix = "ig-workload"
+
+	defaultCount    = 1
+	defaultAddress  = "127.0.0.1"
+	defaultPort     = 8080
+	defaultDir      = "/tmp"
+	defaultInterval = 100 * time.Millisecond
+)
+
+// Step is a step of a workload and the event it generates
+type Step struct {
+	Kind Kind `json:"kind"`
+	// Command is the shell command of the step
+	Command string `json:"command"`
+	// Comm is the name of the process generating the event
+	Comm string `json:"comm"`
+	// Target is what the event is about: the path of the executed program or
+	// of the opened file, the address of the connection or the DNS name
+	Target string `json:"target"`
+}
+
+// Workload generates the same steps each time
+type Workload struct {
+	kinds     []Kind
+	count     int
+	address   string
+	port      int
+	dnsServer string
+	dir       string
+	interval  time.Duration
+}
+
+// Option configures a Workload
+type Option func(*Workload)
+
+// WithKinds sets the kinds of steps to generate, all of them by default
+func WithKinds(kinds ...Kind) Option {
+	return func(w *Workload) {
+		w.kinds = kinds
+	}
+}
+
+// WithCount sets how many times the steps of each kind are repeated, 1 by
+// default
+func WithCount(count int) Option {
+	return func(w *Workload) {
+		w.count = count
+	}
+}
+
+// WithAddress sets the address connected to, the loopback address by default.
+// The port of the n-th connection is the given one plus n.
+func WithAddress(address string, port int) Option {
+	return func(w *Workload) {
+		w.address = address
+		w.port = port
+	}
+}
+
+// WithDNSServer sets the server the DNS queries are sent to, the loopback
+// address by default
+func WithDNSServer(server string) Option {
+	return func(w *Workload) {
+		w.dnsServer = server
+	}
+}
+
+// WithDir sets the directory where the files are created, /tmp by default
+func WithDir(dir string) Option {
+	return func(w *Workload) {
+		w.dir = dir
+	}
+}
+
+// WithInterval sets the time to wait between steps, 100ms by default, so
+// gadgets sorting events by time report them in order
+func WithInterval(interval time.Duration) Option {
+	return func(w *Workload) {
+		w.interval = interval
+	}
+}
+
+// New returns a workload configured with opts
+func New(opts ...Option) *Workload {
+	w := &Workload{
+		kinds:     AllKinds,
+		count:     defaultCount,
+		address:   defaultAddress,
+		port:      defaultPort,
+		dnsServer: defaultAddress,
+		dir:       defaultDir,
+		interval:  defaultInterval,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+// ParseKinds parses a comma-separated list of kinds of steps, e.g. exec,dns
+func ParseKinds(s string) ([]Kind, error) {
+	var kinds []Kind
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		kind, err := parseKind(k)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return nil, errors.New("no kind of step given")
+	}
+	return kinds, nil
+}
+
+func parseKind(s string) (Kind, error) {
+	for _, kind := range AllKinds {
+		if string(kind) == s {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("invalid kind of step %q, expected one of %v", s, AllKinds)
+}
+
+// Validate checks the configuration of the workload
+func (w *Workload) Validate() error {
+	if w.count < 1 {
+		return fmt.Errorf("invalid count %d, expected at least 1", w.count)
+	}
+	if len(w.kinds) == 0 {
+		return errors.New("no kind of step given")
+	}
+	for _, kind := range w.kinds {
+		if _, err := parseKind(string(kind)); err != nil {
+			return err
+		}
+	}
+	if net.ParseIP(w.address) == nil {
+		return fmt.Errorf("invalid address %q", w.address)
+	}
+	if w.port < 1 || w.port+w.count-1 > 65535 {
+		return fmt.Errorf("invalid port %d", w.port)
+	}
+	if net.ParseIP(w.dnsServer) == nil {
+		return fmt.Errorf("invalid DNS server %q", w.dnsServer)
+	}
+	if !strings.HasPrefix(w.dir, "/") {
+		return fmt.Errorf("directory %q isn't absolute", w.dir)
+	}
+	if w.interval < 0 {
+		return fmt.Errorf("invalid interval %s", w.interval)
+	}
+	return nil
+}
+
+// step returns the n-th step of the given kind
+func (w *Workload) step(kind Kind, n int) Step {
+	name := fmt.Sprintf("%s-%d", Prefix, n)
+	switch kind {
+	case KindExec:
+		return Step{
+			Kind:    kind,
+			Command: "/bin/date",
+			Comm:    "date",
+			Target:  "/bin/date",
+		}
+	case KindOpen:
+		path := w.dir + "/" + name
+		return Step{
+			Kind:    kind,
+			Command: "touch " + path,
+			Comm:    "touch",
+			Target:  path,
+		}
+	case KindConnect:
+		address := net.JoinHostPort(w.address, strconv.Itoa(w.port+n))
+		return Step{
+			Kind:    kind,
+			Command: fmt.Sprintf("nc -w 1 %s %d </dev/null", w.address, w.port+n),
+			Comm:    "nc",
+			Target:  address,
+		}
+	case KindDNS:
+		name += ".example.com"
+		return Step{
+			Kind:    kind,
+			Command: fmt.Sprintf("nslookup %s %s", name, w.dnsServer),
+			Comm:    "nslookup",
+			Target:  name + ".",
+		}
+	}
+	return Step{}
+}
+
+// Steps returns the steps of the workload, in the order they run
+func (w *Workload) Steps() []Step {
+	steps := make([]Step, 0, w.count*len(w.kinds))
+	for n := 0; n < w.count; n++ {
+		for _, kind := range w.kinds {
+			steps = append(steps, w.step(kind, n))
+		}
+	}
+	return steps
+}
+
+// Script returns the shell script running the steps of the workload. Steps
+// failing, like connections refused, don't stop it.
+func (w *Workload) Script() string {
+	var commands []string
+	for i, step := range w.Steps() {
+		if i > 0 && w.interval > 0 {
+			commands = append(commands, fmt.Sprintf("sleep %s", formatSeconds(w.interval)))
+		}
+		commands = append(commands, fmt.Sprintf("%s >/dev/null 2>&1", step.Command))
+	}
+	commands = append(commands, "true")
+	return strings.Join(commands, "; ")
+}
+
+// formatSeconds formats d in seconds, the unit understood by sleep
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,116 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workload
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKinds(t *testing.T) {
+	t.Parallel()
+
+	kinds, err := ParseKinds("exec, dns,")
+	require.NoError(t, err)
+	require.Equal(t, []Kind{KindExec, KindDNS}, kinds)
+
+	_, err = ParseKinds("exec,fork")
+	require.ErrorContains(t, err, `invalid kind of step "fork"`)
+
+	_, err = ParseKinds("")
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	type testDefinition struct {
+		opts        []Option
+		expectedErr string
+	}
+
+	tests := map[string]testDefinition{
+		"default": {},
+		"invalid_count": {
+			opts:        []Option{WithCount(0)},
+			expectedErr: "invalid count",
+		},
+		"invalid_address": {
+			opts:        []Option{WithAddress("localhost", 80)},
+			expectedErr: "invalid address",
+		},
+		"port_overflow": {
+			opts:        []Option{WithAddress("10.0.0.1", 65535), WithCount(2)},
+			expectedErr: "invalid port",
+		},
+		"relative_dir": {
+			opts:        []Option{WithDir("tmp")},
+			expectedErr: "isn't absolute",
+		},
+		"invalid_kind": {
+			opts:        []Option{WithKinds("fork")},
+			expectedErr: "invalid kind",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := New(test.opts...).Validate()
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSteps(t *testing.T) {
+	t.Parallel()
+
+	w := New(WithCount(2), WithAddress("10.0.0.1", 80))
+	steps := w.Steps()
+	require.Len(t, steps, 8)
+	require.Equal(t, steps, w.Steps(), "steps aren't deterministic")
+
+	require.Equal(t, Step{
+		Kind:    KindOpen,
+		Command: "touch /tmp/ig-workload-0",
+		Comm:    "touch",
+		Target:  "/tmp/ig-workload-0",
+	}, steps[1])
+	require.Equal(t, "10.0.0.1:81", steps[6].Target)
+	require.Equal(t, Step{
+		Kind:    KindDNS,
+		Command: "nslookup ig-workload-1.example.com 127.0.0.1",
+		Comm:    "nslookup",
+		Target:  "ig-workload-1.example.com.",
+	}, steps[7])
+}
+
+func TestScript(t *testing.T) {
+	t.Parallel()
+
+	w := New(WithKinds(KindExec, KindOpen), WithDir("/run"), WithInterval(250*time.Millisecond))
+	require.Equal(t, "/bin/date >/dev/null 2>&1; sleep 0.25; touch /run/ig-workload-0 >/dev/null 2>&1; true", w.Script())
+
+	w = New(WithKinds(KindConnect), WithCount(2), WithInterval(0))
+	require.Equal(t, "nc -w 1 127.0.0.1 8080 </dev/null >/dev/null 2>&1; nc -w 1 127.0.0.1 8081 </dev/null >/dev/null 2>&1; true", w.Script())
+}
@@ -81,7 +81,9 @@ func (tc *TracerCollection) TracerMapsUpdater() containercollection.FuncNotify {
 					one := uint32(1)
 					if mntnsC != 0 {
 						t.mntnsSetMap.Put(mntnsC, one)
-					} else {
+					} else if !event.Container.Runtime.VMIsolated {
+						// Containers running in a virtual machine don't have
+						// a mount namespace on the host
 						log.Errorf("new container with mntns=0")
 					}
 				}
@@ -134,6 +136,39 @@ func (tc *TracerCollection) AddTracer(id string, containerSelector containercoll
 	return nil
 }
 
+// UpdateTracer changes the container selector of an existing tracer and
+// updates its mount namespace set accordingly.
+func (tc *TracerCollection) UpdateTracer(id string, containerSelector containercollection.ContainerSelector) error {
+	t, ok := tc.tracers[id]
+	if !ok {
+		return fmt.Errorf("unknown tracer %q", id)
+	}
+	t.containerSelector = containerSelector
+	tc.tracers[id] = t
+
+	if t.mntnsSetMap == nil {
+		return nil
+	}
+
+	tc.containerCollection.ContainerRange(func(c *containercollection.Container) {
+		mntnsC := uint64(c.Mntns)
+		if mntnsC == 0 {
+			return
+		}
+		// Skip the pause container, see TracerMapsUpdater()
+		if c.K8s.ContainerName == "" && c.Runtime.ContainerName == "" {
+			return
+		}
+		if containercollection.ContainerSelectorMatches(&containerSelector, c) {
+			one := uint32(1)
+			t.mntnsSetMap.Put(mntnsC, one)
+		} else {
+			t.mntnsSetMap.Delete(mntnsC)
+		}
+	})
+	return nil
+}
+
 func (tc *TracerCollection) RemoveTracer(id string) error {
 	if id == "" {
 		return fmt.Errorf("container id not set")
@@ -84,11 +84,18 @@ func (r RuntimeName) String() string {
 }
 
 const (
-	RuntimeNameDocker     RuntimeName = "docker"
-	RuntimeNameContainerd RuntimeName = "containerd"
-	RuntimeNameCrio       RuntimeName = "cri-o"
-	RuntimeNamePodman     RuntimeName = "podman"
-	RuntimeNameUnknown    RuntimeName = "unknown"
+	RuntimeNameDocker        RuntimeName = "docker"
+	RuntimeNameContainerd    RuntimeName = "containerd"
+	RuntimeNameCrio          RuntimeName = "cri-o"
+	RuntimeNamePodman        RuntimeName = "podman"
+	RuntimeNameLXC           RuntimeName = "lxc"
+	RuntimeNameSystemdNspawn RuntimeName = "systemd-nspawn"
+	RuntimeNameUnknown       RuntimeName = "unknown"
+
+	// RuntimeNameHost is the runtime of the pseudo-container representing
+	// the processes of the host. It isn't a real runtime, so it can't be
+	// selected with String2RuntimeName.
+	RuntimeNameHost RuntimeName = "host"
 )
 
 func String2RuntimeName(name string) RuntimeName {
@@ -101,6 +108,10 @@ func String2RuntimeName(name string) RuntimeName {
 		return RuntimeNameCrio
 	case string(RuntimeNamePodman):
 		return RuntimeNamePodman
+	case string(RuntimeNameLXC):
+		return RuntimeNameLXC
+	case string(RuntimeNameSystemdNspawn):
+		return RuntimeNameSystemdNspawn
 	}
 	return RuntimeNameUnknown
 }
@@ -132,6 +143,31 @@ type BasicRuntimeMetadata struct {
 	// containerd: events from both initial and new containers are enriched
 	// crio: events from initial containers are enriched
 	ContainerImageDigest string `json:"containerImageDigest,omitempty" column:"containerImageDigest,hide"`
+
+	// RuntimeHandler is the handler of the runtime class of the container,
+	// i.e. "runc" or "kata". Only CRI-O provides it.
+	RuntimeHandler string `json:"runtimeHandler,omitempty" column:"runtimeHandler,hide"`
+
+	// UIDMap and GIDMap are the user namespace mappings of the container, as a
+	// comma-separated list of "containerID:hostID:size". They are empty if the
+	// container uses the user namespace of the host.
+	UIDMap string `json:"uidMap,omitempty" column:"uidMap,hide"`
+	GIDMap string `json:"gidMap,omitempty" column:"gidMap,hide"`
+
+	// VMIsolated is true if the container runs in a virtual machine, e.g. with
+	// Kata Containers or Firecracker. Its processes aren't visible from the
+	// host, so only the host side of its activity, like its network traffic,
+	// can be observed.
+	VMIsolated bool `json:"vmIsolated,omitempty" column:"vmIsolated,width:10,fixed,hide"`
+
+	// ContainerAnnotations are the Kubernetes annotations of the container
+	ContainerAnnotations map[string]string `json:"containerAnnotations,omitempty"`
+
+	// Hostname and SystemdUnit are only set for the host pseudo-container.
+	// SystemdUnit is the unit of the init process of the host, e.g.
+	// "init.scope".
+	Hostname    string `json:"hostname,omitempty" column:"hostname,hide"`
+	SystemdUnit string `json:"systemdUnit,omitempty" column:"systemdUnit,hide"`
 }
 
 func (b *BasicRuntimeMetadata) IsEnriched() bool {
@@ -151,6 +187,10 @@ func (b *BasicK8sMetadata) IsEnriched() bool {
 type K8sMetadata struct {
 	Node string `json:"node,omitempty" column:"node,template:node"`
 
+	// Cluster is the name of the kubeconfig context of the cluster, only set
+	// when running the gadget on several clusters at once
+	Cluster string `json:"cluster,omitempty" column:"cluster,template:node,hide"`
+
 	BasicK8sMetadata `json:",inline"`
 
 	// HostNetwork is true if the container uses the host network namespace
@@ -171,12 +211,17 @@ func (c *CommonData) SetNode(node string) {
 	c.K8s.Node = node
 }
 
+func (c *CommonData) SetCluster(cluster string) {
+	c.K8s.Cluster = cluster
+}
+
 func (c *CommonData) SetPodMetadata(k8s *BasicK8sMetadata, runtime *BasicRuntimeMetadata) {
 	c.K8s.PodName = k8s.PodName
 	c.K8s.Namespace = k8s.Namespace
 
 	// All containers in the same pod share the same container runtime
 	c.Runtime.RuntimeName = runtime.RuntimeName
+	c.Runtime.VMIsolated = runtime.VMIsolated
 }
 
 func (c *CommonData) SetContainerMetadata(k8s *BasicK8sMetadata, runtime *BasicRuntimeMetadata) {
@@ -189,6 +234,13 @@ func (c *CommonData) SetContainerMetadata(k8s *BasicK8sMetadata, runtime *BasicR
 	c.Runtime.ContainerID = runtime.ContainerID
 	c.Runtime.ContainerImageName = runtime.ContainerImageName
 	c.Runtime.ContainerImageDigest = runtime.ContainerImageDigest
+	c.Runtime.RuntimeHandler = runtime.RuntimeHandler
+	c.Runtime.UIDMap = runtime.UIDMap
+	c.Runtime.GIDMap = runtime.GIDMap
+	c.Runtime.VMIsolated = runtime.VMIsolated
+	c.Runtime.ContainerAnnotations = runtime.ContainerAnnotations
+	c.Runtime.Hostname = runtime.Hostname
+	c.Runtime.SystemdUnit = runtime.SystemdUnit
 }
 
 func (c *CommonData) GetNode() string {
@@ -297,6 +349,11 @@ const (
 
 	// Indicates the tracer in the node is now is able to produce events
 	READY EventType = "ready"
+
+	// Indicates that the gadget lost events, e.g. because they weren't read
+	// fast enough from the perf buffer. The number of lost events is in
+	// Dropped.
+	EVENTS_DROPPED EventType = "events_dropped"
 )
 
 type Event struct {
@@ -309,8 +366,11 @@ type Event struct {
 	// Type indicates the kind of this event
 	Type EventType `json:"type"`
 
-	// Message when Type is ERR, WARN, DEBUG or INFO
+	// Message when Type is ERR, WARN, DEBUG, INFO or EVENTS_DROPPED
 	Message string `json:"message,omitempty"`
+
+	// Dropped is the number of events lost when Type is EVENTS_DROPPED
+	Dropped uint64 `json:"dropped,omitempty"`
 }
 
 // GetBaseEvent is needed to implement commonutils.BaseElement and
@@ -351,6 +411,20 @@ func Warn(msg string) Event {
 	}
 }
 
+// EventsDropped returns an event telling that count events were lost
+func EventsDropped(count uint64) Event {
+	return Event{
+		CommonData: CommonData{
+			K8s: K8sMetadata{
+				Node: node,
+			},
+		},
+		Type:    EVENTS_DROPPED,
+		Message: fmt.Sprintf("lost %d samples", count),
+		Dropped: count,
+	}
+}
+
 func Debug(msg string) Event {
 	return Event{
 		CommonData: CommonData{
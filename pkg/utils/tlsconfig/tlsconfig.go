@@ -0,0 +1,247 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tlsconfig creates TLS configurations for the gRPC connections
+// between the clients and the ig daemon. Certificates are reloaded from disk
+// when they change, so they can be rotated without restarting the daemon.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileState identifies the content of a file by its modification time and
+// size
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFiles(paths ...string) ([]fileState, error) {
+	states := make([]fileState, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, fileState{modTime: info.ModTime(), size: info.Size()})
+	}
+	return states, nil
+}
+
+func sameStates(a, b []fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// reloader caches the result of loading some files and loads them again when
+// they change
+type reloader[T any] struct {
+	mu     sync.Mutex
+	paths  []string
+	load   func() (T, error)
+	states []fileState
+	value  T
+}
+
+func newReloader[T any](load func() (T, error), paths ...string) (*reloader[T], error) {
+	r := &reloader[T]{paths: paths, load: load}
+	if _, err := r.get(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// get returns the cached value, loading it again if the files changed. The
+// cached value is kept if loading fails, e.g. because only one of the
+// certificate and the key has been written yet.
+func (r *reloader[T]) get() (T, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states, err := statFiles(r.paths...)
+	if err == nil && r.states != nil && sameStates(states, r.states) {
+		return r.value, nil
+	}
+
+	value, loadErr := r.load()
+	if loadErr != nil {
+		if r.states != nil {
+			return r.value, nil
+		}
+		return value, loadErr
+	}
+	if err != nil {
+		// Files changed while loading them, try again next time
+		states = []fileState{}
+	}
+
+	r.value = value
+	r.states = states
+	return value, nil
+}
+
+func newKeyPairReloader(certFile, keyFile string) (*reloader[*tls.Certificate], error) {
+	return newReloader(func() (*tls.Certificate, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading key pair %q, %q: %w", certFile, keyFile, err)
+		}
+		return &cert, nil
+	}, certFile, keyFile)
+}
+
+func newCertPoolReloader(caFile string) (*reloader[*x509.CertPool], error) {
+	return newReloader(func() (*x509.CertPool, error) {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %q", caFile)
+		}
+		return pool, nil
+	}, caFile)
+}
+
+// NewServerConfig returns the TLS configuration of a server using the given
+// certificate and key. If clientCAFile is set, clients must present a
+// certificate signed by one of the CAs in it. nextProtos are the application
+// protocols negotiated through ALPN, e.g. "h2" for gRPC. All files are
+// reloaded when they change.
+func NewServerConfig(certFile, keyFile, clientCAFile string, nextProtos ...string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("both the certificate and the key are required")
+	}
+
+	keyPair, err := newKeyPairReloader(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var clientCAs *reloader[*x509.CertPool]
+	if clientCAFile != "" {
+		clientCAs, err = newCertPoolReloader(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return keyPair.get()
+	}
+
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+	}
+	// The configuration returned for each client replaces the base one, it
+	// has to keep the same protocols
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		config := &tls.Config{
+			MinVersion:     base.MinVersion,
+			NextProtos:     base.NextProtos,
+			GetCertificate: getCertificate,
+		}
+		if clientCAs != nil {
+			pool, err := clientCAs.get()
+			if err != nil {
+				return nil, err
+			}
+			config.ClientCAs = pool
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+		return config, nil
+	}
+	return base, nil
+}
+
+// NewClientConfig returns the TLS configuration of a client verifying the
+// server certificate with the CAs in caFile, or the system ones if empty.
+// certFile and keyFile are optional and used to authenticate the client. All
+// files are reloaded when they change.
+func NewClientConfig(certFile, keyFile, caFile, serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if caFile != "" {
+		caPool, err := newCertPoolReloader(caFile)
+		if err != nil {
+			return nil, err
+		}
+		// RootCAs can't be reloaded, the server certificate is verified
+		// against the current CAs in VerifyConnection instead
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyServer(cs, caPool)
+		}
+	}
+
+	switch {
+	case certFile != "" && keyFile != "":
+		keyPair, err := newKeyPairReloader(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return keyPair.get()
+		}
+	case certFile != "" || keyFile != "":
+		return nil, errors.New("both the certificate and the key are required")
+	}
+
+	return config, nil
+}
+
+// verifyServer does the same verification of the server certificate as
+// crypto/tls, using the CAs of caPool.
+func verifyServer(cs tls.ConnectionState, caPool *reloader[*x509.CertPool]) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server didn't present a certificate")
+	}
+
+	roots, err := caPool.get()
+	if err != nil {
+		return err
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+		return fmt.Errorf("verifying server certificate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 The Inspektor Gadget authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, serial int64, commonName string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signerCert, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0o600))
+
+	if keyFile == "" {
+		return
+	}
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0o600))
+}
+
+// handshake performs a TLS handshake between a client and a server using the
+// given configurations and returns the certificate presented by the server
+func handshake(t *testing.T, serverConfig, clientConfig *tls.Config) (*x509.Certificate, error) {
+	t.Helper()
+
+	// Use a TCP connection instead of net.Pipe(), as the latter isn't buffered
+	// and the alerts and session tickets sent by the server would block
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		server := tls.Server(conn, serverConfig)
+		serverErr <- server.Handshake()
+		server.Close()
+	}()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	client := tls.Client(clientConn, clientConfig)
+	err = client.Handshake()
+	if err == nil {
+		// With TLS 1.3, the client handshake finishes before the server
+		// verified the client certificate
+		err = <-serverErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	ca := newTestCert(t, 1, "ca", nil)
+	ca.write(t, path("ca.crt"), "")
+	newTestCert(t, 2, "server", ca).write(t, path("server.crt"), path("server.key"))
+	newTestCert(t, 3, "client", ca).write(t, path("client.crt"), path("client.key"))
+	otherCA := newTestCert(t, 4, "other-ca", nil)
+	newTestCert(t, 5, "client", otherCA).write(t, path("other.crt"), path("other.key"))
+
+	serverConfig, err := NewServerConfig(path("server.crt"), path("server.key"), path("ca.crt"))
+	require.NoError(t, err)
+
+	// Valid client certificate
+	clientConfig, err := NewClientConfig(path("client.crt"), path("client.key"), path("ca.crt"), "server")
+	require.NoError(t, err)
+	cert, err := handshake(t, serverConfig, clientConfig)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cert.SerialNumber.Int64())
+
+	// No client certificate
+	clientConfig, err = NewClientConfig("", "", path("ca.crt"), "server")
+	require.NoError(t, err)
+	_, err = handshake(t, serverConfig, clientConfig)
+	require.Error(t, err)
+
+	// Client certificate signed by another CA
+	clientConfig, err = NewClientConfig(path("other.crt"), path("other.key"), path("ca.crt"), "server")
+	require.NoError(t, err)
+	_, err = handshake(t, serverConfig, clientConfig)
+	require.Error(t, err)
+
+	// Wrong server name
+	clientConfig, err = NewClientConfig(path("client.crt"), path("client.key"), path("ca.crt"), "foo")
+	require.NoError(t, err)
+	_, err = handshake(t, serverConfig, clientConfig)
+	require.Error(t, err)
+}
+
+func TestCertificateRotation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	ca := newTestCert(t, 1, "ca", nil)
+	ca.write(t, path("ca.crt"), "")
+	newTestCert(t, 2, "server", ca).write(t, path("server.crt"), path("server.key"))
+	newTestCert(t, 3, "client", ca).write(t, path("client.crt"), path("client.key"))
+
+	serverConfig, err := NewServerConfig(path("server.crt"), path("server.key"), path("ca.crt"))
+	require.NoError(t, err)
+	clientConfig, err := NewClientConfig(path("client.crt"), path("client.key"), path("ca.crt"), "server")
+	require.NoError(t, err)
+
+	cert, err := handshake(t, serverConfig, clientConfig)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cert.SerialNumber.Int64())
+
+	// Rotate the server certificate, make sure the modification time changes
+	// even on file systems with a coarse granularity
+	newTestCert(t, 6, "server", ca).write(t, path("server.crt"), path("server.key"))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path("server.crt"), future, future))
+	require.NoError(t, os.Chtimes(path("server.key"), future, future))
+
+	cert, err = handshake(t, serverConfig, clientConfig)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), cert.SerialNumber.Int64())
+
+	// A broken key pair doesn't replace the current one
+	require.NoError(t, os.WriteFile(path("server.key"), []byte("broken"), 0o600))
+	cert, err = handshake(t, serverConfig, clientConfig)
+	require.NoError(t, err)
+	require.Equal(t, int64(6), cert.SerialNumber.Int64())
+}
+
+func TestCARotation(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	ca := newTestCert(t, 1, "ca", nil)
+	ca.write(t, path("ca.crt"), "")
+	newTestCert(t, 2, "server", ca).write(t, path("server.crt"), path("server.key"))
+	newTestCert(t, 3, "client", ca).write(t, path("client.crt"), path("client.key"))
+
+	serverConfig, err := NewServerConfig(path("server.crt"), path("server.key"), path("ca.crt"))
+	require.NoError(t, err)
+	clientConfig, err := NewClientConfig(path("client.crt"), path("client.key"), path("ca.crt"), "server")
+	require.NoError(t, err)
+
+	_, err = handshake(t, serverConfig, clientConfig)
+	require.NoError(t, err)
+
+	// Replace the CA and all the certificates signed by it, both sides must
+	// use the new CA
+	newCA := newTestCert(t, 4, "new-ca", nil)
+	newCA.write(t, path("ca.crt"), "")
+	newTestCert(t, 5, "server", newCA).write(t, path("server.crt"), path("server.key"))
+	newTestCert(t, 6, "client", newCA).write(t, path("client.crt"), path("client.key"))
+	future := time.Now().Add(time.Minute)
+	for _, name := range []string{"ca.crt", "server.crt", "server.key", "client.crt", "client.key"} {
+		require.NoError(t, os.Chtimes(path(name), future, future))
+	}
+
+	cert, err := handshake(t, serverConfig, clientConfig)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), cert.SerialNumber.Int64())
+}
+
+func TestNextProtos(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	ca := newTestCert(t, 1, "ca", nil)
+	ca.write(t, path("ca.crt"), "")
+	newTestCert(t, 2, "server", ca).write(t, path("server.crt"), path("server.key"))
+
+	serverConfig, err := NewServerConfig(path("server.crt"), path("server.key"), path("ca.crt"), "h2")
+	require.NoError(t, err)
+
+	config, err := serverConfig.GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"h2"}, config.NextProtos)
+}
+
+func TestMissingFiles(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewServerConfig("", "", "")
+	require.Error(t, err)
+
+	_, err = NewServerConfig("/nonexistent.crt", "/nonexistent.key", "")
+	require.Error(t, err)
+
+	_, err = NewClientConfig("/nonexistent.crt", "", "", "")
+	require.Error(t, err)
+}